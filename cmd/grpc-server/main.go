@@ -0,0 +1,120 @@
+// Command mark42-grpc serves the knowledge graph over gRPC for other services.
+// The generated code in gen/mark42/v1 is committed; "make proto" regenerates
+// it after proto/mark42/v1/memory.proto changes.
+package main
+
+//go:generate protoc -I ../../proto --go_out=../.. --go_opt=module=github.com/mfenderov/mark42 --go-grpc_out=../.. --go-grpc_opt=module=github.com/mfenderov/mark42 mark42/v1/memory.proto
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+
+	mark42v1 "github.com/mfenderov/mark42/gen/mark42/v1"
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+func main() {
+	home, _ := os.UserHomeDir()
+	defaultDB := os.Getenv("CLAUDE_MEMORY_DB")
+	if defaultDB == "" {
+		defaultDB = filepath.Join(home, ".claude", "memory.db")
+	}
+
+	addr := flag.String("addr", "127.0.0.1:4242", "address to listen on")
+	dbPath := flag.String("db", defaultDB, "path to database file, or a libsql:// URL")
+	flag.Parse()
+
+	if storage.IsLocalPath(*dbPath) {
+		if err := os.MkdirAll(filepath.Dir(*dbPath), 0o755); err != nil {
+			logError("failed to create database directory: %v", err)
+			os.Exit(1)
+		}
+	}
+
+	store, err := storage.NewStore(*dbPath)
+	if err != nil {
+		logError("failed to open database: %v", err)
+		os.Exit(1)
+	}
+	defer store.Close()
+	if err := store.Migrate(); err != nil {
+		logError("failed to migrate database: %v", err)
+		os.Exit(1)
+	}
+
+	if on, _ := strconv.ParseBool(os.Getenv("CLAUDE_MEMORY_CASE_INSENSITIVE_NAMES")); on {
+		store.SetCaseInsensitiveNames(true)
+	}
+	store.SetSource(storage.SourceGRPC)
+	store.SetUser(os.Getenv("CLAUDE_MEMORY_USER"))
+	if on, _ := strconv.ParseBool(os.Getenv("CLAUDE_MEMORY_AUTO_LINK_MENTIONS")); on {
+		store.SetAutoLinkMentions(storage.DefaultAutoLinkMinNameLength)
+	}
+	store.SetEntityTypes(storage.ParseEntityTypes(os.Getenv("CLAUDE_MEMORY_ENTITY_TYPES")))
+	store.SetRelationTypes(storage.ParseRelationTypes(os.Getenv("CLAUDE_MEMORY_RELATION_TYPES")))
+	if path := os.Getenv("CLAUDE_MEMORY_RULES"); path != "" {
+		rules, err := storage.LoadRules(path)
+		if err != nil {
+			logError("failed to load rules: %v", err)
+			os.Exit(1)
+		}
+		rules.Warn = func(v storage.RuleViolation) {
+			logError("rule violated: %s %s %s", v.Kind, v.Subject, v.Reason)
+		}
+		store.SetRules(rules)
+	}
+	if path := os.Getenv("CLAUDE_MEMORY_POLICIES"); path != "" {
+		policies, err := storage.LoadPolicies(path)
+		if err == nil {
+			err = store.SetPolicies(policies)
+		}
+		if err != nil {
+			logError("failed to load policies: %v", err)
+			os.Exit(1)
+		}
+	}
+	redaction := storage.Redaction{Warn: func(r storage.Redacted) {
+		logError("secret redacted from an observation on %s: %s", r.Entity, strings.Join(r.Types, ", "))
+	}}
+	if on, err := strconv.ParseBool(os.Getenv("CLAUDE_MEMORY_REDACT_SECRETS")); err == nil {
+		redaction.Presets = &on
+	}
+	store.SetRedaction(redaction) // Presets only, which always compile
+	if err := store.SetPIIMode(storage.PIIMode(os.Getenv("CLAUDE_MEMORY_PII_MODE"))); err != nil {
+		logError("CLAUDE_MEMORY_PII_MODE: %v", err)
+		os.Exit(1)
+	}
+
+	srv := &memoryServer{store: store}
+	if url := os.Getenv("CLAUDE_MEMORY_EMBEDDER_URL"); url != "" && url != "disabled" {
+		srv.embedder = storage.NewEmbeddingClient(url)
+	}
+
+	lis, err := net.Listen("tcp", *addr)
+	if err != nil {
+		logError("failed to listen on %s: %v", *addr, err)
+		os.Exit(1)
+	}
+
+	grpcServer := grpc.NewServer()
+	mark42v1.RegisterMemoryServiceServer(grpcServer, srv)
+	reflection.Register(grpcServer)
+
+	logError("serving on %s", lis.Addr())
+	if err := grpcServer.Serve(lis); err != nil {
+		logError("server error: %v", err)
+		os.Exit(1)
+	}
+}
+
+func logError(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "[mark42-grpc] "+format+"\n", args...)
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	mark42v1 "github.com/mfenderov/mark42/gen/mark42/v1"
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+const defaultSearchLimit = 20
+
+// memoryServer implements MemoryService on top of the storage layer.
+type memoryServer struct {
+	mark42v1.UnimplementedMemoryServiceServer
+	store    *storage.Store
+	embedder *storage.EmbeddingClient // Optional; enables semantic search
+}
+
+// storeError maps storage errors to gRPC status codes.
+func storeError(err error) error {
+	if errors.Is(err, storage.ErrNotFound) {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	if errors.Is(err, storage.ErrInvalidInput) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if errors.Is(err, storage.ErrEntityExists) {
+		return status.Error(codes.AlreadyExists, err.Error())
+	}
+	if errors.Is(err, storage.ErrSchemaOutdated) {
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+func toProtoEntity(e *storage.Entity) *mark42v1.Entity {
+	return &mark42v1.Entity{
+		Name:         e.Name,
+		Type:         e.Type,
+		Observations: e.Observations,
+		CreatedAt:    timestamppb.New(e.CreatedAt),
+		Version:      int32(e.Version),
+	}
+}
+
+func (s *memoryServer) GetEntity(ctx context.Context, req *mark42v1.GetEntityRequest) (*mark42v1.Entity, error) {
+	if req.GetName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "name is required")
+	}
+	entity, err := s.store.GetEntityContext(ctx, req.GetName())
+	if err != nil {
+		return nil, storeError(err)
+	}
+	return toProtoEntity(entity), nil
+}
+
+func (s *memoryServer) ListEntities(req *mark42v1.ListEntitiesRequest, stream grpc.ServerStreamingServer[mark42v1.Entity]) error {
+	entities, err := s.store.ListEntitiesContext(stream.Context(), req.GetType())
+	if err != nil {
+		return storeError(err)
+	}
+	for _, e := range entities {
+		if err := stream.Send(toProtoEntity(e)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *memoryServer) CreateEntity(ctx context.Context, req *mark42v1.CreateEntityRequest) (*mark42v1.Entity, error) {
+	if req.GetName() == "" || req.GetType() == "" {
+		return nil, status.Error(codes.InvalidArgument, "name and type are required")
+	}
+	entity, err := s.store.CreateEntityContext(ctx, req.GetName(), req.GetType(), req.GetObservations())
+	if err != nil {
+		return nil, storeError(err)
+	}
+	return toProtoEntity(entity), nil
+}
+
+func (s *memoryServer) AddObservations(ctx context.Context, req *mark42v1.AddObservationsRequest) (*mark42v1.AddObservationsResponse, error) {
+	if req.GetEntity() == "" {
+		return nil, status.Error(codes.InvalidArgument, "entity is required")
+	}
+	var added int32
+	for _, content := range req.GetContents() {
+		if strings.TrimSpace(content) == "" {
+			continue
+		}
+		if err := s.store.AddObservationContext(ctx, req.GetEntity(), content); err != nil {
+			return nil, storeError(err)
+		}
+		added++
+	}
+	return &mark42v1.AddObservationsResponse{Added: added}, nil
+}
+
+func (s *memoryServer) CreateRelation(ctx context.Context, req *mark42v1.Relation) (*mark42v1.Relation, error) {
+	if req.GetFrom() == "" || req.GetTo() == "" || req.GetType() == "" {
+		return nil, status.Error(codes.InvalidArgument, "from, to, and type are required")
+	}
+	if err := s.store.CreateRelationContext(ctx, req.GetFrom(), req.GetTo(), req.GetType()); err != nil {
+		return nil, storeError(err)
+	}
+	return req, nil
+}
+
+func (s *memoryServer) ListRelations(ctx context.Context, req *mark42v1.ListRelationsRequest) (*mark42v1.ListRelationsResponse, error) {
+	relations, err := s.store.ListRelationsContext(ctx, req.GetEntity())
+	if err != nil {
+		return nil, storeError(err)
+	}
+	resp := &mark42v1.ListRelationsResponse{}
+	for _, r := range relations {
+		resp.Relations = append(resp.Relations, &mark42v1.Relation{From: r.From, To: r.To, Type: r.Type})
+	}
+	return resp, nil
+}
+
+func (s *memoryServer) Search(req *mark42v1.SearchRequest, stream grpc.ServerStreamingServer[mark42v1.SearchResult]) error {
+	if strings.TrimSpace(req.GetQuery()) == "" {
+		return status.Error(codes.InvalidArgument, "query is required")
+	}
+	limit := int(req.GetLimit())
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+
+	var embedder *storage.EmbeddingClient
+	if req.GetSemantic() {
+		embedder = s.embedder
+	}
+	results, err := s.store.HybridSearchWithEmbedder(stream.Context(), req.GetQuery(), embedder, limit, storage.HybridOptions{})
+	if err != nil {
+		return storeError(err)
+	}
+	for _, r := range results {
+		if err := stream.Send(&mark42v1.SearchResult{
+			EntityName: r.EntityName,
+			EntityType: r.EntityType,
+			Content:    r.Content,
+			Score:      r.FusionScore,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *memoryServer) GetContext(ctx context.Context, req *mark42v1.GetContextRequest) (*mark42v1.GetContextResponse, error) {
+	cfg := storage.DefaultContextConfig()
+	if req.GetTokenBudget() > 0 {
+		cfg.TokenBudget = int(req.GetTokenBudget())
+	}
+	results, err := s.store.GetContextForInjectionContext(ctx, cfg, req.GetProject())
+	if err != nil {
+		return nil, storeError(err)
+	}
+	resp := &mark42v1.GetContextResponse{}
+	for _, r := range results {
+		resp.Items = append(resp.Items, &mark42v1.ContextItem{
+			EntityName: r.EntityName,
+			EntityType: r.EntityType,
+			Content:    r.Content,
+			FactType:   r.FactType,
+			Importance: r.Importance,
+			Pinned:     r.Pinned,
+		})
+	}
+	return resp, nil
+}
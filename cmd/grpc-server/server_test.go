@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	mark42v1 "github.com/mfenderov/mark42/gen/mark42/v1"
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+// newTestClient serves a fresh in-memory store over an in-process
+// connection and returns a client for it.
+func newTestClient(t *testing.T) mark42v1.MemoryServiceClient {
+	t.Helper()
+	store, err := storage.NewStore(storage.InMemory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	store.SetSource(storage.SourceGRPC)
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	mark42v1.RegisterMemoryServiceServer(srv, &memoryServer{store: store})
+	go srv.Serve(lis)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		srv.Stop()
+		store.Close()
+	})
+	return mark42v1.NewMemoryServiceClient(conn)
+}
+
+func TestMemoryService_RoundTrip(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	created, err := client.CreateEntity(ctx, &mark42v1.CreateEntityRequest{
+		Name: "TDD", Type: "pattern", Observations: []string{"Write tests first"},
+	})
+	if err != nil {
+		t.Fatalf("CreateEntity failed: %v", err)
+	}
+	if created.GetName() != "TDD" || created.GetType() != "pattern" || created.GetCreatedAt() == nil {
+		t.Errorf("unexpected entity %v", created)
+	}
+	if _, err := client.CreateEntity(ctx, &mark42v1.CreateEntityRequest{Name: "TDD", Type: "pattern"}); status.Code(err) != codes.AlreadyExists {
+		t.Errorf("expected AlreadyExists, got %v", err)
+	}
+
+	added, err := client.AddObservations(ctx, &mark42v1.AddObservationsRequest{
+		Entity: "TDD", Contents: []string{"Red, green, refactor", " "},
+	})
+	if err != nil || added.GetAdded() != 1 {
+		t.Errorf("expected one observation added, got %v (%v)", added, err)
+	}
+	if _, err := client.CreateEntity(ctx, &mark42v1.CreateEntityRequest{Name: "Go", Type: "language"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.CreateRelation(ctx, &mark42v1.Relation{From: "TDD", To: "Go", Type: "applies_to"}); err != nil {
+		t.Fatalf("CreateRelation failed: %v", err)
+	}
+
+	entity, err := client.GetEntity(ctx, &mark42v1.GetEntityRequest{Name: "TDD"})
+	if err != nil || len(entity.GetObservations()) != 2 {
+		t.Errorf("expected two observations, got %v (%v)", entity, err)
+	}
+	if _, err := client.GetEntity(ctx, &mark42v1.GetEntityRequest{Name: "Missing"}); status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound, got %v", err)
+	}
+	relations, err := client.ListRelations(ctx, &mark42v1.ListRelationsRequest{Entity: "TDD"})
+	if err != nil || len(relations.GetRelations()) != 1 || relations.GetRelations()[0].GetTo() != "Go" {
+		t.Errorf("unexpected relations %v (%v)", relations, err)
+	}
+
+	stream, err := client.ListEntities(ctx, &mark42v1.ListEntitiesRequest{Type: "pattern"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for {
+		e, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("ListEntities failed: %v", err)
+		}
+		names = append(names, e.GetName())
+	}
+	if len(names) != 1 || names[0] != "TDD" {
+		t.Errorf("expected only TDD listed, got %v", names)
+	}
+
+	search, err := client.Search(ctx, &mark42v1.SearchRequest{Query: "refactor"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := search.Recv()
+	if err != nil || first.GetEntityName() != "TDD" {
+		t.Errorf("expected TDD found first, got %v (%v)", first, err)
+	}
+
+	got, err := client.GetContext(ctx, &mark42v1.GetContextRequest{TokenBudget: 500})
+	if err != nil || len(got.GetItems()) == 0 {
+		t.Errorf("expected context items, got %v (%v)", got, err)
+	}
+}
+
+func TestMemoryService_InvalidArguments(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	if _, err := client.CreateEntity(ctx, &mark42v1.CreateEntityRequest{Name: "TDD"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for a missing type, got %v", err)
+	}
+	stream, err := client.Search(ctx, &mark42v1.SearchRequest{Query: "  "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for an empty query, got %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+var aliasCmd = &cobra.Command{
+	Use:   "alias",
+	Short: "Manage other names entities go by",
+	Long: `An alias is another name for an entity, such as "memory plugin" for mark42.
+rel create --resolve and the create_relations tool's resolveAliases option find
+endpoints by alias. Merging an entity makes its name an alias of the kept one.`,
+}
+
+var aliasAddCmd = &cobra.Command{
+	Use:   "add <entity> <alias>...",
+	Short: "Add aliases to an entity",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		if err := store.Migrate(); err != nil {
+			return err
+		}
+
+		for _, alias := range args[1:] {
+			if err := store.AddAlias(args[0], alias); err != nil {
+				return err
+			}
+		}
+		output(successStyle.Render("Updated aliases of " + args[0]))
+		return nil
+	},
+}
+
+var aliasRemoveCmd = &cobra.Command{
+	Use:   "remove <alias>...",
+	Short: "Remove aliases",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		if err := store.Migrate(); err != nil {
+			return err
+		}
+
+		for _, alias := range args {
+			if err := store.RemoveAlias(alias); err != nil {
+				return err
+			}
+		}
+		output(successStyle.Render("Removed aliases"))
+		return nil
+	},
+}
+
+var aliasListCmd = &cobra.Command{
+	Use:   "list [entity]",
+	Short: "List the aliases of an entity, or all aliases",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		if err := store.Migrate(); err != nil {
+			return err
+		}
+
+		entity := ""
+		if len(args) == 1 {
+			entity = args[0]
+		}
+		aliases, err := store.ListAliases(entity)
+		if err != nil {
+			return err
+		}
+		if format, _ := cmd.Flags().GetString("format"); format == "json" {
+			if aliases == nil {
+				aliases = []storage.EntityAlias{}
+			}
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			return enc.Encode(aliases)
+		}
+		if len(aliases) == 0 {
+			output(dimStyle.Render("No aliases"))
+			return nil
+		}
+		for _, a := range aliases {
+			output(a.Alias + " → " + entityStyle.Render(a.Entity))
+		}
+		return nil
+	},
+}
+
+func init() {
+	aliasListCmd.Flags().String("format", "default", "output format: default, json")
+	aliasCmd.AddCommand(aliasAddCmd, aliasRemoveCmd, aliasListCmd)
+	rootCmd.AddCommand(aliasCmd)
+}
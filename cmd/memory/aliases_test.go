@@ -0,0 +1,45 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+func TestAliasCommands(t *testing.T) {
+	useTestDB(t)
+	withStore(t, func(s *storage.Store) {
+		if _, err := s.CreateEntity("mark42", "project", nil); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	runRootCmd(t, "alias", "add", "mark42", "memory plugin")
+	if got := runRootCmd(t, "alias", "list"); !strings.Contains(got, "memory plugin → mark42") {
+		t.Errorf("expected the alias listed:\n%s", got)
+	}
+
+	rootCmd.SetArgs([]string{"rel", "create", "memory plugin", "SQLite", "uses"})
+	if err := rootCmd.Execute(); exitCode(err) != exitNotFound {
+		t.Errorf("expected unresolved endpoints to be not found, got %v", err)
+	}
+
+	defer relCreateCmd.Flags().Set("resolve", "false")
+	defer relCreateCmd.Flags().Set("create-missing", "false")
+	runRootCmd(t, "rel", "create", "memory plugin", "SQLite", "uses", "--resolve", "--create-missing")
+	withStore(t, func(s *storage.Store) {
+		relations, err := s.ListRelations("mark42")
+		if err != nil || len(relations) != 1 || relations[0].To != "SQLite" {
+			t.Errorf("expected mark42 -uses-> SQLite, got %+v (%v)", relations, err)
+		}
+		if e, err := s.GetEntity("SQLite"); err != nil || e.Type != storage.StubEntityType {
+			t.Errorf("expected a stub SQLite entity, got %+v (%v)", e, err)
+		}
+	})
+
+	runRootCmd(t, "alias", "remove", "memory plugin")
+	if got := runRootCmd(t, "alias", "list", "mark42"); !strings.Contains(got, "No aliases") {
+		t.Errorf("expected no aliases left:\n%s", got)
+	}
+}
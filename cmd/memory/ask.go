@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+var askCmd = &cobra.Command{
+	Use:   "ask <question>",
+	Short: "Answer a question from memory",
+	Long: `Run hybrid search for the question and gather the best observations within a
+token budget. When CLAUDE_MEMORY_SUMMARY_MODEL is set, a local LLM answers from
+that evidence, citing it as [n]; otherwise the ranked evidence is printed.
+
+Vector search uses the embedder at CLAUDE_MEMORY_EMBEDDER_URL (default: Ollama)
+and is skipped if it is unavailable or set to "disabled".`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		question := strings.Join(args, " ")
+
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		if err := store.Migrate(); err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+		defer cancel()
+
+		var queryEmbedding []float64
+		if embedder := embedderFromEnv(); embedder != nil {
+			embedCtx, embedCancel := context.WithTimeout(ctx, 5*time.Second)
+			queryEmbedding, err = embedder.CreateEmbedding(embedCtx, question)
+			embedCancel()
+			if err != nil {
+				logger.Debug("embedder unavailable, using keyword search", "error", err)
+			}
+		}
+
+		tokens, _ := cmd.Flags().GetInt("tokens")
+		evidence, err := store.GatherEvidenceContext(ctx, question, queryEmbedding, tokens)
+		if err != nil {
+			return err
+		}
+
+		var answer string
+		noLLM, _ := cmd.Flags().GetBool("no-llm")
+		if client := summarizerFromEnv(); client != nil && !noLLM && len(evidence) > 0 {
+			if answer, err = client.Answer(ctx, question, evidence); err != nil {
+				logger.Warn("LLM answer failed, showing evidence", "error", err)
+			}
+		}
+
+		if format, _ := cmd.Flags().GetString("format"); format == "json" {
+			source := "evidence"
+			if answer != "" {
+				source = "llm"
+			}
+			if evidence == nil {
+				evidence = []storage.Evidence{}
+			}
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			return enc.Encode(map[string]any{"answer": answer, "source": source, "evidence": evidence})
+		}
+
+		if len(evidence) == 0 {
+			output("No memories match the question")
+			return nil
+		}
+		if answer != "" {
+			output(answer)
+			output()
+			output(titleStyle.Render("Sources"))
+		} else {
+			output(titleStyle.Render(fmt.Sprintf("Evidence (%d)", len(evidence))))
+		}
+		for _, e := range evidence {
+			output(fmt.Sprintf("  [%d] %s %s %s", e.Ref, entityStyle.Render(e.EntityName),
+				typeStyle.Render("("+e.EntityType+")"), obsStyle.Render(e.Content)))
+		}
+		return nil
+	},
+}
+
+// embedderFromEnv returns the embedder at CLAUDE_MEMORY_EMBEDDER_URL, else the
+// one in the config files (default: Ollama), or nil when it is "disabled".
+func embedderFromEnv() *storage.EmbeddingClient {
+	cfg := loadEffectiveConfig(configProjectDir())
+	if cfg.EmbedderURL == "disabled" {
+		return nil
+	}
+	client := storage.NewEmbeddingClient(cfg.EmbedderURL)
+	client.SetModel(cfg.EmbedderModel)
+	return client
+}
+
+// embedderSettings returns the embedder URL and model of cmd's --url and
+// --model flags when given, else of the config files.
+func embedderSettings(cmd *cobra.Command) (url, model string) {
+	cfg := loadEffectiveConfig(configProjectDir())
+	url, model = cfg.EmbedderURL, cfg.EmbedderModel
+	if flag := cmd.Flags().Lookup("url"); flag != nil && flag.Changed {
+		url = flag.Value.String()
+	}
+	if flag := cmd.Flags().Lookup("model"); flag != nil && flag.Changed {
+		model = flag.Value.String()
+	}
+	return url, model
+}
+
+func init() {
+	askCmd.Flags().Int("tokens", storage.DefaultAskTokenBudget, "maximum tokens of evidence to gather")
+	askCmd.Flags().Bool("no-llm", false, "print the evidence without asking the LLM")
+	askCmd.Flags().String("format", "text", "output format: text, json")
+	rootCmd.AddCommand(askCmd)
+}
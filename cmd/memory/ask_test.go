@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+func TestAsk(t *testing.T) {
+	useTestDB(t)
+	t.Setenv("CLAUDE_MEMORY_EMBEDDER_URL", "disabled")
+	t.Setenv("CLAUDE_MEMORY_SUMMARY_MODEL", "")
+	withStore(t, func(s *storage.Store) {
+		if _, err := s.CreateEntity("Parser", "component", []string{"Parser uses the Lexer for tokens"}); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	got := runRootCmd(t, "ask", "What does the parser use?")
+	if !strings.Contains(got, "Evidence (1)") || !strings.Contains(got, "[1] Parser") {
+		t.Errorf("expected ranked evidence:\n%s", got)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "The Lexer [1]."}}]}`))
+	}))
+	defer server.Close()
+	t.Setenv("CLAUDE_MEMORY_EMBEDDER_URL", server.URL) // Embedding fails against this server; keyword search remains
+	t.Setenv("CLAUDE_MEMORY_SUMMARY_MODEL", "llama3.2")
+
+	defer askCmd.Flags().Set("format", "text")
+	got = runRootCmd(t, "ask", "--format", "json", "What does the parser use?")
+	var result struct {
+		Answer   string             `json:"answer"`
+		Source   string             `json:"source"`
+		Evidence []storage.Evidence `json:"evidence"`
+	}
+	if err := json.Unmarshal([]byte(got), &result); err != nil {
+		t.Fatalf("invalid JSON %q: %v", got, err)
+	}
+	if result.Answer != "The Lexer [1]." || result.Source != "llm" || len(result.Evidence) != 1 {
+		t.Errorf("expected an LLM answer with evidence, got %+v", result)
+	}
+
+	defer askCmd.Flags().Set("no-llm", "false")
+	got = runRootCmd(t, "ask", "--format", "text", "--no-llm", "What does the parser use?")
+	if strings.Contains(got, "The Lexer [1].") {
+		t.Errorf("expected --no-llm to skip the answer:\n%s", got)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+var attrCmd = &cobra.Command{
+	Use:   "attr",
+	Short: "Manage typed entity attributes",
+	Long: `Attributes are key-value facts such as repo_url, version, or language.
+Values are checked against the schema for the entity's type (see 'mark42 attr
+schema'); types without a schema take any lower_snake_case key. Schemas can be
+overridden per type under "attributeSchemas" in config.json.`,
+}
+
+var attrSetCmd = &cobra.Command{
+	Use:   "set <entity> <key=value>...",
+	Short: "Set attributes on an entity (an empty value removes one)",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		attrs, err := parseAttributes(args[1:])
+		if err != nil {
+			return err
+		}
+
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		if err := store.Migrate(); err != nil {
+			return err
+		}
+
+		if err := store.SetAttributes(args[0], attrs); err != nil {
+			return err
+		}
+		output(successStyle.Render("Updated attributes on " + args[0]))
+		return nil
+	},
+}
+
+var attrGetCmd = &cobra.Command{
+	Use:   "get <entity>",
+	Short: "Show an entity's attributes",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		if err := store.Migrate(); err != nil {
+			return err
+		}
+
+		attrs, err := store.GetAttributes(args[0])
+		if err != nil {
+			return err
+		}
+		if format, _ := cmd.Flags().GetString("format"); format == "json" {
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			return enc.Encode(attrs)
+		}
+		if len(attrs) == 0 {
+			output(dimStyle.Render("No attributes on " + args[0]))
+			return nil
+		}
+		for _, key := range slices.Sorted(maps.Keys(attrs)) {
+			output(typeStyle.Render(key+":") + " " + attrs[key])
+		}
+		return nil
+	},
+}
+
+var attrSchemaCmd = &cobra.Command{
+	Use:   "schema [entity-type]",
+	Short: "Show the attribute schemas in effect",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		schemas := loadEffectiveConfig(configProjectDir()).AttributeSchemas
+		types := slices.Sorted(maps.Keys(schemas))
+		if len(args) == 1 {
+			if _, ok := schemas[args[0]]; !ok {
+				output(dimStyle.Render("No schema for " + args[0] + "; any attribute is accepted"))
+				return nil
+			}
+			types = args
+		}
+		for _, t := range types {
+			output(entityStyle.Render(t))
+			for _, key := range slices.Sorted(maps.Keys(schemas[t])) {
+				output("  " + key + " " + typeStyle.Render("("+string(schemas[t][key])+")"))
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	attrGetCmd.Flags().String("format", "default", "output format: default, json")
+	attrCmd.AddCommand(attrSetCmd, attrGetCmd, attrSchemaCmd)
+	rootCmd.AddCommand(attrCmd)
+}
+
+// parseAttributes turns key=value arguments into a map.
+func parseAttributes(args []string) (map[string]string, error) {
+	attrs := make(map[string]string, len(args))
+	for _, arg := range args {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, &storage.ValidationError{Field: "attribute", Reason: "must be key=value, got " + arg}
+		}
+		attrs[strings.TrimSpace(key)] = value
+	}
+	return attrs, nil
+}
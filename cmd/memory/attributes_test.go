@@ -0,0 +1,54 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+func TestAttrCommands(t *testing.T) {
+	useTestDB(t)
+	t.Setenv("HOME", t.TempDir())
+	withStore(t, func(s *storage.Store) {
+		if _, err := s.CreateEntity("mark42", "project", []string{"Memory for coding agents"}); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	runRootCmd(t, "attr", "set", "mark42", "language=Go", "repo_url=https://github.com/mfenderov/mark42")
+	got := runRootCmd(t, "attr", "get", "mark42")
+	if !strings.Contains(got, "language: Go") || !strings.Contains(got, "repo_url: https://github.com/mfenderov/mark42") {
+		t.Errorf("expected attributes listed:\n%s", got)
+	}
+
+	rootCmd.SetArgs([]string{"attr", "set", "mark42", "started=yesterday"})
+	if err := rootCmd.Execute(); exitCode(err) != exitInvalidInput {
+		t.Errorf("expected a bad date rejected as invalid input, got %v", err)
+	}
+	rootCmd.SetArgs([]string{"attr", "set", "mark42", "language"})
+	if err := rootCmd.Execute(); exitCode(err) != exitInvalidInput {
+		t.Errorf("expected a missing = rejected as invalid input, got %v", err)
+	}
+
+	defer resetSliceFlag(searchCmd, "attr")
+	got = runRootCmd(t, "search", "", "--attr", "language=go")
+	if !strings.Contains(got, "mark42") {
+		t.Errorf("expected the attribute search to find mark42:\n%s", got)
+	}
+
+	got = runRootCmd(t, "attr", "schema", "project")
+	if !strings.Contains(got, "repo_url (url)") {
+		t.Errorf("expected the project schema:\n%s", got)
+	}
+}
+
+// resetSliceFlag empties a slice flag; setting it to "" would append an empty
+// value instead.
+func resetSliceFlag(cmd *cobra.Command, name string) {
+	flag := cmd.Flags().Lookup(name)
+	flag.Value.(interface{ Replace([]string) error }).Replace(nil)
+	flag.Changed = false
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+// backupReplica is the path given to a backup command, else backup.replica
+// in the config or CLAUDE_MEMORY_BACKUP_REPLICA.
+func backupReplica(args []string) (string, error) {
+	if len(args) == 1 {
+		return args[0], nil
+	}
+	if path := loadEffectiveConfig(configProjectDir()).BackupReplica; path != "" {
+		return path, nil
+	}
+	return "", &storage.ValidationError{Field: "path", Reason: "give a path, or set backup.replica in config.json or CLAUDE_MEMORY_BACKUP_REPLICA"}
+}
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Keep a copy of the database elsewhere",
+	Long: `Keep a full copy of the database at a second path, such as an external drive
+or a synced folder. The MCP server keeps one up to date while it runs when
+started with --backup-to or CLAUDE_MEMORY_BACKUP_REPLICA, copying whenever the
+database changed (every 15 minutes by default; --backup-interval or
+CLAUDE_MEMORY_BACKUP_INTERVAL) and once more on exit.
+
+Commands default to backup.replica in config.json or CLAUDE_MEMORY_BACKUP_REPLICA.`,
+}
+
+var backupRunCmd = &cobra.Command{
+	Use:   "run [path]",
+	Short: "Copy the database to the backup path now",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := backupReplica(args)
+		if err != nil {
+			return err
+		}
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		if err := store.BackupTo(path); err != nil {
+			return err
+		}
+		output(successStyle.Render("✓") + " Backed up to " + path)
+		return nil
+	},
+}
+
+var backupVerifyCmd = &cobra.Command{
+	Use:   "verify [path]",
+	Short: "Check the backup opens and matches the database",
+	Long: `Open the backup read-only, run SQLite's quick integrity check on it, and
+compare its row counts with the database's. Fails when the backup is damaged
+or the counts differ; writes made since the last copy show as differences.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := backupReplica(args)
+		if err != nil {
+			return err
+		}
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		check, err := store.VerifyBackup(path)
+		if err != nil {
+			return err
+		}
+		if format, _ := cmd.Flags().GetString("format"); format == "json" {
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(check); err != nil {
+				return err
+			}
+		} else {
+			output(titleStyle.Render("Backup " + path))
+			output()
+			output("  " + dimStyle.Render("Integrity:") + " " + check.Integrity)
+			output(dimStyle.Render(fmt.Sprintf("  %-22s %6s  %6s", "", "live", "backup")))
+			for _, c := range check.Counts {
+				line := fmt.Sprintf("  %-22s %6d  %6d", c.Table, c.Live, c.Backup)
+				if c.Backup < 0 {
+					line = fmt.Sprintf("  %-22s %6d  missing", c.Table, c.Live)
+				}
+				if c.Live != c.Backup {
+					line += " " + dimStyle.Render("differs")
+				}
+				output(line)
+			}
+		}
+		if !check.OK() {
+			return fmt.Errorf("backup %s does not match the database", path)
+		}
+		if format, _ := cmd.Flags().GetString("format"); format != "json" {
+			output(successStyle.Render("✓") + " Backup matches")
+		}
+		return nil
+	},
+}
+
+func init() {
+	backupVerifyCmd.Flags().String("format", "default", "output format: default, json")
+
+	backupCmd.AddCommand(backupRunCmd)
+	backupCmd.AddCommand(backupVerifyCmd)
+	rootCmd.AddCommand(backupCmd)
+}
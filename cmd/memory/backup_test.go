@@ -0,0 +1,34 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+func TestBackupRunAndVerify(t *testing.T) {
+	useTestDB(t)
+	t.Setenv("HOME", t.TempDir())
+	replica := filepath.Join(t.TempDir(), "replica.db")
+	t.Setenv("CLAUDE_MEMORY_BACKUP_REPLICA", replica)
+	withStore(t, func(s *storage.Store) {
+		s.CreateEntity("Go", "language", []string{"Fast compiler"})
+	})
+
+	if got := runRootCmd(t, "backup", "run"); !strings.Contains(got, "Backed up to "+replica) {
+		t.Errorf("expected the backup reported:\n%s", got)
+	}
+	if got := runRootCmd(t, "backup", "verify"); !strings.Contains(got, "Integrity: ok") || !strings.Contains(got, "Backup matches") {
+		t.Errorf("expected the backup to match:\n%s", got)
+	}
+
+	withStore(t, func(s *storage.Store) {
+		s.AddObservation("Go", "Garbage collected")
+	})
+	rootCmd.SetArgs([]string{"backup", "verify"})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("expected a stale backup to fail verification, got %v", err)
+	}
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+// benchVocabulary is the word pool for synthetic observations and queries.
+var benchVocabulary = strings.Fields(`
+	build cache config deploy docker error go graph handler index kafka latency
+	memory migration module network parser pipeline postgres query queue redis
+	release retry schema search server session sqlite storage stream test token
+	trace vector worker backup cluster compile debug embed fetch hook lint merge
+	metric plugin proxy refactor render route script shard socket sync timeout
+`)
+
+var benchEntityTypes = []string{"project", "tool", "pattern", "person", "concept"}
+
+// benchResult is the latency summary of one benchmarked operation.
+type benchResult struct {
+	Name string        `json:"name"`
+	Runs int           `json:"runs"`
+	Mean time.Duration `json:"mean_ns"`
+	P50  time.Duration `json:"p50_ns"`
+	P95  time.Duration `json:"p95_ns"`
+}
+
+func summarize(name string, samples []time.Duration) benchResult {
+	slices.Sort(samples)
+	var total time.Duration
+	for _, d := range samples {
+		total += d
+	}
+	return benchResult{
+		Name: name,
+		Runs: len(samples),
+		Mean: total / time.Duration(len(samples)),
+		P50:  samples[len(samples)/2],
+		P95:  samples[(len(samples)*95)/100],
+	}
+}
+
+// benchQuery returns a two-word query drawn from the vocabulary.
+func benchQuery(rng *rand.Rand) string {
+	return benchVocabulary[rng.Intn(len(benchVocabulary))] + " " + benchVocabulary[rng.Intn(len(benchVocabulary))]
+}
+
+func benchVector(rng *rand.Rand, dims int) []float64 {
+	v := make([]float64, dims)
+	for i := range v {
+		v[i] = rng.NormFloat64()
+	}
+	return v
+}
+
+// seedBenchGraph fills the store with a synthetic graph: entities with
+// observations built from the vocabulary, and random embeddings when dims > 0.
+func seedBenchGraph(store *storage.Store, rng *rand.Rand, entities, obsPerEntity, dims int) error {
+	const chunk = 500
+	for start := 0; start < entities; start += chunk {
+		specs := make([]storage.EntitySpec, 0, chunk)
+		for i := start; i < min(start+chunk, entities); i++ {
+			spec := storage.EntitySpec{
+				Name: fmt.Sprintf("entity-%06d", i),
+				Type: benchEntityTypes[i%len(benchEntityTypes)],
+			}
+			for j := 0; j < obsPerEntity; j++ {
+				words := make([]string, 8)
+				for k := range words {
+					words[k] = benchVocabulary[rng.Intn(len(benchVocabulary))]
+				}
+				spec.Observations = append(spec.Observations, fmt.Sprintf("%s (%d)", strings.Join(words, " "), j))
+			}
+			specs = append(specs, spec)
+		}
+		if _, err := store.CreateEntities(specs); err != nil {
+			return err
+		}
+	}
+
+	if dims <= 0 {
+		return nil
+	}
+	observations, err := store.GetObservationsWithoutEmbeddings()
+	if err != nil {
+		return err
+	}
+	embeddings := make([][]float64, len(observations))
+	for i := range embeddings {
+		embeddings[i] = benchVector(rng, dims)
+	}
+	return store.BatchStoreEmbeddings(observations, embeddings, "bench")
+}
+
+// benchOperations times each operation runs times.
+func benchOperations(store *storage.Store, rng *rand.Rand, runs, dims int) ([]benchResult, error) {
+	ctx := context.Background()
+	ops := []struct {
+		name string
+		run  func() error
+	}{
+		{"search", func() error {
+			_, err := store.SearchWithLimit(benchQuery(rng), 20)
+			return err
+		}},
+		{"hybrid search", func() error {
+			var emb []float64
+			if dims > 0 {
+				emb = benchVector(rng, dims)
+			}
+			_, err := store.HybridSearch(ctx, benchQuery(rng), emb, 20)
+			return err
+		}},
+		{"context injection", func() error {
+			_, err := store.GetContextForInjection(storage.DefaultContextConfig(), "")
+			return err
+		}},
+		{"importance recalculation", func() error {
+			_, err := store.RecalculateImportance()
+			return err
+		}},
+	}
+
+	var results []benchResult
+	for _, op := range ops {
+		samples := make([]time.Duration, runs)
+		for i := range samples {
+			start := time.Now()
+			if err := op.run(); err != nil {
+				return nil, fmt.Errorf("%s: %w", op.name, err)
+			}
+			samples[i] = time.Since(start)
+		}
+		results = append(results, summarize(op.name, samples))
+	}
+	return results, nil
+}
+
+func formatDelta(now, was time.Duration) string {
+	if was == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%+.1f%%", (float64(now)-float64(was))/float64(was)*100)
+}
+
+func printBenchTable(results, baseline []benchResult) {
+	header := fmt.Sprintf("  %-26s %5s %12s %12s %12s", "Operation", "Runs", "Mean", "p50", "p95")
+	if baseline != nil {
+		header += fmt.Sprintf(" %12s %8s", "Base p50", "Δ p50")
+	}
+	output(dimStyle.Render(header))
+
+	for _, r := range results {
+		line := fmt.Sprintf("  %-26s %5d %12s %12s %12s", r.Name, r.Runs,
+			r.Mean.Round(time.Microsecond), r.P50.Round(time.Microsecond), r.P95.Round(time.Microsecond))
+		if baseline != nil {
+			i := slices.IndexFunc(baseline, func(b benchResult) bool { return b.Name == r.Name })
+			if i < 0 {
+				line += fmt.Sprintf(" %12s %8s", "-", "-")
+			} else {
+				line += fmt.Sprintf(" %12s %8s", baseline[i].P50.Round(time.Microsecond), formatDelta(r.P50, baseline[i].P50))
+			}
+		}
+		output(line)
+	}
+}
+
+var benchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Benchmark storage operations on a synthetic graph",
+	Long: `Generate a synthetic knowledge graph in a temporary database and measure
+search, hybrid search, context injection, and importance recalculation latency.
+Your own database is not touched.
+
+Save results with --save and pass them to --compare on a later run to see the
+change, e.g. before and after a storage change.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		entities, _ := cmd.Flags().GetInt("entities")
+		obsPerEntity, _ := cmd.Flags().GetInt("obs-per-entity")
+		dims, _ := cmd.Flags().GetInt("dims")
+		runs, _ := cmd.Flags().GetInt("runs")
+		seed, _ := cmd.Flags().GetInt64("seed")
+		savePath, _ := cmd.Flags().GetString("save")
+		comparePath, _ := cmd.Flags().GetString("compare")
+		if entities <= 0 || obsPerEntity < 0 || runs <= 0 {
+			return fmt.Errorf("--entities and --runs must be positive")
+		}
+
+		var baseline []benchResult
+		if comparePath != "" {
+			data, err := os.ReadFile(comparePath)
+			if err != nil {
+				return err
+			}
+			if err := json.Unmarshal(data, &baseline); err != nil {
+				return fmt.Errorf("read baseline %s: %w", comparePath, err)
+			}
+		}
+
+		dir, err := os.MkdirTemp("", "mark42-bench-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+
+		store, err := storage.NewStore(filepath.Join(dir, "bench.db"))
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		if err := store.Migrate(); err != nil {
+			return err
+		}
+
+		rng := rand.New(rand.NewSource(seed))
+		start := time.Now()
+		if err := seedBenchGraph(store, rng, entities, obsPerEntity, dims); err != nil {
+			return fmt.Errorf("generate graph: %w", err)
+		}
+
+		output(titleStyle.Render("Benchmark"))
+		output(dimStyle.Render(fmt.Sprintf("  %d entities, %d observations, %d-dim embeddings, generated in %s",
+			entities, entities*obsPerEntity, dims, time.Since(start).Round(time.Millisecond))))
+		output()
+
+		results, err := benchOperations(store, rng, runs, dims)
+		if err != nil {
+			return err
+		}
+		printBenchTable(results, baseline)
+
+		if savePath != "" {
+			data, err := json.MarshalIndent(results, "", "  ")
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(savePath, data, 0o644); err != nil {
+				return err
+			}
+			output()
+			output(successStyle.Render("✓ Saved results to " + savePath))
+		}
+		return nil
+	},
+}
+
+func init() {
+	benchCmd.Flags().Int("entities", 1000, "number of synthetic entities")
+	benchCmd.Flags().Int("obs-per-entity", 5, "observations per entity")
+	benchCmd.Flags().Int("dims", 384, "embedding dimensions (0 disables vector search)")
+	benchCmd.Flags().Int("runs", 20, "timed runs per operation")
+	benchCmd.Flags().Int64("seed", 42, "random seed, for comparable runs")
+	benchCmd.Flags().String("save", "", "write results as JSON to this file")
+	benchCmd.Flags().String("compare", "", "compare against results saved with --save")
+	rootCmd.AddCommand(benchCmd)
+}
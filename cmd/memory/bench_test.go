@@ -0,0 +1,40 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSummarize(t *testing.T) {
+	var samples []time.Duration
+	for i := 100; i >= 1; i-- {
+		samples = append(samples, time.Duration(i)*time.Millisecond)
+	}
+	r := summarize("op", samples)
+	if r.Runs != 100 || r.P50 != 51*time.Millisecond || r.P95 != 96*time.Millisecond {
+		t.Errorf("unexpected summary: %+v", r)
+	}
+	if r.Mean != 50500*time.Microsecond {
+		t.Errorf("mean = %s, want 50.5ms", r.Mean)
+	}
+}
+
+func TestBench_SaveAndCompare(t *testing.T) {
+	useTestDB(t)
+	saved := filepath.Join(t.TempDir(), "baseline.json")
+	args := []string{"bench", "--entities", "30", "--obs-per-entity", "2", "--dims", "8", "--runs", "2"}
+
+	got := runRootCmd(t, append(args, "--save", saved, "--compare", "")...)
+	for _, op := range []string{"search", "hybrid search", "context injection", "importance recalculation"} {
+		if !strings.Contains(got, op) {
+			t.Errorf("expected %q in output:\n%s", op, got)
+		}
+	}
+
+	got = runRootCmd(t, append(args, "--save", "", "--compare", saved)...)
+	if !strings.Contains(got, "Base p50") || !strings.Contains(got, "%") {
+		t.Errorf("expected comparison columns:\n%s", got)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+var blameCmd = &cobra.Command{
+	Use:   "blame <entity>",
+	Short: "Show where each of an entity's observations came from",
+	Long: `List an entity's observations, oldest first, with when they were written and
+their source: the MCP tool (mcp:<tool>), cli, hook (hook:<name>), import
+(import:<file>), or session (session:<name>) that wrote them. Observations
+written before provenance was tracked show "unknown". On a shared database
+each observation's author, when known, follows it as @<user>.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		if err := store.Migrate(); err != nil {
+			return err
+		}
+
+		entity, err := store.GetEntity(args[0])
+		if err != nil {
+			return err
+		}
+		observations, err := store.GetObservationProvenance(args[0])
+		if err != nil {
+			return err
+		}
+
+		if format, _ := cmd.Flags().GetString("format"); format == "json" {
+			if observations == nil {
+				observations = []storage.ObservationProvenance{}
+			}
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			return enc.Encode(map[string]any{"name": entity.Name, "entityType": entity.Type, "observations": observations})
+		}
+
+		output(entityStyle.Render(entity.Name) + " " + typeStyle.Render("("+entity.Type+")"))
+		for _, o := range observations {
+			source := o.Source
+			if source == "" {
+				source = "unknown"
+			}
+			line := "  " + dimStyle.Render(o.CreatedAt.Format("2006-01-02 15:04")) + " " +
+				typeStyle.Render(fmt.Sprintf("%-28s", source)) + " " + obsStyle.Render(o.Content)
+			if o.Author != "" {
+				line += " " + dimStyle.Render("@"+o.Author)
+			}
+			output(line)
+		}
+		return nil
+	},
+}
+
+func init() {
+	blameCmd.Flags().String("format", "default", "output format: default, json")
+	rootCmd.AddCommand(blameCmd)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+func TestBlameCommand(t *testing.T) {
+	useTestDB(t)
+	withStore(t, func(s *storage.Store) {
+		s.CreateEntity("konfig", "project", []string{"untracked"})
+		s.DB().Exec(`UPDATE observations SET source = NULL`) // As if written before provenance
+	})
+
+	runRootCmd(t, "obs", "add", "konfig", "Loads YAML")
+
+	got := runRootCmd(t, "blame", "konfig")
+	if !strings.Contains(got, "cli") || !strings.Contains(got, "unknown") {
+		t.Errorf("expected sources in blame output:\n%s", got)
+	}
+
+	defer blameCmd.Flags().Set("format", "default")
+	var blame struct {
+		Observations []storage.ObservationProvenance `json:"observations"`
+	}
+	if err := json.Unmarshal([]byte(runRootCmd(t, "blame", "konfig", "--format", "json")), &blame); err != nil {
+		t.Fatal(err)
+	}
+	if len(blame.Observations) != 2 || blame.Observations[1].Source != "cli" {
+		t.Errorf("expected the CLI observation with source cli, got %+v", blame.Observations)
+	}
+}
+
+func TestCommandSource(t *testing.T) {
+	tests := []struct {
+		cmd  *cobra.Command
+		want string
+	}{
+		{obsAddCmd, "cli"},
+		{hookPostToolUseCmd, "hook:post-tool-use"},
+	}
+	for _, tt := range tests {
+		if got := commandSource(tt.cmd); got != tt.want {
+			t.Errorf("commandSource(%s) = %q, want %q", tt.cmd.Name(), got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+// decayOverrides overrides decay and archival settings. Nil fields keep the
+// value from the previous layer.
+type decayOverrides struct {
+	SoftDecayThreshold  *float64 `json:"softDecayThreshold,omitempty"`
+	ArchiveAfterDays    *int     `json:"archiveAfterDays,omitempty"`
+	ForgetAfterDays     *int     `json:"forgetAfterDays,omitempty"`
+	MinImportanceToKeep *float64 `json:"minImportanceToKeep,omitempty"`
+	DigestAfterDays     *int     `json:"digestAfterDays,omitempty"`
+	// Count-based retention; 0 in a project lifts a global limit
+	KeepPerEntity    *int `json:"keepPerEntity,omitempty"`
+	KeepPerContainer *int `json:"keepPerContainer,omitempty"`
+}
+
+// tokenizerOverrides overrides the FTS tokenizer. Nil fields keep the value
+// from the previous layer.
+type tokenizerOverrides struct {
+	Porter           *bool   `json:"porter,omitempty"`
+	RemoveDiacritics *int    `json:"removeDiacritics,omitempty"`
+	Separators       *string `json:"separators,omitempty"`
+	TokenChars       *string `json:"tokenChars,omitempty"`
+}
+
+func (o tokenizerOverrides) apply(t *storage.FTSTokenizer) {
+	setIfSet(&t.Porter, o.Porter)
+	setIfSet(&t.RemoveDiacritics, o.RemoveDiacritics)
+	setIfSet(&t.Separators, o.Separators)
+	setIfSet(&t.TokenChars, o.TokenChars)
+}
+
+func (o decayOverrides) apply(cfg *storage.DecayConfig) {
+	setIfSet(&cfg.SoftDecayThreshold, o.SoftDecayThreshold)
+	setIfPositive(&cfg.ArchiveAfterDays, o.ArchiveAfterDays)
+	setIfPositive(&cfg.ForgetAfterDays, o.ForgetAfterDays)
+	setIfSet(&cfg.MinImportanceToKeep, o.MinImportanceToKeep)
+	setIfPositive(&cfg.DigestAfterDays, o.DigestAfterDays)
+	setIfSet(&cfg.KeepPerEntity, o.KeepPerEntity)
+	setIfSet(&cfg.KeepPerContainer, o.KeepPerContainer)
+}
+
+// daemonOverrides turns the daemon's subsystems on or off and sets how often
+// each runs. Unset fields keep the value from the previous layer.
+type daemonOverrides struct {
+	Maintenance daemonJobOverrides     `json:"maintenance"`
+	Embeddings  daemonJobOverrides     `json:"embeddings"`
+	Backup      daemonJobOverrides     `json:"backup"`
+	Webhook     daemonWebhookOverrides `json:"webhook"`
+}
+
+type daemonJobOverrides struct {
+	Enabled *bool  `json:"enabled,omitempty"`
+	Every   string `json:"every,omitempty"` // A Go duration, e.g. "6h"; invalid ones are ignored
+}
+
+type daemonWebhookOverrides struct {
+	daemonJobOverrides
+	URL    string `json:"url,omitempty"`
+	Secret string `json:"secret,omitempty"`
+}
+
+func (o daemonOverrides) apply(cfg *daemonConfig) {
+	o.Maintenance.apply(&cfg.Maintenance)
+	o.Embeddings.apply(&cfg.Embeddings)
+	o.Backup.apply(&cfg.Backup)
+	o.Webhook.apply(&cfg.Webhook.daemonJob)
+	if o.Webhook.URL != "" {
+		cfg.Webhook.URL = o.Webhook.URL
+	}
+	if o.Webhook.Secret != "" {
+		cfg.Webhook.Secret = o.Webhook.Secret
+	}
+}
+
+func (o daemonJobOverrides) apply(job *daemonJob) {
+	setIfSet(&job.Enabled, o.Enabled)
+	if every, err := time.ParseDuration(o.Every); err == nil && every > 0 {
+		job.Every = every
+	}
+}
+
+func setIfSet[T any](dst *T, v *T) {
+	if v != nil {
+		*dst = *v
+	}
+}
+
+func setIfPositive[T int | float64](dst *T, v *T) {
+	if v != nil && *v > 0 {
+		*dst = *v
+	}
+}
+
+// effectiveConfig is the importance and decay configuration after layering
+// defaults, the global config, and the project config.
+type effectiveConfig struct {
+	Importance       storage.ImportanceConfig
+	Decay            storage.DecayConfig
+	AttributeSchemas map[string]storage.AttributeSchema
+	User             string   // Owner and author of writes; CLAUDE_MEMORY_USER overrides
+	Sources          []string // Config files that were found, in the order applied
+	// Shortest entity name linked when observations mention it; 0 when
+	// auto-linking is off. CLAUDE_MEMORY_AUTO_LINK_MENTIONS overrides enabled
+	AutoLinkMinName int
+	Rules           storage.Rules // See getStore for CLAUDE_MEMORY_RULES
+	// Automation policies of every layer, in order; see getStore for
+	// CLAUDE_MEMORY_POLICIES
+	Policies []storage.Policy
+	// Registered entity types; CLAUDE_MEMORY_ENTITY_TYPES adds to them
+	EntityTypes map[string]string
+	// Registered relation types; CLAUDE_MEMORY_RELATION_TYPES adds to them
+	RelationTypes map[string]storage.RelationTypeInfo
+	// Tokenizer the FTS indexes are built with by init and search reindex
+	FTSTokenizer storage.FTSTokenizer
+	StopWords    []string // Dropped from search queries
+	// Secret redaction on write; CLAUDE_MEMORY_REDACT_SECRETS turns the
+	// presets on or off
+	Redaction storage.Redaction
+	PIIMode   storage.PIIMode // CLAUDE_MEMORY_PII_MODE overrides
+	// How long destructive operations can be undone
+	UndoWindow time.Duration
+	BackupDir  string // Where snapshots go; CLAUDE_MEMORY_BACKUP_DIR overrides
+	// Where backup run copies the database; CLAUDE_MEMORY_BACKUP_REPLICA overrides
+	BackupReplica string
+	// Context injection overrides of every layer; see ContextConfig
+	contextLayers []storage.ContextOverrides
+	// Embedding endpoint, or "disabled"; CLAUDE_MEMORY_EMBEDDER_URL overrides
+	EmbedderURL   string
+	EmbedderModel string
+	Daemon        daemonConfig
+}
+
+// ContextConfig layers the config files' context settings over base, the
+// defaults of the caller: hooks inject less than the context command.
+func (c effectiveConfig) ContextConfig(base storage.ContextConfig) storage.ContextConfig {
+	for _, layer := range c.contextLayers {
+		layer.Apply(&base)
+	}
+	return base
+}
+
+// globalConfigDir holds the user-wide config.json, alongside the default database.
+func globalConfigDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return mark42Dir(home)
+}
+
+// configProjectDir is the project whose config applies to CLI commands:
+// CLAUDE_PROJECT_DIR inside hooks, the working directory otherwise.
+func configProjectDir() string {
+	if dir := getProjectDir(); dir != "" {
+		return dir
+	}
+	dir, _ := os.Getwd()
+	return dir
+}
+
+// projectConfigDirs holds a project's config.json files in the order they
+// apply: the plugin's .claude/mark42, then .mark42, which wins.
+func projectConfigDirs(projectDir string) []string {
+	return []string{mark42Dir(projectDir), filepath.Join(projectDir, ".mark42")}
+}
+
+// loadEffectiveConfig layers ~/.claude/mark42/config.json and then the
+// project's config files (see projectConfigDirs) over the built-in defaults.
+func loadEffectiveConfig(projectDir string) effectiveConfig {
+	cfg := effectiveConfig{
+		Importance:       storage.DefaultImportanceConfig(),
+		Decay:            storage.DefaultDecayConfig(),
+		AttributeSchemas: storage.DefaultAttributeSchemas(),
+		FTSTokenizer:     storage.DefaultFTSTokenizer(),
+		StopWords:        storage.DefaultStopWords,
+		UndoWindow:       storage.DefaultUndoWindow,
+		EmbedderURL:      storage.DefaultOllamaBaseURL(),
+		EmbedderModel:    storage.DefaultEmbeddingModel,
+		Daemon:           defaultDaemonConfig(),
+	}
+
+	autoLink, autoLinkMinName := false, storage.DefaultAutoLinkMinNameLength
+
+	dirs := []string{globalConfigDir()}
+	if projectDir != "" && mark42Dir(projectDir) != dirs[0] {
+		dirs = append(dirs, projectConfigDirs(projectDir)...)
+	}
+
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		path := filepath.Join(dir, "config.json")
+		layer, err := readPluginConfig(path)
+		if err != nil {
+			continue
+		}
+		layer.Importance.Apply(&cfg.Importance)
+		layer.Decay.apply(&cfg.Decay)
+		layer.Search.Tokenizer.apply(&cfg.FTSTokenizer)
+		setIfSet(&cfg.StopWords, layer.Search.StopWords)
+		if layer.Redaction.Presets != nil {
+			cfg.Redaction.Presets = layer.Redaction.Presets
+		}
+		cfg.Redaction.Patterns = append(cfg.Redaction.Patterns, layer.Redaction.Patterns...)
+		if layer.PII.Mode != "" {
+			cfg.PIIMode = layer.PII.Mode
+		}
+		if layer.Undo.WindowHours != nil {
+			cfg.UndoWindow = time.Duration(*layer.Undo.WindowHours) * time.Hour
+		}
+		if layer.Backup.Dir != "" {
+			cfg.BackupDir = layer.Backup.Dir
+		}
+		if layer.Backup.Replica != "" {
+			cfg.BackupReplica = layer.Backup.Replica
+		}
+		for entityType, schema := range layer.AttributeSchemas {
+			if len(schema) == 0 {
+				delete(cfg.AttributeSchemas, entityType)
+			} else {
+				cfg.AttributeSchemas[entityType] = schema
+			}
+		}
+		if layer.User != "" {
+			cfg.User = layer.User
+		}
+		cfg.contextLayers = append(cfg.contextLayers, layer.Context)
+		if layer.Embedder.URL != "" {
+			cfg.EmbedderURL = layer.Embedder.URL
+		}
+		if layer.Embedder.Model != "" {
+			cfg.EmbedderModel = layer.Embedder.Model
+		}
+		layer.Daemon.apply(&cfg.Daemon)
+		setIfSet(&autoLink, layer.AutoLink.Enabled)
+		setIfPositive(&autoLinkMinName, layer.AutoLink.MinNameLength)
+		if layer.Rules != nil {
+			cfg.Rules = *layer.Rules
+		}
+		cfg.Policies = append(cfg.Policies, layer.Policies...)
+		if len(layer.EntityTypes) > 0 && cfg.EntityTypes == nil {
+			cfg.EntityTypes = map[string]string{}
+		}
+		maps.Copy(cfg.EntityTypes, layer.EntityTypes)
+		if len(layer.RelationTypes) > 0 && cfg.RelationTypes == nil {
+			cfg.RelationTypes = map[string]storage.RelationTypeInfo{}
+		}
+		maps.Copy(cfg.RelationTypes, layer.RelationTypes)
+		cfg.Sources = append(cfg.Sources, path)
+	}
+	if user := os.Getenv("CLAUDE_MEMORY_USER"); user != "" {
+		cfg.User = user
+	}
+	if on, err := strconv.ParseBool(os.Getenv("CLAUDE_MEMORY_AUTO_LINK_MENTIONS")); err == nil {
+		autoLink = on
+	}
+	if on, err := strconv.ParseBool(os.Getenv("CLAUDE_MEMORY_REDACT_SECRETS")); err == nil {
+		cfg.Redaction.Presets = &on
+	}
+	if mode := os.Getenv("CLAUDE_MEMORY_PII_MODE"); mode != "" {
+		cfg.PIIMode = storage.PIIMode(mode)
+	}
+	if dir := os.Getenv("CLAUDE_MEMORY_BACKUP_DIR"); dir != "" {
+		cfg.BackupDir = dir
+	}
+	if path := os.Getenv("CLAUDE_MEMORY_BACKUP_REPLICA"); path != "" {
+		cfg.BackupReplica = path
+	}
+	if url := os.Getenv("CLAUDE_MEMORY_EMBEDDER_URL"); url != "" {
+		cfg.EmbedderURL = url
+	}
+	if autoLink {
+		cfg.AutoLinkMinName = autoLinkMinName
+	}
+	for t := range storage.ParseEntityTypes(os.Getenv("CLAUDE_MEMORY_ENTITY_TYPES")) {
+		if cfg.EntityTypes == nil {
+			cfg.EntityTypes = map[string]string{}
+		}
+		if _, ok := cfg.EntityTypes[t]; !ok {
+			cfg.EntityTypes[t] = ""
+		}
+	}
+	for t, info := range storage.ParseRelationTypes(os.Getenv("CLAUDE_MEMORY_RELATION_TYPES")) {
+		if cfg.RelationTypes == nil {
+			cfg.RelationTypes = map[string]storage.RelationTypeInfo{}
+		}
+		if _, ok := cfg.RelationTypes[t]; !ok {
+			cfg.RelationTypes[t] = info
+		}
+	}
+
+	return cfg
+}
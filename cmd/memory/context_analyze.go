@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+var contextAnalyzeCmd = &cobra.Command{
+	Use:   "analyze",
+	Short: "Summarize which memories context injection uses",
+	Long: `Summarize the context injections logged by the session start hook and the
+MCP get_context tool: how much of the token budget they use, which memories
+they inject most, and which they never surface, most important first.
+Tune the importance threshold and boosts with it.
+
+Logging is opt-in, per database:
+
+  mark42 config set context.log true
+  mark42 config set context.logDays 30   # How long calls are kept`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		days, _ := cmd.Flags().GetInt("days")
+		limit, _ := cmd.Flags().GetInt("limit")
+		analysis, err := store.AnalyzeContextLog(time.Now().AddDate(0, 0, -days), limit)
+		if err != nil {
+			return err
+		}
+
+		if format, _ := cmd.Flags().GetString("format"); format == "json" {
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			return enc.Encode(analysis)
+		}
+
+		if analysis.Calls == 0 {
+			output(fmt.Sprintf("No context injections logged in the last %d days", days))
+			if on, _ := store.GetSetting(storage.SettingContextLog); on != "true" {
+				output(dimStyle.Render("  Logging is off: mark42 config set context.log true"))
+			}
+			return nil
+		}
+
+		var sources []string
+		for _, source := range slices.Sorted(maps.Keys(analysis.BySource)) {
+			sources = append(sources, fmt.Sprintf("%s %d", source, analysis.BySource[source]))
+		}
+		output(titleStyle.Render("Context Injection") + " " + dimStyle.Render(fmt.Sprintf("(last %d days)", days)))
+		output()
+		output("  " + dimStyle.Render("Calls:") + "        " + itoa(analysis.Calls) + " " + dimStyle.Render("("+strings.Join(sources, ", ")+")"))
+		output("  " + dimStyle.Render("Avg memories:") + " " + fmt.Sprintf("%.1f", analysis.AvgResults))
+		output("  " + dimStyle.Render("Avg tokens:") + "   " + fmt.Sprintf("%.0f", analysis.AvgTokensUsed) +
+			" " + dimStyle.Render(fmt.Sprintf("(%.0f%% of budget)", analysis.BudgetUse*100)))
+
+		output()
+		output(titleStyle.Render("Injected Most"))
+		for _, m := range analysis.Top {
+			content := m.Content
+			if content == "" {
+				content = dimStyle.Render("(deleted)")
+			}
+			output(fmt.Sprintf("  %3d× ", m.Injected) + entityStyle.Render(m.EntityName) + ": " + content + " " +
+				dimStyle.Render(fmt.Sprintf("(importance %.2f, score %.2f, position %.1f)", m.Importance, m.AvgScore, m.AvgPosition)))
+		}
+
+		output()
+		output(titleStyle.Render("Never Injected") + " " + dimStyle.Render(fmt.Sprintf("(%d observations, %d below the importance threshold)",
+			analysis.NeverInjected, analysis.BelowMinImportance)))
+		for _, m := range analysis.Unsurfaced {
+			output("  " + entityStyle.Render(m.EntityName) + ": " + m.Content + " " + dimStyle.Render(fmt.Sprintf("(importance %.2f)", m.Importance)))
+		}
+		return nil
+	},
+}
+
+func init() {
+	contextAnalyzeCmd.Flags().Int("days", 30, "analyze calls logged in this many past days")
+	contextAnalyzeCmd.Flags().Int("limit", 10, "memories listed per section")
+	contextAnalyzeCmd.Flags().String("format", "default", "output format: default, json")
+	contextCmd.AddCommand(contextAnalyzeCmd)
+}
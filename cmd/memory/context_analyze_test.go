@@ -0,0 +1,34 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+func TestContextAnalyzeCommand(t *testing.T) {
+	useTestDB(t)
+
+	withStore(t, func(s *storage.Store) {
+		s.CreateEntity("Go", "language", []string{"Fast compiler"})
+	})
+	if got := runRootCmd(t, "context", "analyze"); !strings.Contains(got, "Logging is off") {
+		t.Errorf("expected the opt-in hint:\n%s", got)
+	}
+
+	runRootCmd(t, "config", "set", "context.log", "true")
+	withStore(t, func(s *storage.Store) {
+		cfg := storage.DefaultContextConfig()
+		results, err := s.GetContextForInjection(cfg, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.LogContextInjection(storage.ContextCall{Source: storage.SourceHook, TokenBudget: cfg.TokenBudget, TokensUsed: 100}, results)
+	})
+
+	got := runRootCmd(t, "context", "analyze")
+	if !strings.Contains(got, "hook 1") || !strings.Contains(got, "1× Go: Fast compiler") {
+		t.Errorf("expected the logged call summarized:\n%s", got)
+	}
+}
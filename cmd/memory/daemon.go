@@ -0,0 +1,744 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+// daemonConfig is which of the daemon's subsystems run, and how often.
+type daemonConfig struct {
+	Maintenance daemonJob
+	Embeddings  daemonJob
+	Backup      daemonJob
+	Webhook     daemonWebhook
+}
+
+// daemonJob is the setting of one of the daemon's subsystems.
+type daemonJob struct {
+	Enabled bool
+	Every   time.Duration
+}
+
+// daemonWebhook is the webhook subsystem's setting: where it posts the
+// database's changes, and the secret it signs them with.
+type daemonWebhook struct {
+	daemonJob
+	URL    string
+	Secret string // Empty: posts are not signed
+}
+
+func defaultDaemonConfig() daemonConfig {
+	return daemonConfig{
+		Maintenance: daemonJob{Enabled: true, Every: 6 * time.Hour},
+		Embeddings:  daemonJob{Enabled: true, Every: 10 * time.Minute},
+		Backup:      daemonJob{Enabled: true, Every: storage.DefaultBackupInterval},
+		Webhook:     daemonWebhook{daemonJob: daemonJob{Enabled: true, Every: time.Minute}},
+	}
+}
+
+const (
+	// daemonLeaseHolder names the daemon in the database's write lease.
+	daemonLeaseHolder = "mark42 daemon"
+	// daemonHeartbeat is how often the daemon records that it is alive;
+	// status reports it stopped once three go by unrecorded.
+	daemonHeartbeat = time.Minute
+	// daemonRetry is how soon a subsystem that found the write lease held
+	// tries again.
+	daemonRetry = 5 * time.Minute
+)
+
+// daemonStatus is what the daemon records for daemon status.
+type daemonStatus struct {
+	PID        int                `json:"pid"`
+	Started    time.Time          `json:"started"`
+	Heartbeat  time.Time          `json:"heartbeat"`
+	Stopped    *time.Time         `json:"stopped,omitempty"`
+	Subsystems []*daemonSubsystem `json:"subsystems"`
+}
+
+// running reports whether the daemon that recorded s is still running.
+func (s *daemonStatus) running() bool {
+	return s.Stopped == nil && time.Since(s.Heartbeat) < 3*daemonHeartbeat
+}
+
+// daemonSubsystem is a subsystem of the daemon and what it last did.
+type daemonSubsystem struct {
+	Name    string     `json:"name"`
+	Enabled bool       `json:"enabled"`
+	Off     string     `json:"off,omitempty"` // Why it is not enabled
+	Every   string     `json:"every,omitempty"`
+	Runs    int        `json:"runs"`
+	LastRun *time.Time `json:"lastRun,omitempty"`
+	Result  string     `json:"result,omitempty"` // What the last run did
+	Error   string     `json:"error,omitempty"`  // Why the last run failed
+	NextRun *time.Time `json:"nextRun,omitempty"`
+
+	every time.Duration
+	lease bool // Takes the write lease to run
+	run   func(ctx context.Context) (string, error)
+}
+
+// daemonStatusPath is where the daemon records its status: mark42/daemon.json
+// next to the database, or in the global config directory for a hosted one.
+func daemonStatusPath() string {
+	if storage.IsRemoteDSN(dbPath) {
+		return filepath.Join(globalConfigDir(), "daemon.json")
+	}
+	return filepath.Join(filepath.Dir(dbPath), "mark42", "daemon.json")
+}
+
+// readDaemonStatus reads the status the daemon last recorded, or nil when it
+// never ran.
+func readDaemonStatus() (*daemonStatus, error) {
+	data, err := os.ReadFile(daemonStatusPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var status daemonStatus
+	if err := json.Unmarshal(data, &status); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", daemonStatusPath(), err)
+	}
+	return &status, nil
+}
+
+// daemon runs the enabled subsystems on their schedules.
+type daemon struct {
+	store  *storage.Store
+	mu     sync.Mutex // Guards status
+	status daemonStatus
+}
+
+// newDaemon sets up the subsystems cfg enables against store.
+func newDaemon(store *storage.Store, cfg effectiveConfig) *daemon {
+	d := &daemon{store: store}
+	d.status = daemonStatus{PID: os.Getpid(), Started: time.Now()}
+
+	add := func(name string, job daemonJob, off string, lease bool, run func(ctx context.Context) (string, error)) {
+		sub := &daemonSubsystem{Name: name, every: job.Every, lease: lease, run: run}
+		switch {
+		case !job.Enabled:
+			sub.Off = "turned off in config.json"
+		case off != "":
+			sub.Off = off
+		default:
+			sub.Enabled = true
+			sub.Every = shortDuration(job.Every)
+		}
+		d.status.Subsystems = append(d.status.Subsystems, sub)
+	}
+
+	add("maintenance", cfg.Daemon.Maintenance, "", true, d.maintain)
+
+	embedOff := ""
+	if cfg.EmbedderURL == "disabled" {
+		embedOff = "the embedder is disabled"
+	}
+	add("embeddings", cfg.Daemon.Embeddings, embedOff, true, func(ctx context.Context) (string, error) {
+		return d.embed(ctx, cfg.EmbedderURL, cfg.EmbedderModel)
+	})
+
+	backupOff := ""
+	switch {
+	case store.Remote():
+		backupOff = "hosted databases are backed up by their server"
+	case cfg.BackupReplica == "":
+		backupOff = "no backup.replica set"
+	}
+	state := ""
+	add("backup", cfg.Daemon.Backup, backupOff, false, func(ctx context.Context) (string, error) {
+		now, copied, err := store.BackupIfChangedContext(ctx, cfg.BackupReplica, state)
+		if err != nil {
+			return "", err
+		}
+		state = now
+		if !copied {
+			return "unchanged since the last copy", nil
+		}
+		return "copied to " + cfg.BackupReplica, nil
+	})
+
+	webhookOff := ""
+	if cfg.Daemon.Webhook.URL == "" {
+		webhookOff = "no daemon.webhook.url set"
+	}
+	add("webhook", cfg.Daemon.Webhook.daemonJob, webhookOff, false, func(ctx context.Context) (string, error) {
+		return d.dispatch(ctx, cfg.Daemon.Webhook)
+	})
+	return d
+}
+
+// shortDuration formats d without zero minutes and seconds: 6h, not 6h0m0s.
+func shortDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = s[:len(s)-2]
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = s[:len(s)-2]
+	}
+	return s
+}
+
+// maintain recalculates importance, applies the policies, which may set
+// importance over the recalculated scores, and forgets expired memories.
+func (d *daemon) maintain(ctx context.Context) (string, error) {
+	scored, err := d.store.RecalculateImportanceContext(ctx)
+	if err != nil {
+		return "", fmt.Errorf("recalculating importance: %w", err)
+	}
+	changes, err := d.store.ApplyPoliciesContext(ctx, false)
+	if err != nil {
+		return "", fmt.Errorf("applying policies: %w", err)
+	}
+	forgotten, err := d.store.ForgetExpiredMemoriesContext(ctx)
+	if err != nil {
+		return "", fmt.Errorf("forgetting expired memories: %w", err)
+	}
+	return fmt.Sprintf("%d importance scores, %d policy changes, %d expired forgotten", scored, len(changes), forgotten), nil
+}
+
+// embed generates the embeddings observations are missing.
+func (d *daemon) embed(ctx context.Context, url, model string) (string, error) {
+	observations, err := d.store.GetObservationsWithoutEmbeddingsContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	if len(observations) == 0 {
+		return "every observation has an embedding", nil
+	}
+	client := storage.NewEmbeddingClient(url)
+	client.SetModel(model)
+	client.SetBatchLimits(embedBatch, embedTokens)
+	if _, err := d.store.PrepareEmbedder(ctx, client); err != nil {
+		return "", fmt.Errorf("probing %s: %w", model, err)
+	}
+	embedded, err := embedObservations(ctx, d.store, client, model, observations, nil)
+	return fmt.Sprintf("embedded %d of %d observations", embedded, len(observations)), err
+}
+
+// webhookPayload is what the webhook subsystem posts: the records changed
+// on this database since the last delivery, as replica records.
+type webhookPayload struct {
+	Node    string                  `json:"node"`  // This database's replica identity
+	Clock   int64                   `json:"clock"` // The latest clock among the records
+	Records []storage.ReplicaRecord `json:"records"`
+}
+
+// webhookCursor is the latest clock delivered to a webhook URL.
+type webhookCursor struct {
+	URL   string `json:"url"`
+	Clock int64  `json:"clock"`
+}
+
+// webhookCursorPath is where the webhook subsystem records what it
+// delivered, next to the daemon's status.
+func webhookCursorPath() string {
+	return filepath.Join(filepath.Dir(daemonStatusPath()), "webhook.json")
+}
+
+var webhookClient = &http.Client{Timeout: 30 * time.Second}
+
+// dispatch posts the changes made on this database since the last delivery
+// to the webhook. Changes merged from other databases are theirs to deliver.
+// Local writes always get a clock later than any seen before, so the
+// delivered clock is a cursor; it only advances when the endpoint answers
+// 2xx, so a failed delivery is retried in full. A new URL starts over with
+// the whole graph. With a secret, X-Mark42-Signature carries the body's
+// HMAC-SHA256.
+func (d *daemon) dispatch(ctx context.Context, hook daemonWebhook) (string, error) {
+	var cursor webhookCursor
+	if data, err := os.ReadFile(webhookCursorPath()); err == nil {
+		_ = json.Unmarshal(data, &cursor)
+	}
+	if cursor.URL != hook.URL {
+		cursor = webhookCursor{URL: hook.URL}
+	}
+
+	node, err := d.store.NodeIDContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	records, err := d.store.ExportReplicaContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	payload := webhookPayload{Node: node, Clock: cursor.Clock, Records: []storage.ReplicaRecord{}}
+	for _, r := range records {
+		if r.Origin == node && r.Clock > cursor.Clock {
+			payload.Records = append(payload.Records, r)
+			payload.Clock = max(payload.Clock, r.Clock)
+		}
+	}
+	if len(payload.Records) == 0 {
+		return "no changes to deliver", nil
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if hook.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(hook.Secret))
+		mac.Write(body)
+		req.Header.Set("X-Mark42-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return "", fmt.Errorf("%s answered %s", hook.URL, resp.Status)
+	}
+
+	cursor.Clock = payload.Clock
+	data, err := json.Marshal(cursor)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(webhookCursorPath()), 0o755)
+	}
+	if err == nil {
+		err = os.WriteFile(webhookCursorPath(), data, 0o644)
+	}
+	if err != nil {
+		return "", fmt.Errorf("recording the delivery: %w", err)
+	}
+	return fmt.Sprintf("delivered %d changes to %s", len(payload.Records), hook.URL), nil
+}
+
+// runSubsystem runs sub once and records the outcome. While another process
+// holds the write lease, a subsystem needing it is skipped and tried again
+// after daemonRetry.
+func (d *daemon) runSubsystem(ctx context.Context, sub *daemonSubsystem) error {
+	start := time.Now()
+	next := start.Add(sub.every)
+	result, err := func() (string, error) {
+		if sub.lease {
+			if _, err := d.store.AcquireWriteLeaseContext(ctx, daemonLeaseHolder, commandLeaseTTL); err != nil {
+				return "", err
+			}
+			defer d.store.KeepWriteLease(ctx, daemonLeaseHolder, commandLeaseTTL)()
+		}
+		return sub.run(ctx)
+	}()
+	var held *storage.LeaseHeldError
+	if errors.As(err, &held) {
+		result, err = fmt.Sprintf("skipped: %s (pid %d) holds the write lease", held.Lease.Holder, held.Lease.PID), nil
+		next = start.Add(min(sub.every, daemonRetry))
+	}
+
+	d.mu.Lock()
+	sub.Runs++
+	sub.LastRun, sub.NextRun = &start, &next
+	sub.Result, sub.Error = result, ""
+	if err != nil {
+		sub.Error = err.Error()
+	}
+	d.mu.Unlock()
+	d.save()
+
+	if err != nil {
+		logger.Error(sub.Name+" failed", "error", err)
+	} else {
+		logger.Info(sub.Name, "result", result)
+	}
+	return err
+}
+
+// save records the status, replacing the previous record only once the new
+// one is written.
+func (d *daemon) save() {
+	d.mu.Lock()
+	d.status.Heartbeat = time.Now()
+	data, err := json.MarshalIndent(d.status, "", "  ")
+	d.mu.Unlock()
+	if err != nil {
+		logger.Error("recording daemon status", "error", err)
+		return
+	}
+	path := daemonStatusPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		logger.Error("recording daemon status", "error", err)
+		return
+	}
+	if err := os.WriteFile(path+".tmp", data, 0o644); err == nil {
+		err = os.Rename(path+".tmp", path)
+	}
+	if err != nil {
+		logger.Error("recording daemon status", "error", err)
+	}
+}
+
+// enabled returns the subsystems that run.
+func (d *daemon) enabled() []*daemonSubsystem {
+	var subs []*daemonSubsystem
+	for _, sub := range d.status.Subsystems {
+		if sub.Enabled {
+			subs = append(subs, sub)
+		}
+	}
+	return subs
+}
+
+// once runs each enabled subsystem once, returning the first failure.
+func (d *daemon) once(ctx context.Context) error {
+	var first error
+	for _, sub := range d.enabled() {
+		if err := d.runSubsystem(ctx, sub); err != nil && first == nil {
+			first = fmt.Errorf("%s: %w", sub.Name, err)
+		}
+	}
+	d.stop()
+	return first
+}
+
+// loop runs each enabled subsystem now and then whenever it is due, until
+// ctx is done. The backup subsystem makes a last copy on the way out.
+func (d *daemon) loop(ctx context.Context) {
+	heartbeatDone := make(chan struct{})
+	go func() {
+		defer close(heartbeatDone)
+		ticker := time.NewTicker(daemonHeartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				d.save()
+			}
+		}
+	}()
+
+	subs := d.enabled()
+	for _, sub := range subs {
+		if ctx.Err() != nil {
+			break
+		}
+		_ = d.runSubsystem(ctx, sub)
+	}
+	for ctx.Err() == nil {
+		due := subs[0]
+		for _, sub := range subs[1:] {
+			if sub.NextRun.Before(*due.NextRun) {
+				due = sub
+			}
+		}
+		timer := time.NewTimer(time.Until(*due.NextRun))
+		select {
+		case <-ctx.Done():
+		case <-timer.C:
+			_ = d.runSubsystem(ctx, due)
+		}
+		timer.Stop()
+	}
+	<-heartbeatDone
+
+	for _, sub := range subs {
+		if sub.Name == "backup" {
+			_ = d.runSubsystem(context.WithoutCancel(ctx), sub)
+		}
+	}
+	d.stop()
+}
+
+// stop records that the daemon stopped.
+func (d *daemon) stop() {
+	d.mu.Lock()
+	now := time.Now()
+	d.status.Stopped = &now
+	for _, sub := range d.status.Subsystems {
+		sub.NextRun = nil
+	}
+	d.mu.Unlock()
+	d.save()
+}
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Run background maintenance, embedding, backups, and webhooks",
+	Long: `The daemon runs mark42's background work on a schedule, each part a
+subsystem turned on or off under "daemon" in config.json:
+
+  maintenance  recalculate importance, apply the automation policies, and
+               forget memories past their forgetAfter (every 6h)
+  embeddings   embed observations that have no embedding yet (every 10m;
+               off when the embedder is "disabled")
+  backup       copy the database to backup.replica when it changed (every
+               15m; off when no replica is set)
+  webhook      post the changes made on this database to webhook.url, as
+               replica records (every 1m; off when no URL is set)
+
+  "daemon": {
+    "maintenance": {"every": "12h"},
+    "embeddings": {"enabled": false},
+    "webhook": {"url": "https://example.com/mark42", "secret": "s3cret"}
+  }
+
+Maintenance and embedding take the write lease, so while an MCP server or a
+maintenance command holds it they are skipped and tried again in 5 minutes.
+daemon unit writes a systemd or launchd unit that keeps it running.`,
+}
+
+var daemonRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Run the daemon in the foreground until interrupted",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if readOnly {
+			return &storage.ValidationError{Field: "read-only", Reason: "the daemon writes to the database"}
+		}
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		if err := store.Migrate(); err != nil {
+			return err
+		}
+
+		ctx := cmd.Context()
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		d := newDaemon(store, loadEffectiveConfig(configProjectDir()))
+		if len(d.enabled()) == 0 {
+			return &storage.ValidationError{Field: "daemon", Reason: "every subsystem is off; see mark42 daemon --help"}
+		}
+
+		if once, _ := cmd.Flags().GetBool("once"); once {
+			err := d.once(ctx)
+			for _, sub := range d.status.Subsystems {
+				switch {
+				case !sub.Enabled:
+					output("  " + entityStyle.Render(sub.Name) + " " + dimStyle.Render("off: "+sub.Off))
+				case sub.Error != "":
+					output("  " + entityStyle.Render(sub.Name) + " failed: " + sub.Error)
+				default:
+					output("  " + entityStyle.Render(sub.Name) + " " + sub.Result)
+				}
+			}
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		logger.Info("daemon started", "pid", os.Getpid(), "db", dbPath, "status", daemonStatusPath())
+		d.loop(ctx)
+		logger.Info("daemon stopped")
+		return nil
+	},
+}
+
+var daemonStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether the daemon runs and what each subsystem last did",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		status, err := readDaemonStatus()
+		if err != nil {
+			return err
+		}
+		if format, _ := cmd.Flags().GetString("format"); format == "json" {
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			if status == nil {
+				return enc.Encode(map[string]any{"running": false})
+			}
+			return enc.Encode(struct {
+				Running bool `json:"running"`
+				*daemonStatus
+			}{status.running(), status})
+		}
+		if status == nil {
+			output("The daemon has not run; start it with mark42 daemon run")
+			return nil
+		}
+
+		switch {
+		case status.running():
+			output(titleStyle.Render("Daemon running") + " " +
+				dimStyle.Render(fmt.Sprintf("(pid %d, since %s)", status.PID, status.Started.Local().Format(time.DateTime))))
+		case status.Stopped != nil:
+			output(titleStyle.Render("Daemon stopped") + " " +
+				dimStyle.Render("at "+status.Stopped.Local().Format(time.DateTime)))
+		default:
+			output(titleStyle.Render("Daemon not responding") + " " +
+				dimStyle.Render(fmt.Sprintf("(pid %d, last heard from %s)", status.PID, status.Heartbeat.Local().Format(time.DateTime))))
+		}
+		output()
+		for _, sub := range status.Subsystems {
+			if !sub.Enabled {
+				output(fmt.Sprintf("  %-12s %s", sub.Name, dimStyle.Render("off: "+sub.Off)))
+				continue
+			}
+			line := fmt.Sprintf("  %-12s every %s", sub.Name, sub.Every)
+			if sub.LastRun != nil {
+				line += dimStyle.Render(", last " + sub.LastRun.Local().Format(time.DateTime))
+			}
+			if sub.NextRun != nil {
+				line += dimStyle.Render(", next " + sub.NextRun.Local().Format(time.DateTime))
+			}
+			output(line)
+			switch {
+			case sub.Error != "":
+				output("               failed: " + sub.Error)
+			case sub.Result != "":
+				output("               " + sub.Result)
+			}
+		}
+		return nil
+	},
+}
+
+// daemonUnitLabel names the daemon's systemd unit and launchd job.
+const daemonUnitLabel = "mark42-daemon"
+
+var daemonUnitCmd = &cobra.Command{
+	Use:   "unit [systemd|launchd]",
+	Short: "Print a service unit that keeps the daemon running",
+	Long: `Print a systemd user unit, or a launchd agent on macOS, that runs mark42
+daemon run against this database and restarts it if it exits. The default is
+the kind this system uses. With --install it is written where the service
+manager looks for it instead, with the command that starts it.`,
+	Args:      cobra.MaximumNArgs(1),
+	ValidArgs: []string{"systemd", "launchd"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		kind := "systemd"
+		if runtime.GOOS == "darwin" {
+			kind = "launchd"
+		}
+		if len(args) == 1 {
+			kind = args[0]
+		}
+		exe, err := os.Executable()
+		if err != nil {
+			return err
+		}
+		command := []string{exe, "--db", dbPath, "daemon", "run"}
+
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return err
+		}
+		var unit, path, start string
+		switch kind {
+		case "systemd":
+			unit = systemdUnit(command)
+			path = filepath.Join(home, ".config", "systemd", "user", daemonUnitLabel+".service")
+			start = "systemctl --user daemon-reload && systemctl --user enable --now " + daemonUnitLabel
+		case "launchd":
+			unit = launchdAgent(command, filepath.Join(filepath.Dir(daemonStatusPath()), "daemon.log"))
+			path = filepath.Join(home, "Library", "LaunchAgents", "com.mark42.daemon.plist")
+			start = "launchctl load -w " + path
+		default:
+			return &storage.ValidationError{Field: "kind", Reason: fmt.Sprintf("unknown unit kind %q; use systemd or launchd", kind)}
+		}
+
+		if install, _ := cmd.Flags().GetBool("install"); !install {
+			fmt.Fprint(out, unit)
+			return nil
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(unit), 0o644); err != nil {
+			return err
+		}
+		output(successStyle.Render("✓") + " Wrote " + path)
+		output("  Start it with: " + start)
+		return nil
+	},
+}
+
+// systemdUnit is a systemd user unit running command.
+func systemdUnit(command []string) string {
+	quoted := make([]string, len(command))
+	for i, arg := range command {
+		quoted[i] = arg
+		if strings.ContainsAny(arg, " \t\"\\") {
+			quoted[i] = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg) + `"`
+		}
+	}
+	return `[Unit]
+Description=mark42 memory daemon
+After=network-online.target
+
+[Service]
+ExecStart=` + strings.Join(quoted, " ") + `
+Restart=on-failure
+RestartSec=30
+
+[Install]
+WantedBy=default.target
+`
+}
+
+// launchdAgent is a launchd agent running command, logging to logPath.
+func launchdAgent(command []string, logPath string) string {
+	escape := func(s string) string {
+		var b strings.Builder
+		_ = xml.EscapeText(&b, []byte(s))
+		return b.String()
+	}
+	var args strings.Builder
+	for _, arg := range command {
+		args.WriteString("\t\t<string>" + escape(arg) + "</string>\n")
+	}
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>com.mark42.daemon</string>
+	<key>ProgramArguments</key>
+	<array>
+` + args.String() + `	</array>
+	<key>RunAtLoad</key>
+	<true/>
+	<key>KeepAlive</key>
+	<dict>
+		<key>SuccessfulExit</key>
+		<false/>
+	</dict>
+	<key>StandardErrorPath</key>
+	<string>` + escape(logPath) + `</string>
+</dict>
+</plist>
+`
+}
+
+func init() {
+	daemonRunCmd.Flags().Bool("once", false, "run each enabled subsystem once and exit, as from cron")
+	daemonStatusCmd.Flags().String("format", "default", "output format: default, json")
+	daemonUnitCmd.Flags().Bool("install", false, "write the unit where the service manager looks for it")
+	daemonCmd.AddCommand(daemonRunCmd, daemonStatusCmd, daemonUnitCmd)
+	rootCmd.AddCommand(daemonCmd)
+}
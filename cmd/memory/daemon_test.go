@@ -0,0 +1,196 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+func TestDaemonConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	writeConfig(t, home, `{"daemon": {
+		"maintenance": {"every": "12h"},
+		"embeddings": {"enabled": false},
+		"backup": {"every": "soon"},
+		"webhook": {"url": "http://localhost:9000/hook", "every": "5m"}
+	}}`)
+
+	cfg := loadEffectiveConfig(t.TempDir()).Daemon
+	if !cfg.Maintenance.Enabled || cfg.Maintenance.Every != 12*time.Hour {
+		t.Errorf("expected maintenance every 12h, got %+v", cfg.Maintenance)
+	}
+	if cfg.Embeddings.Enabled {
+		t.Error("expected embeddings turned off")
+	}
+	if !cfg.Backup.Enabled || cfg.Backup.Every != storage.DefaultBackupInterval {
+		t.Errorf("expected an invalid interval ignored, got %+v", cfg.Backup)
+	}
+	if !cfg.Webhook.Enabled || cfg.Webhook.Every != 5*time.Minute || cfg.Webhook.URL != "http://localhost:9000/hook" {
+		t.Errorf("expected the webhook every 5m, got %+v", cfg.Webhook)
+	}
+}
+
+func TestDaemonRunOnceAndStatus(t *testing.T) {
+	useTestDB(t)
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("CLAUDE_MEMORY_EMBEDDER_URL", "disabled")
+	replica := filepath.Join(t.TempDir(), "replica.db")
+	t.Setenv("CLAUDE_MEMORY_BACKUP_REPLICA", replica)
+	withStore(t, func(s *storage.Store) {
+		s.CreateEntity("Use SQLite", "decision", []string{"Chosen for zero setup"})
+	})
+	writeConfig(t, home, `{"policies": [
+		{"name": "pin decisions", "when": "entity_type == 'decision'", "set": {"pinned": true}}
+	]}`)
+
+	if got := runRootCmd(t, "daemon", "status"); !strings.Contains(got, "has not run") {
+		t.Errorf("expected no status yet:\n%s", got)
+	}
+
+	defer daemonRunCmd.Flags().Set("once", "false")
+	got := runRootCmd(t, "daemon", "run", "--once")
+	for _, want := range []string{"1 policy changes", "off: the embedder is disabled", "copied to " + replica, "off: no daemon.webhook.url set"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q:\n%s", want, got)
+		}
+	}
+	withStore(t, func(s *storage.Store) {
+		if check, err := s.VerifyBackup(replica); err != nil || !check.OK() {
+			t.Errorf("expected the replica to match, got %+v, %v", check, err)
+		}
+	})
+
+	got = runRootCmd(t, "daemon", "status")
+	if !strings.Contains(got, "Daemon stopped") || !strings.Contains(got, "maintenance") || !strings.Contains(got, "every 6h") {
+		t.Errorf("expected the last run reported:\n%s", got)
+	}
+
+	// Work needing the write lease waits out an active server
+	withStore(t, func(s *storage.Store) {
+		if _, err := s.AcquireWriteLease("mcp server", time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	})
+	runRootCmd(t, "daemon", "run", "--once")
+	var status struct {
+		Running    bool               `json:"running"`
+		Subsystems []*daemonSubsystem `json:"subsystems"`
+	}
+	if err := json.Unmarshal([]byte(runRootCmd(t, "daemon", "status", "--format", "json")), &status); err != nil {
+		t.Fatal(err)
+	}
+	if status.Running || len(status.Subsystems) != 4 {
+		t.Fatalf("expected four subsystems of a stopped daemon, got %+v", status)
+	}
+	if maint := status.Subsystems[0]; !strings.Contains(maint.Result, "skipped: mcp server") {
+		t.Errorf("expected maintenance skipped, got %+v", maint)
+	}
+	if backup := status.Subsystems[2]; backup.Error != "" || !strings.HasPrefix(backup.Result, "copied") && !strings.HasPrefix(backup.Result, "unchanged") {
+		t.Errorf("expected the backup made without the lease, got %+v", backup)
+	}
+}
+
+func TestDaemonWebhook(t *testing.T) {
+	useTestDB(t)
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("CLAUDE_MEMORY_EMBEDDER_URL", "disabled")
+
+	var posts []webhookPayload
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, []byte("s3cret"))
+		mac.Write(body)
+		if got := r.Header.Get("X-Mark42-Signature"); got != "sha256="+hex.EncodeToString(mac.Sum(nil)) {
+			t.Errorf("unexpected signature %q", got)
+		}
+		var payload webhookPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Errorf("unexpected body %s: %v", body, err)
+		}
+		posts = append(posts, payload)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+	writeConfig(t, home, `{"daemon": {
+		"maintenance": {"enabled": false},
+		"webhook": {"url": "`+srv.URL+`", "secret": "s3cret"}
+	}}`)
+	withStore(t, func(s *storage.Store) {
+		s.CreateEntity("Use SQLite", "decision", []string{"Chosen for zero setup"})
+	})
+
+	defer daemonRunCmd.Flags().Set("once", "false")
+	if got := runRootCmd(t, "daemon", "run", "--once"); !strings.Contains(got, "delivered 2 changes to "+srv.URL) {
+		t.Errorf("expected the entity and its observation delivered:\n%s", got)
+	}
+	if got := runRootCmd(t, "daemon", "run", "--once"); !strings.Contains(got, "no changes to deliver") || len(posts) != 1 {
+		t.Errorf("expected nothing new to deliver:\n%s", got)
+	}
+
+	// A failed delivery is sent again in full
+	withStore(t, func(s *storage.Store) {
+		s.AddObservation("Use SQLite", "WAL mode on")
+	})
+	status = http.StatusServiceUnavailable
+	rootCmd.SetArgs([]string{"daemon", "run", "--once"})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("expected the failed delivery reported, got %v", err)
+	}
+	status = http.StatusOK
+	runRootCmd(t, "daemon", "run", "--once")
+	if len(posts) != 3 || len(posts[2].Records) != 1 || posts[2].Records[0].Content != "WAL mode on" || posts[2].Clock <= posts[0].Clock {
+		t.Errorf("expected only the new observation redelivered, got %+v", posts)
+	}
+}
+
+func TestDaemonUnit(t *testing.T) {
+	useTestDB(t)
+	t.Setenv("HOME", t.TempDir())
+
+	got := runRootCmd(t, "daemon", "unit", "systemd")
+	if !strings.Contains(got, "ExecStart=") || !strings.Contains(got, "--db "+dbPath+" daemon run") {
+		t.Errorf("expected the unit to run the daemon on this database:\n%s", got)
+	}
+	if got := runRootCmd(t, "daemon", "unit", "launchd"); !strings.Contains(got, "<string>daemon</string>") {
+		t.Errorf("expected a launchd agent:\n%s", got)
+	}
+
+	if unit := systemdUnit([]string{"/opt/my tools/mark42", "run"}); !strings.Contains(unit, `ExecStart="/opt/my tools/mark42" run`) {
+		t.Errorf("expected paths with spaces quoted:\n%s", unit)
+	}
+	if agent := launchdAgent([]string{"a&b"}, "/tmp/log"); !strings.Contains(agent, "<string>a&amp;b</string>") {
+		t.Errorf("expected arguments escaped:\n%s", agent)
+	}
+
+	defer daemonUnitCmd.Flags().Set("install", "false")
+	if got := runRootCmd(t, "daemon", "unit", "systemd", "--install"); !strings.Contains(got, "systemctl --user enable --now mark42-daemon") {
+		t.Errorf("expected how to start it:\n%s", got)
+	}
+}
+
+func TestShortDuration(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		6 * time.Hour:    "6h",
+		10 * time.Minute: "10m",
+		90 * time.Minute: "1h30m",
+		45 * time.Second: "45s",
+	} {
+		if got := shortDuration(d); got != want {
+			t.Errorf("shortDuration(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+var dedupeCmd = &cobra.Command{
+	Use:   "dedupe",
+	Short: "Find entities that are probably the same thing",
+}
+
+var dedupeScanCmd = &cobra.Command{
+	Use:   "scan",
+	Short: "Report probable duplicate entities, most similar first",
+	Long: `Compare entity names ignoring case, spacing, and punctuation, and with
+--embeddings also the mean embedding of each entity's observations, and list
+the pairs that are probably duplicates, most similar first.
+
+Each pair names the entity to keep (the one with more observations, or else
+the older) and the one to merge into it. --format json writes the report as
+a list of {keep, merge, score, ...} objects.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		if err := store.Migrate(); err != nil {
+			return err
+		}
+
+		candidates, err := findDuplicates(cmd, store)
+		if err != nil {
+			return err
+		}
+
+		if format, _ := cmd.Flags().GetString("format"); format == "json" {
+			if candidates == nil {
+				candidates = []storage.DuplicateCandidate{}
+			}
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			return enc.Encode(candidates)
+		}
+
+		if len(candidates) == 0 {
+			output("No duplicate entities found")
+			return nil
+		}
+		output(titleStyle.Render(fmt.Sprintf("Probable duplicates (%d)", len(candidates))))
+		output()
+		for _, c := range candidates {
+			renderDuplicate(c)
+		}
+		return nil
+	},
+}
+
+var dedupeRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Walk through probable duplicates and merge them",
+	Long: `Find probable duplicates as dedupe scan does and, for each pair, preview
+what merging would move and drop, then ask whether to merge: y merges the
+second entity into the first, s merges the other way round, q stops.
+
+--auto merges every pair scoring at least --threshold without asking. Each
+merge is recorded; dedupe undo reverses it. The database is snapshotted
+before the first merge unless --no-snapshot is given.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		if err := store.Migrate(); err != nil {
+			return err
+		}
+
+		candidates, err := findDuplicates(cmd, store)
+		if err != nil {
+			return err
+		}
+		if len(candidates) == 0 {
+			output("No duplicate entities found")
+			return nil
+		}
+
+		auto, _ := cmd.Flags().GetBool("auto")
+		threshold, _ := cmd.Flags().GetFloat64("threshold")
+		in := bufio.NewScanner(cmd.InOrStdin())
+		gone := map[string]bool{} // Merged away earlier in this run
+		merged := 0
+		snapshotted := false // Taken before the first merge
+		for _, c := range candidates {
+			if gone[c.Keep] || gone[c.Merge] {
+				continue
+			}
+			keep, merge := c.Keep, c.Merge
+			if auto {
+				if c.Score < threshold {
+					break // Sorted by score
+				}
+			} else {
+				renderDuplicate(c)
+				preview, err := store.PreviewMerge(keep, merge)
+				if err != nil {
+					logger.Warn("failed to preview merge", "keep", keep, "merge", merge, "error", err)
+					continue
+				}
+				renderMergePreview(preview)
+				fmt.Fprint(out, "  Merge? [y/N/s(wap)/q] ")
+				answer := ""
+				if in.Scan() {
+					answer = strings.ToLower(strings.TrimSpace(in.Text()))
+				}
+				if answer == "q" {
+					break
+				}
+				if answer == "s" {
+					keep, merge = merge, keep
+				} else if answer != "y" {
+					continue
+				}
+			}
+
+			if !snapshotted {
+				release, err := takeWriteLease(cmd, store)
+				if err != nil {
+					return err
+				}
+				defer release()
+				if err := snapshotBefore(cmd, store, "dedupe run"); err != nil {
+					return err
+				}
+				snapshotted = true
+			}
+			m, err := store.MergeEntities(keep, merge)
+			if err != nil {
+				logger.Warn("failed to merge", "keep", keep, "merge", merge, "error", err)
+				continue
+			}
+			gone[m.Merged] = true
+			merged++
+			output("  " + successStyle.Render("✓") + " Merged " + entityStyle.Render(m.Merged) + " into " +
+				entityStyle.Render(m.Keep) + " " + dimStyle.Render(fmt.Sprintf("(merge #%d)", m.ID)))
+		}
+		output(successStyle.Render(fmt.Sprintf("Merged %d entities", merged)))
+		if merged > 0 {
+			output(dimStyle.Render("Undo a merge with: mark42 dedupe undo <merge-id>"))
+		}
+		return nil
+	},
+}
+
+var dedupeUndoCmd = &cobra.Command{
+	Use:   "undo [merge-id]",
+	Short: "Undo a merge, restoring the merged entity",
+	Long: `Restore the entity a merge deleted, with its observations, relations, and
+attributes, taking back what moved to the kept entity. Without an ID, undoes
+the latest merge not yet undone. Embeddings of observations the kept entity
+already had are not restored; run embed generate to recreate them.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var id int64
+		if len(args) == 1 {
+			var err error
+			if id, err = strconv.ParseInt(strings.TrimPrefix(args[0], "#"), 10, 64); err != nil || id <= 0 {
+				return &storage.ValidationError{Field: "merge-id", Reason: "must be a positive number"}
+			}
+		}
+
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		if err := store.Migrate(); err != nil {
+			return err
+		}
+
+		m, err := store.UndoMerge(id)
+		if err != nil {
+			return err
+		}
+		output(successStyle.Render("✓") + " Undid merge " + dimStyle.Render(fmt.Sprintf("#%d", m.ID)) + ": " +
+			entityStyle.Render(m.Merged) + " restored from " + entityStyle.Render(m.Keep))
+		return nil
+	},
+}
+
+var dedupeObservationsCmd = &cobra.Command{
+	Use:   "observations",
+	Short: "Report observation text stored word for word under several entities",
+	Long: `List observations whose exact text several entities hold, matched by
+content hash, with the most copies first.
+
+--link makes the oldest copy of each text canonical and links the others to
+it: every copy stays on its entity, but 'obs edit' on the canonical one
+updates the linked copies too, so a convention written under five entities
+is edited once. Editing a linked copy unlinks it.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		if err := store.Migrate(); err != nil {
+			return err
+		}
+
+		if link, _ := cmd.Flags().GetBool("link"); link {
+			linked, err := store.LinkDuplicateObservations()
+			if err != nil {
+				return err
+			}
+			output(successStyle.Render(fmt.Sprintf("✓ Linked %d copies to their canonical observation", linked)))
+		}
+
+		duplicates, err := store.DuplicateObservations()
+		if err != nil {
+			return err
+		}
+
+		if format, _ := cmd.Flags().GetString("format"); format == "json" {
+			if duplicates == nil {
+				duplicates = []storage.ObservationDuplicate{}
+			}
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			return enc.Encode(duplicates)
+		}
+
+		if len(duplicates) == 0 {
+			output("No duplicate observations found")
+			return nil
+		}
+		output(titleStyle.Render(fmt.Sprintf("Duplicate observations (%d)", len(duplicates))))
+		output()
+		for _, d := range duplicates {
+			detail := fmt.Sprintf("%d entities", len(d.Entities))
+			if d.Linked > 0 {
+				detail += fmt.Sprintf(", %d linked", d.Linked)
+			}
+			output("  " + obsStyle.Render(d.Content) + " " + dimStyle.Render("("+detail+")"))
+			names := make([]string, len(d.Entities))
+			for i, name := range d.Entities {
+				names[i] = entityStyle.Render(name)
+			}
+			output("    " + strings.Join(names, ", "))
+		}
+		return nil
+	},
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{dedupeScanCmd, dedupeRunCmd} {
+		cmd.Flags().Float64("min-name", 0.85, "name similarity (0-1) that makes two entities candidates")
+		cmd.Flags().Bool("embeddings", false, "also compare the embeddings of the entities' observations")
+		cmd.Flags().Float64("min-embedding", 0.92, "embedding similarity (0-1) that makes two entities candidates, with --embeddings")
+		cmd.Flags().Int("limit", 0, "consider at most this many pairs (0 = all)")
+	}
+	dedupeScanCmd.Flags().String("format", "default", "output format: default, json")
+	dedupeRunCmd.Flags().Bool("auto", false, "merge every pair scoring at least --threshold without asking")
+	dedupeRunCmd.Flags().Float64("threshold", 0.93, "score (0-1) a pair needs to be merged with --auto")
+	addNoSnapshotFlag(dedupeRunCmd)
+	addWaitFlag(dedupeRunCmd)
+	dedupeCmd.AddCommand(dedupeScanCmd)
+	dedupeCmd.AddCommand(dedupeRunCmd)
+	dedupeCmd.AddCommand(dedupeUndoCmd)
+	dedupeObservationsCmd.Flags().Bool("link", false, "link each copy to the oldest, so updating that one updates them all")
+	dedupeObservationsCmd.Flags().String("format", "default", "output format: default, json")
+	dedupeCmd.AddCommand(dedupeObservationsCmd)
+	rootCmd.AddCommand(dedupeCmd)
+}
+
+// findDuplicates runs FindDuplicates with the command's candidate flags.
+func findDuplicates(cmd *cobra.Command, store *storage.Store) ([]storage.DuplicateCandidate, error) {
+	minName, _ := cmd.Flags().GetFloat64("min-name")
+	minEmbedding, _ := cmd.Flags().GetFloat64("min-embedding")
+	embeddings, _ := cmd.Flags().GetBool("embeddings")
+	candidates, err := store.FindDuplicates(storage.DuplicateOptions{
+		MinNameSimilarity:      minName,
+		Embeddings:             embeddings,
+		MinEmbeddingSimilarity: minEmbedding,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if limit, _ := cmd.Flags().GetInt("limit"); limit > 0 && len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	return candidates, nil
+}
+
+func renderMergePreview(m *storage.EntityMerge) {
+	output("      " + dimStyle.Render(fmt.Sprintf(
+		"moves %d observations and %d relations, copies %d attributes; drops %d observations and %d relations %s already has",
+		m.ObservationsMoved, m.RelationsMoved, m.AttributesCopied, m.ObservationsDropped, m.RelationsDropped, m.Keep)))
+}
+
+func renderDuplicate(c storage.DuplicateCandidate) {
+	detail := fmt.Sprintf("name %.2f", c.NameSimilarity)
+	if c.EmbeddingSimilarity > 0 {
+		detail += fmt.Sprintf(", embedding %.2f", c.EmbeddingSimilarity)
+	}
+	output("  " + fmt.Sprintf("%.2f", c.Score) + "  " +
+		entityStyle.Render(c.Keep) + " " + typeStyle.Render("("+c.KeepType+")") + " ← " +
+		entityStyle.Render(c.Merge) + " " + typeStyle.Render("("+c.MergeType+")") + " " +
+		dimStyle.Render("("+detail+")"))
+}
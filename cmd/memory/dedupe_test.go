@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+func TestDedupeScan(t *testing.T) {
+	useTestDB(t)
+
+	if got := runRootCmd(t, "dedupe", "scan"); !strings.Contains(got, "No duplicate entities found") {
+		t.Errorf("expected no duplicates in an empty database:\n%s", got)
+	}
+
+	withStore(t, func(s *storage.Store) {
+		s.CreateEntity("mark42", "project", []string{"Memory for coding agents"})
+		s.CreateEntity("Mark 42", "project", nil)
+		s.CreateEntity("konfig", "project", nil)
+	})
+
+	got := runRootCmd(t, "dedupe", "scan")
+	if !strings.Contains(got, "Probable duplicates (1)") || !strings.Contains(got, "mark42 (project) ← Mark 42 (project)") {
+		t.Errorf("expected mark42 and Mark 42 reported:\n%s", got)
+	}
+
+	defer dedupeScanCmd.Flags().Set("format", "default")
+	var candidates []storage.DuplicateCandidate
+	if err := json.Unmarshal([]byte(runRootCmd(t, "dedupe", "scan", "--format", "json")), &candidates); err != nil {
+		t.Fatal(err)
+	}
+	if len(candidates) != 1 || candidates[0].Keep != "mark42" || candidates[0].Merge != "Mark 42" {
+		t.Errorf("expected a JSON report keeping mark42, got %+v", candidates)
+	}
+}
+
+func TestDedupeRunAndUndo(t *testing.T) {
+	useTestDB(t)
+	withStore(t, func(s *storage.Store) {
+		s.CreateEntity("mark42", "project", []string{"Memory for coding agents"})
+		s.CreateEntity("Mark 42", "project", []string{"Uses SQLite"})
+		s.CreateEntity("konfig", "project", []string{"Loads YAML"})
+		s.CreateEntity("Konfig", "project", nil)
+	})
+
+	// Both pairs score 1, so konfig's comes first: merge it, skip mark42's
+	rootCmd.SetIn(strings.NewReader("y\nn\n"))
+	defer rootCmd.SetIn(nil)
+	got := runRootCmd(t, "dedupe", "run")
+	if !strings.Contains(got, "moves 1 observations") || !strings.Contains(got, "Merged 1 entities") {
+		t.Errorf("expected a preview and one merge:\n%s", got)
+	}
+	withStore(t, func(s *storage.Store) {
+		if _, err := s.GetEntity("Konfig"); err == nil {
+			t.Error("expected Konfig merged into konfig")
+		}
+		if _, err := s.GetEntity("Mark 42"); err != nil {
+			t.Errorf("expected Mark 42 kept: %v", err)
+		}
+	})
+
+	defer dedupeRunCmd.Flags().Set("auto", "false")
+	got = runRootCmd(t, "dedupe", "run", "--auto")
+	if !strings.Contains(got, "Merged Mark 42 into mark42") {
+		t.Errorf("expected --auto to merge Mark 42:\n%s", got)
+	}
+
+	got = runRootCmd(t, "dedupe", "undo")
+	if !strings.Contains(got, "Mark 42 restored from mark42") {
+		t.Errorf("expected the latest merge undone:\n%s", got)
+	}
+	got = runRootCmd(t, "dedupe", "undo", "1")
+	if !strings.Contains(got, "Konfig restored from konfig") {
+		t.Errorf("expected merge #1 undone:\n%s", got)
+	}
+	rootCmd.SetArgs([]string{"dedupe", "undo", "abc"})
+	if err := rootCmd.Execute(); exitCode(err) != exitInvalidInput {
+		t.Errorf("expected a bad merge ID rejected as invalid input, got %v", err)
+	}
+}
+
+func TestDedupeObservations(t *testing.T) {
+	useTestDB(t)
+	withStore(t, func(s *storage.Store) {
+		s.CreateEntity("api", "project", []string{"Use table-driven tests"})
+		s.CreateEntity("cli", "project", []string{"Use table-driven tests"})
+		s.CreateEntity("web", "project", []string{"Serves HTML"})
+	})
+
+	got := runRootCmd(t, "dedupe", "observations")
+	if !strings.Contains(got, "Duplicate observations (1)") || !strings.Contains(got, "api, cli") {
+		t.Errorf("expected the shared observation reported:\n%s", got)
+	}
+
+	defer dedupeObservationsCmd.Flags().Set("link", "false")
+	if got := runRootCmd(t, "dedupe", "observations", "--link"); !strings.Contains(got, "Linked 1 copies") || !strings.Contains(got, "1 linked") {
+		t.Errorf("expected the copy linked:\n%s", got)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the database for corruption and orphaned rows",
+	Long: `Check that foreign keys are enforced, that the database file is sound, and
+that no observations, embeddings, or relations point at rows that no longer
+exist. --fix deletes orphaned rows and normalizes entity names to Unicode NFC.
+
+Entity names that differ only in case or normalization are listed but not
+changed; rename or merge them before setting CLAUDE_MEMORY_CASE_INSENSITIVE_NAMES.
+Like that setting, which uses SQLite's NOCASE collation, case here means ASCII
+letters only: "Émile" and "émile" are neither listed nor matched as one name.
+Entities with observations tagged as personal data in PII warn mode are listed
+too, for review; neither fails the check.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		if err := store.Migrate(); err != nil {
+			return err
+		}
+
+		fix, _ := cmd.Flags().GetBool("fix")
+		if fix {
+			fixed, err := store.DeleteOrphans()
+			if err != nil {
+				return err
+			}
+			if fixed > 0 {
+				output(successStyle.Render(fmt.Sprintf("✓ Fixed %d orphaned rows", fixed)))
+			}
+			renamed, err := store.NormalizeEntityNames()
+			if err != nil {
+				return err
+			}
+			if renamed > 0 {
+				output(successStyle.Render(fmt.Sprintf("✓ Normalized %d entity names", renamed)))
+			}
+		}
+
+		report, err := store.CheckIntegrity()
+		if err != nil {
+			return err
+		}
+
+		output(titleStyle.Render("Database Check"))
+		output()
+		check := func(ok bool, label string) {
+			mark := successStyle.Render("✓")
+			if !ok {
+				mark = "✗"
+			}
+			output("  " + mark + " " + label)
+		}
+		check(report.ForeignKeys, "Foreign keys enforced")
+		check(len(report.Problems) == 0, "Database file")
+		for _, p := range report.Problems {
+			output("      " + p)
+		}
+
+		tables := make([]string, 0, len(report.Orphans))
+		for table := range report.Orphans {
+			tables = append(tables, table)
+		}
+		sort.Strings(tables)
+		check(len(tables) == 0, "Referential integrity")
+		for _, table := range tables {
+			output(fmt.Sprintf("      %d orphaned rows in %s", report.Orphans[table], table))
+		}
+
+		check(len(report.NameCollisions) == 0, "Entity names distinct ignoring case")
+		for _, group := range report.NameCollisions {
+			output("      " + strings.Join(quoteAll(group), ", "))
+		}
+
+		check(len(report.PIIExposure) == 0, "No observations tagged as personal data")
+		for _, e := range report.PIIExposure {
+			output(fmt.Sprintf("      %s: %d (%s)", strconv.QuoteToASCII(e.Entity), e.Observations, strings.Join(e.Kinds, ", ")))
+		}
+
+		if !report.OK() {
+			if len(tables) > 0 && !fix {
+				output()
+				output(dimStyle.Render("  Run 'mark42 doctor --fix' to delete orphaned rows"))
+			}
+			return errors.New("database check failed")
+		}
+		return nil
+	},
+}
+
+func quoteAll(names []string) []string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = strconv.QuoteToASCII(name)
+	}
+	return quoted
+}
+
+func init() {
+	doctorCmd.Flags().Bool("fix", false, "delete orphaned rows")
+	rootCmd.AddCommand(doctorCmd)
+}
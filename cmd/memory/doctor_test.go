@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+func TestDoctor_HealthyDatabase(t *testing.T) {
+	useTestDB(t)
+	withStore(t, func(s *storage.Store) {
+		s.CreateEntity("Go", "language", []string{"Fast compiler"})
+	})
+
+	got := runRootCmd(t, "doctor", "--fix=false")
+	for _, want := range []string{"Foreign keys enforced", "Database file", "Referential integrity"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in output:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "✗") {
+		t.Errorf("expected all checks to pass:\n%s", got)
+	}
+}
+
+func TestDoctor_ReportsNameCollisions(t *testing.T) {
+	useTestDB(t)
+	withStore(t, func(s *storage.Store) {
+		s.CreateEntity("Go", "language", nil)
+		s.CreateEntity("go", "verb", nil)
+	})
+
+	got := runRootCmd(t, "doctor", "--fix=false")
+	if !strings.Contains(got, `✗ Entity names distinct ignoring case`) || !strings.Contains(got, `"Go", "go"`) {
+		t.Errorf("expected the colliding names listed:\n%s", got)
+	}
+}
+
+func TestDoctor_ReportsPIIExposure(t *testing.T) {
+	useTestDB(t)
+	withStore(t, func(s *storage.Store) {
+		s.SetPIIMode(storage.PIIModeWarn)
+		s.CreateEntity("Support", "process", []string{"Escalate to help@example.com"})
+	})
+
+	got := runRootCmd(t, "doctor", "--fix=false") // Fails the test if the check fails
+	if !strings.Contains(got, `"Support": 1 (email)`) {
+		t.Errorf("expected the exposed entity listed:\n%s", got)
+	}
+
+	got = runRootCmd(t, "stats")
+	if !strings.Contains(got, "Personal data (1 observations)") {
+		t.Errorf("expected stats to report the tagged observation:\n%s", got)
+	}
+}
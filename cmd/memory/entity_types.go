@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+var entityTypesCmd = &cobra.Command{
+	Use:   "types",
+	Short: "Inspect entity types",
+	Long: `Entity types are free-form unless registered under "entityTypes" in
+config.json (a map of type to description) or listed, comma-separated, in
+CLAUDE_MEMORY_ENTITY_TYPES. Entities written with a variant of a registered
+type, differing only in case, separators, or a plural ending, get the
+registered type. entity retype remaps types already stored.`,
+}
+
+var entityTypesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List entity types with entity counts",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		if err := store.Migrate(); err != nil {
+			return err
+		}
+
+		types, err := store.ListEntityTypes()
+		if err != nil {
+			return err
+		}
+		if format, _ := cmd.Flags().GetString("format"); format == "json" {
+			if types == nil {
+				types = []storage.EntityTypeCount{}
+			}
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			return enc.Encode(types)
+		}
+		if len(types) == 0 {
+			output(dimStyle.Render("No entity types"))
+			return nil
+		}
+
+		strays := 0
+		for _, t := range types {
+			line := fmt.Sprintf("%6d  ", t.Count) + typeStyle.Render(t.Type)
+			switch {
+			case t.Canonical != "":
+				strays++
+				line += " " + dimStyle.Render("→ "+t.Canonical)
+			case t.Registered && t.Description != "":
+				line += " " + dimStyle.Render(t.Description)
+			case !t.Registered && len(store.EntityTypes()) > 0:
+				line += " " + dimStyle.Render("(unregistered)")
+			}
+			output(line)
+		}
+		if strays > 0 {
+			output()
+			output(dimStyle.Render("Remap a variant onto its registered type with: mark42 entity retype <type>"))
+		}
+		return nil
+	},
+}
+
+var entityRetypeCmd = &cobra.Command{
+	Use:   "retype <from-type> [to-type]",
+	Short: "Change the type of every entity of a type",
+	Long: `Change the type of every entity of from-type, all versions included, to
+to-type. Without to-type, from-type must be a variant of a registered type,
+which it is remapped onto.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		if err := store.Migrate(); err != nil {
+			return err
+		}
+
+		from, to := args[0], store.CanonicalEntityType(args[0])
+		if len(args) == 2 {
+			to = args[1]
+		} else if to == from {
+			return &storage.ValidationError{Field: "entity type", Reason: fmt.Sprintf("%q is not a variant of a registered type; name the type to change it to", from)}
+		}
+
+		n, err := store.RetypeEntities(from, to)
+		if err != nil {
+			return err
+		}
+		output(successStyle.Render("✓") + fmt.Sprintf(" Retyped %d entities from ", n) +
+			typeStyle.Render(from) + " to " + typeStyle.Render(to))
+		return nil
+	},
+}
+
+func init() {
+	entityTypesListCmd.Flags().String("format", "default", "output format: default, json")
+	entityTypesCmd.AddCommand(entityTypesListCmd)
+	entityCmd.AddCommand(entityTypesCmd)
+	entityCmd.AddCommand(entityRetypeCmd)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+func TestEntityTypesCommands(t *testing.T) {
+	useTestDB(t)
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	withStore(t, func(s *storage.Store) {
+		s.CreateEntity("TDD", "pattern", nil)
+		s.CreateEntity("DRY", "patterns", nil)
+	})
+	writeConfig(t, home, `{"entityTypes": {"pattern": "A reusable practice"}}`)
+
+	got := runRootCmd(t, "entity", "types", "list")
+	if !strings.Contains(got, "patterns → pattern") || !strings.Contains(got, "A reusable practice") {
+		t.Errorf("expected counts with the stray type's canonical form:\n%s", got)
+	}
+
+	runRootCmd(t, "entity", "create", "KISS", "Pattern")
+	runRootCmd(t, "entity", "retype", "patterns")
+	withStore(t, func(s *storage.Store) {
+		for _, name := range []string{"DRY", "KISS"} {
+			if e, err := s.GetEntity(name); err != nil || e.Type != "pattern" {
+				t.Errorf("expected %s to be a pattern, got %+v (%v)", name, e, err)
+			}
+		}
+	})
+
+	rootCmd.SetArgs([]string{"entity", "retype", "project"})
+	if err := rootCmd.Execute(); exitCode(err) != exitInvalidInput {
+		t.Errorf("expected a type with no registered form to need a target, got %v", err)
+	}
+}
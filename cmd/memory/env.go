@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// flagEnvPrefix prefixes the environment variables of flags: --db is
+// MARK42_DB, and --token-budget of the context command MARK42_TOKEN_BUDGET,
+// or MARK42_CONTEXT_TOKEN_BUDGET for that command alone.
+const flagEnvPrefix = "MARK42_"
+
+// flagEnvAliases are CLAUDE_MEMORY_* variables honored for flags after the
+// MARK42_* ones, by flag name or by command path and flag name.
+var flagEnvAliases = map[string]string{
+	"db":                     "CLAUDE_MEMORY_DB",
+	"tokenizer":              "CLAUDE_MEMORY_TOKENIZER",
+	"context token-budget":   "CLAUDE_MEMORY_TOKEN_BUDGET",
+	"context min-importance": "CLAUDE_MEMORY_MIN_IMPORTANCE",
+	"context project-boost":  "CLAUDE_MEMORY_BOOST",
+}
+
+// envName turns words such as a command path or flag name into the name
+// of an environment variable.
+func envName(words ...string) string {
+	name := strings.Join(words, "_")
+	return flagEnvPrefix + strings.ToUpper(strings.NewReplacer("-", "_", " ", "_").Replace(name))
+}
+
+// applyFlagEnv sets each of cmd's flags not given on the command line from
+// its environment variable, so scripts and plugin configs can set any flag
+// once. The command's own variable wins over the one shared by every
+// command with the flag.
+func applyFlagEnv(cmd *cobra.Command) error {
+	path := strings.TrimPrefix(strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()), " ")
+	var err error
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if err != nil || f.Changed || f.Name == "help" {
+			return
+		}
+		names := []string{envName(f.Name)}
+		if path != "" {
+			names = []string{envName(path, f.Name), names[0]}
+		}
+		if alias, ok := flagEnvAliases[strings.TrimSpace(path+" "+f.Name)]; ok {
+			names = append(names, alias)
+		} else if alias, ok := flagEnvAliases[f.Name]; ok {
+			names = append(names, alias)
+		}
+		for _, name := range names {
+			if value := os.Getenv(name); value != "" {
+				if setErr := cmd.Flags().Set(f.Name, value); setErr != nil {
+					err = fmt.Errorf("%s: %w", name, setErr)
+				}
+				return
+			}
+		}
+	})
+	return err
+}
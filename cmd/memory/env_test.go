@@ -0,0 +1,48 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+func TestFlagEnv(t *testing.T) {
+	useTestDB(t)
+	envDB := filepath.Join(t.TempDir(), "env.db")
+	t.Setenv("CLAUDE_MEMORY_DB", envDB)
+	defer rootCmd.PersistentFlags().Set("db", dbPath)
+
+	runRootCmd(t, "entity", "create", "Go", "language", "--obs", "Fast compiler")
+	if dbPath != envDB {
+		t.Errorf("expected CLAUDE_MEMORY_DB used, got %s", dbPath)
+	}
+	withStore(t, func(s *storage.Store) {
+		if _, err := s.GetEntity("Go"); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Setenv("CLAUDE_MEMORY_TOKENIZER", "chars")
+	defer rootCmd.PersistentFlags().Set("tokenizer", "")
+	runRootCmd(t, "stats")
+	if tokenizerModel != "chars" {
+		t.Errorf("expected CLAUDE_MEMORY_TOKENIZER used, got %q", tokenizerModel)
+	}
+
+	t.Setenv("MARK42_FORMAT", "default")
+	t.Setenv("MARK42_STATS_FORMAT", "json")
+	defer statsCmd.Flags().Set("format", "default")
+	if got := runRootCmd(t, "stats"); !strings.HasPrefix(strings.TrimSpace(got), "{") {
+		t.Errorf("expected MARK42_STATS_FORMAT to select JSON:\n%s", got)
+	}
+
+	t.Setenv("MARK42_LIMIT", "many")
+	defer searchCmd.Flags().Set("format", "default")
+	defer searchCmd.Flags().Set("limit", "10")
+	rootCmd.SetArgs([]string{"search", "Go"})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "MARK42_LIMIT") {
+		t.Errorf("expected the bad variable named, got %v", err)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+// evalFile is the layout of an eval --cases file.
+type evalFile struct {
+	K     int                `yaml:"k"`
+	Cases []storage.EvalCase `yaml:"cases"`
+}
+
+// loadEvalCases reads an eval --cases file, YAML or JSON.
+func loadEvalCases(path string) (*evalFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file evalFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return &file, nil
+}
+
+var evalCmd = &cobra.Command{
+	Use:   "eval --cases eval.yaml",
+	Short: "Measure search quality against expected results",
+	Long: `Run each case's query through keyword (fts), vector, and hybrid search and
+report, per mode, recall@k (the share of expected entities in the top k
+entities) and MRR (the mean of 1/rank of the first expected entity). Run it
+before and after changing the embedding model or search.vectorWeight to see
+whether the change helps.
+
+The cases file is YAML (or JSON):
+
+  k: 5
+  cases:
+    - query: which editor do I use
+      expected: [user_editor]
+    - query: deployment pipeline
+      expected: [ci, docker]
+
+Vector search uses the embedder at CLAUDE_MEMORY_EMBEDDER_URL (default:
+Ollama) and is skipped when it is disabled or unreachable.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, _ := cmd.Flags().GetString("cases")
+		if path == "" {
+			return &storage.ValidationError{Field: "cases", Reason: "give a cases file with --cases"}
+		}
+		file, err := loadEvalCases(path)
+		if err != nil {
+			return err
+		}
+		if k, _ := cmd.Flags().GetInt("k"); k > 0 {
+			file.K = k
+		}
+
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		report, err := store.EvaluateSearch(context.Background(), file.Cases, file.K, embedderFromEnv())
+		if err != nil {
+			return err
+		}
+
+		if format, _ := cmd.Flags().GetString("format"); format == "json" {
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			return enc.Encode(report)
+		}
+
+		output(titleStyle.Render("Search Evaluation") + " " + dimStyle.Render(fmt.Sprintf("(%d cases, k=%d)", report.Cases, report.K)))
+		output()
+		output(dimStyle.Render(fmt.Sprintf("  %-8s %9s %7s", "mode", fmt.Sprintf("recall@%d", report.K), "MRR")))
+		for _, m := range report.Modes {
+			if m.Skipped != "" {
+				output(fmt.Sprintf("  %-8s ", m.Mode) + dimStyle.Render("skipped: "+m.Skipped))
+				continue
+			}
+			output(fmt.Sprintf("  %-8s %9.3f %7.3f", m.Mode, m.Recall, m.MRR))
+		}
+
+		if verbose, _ := cmd.Flags().GetBool("verbose"); verbose {
+			output()
+			output(titleStyle.Render("Cases"))
+			for _, r := range report.Results {
+				rank := "not found"
+				if r.Rank > 0 {
+					rank = fmt.Sprintf("rank %d", r.Rank)
+				}
+				output(fmt.Sprintf("  %-8s %.2f  %-10s ", r.Mode, r.Recall, rank) + r.Query)
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	evalCmd.Flags().String("cases", "", "YAML or JSON file of queries and expected entities")
+	evalCmd.Flags().Int("k", 0, fmt.Sprintf("cutoff for recall (default: k in the file, else %d)", storage.DefaultEvalK))
+	evalCmd.Flags().Bool("verbose", false, "show each case's rank and recall per mode")
+	evalCmd.Flags().String("format", "default", "output format: default, json")
+	rootCmd.AddCommand(evalCmd)
+}
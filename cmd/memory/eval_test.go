@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+func TestEvalCommand(t *testing.T) {
+	useTestDB(t)
+	t.Setenv("CLAUDE_MEMORY_EMBEDDER_URL", "disabled")
+	withStore(t, func(s *storage.Store) {
+		s.CreateEntity("user_editor", "person", []string{"uses neovim editor daily"})
+		s.CreateEntity("user_lang", "person", []string{"prefers typescript programming"})
+	})
+
+	cases := filepath.Join(t.TempDir(), "eval.yaml")
+	os.WriteFile(cases, []byte(`k: 3
+cases:
+  - query: neovim
+    expected: [user_editor]
+  - query: golang
+    expected: [user_lang]
+`), 0o644)
+
+	defer evalCmd.Flags().Set("verbose", "false")
+	got := runRootCmd(t, "eval", "--cases", cases, "--verbose")
+	if !strings.Contains(got, "2 cases, k=3") {
+		t.Errorf("expected the case count and k:\n%s", got)
+	}
+	if !strings.Contains(got, "fts          0.500   0.500") {
+		t.Errorf("expected fts to find one of two cases:\n%s", got)
+	}
+	if !strings.Contains(got, "skipped: no embedder") {
+		t.Errorf("expected vector search skipped:\n%s", got)
+	}
+	if !strings.Contains(got, "not found") {
+		t.Errorf("expected the missed case listed:\n%s", got)
+	}
+}
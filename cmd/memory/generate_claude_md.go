@@ -0,0 +1,161 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+// The markers around the block of CLAUDE.md generate-claude-md manages.
+const (
+	claudeMDBegin = "<!-- mark42:begin -->"
+	claudeMDEnd   = "<!-- mark42:end -->"
+)
+
+// renderClaudeMDBlock renders entities as the managed block, markers included.
+func renderClaudeMDBlock(project string, entities []*storage.Entity) string {
+	var b strings.Builder
+	b.WriteString(claudeMDBegin + "\n")
+	fmt.Fprintf(&b, "<!-- Generated from mark42 memories by `mark42 generate-claude-md --project %s`; edits inside these markers are overwritten. -->\n", project)
+	b.WriteString("## Project memory\n")
+	if len(entities) == 0 {
+		b.WriteString("\nNo memories recorded yet.\n")
+	}
+	for _, e := range entities {
+		fmt.Fprintf(&b, "\n### %s", oneLine(e.Name))
+		if e.Type != "" {
+			fmt.Fprintf(&b, " (%s)", e.Type)
+		}
+		b.WriteString("\n\n")
+		for _, obs := range e.Observations {
+			b.WriteString("- " + oneLine(obs) + "\n")
+		}
+	}
+	b.WriteString(claudeMDEnd)
+	return b.String()
+}
+
+// oneLine collapses s to one line, so a memory can't end a list item or the
+// block early.
+func oneLine(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	return strings.NewReplacer(claudeMDBegin, "", claudeMDEnd, "").Replace(s)
+}
+
+// replaceManagedBlock puts block in place of doc's managed block, or appends
+// it when doc has none.
+func replaceManagedBlock(doc, block string) (string, error) {
+	begin := strings.Index(doc, claudeMDBegin)
+	if begin < 0 {
+		if strings.Contains(doc, claudeMDEnd) {
+			return "", fmt.Errorf("%s without %s", claudeMDEnd, claudeMDBegin)
+		}
+		if strings.TrimSpace(doc) == "" {
+			return block + "\n", nil
+		}
+		return strings.TrimRight(doc, "\n") + "\n\n" + block + "\n", nil
+	}
+	end := strings.Index(doc[begin:], claudeMDEnd)
+	if end < 0 {
+		return "", fmt.Errorf("%s without %s", claudeMDBegin, claudeMDEnd)
+	}
+	return doc[:begin] + block + doc[begin+end+len(claudeMDEnd):], nil
+}
+
+var generateClaudeMDCmd = &cobra.Command{
+	Use:   "generate-claude-md [dir]",
+	Short: "Write a project's key memories into its CLAUDE.md",
+	Long: `Render the memories a project's CLAUDE.md should carry into a block between
+` + claudeMDBegin + ` and ` + claudeMDEnd + ` in dir's CLAUDE.md (default:
+the current directory's), creating the file or appending the block when it has
+none. Text outside the markers is left alone.
+
+The block lists, per entity tagged with the project, its static facts and, for
+conventions, all its observations, at --min-importance or pinned; at most
+--limit, the pinned and most important first. Facts imported from the
+project's own files, by index or import-git, are left out.
+
+With --check nothing is written; it fails when the block is out of date, so CI
+can catch a CLAUDE.md that no longer matches the memories.`,
+	Example: `  mark42 generate-claude-md --project api
+  mark42 generate-claude-md ~/src/api --check`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir := "."
+		if len(args) > 0 {
+			dir = args[0]
+		}
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return err
+		}
+		if info, err := os.Stat(abs); err != nil || !info.IsDir() {
+			return &storage.ValidationError{Field: "dir", Reason: fmt.Sprintf("%s is not a directory", dir)}
+		}
+		project, _ := cmd.Flags().GetString("project")
+		if project == "" {
+			project = filepath.Base(abs)
+		}
+		file, _ := cmd.Flags().GetString("file")
+		if file == "" {
+			file = filepath.Join(abs, "CLAUDE.md")
+		}
+		minImportance, _ := cmd.Flags().GetFloat64("min-importance")
+		limit, _ := cmd.Flags().GetInt("limit")
+		check, _ := cmd.Flags().GetBool("check")
+
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		if err := store.Migrate(); err != nil {
+			return err
+		}
+		entities, err := store.ProjectInstructions(project, minImportance, limit)
+		if err != nil {
+			return err
+		}
+		count := 0
+		for _, e := range entities {
+			count += len(e.Observations)
+		}
+
+		current, err := os.ReadFile(file)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		updated, err := replaceManagedBlock(string(current), renderClaudeMDBlock(project, entities))
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+
+		if updated == string(current) {
+			output(successStyle.Render("✓") + " " + file + " is up to date " + dimStyle.Render(fmt.Sprintf("(%d memories of %s)", count, project)))
+			return nil
+		}
+		if check {
+			return fmt.Errorf("%s is out of date with the memories of %s; run mark42 generate-claude-md --project %s", file, project, project)
+		}
+		if err := os.WriteFile(file, []byte(updated), 0o644); err != nil {
+			return err
+		}
+		output(successStyle.Render("✓") + " Updated " + file + " " + dimStyle.Render(fmt.Sprintf("(%d memories of %s)", count, project)))
+		return nil
+	},
+}
+
+func init() {
+	generateClaudeMDCmd.Flags().String("project", "", "container tag of the project's memories (default: the directory's name)")
+	generateClaudeMDCmd.Flags().String("file", "", "file to write the block into (default: CLAUDE.md in dir)")
+	generateClaudeMDCmd.Flags().Float64("min-importance", 0.7, "least importance of an unpinned memory to include")
+	generateClaudeMDCmd.Flags().Int("limit", 50, "most memories to include; 0 for no limit")
+	generateClaudeMDCmd.Flags().Bool("check", false, "write nothing; fail if the block is out of date")
+	rootCmd.AddCommand(generateClaudeMDCmd)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+func TestReplaceManagedBlock(t *testing.T) {
+	block := claudeMDBegin + "\nnew\n" + claudeMDEnd
+	for _, tc := range []struct{ doc, want string }{
+		{"", block + "\n"},
+		{"# App\n\nHand-written.\n", "# App\n\nHand-written.\n\n" + block + "\n"},
+		{"# App\n" + claudeMDBegin + "\nold\n" + claudeMDEnd + "\n\nAfter.\n", "# App\n" + block + "\n\nAfter.\n"},
+	} {
+		got, err := replaceManagedBlock(tc.doc, block)
+		if err != nil || got != tc.want {
+			t.Errorf("replaceManagedBlock(%q) = %q, %v; want %q", tc.doc, got, err, tc.want)
+		}
+	}
+	if _, err := replaceManagedBlock("# App\n"+claudeMDBegin+"\nold\n", block); err == nil {
+		t.Error("expected an unterminated block rejected")
+	}
+}
+
+func TestGenerateClaudeMDCommand(t *testing.T) {
+	useTestDB(t)
+	t.Setenv("HOME", t.TempDir())
+	dir := filepath.Join(t.TempDir(), "api")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "CLAUDE.md")
+	if err := os.WriteFile(file, []byte("# API\n\nRun make test before committing.\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	withStore(t, func(s *storage.Store) {
+		s.CreateEntityWithContainer("Go Errors", "convention", []string{"Wrap errors with %w"}, "api")
+		s.CreateEntityWithContainer("API Server", "project", nil, "api")
+		s.AddObservationWithType("API Server", "Listens on port\n8080", storage.FactTypeStatic)
+		s.CreateEntityWithContainer("Billing", "project", nil, "billing")
+		s.AddObservationWithType("Billing", "Bills monthly", storage.FactTypeStatic)
+	})
+
+	if got := runRootCmd(t, "generate-claude-md", dir); !strings.Contains(got, "Updated") || !strings.Contains(got, "2 memories of api") {
+		t.Errorf("expected CLAUDE.md updated:\n%s", got)
+	}
+	written, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := string(written)
+	for _, want := range []string{
+		"# API\n\nRun make test before committing.\n\n" + claudeMDBegin,
+		"### Go Errors (convention)\n\n- Wrap errors with %w\n",
+		"### API Server (project)\n\n- Listens on port 8080\n" + claudeMDEnd,
+	} {
+		if !strings.Contains(doc, want) {
+			t.Errorf("expected %q in:\n%s", want, doc)
+		}
+	}
+	if strings.Contains(doc, "Bills monthly") {
+		t.Errorf("expected another project's memories left out:\n%s", doc)
+	}
+
+	defer generateClaudeMDCmd.Flags().Set("check", "false")
+	if got := runRootCmd(t, "generate-claude-md", dir, "--check"); !strings.Contains(got, "is up to date") {
+		t.Errorf("expected the check to pass:\n%s", got)
+	}
+
+	// A new memory makes the check fail without touching the file
+	withStore(t, func(s *storage.Store) {
+		s.AddObservationWithType("API Server", "Deploys on Fridays", storage.FactTypeStatic)
+	})
+	var buf bytes.Buffer
+	oldOut := out
+	out = &buf
+	defer func() { out = oldOut }()
+	rootCmd.SetArgs([]string{"generate-claude-md", dir, "--check"})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "out of date") {
+		t.Errorf("expected the check to fail, got %v", err)
+	}
+	if after, _ := os.ReadFile(file); string(after) != doc {
+		t.Error("expected --check to write nothing")
+	}
+}
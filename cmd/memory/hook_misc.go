@@ -1,34 +1,94 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/spf13/cobra"
+
+	"github.com/mfenderov/mark42/internal/storage"
 )
 
+type preCompactInput struct {
+	TranscriptPath     string `json:"transcript_path"`
+	Trigger            string `json:"trigger"`
+	CustomInstructions string `json:"custom_instructions"`
+}
+
+type sessionEndInput struct {
+	TranscriptPath string `json:"transcript_path"`
+	Reason         string `json:"reason"`
+}
+
+func withSessionEndInput(input *sessionEndInput) hookOption {
+	return func(cfg *hookConfig) {
+		cfg.sessionEndInput = input
+	}
+}
+
+func withSummarizer(client *storage.SummaryClient) hookOption {
+	return func(cfg *hookConfig) {
+		cfg.summarizer = client
+	}
+}
+
+// summarizerFromEnv returns an LLM summary client when CLAUDE_MEMORY_SUMMARY_MODEL
+// is set, using the same OpenAI-compatible endpoint as embeddings.
+func summarizerFromEnv() *storage.SummaryClient {
+	model := os.Getenv("CLAUDE_MEMORY_SUMMARY_MODEL")
+	if model == "" {
+		return nil
+	}
+	baseURL := loadEffectiveConfig(configProjectDir()).EmbedderURL
+	if baseURL == "disabled" {
+		baseURL = storage.DefaultOllamaBaseURL()
+	}
+	return storage.NewSummaryClient(baseURL, model)
+}
+
+func withPreCompactInput(input *preCompactInput) hookOption {
+	return func(cfg *hookConfig) {
+		cfg.preCompactInput = input
+	}
+}
+
 var hookSessionEndCmd = &cobra.Command{
 	Use:   "session-end",
-	Short: "SessionEnd hook: silent stats collection",
+	Short: "SessionEnd hook: capture anything the Stop hook missed",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		projectDir := getProjectDir()
 		if projectDir == "" {
 			return nil
 		}
-		runSessionEndHook(projectDir)
+
+		var input sessionEndInput
+		_ = readStdinJSON(&input)
+
+		opts := []hookOption{withSessionEndInput(&input)}
+		if client := summarizerFromEnv(); client != nil {
+			opts = append(opts, withSummarizer(client))
+		}
+		runSessionEndHook(projectDir, opts...)
 		return nil
 	},
 }
 
 var hookPreCompactCmd = &cobra.Command{
 	Use:   "pre-compact",
-	Short: "PreCompact hook: report tracked files",
+	Short: "PreCompact hook: capture session state before compaction",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		projectDir := getProjectDir()
 		if projectDir == "" {
 			return nil
 		}
-		runPreCompactHook(projectDir)
+
+		var input preCompactInput
+		_ = readStdinJSON(&input)
+
+		runPreCompactHook(projectDir, withPreCompactInput(&input))
 		return nil
 	},
 }
@@ -38,9 +98,77 @@ func init() {
 	hookCmd.AddCommand(hookPreCompactCmd)
 }
 
+// runSessionEndHook captures whatever is still buffered when the session ends,
+// so sessions are recorded even when the agent never calls capture_session
+// and the Stop hook already fired. SessionEnd hooks must not produce output.
 func runSessionEndHook(projectDir string, opts ...hookOption) {
-	// SessionEnd hooks must not produce structured output.
-	// Stats are collected silently for future use.
+	cfg := &hookConfig{}
+	for _, o := range opts {
+		o(cfg)
+	}
+
+	if projectDir == "" {
+		return
+	}
+
+	m42 := mark42Dir(projectDir)
+	events := readJSONLines[storage.SessionEvent](filepath.Join(m42, "session-events"))
+	files := readLines(filepath.Join(m42, "dirty-files"))
+	if len(events) == 0 && len(files) == 0 {
+		return
+	}
+
+	var transcriptPath string
+	if cfg.sessionEndInput != nil {
+		transcriptPath = cfg.sessionEndInput.TranscriptPath
+	}
+
+	summary := buildAutoSummary(events, files, lastAssistantText(transcriptPath))
+	if cfg.summarizer != nil {
+		summary = summarizeSession(cfg.summarizer, summary, buildSessionDigest(transcriptPath))
+	}
+
+	captureSessionDirectly(projectDir, events, summary)
+
+	clearFile(filepath.Join(m42, "session-events"))
+	clearFile(filepath.Join(m42, "dirty-files"))
+}
+
+// checkpointSession captures the buffered events and dirty files as their own
+// session, then resets the buffers so the Stop hook only captures what happens
+// afterwards. Returns false when there was nothing to capture.
+func checkpointSession(projectDir, label, lastMsg string) bool {
+	m42 := mark42Dir(projectDir)
+	events := readJSONLines[storage.SessionEvent](filepath.Join(m42, "session-events"))
+	files := readLines(filepath.Join(m42, "dirty-files"))
+	if len(events) == 0 && len(files) == 0 && lastMsg == "" {
+		return false
+	}
+
+	summary := label + ". " + buildAutoSummary(events, files, lastMsg)
+	captureSessionDirectly(projectDir, events, summary)
+
+	clearFile(filepath.Join(m42, "session-events"))
+	clearFile(filepath.Join(m42, "dirty-files"))
+	return true
+}
+
+// summarizeSession asks the LLM for a summary of the tracked activity and
+// transcript digest, falling back to the heuristic summary on any error.
+func summarizeSession(client *storage.SummaryClient, heuristic, digest string) string {
+	notes := heuristic
+	if digest != "" {
+		notes += "\n\n" + digest
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	summary, err := client.Summarize(ctx, notes)
+	if err != nil {
+		return heuristic
+	}
+	return summary
 }
 
 func runPreCompactHook(projectDir string, opts ...hookOption) {
@@ -56,6 +184,20 @@ func runPreCompactHook(projectDir string, opts ...hookOption) {
 	projectName := filepath.Base(projectDir)
 	files := readLines(filepath.Join(mark42Dir(projectDir), "dirty-files"))
 
+	trigger := "auto"
+	var lastMsg string
+	if cfg.preCompactInput != nil {
+		if cfg.preCompactInput.Trigger != "" {
+			trigger = cfg.preCompactInput.Trigger
+		}
+		if cfg.preCompactInput.TranscriptPath != "" {
+			lastMsg = lastAssistantText(cfg.preCompactInput.TranscriptPath)
+		}
+	}
+
+	// Checkpoint what compaction is about to drop before it is lost
+	checkpointSession(projectDir, "Pre-compaction checkpoint ("+trigger+")", lastMsg)
+
 	output := map[string]any{
 		"hookSpecificOutput": map[string]any{
 			"memoriesPreserved": len(files),
@@ -2,14 +2,19 @@ package main
 
 import (
 	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/mfenderov/mark42/internal/storage"
 )
 
 func TestHookSessionEnd(t *testing.T) {
 	t.Run("produces no output", func(t *testing.T) {
+		useTestDB(t)
 		dir := setupProjectDir(t)
 
 		var buf captureBuffer
@@ -24,10 +29,101 @@ func TestHookSessionEnd(t *testing.T) {
 		var buf captureBuffer
 		runSessionEndHook("", withOutput(&buf))
 	})
+
+	t.Run("captures buffered activity and clears buffers", func(t *testing.T) {
+		useTestDB(t)
+		dir := setupProjectDir(t)
+		m42 := mark42Dir(dir)
+		os.WriteFile(filepath.Join(m42, "dirty-files"), []byte("src/main.go\n"), 0o644)
+		os.WriteFile(filepath.Join(m42, "session-events"),
+			[]byte(`{"toolName":"Bash","command":"go test ./..."}`+"\n"), 0o644)
+
+		var buf captureBuffer
+		runSessionEndHook(dir, withOutput(&buf))
+
+		if buf.String() != "" {
+			t.Errorf("session-end should produce no output, got: %s", buf.String())
+		}
+
+		session := onlySession(t, filepath.Base(dir))
+		if !strings.Contains(session.Summary, "main.go") || !strings.Contains(session.Summary, "Ran: go test ./...") {
+			t.Errorf("unexpected summary: %s", session.Summary)
+		}
+		if lines := readLines(filepath.Join(m42, "session-events")); len(lines) != 0 {
+			t.Errorf("session-events should be cleared, got %v", lines)
+		}
+	})
+
+	t.Run("uses LLM summary when configured", func(t *testing.T) {
+		useTestDB(t)
+		dir := setupProjectDir(t)
+		os.WriteFile(filepath.Join(mark42Dir(dir), "dirty-files"), []byte("src/main.go\n"), 0o644)
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "Reworked the CLI entry point."}}]}`))
+		}))
+		defer server.Close()
+
+		runSessionEndHook(dir, withSummarizer(storage.NewSummaryClient(server.URL, "test-model")))
+
+		if session := onlySession(t, filepath.Base(dir)); session.Summary != "Reworked the CLI entry point." {
+			t.Errorf("expected LLM summary, got: %s", session.Summary)
+		}
+	})
+
+	t.Run("falls back to heuristic summary when LLM fails", func(t *testing.T) {
+		useTestDB(t)
+		dir := setupProjectDir(t)
+		os.WriteFile(filepath.Join(mark42Dir(dir), "dirty-files"), []byte("src/main.go\n"), 0o644)
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "model not found", http.StatusNotFound)
+		}))
+		defer server.Close()
+
+		runSessionEndHook(dir, withSummarizer(storage.NewSummaryClient(server.URL, "missing")))
+
+		if session := onlySession(t, filepath.Base(dir)); !strings.Contains(session.Summary, "Modified 1 files") {
+			t.Errorf("expected heuristic summary, got: %s", session.Summary)
+		}
+	})
+}
+
+// onlySession returns the single session captured for project in the test database.
+func onlySession(t *testing.T, project string) *storage.Session {
+	t.Helper()
+	store, err := getStore()
+	if err != nil {
+		t.Fatalf("getStore failed: %v", err)
+	}
+	defer store.Close()
+
+	sessions, err := store.ListSessions(project, "", 10)
+	if err != nil {
+		t.Fatalf("ListSessions failed: %v", err)
+	}
+	if len(sessions) != 1 {
+		t.Fatalf("expected 1 captured session, got %d", len(sessions))
+	}
+	session, err := store.GetSession(sessions[0].Name)
+	if err != nil {
+		t.Fatalf("GetSession failed: %v", err)
+	}
+	return session
+}
+
+// useTestDB points the CLI at a throwaway database for the duration of the test.
+func useTestDB(t *testing.T) string {
+	t.Helper()
+	oldDBPath := dbPath
+	dbPath = filepath.Join(t.TempDir(), "test.db")
+	t.Cleanup(func() { dbPath = oldDBPath })
+	return dbPath
 }
 
 func TestHookPreCompact(t *testing.T) {
 	t.Run("outputs hookSpecificOutput with file count", func(t *testing.T) {
+		useTestDB(t)
 		dir := setupProjectDir(t)
 		os.WriteFile(filepath.Join(mark42Dir(dir), "dirty-files"),
 			[]byte("a.go\nb.go\nc.go\n"), 0o644)
@@ -56,6 +152,7 @@ func TestHookPreCompact(t *testing.T) {
 	})
 
 	t.Run("zero files produces zero count", func(t *testing.T) {
+		useTestDB(t)
 		dir := setupProjectDir(t)
 
 		var buf captureBuffer
@@ -69,4 +166,56 @@ func TestHookPreCompact(t *testing.T) {
 			t.Errorf("memoriesPreserved = %v, want 0", specific["memoriesPreserved"])
 		}
 	})
+
+	t.Run("captures a session and clears buffers", func(t *testing.T) {
+		useTestDB(t)
+		dir := setupProjectDir(t)
+		m42 := mark42Dir(dir)
+		os.WriteFile(filepath.Join(m42, "dirty-files"), []byte("src/main.go\n"), 0o644)
+		os.WriteFile(filepath.Join(m42, "session-events"),
+			[]byte(`{"toolName":"Edit","filePath":"src/main.go"}`+"\n"), 0o644)
+
+		transcript := filepath.Join(t.TempDir(), "transcript.jsonl")
+		os.WriteFile(transcript, []byte(
+			`{"type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"Refactored the parser"}]}}`+"\n"), 0o644)
+
+		var buf captureBuffer
+		runPreCompactHook(dir, withOutput(&buf), withPreCompactInput(&preCompactInput{
+			TranscriptPath: transcript,
+			Trigger:        "manual",
+		}))
+
+		session := onlySession(t, filepath.Base(dir))
+		summary := session.Summary
+		if !strings.Contains(summary, "Pre-compaction checkpoint (manual)") ||
+			!strings.Contains(summary, "main.go") ||
+			!strings.Contains(summary, "Refactored the parser") {
+			t.Errorf("unexpected summary: %s", summary)
+		}
+		if session.EventCount != 1 {
+			t.Errorf("EventCount = %d, want 1", session.EventCount)
+		}
+
+		if lines := readLines(filepath.Join(m42, "dirty-files")); len(lines) != 0 {
+			t.Errorf("dirty-files should be cleared, got %v", lines)
+		}
+		if lines := readLines(filepath.Join(m42, "session-events")); len(lines) != 0 {
+			t.Errorf("session-events should be cleared, got %v", lines)
+		}
+	})
+
+	t.Run("skips capture with nothing tracked", func(t *testing.T) {
+		useTestDB(t)
+		dir := setupProjectDir(t)
+
+		var buf captureBuffer
+		runPreCompactHook(dir, withOutput(&buf))
+
+		store, _ := getStore()
+		defer store.Close()
+		sessions, _ := store.ListSessions("", "", 10)
+		if len(sessions) != 0 {
+			t.Errorf("expected no sessions, got %d", len(sessions))
+		}
+	})
 }
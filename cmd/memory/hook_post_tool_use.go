@@ -3,11 +3,14 @@ package main
 import (
 	"encoding/json"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
+
+	"github.com/mfenderov/mark42/internal/storage"
 )
 
 type hookInput struct {
@@ -15,8 +18,89 @@ type hookInput struct {
 	ToolInput map[string]any `json:"tool_input"`
 }
 
+// Trigger modes control when tracked activity is captured:
+// default captures at Stop, gitmode only tracks git commits, threshold
+// checkpoints every EventThreshold events, interval every IntervalMinutes.
+const (
+	triggerDefault   = "default"
+	triggerGitMode   = "gitmode"
+	triggerThreshold = "threshold"
+	triggerInterval  = "interval"
+
+	defaultEventThreshold  = 50
+	defaultIntervalMinutes = 30
+)
+
 type pluginConfig struct {
-	TriggerMode string `json:"triggerMode"`
+	TriggerMode     string                      `json:"triggerMode"`
+	EventThreshold  int                         `json:"eventThreshold,omitempty"`
+	IntervalMinutes int                         `json:"intervalMinutes,omitempty"`
+	Context         storage.ContextOverrides    `json:"context"`
+	Importance      storage.ImportanceOverrides `json:"importance"`
+	Decay           decayOverrides              `json:"decay"`
+	// Attribute schemas by entity type; each replaces the built-in schema for
+	// its type, and an empty one lets the type take any attribute
+	AttributeSchemas map[string]storage.AttributeSchema `json:"attributeSchemas,omitempty"`
+	// User recorded as owner and author of writes to a shared database
+	User string `json:"user,omitempty"`
+	// AutoLink relates entities to the entities their new observations name
+	AutoLink autoLinkConfig `json:"autoLink"`
+	// Rules writes are checked against; a project's rules replace global ones
+	Rules *storage.Rules `json:"rules,omitempty"`
+	// Automation policies; a project's run after the global ones
+	Policies []storage.Policy `json:"policies,omitempty"`
+	// Canonical entity types and their descriptions, merged across layers
+	EntityTypes map[string]string `json:"entityTypes,omitempty"`
+	// Relation types with their inverses, merged across layers
+	RelationTypes map[string]storage.RelationTypeInfo `json:"relationTypes,omitempty"`
+	Search        searchConfig                        `json:"search"`
+	// Secret redaction on write; patterns add to the previous layer's
+	Redaction storage.Redaction `json:"redaction"`
+	PII       piiConfig         `json:"pii"`
+	Undo      undoConfig        `json:"undo"`
+	Backup    backupConfig      `json:"backup"`
+	Embedder  embedderConfig    `json:"embedder"`
+	// Which of the daemon's subsystems run, and how often
+	Daemon daemonOverrides `json:"daemon"`
+}
+
+// searchConfig overrides full-text search settings.
+type searchConfig struct {
+	Tokenizer tokenizerOverrides `json:"tokenizer"`
+	// Words dropped from queries; replaces the previous layer's list, and
+	// an empty list keeps every word
+	StopWords *[]string `json:"stopWords,omitempty"`
+}
+
+// autoLinkConfig turns on linking of mentioned entities; see
+// storage.SetAutoLinkMentions.
+type autoLinkConfig struct {
+	Enabled       *bool `json:"enabled,omitempty"`
+	MinNameLength *int  `json:"minNameLength,omitempty"` // Default 4
+}
+
+// piiConfig sets what writes of observations holding probable personal data
+// do: off (the default), warn, or strict.
+type piiConfig struct {
+	Mode storage.PIIMode `json:"mode,omitempty"`
+}
+
+// undoConfig sets how long destructive operations can be undone.
+type undoConfig struct {
+	WindowHours *int `json:"windowHours,omitempty"` // Default 24; 0 keeps nothing to undo
+}
+
+// backupConfig sets where snapshots and backups of the database are written.
+type backupConfig struct {
+	Dir string `json:"dir,omitempty"` // Default: mark42/backups next to the database
+	// Copy the server keeps up to date, and backup run and verify default to
+	Replica string `json:"replica,omitempty"`
+}
+
+// embedderConfig sets the embedding endpoint and model.
+type embedderConfig struct {
+	URL   string `json:"url,omitempty"`   // Or "disabled"; CLAUDE_MEMORY_EMBEDDER_URL overrides
+	Model string `json:"model,omitempty"` // Default nomic-embed-text
 }
 
 var hookPostToolUseCmd = &cobra.Command{
@@ -43,20 +127,26 @@ func init() {
 }
 
 func loadPluginConfig(projectDir string) pluginConfig {
-	data, err := os.ReadFile(filepath.Join(mark42Dir(projectDir), "config.json"))
+	cfg, err := readPluginConfig(filepath.Join(mark42Dir(projectDir), "config.json"))
 	if err != nil {
-		return pluginConfig{TriggerMode: "default"}
-	}
-	var cfg pluginConfig
-	if err := json.Unmarshal(data, &cfg); err != nil {
-		return pluginConfig{TriggerMode: "default"}
+		return pluginConfig{TriggerMode: triggerDefault}
 	}
 	if cfg.TriggerMode == "" {
-		cfg.TriggerMode = "default"
+		cfg.TriggerMode = triggerDefault
 	}
 	return cfg
 }
 
+func readPluginConfig(path string) (pluginConfig, error) {
+	var cfg pluginConfig
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	err = json.Unmarshal(data, &cfg)
+	return cfg, err
+}
+
 func runPostToolUseHook(projectDir string, input hookInput) {
 	cfg := loadPluginConfig(projectDir)
 
@@ -69,7 +159,7 @@ func runPostToolUseHook(projectDir string, input hookInput) {
 		}
 	}
 
-	if cfg.TriggerMode == "gitmode" && !isGitCommit {
+	if cfg.TriggerMode == triggerGitMode && !isGitCommit {
 		return
 	}
 
@@ -151,25 +241,67 @@ func runPostToolUseHook(projectDir string, input hookInput) {
 		_ = os.WriteFile(dirtyPath, []byte(sb.String()), 0o644)
 	}
 
+	maybeCheckpoint(projectDir, cfg, time.Now())
+
 	// CRITICAL: zero stdout output
 }
 
-func shouldTrack(filePath, projectDir string) bool {
-	if !strings.HasPrefix(filePath, projectDir) {
-		return false
+// maybeCheckpoint captures a session checkpoint when the threshold or interval
+// trigger mode says one is due.
+func maybeCheckpoint(projectDir string, cfg pluginConfig, now time.Time) {
+	m42 := mark42Dir(projectDir)
+
+	switch cfg.TriggerMode {
+	case triggerThreshold:
+		threshold := cfg.EventThreshold
+		if threshold <= 0 {
+			threshold = defaultEventThreshold
+		}
+		if len(readLines(filepath.Join(m42, "session-events"))) >= threshold {
+			checkpointSession(projectDir, "Checkpoint after "+itoa(threshold)+" events", "")
+		}
+
+	case triggerInterval:
+		interval := time.Duration(cfg.IntervalMinutes) * time.Minute
+		if interval <= 0 {
+			interval = defaultIntervalMinutes * time.Minute
+		}
+		markerPath := filepath.Join(m42, "last-checkpoint")
+		last, err := readCheckpointTime(markerPath)
+		if err != nil {
+			// First tracked event of the session starts the clock
+			_ = os.WriteFile(markerPath, []byte(now.UTC().Format(time.RFC3339)), 0o644)
+			return
+		}
+		if now.Sub(last) >= interval {
+			checkpointSession(projectDir, "Checkpoint after "+itoa(int(interval.Minutes()))+" minutes", "")
+			_ = os.WriteFile(markerPath, []byte(now.UTC().Format(time.RFC3339)), 0o644)
+		}
 	}
+}
 
-	rel, err := filepath.Rel(projectDir, filePath)
+func readCheckpointTime(path string) (time.Time, error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339, strings.TrimSpace(string(data)))
+}
+
+func shouldTrack(filePath, projectDir string) bool {
+	if !isWithinDir(filePath, projectDir) {
 		return false
 	}
 
-	parts := strings.SplitN(rel, string(filepath.Separator), 2)
-	if len(parts) > 0 && parts[0] == ".claude" {
+	fp, dir := normalizeHookPath(filePath), normalizeHookPath(projectDir)
+	rel := strings.TrimPrefix(strings.TrimPrefix(fp, dir), "/")
+
+	parts := strings.SplitN(rel, "/", 2)
+	if len(parts) > 0 && strings.EqualFold(parts[0], ".claude") {
 		return false
 	}
 
-	if filepath.Base(filePath) == "CLAUDE.md" {
+	if strings.EqualFold(path.Base(fp), "CLAUDE.md") {
 		return false
 	}
 
@@ -257,10 +389,7 @@ func extractFilesFromBash(command, projectDir string) []string {
 
 	var resolved []string
 	for _, f := range files {
-		if !filepath.IsAbs(f) {
-			f = filepath.Join(projectDir, f)
-		}
-		resolved = append(resolved, filepath.Clean(f))
+		resolved = append(resolved, resolveHookPath(projectDir, f))
 	}
 	return resolved
 }
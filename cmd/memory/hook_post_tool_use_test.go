@@ -4,8 +4,10 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestShouldTrack(t *testing.T) {
@@ -20,6 +22,14 @@ func TestShouldTrack(t *testing.T) {
 		{"CLAUDE.md at root", "/proj/CLAUDE.md", "/proj", false},
 		{"CLAUDE.md nested", "/proj/sub/CLAUDE.md", "/proj", false},
 		{"outside project", "/other/file.go", "/proj", false},
+		{"sibling with shared prefix", "/proj2/file.go", "/proj", false},
+		{"windows file", `C:\Users\dev\proj\main.go`, `C:\Users\dev\proj`, true},
+		{"windows mixed separators", `C:/Users/dev/proj\src\main.go`, `C:\Users\dev\proj`, true},
+		{"windows drive letter case", `c:\users\dev\proj\main.go`, `C:\Users\dev\proj`, true},
+		{"windows .claude dir", `C:\Users\dev\proj\.claude\mark42\config.json`, `C:\Users\dev\proj`, false},
+		{"windows CLAUDE.md", `C:\Users\dev\proj\sub\CLAUDE.md`, `C:\Users\dev\proj`, false},
+		{"windows sibling", `C:\Users\dev\proj2\main.go`, `C:\Users\dev\proj`, false},
+		{"windows other drive", `D:\Users\dev\proj\main.go`, `C:\Users\dev\proj`, false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -129,6 +139,30 @@ func TestExtractFilesFromBash(t *testing.T) {
 	}
 }
 
+func TestExtractFilesFromBash_Windows(t *testing.T) {
+	proj := `C:\Users\dev\proj`
+	tests := []struct {
+		name    string
+		command string
+		want    []string
+	}{
+		{"rm relative", "rm old.go", []string{`C:\Users\dev\proj\old.go`}},
+		{"rm nested relative", "rm src/old.go", []string{`C:\Users\dev\proj\src\old.go`}},
+		{"rm absolute backslashes", `rm C:\Users\dev\proj\old.go`, []string{`C:\Users\dev\proj\old.go`}},
+		{"rm absolute forward slashes", "rm C:/Users/dev/proj/old.go", []string{`C:\Users\dev\proj\old.go`}},
+		{"git rm dot segments", `git rm .\src\..\old.go`, []string{`C:\Users\dev\proj\old.go`}},
+		{"rm UNC path", `rm \\server\share\old.go`, []string{`\\server\share\old.go`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := extractFilesFromBash(tt.command, proj)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("extractFilesFromBash(%q) = %v, want %v", tt.command, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestPostToolUseHook(t *testing.T) {
 	t.Run("tracks Edit file", func(t *testing.T) {
 		dir := setupProjectDir(t)
@@ -253,6 +287,70 @@ func TestPostToolUseHook(t *testing.T) {
 		}
 	})
 
+	t.Run("threshold mode checkpoints after N events", func(t *testing.T) {
+		useTestDB(t)
+		dir := setupProjectDir(t)
+		configDir := mark42Dir(dir)
+		os.WriteFile(filepath.Join(configDir, "config.json"),
+			[]byte(`{"triggerMode":"threshold","eventThreshold":3}`), 0o644)
+
+		input := hookInput{
+			ToolName:  "Edit",
+			ToolInput: map[string]any{"file_path": filepath.Join(dir, "a.go")},
+		}
+		for i := 0; i < 2; i++ {
+			runPostToolUseHook(dir, input)
+		}
+		if events := readLines(filepath.Join(configDir, "session-events")); len(events) != 2 {
+			t.Fatalf("expected 2 buffered events before threshold, got %d", len(events))
+		}
+
+		runPostToolUseHook(dir, input)
+
+		if events := readLines(filepath.Join(configDir, "session-events")); len(events) != 0 {
+			t.Errorf("expected buffer cleared after checkpoint, got %d events", len(events))
+		}
+		session := onlySession(t, filepath.Base(dir))
+		if !strings.Contains(session.Summary, "Checkpoint after 3 events") {
+			t.Errorf("unexpected checkpoint session: %+v", session)
+		}
+	})
+
+	t.Run("interval mode checkpoints once the interval elapses", func(t *testing.T) {
+		useTestDB(t)
+		dir := setupProjectDir(t)
+		configDir := mark42Dir(dir)
+		cfg := pluginConfig{TriggerMode: triggerInterval, IntervalMinutes: 10}
+		start := time.Now()
+
+		writeEvent := func() {
+			os.WriteFile(filepath.Join(configDir, "session-events"),
+				[]byte(`{"toolName":"Edit","filePath":"a.go"}`+"\n"), 0o644)
+		}
+
+		writeEvent()
+		maybeCheckpoint(dir, cfg, start) // starts the clock
+		maybeCheckpoint(dir, cfg, start.Add(5*time.Minute))
+		if events := readLines(filepath.Join(configDir, "session-events")); len(events) != 1 {
+			t.Fatalf("expected no checkpoint before interval, got %d events buffered", len(events))
+		}
+
+		maybeCheckpoint(dir, cfg, start.Add(11*time.Minute))
+		if events := readLines(filepath.Join(configDir, "session-events")); len(events) != 0 {
+			t.Errorf("expected checkpoint after interval, got %d events buffered", len(events))
+		}
+		if session := onlySession(t, filepath.Base(dir)); !strings.Contains(session.Summary, "Checkpoint after 10 minutes") {
+			t.Errorf("unexpected checkpoint summary: %s", session.Summary)
+		}
+
+		// The clock restarts from the last checkpoint
+		writeEvent()
+		maybeCheckpoint(dir, cfg, start.Add(15*time.Minute))
+		if events := readLines(filepath.Join(configDir, "session-events")); len(events) != 1 {
+			t.Errorf("expected no second checkpoint yet, got %d events buffered", len(events))
+		}
+	})
+
 	t.Run("read-only Bash writes event but no dirty files", func(t *testing.T) {
 		dir := setupProjectDir(t)
 
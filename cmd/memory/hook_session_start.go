@@ -13,8 +13,11 @@ import (
 type hookOption func(*hookConfig)
 
 type hookConfig struct {
-	writer    *captureBuffer
-	stopInput *stopInput
+	writer          *captureBuffer
+	stopInput       *stopInput
+	preCompactInput *preCompactInput
+	sessionEndInput *sessionEndInput
+	summarizer      *storage.SummaryClient
 }
 
 type captureBuffer struct {
@@ -75,6 +78,7 @@ func runSessionStartHook(projectDir string, store *storage.Store, opts ...hookOp
 	}
 
 	clearFlag(filepath.Join(mark42Dir(projectDir), "stop-prompted"))
+	clearFlag(filepath.Join(mark42Dir(projectDir), "last-checkpoint"))
 
 	if store == nil {
 		return
@@ -95,12 +99,24 @@ func runSessionStartHook(projectDir string, store *storage.Store, opts ...hookOp
 	// Knowledge graph context
 	ctxCfg := storage.DefaultContextConfig()
 	ctxCfg.TokenBudget = 1500
+	ctxCfg = loadEffectiveConfig(projectDir).ContextConfig(ctxCfg)
 	ctxResults, err := store.GetContextForInjection(ctxCfg, projectName)
-	if err == nil && len(ctxResults) > 0 {
-		formatted := storage.FormatContextResults(ctxResults)
+	if err == nil {
+		formatted := ""
+		if len(ctxResults) > 0 {
+			_ = store.RecordContextAccess(ctxResults)
+			formatted = storage.FormatContextResults(ctxResults)
+		}
 		if formatted != "" {
 			parts = append(parts, strings.TrimSpace(formatted))
 		}
+		_ = store.LogContextInjection(storage.ContextCall{
+			Source:        storage.SourceHook,
+			Project:       projectName,
+			TokenBudget:   ctxCfg.TokenBudget,
+			MinImportance: ctxCfg.MinImportance,
+			TokensUsed:    store.CountTokens(formatted),
+		}, ctxResults) // Opt-in; see the context.log setting
 	}
 
 	if len(parts) == 0 {
@@ -108,7 +124,7 @@ func runSessionStartHook(projectDir string, store *storage.Store, opts ...hookOp
 	}
 
 	combined := strings.Join(parts, "\n\n")
-	estimatedTokens := storage.EstimateTokens(combined)
+	estimatedTokens := store.CountTokens(combined)
 
 	hookPrintf(cfg, "=== mark42: %s ===\n", projectName)
 	hookPrintf(cfg, "[%d estimated tokens]\n\n", estimatedTokens)
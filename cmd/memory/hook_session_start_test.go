@@ -97,3 +97,24 @@ func containsStr(s, sub string) bool {
 	}
 	return false
 }
+
+func TestLoadPluginConfig_Context(t *testing.T) {
+	dir := t.TempDir()
+	m42 := mark42Dir(dir)
+	os.MkdirAll(m42, 0o755)
+	os.WriteFile(filepath.Join(m42, "config.json"), []byte(`{
+		"triggerMode": "gitmode",
+		"context": {"tokenBudget": 800, "budgetShares": {"static": 0.6, "dynamic": 0.4}}
+	}`), 0o644)
+
+	cfg := loadPluginConfig(dir)
+	if cfg.TriggerMode != "gitmode" {
+		t.Errorf("TriggerMode = %q, want gitmode", cfg.TriggerMode)
+	}
+	if cfg.Context.TokenBudget != 800 {
+		t.Errorf("Context.TokenBudget = %d, want 800", cfg.Context.TokenBudget)
+	}
+	if cfg.Context.BudgetShares["static"] != 0.6 {
+		t.Errorf("Context.BudgetShares[static] = %v, want 0.6", cfg.Context.BudgetShares["static"])
+	}
+}
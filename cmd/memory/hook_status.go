@@ -0,0 +1,159 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// hookStateFiles are the files hooks write under .claude/mark42.
+var hookStateFiles = []string{
+	"session-events",
+	"dirty-files",
+	"stop-prompted",
+	"last-checkpoint",
+	"last-capture",
+	"active-session",
+	"session-digest.md",
+}
+
+// hookStatus is a snapshot of the hook state for a project.
+type hookStatus struct {
+	StateDir        string
+	TriggerMode     string
+	EventThreshold  int
+	IntervalMinutes int
+	PendingEvents   int
+	DirtyFiles      int
+	ActiveSession   string
+	LastCapture     time.Time // Zero when nothing has been captured yet
+	Problems        []string  // State files or directories hooks cannot write
+	StateDirMissing bool      // No hook has run in the project yet
+}
+
+func lastCapturePath(projectDir string) string {
+	return filepath.Join(mark42Dir(projectDir), "last-capture")
+}
+
+// recordCapture remembers when hooks last stored a session, for hook status.
+func recordCapture(projectDir string, now time.Time) {
+	_ = os.WriteFile(lastCapturePath(projectDir), []byte(now.UTC().Format(time.RFC3339)), 0o644)
+}
+
+func collectHookStatus(projectDir string) hookStatus {
+	cfg := loadPluginConfig(projectDir)
+	m42 := mark42Dir(projectDir)
+
+	status := hookStatus{
+		StateDir:        m42,
+		TriggerMode:     cfg.TriggerMode,
+		EventThreshold:  cfg.EventThreshold,
+		IntervalMinutes: cfg.IntervalMinutes,
+		PendingEvents:   len(readLines(filepath.Join(m42, "session-events"))),
+		DirtyFiles:      len(readLines(filepath.Join(m42, "dirty-files"))),
+	}
+	if status.EventThreshold <= 0 {
+		status.EventThreshold = defaultEventThreshold
+	}
+	if status.IntervalMinutes <= 0 {
+		status.IntervalMinutes = defaultIntervalMinutes
+	}
+	if lines := readLines(activeSessionPath(projectDir)); len(lines) > 0 {
+		status.ActiveSession = lines[0]
+	}
+	if t, err := readCheckpointTime(lastCapturePath(projectDir)); err == nil {
+		status.LastCapture = t
+	}
+
+	if info, err := os.Stat(m42); err != nil || !info.IsDir() {
+		status.StateDirMissing = true
+		return status
+	}
+	status.Problems = checkHookStateWritable(m42)
+	return status
+}
+
+// checkHookStateWritable verifies hooks can create files in the state
+// directory and append to the state files that already exist. It only looks:
+// a missing directory is reported in hookStatus.StateDirMissing, as the first
+// hook to run creates it.
+func checkHookStateWritable(m42 string) []string {
+	var problems []string
+	if err := checkWritable(m42); err != nil {
+		problems = append(problems, m42+": "+err.Error())
+	}
+	for _, name := range hookStateFiles {
+		path := filepath.Join(m42, name)
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err := checkWritable(path); err != nil {
+			problems = append(problems, path+": "+err.Error())
+		}
+	}
+	return problems
+}
+
+var hookStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show hook trigger mode, buffered activity, and state health",
+	Long: `Show the hook state for the current project: trigger mode, pending session
+events, dirty files, the active session, and when a session was last captured.
+Also checks, without writing anything, that the files under .claude/mark42
+are writable.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		status := collectHookStatus(configProjectDir())
+
+		trigger := status.TriggerMode
+		switch trigger {
+		case triggerThreshold:
+			trigger += " (every " + itoa(status.EventThreshold) + " events)"
+		case triggerInterval:
+			trigger += " (every " + itoa(status.IntervalMinutes) + " minutes)"
+		}
+
+		lastCapture := "never"
+		if !status.LastCapture.IsZero() {
+			lastCapture = status.LastCapture.Local().Format("2006-01-02 15:04:05")
+		}
+
+		activeSession := "none"
+		if status.ActiveSession != "" {
+			activeSession = status.ActiveSession
+		}
+
+		output(titleStyle.Render("Hook Status"))
+		output()
+		stateDir := status.StateDir
+		if status.StateDirMissing {
+			stateDir += " (missing; the first hook to run creates it)"
+		}
+		output("  " + dimStyle.Render("State dir:") + "      " + stateDir)
+		output("  " + dimStyle.Render("Trigger mode:") + "   " + trigger)
+		output("  " + dimStyle.Render("Pending events:") + " " + itoa(status.PendingEvents))
+		output("  " + dimStyle.Render("Dirty files:") + "    " + itoa(status.DirtyFiles))
+		output("  " + dimStyle.Render("Active session:") + " " + activeSession)
+		output("  " + dimStyle.Render("Last capture:") + "   " + lastCapture)
+		output()
+
+		if len(status.Problems) > 0 {
+			for _, p := range status.Problems {
+				output("  " + p)
+			}
+			return errors.New("hook state is not writable")
+		}
+		if status.StateDirMissing {
+			output(dimStyle.Render("No hook state yet"))
+			return nil
+		}
+		output(successStyle.Render("✓ Hook state is writable"))
+		return nil
+	},
+}
+
+func init() {
+	hookCmd.AddCommand(hookStatusCmd)
+}
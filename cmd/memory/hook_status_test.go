@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCollectHookStatus(t *testing.T) {
+	t.Run("empty project", func(t *testing.T) {
+		dir := setupProjectDir(t)
+
+		status := collectHookStatus(dir)
+		if status.TriggerMode != triggerDefault {
+			t.Errorf("TriggerMode = %q, want %q", status.TriggerMode, triggerDefault)
+		}
+		if status.PendingEvents != 0 || status.DirtyFiles != 0 {
+			t.Errorf("got %d events, %d files, want none", status.PendingEvents, status.DirtyFiles)
+		}
+		if !status.LastCapture.IsZero() {
+			t.Errorf("LastCapture = %v, want zero", status.LastCapture)
+		}
+		if len(status.Problems) != 0 {
+			t.Errorf("Problems = %v, want none", status.Problems)
+		}
+	})
+
+	t.Run("counts buffered state", func(t *testing.T) {
+		dir := setupProjectDir(t)
+		writeConfig(t, dir, `{"triggerMode": "threshold", "eventThreshold": 10}`)
+		m42 := mark42Dir(dir)
+		os.WriteFile(filepath.Join(m42, "session-events"), []byte("{}\n{}\n{}\n"), 0o644)
+		os.WriteFile(filepath.Join(m42, "dirty-files"), []byte("/a.go\n/b.go\n"), 0o644)
+		os.WriteFile(activeSessionPath(dir), []byte("session-1\n"), 0o644)
+		captured := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+		recordCapture(dir, captured)
+
+		status := collectHookStatus(dir)
+		if status.TriggerMode != triggerThreshold || status.EventThreshold != 10 {
+			t.Errorf("trigger = %q/%d, want threshold/10", status.TriggerMode, status.EventThreshold)
+		}
+		if status.PendingEvents != 3 {
+			t.Errorf("PendingEvents = %d, want 3", status.PendingEvents)
+		}
+		if status.DirtyFiles != 2 {
+			t.Errorf("DirtyFiles = %d, want 2", status.DirtyFiles)
+		}
+		if status.ActiveSession != "session-1" {
+			t.Errorf("ActiveSession = %q, want session-1", status.ActiveSession)
+		}
+		if !status.LastCapture.Equal(captured) {
+			t.Errorf("LastCapture = %v, want %v", status.LastCapture, captured)
+		}
+	})
+
+	t.Run("missing state dir", func(t *testing.T) {
+		dir := t.TempDir()
+
+		status := collectHookStatus(dir)
+		if !status.StateDirMissing || len(status.Problems) != 0 {
+			t.Errorf("got missing=%v problems=%v, want the missing directory reported", status.StateDirMissing, status.Problems)
+		}
+		if _, err := os.Stat(mark42Dir(dir)); !os.IsNotExist(err) {
+			t.Errorf("expected hook status to leave the state dir uncreated, got %v", err)
+		}
+	})
+
+	t.Run("reports read-only state files", func(t *testing.T) {
+		if os.Getuid() == 0 {
+			t.Skip("root can write read-only files")
+		}
+		dir := setupProjectDir(t)
+		path := filepath.Join(mark42Dir(dir), "dirty-files")
+		os.WriteFile(path, []byte("/a.go\n"), 0o444)
+
+		status := collectHookStatus(dir)
+		if len(status.Problems) != 1 || !strings.HasPrefix(status.Problems[0], path) {
+			t.Errorf("Problems = %v, want one for %s", status.Problems, path)
+		}
+	})
+}
+
+func TestCaptureRecordsLastCapture(t *testing.T) {
+	useTestDB(t)
+	dir := setupProjectDir(t)
+	os.WriteFile(filepath.Join(mark42Dir(dir), "session-events"),
+		[]byte(`{"toolName":"Edit","filePath":"/a.go"}`+"\n"), 0o644)
+
+	runSessionEndHook(dir)
+
+	if status := collectHookStatus(dir); status.LastCapture.IsZero() {
+		t.Error("expected last capture time after session-end capture")
+	}
+}
+
+func TestHookStatusCommand(t *testing.T) {
+	dir := setupProjectDir(t)
+	t.Setenv("CLAUDE_PROJECT_DIR", dir)
+
+	var buf bytes.Buffer
+	oldOut := out
+	out = &buf
+	defer func() { out = oldOut }()
+
+	if err := hookStatusCmd.RunE(hookStatusCmd, nil); err != nil {
+		t.Fatalf("hook status failed: %v", err)
+	}
+
+	got := buf.String()
+	for _, want := range []string{"Trigger mode:", "default", "Last capture:", "never", "writable"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -63,8 +64,6 @@ func runStopHook(projectDir string, opts ...hookOption) {
 		return
 	}
 
-	projectName := filepath.Base(projectDir)
-
 	// Read session events
 	type eventEntry struct {
 		ToolName  string `json:"toolName"`
@@ -93,7 +92,7 @@ func runStopHook(projectDir string, opts ...hookOption) {
 	}
 
 	// Capture session directly in SQLite (silent, no blocking)
-	captureSessionDirectly(projectName, events, files, lastMsg)
+	captureSessionDirectly(projectDir, events, buildAutoSummary(events, files, lastMsg))
 
 	// Clear both buffers (deterministic cleanup — don't rely on agent)
 	clearFile(filepath.Join(m42, "session-events"))
@@ -119,6 +118,7 @@ const (
 	maxMessageLen = 500
 	maxDigestSize = 30 * 1024
 	maxContextLen = 200
+	maxCommandLen = 60
 )
 
 func buildSessionDigest(transcriptPath string) string {
@@ -177,6 +177,30 @@ func buildSessionDigest(transcriptPath string) string {
 	return result
 }
 
+// lastAssistantText returns the text of the final assistant message in a transcript.
+func lastAssistantText(transcriptPath string) string {
+	f, err := os.Open(transcriptPath)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	var last string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 256*1024), 1024*1024)
+
+	for scanner.Scan() {
+		var msg transcriptMessage
+		if json.Unmarshal(scanner.Bytes(), &msg) != nil || msg.Type != "assistant" {
+			continue
+		}
+		if text := extractAssistantText(msg.Message); text != "" {
+			last = text
+		}
+	}
+	return last
+}
+
 type transcriptMessage struct {
 	Type    string          `json:"type"`
 	Message json.RawMessage `json:"message"`
@@ -188,8 +212,10 @@ type messageEnvelope struct {
 }
 
 type contentBlock struct {
-	Type string `json:"type"`
-	Text string `json:"text"`
+	Type  string         `json:"type"`
+	Text  string         `json:"text"`
+	Name  string         `json:"name,omitempty"`  // Tool of a tool_use block
+	Input map[string]any `json:"input,omitempty"` // Arguments of a tool_use block
 }
 
 func extractUserText(raw json.RawMessage) string {
@@ -235,17 +261,19 @@ func truncate(s string, maxLen int) string {
 	return s[:maxLen] + "..."
 }
 
-func captureSessionDirectly[E any](projectName string, events []E, files []string, lastMsg string) {
+func captureSessionDirectly[E any](projectDir string, events []E, summary string) {
 	store, err := getStore()
 	if err != nil {
 		return // fail silently
 	}
 	defer store.Close()
 
-	session, err := store.CreateSession(projectName)
+	// Complete the session the user-prompt hook opened, if any
+	session, err := activeSession(store, projectDir)
 	if err != nil {
 		return
 	}
+	clearFlag(activeSessionPath(projectDir))
 
 	// Store each event as observation
 	for _, evt := range events {
@@ -260,9 +288,9 @@ func captureSessionDirectly[E any](projectName string, events []E, files []strin
 		_ = store.CaptureSessionEvent(session.Name, se)
 	}
 
-	// Auto-generate summary from events and files
-	summary := buildAutoSummary(events, files, lastMsg)
-	_ = store.CompleteSession(session.Name, summary)
+	if err := store.CompleteSession(session.Name, summary); err == nil {
+		recordCapture(projectDir, time.Now())
+	}
 }
 
 func buildAutoSummary[E any](events []E, files []string, lastMsg string) string {
@@ -298,13 +326,18 @@ func buildAutoSummary[E any](events []E, files []string, lastMsg string) string
 	// Count tool usage
 	type eventEntry struct {
 		ToolName string `json:"toolName"`
+		Command  string `json:"command,omitempty"`
 	}
 	toolCounts := map[string]int{}
+	var commands []string
 	for _, evt := range events {
 		raw, _ := json.Marshal(evt)
 		var e eventEntry
 		if json.Unmarshal(raw, &e) == nil && e.ToolName != "" {
 			toolCounts[e.ToolName]++
+			if e.Command != "" {
+				commands = append(commands, truncate(e.Command, maxCommandLen))
+			}
 		}
 	}
 	if len(toolCounts) > 0 {
@@ -315,6 +348,15 @@ func buildAutoSummary[E any](events []E, files []string, lastMsg string) string
 		parts = append(parts, fmt.Sprintf("%d tool calls (%s)", len(events), strings.Join(tools, ", ")))
 	}
 
+	// List commands run
+	if len(commands) > 0 {
+		if len(commands) <= 3 {
+			parts = append(parts, "Ran: "+strings.Join(commands, "; "))
+		} else {
+			parts = append(parts, fmt.Sprintf("Ran: %s; +%d more", strings.Join(commands[:3], "; "), len(commands)-3))
+		}
+	}
+
 	// Add session context from last assistant message
 	if lastMsg != "" {
 		parts = append(parts, "Session context: "+truncate(lastMsg, maxContextLen))
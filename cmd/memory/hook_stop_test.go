@@ -6,6 +6,8 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/mfenderov/mark42/internal/storage"
 )
 
 func TestBuildSessionDigest(t *testing.T) {
@@ -247,6 +249,20 @@ func TestBuildAutoSummaryWithContext(t *testing.T) {
 			t.Errorf("summary should not contain session context when empty, got: %s", summary)
 		}
 	})
+
+	t.Run("lists commands run", func(t *testing.T) {
+		events := []storage.SessionEvent{
+			{ToolName: "Bash", Command: "go build ./..."},
+			{ToolName: "Bash", Command: "go vet ./..."},
+			{ToolName: "Bash", Command: "go test ./..."},
+			{ToolName: "Bash", Command: "git status"},
+		}
+
+		summary := buildAutoSummary(events, nil, "")
+		if !strings.Contains(summary, "Ran: go build ./...; go vet ./...; go test ./...; +1 more") {
+			t.Errorf("summary should list commands, got: %s", summary)
+		}
+	})
 }
 
 func TestHookStop(t *testing.T) {
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+type userPromptInput struct {
+	Prompt string `json:"prompt"`
+}
+
+const maxPromptLen = 500
+
+var hookUserPromptCmd = &cobra.Command{
+	Use:   "user-prompt",
+	Short: "UserPromptSubmit hook: record the prompt as a session turn",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		projectDir := getProjectDir()
+		if projectDir == "" {
+			return nil
+		}
+
+		var input userPromptInput
+		if err := readStdinJSON(&input); err != nil {
+			return nil
+		}
+
+		runUserPromptHook(projectDir, input)
+		return nil
+	},
+}
+
+func init() {
+	hookCmd.AddCommand(hookUserPromptCmd)
+}
+
+// runUserPromptHook stores the prompt on the active session so recent context
+// can recall what was discussed. UserPromptSubmit stdout is injected into the
+// conversation, so this must stay silent.
+func runUserPromptHook(projectDir string, input userPromptInput) {
+	prompt := strings.TrimSpace(input.Prompt)
+	if prompt == "" {
+		return
+	}
+
+	store, err := getStore()
+	if err != nil {
+		return
+	}
+	defer store.Close()
+
+	session, err := activeSession(store, projectDir)
+	if err != nil {
+		return
+	}
+
+	// Redact before truncating, so a secret cut short is not missed
+	prompt, _ = store.RedactSecrets(prompt)
+	_ = store.CaptureSessionTurn(session.Name, "User: "+truncate(prompt, maxPromptLen))
+}
+
+func activeSessionPath(projectDir string) string {
+	return filepath.Join(mark42Dir(projectDir), "active-session")
+}
+
+// activeSession returns the session recorded in the active-session state file,
+// creating and recording a new one when there is none or it has completed.
+func activeSession(store *storage.Store, projectDir string) (*storage.Session, error) {
+	path := activeSessionPath(projectDir)
+	if lines := readLines(path); len(lines) > 0 {
+		if session, err := store.GetSession(lines[0]); err == nil && session.Status == "active" {
+			return session, nil
+		}
+	}
+
+	session, err := store.CreateSession(filepath.Base(projectDir))
+	if err != nil {
+		return nil, err
+	}
+	_ = os.MkdirAll(filepath.Dir(path), 0o755)
+	_ = os.WriteFile(path, []byte(session.Name+"\n"), 0o644)
+	return session, nil
+}
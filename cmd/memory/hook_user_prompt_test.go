@@ -0,0 +1,79 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHookUserPrompt(t *testing.T) {
+	t.Run("records prompts as turns on one active session", func(t *testing.T) {
+		useTestDB(t)
+		dir := setupProjectDir(t)
+
+		runUserPromptHook(dir, userPromptInput{Prompt: "Add retries to the fetcher"})
+		runUserPromptHook(dir, userPromptInput{Prompt: "Now cover it with tests"})
+
+		session := onlySession(t, filepath.Base(dir))
+		if session.Status != "active" || session.TurnCount != 2 {
+			t.Errorf("expected active session with 2 turns, got %+v", session)
+		}
+	})
+
+	t.Run("ignores empty prompts", func(t *testing.T) {
+		useTestDB(t)
+		dir := setupProjectDir(t)
+
+		runUserPromptHook(dir, userPromptInput{Prompt: "   "})
+
+		if _, err := os.Stat(activeSessionPath(dir)); err == nil {
+			t.Error("empty prompt should not open a session")
+		}
+	})
+
+	t.Run("capture completes the active session", func(t *testing.T) {
+		useTestDB(t)
+		dir := setupProjectDir(t)
+		os.WriteFile(filepath.Join(mark42Dir(dir), "dirty-files"), []byte("src/main.go\n"), 0o644)
+
+		runUserPromptHook(dir, userPromptInput{Prompt: "Refactor main"})
+		runSessionEndHook(dir)
+
+		session := onlySession(t, filepath.Base(dir))
+		if session.Status != "completed" || session.TurnCount != 1 {
+			t.Errorf("expected the prompted session to be completed, got %+v", session)
+		}
+
+		// The next prompt opens a fresh session
+		runUserPromptHook(dir, userPromptInput{Prompt: "Next task"})
+		store, _ := getStore()
+		defer store.Close()
+		sessions, _ := store.ListSessions(filepath.Base(dir), "active", 10)
+		if len(sessions) != 1 {
+			t.Errorf("expected a new active session, got %d", len(sessions))
+		}
+	})
+
+	t.Run("redacts and truncates prompts", func(t *testing.T) {
+		useTestDB(t)
+		dir := setupProjectDir(t)
+
+		prompt := "use password=abc123secret and key sk-ant-REDACTED " + strings.Repeat("x", 600)
+		runUserPromptHook(dir, userPromptInput{Prompt: prompt})
+
+		store, _ := getStore()
+		defer store.Close()
+		entity, err := store.GetEntity(onlySession(t, filepath.Base(dir)).Name)
+		if err != nil || len(entity.Observations) != 1 {
+			t.Fatalf("expected one stored turn, got %v (%v)", entity, err)
+		}
+		turn := entity.Observations[0]
+		if !strings.HasPrefix(turn, "User: use password=[REDACTED:password] and key [REDACTED:anthropic_key] ") {
+			t.Errorf("secrets should be redacted with their types, got: %s", turn)
+		}
+		if len(turn) > len("User: ")+maxPromptLen+3 {
+			t.Errorf("turn should be truncated, got %d chars", len(turn))
+		}
+	})
+}
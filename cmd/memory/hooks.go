@@ -3,6 +3,7 @@ package main
 import (
 	"encoding/json"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 
@@ -27,6 +28,56 @@ func mark42Dir(projectDir string) string {
 	return filepath.Join(projectDir, ".claude", "mark42")
 }
 
+// Hook payloads carry paths in the host's format, which on Windows means drive
+// letters and backslashes (sometimes mixed with forward slashes). These helpers
+// handle both forms regardless of the OS the binary runs on.
+
+// hasDriveLetter reports whether p starts with a Windows drive letter, e.g. C:\ or c:/.
+func hasDriveLetter(p string) bool {
+	return len(p) >= 2 && p[1] == ':' &&
+		(('a' <= p[0] && p[0] <= 'z') || ('A' <= p[0] && p[0] <= 'Z'))
+}
+
+// isAbsHookPath reports whether p is absolute on Unix or Windows (drive letter or UNC).
+func isAbsHookPath(p string) bool {
+	return filepath.IsAbs(p) || hasDriveLetter(p) && len(p) > 2 && (p[2] == '\\' || p[2] == '/') ||
+		strings.HasPrefix(p, `\\`)
+}
+
+// normalizeHookPath returns a comparable form of p: forward slashes, cleaned,
+// and lowercased for Windows paths since their file systems ignore case.
+func normalizeHookPath(p string) string {
+	windows := hasDriveLetter(p) || strings.HasPrefix(p, `\\`)
+	p = path.Clean(strings.ReplaceAll(p, `\`, "/"))
+	if windows {
+		p = strings.ToLower(p)
+	}
+	return p
+}
+
+// resolveHookPath makes p absolute relative to projectDir, keeping the path style
+// of the project directory.
+func resolveHookPath(projectDir, p string) string {
+	if !isAbsHookPath(p) {
+		p = projectDir + "/" + p
+	}
+	if hasDriveLetter(p) || strings.HasPrefix(p, `\\`) {
+		unc := strings.HasPrefix(p, `\\`)
+		p = strings.ReplaceAll(path.Clean(strings.ReplaceAll(p, `\`, "/")), "/", `\`)
+		if unc {
+			p = `\` + p
+		}
+		return p
+	}
+	return filepath.Clean(p)
+}
+
+// isWithinDir reports whether p is dir or inside it, on normalized paths.
+func isWithinDir(p, dir string) bool {
+	p, dir = normalizeHookPath(p), normalizeHookPath(dir)
+	return p == dir || strings.HasPrefix(p, strings.TrimSuffix(dir, "/")+"/")
+}
+
 func readStdinJSON(v any) error {
 	return json.NewDecoder(os.Stdin).Decode(v)
 }
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+// gitCommit is one commit of the log import-git reads.
+type gitCommit struct {
+	Hash    string
+	Author  string
+	Date    time.Time
+	Subject string
+	Files   []string // Slash-separated, relative to the repository's top level
+}
+
+// gitLogFormat separates commits with \x1e and their fields with \x1f, so
+// subjects and author names can hold anything.
+const gitLogFormat = "--format=%x1e%H%x1f%an%x1f%aI%x1f%s"
+
+// parseGitLog reads `git log --name-only` output in gitLogFormat.
+func parseGitLog(log string) ([]gitCommit, error) {
+	var commits []gitCommit
+	for record := range strings.SplitSeq(log, "\x1e") {
+		if strings.TrimSpace(record) == "" {
+			continue
+		}
+		header, files, _ := strings.Cut(record, "\n")
+		fields := strings.Split(header, "\x1f")
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("unexpected git log line %q", header)
+		}
+		date, err := time.Parse(time.RFC3339, fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("commit %s: %w", fields[0], err)
+		}
+		c := gitCommit{Hash: fields[0], Author: fields[1], Date: date, Subject: strings.TrimSpace(fields[3])}
+		for file := range strings.SplitSeq(files, "\n") {
+			if file = strings.TrimSpace(file); file != "" {
+				c.Files = append(c.Files, file)
+			}
+		}
+		commits = append(commits, c)
+	}
+	return commits, nil
+}
+
+// gitHistoryGroup is the commits import-git records as one entity.
+type gitHistoryGroup struct {
+	Name    string
+	Commits []gitCommit
+}
+
+// groupCommits groups commits into entities named for project and the day
+// they were authored, or their author, keeping the log's order within each.
+func groupCommits(project, by string, commits []gitCommit) []gitHistoryGroup {
+	var groups []gitHistoryGroup
+	index := map[string]int{}
+	for _, c := range commits {
+		name := project + " commits " + c.Date.Format(time.DateOnly)
+		if by == "author" {
+			name = project + " commits by " + c.Author
+		}
+		i, ok := index[name]
+		if !ok {
+			i = len(groups)
+			index[name] = i
+			groups = append(groups, gitHistoryGroup{Name: name})
+		}
+		groups[i].Commits = append(groups[i].Commits, c)
+	}
+	return groups
+}
+
+// commitObservation describes c, leaving out what the group's name says.
+func commitObservation(c gitCommit, by string) string {
+	short := c.Hash[:min(len(c.Hash), 7)]
+	if by == "author" {
+		return fmt.Sprintf("%s %s %s", short, c.Date.Format(time.DateOnly), c.Subject)
+	}
+	return fmt.Sprintf("%s %s (%s)", short, c.Subject, c.Author)
+}
+
+// fileEntityNames returns the entity names a changed file matches: its path,
+// its path under the project as index names documents, its base name, and
+// its directory.
+func fileEntityNames(project, file string) []string {
+	names := []string{file, project + "/" + file, path.Base(file)}
+	if dir := path.Dir(file); dir != "." {
+		names = append(names, dir)
+	}
+	return names
+}
+
+// importedHistory is one entity import-git wrote, as its JSON output lists it.
+type importedHistory struct {
+	Entity     string `json:"entity"`
+	Commits    int    `json:"commits"`
+	NewCommits int    `json:"newCommits"`
+	Modified   int    `json:"modified"` // New modified relations
+}
+
+// importGitReport is what import-git did.
+type importGitReport struct {
+	Project  string            `json:"project"`
+	Since    time.Time         `json:"since"`
+	By       string            `json:"by"`
+	Commits  int               `json:"commits"`
+	Entities []importedHistory `json:"entities"`
+}
+
+var importGitCmd = &cobra.Command{
+	Use:   "import-git [dir]",
+	Short: "Record a repository's commit history as entities",
+	Long: `Read the commit log of the git repository at dir (default: the current
+directory) since --since ago and record it as history entities tagged with the
+project's container tag: one per day, named "<project> commits 2026-10-14", or
+with --by author one per author, named "<project> commits by Jane Doe". Each
+commit becomes an observation with its short hash and subject, from source
+import:git, and the entity gets a modified relation to every existing entity
+named like a file the commits changed: its path, <project>/<path> as index
+names documents, its base name, or its directory. Merge commits are skipped.
+
+Run it again to pick up new commits; commits already recorded are skipped.`,
+	Example: `  mark42 import-git
+  mark42 import-git ~/src/api --since 2w --by author`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir := "."
+		if len(args) > 0 {
+			dir = args[0]
+		}
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			return &storage.ValidationError{Field: "dir", Reason: fmt.Sprintf("%s is not a directory", dir)}
+		}
+		sinceFlag, _ := cmd.Flags().GetString("since")
+		window, err := parseSince(sinceFlag)
+		if err != nil {
+			return &storage.ValidationError{Field: "since", Reason: err.Error()}
+		}
+		by, _ := cmd.Flags().GetString("by")
+		if by != "day" && by != "author" {
+			return &storage.ValidationError{Field: "by", Reason: fmt.Sprintf("unknown grouping %q (want day or author)", by)}
+		}
+
+		toplevel, err := runGit(dir, "rev-parse", "--show-toplevel")
+		if err != nil {
+			return fmt.Errorf("%s is not a git repository: %w", dir, err)
+		}
+		project, _ := cmd.Flags().GetString("project")
+		if project == "" {
+			project = filepath.Base(toplevel)
+		}
+		since := time.Now().Add(-window)
+		log, err := runGit(toplevel, "log", "--no-merges", "--name-only", "--since="+since.Format(time.RFC3339), gitLogFormat)
+		if err != nil {
+			return err
+		}
+		commits, err := parseGitLog(log)
+		if err != nil {
+			return err
+		}
+
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		if err := store.Migrate(); err != nil {
+			return err
+		}
+		release, err := takeWriteLease(cmd, store)
+		if err != nil {
+			return err
+		}
+		defer release()
+
+		report := importGitReport{Project: project, Since: since, By: by, Commits: len(commits), Entities: []importedHistory{}}
+		var failures []error
+		if len(commits) > 0 {
+			report.Entities, failures, err = importGitHistory(cmd.Context(), store, project, by, commits)
+			if err != nil {
+				return err
+			}
+		}
+
+		if format, _ := cmd.Flags().GetString("format"); format == "json" {
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(report); err != nil {
+				return err
+			}
+		} else {
+			printImportGitReport(report, sinceFlag)
+		}
+		if len(failures) > 0 {
+			return fmt.Errorf("%d history entities failed to import: %w", len(failures), errors.Join(failures...))
+		}
+		return nil
+	},
+}
+
+// importGitHistory writes commits as history entities. Failures of one entity
+// are collected so the others are still written; err is for the store failing
+// as a whole.
+func importGitHistory(ctx context.Context, store *storage.Store, project, by string, commits []gitCommit) (imported []importedHistory, failures []error, err error) {
+	ctx = storage.WithSource(ctx, storage.SourceImport+":git")
+
+	// Entities changed files may name, by lowercased name
+	entities, err := store.ListEntitiesContext(ctx, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	known := make(map[string]string, len(entities))
+	for _, e := range entities {
+		if e.Type != "history" {
+			known[strings.ToLower(e.Name)] = e.Name
+		}
+	}
+
+	imported = []importedHistory{}
+	for _, group := range groupCommits(project, by, commits) {
+		result, err := importHistoryGroup(ctx, store, project, by, group, known)
+		if err != nil {
+			failures = append(failures, fmt.Errorf("%s: %w", group.Name, err))
+			continue
+		}
+		imported = append(imported, result)
+	}
+	return imported, failures, nil
+}
+
+func importHistoryGroup(ctx context.Context, store *storage.Store, project, by string, group gitHistoryGroup, known map[string]string) (importedHistory, error) {
+	result := importedHistory{Entity: group.Name, Commits: len(group.Commits)}
+	if _, err := store.CreateEntityContext(ctx, group.Name, "history", nil); err != nil && !errors.Is(err, storage.ErrEntityExists) {
+		return result, err
+	}
+	entity, err := store.GetEntityContext(ctx, group.Name)
+	if err != nil {
+		return result, err
+	}
+	// Oldest first, so observations read in the order things happened
+	for _, c := range slices.Backward(group.Commits) {
+		obs := commitObservation(c, by)
+		// Stored observations have secrets redacted; compare them that way
+		if redacted, _ := store.RedactSecrets(obs); slices.Contains(entity.Observations, redacted) {
+			continue
+		}
+		if err := store.AddObservationWithTypeContext(ctx, group.Name, obs, storage.FactTypeDynamic); err != nil {
+			return result, err
+		}
+		result.NewCommits++
+	}
+	if err := store.SetContainerTagContext(ctx, group.Name, project); err != nil {
+		return result, err
+	}
+
+	relations, err := store.ListRelationsContext(ctx, group.Name)
+	if err != nil {
+		return result, err
+	}
+	linked := map[string]bool{}
+	for _, r := range relations {
+		if r.Type == "modified" && r.From == entity.Name && r.ValidTo == nil {
+			linked[r.To] = true
+		}
+	}
+	for _, c := range group.Commits {
+		for _, file := range c.Files {
+			for _, candidate := range fileEntityNames(project, file) {
+				target, ok := known[strings.ToLower(candidate)]
+				if !ok || linked[target] {
+					continue
+				}
+				linked[target] = true
+				if err := store.CreateRelationContext(ctx, group.Name, target, "modified"); err != nil {
+					return result, err
+				}
+				result.Modified++
+			}
+		}
+	}
+	return result, nil
+}
+
+func printImportGitReport(r importGitReport, since string) {
+	newCommits, relations := 0, 0
+	for _, e := range r.Entities {
+		newCommits += e.NewCommits
+		relations += e.Modified
+	}
+	output(titleStyle.Render(fmt.Sprintf("Imported git history of %s", r.Project)) + " " +
+		dimStyle.Render(fmt.Sprintf("(%d commits in the last %s, %d new, %d modified relations)", r.Commits, since, newCommits, relations)))
+	if len(r.Entities) > 0 {
+		output()
+	}
+	for _, e := range r.Entities {
+		changes := fmt.Sprintf("%d commits", e.Commits)
+		if e.NewCommits < e.Commits {
+			changes += fmt.Sprintf(", %d new", e.NewCommits)
+		}
+		if e.Modified > 0 {
+			changes += fmt.Sprintf(", +%d modified", e.Modified)
+		}
+		output("  " + entityStyle.Render(e.Entity) + " " + dimStyle.Render(changes))
+	}
+}
+
+func init() {
+	importGitCmd.Flags().String("since", "90d", "how far back to read the log, e.g. 90d, 2w, or 720h")
+	importGitCmd.Flags().String("by", "day", "group commits into entities by day or author")
+	importGitCmd.Flags().String("project", "", "container tag and entity name prefix (default: the repository's name)")
+	importGitCmd.Flags().String("format", "default", "output format: default, json")
+	addWaitFlag(importGitCmd)
+	rootCmd.AddCommand(importGitCmd)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+func TestParseGitLog(t *testing.T) {
+	log := "\x1eabc1234def\x1fJane Doe\x1f2026-10-14T09:30:00+02:00\x1fAdd search\n\ninternal/search.go\nREADME.md\n" +
+		"\x1e0123456789\x1fBob\x1f2026-10-13T18:00:00Z\x1fEmpty commit\n"
+	commits, err := parseGitLog(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 2 {
+		t.Fatalf("expected 2 commits, got %+v", commits)
+	}
+	if c := commits[0]; c.Author != "Jane Doe" || c.Subject != "Add search" || !slices.Equal(c.Files, []string{"internal/search.go", "README.md"}) ||
+		c.Date.Format(time.DateOnly) != "2026-10-14" {
+		t.Errorf("unexpected first commit %+v", c)
+	}
+	if len(commits[1].Files) != 0 {
+		t.Errorf("expected no files, got %v", commits[1].Files)
+	}
+	if _, err := parseGitLog("\x1enot a commit\n"); err == nil {
+		t.Error("expected malformed output rejected")
+	}
+
+	groups := groupCommits("api", "author", append(commits, gitCommit{Hash: "fff", Author: "Jane Doe", Subject: "Fix"}))
+	if len(groups) != 2 || groups[0].Name != "api commits by Jane Doe" || len(groups[0].Commits) != 2 {
+		t.Errorf("unexpected groups %+v", groups)
+	}
+}
+
+// gitCommitAt commits everything in repo as author at date.
+func gitCommitAt(t *testing.T, repo, author, message string, date time.Time) {
+	t.Helper()
+	t.Setenv("GIT_AUTHOR_NAME", author)
+	t.Setenv("GIT_AUTHOR_DATE", date.Format(time.RFC3339))
+	t.Setenv("GIT_COMMITTER_DATE", date.Format(time.RFC3339))
+	if _, err := runGit(repo, "add", "-A"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runGit(repo, "commit", "-q", "-m", message); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestImportGitCommand(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	useTestDB(t)
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GIT_COMMITTER_NAME", "mark42 test")
+	for _, v := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(v, "test@example.com")
+	}
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	repo := filepath.Join(t.TempDir(), "api")
+	if err := os.MkdirAll(repo, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runGit(repo, "init", "-q"); err != nil {
+		t.Fatal(err)
+	}
+	day := time.Now().Add(-48 * time.Hour).Truncate(time.Hour)
+	writeDoc(t, repo, "old.txt", "old\n", day)
+	gitCommitAt(t, repo, "Jane Doe", "Ancient history", day.Add(-200*24*time.Hour))
+	writeDoc(t, repo, "README.md", "# API\n", day)
+	writeDoc(t, repo, "internal/storage/store.go", "package storage\n", day)
+	gitCommitAt(t, repo, "Jane Doe", "Add the store", day)
+	writeDoc(t, repo, "internal/storage/store.go", "package storage // v2\n", day)
+	gitCommitAt(t, repo, "Bob", "Tune the store", day.Add(time.Minute))
+	writeDoc(t, repo, "README.md", "# API v2\n", day)
+	gitCommitAt(t, repo, "Jane Doe", "Document the API", day.Add(24*time.Hour))
+
+	withStore(t, func(s *storage.Store) {
+		s.CreateEntity("internal/storage", "package", []string{"SQLite storage layer"})
+		s.CreateEntity("api/README.md", "document", nil)
+	})
+
+	got := runRootCmd(t, "import-git", repo)
+	for _, want := range []string{"Imported git history of api", "3 commits in the last 90d", "api commits " + day.Format(time.DateOnly)} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q:\n%s", want, got)
+		}
+	}
+	withStore(t, func(s *storage.Store) {
+		entity, err := s.GetEntity("api commits " + day.Format(time.DateOnly))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entity.Type != "history" || len(entity.Observations) != 2 ||
+			!strings.HasSuffix(entity.Observations[0], " Add the store (Jane Doe)") ||
+			!strings.HasSuffix(entity.Observations[1], " Tune the store (Bob)") {
+			t.Errorf("unexpected history entity %+v", entity)
+		}
+		if tag, _ := s.GetContainerTag(entity.Name); tag != "api" {
+			t.Errorf("expected the container tag api, got %q", tag)
+		}
+		relations, err := s.ListRelations(entity.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var modified []string
+		for _, r := range relations {
+			if r.Type == "modified" {
+				modified = append(modified, r.To)
+			}
+		}
+		slices.Sort(modified)
+		if want := []string{"api/README.md", "internal/storage"}; !slices.Equal(modified, want) {
+			t.Errorf("expected %v modified, got %v", want, modified)
+		}
+		next, _ := s.ListRelations("api commits " + day.Add(24*time.Hour).Format(time.DateOnly))
+		if len(next) != 1 || next[0].To != "api/README.md" {
+			t.Errorf("expected the indexed README modified, got %+v", next)
+		}
+	})
+
+	// A second import finds nothing new
+	defer importGitCmd.Flags().Set("format", "default")
+	defer importGitCmd.Flags().Set("by", "day")
+	var report importGitReport
+	if err := json.Unmarshal([]byte(runRootCmd(t, "import-git", repo, "--format", "json")), &report); err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range report.Entities {
+		if e.NewCommits != 0 || e.Modified != 0 {
+			t.Errorf("expected nothing new on re-import, got %+v", e)
+		}
+	}
+
+	if err := json.Unmarshal([]byte(runRootCmd(t, "import-git", repo, "--format", "json", "--by", "author")), &report); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Entities) != 2 || report.Entities[0].Entity != "api commits by Jane Doe" || report.Entities[0].Commits != 2 {
+		t.Errorf("expected commits grouped by author, got %+v", report.Entities)
+	}
+}
@@ -0,0 +1,390 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+// maxTranscriptEvents bounds the events kept of one transcript, as the Stop
+// hook bounds a captured session's.
+const maxTranscriptEvents = 50
+
+// transcriptLine is one line of a Claude Code transcript.
+type transcriptLine struct {
+	Type             string          `json:"type"`
+	Timestamp        string          `json:"timestamp"`
+	SessionID        string          `json:"sessionId"`
+	Cwd              string          `json:"cwd"`
+	IsSidechain      bool            `json:"isSidechain"`
+	IsMeta           bool            `json:"isMeta"`
+	IsCompactSummary bool            `json:"isCompactSummary"`
+	Message          json.RawMessage `json:"message"`
+}
+
+// transcriptSession is what readTranscript keeps of a transcript: the user
+// prompts and final replies as turns, and the edits and commands the hooks
+// would have tracked as events.
+type transcriptSession struct {
+	ID          string
+	Cwd         string
+	StartedAt   time.Time
+	EndedAt     time.Time
+	Timeline    []storage.SessionEntry
+	Prompts     int
+	Events      []storage.SessionEvent
+	Files       []string // Files edited, in the order first edited
+	FirstPrompt string
+	LastReply   string
+}
+
+// readTranscript reads a Claude Code JSONL transcript. Subagent (sidechain)
+// messages, meta messages, compaction summaries, and slash command output are
+// skipped; of the assistant's messages between two prompts only the last text
+// is kept as the reply. Secrets the store redacts are redacted from turns and
+// commands before they are truncated.
+func readTranscript(store *storage.Store, path string) (*transcriptSession, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ts := &transcriptSession{}
+	var reply string
+	var replyAt time.Time
+	endTurn := func() {
+		if reply != "" {
+			redacted, _ := store.RedactSecrets(reply)
+			ts.Timeline = append(ts.Timeline, storage.SessionEntry{
+				FactType: storage.FactTypeSessionTurn,
+				Content:  "Assistant: " + truncate(redacted, maxMessageLen),
+				At:       replyAt,
+			})
+			ts.LastReply = reply
+		}
+		reply = ""
+	}
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 256*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var line transcriptLine
+		if json.Unmarshal(scanner.Bytes(), &line) != nil || line.IsSidechain || line.IsMeta || line.IsCompactSummary {
+			continue
+		}
+		if line.Type != "user" && line.Type != "assistant" {
+			continue
+		}
+		at, err := time.Parse(time.RFC3339, line.Timestamp)
+		if err != nil {
+			continue
+		}
+		if ts.ID == "" {
+			ts.ID, ts.StartedAt = line.SessionID, at
+		}
+		if ts.Cwd == "" {
+			ts.Cwd = line.Cwd
+		}
+		ts.EndedAt = at
+
+		if line.Type == "user" {
+			text := strings.TrimSpace(extractUserText(line.Message))
+			if text == "" || strings.HasPrefix(text, "<") || strings.HasPrefix(text, "[Request interrupted") {
+				continue
+			}
+			endTurn()
+			ts.Prompts++
+			if ts.FirstPrompt == "" {
+				ts.FirstPrompt = text
+			}
+			redacted, _ := store.RedactSecrets(text)
+			ts.Timeline = append(ts.Timeline, storage.SessionEntry{
+				FactType: storage.FactTypeSessionTurn,
+				Content:  "User: " + truncate(redacted, maxPromptLen),
+				At:       at,
+			})
+			continue
+		}
+
+		if text := extractAssistantText(line.Message); text != "" {
+			reply, replyAt = text, at
+		}
+		for _, evt := range transcriptEvents(store, line.Message, at) {
+			if evt.FilePath != "" && !slices.Contains(ts.Files, evt.FilePath) {
+				ts.Files = append(ts.Files, evt.FilePath)
+			}
+			if len(ts.Events) < maxTranscriptEvents {
+				ts.Events = append(ts.Events, evt)
+				ts.Timeline = append(ts.Timeline, storage.SessionEntry{FactType: storage.FactTypeSessionEvent, Event: evt, At: at})
+			}
+		}
+	}
+	endTurn()
+	return ts, scanner.Err()
+}
+
+// transcriptEvents returns the session events of an assistant message's tool
+// calls: edits with their file and commands with their text, as the
+// PostToolUse hook records them.
+func transcriptEvents(store *storage.Store, raw json.RawMessage, at time.Time) []storage.SessionEvent {
+	var env messageEnvelope
+	if json.Unmarshal(raw, &env) != nil {
+		return nil
+	}
+	var blocks []contentBlock
+	if json.Unmarshal(env.Content, &blocks) != nil {
+		return nil
+	}
+	var events []storage.SessionEvent
+	for _, b := range blocks {
+		if b.Type != "tool_use" {
+			continue
+		}
+		evt := storage.SessionEvent{ToolName: b.Name, Timestamp: at.UTC().Format(time.RFC3339)}
+		switch b.Name {
+		case "Edit", "Write", "MultiEdit", "NotebookEdit":
+			path, _ := b.Input["file_path"].(string)
+			if path == "" {
+				path, _ = b.Input["notebook_path"].(string)
+			}
+			if path == "" {
+				continue
+			}
+			evt.FilePath = path
+		case "Bash":
+			command, _ := b.Input["command"].(string)
+			if command = strings.TrimSpace(command); command == "" {
+				continue
+			}
+			command, _ = store.RedactSecrets(command)
+			evt.Command = truncate(command, 200)
+		default:
+			continue
+		}
+		events = append(events, evt)
+	}
+	return events
+}
+
+// transcriptSummary summarizes ts as the Stop hook summarizes a session, led
+// by what the user first asked; with summarizer, an LLM condenses that and
+// the transcript at path.
+func transcriptSummary(store *storage.Store, ts *transcriptSession, path string, summarizer *storage.SummaryClient) string {
+	summary := buildAutoSummary(ts.Events, ts.Files, ts.LastReply)
+	if ts.FirstPrompt != "" {
+		asked, _ := store.RedactSecrets(ts.FirstPrompt)
+		summary = "Asked: " + truncate(asked, maxContextLen) + ". " + summary
+	}
+	if summarizer != nil {
+		summary = summarizeSession(summarizer, summary, buildSessionDigest(path))
+	}
+	return summary
+}
+
+// findTranscripts returns the .jsonl files at path, a transcript or a
+// directory of them searched recursively.
+func findTranscripts(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	var files []string
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.EqualFold(filepath.Ext(p), ".jsonl") {
+			files = append(files, p)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// importedTranscript is what import-transcripts did with one transcript, as
+// its JSON output lists it.
+type importedTranscript struct {
+	Path    string `json:"path"`
+	Session string `json:"session,omitempty"`
+	Project string `json:"project,omitempty"`
+	Turns   int    `json:"turns"`
+	Events  int    `json:"events"`
+	Status  string `json:"status"` // imported, exists, empty, old, or failed
+	Error   string `json:"error,omitempty"`
+}
+
+var importTranscriptsCmd = &cobra.Command{
+	Use:   "import-transcripts [path...]",
+	Short: "Import past Claude Code conversations as sessions",
+	Long: `Read Claude Code's JSONL transcripts (default: all of them, under
+~/.claude/projects) and record each conversation as a completed session, as
+the hooks capture sessions now: user prompts and the assistant's final replies
+as turns, edits and commands as events, and a summary. Each session is dated
+when the conversation happened and named for the project it ran in, the base
+name of its working directory unless --project is given, so session list,
+recall, and search_sessions find conversations from before mark42 was
+installed.
+
+Subagent messages and slash command output are skipped, as are transcripts
+without prompts or, with --since, older ones. Importing a transcript again
+finds its session and skips it. With CLAUDE_MEMORY_SUMMARY_MODEL set, an LLM
+writes the summaries, as for the session end hooks.`,
+	Example: `  mark42 import-transcripts
+  mark42 import-transcripts ~/.claude/projects/-src-api --project api --since 30d`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		paths := args
+		if len(paths) == 0 {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return err
+			}
+			paths = []string{filepath.Join(home, ".claude", "projects")}
+		}
+		project, _ := cmd.Flags().GetString("project")
+		var since time.Time
+		if s, _ := cmd.Flags().GetString("since"); s != "" {
+			window, err := parseSince(s)
+			if err != nil {
+				return &storage.ValidationError{Field: "since", Reason: err.Error()}
+			}
+			since = time.Now().Add(-window)
+		}
+
+		var files []string
+		for _, path := range paths {
+			found, err := findTranscripts(path)
+			if err != nil {
+				return err
+			}
+			files = append(files, found...)
+		}
+
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		if err := store.Migrate(); err != nil {
+			return err
+		}
+		release, err := takeWriteLease(cmd, store)
+		if err != nil {
+			return err
+		}
+		defer release()
+
+		summarizer := summarizerFromEnv()
+		results := []importedTranscript{}
+		var failures []error
+		for _, file := range files {
+			result := importedTranscript{Path: file}
+			ts, err := readTranscript(store, file)
+			switch {
+			case err != nil:
+				result.Status, result.Error = "failed", err.Error()
+				failures = append(failures, fmt.Errorf("%s: %w", file, err))
+			case ts.Prompts == 0:
+				result.Status = "empty"
+			case ts.StartedAt.Before(since):
+				result.Status = "old"
+			default:
+				result = importTranscript(store, file, ts, project, summarizer)
+				if result.Status == "failed" {
+					failures = append(failures, fmt.Errorf("%s: %s", file, result.Error))
+				}
+			}
+			results = append(results, result)
+		}
+
+		if format, _ := cmd.Flags().GetString("format"); format == "json" {
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(results); err != nil {
+				return err
+			}
+		} else {
+			printImportTranscripts(results)
+		}
+		if len(failures) > 0 {
+			return fmt.Errorf("%d transcripts failed to import: %w", len(failures), errors.Join(failures...))
+		}
+		return nil
+	},
+}
+
+// importTranscript records ts as a session of project, or of the base name of
+// its working directory when project is empty.
+func importTranscript(store *storage.Store, path string, ts *transcriptSession, project string, summarizer *storage.SummaryClient) importedTranscript {
+	if project == "" && ts.Cwd != "" {
+		project = filepath.Base(ts.Cwd)
+	}
+	if project == "" {
+		project = strings.TrimLeft(filepath.Base(filepath.Dir(path)), "-")
+	}
+	result := importedTranscript{Path: path, Project: project, Turns: len(ts.Timeline) - len(ts.Events), Events: len(ts.Events)}
+
+	attrs := map[string]string{"transcript": path}
+	if ts.ID != "" {
+		attrs["transcript_session"] = ts.ID
+	}
+	session, err := store.ImportSession(storage.ImportedSession{
+		Project:    project,
+		StartedAt:  ts.StartedAt,
+		EndedAt:    ts.EndedAt,
+		Timeline:   ts.Timeline,
+		Summary:    transcriptSummary(store, ts, path, summarizer),
+		Attributes: attrs,
+	})
+	switch {
+	case errors.Is(err, storage.ErrEntityExists):
+		result.Status = "exists"
+	case err != nil:
+		result.Status, result.Error = "failed", err.Error()
+	default:
+		result.Status, result.Session = "imported", session.Name
+	}
+	return result
+}
+
+func printImportTranscripts(results []importedTranscript) {
+	counts := map[string]int{}
+	for _, r := range results {
+		counts[r.Status]++
+	}
+	output(titleStyle.Render("Imported transcripts") + " " + dimStyle.Render(fmt.Sprintf("(%d imported, %d already imported, %d skipped, %d failed)",
+		counts["imported"], counts["exists"], counts["empty"]+counts["old"], counts["failed"])))
+	if counts["imported"]+counts["failed"] > 0 {
+		output()
+	}
+	for _, r := range results {
+		switch r.Status {
+		case "imported":
+			output("  " + entityStyle.Render(r.Session) + " " + dimStyle.Render(fmt.Sprintf("%d turns, %d events", r.Turns, r.Events)))
+		case "failed":
+			output("  " + r.Path + " " + dimStyle.Render("failed: "+r.Error))
+		}
+	}
+}
+
+func init() {
+	importTranscriptsCmd.Flags().String("project", "", "project of the sessions (default: each conversation's working directory name)")
+	importTranscriptsCmd.Flags().String("since", "", "only conversations started this recently, e.g. 30d or 2w")
+	importTranscriptsCmd.Flags().String("format", "default", "output format: default, json")
+	addWaitFlag(importTranscriptsCmd)
+	rootCmd.AddCommand(importTranscriptsCmd)
+}
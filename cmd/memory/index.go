@@ -0,0 +1,253 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+// docFile is a document index found.
+type docFile struct {
+	Path     string // Slash-separated, relative to the indexed directory
+	Modified time.Time
+	Decision bool // An architecture decision record
+}
+
+var (
+	// docDirs hold documentation; adrDirs hold decision records.
+	docDirs = map[string]bool{"docs": true, "doc": true, "documentation": true}
+	adrDirs = map[string]bool{"adr": true, "adrs": true, "decisions": true, "decision-records": true}
+	// skippedDirs are never searched, with hidden directories.
+	skippedDirs = map[string]bool{"node_modules": true, "vendor": true, "testdata": true, "dist": true, "build": true, "target": true}
+	docExts     = map[string]bool{".md": true, ".markdown": true, ".mdx": true, ".txt": true, ".rst": true}
+	adrName     = regexp.MustCompile(`^(?i:adr)?[-_]?\d{3,4}[-_]`)
+)
+
+// findDocs returns root's READMEs anywhere, the documents under directories
+// named docs, doc, or documentation, and decision records under adr, adrs,
+// decisions, or decision-records, or numbered like 0007-use-sqlite.md in docs.
+func findDocs(root string) ([]docFile, error) {
+	var docs []docFile
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path != root && (strings.HasPrefix(name, ".") || skippedDirs[name]) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		ext := strings.ToLower(filepath.Ext(name))
+		dirs := strings.Split(strings.ToLower(filepath.ToSlash(filepath.Dir(rel))), "/")
+		inDocs := slices.ContainsFunc(dirs, func(dir string) bool { return docDirs[dir] })
+		inADRs := slices.ContainsFunc(dirs, func(dir string) bool { return adrDirs[dir] })
+
+		readme := strings.HasPrefix(strings.ToUpper(name), "README") && (ext == "" || docExts[ext])
+		if !readme && (!docExts[ext] || !inDocs && !inADRs) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		docs = append(docs, docFile{
+			Path:     rel,
+			Modified: info.ModTime(),
+			Decision: !readme && (inADRs || adrName.MatchString(name)),
+		})
+		return nil
+	})
+	return docs, err
+}
+
+// indexedDoc is a document index recorded, as its JSON output lists it.
+type indexedDoc struct {
+	Entity  string `json:"entity"`
+	Path    string `json:"path"`
+	Type    string `json:"type"`
+	Added   int    `json:"added"`
+	Removed int    `json:"removed"`
+}
+
+// indexReport is what index did.
+type indexReport struct {
+	Project   string       `json:"project"`
+	Indexed   []indexedDoc `json:"indexed"`
+	Unchanged []string     `json:"unchanged"`
+	Missing   []string     `json:"missing"` // Entities of documents no longer there
+	Pruned    bool         `json:"pruned"`  // Whether the missing were deleted
+	Failed    []string     `json:"failed,omitempty"`
+}
+
+var indexCmd = &cobra.Command{
+	Use:   "index <dir>",
+	Short: "Index a project's README, docs, and ADRs as entities",
+	Long: `Scan dir for READMEs, documents under docs/, and architecture decision
+records, and record each as an entity named <project>/<path>: a document, or a
+decision for ADRs, tagged with the project's container tag. Its observations
+are static facts summarizing the document: the first sentence of the opening
+and of each section, a section list, the first bullets of each section, and
+ADR fields like Status.
+
+Run it again to re-index: only documents whose modification time changed are
+read, observations no longer in a document are deleted, and observations
+added to a document's entity by hand stay. Entities of documents that were
+removed are listed, and deleted with --prune.`,
+	Example: `  mark42 index .
+  mark42 index ~/src/api --project api --prune`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		root, err := filepath.Abs(args[0])
+		if err != nil {
+			return err
+		}
+		if info, err := os.Stat(root); err != nil || !info.IsDir() {
+			return &storage.ValidationError{Field: "dir", Reason: fmt.Sprintf("%s is not a directory", args[0])}
+		}
+		project, _ := cmd.Flags().GetString("project")
+		if project == "" {
+			project = filepath.Base(root)
+		}
+		force, _ := cmd.Flags().GetBool("force")
+		prune, _ := cmd.Flags().GetBool("prune")
+
+		docs, err := findDocs(root)
+		if err != nil {
+			return err
+		}
+
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		if err := store.Migrate(); err != nil {
+			return err
+		}
+		release, err := takeWriteLease(cmd, store)
+		if err != nil {
+			return err
+		}
+		defer release()
+
+		report := indexReport{Project: project, Indexed: []indexedDoc{}, Unchanged: []string{}, Missing: []string{}, Pruned: prune}
+		var failures []error
+		found := map[string]bool{}
+		for _, doc := range docs {
+			name := project + "/" + doc.Path
+			found[storage.NormalizeName(name)] = true
+			if !force {
+				if attrs, err := store.GetAttributes(name); err == nil &&
+					attrs["mtime"] == doc.Modified.UTC().Format(storage.DocumentMtimeLayout) {
+					report.Unchanged = append(report.Unchanged, name)
+					continue
+				}
+			}
+
+			text, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(doc.Path)))
+			if err != nil {
+				failures = append(failures, err)
+				report.Failed = append(report.Failed, doc.Path)
+				continue
+			}
+			entityType := "document"
+			if doc.Decision {
+				entityType = "decision"
+			}
+			added, removed, err := store.IndexDocument(storage.IndexedDocument{
+				Name: name, Type: entityType, Project: project, Path: doc.Path,
+				Modified: doc.Modified, Summary: storage.SummarizeDocument(string(text)),
+			})
+			if err != nil {
+				failures = append(failures, fmt.Errorf("%s: %w", doc.Path, err))
+				report.Failed = append(report.Failed, doc.Path)
+				continue
+			}
+			report.Indexed = append(report.Indexed, indexedDoc{Entity: name, Path: doc.Path, Type: entityType, Added: added, Removed: removed})
+		}
+
+		// Entities an earlier index made, by their path attribute, whose
+		// documents are gone
+		tagged, err := store.GetEntitiesByContainerTag(project)
+		if err != nil {
+			return err
+		}
+		for _, e := range tagged {
+			if found[e.Name] || !strings.HasPrefix(e.Name, project+"/") {
+				continue
+			}
+			if attrs, err := store.GetAttributes(e.Name); err != nil || attrs["path"] == "" {
+				continue
+			}
+			report.Missing = append(report.Missing, e.Name)
+			if prune {
+				if err := store.DeleteEntity(e.Name); err != nil {
+					failures = append(failures, fmt.Errorf("%s: %w", e.Name, err))
+				}
+			}
+		}
+
+		if format, _ := cmd.Flags().GetString("format"); format == "json" {
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(report); err != nil {
+				return err
+			}
+		} else {
+			printIndexReport(report)
+		}
+		if len(failures) > 0 {
+			return fmt.Errorf("%d documents failed to index: %w", len(report.Failed), errors.Join(failures...))
+		}
+		return nil
+	},
+}
+
+func printIndexReport(r indexReport) {
+	output(titleStyle.Render(fmt.Sprintf("Indexed %s", r.Project)) + " " +
+		dimStyle.Render(fmt.Sprintf("(%d indexed, %d unchanged)", len(r.Indexed), len(r.Unchanged))))
+	if len(r.Indexed)+len(r.Missing) > 0 {
+		output()
+	}
+	for _, doc := range r.Indexed {
+		changes := fmt.Sprintf("+%d", doc.Added)
+		if doc.Removed > 0 {
+			changes += fmt.Sprintf(" -%d", doc.Removed)
+		}
+		output("  " + entityStyle.Render(doc.Entity) + " " + typeStyle.Render("("+doc.Type+")") + " " + dimStyle.Render(changes))
+	}
+	for _, name := range r.Missing {
+		if r.Pruned {
+			output("  " + entityStyle.Render(name) + " " + dimStyle.Render("deleted: the document is gone"))
+		} else {
+			output("  " + entityStyle.Render(name) + " " + dimStyle.Render("the document is gone; --prune deletes it"))
+		}
+	}
+}
+
+func init() {
+	indexCmd.Flags().String("project", "", "container tag and entity name prefix (default: the directory's name)")
+	indexCmd.Flags().Bool("force", false, "re-read every document, changed or not")
+	indexCmd.Flags().Bool("prune", false, "delete the entities of documents that are gone")
+	indexCmd.Flags().String("format", "default", "output format: default, json")
+	addWaitFlag(indexCmd)
+	rootCmd.AddCommand(indexCmd)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+// writeDoc writes a document under root, modified at mtime.
+func writeDoc(t *testing.T, root, path, body string, mtime time.Time) {
+	t.Helper()
+	full := filepath.Join(root, filepath.FromSlash(path))
+	if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(full, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(full, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFindDocs(t *testing.T) {
+	root := t.TempDir()
+	then := time.Now().Add(-time.Hour)
+	for _, path := range []string{
+		"README.md", "cmd/tool/README", "docs/guide.md", "docs/adr/0001-use-sqlite.md",
+		"docs/0002-drop-testify.md", "decisions/003-go.md",
+		"main.go", "CHANGELOG.md", "docs/diagram.png", "node_modules/pkg/README.md", ".github/README.md",
+	} {
+		writeDoc(t, root, path, "# Doc\n", then)
+	}
+
+	docs, err := findDocs(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, doc := range docs {
+		kind := "doc"
+		if doc.Decision {
+			kind = "adr"
+		}
+		got = append(got, doc.Path+" "+kind)
+	}
+	want := []string{
+		"README.md doc", "cmd/tool/README doc", "decisions/003-go.md adr",
+		"docs/0002-drop-testify.md adr", "docs/adr/0001-use-sqlite.md adr", "docs/guide.md doc",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("findDocs = %v\nwant %v", got, want)
+	}
+}
+
+func TestIndexCommand(t *testing.T) {
+	useTestDB(t)
+	t.Setenv("HOME", t.TempDir())
+	root := filepath.Join(t.TempDir(), "api")
+	then := time.Now().Add(-time.Hour).Truncate(time.Second)
+	writeDoc(t, root, "README.md", "# API\n\nServes the mark42 graph over HTTP.\n\n## Usage\n\n- Run `api serve`\n", then)
+	writeDoc(t, root, "docs/adr/0001-use-grpc.md", "# Use gRPC\n\nStatus: Accepted\n\n## Decision\n\nUse gRPC for internal calls.\n", then)
+	writeDoc(t, root, "docs/old.md", "# Old\n\nOutdated notes.\n", then)
+
+	got := runRootCmd(t, "index", root)
+	for _, want := range []string{"Indexed api", "3 indexed", "api/README.md", "api/docs/adr/0001-use-grpc.md (decision)"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q:\n%s", want, got)
+		}
+	}
+	withStore(t, func(s *storage.Store) {
+		entity, err := s.GetEntity("api/README.md")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Contains(entity.Observations, "API: Serves the mark42 graph over HTTP.") ||
+			!slices.Contains(entity.Observations, "Usage: Run `api serve`") {
+			t.Errorf("unexpected observations %v", entity.Observations)
+		}
+		if tag, _ := s.GetContainerTag("api/docs/adr/0001-use-grpc.md"); tag != "api" {
+			t.Errorf("expected the ADR tagged api, got %q", tag)
+		}
+	})
+
+	// Only changed documents are read again, and removed ones are noticed
+	writeDoc(t, root, "README.md", "# API\n\nServes the mark42 graph over HTTP and gRPC.\n", then.Add(time.Minute))
+	if err := os.Remove(filepath.Join(root, "docs", "old.md")); err != nil {
+		t.Fatal(err)
+	}
+	var report indexReport
+	if err := json.Unmarshal([]byte(runRootCmd(t, "index", root, "--format", "json")), &report); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Indexed) != 1 || report.Indexed[0].Path != "README.md" || report.Indexed[0].Added != 1 || report.Indexed[0].Removed != 2 {
+		t.Errorf("expected only the README re-indexed, got %+v", report.Indexed)
+	}
+	if len(report.Unchanged) != 1 || len(report.Missing) != 1 || report.Missing[0] != "api/docs/old.md" {
+		t.Errorf("expected the ADR unchanged and old.md missing, got %+v", report)
+	}
+
+	defer indexCmd.Flags().Set("format", "default")
+	defer indexCmd.Flags().Set("prune", "false")
+	if got := runRootCmd(t, "index", root, "--format", "default", "--prune"); !strings.Contains(got, "deleted: the document is gone") {
+		t.Errorf("expected old.md deleted:\n%s", got)
+	}
+	withStore(t, func(s *storage.Store) {
+		if _, err := s.GetEntity("api/docs/old.md"); err == nil {
+			t.Error("expected the pruned entity gone")
+		}
+	})
+}
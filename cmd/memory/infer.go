@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+var inferRelationsCmd = &cobra.Command{
+	Use:   "infer-relations",
+	Short: "Propose relations between entities that mention each other",
+	Long: `Scan observations for the names of other entities and propose relations
+the graph is missing:
+
+  A -[mentions]-> B     an observation of A names B
+  B -[related_to]-> C   observations of other entities name B and C together
+
+Pairs that are already related are skipped. By default the proposals are only
+listed; --review asks about each one and --apply adds them all.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		if err := store.Migrate(); err != nil {
+			return err
+		}
+
+		minCo, _ := cmd.Flags().GetInt("min-co-mentions")
+		proposals, err := store.InferRelations(storage.InferOptions{MinCoMentions: minCo})
+		if err != nil {
+			return err
+		}
+		if limit, _ := cmd.Flags().GetInt("limit"); limit > 0 && len(proposals) > limit {
+			proposals = proposals[:limit]
+		}
+		if len(proposals) == 0 {
+			output("No relations to infer")
+			return nil
+		}
+
+		apply, _ := cmd.Flags().GetBool("apply")
+		review, _ := cmd.Flags().GetBool("review")
+		if !apply && !review {
+			output(titleStyle.Render(fmt.Sprintf("Proposed relations (%d)", len(proposals))))
+			output()
+			for _, p := range proposals {
+				renderInferred(p)
+			}
+			output()
+			output(dimStyle.Render("Run with --review to choose, or --apply to add them all"))
+			return nil
+		}
+
+		in := bufio.NewScanner(cmd.InOrStdin())
+		added := 0
+		for _, p := range proposals {
+			if review && !apply {
+				renderInferred(p)
+				fmt.Fprint(out, "  Add? [y/N/a(ll)/q] ")
+				answer := ""
+				if in.Scan() {
+					answer = strings.ToLower(strings.TrimSpace(in.Text()))
+				}
+				if answer == "q" {
+					break
+				}
+				if answer == "a" {
+					apply = true
+				} else if answer != "y" {
+					continue
+				}
+			}
+			if err := store.CreateRelation(p.From, p.To, p.Type); err != nil {
+				logger.Warn("failed to add relation", "from", p.From, "to", p.To, "error", err)
+				continue
+			}
+			added++
+		}
+		output(successStyle.Render(fmt.Sprintf("Added %d relations", added)))
+		return nil
+	},
+}
+
+func init() {
+	inferRelationsCmd.Flags().Bool("apply", false, "add every proposed relation")
+	inferRelationsCmd.Flags().Bool("review", false, "ask about each proposed relation")
+	inferRelationsCmd.Flags().Int("min-co-mentions", 2, "observations that must name two entities together to propose related_to")
+	inferRelationsCmd.Flags().Int("limit", 0, "propose at most this many relations (0 = all)")
+	rootCmd.AddCommand(inferRelationsCmd)
+}
+
+func renderInferred(p storage.InferredRelation) {
+	output("  " + p.From + " " + relationStyle.Render("-["+p.Type+"]->") + " " + p.To + " " +
+		dimStyle.Render(fmt.Sprintf("(%d× — %q)", p.Count, truncate(p.Evidence, 60))))
+}
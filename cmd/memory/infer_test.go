@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+func TestInferRelations(t *testing.T) {
+	useTestDB(t)
+	withStore(t, func(s *storage.Store) {
+		for _, name := range []string{"Lexer", "Parser", "Printer"} {
+			if _, err := s.CreateEntity(name, "component", nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := s.CreateEntity("Compiler", "project", []string{
+			"Runs the Lexer, then the Parser, then the Printer",
+		}); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	got := runRootCmd(t, "infer-relations")
+	if !strings.Contains(got, "Proposed relations (3)") || !strings.Contains(got, "Compiler -[mentions]-> Lexer") {
+		t.Errorf("expected proposals listed:\n%s", got)
+	}
+	withStore(t, func(s *storage.Store) {
+		if relations, _ := s.ListRelations("Compiler"); len(relations) != 0 {
+			t.Errorf("expected listing to add nothing, got %+v", relations)
+		}
+	})
+
+	rootCmd.SetIn(strings.NewReader("y\nn\nq\n"))
+	defer rootCmd.SetIn(nil)
+	defer inferRelationsCmd.Flags().Set("review", "false")
+	got = runRootCmd(t, "infer-relations", "--review")
+	if !strings.Contains(got, "Added 1 relations") {
+		t.Errorf("expected one relation accepted:\n%s", got)
+	}
+
+	defer inferRelationsCmd.Flags().Set("apply", "false")
+	got = runRootCmd(t, "infer-relations", "--review=false", "--apply")
+	if !strings.Contains(got, "Added 2 relations") {
+		t.Errorf("expected the remaining relations added:\n%s", got)
+	}
+	withStore(t, func(s *storage.Store) {
+		if relations, _ := s.ListRelations("Compiler"); len(relations) != 3 {
+			t.Errorf("expected 3 relations, got %+v", relations)
+		}
+	})
+	if got = runRootCmd(t, "infer-relations", "--apply=false"); !strings.Contains(got, "No relations to infer") {
+		t.Errorf("expected nothing left to infer:\n%s", got)
+	}
+}
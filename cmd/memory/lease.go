@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+// commandLeaseTTL is how long a command's write lease lasts unrenewed, so
+// one that crashes leaves it behind for no longer.
+const commandLeaseTTL = 30 * time.Second
+
+// addWaitFlag lets cmd, which takes the write lease, wait for it.
+func addWaitFlag(cmd *cobra.Command) {
+	cmd.Flags().Duration("wait", 0, "wait this long for the MCP server or another command to release the write lease, instead of refusing")
+}
+
+// takeWriteLease takes the database's write lease for cmd, a maintenance
+// command, so it doesn't contend with an active MCP server. While another
+// process holds it, cmd refuses, or waits for up to --wait. The returned
+// function releases it.
+func takeWriteLease(cmd *cobra.Command, store *storage.Store) (release func(), err error) {
+	if store.ReadOnly() {
+		return func() {}, nil
+	}
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	holder := cmd.CommandPath()
+	_, err = store.AcquireWriteLeaseContext(ctx, holder, commandLeaseTTL)
+	var held *storage.LeaseHeldError
+	if wait, _ := cmd.Flags().GetDuration("wait"); wait > 0 && errors.As(err, &held) {
+		output(dimStyle.Render(fmt.Sprintf("Waiting up to %s for %s (pid %d) to release the write lease", wait, held.Lease.Holder, held.Lease.PID)))
+		_, err = store.WaitWriteLease(ctx, holder, commandLeaseTTL, wait)
+	}
+	if errors.As(err, &held) {
+		return nil, fmt.Errorf("%w; retry when it is done, or queue behind it with --wait 5m", err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return store.KeepWriteLease(ctx, holder, commandLeaseTTL), nil
+}
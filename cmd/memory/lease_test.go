@@ -0,0 +1,36 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+func TestMaintenanceWriteLease(t *testing.T) {
+	useTestDB(t)
+
+	withStore(t, func(s *storage.Store) {
+		if _, err := s.AcquireWriteLease("mcp server", 600*time.Millisecond); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	rootCmd.SetArgs([]string{"decay", "apply"})
+	err := rootCmd.Execute()
+	if exitCode(err) != exitLeaseHeld || !strings.Contains(err.Error(), "mcp server") || !strings.Contains(err.Error(), "--wait") {
+		t.Fatalf("expected a refusal naming the holder, got %v", err)
+	}
+
+	defer decaySoftCmd.Flags().Set("wait", "0s")
+	got := runRootCmd(t, "decay", "apply", "--wait", "5s")
+	if !strings.Contains(got, "Waiting up to 5s for mcp server") {
+		t.Errorf("expected the command to queue:\n%s", got)
+	}
+	withStore(t, func(s *storage.Store) {
+		if lease, _ := s.GetWriteLease(); lease != nil {
+			t.Errorf("expected the lease released after the command, got %+v", lease)
+		}
+	})
+}
@@ -1,12 +1,17 @@
 package main
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -18,8 +23,15 @@ import (
 )
 
 var (
-	dbPath  string
-	Version = "dev"
+	dbPath         string
+	readOnly       bool
+	tokenizerModel string
+	tokenizer      storage.Tokenizer // From --tokenizer; nil for the default
+	Version                          = "dev"
+
+	// writeSource is the provenance recorded on observations the running
+	// command writes; see commandSource
+	writeSource = storage.SourceCLI
 
 	// logger writes operational messages (errors, info) to stderr
 	logger = log.NewWithOptions(os.Stderr, log.Options{
@@ -64,7 +76,34 @@ var (
 
 func main() {
 	if err := rootCmd.Execute(); err != nil {
-		os.Exit(1)
+		os.Exit(exitCode(err))
+	}
+}
+
+// Exit codes beyond 1 let scripts tell storage errors apart.
+const (
+	exitInvalidInput   = 2
+	exitNotFound       = 3
+	exitEntityExists   = 4
+	exitSchemaOutdated = 5
+	exitLeaseHeld      = 6
+)
+
+// exitCode maps an error returned by a command to the process exit status.
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, storage.ErrInvalidInput):
+		return exitInvalidInput
+	case errors.Is(err, storage.ErrNotFound):
+		return exitNotFound
+	case errors.Is(err, storage.ErrEntityExists):
+		return exitEntityExists
+	case errors.Is(err, storage.ErrSchemaOutdated):
+		return exitSchemaOutdated
+	case errors.Is(err, storage.ErrLeaseHeld):
+		return exitLeaseHeld
+	default:
+		return 1
 	}
 }
 
@@ -74,11 +113,31 @@ var rootCmd = &cobra.Command{
 	Long: titleStyle.Render("mark42") + " - A privacy-first, SQLite-based memory system\n\n" +
 		"Store entities, observations, and relations in a local database\n" +
 		"with full-text search capabilities.",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// Arguments were valid; a failure from here on is not a usage error
+		cmd.SilenceUsage = true
+		writeSource = commandSource(cmd)
+		if err := applyFlagEnv(cmd); err != nil {
+			return err
+		}
+		tokenizer = nil
+		if tokenizerModel == "" {
+			return nil
+		}
+		var err error
+		tokenizer, err = storage.TokenizerForModel(tokenizerModel)
+		return err
+	},
 }
 
 func init() {
-	defaultDB := filepath.Join(os.Getenv("HOME"), ".claude", "memory.db")
-	rootCmd.PersistentFlags().StringVar(&dbPath, "db", defaultDB, "path to database file")
+	home, _ := os.UserHomeDir() // $HOME, or %USERPROFILE% on Windows
+	defaultDB := filepath.Join(home, ".claude", "memory.db")
+	rootCmd.PersistentFlags().StringVar(&dbPath, "db", defaultDB, "path to database file, or a libsql:// URL for a hosted libSQL/Turso database (postgres:// is for pkg/memory only)")
+	rootCmd.PersistentFlags().BoolVar(&readOnly, "read-only", false,
+		"open the database read-only, to inspect one another process is writing; writes fail")
+	rootCmd.PersistentFlags().StringVar(&tokenizerModel, "tokenizer", "",
+		"model or encoding used to count tokens for context budgets (e.g. claude, gpt-4o, cl100k_base, chars)")
 
 	rootCmd.AddCommand(entityCmd)
 	rootCmd.AddCommand(obsCmd)
@@ -91,11 +150,79 @@ func init() {
 }
 
 func getStore() (*storage.Store, error) {
-	dir := filepath.Dir(dbPath)
-	if err := os.MkdirAll(dir, 0o755); err != nil {
+	open := storage.NewStore
+	if readOnly {
+		open = storage.NewStoreReadOnly
+	} else if storage.IsLocalPath(dbPath) {
+		if err := os.MkdirAll(filepath.Dir(dbPath), 0o755); err != nil {
+			return nil, err
+		}
+	}
+	store, err := open(dbPath)
+	if err != nil {
 		return nil, err
 	}
-	return storage.NewStore(dbPath)
+	cfg := loadEffectiveConfig(configProjectDir())
+	store.SetImportanceConfig(cfg.Importance)
+	store.SetAttributeSchemas(cfg.AttributeSchemas)
+	store.SetSource(writeSource)
+	store.SetUser(cfg.User)
+	store.SetAutoLinkMentions(cfg.AutoLinkMinName)
+	store.SetEntityTypes(cfg.EntityTypes)
+	store.SetRelationTypes(cfg.RelationTypes)
+	store.SetStopWords(cfg.StopWords)
+	store.SetTokenizer(tokenizer)
+	rules := cfg.Rules
+	if path := os.Getenv("CLAUDE_MEMORY_RULES"); path != "" {
+		if rules, err = storage.LoadRules(path); err != nil {
+			store.Close()
+			return nil, err
+		}
+	} else if err := rules.Validate(); err != nil {
+		store.Close()
+		return nil, fmt.Errorf("config.json rules: %w", err)
+	}
+	rules.Warn = func(v storage.RuleViolation) {
+		logger.Warn("rule violated", v.Kind, v.Subject, "reason", v.Reason)
+	}
+	store.SetRules(rules)
+	policies := cfg.Policies
+	if path := os.Getenv("CLAUDE_MEMORY_POLICIES"); path != "" {
+		if policies, err = storage.LoadPolicies(path); err != nil {
+			store.Close()
+			return nil, err
+		}
+	}
+	if err := store.SetPolicies(policies); err != nil {
+		store.Close()
+		return nil, fmt.Errorf("policies: %w", err)
+	}
+	redaction := cfg.Redaction
+	redaction.Warn = func(r storage.Redacted) {
+		logger.Warn("secret redacted", "entity", r.Entity, "types", strings.Join(r.Types, ", "))
+	}
+	if err := store.SetRedaction(redaction); err != nil {
+		store.Close()
+		return nil, fmt.Errorf("config.json redaction: %w", err)
+	}
+	if err := store.SetPIIMode(cfg.PIIMode); err != nil {
+		store.Close()
+		return nil, fmt.Errorf("config.json pii: %w", err)
+	}
+	store.SetUndoWindow(cfg.UndoWindow)
+	if on, _ := strconv.ParseBool(os.Getenv("CLAUDE_MEMORY_CASE_INSENSITIVE_NAMES")); on {
+		store.SetCaseInsensitiveNames(true)
+	}
+	return store, nil
+}
+
+// commandSource is the provenance of writes made by cmd: hook:<name> for
+// hooks, cli otherwise.
+func commandSource(cmd *cobra.Command) string {
+	if cmd.Parent() == hookCmd {
+		return storage.SourceHook + ":" + cmd.Name()
+	}
+	return storage.SourceCLI
 }
 
 // --- Entity commands ---
@@ -141,12 +268,17 @@ var entityGetCmd = &cobra.Command{
 		}
 		defer store.Close()
 
-		entity, err := store.GetEntity(args[0])
+		asOf, err := parseTimeFlag(cmd, "as-of")
+		if err != nil {
+			return err
+		}
+		var entity *storage.Entity
+		if asOf.IsZero() {
+			entity, err = store.GetEntity(args[0])
+		} else {
+			entity, err = store.GetEntityAsOf(args[0], asOf)
+		}
 		if err != nil {
-			if err == storage.ErrNotFound {
-				logger.Error("Entity not found", "name", args[0])
-				os.Exit(1)
-			}
 			return err
 		}
 
@@ -166,7 +298,8 @@ var entityListCmd = &cobra.Command{
 		defer store.Close()
 
 		entityType, _ := cmd.Flags().GetString("type")
-		entities, err := store.ListEntities(entityType)
+		user, _ := cmd.Flags().GetString("user")
+		entities, err := store.ListEntitiesWithFilter(storage.EntityFilter{Type: entityType, User: user})
 		if err != nil {
 			return err
 		}
@@ -177,7 +310,11 @@ var entityListCmd = &cobra.Command{
 		}
 
 		for _, e := range entities {
-			output(entityStyle.Render(e.Name) + " " + typeStyle.Render("("+e.Type+")"))
+			line := entityStyle.Render(e.Name) + " " + typeStyle.Render("("+e.Type+")")
+			if e.Owner != "" {
+				line += " " + dimStyle.Render("@"+e.Owner)
+			}
+			output(line)
 		}
 		return nil
 	},
@@ -195,10 +332,6 @@ var entityDeleteCmd = &cobra.Command{
 		defer store.Close()
 
 		if err := store.DeleteEntity(args[0]); err != nil {
-			if err == storage.ErrNotFound {
-				logger.Error("Entity not found", "name", args[0])
-				os.Exit(1)
-			}
 			return err
 		}
 
@@ -209,7 +342,9 @@ var entityDeleteCmd = &cobra.Command{
 
 func init() {
 	entityCreateCmd.Flags().StringSlice("obs", nil, "observations to add")
+	entityGetCmd.Flags().String("as-of", "", "show the entity as it was at this date (YYYY-MM-DD) or RFC 3339 time")
 	entityListCmd.Flags().String("type", "", "filter by entity type")
+	entityListCmd.Flags().String("user", "", "only entities this user created or wrote observations on")
 
 	entityCmd.AddCommand(entityCreateCmd)
 	entityCmd.AddCommand(entityGetCmd)
@@ -236,10 +371,6 @@ var obsAddCmd = &cobra.Command{
 		defer store.Close()
 
 		if err := store.AddObservation(args[0], args[1]); err != nil {
-			if err == storage.ErrNotFound {
-				logger.Error("Entity not found", "name", args[0])
-				os.Exit(1)
-			}
 			return err
 		}
 
@@ -260,10 +391,6 @@ var obsDeleteCmd = &cobra.Command{
 		defer store.Close()
 
 		if err := store.DeleteObservation(args[0], args[1]); err != nil {
-			if err == storage.ErrNotFound {
-				logger.Error("Observation not found")
-				os.Exit(1)
-			}
 			return err
 		}
 
@@ -272,9 +399,180 @@ var obsDeleteCmd = &cobra.Command{
 	},
 }
 
+var obsEditCmd = &cobra.Command{
+	Use:   "edit <entity> <old-content> <new-content>",
+	Short: "Edit an observation in place",
+	Long: `Replace an observation's text, keeping its creation time, fact type,
+importance and pin. The new text is re-embedded when the embedder at
+CLAUDE_MEMORY_EMBEDDER_URL is available; otherwise 'mark42 embed generate'
+fills it in later.`,
+	Args: cobra.ExactArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		if err := store.UpdateObservation(args[0], args[1], args[2]); err != nil {
+			return err
+		}
+
+		if embedder := embedderFromEnv(); embedder != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			embedding, err := embedder.CreateEmbedding(ctx, args[2])
+			if obs := store.GetObservationWithID(args[0], args[2]); err == nil && obs != nil {
+				err = store.StoreEmbedding(obs.ID, embedding, embedder.Model())
+			}
+			if err != nil {
+				logger.Debug("re-embedding skipped", "error", err)
+			}
+		}
+
+		logger.Info("Edited observation", "entity", entityStyle.Render(args[0]))
+		return nil
+	},
+}
+
+var obsPinCmd = &cobra.Command{
+	Use:   "pin <entity> <content>",
+	Short: "Pin an observation so it always appears in context",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setObservationPinned(args[0], args[1], true)
+	},
+}
+
+var obsUnpinCmd = &cobra.Command{
+	Use:   "unpin <entity> <content>",
+	Short: "Unpin an observation",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setObservationPinned(args[0], args[1], false)
+	},
+}
+
+var obsPinnedCmd = &cobra.Command{
+	Use:   "pinned",
+	Short: "List pinned observations",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		observations, err := store.ListPinnedObservations()
+		if err != nil {
+			return err
+		}
+
+		if len(observations) == 0 {
+			logger.Info("No pinned observations")
+			return nil
+		}
+
+		for _, o := range observations {
+			output(entityStyle.Render(o.EntityName) + " " + obsStyle.Render(o.Content))
+		}
+		return nil
+	},
+}
+
+var obsSuppressCmd = &cobra.Command{
+	Use:   "suppress <entity> <content>",
+	Short: "Hide a stale or wrong observation from search and context without deleting it",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setObservationSuppressed(args[0], args[1], true)
+	},
+}
+
+var obsUnsuppressCmd = &cobra.Command{
+	Use:   "unsuppress <entity> <content>",
+	Short: "Restore a suppressed observation",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setObservationSuppressed(args[0], args[1], false)
+	},
+}
+
+var obsSuppressedCmd = &cobra.Command{
+	Use:   "suppressed",
+	Short: "List suppressed observations",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		observations, err := store.ListSuppressedObservations()
+		if err != nil {
+			return err
+		}
+
+		if len(observations) == 0 {
+			logger.Info("No suppressed observations")
+			return nil
+		}
+
+		for _, o := range observations {
+			output(entityStyle.Render(o.EntityName) + " " + dimStyle.Render(o.Content))
+		}
+		return nil
+	},
+}
+
+func setObservationSuppressed(entityName, content string, suppressed bool) error {
+	store, err := getStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	if err := store.SetObservationSuppressed(entityName, content, suppressed); err != nil {
+		return err
+	}
+
+	if suppressed {
+		logger.Info("Suppressed observation", "entity", entityStyle.Render(entityName))
+	} else {
+		logger.Info("Restored observation", "entity", entityStyle.Render(entityName))
+	}
+	return nil
+}
+
+func setObservationPinned(entityName, content string, pinned bool) error {
+	store, err := getStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	if err := store.SetObservationPinned(entityName, content, pinned); err != nil {
+		return err
+	}
+
+	if pinned {
+		logger.Info("Pinned observation", "entity", entityStyle.Render(entityName))
+	} else {
+		logger.Info("Unpinned observation", "entity", entityStyle.Render(entityName))
+	}
+	return nil
+}
+
 func init() {
 	obsCmd.AddCommand(obsAddCmd)
 	obsCmd.AddCommand(obsDeleteCmd)
+	obsCmd.AddCommand(obsEditCmd)
+	obsCmd.AddCommand(obsPinCmd)
+	obsCmd.AddCommand(obsUnpinCmd)
+	obsCmd.AddCommand(obsPinnedCmd)
+	obsCmd.AddCommand(obsSuppressCmd)
+	obsCmd.AddCommand(obsUnsuppressCmd)
+	obsCmd.AddCommand(obsSuppressedCmd)
 }
 
 // --- Relation commands ---
@@ -295,18 +593,28 @@ var relCreateCmd = &cobra.Command{
 		}
 		defer store.Close()
 
-		if err := store.CreateRelation(args[0], args[1], args[2]); err != nil {
-			if err == storage.ErrNotFound {
-				logger.Error("One or both entities not found")
-				os.Exit(1)
-			}
+		validFrom, err := parseTimeFlag(cmd, "valid-from")
+		if err != nil {
+			return err
+		}
+		resolve, _ := cmd.Flags().GetBool("resolve")
+		createMissing, _ := cmd.Flags().GetBool("create-missing")
+		rel, err := store.CreateRelationResolved(args[0], args[1], args[2], storage.ResolveOptions{
+			Aliases:       resolve,
+			CreateMissing: createMissing,
+			ValidFrom:     validFrom,
+		})
+		if err != nil {
 			return err
 		}
 
+		for _, name := range rel.Created {
+			logger.Info("Created stub entity", "name", entityStyle.Render(name), "type", storage.StubEntityType)
+		}
 		logger.Info("Created relation",
-			"from", entityStyle.Render(args[0]),
+			"from", entityStyle.Render(rel.From),
 			"type", relationStyle.Render(args[2]),
-			"to", entityStyle.Render(args[1]))
+			"to", entityStyle.Render(rel.To))
 		return nil
 	},
 }
@@ -322,12 +630,12 @@ var relListCmd = &cobra.Command{
 		}
 		defer store.Close()
 
-		relations, err := store.ListRelations(args[0])
+		filter, err := relationFilterFlags(cmd)
+		if err != nil {
+			return err
+		}
+		relations, err := store.ListRelationsWithFilter(args[0], filter)
 		if err != nil {
-			if err == storage.ErrNotFound {
-				logger.Error("Entity not found", "name", args[0])
-				os.Exit(1)
-			}
 			return err
 		}
 
@@ -337,9 +645,14 @@ var relListCmd = &cobra.Command{
 		}
 
 		for _, r := range relations {
-			output(entityStyle.Render(r.From) + " " +
+			*r = store.OrientRelation(*r, args[0])
+			line := entityStyle.Render(r.From) + " " +
 				relationStyle.Render("─["+r.Type+"]→") + " " +
-				entityStyle.Render(r.To))
+				entityStyle.Render(r.To)
+			if r.ValidTo != nil {
+				line += " " + dimStyle.Render("(ended "+r.ValidTo.Local().Format(time.DateOnly)+")")
+			}
+			output(line)
 		}
 		return nil
 	},
@@ -357,10 +670,6 @@ var relDeleteCmd = &cobra.Command{
 		defer store.Close()
 
 		if err := store.DeleteRelation(args[0], args[1], args[2]); err != nil {
-			if err == storage.ErrNotFound {
-				logger.Error("Relation not found")
-				os.Exit(1)
-			}
 			return err
 		}
 
@@ -372,10 +681,67 @@ var relDeleteCmd = &cobra.Command{
 	},
 }
 
+var relEndCmd = &cobra.Command{
+	Use:   "end <from> <to> <type>",
+	Short: "End a relation that no longer holds, keeping it for history",
+	Args:  cobra.ExactArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		at, err := parseTimeFlag(cmd, "at")
+		if err != nil {
+			return err
+		}
+		if err := store.EndRelation(args[0], args[1], args[2], at); err != nil {
+			return err
+		}
+
+		logger.Info("Ended relation",
+			"from", args[0],
+			"type", args[2],
+			"to", args[1])
+		return nil
+	},
+}
+
 func init() {
+	relCreateCmd.Flags().Bool("resolve", false, "find endpoints by alias or ignoring case when no entity has the exact name")
+	relCreateCmd.Flags().Bool("create-missing", false, "create endpoints that aren't found as entities of type \"unknown\"")
+	relCreateCmd.Flags().String("valid-from", "", "date (YYYY-MM-DD) or RFC 3339 time the relation holds from (default: now)")
+	relListCmd.Flags().Bool("all", false, "include ended relations")
+	relListCmd.Flags().String("as-of", "", "list the relations valid at this date (YYYY-MM-DD) or RFC 3339 time")
+	relEndCmd.Flags().String("at", "", "date (YYYY-MM-DD) or RFC 3339 time the relation stopped holding (default: now)")
 	relCmd.AddCommand(relCreateCmd)
 	relCmd.AddCommand(relListCmd)
 	relCmd.AddCommand(relDeleteCmd)
+	relCmd.AddCommand(relEndCmd)
+}
+
+// parseTimeFlag parses a date (YYYY-MM-DD, local midnight) or RFC 3339 time
+// flag; an unset flag is the zero time.
+func parseTimeFlag(cmd *cobra.Command, name string) (time.Time, error) {
+	value, _ := cmd.Flags().GetString(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, &storage.ValidationError{Field: "--" + name, Reason: "must be YYYY-MM-DD or an RFC 3339 time"}
+}
+
+// relationFilterFlags reads the --all and --as-of flags.
+func relationFilterFlags(cmd *cobra.Command) (storage.RelationFilter, error) {
+	all, _ := cmd.Flags().GetBool("all")
+	asOf, err := parseTimeFlag(cmd, "as-of")
+	return storage.RelationFilter{AsOf: asOf, IncludeEnded: all}, err
 }
 
 // --- Search command ---
@@ -393,8 +759,24 @@ var searchCmd = &cobra.Command{
 
 		limit, _ := cmd.Flags().GetInt("limit")
 		format, _ := cmd.Flags().GetString("format")
+		includeSuppressed, _ := cmd.Flags().GetBool("include-suppressed")
+		if noStopWords, _ := cmd.Flags().GetBool("no-stopwords"); noStopWords {
+			store.SetStopWords(nil)
+		}
+		user, _ := cmd.Flags().GetString("user")
 
-		results, err := store.SearchWithLimit(args[0], limit)
+		attrFlags, _ := cmd.Flags().GetStringArray("attr")
+		attrs, err := parseAttributes(attrFlags)
+		if err != nil {
+			return err
+		}
+
+		results, err := store.SearchWithOptions(args[0], storage.SearchOptions{
+			Limit:             limit,
+			IncludeSuppressed: includeSuppressed,
+			Attributes:        attrs,
+			User:              user,
+		})
 		if err != nil {
 			return err
 		}
@@ -419,8 +801,13 @@ var searchCmd = &cobra.Command{
 				output()
 			}
 		default:
-			for _, r := range results {
+			explain, _ := cmd.Flags().GetBool("explain")
+			for i, r := range results {
 				printEntity(r.Entity)
+				if explain {
+					// BM25 is negated so higher is better, as in hybrid-search
+					output("  " + dimStyle.Render(fmt.Sprintf("fts #%d bm25 %.4f", i+1, -r.Score)))
+				}
 				output()
 			}
 		}
@@ -431,6 +818,11 @@ var searchCmd = &cobra.Command{
 func init() {
 	searchCmd.Flags().Int("limit", 10, "maximum number of results")
 	searchCmd.Flags().String("format", "default", "output format: default, json, context")
+	searchCmd.Flags().Bool("include-suppressed", false, "include suppressed observations")
+	searchCmd.Flags().StringArray("attr", nil, "only entities with this attribute, as key=value (repeatable; the query may then be empty)")
+	searchCmd.Flags().String("user", "", "only entities this user created or wrote observations on (the query may then be empty)")
+	searchCmd.Flags().Bool("no-stopwords", false, "search for every word of the query, stop words included")
+	searchCmd.Flags().Bool("explain", false, "show each result's BM25 rank and score")
 }
 
 // --- Hybrid Search command ---
@@ -460,8 +852,10 @@ Falls back to FTS-only search if Ollama is unavailable.`,
 
 		limit, _ := cmd.Flags().GetInt("limit")
 		format, _ := cmd.Flags().GetString("format")
-		model, _ := cmd.Flags().GetString("model")
-		url, _ := cmd.Flags().GetString("url")
+		url, model := embedderSettings(cmd)
+		if noStopWords, _ := cmd.Flags().GetBool("no-stopwords"); noStopWords {
+			store.SetStopWords(nil)
+		}
 
 		// Create embedding client
 		client := storage.NewEmbeddingClient(url)
@@ -470,7 +864,8 @@ Falls back to FTS-only search if Ollama is unavailable.`,
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
 
-		results, err := store.HybridSearchWithEmbedder(ctx, args[0], client, limit)
+		includeSuppressed, _ := cmd.Flags().GetBool("include-suppressed")
+		results, err := store.HybridSearchWithEmbedder(ctx, args[0], client, limit, storage.HybridOptions{IncludeSuppressed: includeSuppressed})
 		if err != nil {
 			return err
 		}
@@ -520,20 +915,24 @@ Falls back to FTS-only search if Ollama is unavailable.`,
 			}
 		default:
 			// Default: show results with scores
+			explain, _ := cmd.Flags().GetBool("explain")
 			output(titleStyle.Render("Hybrid Search Results"))
 			output()
 			for _, r := range results {
 				score := fmt.Sprintf("%.4f", r.FusionScore)
 				// Build sources list from SourceScores map
 				var sources []string
-				for source := range r.SourceScores {
-					sources = append(sources, source)
+				for _, part := range r.Explain() {
+					sources = append(sources, part.Source)
 				}
 				sourcesStr := strings.Join(sources, ", ")
 				output(entityStyle.Render(r.EntityName) + " " +
 					typeStyle.Render("("+r.EntityType+")") + " " +
 					dimStyle.Render("["+score+"] ["+sourcesStr+"]"))
 				output("  " + obsStyle.Render(r.Content))
+				if explain {
+					output("  " + dimStyle.Render(explainFused(r)))
+				}
 				output()
 			}
 		}
@@ -546,17 +945,46 @@ func init() {
 
 	hybridSearchCmd.Flags().Int("limit", 10, "maximum number of results")
 	hybridSearchCmd.Flags().String("format", "default", "output format: default, json, context")
-	hybridSearchCmd.Flags().String("model", "nomic-embed-text", "embedding model for vector search")
+	hybridSearchCmd.Flags().String("model", storage.DefaultEmbeddingModel, "embedding model for vector search")
 	hybridSearchCmd.Flags().String("url", defaultOllamaURL, "Ollama API URL")
+	hybridSearchCmd.Flags().Bool("no-stopwords", false, "search for every word of the query, stop words included")
+	hybridSearchCmd.Flags().Bool("include-suppressed", false, "include suppressed observations")
+	hybridSearchCmd.Flags().Bool("explain", false, "show how each result was scored: per-source rank, score, and RRF contribution")
 
 	rootCmd.AddCommand(hybridSearchCmd)
 }
 
+// explainFused describes how r's fusion score was reached, e.g.
+// "fts #2 bm25 3.1000 → +0.0161 · vector #1 similarity 0.8200 → +0.0164 · boost ×1.50 = 0.0488".
+func explainFused(r storage.FusedResult) string {
+	var parts []string
+	for _, p := range r.Explain() {
+		label := "score"
+		switch p.Source {
+		case "fts":
+			label = "bm25"
+		case "vector":
+			label = "similarity"
+		}
+		parts = append(parts, fmt.Sprintf("%s #%d %s %.4f → +%.4f", p.Source, p.Rank, label, p.Score, p.Contribution))
+	}
+	if r.Boost != 0 {
+		parts = append(parts, fmt.Sprintf("boost ×%.2f", r.Boost))
+	}
+	return strings.Join(parts, " · ") + fmt.Sprintf(" = %.4f", r.FusionScore)
+}
+
 // --- Graph command ---
 
 var graphCmd = &cobra.Command{
 	Use:   "graph",
 	Short: "Output the entire knowledge graph",
+	Long: `Output the knowledge graph.
+
+--tag and --type export part of it: the entities with that container tag and
+type, their observations, and only the relations between two of them. A scoped
+replica export carries no deletions, so merging it elsewhere only adds and
+updates.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		store, err := getStore()
 		if err != nil {
@@ -564,12 +992,32 @@ var graphCmd = &cobra.Command{
 		}
 		defer store.Close()
 
-		graph, err := store.ReadGraph()
+		format, _ := cmd.Flags().GetString("format")
+		filter, err := relationFilterFlags(cmd)
 		if err != nil {
 			return err
 		}
+		var scope storage.GraphScope
+		scope.ContainerTag, _ = cmd.Flags().GetString("tag")
+		scope.Type, _ = cmd.Flags().GetString("type")
 
-		format, _ := cmd.Flags().GetString("format")
+		switch format {
+		case "replica":
+			embeddings, _ := cmd.Flags().GetBool("embeddings")
+			return writeReplica(store, scope, embeddings)
+		case "ndjson":
+			if scope != (storage.GraphScope{}) {
+				return fmt.Errorf("--tag and --type do not apply to --format ndjson")
+			}
+			pageSize, _ := cmd.Flags().GetInt("page-size")
+			noObs, _ := cmd.Flags().GetBool("no-observations")
+			return writeGraphNDJSON(store, pageSize, noObs, filter)
+		}
+
+		graph, err := store.ReadGraphScoped(scope, filter)
+		if err != nil {
+			return err
+		}
 
 		switch format {
 		case "dot":
@@ -592,7 +1040,57 @@ var graphCmd = &cobra.Command{
 }
 
 func init() {
-	graphCmd.Flags().String("format", "json", "output format: json, dot")
+	graphCmd.Flags().String("format", "json", "output format: json, dot, ndjson (streamed), replica (NDJSON for mark42 merge)")
+	graphCmd.Flags().Int("page-size", 500, "entities read per page with --format ndjson")
+	graphCmd.Flags().Bool("no-observations", false, "omit observations with --format ndjson")
+	graphCmd.Flags().Bool("embeddings", false, "include observation embeddings with --format replica")
+	graphCmd.Flags().String("tag", "", "only entities with this container tag")
+	graphCmd.Flags().String("type", "", "only entities of this type")
+	graphCmd.Flags().Bool("all", false, "include ended relations")
+	graphCmd.Flags().String("as-of", "", "the graph as it was at this date (YYYY-MM-DD) or RFC 3339 time")
+}
+
+// graphLine is one line of `graph --format ndjson`: an entity, or a relation
+// following the entity it starts at.
+type graphLine struct {
+	Kind         string     `json:"kind"`
+	Name         string     `json:"name,omitempty"`
+	From         string     `json:"from,omitempty"`
+	To           string     `json:"to,omitempty"`
+	Type         string     `json:"type"`
+	Observations []string   `json:"observations,omitempty"`
+	ValidTo      *time.Time `json:"validTo,omitempty"` // Ended relations, with --all
+}
+
+// writeGraphNDJSON streams the graph a page at a time, so memory use stays
+// bounded by the page size rather than the graph.
+func writeGraphNDJSON(store *storage.Store, pageSize int, excludeObservations bool, relations storage.RelationFilter) error {
+	if pageSize <= 0 {
+		return fmt.Errorf("--page-size must be positive")
+	}
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	opts := storage.GraphPageOptions{Limit: pageSize, ExcludeObservations: excludeObservations, Relations: relations, AsOf: relations.AsOf}
+	for {
+		page, err := store.ReadGraphPage(opts)
+		if err != nil {
+			return err
+		}
+		for _, e := range page.Entities {
+			if err := enc.Encode(graphLine{Kind: "entity", Name: e.Name, Type: e.Type, Observations: e.Observations}); err != nil {
+				return err
+			}
+		}
+		for _, r := range page.Relations {
+			if err := enc.Encode(graphLine{Kind: "relation", From: r.From, To: r.To, Type: r.Type, ValidTo: r.ValidTo}); err != nil {
+				return err
+			}
+		}
+		if page.NextOffset == 0 {
+			return nil
+		}
+		opts.Offset = page.NextOffset
+	}
 }
 
 // --- Init command ---
@@ -600,23 +1098,68 @@ func init() {
 var initCmd = &cobra.Command{
 	Use:   "init",
 	Short: "Initialize the database",
+	Long: `Initialize the database, building the full-text indexes with the tokenizer
+under "search" in config.json if they were built with another.
+
+--template seeds a starter knowledge pack: it registers the pack's entity and
+relation types in ~/.claude/mark42/config.json and creates example convention
+entities with static observations. Types and entities that already exist are
+left alone, so running it again is safe. Templates:` + templateHelp(),
 	RunE: func(cmd *cobra.Command, args []string) error {
+		template, _ := cmd.Flags().GetString("template")
+		if _, ok := starterTemplates[template]; template != "" && !ok {
+			return fmt.Errorf("unknown template %q (available: %s)", template, strings.Join(templateNames(), ", "))
+		}
+
 		store, err := getStore()
 		if err != nil {
 			return err
 		}
 		defer store.Close()
 
+		tokenizer := loadEffectiveConfig(configProjectDir()).FTSTokenizer
+		if reindexed, err := applyFTSTokenizer(store, tokenizer); err != nil {
+			return err
+		} else if reindexed {
+			logger.Info("Rebuilt search index", "tokenizer", tokenizer.Spec())
+		}
+
 		logger.Info("Database initialized", "path", dimStyle.Render(dbPath))
+
+		if template != "" {
+			if err := store.Migrate(); err != nil {
+				return err
+			}
+			result, err := applyTemplate(store, template, globalConfigDir())
+			if err != nil {
+				return err
+			}
+			output(successStyle.Render(fmt.Sprintf("✓ Applied template %s", template)))
+			output(dimStyle.Render(fmt.Sprintf("  %d entity types and %d relation types registered, %d entities and %d relations created",
+				result.EntityTypes, result.RelationTypes, result.Entities, result.Relations)))
+			if result.Existing > 0 {
+				output(dimStyle.Render(fmt.Sprintf("  %d entities already existed and were left as they are", result.Existing)))
+			}
+		}
 		return nil
 	},
 }
 
+func init() {
+	initCmd.Flags().String("template", "", "seed a starter knowledge pack: "+strings.Join(templateNames(), ", "))
+}
+
 // --- Stats command ---
 
 var statsCmd = &cobra.Command{
 	Use:   "stats",
 	Short: "Show database statistics",
+	Long: `Show totals, the database size, embedding coverage, and breakdowns of
+entities by type and container (sessions count under their project),
+observations by fact type, and the most connected entities.
+
+--since adds the records added per week since that date, to see how fast
+memory grows.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		store, err := getStore()
 		if err != nil {
@@ -624,39 +1167,114 @@ var statsCmd = &cobra.Command{
 		}
 		defer store.Close()
 
-		graph, err := store.ReadGraph()
+		since, err := parseTimeFlag(cmd, "since")
 		if err != nil {
 			return err
 		}
+		stats, err := store.GetDatabaseStats()
+		if err != nil {
+			return err
+		}
+		var growth []storage.GrowthWeek
+		if !since.IsZero() {
+			if growth, err = store.GetGrowth(since); err != nil {
+				return err
+			}
+		}
 
-		obsCount := 0
-		for _, e := range graph.Entities {
-			obsCount += len(e.Observations)
+		if format, _ := cmd.Flags().GetString("format"); format == "json" {
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			return enc.Encode(struct {
+				*storage.DatabaseStats
+				Growth []storage.GrowthWeek `json:"growth,omitempty"`
+			}{stats, growth})
 		}
 
 		output(titleStyle.Render("Database Statistics"))
 		output()
 		output("  " + dimStyle.Render("Path:") + "         " + dbPath)
-		output("  " + dimStyle.Render("Entities:") + "     " + successStyle.Render(itoa(len(graph.Entities))))
-		output("  " + dimStyle.Render("Observations:") + " " + successStyle.Render(itoa(obsCount)))
-		output("  " + dimStyle.Render("Relations:") + "    " + successStyle.Render(itoa(len(graph.Relations))))
+		output("  " + dimStyle.Render("Size:") + "         " + formatBytes(stats.SizeBytes))
+		output("  " + dimStyle.Render("Entities:") + "     " + successStyle.Render(itoa(stats.Entities)))
+		output("  " + dimStyle.Render("Observations:") + " " + successStyle.Render(itoa(stats.Observations)))
+		output("  " + dimStyle.Render("Relations:") + "    " + successStyle.Render(itoa(stats.Relations)))
+
+		pct := 0.0
+		if stats.Observations > 0 {
+			pct = float64(stats.Embedded) / float64(stats.Observations) * 100
+		}
+		indicator := ""
+		if stats.Embedded < stats.Observations {
+			indicator = " !"
+		}
+		output("  " + dimStyle.Render("Embeddings:") + "   " + successStyle.Render(fmt.Sprintf("%d/%d (%.1f%%)", stats.Embedded, stats.Observations, pct)) + indicator)
+
+		printStatCounts("Entities by type", stats.EntitiesByType)
+		printStatCounts("Observations by fact type", stats.ObservationsByFactType)
+		printStatCounts("Entities by container", stats.EntitiesByContainer)
+		printStatCounts(fmt.Sprintf("Personal data (%d observations)", stats.PII), stats.PIIByKind)
+		if len(stats.MostConnected) > 0 {
+			output()
+			output(titleStyle.Render("Most connected"))
+			for _, e := range stats.MostConnected {
+				output("  " + fmt.Sprintf("%6d", e.Relations) + "  " + entityStyle.Render(e.Name))
+			}
+		}
 
-		if total, withEmb, err := store.EmbeddingStats(); err == nil {
-			pct := 0.0
-			if total > 0 {
-				pct = float64(withEmb) / float64(total) * 100
+		if !since.IsZero() {
+			output()
+			output(titleStyle.Render("Added per week since " + since.Format(time.DateOnly)))
+			if len(growth) == 0 {
+				output("  " + dimStyle.Render("Nothing added"))
 			}
-			indicator := ""
-			if total > 0 && withEmb < total {
-				indicator = " !"
+			for _, w := range growth {
+				output("  " + dimStyle.Render(w.Week) + fmt.Sprintf("  %5d entities  %6d observations  %5d relations",
+					w.Entities, w.Observations, w.Relations))
 			}
-			output("  " + dimStyle.Render("Embeddings:") + "   " + successStyle.Render(fmt.Sprintf("%d/%d (%.1f%%)", withEmb, total, pct)) + indicator)
 		}
-
 		return nil
 	},
 }
 
+// keepLimit renders a retention limit, 0 being none.
+func keepLimit(n int) string {
+	if n <= 0 {
+		return "no limit"
+	}
+	return itoa(n) + " memories"
+}
+
+// printStatCounts prints a titled breakdown, or nothing when it is empty.
+func printStatCounts(title string, counts []storage.StatCount) {
+	if len(counts) == 0 {
+		return
+	}
+	output()
+	output(titleStyle.Render(title))
+	for _, c := range counts {
+		output("  " + fmt.Sprintf("%6d", c.Count) + "  " + typeStyle.Render(c.Key))
+	}
+}
+
+// formatBytes renders a size in bytes with a binary unit, e.g. "1.5 MiB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func init() {
+	statsCmd.Flags().String("since", "", "also show records added per week since this date (YYYY-MM-DD) or RFC 3339 time")
+	statsCmd.Flags().String("format", "default", "output format: default, json")
+}
+
 // --- Version command ---
 
 var versionCmd = &cobra.Command{
@@ -722,6 +1340,7 @@ Supports two formats:
 			return err
 		}
 		defer store.Close()
+		store.SetSource(storage.SourceImport + ":" + filepath.Base(fromPath))
 
 		var entities []jsonEntity
 		var relations []jsonRelation
@@ -808,7 +1427,11 @@ func init() {
 var upgradeCmd = &cobra.Command{
 	Use:   "upgrade",
 	Short: "Run database schema migrations",
-	Long:  "Applies pending schema migrations to upgrade the database to the latest version.",
+	Long: `Applies pending schema migrations to upgrade the database to the latest version.
+
+With --check, applies nothing and exits with status 5 if migrations are pending.
+Otherwise, when migrations are pending, the database is snapshotted first
+unless --no-snapshot is given; see snapshot.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		store, err := getStore()
 		if err != nil {
@@ -816,10 +1439,24 @@ var upgradeCmd = &cobra.Command{
 		}
 		defer store.Close()
 
+		if check, _ := cmd.Flags().GetBool("check"); check {
+			if err := store.CheckSchema(); err != nil {
+				return err
+			}
+			output("  " + dimStyle.Render("Status:") + "  " + successStyle.Render("Up to date"))
+			return nil
+		}
+
 		beforeVersion, err := store.GetSchemaVersion()
 		if err != nil {
 			return err
 		}
+		// A new database has nothing worth keeping
+		if beforeVersion > 0 && errors.Is(store.CheckSchema(), storage.ErrSchemaOutdated) {
+			if err := snapshotBefore(cmd, store, "upgrade"); err != nil {
+				return err
+			}
+		}
 
 		if err := store.Migrate(); err != nil {
 			return err
@@ -845,15 +1482,18 @@ var upgradeCmd = &cobra.Command{
 }
 
 func init() {
+	upgradeCmd.Flags().Bool("check", false, "report pending migrations without applying them")
+	addNoSnapshotFlag(upgradeCmd)
 	rootCmd.AddCommand(upgradeCmd)
 }
 
 // --- Embed commands ---
 
 var (
-	ollamaURL  string
-	embedModel string
-	embedBatch int
+	ollamaURL   string
+	embedModel  string
+	embedBatch  int
+	embedTokens int
 )
 
 var embedCmd = &cobra.Command{
@@ -866,15 +1506,18 @@ var embedTestCmd = &cobra.Command{
 	Short: "Test Ollama embedding generation",
 	Long: `Test that Ollama is running and can generate embeddings.
 
-If no text is provided, uses "Hello, world!" as test input.`,
+If no text is provided, uses "Hello, world!" as test input. The model's
+dimensions and maximum input length are recorded in the database, and
+embeddings stored later are checked against them.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		text := "Hello, world!"
 		if len(args) > 0 {
 			text = strings.Join(args, " ")
 		}
 
-		client := storage.NewEmbeddingClient(ollamaURL)
-		client.SetModel(embedModel)
+		url, embedderModel := embedderSettings(cmd)
+		client := storage.NewEmbeddingClient(url)
+		client.SetModel(embedderModel)
 
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
@@ -885,24 +1528,38 @@ If no text is provided, uses "Hello, world!" as test input.`,
 
 		if err != nil {
 			logger.Error("Embedding failed - is Ollama running?",
-				"url", ollamaURL,
+				"url", url,
 				"error", err)
 			output()
 			output(dimStyle.Render("To start Ollama:"))
 			output("  ollama serve")
 			output()
-			output(dimStyle.Render("To pull the embedding model:"))
-			output("  ollama pull " + embedModel)
+			output(dimStyle.Render("To pull the embedding embedderModel:"))
+			output("  ollama pull " + embedderModel)
 			os.Exit(1)
 		}
 
 		output(titleStyle.Render("Embedding Test"))
 		output()
-		output("  " + dimStyle.Render("URL:") + "        " + ollamaURL)
-		output("  " + dimStyle.Render("Model:") + "      " + embedModel)
+		output("  " + dimStyle.Render("URL:") + "        " + url)
+		output("  " + dimStyle.Render("Model:") + "      " + embedderModel)
 		output("  " + dimStyle.Render("Input:") + "      " + text)
 		output("  " + dimStyle.Render("Dimensions:") + " " + successStyle.Render(itoa(len(embedding))))
 		output("  " + dimStyle.Render("Time:") + "       " + successStyle.Render(elapsed.String()))
+
+		// Record what was learned, so generate can check vectors against it
+		model, err := client.Probe(ctx)
+		if err == nil {
+			maxInput := "unknown"
+			if model.MaxInputTokens > 0 {
+				maxInput = itoa(model.MaxInputTokens) + " tokens"
+			}
+			output("  " + dimStyle.Render("Max input:") + "  " + maxInput)
+			err = recordEmbeddingModel(*model)
+		}
+		if err != nil {
+			output("  " + dimStyle.Render("Not recorded: "+err.Error()))
+		}
 		output()
 		output(successStyle.Render("✓ Ollama is working!"))
 
@@ -910,10 +1567,34 @@ If no text is provided, uses "Hello, world!" as test input.`,
 	},
 }
 
+// recordEmbeddingModel saves model's metadata in the database.
+func recordEmbeddingModel(model storage.EmbeddingModel) error {
+	store, err := getStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	return store.SaveEmbeddingModel(model)
+}
+
+// describeEmbeddingModel summarizes model's dimensions and input limit.
+func describeEmbeddingModel(model *storage.EmbeddingModel) string {
+	desc := itoa(model.Dimensions) + " dims"
+	if model.MaxInputTokens > 0 {
+		desc += ", " + itoa(model.MaxInputTokens) + " tokens max input"
+	}
+	return "(" + desc + ")"
+}
+
 var embedGenerateCmd = &cobra.Command{
 	Use:   "generate",
 	Short: "Generate embeddings for all observations",
-	Long:  "Generates embeddings for observations that don't have them yet.",
+	Long: `Generates embeddings for observations that don't have them yet.
+
+The first time a model is used it is probed for its dimensions and maximum
+input length (Ollama reports the latter), which are recorded. Vectors of other
+dimensions are refused, and observations longer than the maximum are embedded
+in parts rather than cut short.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		store, err := getStore()
 		if err != nil {
@@ -925,6 +1606,11 @@ var embedGenerateCmd = &cobra.Command{
 		if err := store.Migrate(); err != nil {
 			return err
 		}
+		release, err := takeWriteLease(cmd, store)
+		if err != nil {
+			return err
+		}
+		defer release()
 
 		// Get observations without embeddings
 		observations, err := store.GetObservationsWithoutEmbeddings()
@@ -937,52 +1623,31 @@ var embedGenerateCmd = &cobra.Command{
 			return nil
 		}
 
+		url, embedderModel := embedderSettings(cmd)
+		client := storage.NewEmbeddingClient(url)
+		client.SetModel(embedderModel)
+		client.SetBatchLimits(embedBatch, embedTokens)
+
+		ctx := context.Background()
+		model, err := store.PrepareEmbedder(ctx, client)
+		if err != nil {
+			return fmt.Errorf("probing %s - is Ollama running? %w", embedderModel, err)
+		}
+
 		output(titleStyle.Render("Generating Embeddings"))
 		output()
 		output("  " + dimStyle.Render("Observations:") + " " + itoa(len(observations)))
-		output("  " + dimStyle.Render("Model:") + "        " + embedModel)
+		output("  " + dimStyle.Render("Model:") + "        " + embedderModel + " " + dimStyle.Render(describeEmbeddingModel(model)))
 		output("  " + dimStyle.Render("Batch size:") + "   " + itoa(embedBatch))
 		output()
 
-		client := storage.NewEmbeddingClient(ollamaURL)
-		client.SetModel(embedModel)
-
-		ctx := context.Background()
 		start := time.Now()
-		processed := 0
-
-		// Process in batches
-		for i := 0; i < len(observations); i += embedBatch {
-			end := i + embedBatch
-			if end > len(observations) {
-				end = len(observations)
-			}
-
-			batch := observations[i:end]
-			texts := make([]string, len(batch))
-			for j, obs := range batch {
-				texts[j] = obs.Content
-			}
-
-			embeddings, err := client.CreateBatchEmbedding(ctx, texts)
-			if err != nil {
-				logger.Error("Batch embedding failed",
-					"batch", i/embedBatch+1,
-					"error", err)
-				continue
-			}
-
-			if err := store.BatchStoreEmbeddings(batch, embeddings, embedModel); err != nil {
-				logger.Error("Failed to store embeddings", "error", err)
-				continue
-			}
-
-			processed += len(batch)
+		processed, _ := embedObservations(ctx, store, client, embedderModel, observations, func(processed int) {
 			progress := float64(processed) / float64(len(observations)) * 100
 			fmt.Printf("\r  Progress: %s%.1f%% (%d/%d)%s",
 				successStyle.Render(""), progress, processed, len(observations),
 				strings.Repeat(" ", 10))
-		}
+		})
 
 		elapsed := time.Since(start)
 		output()
@@ -996,6 +1661,45 @@ var embedGenerateCmd = &cobra.Command{
 	},
 }
 
+// embedObservations embeds observations in batches of --batch and stores
+// the vectors, calling progress with the count done after each batch. A
+// failed batch is logged and skipped; the first failure is returned with
+// the count embedded.
+func embedObservations(ctx context.Context, store *storage.Store, client *storage.EmbeddingClient, model string,
+	observations []storage.ObservationWithID, progress func(processed int)) (int, error) {
+	processed := 0
+	var firstErr error
+	for i := 0; i < len(observations); i += embedBatch {
+		end := min(i+embedBatch, len(observations))
+		batch := observations[i:end]
+		texts := make([]string, len(batch))
+		for j, obs := range batch {
+			texts[j] = obs.Content
+		}
+
+		embeddings, err := client.CreateBatchEmbedding(ctx, texts)
+		if err != nil {
+			logger.Error("Batch embedding failed",
+				"batch", i/embedBatch+1,
+				"error", err)
+			firstErr = cmp.Or(firstErr, err)
+			continue
+		}
+
+		if err := store.BatchStoreEmbeddingsContext(ctx, batch, embeddings, model); err != nil {
+			logger.Error("Failed to store embeddings", "error", err)
+			firstErr = cmp.Or(firstErr, err)
+			continue
+		}
+
+		processed += len(batch)
+		if progress != nil {
+			progress(processed)
+		}
+	}
+	return processed, firstErr
+}
+
 var embedStatsCmd = &cobra.Command{
 	Use:   "stats",
 	Short: "Show embedding statistics",
@@ -1023,6 +1727,46 @@ var embedStatsCmd = &cobra.Command{
 		output("  " + dimStyle.Render("Without embeddings:") + "     " + itoa(total-withEmbeddings))
 		output("  " + dimStyle.Render("Coverage:") + "               " + successStyle.Render(fmt.Sprintf("%.1f%%", coverage)))
 
+		models, err := store.ListEmbeddingModels()
+		if err != nil {
+			return err
+		}
+		if len(models) > 0 {
+			output()
+			output(titleStyle.Render("Models"))
+			for _, m := range models {
+				output("  " + m.Name + " " + dimStyle.Render(describeEmbeddingModel(&m)))
+			}
+		}
+
+		return nil
+	},
+}
+
+var embedClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove the embeddings of a model",
+	Long: `Remove the stored embeddings of --model, so the next embed generate embeds
+every observation again. Needed before a model's dimensions can change.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		release, err := takeWriteLease(cmd, store)
+		if err != nil {
+			return err
+		}
+		defer release()
+		_, model := embedderSettings(cmd)
+		n, err := store.ClearEmbeddings(model)
+		if err != nil {
+			return err
+		}
+		output(successStyle.Render("✓") + fmt.Sprintf(" Removed %d embeddings from %s", n, model))
 		return nil
 	},
 }
@@ -1031,12 +1775,16 @@ func init() {
 	defaultOllamaURL := storage.DefaultOllamaBaseURL()
 
 	embedCmd.PersistentFlags().StringVar(&ollamaURL, "url", defaultOllamaURL, "Ollama API URL")
-	embedCmd.PersistentFlags().StringVar(&embedModel, "model", "nomic-embed-text", "embedding model name")
-	embedGenerateCmd.Flags().IntVar(&embedBatch, "batch", 10, "batch size for embedding generation")
+	embedCmd.PersistentFlags().StringVar(&embedModel, "model", storage.DefaultEmbeddingModel, "embedding model name")
+	embedGenerateCmd.Flags().IntVar(&embedBatch, "batch", 100, "observations embedded per request")
+	embedGenerateCmd.Flags().IntVar(&embedTokens, "batch-tokens", storage.DefaultEmbeddingBatchTokens, "estimated tokens per request; larger batches are split")
+	addWaitFlag(embedGenerateCmd)
+	addWaitFlag(embedClearCmd)
 
 	embedCmd.AddCommand(embedTestCmd)
 	embedCmd.AddCommand(embedGenerateCmd)
 	embedCmd.AddCommand(embedStatsCmd)
+	embedCmd.AddCommand(embedClearCmd)
 	rootCmd.AddCommand(embedCmd)
 }
 
@@ -1067,6 +1815,11 @@ This helps prioritize which memories to include in context injection.`,
 		if err := store.Migrate(); err != nil {
 			return err
 		}
+		release, err := takeWriteLease(cmd, store)
+		if err != nil {
+			return err
+		}
+		defer release()
 
 		start := time.Now()
 		updated, err := store.RecalculateImportance()
@@ -1134,9 +1887,68 @@ var importanceStatsCmd = &cobra.Command{
 	},
 }
 
+var importanceConfigCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect importance and decay configuration",
+}
+
+var importanceConfigShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show effective importance and decay settings",
+	Long: `Show the importance and decay settings in effect after layering the
+built-in defaults, ~/.claude/mark42/config.json, and the project's
+.claude/mark42/config.json and .mark42/config.json.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := loadEffectiveConfig(configProjectDir())
+
+		output(titleStyle.Render("Importance Configuration"))
+		output()
+		output("  " + dimStyle.Render("Decay constant:") + "    " + fmt.Sprintf("%.1f days", cfg.Importance.DecayConstant))
+		output("  " + dimStyle.Render("Recency weight:") + "    " + fmt.Sprintf("%.2f", cfg.Importance.RecencyWeight))
+		output("  " + dimStyle.Render("Frequency weight:") + "  " + fmt.Sprintf("%.2f", cfg.Importance.FrequencyWeight))
+		output("  " + dimStyle.Render("Centrality weight:") + " " + fmt.Sprintf("%.2f", cfg.Importance.CentralityWeight))
+		for _, factType := range slices.Sorted(maps.Keys(cfg.Importance.FactTypeBoosts)) {
+			output("  " + dimStyle.Render("Boost ("+factType+"):") + " " + fmt.Sprintf("%.2fx", cfg.Importance.FactTypeBoosts[factType]))
+		}
+		for _, relationType := range slices.Sorted(maps.Keys(cfg.Importance.RelationWeights)) {
+			output("  " + dimStyle.Render("Relation weight ("+relationType+"):") + " " + fmt.Sprintf("%.2fx", cfg.Importance.RelationWeight(relationType)))
+		}
+
+		output()
+		output(titleStyle.Render("Decay Configuration"))
+		output()
+		output("  " + dimStyle.Render("Soft decay threshold:") + "   " + fmt.Sprintf("%.2f", cfg.Decay.SoftDecayThreshold))
+		output("  " + dimStyle.Render("Archive after:") + "          " + itoa(cfg.Decay.ArchiveAfterDays) + " days")
+		output("  " + dimStyle.Render("Forget after:") + "           " + itoa(cfg.Decay.ForgetAfterDays) + " days")
+		output("  " + dimStyle.Render("Min importance to keep:") + " " + fmt.Sprintf("%.2f", cfg.Decay.MinImportanceToKeep))
+		if cfg.Decay.DigestAfterDays > 0 {
+			output("  " + dimStyle.Render("Digest archive after:") + "   " + itoa(cfg.Decay.DigestAfterDays) + " days")
+		} else {
+			output("  " + dimStyle.Render("Digest archive after:") + "   never")
+		}
+		output("  " + dimStyle.Render("Keep per entity:") + "        " + keepLimit(cfg.Decay.KeepPerEntity))
+		output("  " + dimStyle.Render("Keep per container:") + "     " + keepLimit(cfg.Decay.KeepPerContainer))
+
+		output()
+		if len(cfg.Sources) == 0 {
+			output(dimStyle.Render("Using built-in defaults (no config files found)"))
+		} else {
+			output(dimStyle.Render("Loaded from:"))
+			for _, source := range cfg.Sources {
+				output("  " + source)
+			}
+		}
+
+		return nil
+	},
+}
+
 func init() {
+	importanceConfigCmd.AddCommand(importanceConfigShowCmd)
+	addWaitFlag(importanceRecalculateCmd)
 	importanceCmd.AddCommand(importanceRecalculateCmd)
 	importanceCmd.AddCommand(importanceStatsCmd)
+	importanceCmd.AddCommand(importanceConfigCmd)
 	rootCmd.AddCommand(importanceCmd)
 }
 
@@ -1148,7 +1960,12 @@ var contextCmd = &cobra.Command{
 	Long: `Get memories optimized for context injection at session start.
 
 Orders by fact type (static > dynamic > session_turn), then by importance.
-Respects token budget to avoid context overflow.`,
+Respects token budget to avoid context overflow. The budget, its per-fact-type
+shares, and the other "context" settings come from config.json; flags given
+explicitly win.
+
+Output layout is controlled by --template: one of the built-in templates
+(default, compact, xml-tags, markdown) or a path to a Go text/template file.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		store, err := getStore()
 		if err != nil {
@@ -1164,14 +1981,28 @@ Respects token budget to avoid context overflow.`,
 		tokenBudget, _ := cmd.Flags().GetInt("token-budget")
 		minImportance, _ := cmd.Flags().GetFloat64("min-importance")
 		projectName, _ := cmd.Flags().GetString("project")
+		templateName, _ := cmd.Flags().GetString("template")
+		noDedup, _ := cmd.Flags().GetBool("no-dedup")
+		user, _ := cmd.Flags().GetString("user")
+
+		tmpl, err := storage.LoadContextTemplate(templateName)
+		if err != nil {
+			return err
+		}
 
-		cfg := storage.DefaultContextConfig()
-		if tokenBudget > 0 {
+		// Flags given explicitly override the config files
+		cfg := loadEffectiveConfig(configProjectDir()).ContextConfig(storage.DefaultContextConfig())
+		if cmd.Flags().Changed("token-budget") && tokenBudget > 0 {
 			cfg.TokenBudget = tokenBudget
 		}
-		if minImportance > 0 {
+		if cmd.Flags().Changed("min-importance") && minImportance > 0 {
 			cfg.MinImportance = minImportance
 		}
+		if boost, _ := cmd.Flags().GetFloat64("project-boost"); cmd.Flags().Changed("project-boost") && boost > 0 {
+			cfg.ProjectBoost = boost
+		}
+		cfg.Dedup = !noDedup
+		cfg.User = user
 
 		results, err := store.GetContextForInjection(cfg, projectName)
 		if err != nil {
@@ -1183,8 +2014,11 @@ Respects token budget to avoid context overflow.`,
 			return nil
 		}
 
-		formatted := storage.FormatContextResults(results)
-		estimatedTokens := storage.EstimateTokens(formatted)
+		formatted, err := storage.RenderContextTemplate(tmpl, results)
+		if err != nil {
+			return err
+		}
+		estimatedTokens := store.CountTokens(formatted)
 
 		output(titleStyle.Render("Context for Injection"))
 		output(dimStyle.Render(fmt.Sprintf("[%d estimated tokens, %d memories]", estimatedTokens, len(results))))
@@ -1198,7 +2032,12 @@ Respects token budget to avoid context overflow.`,
 func init() {
 	contextCmd.Flags().Int("token-budget", 2000, "maximum tokens to include")
 	contextCmd.Flags().Float64("min-importance", 0.3, "minimum importance score (0-1)")
+	contextCmd.Flags().Float64("project-boost", 1.5, "score multiplier for memories matching --project")
 	contextCmd.Flags().String("project", "", "project name for boosting relevant memories")
+	contextCmd.Flags().Bool("no-dedup", false, "keep near-identical observations")
+	contextCmd.Flags().String("user", "", "only observations this user wrote")
+	contextCmd.Flags().String("template", storage.DefaultContextTemplate,
+		"output template: "+strings.Join(storage.ContextTemplateNames(), ", ")+", or path to a template file")
 
 	rootCmd.AddCommand(contextCmd)
 }
@@ -1257,6 +2096,14 @@ var decaySoftCmd = &cobra.Command{
 		}
 
 		threshold, _ := cmd.Flags().GetFloat64("threshold")
+		if !cmd.Flags().Changed("threshold") {
+			threshold = loadEffectiveConfig(configProjectDir()).Decay.SoftDecayThreshold
+		}
+		release, err := takeWriteLease(cmd, store)
+		if err != nil {
+			return err
+		}
+		defer release()
 
 		start := time.Now()
 		affected, err := store.ApplySoftDecay(threshold)
@@ -1277,7 +2124,17 @@ var decaySoftCmd = &cobra.Command{
 var decayArchiveCmd = &cobra.Command{
 	Use:   "archive",
 	Short: "Archive old, low-importance memories",
-	Long:  "Moves memories to archive table based on age and importance.",
+	Long: `Moves memories to the archive table, compressed, based on age and importance.
+
+With --keep-per-entity and --keep-per-container (or decay.keepPerEntity and
+decay.keepPerContainer in the config), only that many of each entity's, and
+each container tag's, most important memories are kept; the rest are archived
+too. Pinned and static memories are never archived this way.
+
+With --digest-after (or decay.digestAfterDays in the config), each entity's
+archived memories from months that ended longer ago than that fold into one
+digest per month, further shrinking long-lived databases. Read the archive
+back with "decay archive list".`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		store, err := getStore()
 		if err != nil {
@@ -1291,11 +2148,28 @@ var decayArchiveCmd = &cobra.Command{
 
 		days, _ := cmd.Flags().GetInt("days")
 		minImportance, _ := cmd.Flags().GetFloat64("min-importance")
+		digestAfter, _ := cmd.Flags().GetInt("digest-after")
+		keepPerEntity, _ := cmd.Flags().GetInt("keep-per-entity")
+		keepPerContainer, _ := cmd.Flags().GetInt("keep-per-container")
 		dryRun, _ := cmd.Flags().GetBool("dry-run")
 
-		cfg := storage.DefaultDecayConfig()
-		cfg.ArchiveAfterDays = days
-		cfg.MinImportanceToKeep = minImportance
+		cfg := loadEffectiveConfig(configProjectDir()).Decay
+		if cmd.Flags().Changed("days") {
+			cfg.ArchiveAfterDays = days
+		}
+		if cmd.Flags().Changed("min-importance") {
+			cfg.MinImportanceToKeep = minImportance
+		}
+		if cmd.Flags().Changed("digest-after") {
+			cfg.DigestAfterDays = digestAfter
+		}
+		if cmd.Flags().Changed("keep-per-entity") {
+			cfg.KeepPerEntity = keepPerEntity
+		}
+		if cmd.Flags().Changed("keep-per-container") {
+			cfg.KeepPerContainer = keepPerContainer
+		}
+		retention := cfg.KeepPerEntity > 0 || cfg.KeepPerContainer > 0
 
 		if dryRun {
 			// Show what would be archived
@@ -1306,26 +2180,107 @@ var decayArchiveCmd = &cobra.Command{
 			output(titleStyle.Render("Archive Preview (Dry Run)"))
 			output()
 			output("  " + dimStyle.Render("Would archive approximately:") + " " + itoa(stats.LowImportance) + " observations")
+			if retention {
+				excess, err := store.CountExcessMemories(cfg)
+				if err != nil {
+					return err
+				}
+				output("  " + dimStyle.Render("Over the retention limits:") + "   " + itoa(excess) + " observations")
+			}
 			output("  " + dimStyle.Render("(Run without --dry-run to execute)"))
 			return nil
 		}
 
+		release, err := takeWriteLease(cmd, store)
+		if err != nil {
+			return err
+		}
+		defer release()
+		if err := snapshotBefore(cmd, store, "decay archive"); err != nil {
+			return err
+		}
 		start := time.Now()
 		archived, err := store.ArchiveOldMemories(cfg)
 		if err != nil {
 			return err
 		}
+		var excess int
+		if retention {
+			if excess, err = store.ArchiveExcessMemories(cfg); err != nil {
+				return err
+			}
+		}
+		var digested storage.DigestResult
+		if cfg.DigestAfterDays > 0 {
+			if digested, err = store.DigestArchive(cfg.DigestAfterDays); err != nil {
+				return err
+			}
+		}
 		elapsed := time.Since(start)
 
 		output(titleStyle.Render("Archive Complete"))
 		output()
 		output("  " + dimStyle.Render("Archived:") + " " + successStyle.Render(itoa(archived)) + " observations")
+		if retention {
+			output("  " + dimStyle.Render("Over limit:") + " " + successStyle.Render(itoa(excess)) + " observations")
+		}
+		if cfg.DigestAfterDays > 0 {
+			output("  " + dimStyle.Render("Digested:") + " " + successStyle.Render(itoa(digested.Folded)) + " observations into " + itoa(digested.Digests) + " monthly digests")
+		}
 		output("  " + dimStyle.Render("Time:") + "     " + successStyle.Render(elapsed.String()))
 
 		return nil
 	},
 }
 
+var decayArchiveListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List archived memories, newest first",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		if err := store.Migrate(); err != nil {
+			return err
+		}
+
+		entity, _ := cmd.Flags().GetString("entity")
+		limit, _ := cmd.Flags().GetInt("limit")
+		archived, err := store.ListArchivedObservations(entity, limit)
+		if err != nil {
+			return err
+		}
+		if format, _ := cmd.Flags().GetString("format"); format == "json" {
+			if archived == nil {
+				archived = []storage.ArchivedObservation{}
+			}
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			return enc.Encode(archived)
+		}
+		if len(archived) == 0 {
+			output(dimStyle.Render("No archived memories"))
+			return nil
+		}
+
+		output(titleStyle.Render("Archived Memories"))
+		output()
+		for _, a := range archived {
+			header := entityStyle.Render(a.EntityName) + " " + dimStyle.Render(a.ArchivedAt.Format("2006-01-02"))
+			if a.Digested > 0 {
+				header += " " + typeStyle.Render(fmt.Sprintf("(digest of %d, %s)", a.Digested, a.ArchivedAt.Format("January 2006")))
+			}
+			output("  " + header)
+			for line := range strings.SplitSeq(a.Content, "\n") {
+				output("    " + obsStyle.Render("- "+line))
+			}
+		}
+		return nil
+	},
+}
+
 var decayForgetCmd = &cobra.Command{
 	Use:   "forget",
 	Short: "Delete expired memories",
@@ -1345,6 +2300,20 @@ var decayForgetCmd = &cobra.Command{
 		archiveDays, _ := cmd.Flags().GetInt("archive-days")
 		dryRun, _ := cmd.Flags().GetBool("dry-run")
 
+		if !dryRun {
+			release, err := takeWriteLease(cmd, store)
+			if err != nil {
+				return err
+			}
+			defer release()
+		}
+		// A dry run of --expired returns before anything is deleted
+		if (expired || archiveDays > 0) && !(expired && dryRun) {
+			if err := snapshotBefore(cmd, store, "decay forget"); err != nil {
+				return err
+			}
+		}
+
 		var deleted int
 
 		if expired {
@@ -1379,21 +2348,94 @@ var decayForgetCmd = &cobra.Command{
 	},
 }
 
+var decaySimulateCmd = &cobra.Command{
+	Use:   "simulate",
+	Short: "Project which memories would be archived or forgotten",
+	Long: `Projects decay forward with the current configuration, assuming nothing
+is accessed again and soft decay runs daily. Nothing is modified.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		if err := store.Migrate(); err != nil {
+			return err
+		}
+
+		days, _ := cmd.Flags().GetInt("days")
+		limit, _ := cmd.Flags().GetInt("limit")
+
+		cfg := loadEffectiveConfig(configProjectDir()).Decay
+		sim, err := store.SimulateDecay(cfg, days)
+		if err != nil {
+			return err
+		}
+
+		output(titleStyle.Render("Decay Simulation (" + itoa(days) + " days)"))
+		output()
+		output("  " + dimStyle.Render("Observations:") + "     " + itoa(sim.Total))
+		output("  " + dimStyle.Render("Pinned (exempt):") + "  " + itoa(sim.Pinned))
+		output("  " + dimStyle.Render("Would archive:") + "    " + itoa(len(sim.Archived)))
+		output("  " + dimStyle.Render("Would forget:") + "     " + itoa(len(sim.Forgotten)))
+
+		printProjections := func(title string, projections []storage.DecayProjection, day func(storage.DecayProjection) int) {
+			if len(projections) == 0 {
+				return
+			}
+			output()
+			output(titleStyle.Render(title))
+			for i, p := range projections {
+				if limit > 0 && i == limit {
+					output(dimStyle.Render("  ... and " + itoa(len(projections)-limit) + " more"))
+					break
+				}
+				output(fmt.Sprintf("  %s %s %s %s",
+					dimStyle.Render(fmt.Sprintf("day %3d", day(p))),
+					entityStyle.Render(p.EntityName),
+					dimStyle.Render(fmt.Sprintf("(%.2f → %.2f)", p.Importance, p.ProjectedImportance)),
+					truncate(p.Content, 60)))
+			}
+		}
+		printProjections("Archived", sim.Archived, func(p storage.DecayProjection) int { return p.ArchiveDay })
+		printProjections("Forgotten", sim.Forgotten, func(p storage.DecayProjection) int { return p.ForgetDay })
+
+		return nil
+	},
+}
+
 func init() {
 	decaySoftCmd.Flags().Float64("threshold", 0.3, "minimum importance to apply decay")
+	addWaitFlag(decaySoftCmd)
+
+	decaySimulateCmd.Flags().Int("days", 90, "days to project forward")
+	decaySimulateCmd.Flags().Int("limit", 20, "max observations to list per section (0 for all)")
 
 	decayArchiveCmd.Flags().Int("days", 90, "archive memories older than this")
 	decayArchiveCmd.Flags().Float64("min-importance", 0.1, "archive below this importance")
+	decayArchiveCmd.Flags().Int("digest-after", 0, "fold archived months older than this many days into digests (0 never)")
+	decayArchiveCmd.Flags().Int("keep-per-entity", 0, "archive all but this many most important memories per entity (0 no limit)")
+	decayArchiveCmd.Flags().Int("keep-per-container", 0, "archive all but this many most important memories per container tag (0 no limit)")
 	decayArchiveCmd.Flags().Bool("dry-run", false, "preview without executing")
+	addNoSnapshotFlag(decayArchiveCmd)
+	addWaitFlag(decayArchiveCmd)
+	decayArchiveListCmd.Flags().String("entity", "", "only this entity's archived memories")
+	decayArchiveListCmd.Flags().Int("limit", 50, "max archived memories to list (0 for all)")
+	decayArchiveListCmd.Flags().String("format", "text", "output format: text or json")
+	decayArchiveCmd.AddCommand(decayArchiveListCmd)
 
 	decayForgetCmd.Flags().Bool("expired", false, "delete memories past forget_after date")
 	decayForgetCmd.Flags().Int("archive-days", 0, "delete archived memories older than this")
 	decayForgetCmd.Flags().Bool("dry-run", false, "preview without executing")
+	addNoSnapshotFlag(decayForgetCmd)
+	addWaitFlag(decayForgetCmd)
 
 	decayCmd.AddCommand(decayStatsCmd)
 	decayCmd.AddCommand(decaySoftCmd)
 	decayCmd.AddCommand(decayArchiveCmd)
 	decayCmd.AddCommand(decayForgetCmd)
+	decayCmd.AddCommand(decaySimulateCmd)
 	rootCmd.AddCommand(decayCmd)
 }
 
@@ -1434,10 +2476,6 @@ This associates the entity with the specified project.`,
 		containerTag := args[1]
 
 		if err := store.SetContainerTag(entityName, containerTag); err != nil {
-			if err == storage.ErrNotFound {
-				logger.Error("Entity not found", "name", entityName)
-				os.Exit(1)
-			}
 			return err
 		}
 
@@ -1463,10 +2501,6 @@ var workdirGetCmd = &cobra.Command{
 
 		tag, err := store.GetContainerTag(entityName)
 		if err != nil {
-			if err == storage.ErrNotFound {
-				logger.Error("Entity not found", "name", entityName)
-				os.Exit(1)
-			}
 			return err
 		}
 
@@ -1533,6 +2567,7 @@ This helps surface project-specific memories first.`,
 		limit, _ := cmd.Flags().GetInt("limit")
 		containerTag, _ := cmd.Flags().GetString("tag")
 		boost, _ := cmd.Flags().GetFloat64("boost")
+		explain, _ := cmd.Flags().GetBool("explain")
 
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
@@ -1555,21 +2590,81 @@ This helps surface project-specific memories first.`,
 				typeStyle.Render("("+r.EntityType+")") + " " +
 				dimStyle.Render("["+score+"]"))
 			output("  " + obsStyle.Render(r.Content))
+			if explain {
+				output("  " + dimStyle.Render(explainFused(r)))
+			}
 			output()
 		}
 		return nil
 	},
 }
 
+var workdirCloneCmd = &cobra.Command{
+	Use:   "clone --from <container-tag> --to <container-tag>",
+	Short: "Copy a project's memories to a new container tag",
+	Long: `Copy the entities tagged --from, with their observations and the relations
+among them, to new entities tagged --to, so a new project starts with an old
+one's conventions. The copies have no shared history with the originals and
+change independently.
+
+Each copy is named after the original with the old tag replaced by the new
+one, or with " (<new tag>)" appended when the name does not contain the tag.
+Entities whose copy's name is taken are skipped.
+
+Example:
+  mark42 workdir clone --from old-app --to new-app --types pattern,decision`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		if err := store.Migrate(); err != nil {
+			return err
+		}
+
+		from, _ := cmd.Flags().GetString("from")
+		to, _ := cmd.Flags().GetString("to")
+		types, _ := cmd.Flags().GetStringSlice("types")
+
+		clone, err := store.CloneContainer(from, to, types)
+		if err != nil {
+			return err
+		}
+		if len(clone.Entities) == 0 && len(clone.Skipped) == 0 {
+			logger.Info("No entities found with tag", "tag", from)
+			return nil
+		}
+
+		for _, e := range clone.Entities {
+			output("  " + entityStyle.Render(e.From) + " " + dimStyle.Render("→") + " " + entityStyle.Render(e.To) + " " + typeStyle.Render("("+e.Type+")"))
+		}
+		for _, name := range clone.Skipped {
+			output("  " + dimStyle.Render("skipped "+name+": its copy's name is taken"))
+		}
+		output(successStyle.Render(fmt.Sprintf("✓ Cloned %d entities, %d observations, and %d relations into %s",
+			len(clone.Entities), clone.Observations, clone.Relations, to)))
+		return nil
+	},
+}
+
 func init() {
+	workdirCloneCmd.Flags().String("from", "", "container tag to copy from (required)")
+	workdirCloneCmd.Flags().String("to", "", "container tag of the copies (required)")
+	workdirCloneCmd.Flags().StringSlice("types", nil, "only entities of these types (comma-separated)")
+
 	workdirSearchCmd.Flags().Int("limit", 10, "maximum number of results")
 	workdirSearchCmd.Flags().String("tag", "", "container tag to boost (required)")
 	workdirSearchCmd.Flags().Float64("boost", 1.5, "score multiplier for matching entities")
+	workdirSearchCmd.Flags().Bool("explain", false, "show how each result was scored, container tag boost included")
 
 	workdirCmd.AddCommand(workdirSetCmd)
 	workdirCmd.AddCommand(workdirGetCmd)
 	workdirCmd.AddCommand(workdirListCmd)
 	workdirCmd.AddCommand(workdirSearchCmd)
+	workdirCmd.AddCommand(workdirCloneCmd)
 	rootCmd.AddCommand(workdirCmd)
 }
 
@@ -1695,10 +2790,6 @@ var sessionGetCmd = &cobra.Command{
 
 		session, err := store.GetSession(args[0])
 		if err != nil {
-			if err == storage.ErrNotFound {
-				logger.Error("Session not found", "name", args[0])
-				os.Exit(1)
-			}
 			return err
 		}
 
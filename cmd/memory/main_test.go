@@ -3,12 +3,17 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
 	"github.com/spf13/cobra"
+
+	"github.com/mfenderov/mark42/internal/storage"
 )
 
 // captureOutput captures stdout/stderr during command execution.
@@ -65,6 +70,20 @@ func TestInitCommand(t *testing.T) {
 	}
 }
 
+func TestGetStore_PostgresDSN(t *testing.T) {
+	t.Chdir(t.TempDir())
+	oldDBPath := dbPath
+	dbPath = "postgres://memory@db.internal/mark42"
+	defer func() { dbPath = oldDBPath }()
+
+	if _, err := getStore(); !errors.Is(err, storage.ErrPostgresStore) {
+		t.Errorf("expected ErrPostgresStore, got %v", err)
+	}
+	if entries, _ := os.ReadDir("."); len(entries) != 0 {
+		t.Errorf("expected no directory created for the DSN, found %v", entries)
+	}
+}
+
 func TestEntityCommands(t *testing.T) {
 	tmpDir := t.TempDir()
 	testDBPath := filepath.Join(tmpDir, "test.db")
@@ -134,6 +153,54 @@ func TestEntityCommands(t *testing.T) {
 	})
 }
 
+func TestExitCodes(t *testing.T) {
+	useTestDB(t)
+	withStore(t, func(s *storage.Store) {
+		s.CreateEntity("Go", "language", nil)
+	})
+
+	run := func(args ...string) int {
+		t.Helper()
+		rootCmd.SetArgs(args)
+		rootCmd.SetErr(io.Discard)
+		defer rootCmd.SetErr(nil)
+		if err := rootCmd.Execute(); err != nil {
+			return exitCode(err)
+		}
+		return 0
+	}
+
+	for _, tc := range []struct {
+		args []string
+		want int
+	}{
+		{[]string{"entity", "get", "Go"}, 0},
+		{[]string{"entity", "get", "Missing"}, exitNotFound},
+		{[]string{"entity", "create", "Go", "language"}, exitEntityExists},
+		{[]string{"obs", "add", "Go", ""}, exitInvalidInput},
+		{[]string{"rel", "delete", "Go", "Go", "uses"}, exitNotFound},
+	} {
+		if got := run(tc.args...); got != tc.want {
+			t.Errorf("%v: exit code %d, want %d", tc.args, got, tc.want)
+		}
+	}
+
+	withStore(t, func(s *storage.Store) {
+		if err := s.MigrateDown(); err != nil {
+			t.Fatalf("MigrateDown failed: %v", err)
+		}
+	})
+	if got := run("upgrade", "--check"); got != exitSchemaOutdated {
+		t.Errorf("upgrade --check: exit code %d, want %d", got, exitSchemaOutdated)
+	}
+	if got := run("upgrade", "--check=false"); got != 0 {
+		t.Errorf("upgrade: exit code %d, want 0", got)
+	}
+	if got := run("upgrade", "--check"); got != 0 {
+		t.Errorf("upgrade --check after upgrade: exit code %d, want 0", got)
+	}
+}
+
 func TestObservationCommands(t *testing.T) {
 	tmpDir := t.TempDir()
 	testDBPath := filepath.Join(tmpDir, "test.db")
@@ -284,6 +351,65 @@ func TestSearchCommand(t *testing.T) {
 	store.Close()
 }
 
+func TestSearchStopWords(t *testing.T) {
+	useTestDB(t)
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	withStore(t, func(s *storage.Store) {
+		s.CreateEntity("redis", "tool", []string{"Cache for the API"})
+		s.CreateEntity("postgres", "tool", []string{"Stores the orders"})
+	})
+
+	if got := runRootCmd(t, "search", "the cache"); strings.Contains(got, "postgres") {
+		t.Errorf("expected stop words dropped from the query:\n%s", got)
+	}
+	if got := runRootCmd(t, "search", "the cache", "--no-stopwords"); !strings.Contains(got, "postgres") {
+		t.Errorf("expected --no-stopwords to search every word:\n%s", got)
+	}
+	searchCmd.Flags().Set("no-stopwords", "false")
+
+	writeConfig(t, home, `{"search": {"stopWords": ["cache"]}}`)
+	if got := runRootCmd(t, "search", "cache orders"); !strings.Contains(got, "postgres") || strings.Contains(got, "redis") {
+		t.Errorf("expected the configured list to replace the default:\n%s", got)
+	}
+}
+
+func TestSearchExplain(t *testing.T) {
+	useTestDB(t)
+	withStore(t, func(s *storage.Store) {
+		s.CreateEntity("redis", "tool", []string{"Cache for the API"})
+		s.SetContainerTag("redis", "api")
+	})
+
+	got := runRootCmd(t, "search", "cache", "--explain")
+	searchCmd.Flags().Set("explain", "false")
+	if !strings.Contains(got, "fts #1 bm25 ") {
+		t.Errorf("expected the BM25 rank and score:\n%s", got)
+	}
+
+	got = runRootCmd(t, "workdir", "search", "cache", "--tag", "api", "--explain")
+	workdirSearchCmd.Flags().Set("explain", "false")
+	if !strings.Contains(got, "fts #1 bm25 ") || !strings.Contains(got, "boost ×1.50") {
+		t.Errorf("expected the source scores and container tag boost:\n%s", got)
+	}
+}
+
+func TestWorkdirClone(t *testing.T) {
+	useTestDB(t)
+	withStore(t, func(s *storage.Store) {
+		s.CreateEntityWithContainer("old-app conventions", "pattern", []string{"Wrap errors"}, "old-app")
+		s.CreateEntityWithContainer("billing", "project", []string{"Legacy"}, "old-app")
+	})
+
+	got := runRootCmd(t, "workdir", "clone", "--from", "old-app", "--to", "new-app", "--types", "pattern")
+	if !strings.Contains(got, "new-app conventions") || !strings.Contains(got, "Cloned 1 entities, 1 observations") {
+		t.Errorf("expected the pattern cloned:\n%s", got)
+	}
+	if got := runRootCmd(t, "workdir", "list", "new-app"); !strings.Contains(got, "new-app conventions") || strings.Contains(got, "billing") {
+		t.Errorf("expected only the clone under new-app:\n%s", got)
+	}
+}
+
 func TestGraphCommand(t *testing.T) {
 	tmpDir := t.TempDir()
 	testDBPath := filepath.Join(tmpDir, "test.db")
@@ -318,6 +444,63 @@ func TestGraphCommand(t *testing.T) {
 	store.Close()
 }
 
+func TestObsEditCommand(t *testing.T) {
+	useTestDB(t)
+	t.Setenv("CLAUDE_MEMORY_EMBEDDER_URL", "disabled")
+	withStore(t, func(s *storage.Store) {
+		s.CreateEntity("TDD", "pattern", nil)
+		s.AddObservationWithType("TDD", "Wirte tests first", storage.FactTypeStatic)
+	})
+
+	runRootCmd(t, "obs", "edit", "TDD", "Wirte tests first", "Write tests first")
+
+	withStore(t, func(s *storage.Store) {
+		static, _ := s.GetObservationsByFactType(storage.FactTypeStatic)
+		if len(static) != 1 || static[0].Content != "Write tests first" {
+			t.Errorf("expected the observation edited with its fact type kept, got %+v", static)
+		}
+	})
+
+	rootCmd.SetArgs([]string{"obs", "edit", "TDD", "Wirte tests first", "again"})
+	if err := rootCmd.Execute(); exitCode(err) != exitNotFound {
+		t.Errorf("expected not found for the old content, got %v", err)
+	}
+}
+
+func TestRelEndCommand(t *testing.T) {
+	useTestDB(t)
+	withStore(t, func(s *storage.Store) {
+		s.CreateEntity("konfig", "project", nil)
+		s.CreateEntity("X", "library", nil)
+		s.DB().Exec(`UPDATE entities SET created_at = '2025-12-01 00:00:00'`)
+	})
+
+	runRootCmd(t, "rel", "create", "konfig", "X", "depends_on", "--valid-from", "2026-01-01")
+	runRootCmd(t, "rel", "end", "konfig", "X", "depends_on", "--at", "2026-06-01")
+
+	got := runRootCmd(t, "graph", "--format", "dot")
+	if strings.Contains(got, "depends_on") {
+		t.Errorf("expected the ended relation left out of the graph:\n%s", got)
+	}
+	defer graphCmd.Flags().Set("as-of", "")
+	got = runRootCmd(t, "graph", "--format", "dot", "--as-of", "2026-03-01")
+	if !strings.Contains(got, `"konfig" -> "X" [label="depends_on"]`) {
+		t.Errorf("expected the relation valid in March:\n%s", got)
+	}
+
+	defer relListCmd.Flags().Set("all", "false")
+	got = runRootCmd(t, "rel", "list", "konfig", "--all")
+	if !strings.Contains(got, "depends_on") || !strings.Contains(got, "(ended 2026-06-01)") {
+		t.Errorf("expected the ended relation listed with --all:\n%s", got)
+	}
+
+	rootCmd.SetArgs([]string{"rel", "end", "konfig", "X", "depends_on", "--at", "soon"})
+	if err := rootCmd.Execute(); exitCode(err) != exitInvalidInput {
+		t.Errorf("expected a bad --at rejected as invalid input, got %v", err)
+	}
+	relEndCmd.Flags().Set("at", "")
+}
+
 func TestMigrateCommand_JSONFormat(t *testing.T) {
 	tmpDir := t.TempDir()
 	testDBPath := filepath.Join(tmpDir, "test.db")
@@ -480,6 +663,35 @@ func TestStatsCommand(t *testing.T) {
 	store.Close()
 }
 
+func TestStatsCommand_Breakdowns(t *testing.T) {
+	useTestDB(t)
+	withStore(t, func(s *storage.Store) {
+		s.CreateEntity("konfig", "project", []string{"Loads YAML"})
+		s.CreateEntity("Go", "language", nil)
+		s.CreateRelation("konfig", "Go", "written_in")
+	})
+
+	got := runRootCmd(t, "stats", "--since", "2020-01-01")
+	for _, want := range []string{"Entities by type", "project", "Observations by fact type", "Most connected", "Added per week since 2020-01-01"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in stats output:\n%s", want, got)
+		}
+	}
+
+	defer statsCmd.Flags().Set("format", "default")
+	var stats struct {
+		storage.DatabaseStats
+		Growth []storage.GrowthWeek `json:"growth"`
+	}
+	if err := json.Unmarshal([]byte(runRootCmd(t, "stats", "--format", "json")), &stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Entities != 2 || stats.Relations != 1 || len(stats.Growth) != 1 {
+		t.Errorf("expected 2 entities, 1 relation, and one week of growth, got %+v", stats)
+	}
+	statsCmd.Flags().Set("since", "")
+}
+
 func TestStatsCommand_EmbeddingCoverage(t *testing.T) {
 	tmpDir := t.TempDir()
 	testDBPath := filepath.Join(tmpDir, "test.db")
@@ -620,6 +832,61 @@ func TestDecayCommands(t *testing.T) {
 	store.Close()
 }
 
+func TestDecayArchiveList(t *testing.T) {
+	useTestDB(t)
+	withStore(t, func(s *storage.Store) {
+		s.CreateEntity("Old", "test", []string{"Old low importance memory"})
+		s.SetObservationImportance("Old", "Old low importance memory", 0.05)
+		if _, err := s.DB().Exec(`UPDATE observations SET last_accessed = datetime('now', '-120 days')`); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	if got := runRootCmd(t, "decay", "archive"); !strings.Contains(got, "Archived: 1") {
+		t.Errorf("expected the memory archived:\n%s", got)
+	}
+	if got := runRootCmd(t, "decay", "archive", "list"); !strings.Contains(got, "Old low importance memory") {
+		t.Errorf("expected the archived memory listed:\n%s", got)
+	}
+
+	defer decayArchiveListCmd.Flags().Set("format", "text")
+	var archived []storage.ArchivedObservation
+	if err := json.Unmarshal([]byte(runRootCmd(t, "decay", "archive", "list", "--format", "json")), &archived); err != nil {
+		t.Fatal(err)
+	}
+	if len(archived) != 1 || archived[0].EntityName != "Old" {
+		t.Errorf("unexpected archive %+v", archived)
+	}
+}
+
+func TestDecayArchive_KeepPerEntity(t *testing.T) {
+	useTestDB(t)
+	t.Setenv("HOME", t.TempDir())
+	withStore(t, func(s *storage.Store) {
+		s.CreateEntity("Noisy", "project", []string{"Best", "Good", "Meh"})
+		s.SetObservationImportance("Noisy", "Good", 0.6)
+		s.SetObservationImportance("Noisy", "Meh", 0.2)
+	})
+
+	defer decayArchiveCmd.Flags().Set("keep-per-entity", "0")
+	defer decayArchiveCmd.Flags().Set("dry-run", "false")
+	if got := runRootCmd(t, "decay", "archive", "--keep-per-entity", "2", "--dry-run"); !strings.Contains(got, "Over the retention limits:   1") {
+		t.Errorf("expected the excess previewed:\n%s", got)
+	}
+	if got := runRootCmd(t, "decay", "archive", "--keep-per-entity", "2", "--dry-run=false"); !strings.Contains(got, "Over limit: 1") {
+		t.Errorf("expected the excess archived:\n%s", got)
+	}
+	withStore(t, func(s *storage.Store) {
+		entity, err := s.GetEntity("Noisy")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if slices.Contains(entity.Observations, "Meh") || len(entity.Observations) != 2 {
+			t.Errorf("expected the least important memory archived, got %v", entity.Observations)
+		}
+	})
+}
+
 func TestContextCommand(t *testing.T) {
 	tmpDir := t.TempDir()
 	testDBPath := filepath.Join(tmpDir, "test.db")
@@ -692,3 +959,24 @@ func TestImportanceCommands(t *testing.T) {
 
 	store.Close()
 }
+
+func TestEntityGetCommand_AsOf(t *testing.T) {
+	useTestDB(t)
+	withStore(t, func(s *storage.Store) {
+		s.CreateEntity("konfig", "project", []string{"Loads YAML"})
+		s.DB().Exec(`UPDATE entities SET created_at = '2026-01-01 00:00:00'`)
+		s.DB().Exec(`UPDATE observations SET created_at = '2026-01-01 00:00:00'`)
+		s.CreateOrUpdateEntity("konfig", "library", []string{"Loads YAML and TOML"})
+	})
+
+	defer entityGetCmd.Flags().Set("as-of", "")
+	got := runRootCmd(t, "entity", "get", "konfig", "--as-of", "2026-03-01")
+	if !strings.Contains(got, "project") || strings.Contains(got, "TOML") {
+		t.Errorf("expected konfig's first version:\n%s", got)
+	}
+
+	rootCmd.SetArgs([]string{"entity", "get", "konfig", "--as-of", "2025-01-01"})
+	if err := rootCmd.Execute(); exitCode(err) != exitNotFound {
+		t.Errorf("expected not found before konfig existed, got %v", err)
+	}
+}
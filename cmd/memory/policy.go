@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+var policyCmd = &cobra.Command{
+	Use:   "policy",
+	Short: "List and apply automation policies",
+	Long: `Policies are automation rules under "policies" in config.json (or in the JSON
+file CLAUDE_MEMORY_POLICIES names): each sets fields of the observations its
+condition matches, as they are written and whenever policy apply runs.
+
+  [
+    {"name": "expire todos",
+     "when": "fact_type == 'dynamic' && content contains 'TODO'",
+     "set": {"forgetAfter": "+14d"}},
+    {"name": "pin decisions",
+     "when": "entity_type == 'decision'",
+     "set": {"pinned": true}}
+  ]
+
+Conditions compare the fields entity, entity_type, content, fact_type, source,
+importance, pinned, and age_days with == != < <= > >= contains startswith
+matches, joined with && || ! and parentheses. set takes forgetAfter (relative
+to when the observation was written, or "never"), pinned, importance, and
+factType.`,
+}
+
+var policyListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the policies in effect",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		policies := store.Policies()
+		if format, _ := cmd.Flags().GetString("format"); format == "json" {
+			if policies == nil {
+				policies = []storage.Policy{}
+			}
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			return enc.Encode(policies)
+		}
+		if len(policies) == 0 {
+			output("No policies configured")
+			return nil
+		}
+		output(titleStyle.Render(fmt.Sprintf("Policies (%d)", len(policies))))
+		output()
+		for _, p := range policies {
+			output("  " + entityStyle.Render(p.Name) + " " + dimStyle.Render("when "+p.When))
+			output("      set " + strings.Join(policySets(p.Set), ", "))
+		}
+		return nil
+	},
+}
+
+// policySets renders the changes a policy makes.
+func policySets(c storage.PolicyChanges) []string {
+	var sets []string
+	if c.ForgetAfter != "" {
+		sets = append(sets, "forgetAfter="+c.ForgetAfter)
+	}
+	if c.Pinned != nil {
+		sets = append(sets, fmt.Sprintf("pinned=%v", *c.Pinned))
+	}
+	if c.Importance != nil {
+		sets = append(sets, fmt.Sprintf("importance=%g", *c.Importance))
+	}
+	if c.FactType != "" {
+		sets = append(sets, "factType="+string(c.FactType))
+	}
+	return sets
+}
+
+var policyApplyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Apply the policies to every stored observation",
+	Long: `Evaluate the policies against every observation of the latest entities and
+change those they match, listing each change. Writes apply policies as they
+happen; run this after adding a policy, and periodically for conditions on
+age_days.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		if err := store.Migrate(); err != nil {
+			return err
+		}
+
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		if !dryRun {
+			release, err := takeWriteLease(cmd, store)
+			if err != nil {
+				return err
+			}
+			defer release()
+		}
+		changes, err := store.ApplyPolicies(dryRun)
+		if err != nil {
+			return err
+		}
+
+		if format, _ := cmd.Flags().GetString("format"); format == "json" {
+			if changes == nil {
+				changes = []storage.PolicyChange{}
+			}
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			return enc.Encode(changes)
+		}
+		if len(changes) == 0 {
+			output(successStyle.Render("✓") + " Every observation follows the policies")
+			return nil
+		}
+		verb := "Changed"
+		if dryRun {
+			verb = "Would change"
+		}
+		output(titleStyle.Render(fmt.Sprintf("%s %d observations", verb, len(changes))))
+		output()
+		for _, c := range changes {
+			output("  " + entityStyle.Render(c.Entity) + ": " + truncate(c.Content, 60))
+			output("      " + strings.Join(c.Set, ", ") + dimStyle.Render(" ("+strings.Join(c.Policies, ", ")+")"))
+		}
+		return nil
+	},
+}
+
+func init() {
+	policyListCmd.Flags().String("format", "default", "output format: default, json")
+	policyApplyCmd.Flags().Bool("dry-run", false, "list the changes without making them")
+	policyApplyCmd.Flags().String("format", "default", "output format: default, json")
+	addWaitFlag(policyApplyCmd)
+	policyCmd.AddCommand(policyListCmd, policyApplyCmd)
+	rootCmd.AddCommand(policyCmd)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+func TestPolicyCommands(t *testing.T) {
+	useTestDB(t)
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	withStore(t, func(s *storage.Store) {
+		s.CreateEntity("Use SQLite", "decision", []string{"Chosen for zero setup"})
+	})
+
+	if got := runRootCmd(t, "policy", "list"); !strings.Contains(got, "No policies configured") {
+		t.Errorf("expected no policies:\n%s", got)
+	}
+
+	writeConfig(t, home, `{"policies": [
+		{"name": "pin decisions", "when": "entity_type == 'decision'", "set": {"pinned": true}},
+		{"name": "expire todos", "when": "content contains 'TODO'", "set": {"forgetAfter": "+14d"}}
+	]}`)
+
+	got := runRootCmd(t, "policy", "list")
+	if !strings.Contains(got, "pin decisions") || !strings.Contains(got, "forgetAfter=+14d") {
+		t.Errorf("expected both policies listed:\n%s", got)
+	}
+
+	defer policyApplyCmd.Flags().Set("dry-run", "false")
+	if got := runRootCmd(t, "policy", "apply", "--dry-run"); !strings.Contains(got, "Would change 1 observations") ||
+		!strings.Contains(got, "pinned=true") {
+		t.Errorf("expected the decision listed:\n%s", got)
+	}
+	policyApplyCmd.Flags().Set("dry-run", "false")
+	runRootCmd(t, "policy", "apply")
+	if got := runRootCmd(t, "policy", "apply"); !strings.Contains(got, "follows the policies") {
+		t.Errorf("expected nothing left to change:\n%s", got)
+	}
+
+	// Writes apply them too
+	runRootCmd(t, "obs", "add", "Use SQLite", "TODO: revisit for teams")
+	withStore(t, func(s *storage.Store) {
+		var set int
+		s.DB().Get(&set, "SELECT COUNT(*) FROM observations WHERE forget_after IS NOT NULL AND pinned = 1")
+		if set != 1 {
+			t.Errorf("expected the new observation pinned and expiring, got %d", set)
+		}
+	})
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+var purgeCmd = &cobra.Command{
+	Use:   "purge <entity>",
+	Short: "Erase every trace of an entity",
+	Long: `Erase an entity completely, unlike entity delete: every version of it, their
+observations and embeddings, its relations, attributes, and aliases, its
+archived observations, and the merge records and undo snapshots naming it.
+Copies other entities hold of its observations are kept but unlinked. The full-text indexes are
+optimized afterwards and the purge is verified before it is committed.
+
+Shows what would be erased and asks for confirmation unless --yes is given.
+--dry-run only shows it. --vacuum also rebuilds the database file so no free
+page keeps the erased content. Tombstones are kept, stripped of the entity's
+name, so the deletion still reaches replicas. Snapshot files taken earlier
+(see snapshot list) still hold the entity; delete them to finish the job.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		if err := store.Migrate(); err != nil {
+			return err
+		}
+
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		vacuum, _ := cmd.Flags().GetBool("vacuum")
+		yes, _ := cmd.Flags().GetBool("yes")
+		format, _ := cmd.Flags().GetString("format")
+
+		preview, err := store.PurgeEntity(args[0], storage.PurgeOptions{DryRun: true})
+		if err != nil {
+			return err
+		}
+		if dryRun {
+			return renderPurge(format, preview, "Would erase ")
+		}
+		if !yes {
+			if format == "json" {
+				return fmt.Errorf("purging %q needs --yes with --format json", preview.Entity)
+			}
+			renderPurge(format, preview, "Would erase ")
+			fmt.Fprint(out, "  Erase it permanently? [y/N] ")
+			in := bufio.NewScanner(cmd.InOrStdin())
+			if !in.Scan() || strings.ToLower(strings.TrimSpace(in.Text())) != "y" {
+				output("Nothing erased")
+				return nil
+			}
+		}
+
+		release, err := takeWriteLease(cmd, store)
+		if err != nil {
+			return err
+		}
+		defer release()
+		report, err := store.PurgeEntity(args[0], storage.PurgeOptions{Vacuum: vacuum})
+		if err != nil {
+			return err
+		}
+		logger.Info("Purged entity", "name", report.Entity)
+		return renderPurge(format, report, successStyle.Render("✓")+" Erased ")
+	},
+}
+
+// renderPurge prints what a purge erased, or would erase, after prefix.
+func renderPurge(format string, report *storage.PurgeReport, prefix string) error {
+	if format == "json" {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	output(prefix + entityStyle.Render(report.Entity))
+	counts := []struct {
+		n    int
+		what string
+	}{
+		{report.Versions, "versions"},
+		{report.Observations, "observations"},
+		{report.Embeddings, "embeddings"},
+		{report.Relations, "relations"},
+		{report.Attributes, "attributes"},
+		{report.Aliases, "aliases"},
+		{report.Archived, "archived observations"},
+		{report.Merges, "merge records"},
+		{report.Trash, "undo snapshots"},
+		{report.ContextLog, "context log entries"},
+	}
+	for _, c := range counts {
+		if c.n > 0 {
+			output(fmt.Sprintf("  %d %s", c.n, c.what))
+		}
+	}
+	if report.Vacuumed {
+		output(dimStyle.Render("  Database vacuumed"))
+	}
+	return nil
+}
+
+func init() {
+	purgeCmd.Flags().Bool("dry-run", false, "show what would be erased without erasing it")
+	purgeCmd.Flags().Bool("vacuum", false, "rebuild the database file afterwards")
+	purgeCmd.Flags().Bool("yes", false, "erase without asking")
+	purgeCmd.Flags().String("format", "default", "output format: default, json")
+	addWaitFlag(purgeCmd)
+	rootCmd.AddCommand(purgeCmd)
+}
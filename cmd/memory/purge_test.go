@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+func TestPurgeCommand(t *testing.T) {
+	useTestDB(t)
+	withStore(t, func(s *storage.Store) {
+		s.CreateEntity("Alice", "person", []string{"Lives at 12 Elm Street"})
+		s.CreateEntity("konfig", "project", []string{"Loads YAML"})
+		s.CreateRelation("konfig", "Alice", "owned_by")
+	})
+
+	rootCmd.SetIn(strings.NewReader("n\n"))
+	defer rootCmd.SetIn(nil)
+	if got := runRootCmd(t, "purge", "Alice"); !strings.Contains(got, "1 relations") || !strings.Contains(got, "Nothing erased") {
+		t.Errorf("expected a preview and nothing erased:\n%s", got)
+	}
+	withStore(t, func(s *storage.Store) {
+		if _, err := s.GetEntity("Alice"); err != nil {
+			t.Errorf("expected Alice kept after declining: %v", err)
+		}
+	})
+
+	defer purgeCmd.Flags().Set("yes", "false")
+	defer purgeCmd.Flags().Set("format", "default")
+	var report storage.PurgeReport
+	if err := json.Unmarshal([]byte(runRootCmd(t, "purge", "Alice", "--yes", "--format", "json")), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Versions != 1 || report.Observations != 1 || report.Relations != 1 {
+		t.Errorf("unexpected report %+v", report)
+	}
+	withStore(t, func(s *storage.Store) {
+		if _, err := s.GetEntity("Alice"); err == nil {
+			t.Error("expected Alice erased")
+		}
+	})
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+func TestReadOnlyFlag(t *testing.T) {
+	useTestDB(t)
+	withStore(t, func(s *storage.Store) {
+		s.CreateEntity("konfig", "project", []string{"Loads YAML"})
+	})
+
+	defer rootCmd.PersistentFlags().Set("read-only", "false")
+	if got := runRootCmd(t, "search", "YAML", "--read-only"); !strings.Contains(got, "konfig") {
+		t.Errorf("expected the read-only search to find konfig:\n%s", got)
+	}
+	runRootCmd(t, "graph", "--read-only")
+	runRootCmd(t, "stats", "--read-only")
+
+	rootCmd.SetArgs([]string{"obs", "add", "konfig", "Loads TOML", "--read-only"})
+	if err := rootCmd.Execute(); err == nil {
+		t.Error("expected a write with --read-only to fail")
+	}
+}
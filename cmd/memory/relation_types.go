@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+var relTypesCmd = &cobra.Command{
+	Use:   "types",
+	Short: "Inspect relation types",
+	Long: `Relation types are free-form unless registered under "relationTypes" in
+config.json (a map of type to {"inverse", "description"}) or listed,
+comma-separated, in CLAUDE_MEMORY_RELATION_TYPES as type or type:inverse.
+A relation whose type has an inverse is listed from its target under the
+inverse, so "B used_by A" reads "A uses B" in rel list for A. A type with
+"symmetric": true (type:type in the variable) reads the same from either end:
+one relation links both ways, and creating it in reverse changes nothing.`,
+}
+
+var relTypesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List relation types with relation counts",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		if err := store.Migrate(); err != nil {
+			return err
+		}
+
+		types, err := store.ListRelationTypes()
+		if err != nil {
+			return err
+		}
+		if format, _ := cmd.Flags().GetString("format"); format == "json" {
+			if types == nil {
+				types = []storage.RelationTypeCount{}
+			}
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			return enc.Encode(types)
+		}
+		if len(types) == 0 {
+			output(dimStyle.Render("No relation types"))
+			return nil
+		}
+
+		typos := 0
+		for _, t := range types {
+			line := fmt.Sprintf("%6d  ", t.Count) + relationStyle.Render(t.Type)
+			if t.Symmetric {
+				line += " " + dimStyle.Render("(symmetric)")
+			} else if t.Inverse != "" {
+				line += " " + dimStyle.Render("↔ "+t.Inverse)
+			}
+			switch {
+			case t.Suggestion != "":
+				typos++
+				line += " " + dimStyle.Render("(did you mean "+t.Suggestion+"?)")
+			case t.Registered && t.Description != "":
+				line += " " + dimStyle.Render(t.Description)
+			case !t.Registered && len(store.RelationTypes()) > 0 && store.InverseRelationType(t.Type) == "":
+				line += " " + dimStyle.Render("(unregistered)")
+			}
+			output(line)
+		}
+		if typos > 0 {
+			output()
+			output(dimStyle.Render("Fix a misspelled relation with: mark42 rel delete and mark42 rel create"))
+		}
+		return nil
+	},
+}
+
+func init() {
+	relTypesListCmd.Flags().String("format", "default", "output format: default, json")
+	relTypesCmd.AddCommand(relTypesListCmd)
+	relCmd.AddCommand(relTypesCmd)
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+func TestRelationTypesCommands(t *testing.T) {
+	useTestDB(t)
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	withStore(t, func(s *storage.Store) {
+		s.CreateEntity("TDD", "pattern", nil)
+		s.CreateEntity("mark42", "project", nil)
+		s.CreateEntity("sqlite", "project", nil)
+		s.CreateRelation("TDD", "mark42", "used_by")
+		s.CreateRelation("mark42", "sqlite", "depends_on")
+		s.CreateRelation("TDD", "sqlite", "depnds_on")
+	})
+	writeConfig(t, home, `{"relationTypes": {"used_by": {"inverse": "uses"}, "depends_on": {}}}`)
+
+	got := runRootCmd(t, "rel", "types", "list")
+	if !strings.Contains(got, "used_by ↔ uses") || !strings.Contains(got, "depnds_on (did you mean depends_on?)") {
+		t.Errorf("expected counts with inverses and typo suggestions:\n%s", got)
+	}
+
+	got = runRootCmd(t, "rel", "list", "mark42")
+	if !strings.Contains(got, "mark42 ─[uses]→ TDD") || !strings.Contains(got, "mark42 ─[depends_on]→ sqlite") {
+		t.Errorf("expected relations read from mark42:\n%s", got)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+var rememberCmd = &cobra.Command{
+	Use:   "remember [text]",
+	Short: "Store facts from free text",
+	Long: `Extract entities, observations, and relations from free text and store them.
+With no arguments, the text is read from stdin.
+
+Each sentence becomes an observation on the first entity it names, and phrases
+like "A uses B" or "A depends on B" become relations. Entities are existing
+entity names, ` + "`backticked`" + ` terms, code-like words, and capitalized names. When
+CLAUDE_MEMORY_SUMMARY_MODEL is set, a local LLM does the extraction instead
+(--mode auto falls back to the heuristic if it fails).`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		text := strings.Join(args, " ")
+		if len(args) == 0 || text == "-" {
+			data, err := io.ReadAll(cmd.InOrStdin())
+			if err != nil {
+				return err
+			}
+			text = string(data)
+		}
+		if strings.TrimSpace(text) == "" {
+			return &storage.ValidationError{Field: "text", Reason: "is required"}
+		}
+
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		if err := store.Migrate(); err != nil {
+			return err
+		}
+
+		mode, _ := cmd.Flags().GetString("mode")
+		ex, source, err := extractFacts(store, text, mode)
+		if err != nil {
+			return err
+		}
+
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		var result *storage.ExtractionResult
+		if !dryRun {
+			if result, err = store.SaveExtraction(ex); err != nil {
+				return err
+			}
+		}
+		renderExtraction(ex, result, source)
+		return nil
+	},
+}
+
+func init() {
+	rememberCmd.Flags().String("mode", "auto", "extraction: auto (LLM if configured, else heuristic), heuristic, or llm")
+	rememberCmd.Flags().Bool("dry-run", false, "show the extraction without storing it")
+	rootCmd.AddCommand(rememberCmd)
+}
+
+// extractFacts runs the extraction mode asks for and names the one used.
+func extractFacts(store *storage.Store, text, mode string) (*storage.Extraction, string, error) {
+	switch mode {
+	case "auto", "llm":
+		client := summarizerFromEnv()
+		if client == nil {
+			if mode == "llm" {
+				return nil, "", &storage.ValidationError{Field: "--mode", Reason: "llm needs CLAUDE_MEMORY_SUMMARY_MODEL set"}
+			}
+			break
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+		defer cancel()
+		ex, err := client.ExtractFacts(ctx, text)
+		if err == nil {
+			return ex, "llm", nil
+		}
+		if mode == "llm" {
+			return nil, "", fmt.Errorf("extraction failed: %w", err)
+		}
+		logger.Warn("LLM extraction failed, using heuristic", "error", err)
+	case "heuristic":
+	default:
+		return nil, "", &storage.ValidationError{Field: "--mode", Reason: "must be auto, heuristic, or llm"}
+	}
+
+	ex, err := store.ExtractKnownFacts(text)
+	return ex, "heuristic", err
+}
+
+// renderExtraction prints what was extracted and, when result is set, what
+// storing it did.
+func renderExtraction(ex *storage.Extraction, result *storage.ExtractionResult, source string) {
+	title := "Extracted"
+	if result != nil {
+		title = "Remembered"
+	}
+	output(titleStyle.Render(title) + " " + dimStyle.Render("("+source+")"))
+	output()
+
+	for i, e := range ex.Entities {
+		line := "  " + entityStyle.Render(e.Name) + " " + typeStyle.Render("("+e.Type+")")
+		if result != nil {
+			line += " " + entityStatus(result.Entities[i])
+		}
+		output(line)
+		for _, obs := range e.Observations {
+			output("    " + obsStyle.Render("- "+obs))
+		}
+	}
+
+	if len(ex.Relations) > 0 {
+		output()
+		for i, r := range ex.Relations {
+			line := "  " + r.From + " " + relationStyle.Render("-["+r.Type+"]->") + " " + r.To
+			if result != nil && result.Relations[i] != nil {
+				line += " " + dimStyle.Render("error: "+result.Relations[i].Error())
+			}
+			output(line)
+		}
+	}
+
+	if len(ex.Skipped) > 0 {
+		output()
+		output(dimStyle.Render(fmt.Sprintf("  Skipped %d sentences naming no entity:", len(ex.Skipped))))
+		for _, s := range ex.Skipped {
+			output("    " + dimStyle.Render(s))
+		}
+	}
+}
+
+func entityStatus(r storage.EntityResult) string {
+	switch {
+	case errors.Is(r.Err, storage.ErrInvalidInput):
+		return dimStyle.Render("error: " + r.Err.Error())
+	case r.Created:
+		return successStyle.Render("created")
+	default:
+		return dimStyle.Render(fmt.Sprintf("exists, %d new observations", r.Added))
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+func TestRemember(t *testing.T) {
+	useTestDB(t)
+	t.Setenv("CLAUDE_MEMORY_SUMMARY_MODEL", "")
+
+	got := runRootCmd(t, "remember", "--dry-run", "--mode", "auto", "The Parser uses the Lexer.")
+	if !strings.Contains(got, "Extracted") || !strings.Contains(got, "Parser -[uses]-> Lexer") {
+		t.Errorf("expected the extraction shown:\n%s", got)
+	}
+	withStore(t, func(s *storage.Store) {
+		if _, err := s.GetEntity("Parser"); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("expected a dry run to write nothing, got %v", err)
+		}
+	})
+
+	rootCmd.SetIn(strings.NewReader("The Parser uses the Lexer.\nremember to rebase"))
+	defer rootCmd.SetIn(nil)
+	got = runRootCmd(t, "remember", "--dry-run=false")
+	if !strings.Contains(got, "Remembered") || !strings.Contains(got, "Skipped 1 sentences") {
+		t.Errorf("expected the stored extraction shown:\n%s", got)
+	}
+	withStore(t, func(s *storage.Store) {
+		relations, err := s.ListRelations("Lexer")
+		if err != nil || len(relations) != 1 || relations[0].From != "Parser" {
+			t.Errorf("expected Parser -> Lexer stored, got %+v, %v", relations, err)
+		}
+	})
+
+	defer rememberCmd.Flags().Set("mode", "auto")
+	rootCmd.SetArgs([]string{"remember", "--mode", "llm", "Go is fast"})
+	if err := rootCmd.Execute(); exitCode(err) != exitInvalidInput {
+		t.Errorf("expected llm mode without a model rejected as invalid input, got %v", err)
+	}
+}
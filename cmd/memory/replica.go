@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+// writeReplica prints the part of the graph in scope as replica records, one
+// JSON object per line, with observation embeddings when embeddings is set.
+func writeReplica(store *storage.Store, scope storage.GraphScope, embeddings bool) error {
+	if err := store.Migrate(); err != nil {
+		return err
+	}
+	records, err := store.ExportReplicaScoped(scope)
+	if err != nil {
+		return err
+	}
+	if embeddings {
+		if err := store.AddReplicaEmbeddings(records); err != nil {
+			return err
+		}
+	}
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readReplica decodes replica records written by writeReplica.
+func readReplica(r io.Reader) ([]storage.ReplicaRecord, error) {
+	var records []storage.ReplicaRecord
+	dec := json.NewDecoder(r)
+	for {
+		var rec storage.ReplicaRecord
+		err := dec.Decode(&rec)
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", len(records)+1, err)
+		}
+		records = append(records, rec)
+	}
+}
+
+var mergeCmd = &cobra.Command{
+	Use:   "merge <file>",
+	Short: "Merge a replica export from another database",
+	Long: `Merge records exported with 'mark42 graph --format replica' on another machine
+("-" reads stdin). Each entity, observation, and relation has a stable ID and a
+logical timestamp; per record the later change wins, and deletions travel as
+tombstones. Merging in both directions leaves both databases with the same graph.
+
+Observation embeddings in the export (graph --format replica --embeddings) are
+stored for observations that have none here, so semantic search works without
+re-embedding. Export from a database embedded with the model this one uses;
+vectors from another model do not compare with its queries.
+
+The database is snapshotted first unless --no-snapshot is given.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var in io.Reader = os.Stdin
+		if args[0] != "-" {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			in = f
+		}
+		records, err := readReplica(in)
+		if err != nil {
+			return err
+		}
+
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		if err := store.Migrate(); err != nil {
+			return err
+		}
+		release, err := takeWriteLease(cmd, store)
+		if err != nil {
+			return err
+		}
+		defer release()
+		if err := snapshotBefore(cmd, store, "merge"); err != nil {
+			return err
+		}
+		if args[0] == "-" {
+			store.SetSource(storage.SourceImport + ":stdin")
+		} else {
+			store.SetSource(storage.SourceImport + ":" + filepath.Base(args[0]))
+		}
+
+		stats, err := store.MergeReplica(records)
+		if err != nil {
+			return err
+		}
+
+		output(successStyle.Render(fmt.Sprintf("✓ Merged %d records", len(records))))
+		output(dimStyle.Render(fmt.Sprintf("  %d created, %d updated, %d deleted", stats.Created, stats.Updated, stats.Deleted)))
+		if stats.Embedded > 0 {
+			output(dimStyle.Render(fmt.Sprintf("  %d embeddings imported", stats.Embedded)))
+		}
+		if stats.Skipped > 0 {
+			output(dimStyle.Render(fmt.Sprintf("  %d skipped (their entities are not in this database)", stats.Skipped)))
+		}
+		return nil
+	},
+}
+
+func init() {
+	addNoSnapshotFlag(mergeCmd)
+	addWaitFlag(mergeCmd)
+	rootCmd.AddCommand(mergeCmd)
+}
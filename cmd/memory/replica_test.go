@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+func runRootCmd(t *testing.T, args ...string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	oldOut := out
+	out = &buf
+	defer func() { out = oldOut }()
+
+	rootCmd.SetArgs(args)
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("%v failed: %v", args, err)
+	}
+	return buf.String()
+}
+
+func TestReplicaExportAndMerge(t *testing.T) {
+	dir := t.TempDir()
+	desktopDB := filepath.Join(dir, "desktop.db")
+	laptopDB := filepath.Join(dir, "laptop.db")
+	useTestDB(t)
+
+	dbPath = desktopDB
+	withStore(t, func(s *storage.Store) {
+		s.CreateEntity("Go", "language", []string{"Fast compiler"})
+		s.DeleteObservation("Go", "Fast compiler")
+		s.AddObservation("Go", "Has generics")
+	})
+
+	export := runRootCmd(t, "graph", "--format", "replica")
+	lines := strings.Split(strings.TrimSpace(export), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[2], `"deleted":true`) {
+		t.Fatalf("expected entity, observation, and tombstone lines, got:\n%s", export)
+	}
+	file := filepath.Join(dir, "desktop.ndjson")
+	os.WriteFile(file, []byte(export), 0o644)
+
+	dbPath = laptopDB
+	got := runRootCmd(t, "merge", file)
+	if !strings.Contains(got, "Merged 3 records") || !strings.Contains(got, "2 created") {
+		t.Errorf("unexpected merge output:\n%s", got)
+	}
+	withStore(t, func(s *storage.Store) {
+		entity, err := s.GetEntity("Go")
+		if err != nil || len(entity.Observations) != 1 || entity.Observations[0] != "Has generics" {
+			t.Errorf("expected merged entity with one observation, got %+v, %v", entity, err)
+		}
+	})
+}
+
+func TestGraphScoped(t *testing.T) {
+	useTestDB(t)
+	withStore(t, func(s *storage.Store) {
+		s.CreateEntityWithContainer("api", "project", []string{"REST API"}, "my-app")
+		s.CreateEntityWithContainer("tdd", "pattern", []string{"Tests first"}, "my-app")
+		s.CreateEntityWithContainer("other", "pattern", nil, "other-app")
+		s.CreateRelation("api", "tdd", "uses")
+		s.CreateRelation("tdd", "other", "uses")
+	})
+	defer graphCmd.Flags().Set("tag", "")
+	defer graphCmd.Flags().Set("type", "")
+	defer graphCmd.Flags().Set("format", "json")
+
+	export := runRootCmd(t, "graph", "--format", "replica", "--tag", "my-app")
+	if lines := strings.Split(strings.TrimSpace(export), "\n"); len(lines) != 5 || strings.Contains(export, "other") {
+		t.Errorf("expected my-app's 2 entities, 2 observations, and 1 relation, got:\n%s", export)
+	}
+
+	got := runRootCmd(t, "graph", "--format", "dot", "--tag", "", "--type", "pattern")
+	if !strings.Contains(got, `"tdd" -> "other"`) || strings.Contains(got, `"api"`) {
+		t.Errorf("expected only the pattern entities:\n%s", got)
+	}
+}
+
+func TestGraphNDJSON_Paged(t *testing.T) {
+	useTestDB(t)
+	withStore(t, func(s *storage.Store) {
+		s.CreateEntity("A", "node", []string{"first"})
+		s.CreateEntity("B", "node", []string{"second"})
+		s.CreateEntity("C", "node", nil)
+		s.CreateRelation("A", "B", "links")
+	})
+
+	got := runRootCmd(t, "graph", "--format", "ndjson", "--page-size", "2", "--no-observations=false")
+	want := `{"kind":"entity","name":"A","type":"node","observations":["first"]}
+{"kind":"entity","name":"B","type":"node","observations":["second"]}
+{"kind":"relation","from":"A","to":"B","type":"links"}
+{"kind":"entity","name":"C","type":"node"}
+`
+	if got != want {
+		t.Errorf("unexpected NDJSON:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+var searchReindexCmd = &cobra.Command{
+	Use:   "reindex",
+	Short: "Rebuild the full-text search index",
+	Long: `Rebuild the full-text indexes with the tokenizer under "search" in
+config.json, e.g.
+
+  {"search": {"tokenizer": {"porter": true, "removeDiacritics": 2, "tokenChars": "-_"}}}
+
+Flags override the config for this run. Searches use the tokenizer the index
+was built with, so run this after changing the tokenizer settings.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		tokenizer := loadEffectiveConfig(configProjectDir()).FTSTokenizer
+		flags := cmd.Flags()
+		if flags.Changed("porter") {
+			tokenizer.Porter, _ = flags.GetBool("porter")
+		}
+		if flags.Changed("remove-diacritics") {
+			tokenizer.RemoveDiacritics, _ = flags.GetInt("remove-diacritics")
+		}
+		if flags.Changed("separators") {
+			tokenizer.Separators, _ = flags.GetString("separators")
+		}
+		if flags.Changed("tokenchars") {
+			tokenizer.TokenChars, _ = flags.GetString("tokenchars")
+		}
+
+		release, err := takeWriteLease(cmd, store)
+		if err != nil {
+			return err
+		}
+		defer release()
+		if err := store.ReindexFTS(tokenizer); err != nil {
+			return err
+		}
+		output(successStyle.Render("✓") + " Rebuilt search index with " + fmt.Sprintf("%q", tokenizer.Spec()))
+		return nil
+	},
+}
+
+// applyFTSTokenizer rebuilds the full-text indexes with tokenizer unless
+// they already use it, and reports whether it did.
+func applyFTSTokenizer(store *storage.Store, tokenizer storage.FTSTokenizer) (bool, error) {
+	if !store.FTSEnabled() {
+		return false, nil
+	}
+	current, err := store.FTSTokenizerSpec()
+	if err != nil || current == tokenizer.Spec() {
+		return false, err
+	}
+	return true, store.ReindexFTS(tokenizer)
+}
+
+func init() {
+	searchReindexCmd.Flags().Bool("porter", true, "stem words, so \"testing\" matches \"tests\"")
+	searchReindexCmd.Flags().Int("remove-diacritics", 1, "0 keeps diacritics, 1 removes most, 2 removes all")
+	searchReindexCmd.Flags().String("separators", "", "extra characters that split tokens")
+	searchReindexCmd.Flags().String("tokenchars", "", "extra characters kept inside tokens")
+	addWaitFlag(searchReindexCmd)
+	searchCmd.AddCommand(searchReindexCmd)
+}
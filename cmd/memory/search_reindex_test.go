@@ -0,0 +1,32 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+func TestSearchReindex(t *testing.T) {
+	useTestDB(t)
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	writeConfig(t, home, `{"search": {"tokenizer": {"removeDiacritics": 2, "tokenChars": "-"}}}`)
+
+	runRootCmd(t, "init")
+	withStore(t, func(s *storage.Store) {
+		if spec, _ := s.FTSTokenizerSpec(); spec != "porter unicode61 remove_diacritics 2 tokenchars '-'" {
+			t.Errorf("expected init to apply the configured tokenizer, got %q", spec)
+		}
+	})
+
+	got := runRootCmd(t, "search", "reindex", "--porter=false")
+	if !strings.Contains(got, `"unicode61 remove_diacritics 2 tokenchars '-'"`) {
+		t.Errorf("expected flags layered over the config:\n%s", got)
+	}
+	withStore(t, func(s *storage.Store) {
+		if spec, _ := s.FTSTokenizerSpec(); strings.HasPrefix(spec, "porter") {
+			t.Errorf("expected stemming off after reindexing, got %q", spec)
+		}
+	})
+}
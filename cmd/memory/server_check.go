@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mfenderov/mark42/internal/mcp"
+)
+
+// serverBinary is the MCP server Claude Code launches.
+const serverBinary = "mark42-server"
+
+var serverCmd = &cobra.Command{
+	Use:   "server",
+	Short: "Inspect the MCP server",
+}
+
+var serverCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Check that the MCP server can start and answer",
+	Long: `Check what the MCP server needs, for diagnosing a broken Claude Code
+integration: the database opens, its schema is current, the embedder at
+CLAUDE_MEMORY_EMBEDDER_URL responds, and a spawned server answers initialize,
+ping, and tools/list over stdio.
+
+The server is started with this database (CLAUDE_MEMORY_DB) and otherwise the
+same environment. An unreachable embedder is a warning: the server runs
+without semantic search.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		serverPath, _ := cmd.Flags().GetString("server")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		failed := false
+
+		output(titleStyle.Render("Server Check"))
+		output()
+		check := func(ok bool, label, detail string) {
+			mark := successStyle.Render("✓")
+			if !ok {
+				mark = "✗"
+				failed = true
+			}
+			output("  " + mark + " " + label)
+			if detail != "" {
+				output("      " + detail)
+			}
+		}
+
+		store, err := getStore()
+		check(err == nil, "Database opens", errDetail(err))
+		if err == nil {
+			err = store.CheckSchema()
+			store.Close()
+			check(err == nil, "Schema current", errDetail(err))
+		}
+
+		if embedder := embedderFromEnv(); embedder == nil {
+			output("  " + dimStyle.Render("- Embedder disabled"))
+		} else {
+			ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+			_, err := embedder.CreateEmbedding(ctx, "test")
+			cancel()
+			if err != nil {
+				output("  ! Embedder responds")
+				output("      " + err.Error() + "; semantic search will be disabled")
+			} else {
+				check(true, "Embedder responds", "")
+			}
+		}
+
+		if serverPath == "" {
+			serverPath = findServerBinary()
+		}
+		ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+		tools, err := pingStdioServer(ctx, serverPath, dbPath)
+		cancel()
+		if err == nil {
+			check(true, "Server answers over stdio", fmt.Sprintf("%s: %d tools", serverPath, tools))
+		} else {
+			check(false, "Server answers over stdio", err.Error())
+		}
+
+		if failed {
+			return errors.New("server check failed")
+		}
+		return nil
+	},
+}
+
+// errDetail is err's message, or "" for nil.
+func errDetail(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// findServerBinary returns the server installed next to this binary, or else
+// the one on PATH.
+func findServerBinary() string {
+	if exe, err := os.Executable(); err == nil {
+		path := filepath.Join(filepath.Dir(exe), serverBinary)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	if path, err := exec.LookPath(serverBinary); err == nil {
+		return path
+	}
+	return serverBinary
+}
+
+// pingStdioServer starts the server at path on database db, sends it
+// initialize, ping, and tools/list, and returns how many tools it lists.
+func pingStdioServer(ctx context.Context, path, db string) (int, error) {
+	cmd := exec.CommandContext(ctx, path)
+	cmd.Env = append(os.Environ(), "CLAUDE_MEMORY_DB="+db)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	var requests bytes.Buffer
+	enc := json.NewEncoder(&requests)
+	for _, req := range []mcp.Request{
+		{JSONRPC: "2.0", ID: 1, Method: "initialize", Params: json.RawMessage(`{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"mark42 server check"}}`)},
+		{JSONRPC: "2.0", Method: "notifications/initialized"},
+		{JSONRPC: "2.0", ID: 2, Method: "ping"},
+		{JSONRPC: "2.0", ID: 3, Method: "tools/list"},
+	} {
+		if err := enc.Encode(req); err != nil {
+			return 0, err
+		}
+	}
+	// The server exits once stdin closes, after answering every request
+	cmd.Stdin = &requests
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return 0, err
+	}
+	if err := cmd.Start(); err != nil {
+		return 0, err
+	}
+	responses, readErr := readResponses(stdout)
+	waitErr := cmd.Wait()
+	if ctx.Err() != nil {
+		return 0, fmt.Errorf("no answer within the timeout")
+	}
+	if readErr != nil {
+		return 0, readErr
+	}
+
+	for id, method := range []string{"initialize", "ping", "tools/list"} {
+		resp, ok := responses[fmt.Sprint(id+1)]
+		if !ok {
+			if waitErr != nil {
+				return 0, fmt.Errorf("%s: exited before answering %s: %w%s", path, method, waitErr, stderrDetail(&stderr))
+			}
+			return 0, fmt.Errorf("no answer to %s%s", method, stderrDetail(&stderr))
+		}
+		if resp.Error != nil {
+			return 0, fmt.Errorf("%s failed: %s", method, resp.Error.Message)
+		}
+	}
+
+	var list struct {
+		Tools []mcp.Tool `json:"tools"`
+	}
+	if err := json.Unmarshal(responses["3"].Result, &list); err != nil {
+		return 0, fmt.Errorf("invalid tools/list answer: %w", err)
+	}
+	if len(list.Tools) == 0 {
+		return 0, errors.New("tools/list returned no tools")
+	}
+	return len(list.Tools), nil
+}
+
+// rawResponse is a JSON-RPC response with its result left undecoded.
+type rawResponse struct {
+	ID     json.RawMessage `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *mcp.Error      `json:"error"`
+}
+
+// readResponses reads one JSON-RPC response per line until r ends, keyed by
+// id.
+func readResponses(r io.Reader) (map[string]rawResponse, error) {
+	responses := make(map[string]rawResponse)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		var resp rawResponse
+		if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
+			return nil, fmt.Errorf("invalid response %q: %w", scanner.Text(), err)
+		}
+		responses[string(resp.ID)] = resp
+	}
+	return responses, scanner.Err()
+}
+
+// stderrDetail is the last line the server logged, to explain a failure.
+func stderrDetail(stderr *bytes.Buffer) string {
+	lines := strings.Split(strings.TrimSpace(stderr.String()), "\n")
+	if last := lines[len(lines)-1]; last != "" {
+		return " (" + last + ")"
+	}
+	return ""
+}
+
+func init() {
+	serverCheckCmd.Flags().String("server", "", "server binary to start (default: "+serverBinary+" next to this binary, else on PATH)")
+	serverCheckCmd.Flags().Duration("timeout", 10*time.Second, "how long to wait for the embedder and the server")
+	serverCmd.AddCommand(serverCheckCmd)
+	rootCmd.AddCommand(serverCmd)
+}
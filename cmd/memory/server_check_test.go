@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+// fakeServer writes a script that answers the server check's requests with
+// the given tools/list result.
+func fakeServer(t *testing.T, toolsResult string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a shell script")
+	}
+	path := filepath.Join(t.TempDir(), "mark42-server")
+	script := `#!/bin/sh
+cat >/dev/null
+echo '{"jsonrpc":"2.0","id":1,"result":{"protocolVersion":"2024-11-05","serverInfo":{"name":"mark42"}}}'
+echo '{"jsonrpc":"2.0","id":2,"result":{}}'
+echo '{"jsonrpc":"2.0","id":3,"result":` + toolsResult + `}'
+`
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestServerCheck(t *testing.T) {
+	useTestDB(t)
+	t.Setenv("CLAUDE_MEMORY_EMBEDDER_URL", "disabled")
+	withStore(t, func(s *storage.Store) {})
+
+	server := fakeServer(t, `{"tools":[{"name":"search_nodes"},{"name":"open_nodes"}]}`)
+	got := runRootCmd(t, "server", "check", "--server", server)
+	for _, want := range []string{"✓ Database opens", "✓ Schema current", "Embedder disabled", "2 tools"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in:\n%s", want, got)
+		}
+	}
+
+	t.Run("fails without tools", func(t *testing.T) {
+		var buf bytes.Buffer
+		oldOut := out
+		out = &buf
+		defer func() { out = oldOut }()
+
+		rootCmd.SetArgs([]string{"server", "check", "--server", fakeServer(t, `{"tools":[]}`)})
+		if err := rootCmd.Execute(); err == nil {
+			t.Fatal("expected the check to fail")
+		}
+		if !strings.Contains(buf.String(), "tools/list returned no tools") {
+			t.Errorf("expected the reason in:\n%s", buf.String())
+		}
+	})
+
+	t.Run("fails when the server is missing", func(t *testing.T) {
+		var buf bytes.Buffer
+		oldOut := out
+		out = &buf
+		defer func() { out = oldOut }()
+
+		rootCmd.SetArgs([]string{"server", "check", "--server", filepath.Join(t.TempDir(), "missing")})
+		if err := rootCmd.Execute(); err == nil {
+			t.Fatal("expected the check to fail")
+		}
+		if !strings.Contains(buf.String(), "✗ Server answers over stdio") {
+			t.Errorf("expected the server check to fail in:\n%s", buf.String())
+		}
+	})
+}
@@ -0,0 +1,506 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+const (
+	formatText     = "text"
+	formatMarkdown = "markdown"
+)
+
+// fileActivity counts how often a file was touched during a session.
+type fileActivity struct {
+	Path  string
+	Count int
+}
+
+// sessionFiles returns the files a session's events touched, most active first.
+func sessionFiles(s *storage.Session) []fileActivity {
+	counts := map[string]int{}
+	for _, entry := range s.Timeline {
+		if entry.Event.FilePath != "" {
+			counts[entry.Event.FilePath]++
+		}
+	}
+
+	files := make([]fileActivity, 0, len(counts))
+	for path, n := range counts {
+		files = append(files, fileActivity{Path: path, Count: n})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].Count != files[j].Count {
+			return files[i].Count > files[j].Count
+		}
+		return files[i].Path < files[j].Path
+	})
+	return files
+}
+
+// describeEntry renders a timeline entry as plain text, without its time.
+func describeEntry(entry storage.SessionEntry) string {
+	if entry.FactType == storage.FactTypeSessionTurn {
+		return truncate(entry.Content, 120)
+	}
+	evt := entry.Event
+	switch {
+	case evt.FilePath != "":
+		return evt.ToolName + " " + evt.FilePath
+	case evt.Command != "":
+		return evt.ToolName + ": " + truncate(evt.Command, 100)
+	default:
+		return evt.ToolName
+	}
+}
+
+func formatSessionTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+// renderSessionText writes a session as styled terminal output.
+func renderSessionText(s *storage.Session) {
+	output(titleStyle.Render(s.Name))
+	output()
+	output("  " + dimStyle.Render("Project:") + "  " + s.Project)
+	output("  " + dimStyle.Render("Status:") + "   " + s.Status)
+	output("  " + dimStyle.Render("Started:") + "  " + formatSessionTime(s.StartedAt))
+	output("  " + dimStyle.Render("Ended:") + "    " + formatSessionTime(s.EndedAt))
+	output("  " + dimStyle.Render("Events:") + "   " + itoa(s.EventCount) + "  " +
+		dimStyle.Render("Prompts:") + " " + itoa(s.TurnCount))
+	if s.Summary != "" {
+		output("  " + dimStyle.Render("Summary:") + "  " + s.Summary)
+	}
+
+	if files := sessionFiles(s); len(files) > 0 {
+		output()
+		output("  " + typeStyle.Render("Files ("+itoa(len(files))+")"))
+		for _, f := range files {
+			output("    " + entityStyle.Render(filepath.Base(f.Path)) + " " + dimStyle.Render(f.Path+" ×"+itoa(f.Count)))
+		}
+	}
+
+	if len(s.Timeline) > 0 {
+		output()
+		output("  " + typeStyle.Render("Timeline"))
+		for _, entry := range s.Timeline {
+			output("    " + dimStyle.Render(entry.At.Local().Format("15:04:05")) + "  " + describeEntry(entry))
+		}
+	}
+}
+
+// renderSessionMarkdown renders a session as markdown with headings at the given level.
+func renderSessionMarkdown(sb *strings.Builder, s *storage.Session, level int) {
+	heading := strings.Repeat("#", level)
+
+	sb.WriteString(heading + " " + s.Name + "\n\n")
+	sb.WriteString("- **Project:** " + s.Project + "\n")
+	sb.WriteString("- **Status:** " + s.Status + "\n")
+	sb.WriteString("- **Started:** " + formatSessionTime(s.StartedAt) + "\n")
+	sb.WriteString("- **Ended:** " + formatSessionTime(s.EndedAt) + "\n")
+	sb.WriteString("- **Events:** " + itoa(s.EventCount) + ", **Prompts:** " + itoa(s.TurnCount) + "\n")
+	if s.Summary != "" {
+		sb.WriteString("\n" + s.Summary + "\n")
+	}
+
+	if files := sessionFiles(s); len(files) > 0 {
+		sb.WriteString("\n" + heading + "# Files\n\n")
+		for _, f := range files {
+			sb.WriteString("- `" + f.Path + "` (" + itoa(f.Count) + ")\n")
+		}
+	}
+
+	if len(s.Timeline) > 0 {
+		sb.WriteString("\n" + heading + "# Timeline\n\n")
+		for _, entry := range s.Timeline {
+			sb.WriteString("- `" + entry.At.Local().Format("15:04:05") + "` " + describeEntry(entry) + "\n")
+		}
+	}
+}
+
+// parseSince parses a lookback window such as 7d, 2w, or 36h.
+func parseSince(s string) (time.Duration, error) {
+	if n, ok := strings.CutSuffix(s, "d"); ok {
+		days, err := strconv.Atoi(n)
+		if err != nil || days < 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	if n, ok := strings.CutSuffix(s, "w"); ok {
+		weeks, err := strconv.Atoi(n)
+		if err != nil || weeks < 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(weeks) * 7 * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+func validateFormat(format string) error {
+	if format != formatText && format != formatMarkdown {
+		return errors.New("unknown format " + strconv.Quote(format) + " (want text or markdown)")
+	}
+	return nil
+}
+
+var sessionShowCmd = &cobra.Command{
+	Use:   "show <name>",
+	Short: "Show a session as a timeline with files and summary",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		if err := validateFormat(format); err != nil {
+			return err
+		}
+
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		if err := store.Migrate(); err != nil {
+			return err
+		}
+
+		session, err := store.GetSession(args[0])
+		if err != nil {
+			return err
+		}
+
+		if format == formatMarkdown {
+			var sb strings.Builder
+			renderSessionMarkdown(&sb, session, 1)
+			fmt.Fprint(out, sb.String())
+			return nil
+		}
+		renderSessionText(session)
+		return nil
+	},
+}
+
+var sessionReportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Report recent sessions with their timelines and files",
+	Long: `Render every session started within the lookback window, oldest first,
+with totals across the period.
+
+Examples:
+  mark42 session report --project myapp --since 7d
+  mark42 session report --since 2w --format markdown > report.md`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		if err := validateFormat(format); err != nil {
+			return err
+		}
+		sinceFlag, _ := cmd.Flags().GetString("since")
+		window, err := parseSince(sinceFlag)
+		if err != nil {
+			return err
+		}
+		project, _ := cmd.Flags().GetString("project")
+
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		if err := store.Migrate(); err != nil {
+			return err
+		}
+
+		sessions, err := store.ListSessionsSince(project, time.Now().Add(-window))
+		if err != nil {
+			return err
+		}
+
+		if len(sessions) == 0 {
+			logger.Info("No sessions found", "since", sinceFlag)
+			return nil
+		}
+
+		var events int
+		files := map[string]bool{}
+		for _, s := range sessions {
+			events += s.EventCount
+			for _, f := range sessionFiles(s) {
+				files[f.Path] = true
+			}
+		}
+
+		title := "Session report"
+		if project != "" {
+			title += ": " + project
+		}
+		totals := itoa(len(sessions)) + " sessions, " + itoa(events) + " events, " +
+			itoa(len(files)) + " files (last " + sinceFlag + ")"
+
+		if format == formatMarkdown {
+			var sb strings.Builder
+			sb.WriteString("# " + title + "\n\n" + totals + "\n")
+			for _, s := range sessions {
+				sb.WriteString("\n")
+				renderSessionMarkdown(&sb, s, 2)
+			}
+			fmt.Fprint(out, sb.String())
+			return nil
+		}
+
+		output(titleStyle.Render(title))
+		output(dimStyle.Render(totals))
+		for _, s := range sessions {
+			output()
+			renderSessionText(s)
+		}
+		return nil
+	},
+}
+
+// maxKeyCommands caps the commands listed per session in exports.
+const maxKeyCommands = 5
+
+// routineCommands are read-only commands left out of exported worklogs.
+var routineCommands = []string{"ls", "cat", "cd", "pwd", "echo", "head", "tail", "grep", "rg", "find", "which",
+	"git status", "git diff", "git log", "git show", "git branch"}
+
+// keyCommands returns the distinct non-routine commands a session ran, in order.
+func keyCommands(s *storage.Session) []string {
+	var commands []string
+	for _, entry := range s.Timeline {
+		command := strings.TrimSpace(entry.Event.Command)
+		if command == "" || isRoutineCommand(command) || slices.Contains(commands, command) {
+			continue
+		}
+		commands = append(commands, command)
+		if len(commands) == maxKeyCommands {
+			break
+		}
+	}
+	return commands
+}
+
+func isRoutineCommand(command string) bool {
+	for _, routine := range routineCommands {
+		if command == routine || strings.HasPrefix(command, routine+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// renderWorklog renders sessions as a markdown changelog grouped by day, newest first.
+func renderWorklog(sb *strings.Builder, title string, sessions []*storage.Session) {
+	sb.WriteString("# " + title + "\n")
+
+	var day string
+	for i := len(sessions) - 1; i >= 0; i-- {
+		s := sessions[i]
+		files := sessionFiles(s)
+		if s.Summary == "" && len(files) == 0 {
+			continue
+		}
+
+		if d := s.StartedAt.Local().Format(time.DateOnly); d != day {
+			day = d
+			sb.WriteString("\n## " + day + "\n\n")
+		}
+
+		summary := s.Summary
+		if summary == "" {
+			summary = "Untitled session"
+		}
+		sb.WriteString("- " + summary + " _(" + s.Name + ")_\n")
+
+		if len(files) > 0 {
+			names := make([]string, len(files))
+			for i, f := range files {
+				names[i] = "`" + f.Path + "`"
+			}
+			sb.WriteString("  - Files: " + strings.Join(names, ", ") + "\n")
+		}
+		if commands := keyCommands(s); len(commands) > 0 {
+			for i, c := range commands {
+				commands[i] = "`" + truncate(c, 80) + "`"
+			}
+			sb.WriteString("  - Commands: " + strings.Join(commands, ", ") + "\n")
+		}
+	}
+}
+
+var sessionExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export sessions as a markdown worklog",
+	Long: `Export sessions started within the lookback window as a changelog grouped
+by day: summary, files touched, and key commands for each session. Useful for
+standups and release notes.
+
+Examples:
+  mark42 session export --project myapp --since 30d > WORKLOG.md
+  mark42 session export --since 7d --format markdown`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		if format != formatMarkdown {
+			return errors.New("unknown format " + strconv.Quote(format) + " (want markdown)")
+		}
+		sinceFlag, _ := cmd.Flags().GetString("since")
+		window, err := parseSince(sinceFlag)
+		if err != nil {
+			return err
+		}
+		project, _ := cmd.Flags().GetString("project")
+
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		if err := store.Migrate(); err != nil {
+			return err
+		}
+
+		sessions, err := store.ListSessionsSince(project, time.Now().Add(-window))
+		if err != nil {
+			return err
+		}
+
+		title := "Worklog"
+		if project != "" {
+			title += ": " + project
+		}
+
+		var sb strings.Builder
+		renderWorklog(&sb, title, sessions)
+		if len(sessions) == 0 {
+			sb.WriteString("\nNo sessions in the last " + sinceFlag + ".\n")
+		}
+		fmt.Fprint(out, sb.String())
+		return nil
+	},
+}
+
+var sessionSearchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Search session summaries, events, and prompts",
+	Long: `Full-text search over captured sessions: summaries, tracked events
+(files edited, commands run), and recorded prompts.
+
+Examples:
+  mark42 session search "race condition"
+  mark42 session search worker.go --project myapp`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		if err := store.Migrate(); err != nil {
+			return err
+		}
+
+		project, _ := cmd.Flags().GetString("project")
+		limit, _ := cmd.Flags().GetInt("limit")
+		if noStopWords, _ := cmd.Flags().GetBool("no-stopwords"); noStopWords {
+			store.SetStopWords(nil)
+		}
+
+		matches, err := store.SearchSessions(args[0], project, limit)
+		if err != nil {
+			return err
+		}
+
+		if len(matches) == 0 {
+			logger.Info("No matching sessions", "query", args[0])
+			return nil
+		}
+
+		output(titleStyle.Render("Sessions matching: " + args[0]))
+		for _, m := range matches {
+			output()
+			output("  " + entityStyle.Render(m.Name) + " " + dimStyle.Render(formatSessionTime(m.StartedAt)))
+			if m.Summary != "" {
+				output("    " + m.Summary)
+			}
+			for _, match := range m.Matches {
+				if match != m.Summary {
+					output("    " + dimStyle.Render("↳ "+truncate(match, 100)))
+				}
+			}
+		}
+		return nil
+	},
+}
+
+var sessionMergeCmd = &cobra.Command{
+	Use:   "merge <target> <source>...",
+	Short: "Merge interrupted sessions into one",
+	Long: `Fold one or more source sessions into the target session. Events and
+prompts are kept in their original order, summaries are combined oldest first,
+and the source sessions are deleted.
+
+Example:
+  mark42 session merge session-myapp-20260102-150405.000 session-myapp-20260102-143012.120`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		if err := store.Migrate(); err != nil {
+			return err
+		}
+
+		merged, err := store.MergeSessions(args[0], args[1:]...)
+		if err != nil {
+			return err
+		}
+
+		output(successStyle.Render("✓") + " Merged " + itoa(len(args)-1) + " session(s) into " + entityStyle.Render(merged.Name))
+		output("  " + dimStyle.Render("Events:") + "  " + itoa(merged.EventCount))
+		if merged.Summary != "" {
+			output("  " + dimStyle.Render("Summary:") + " " + merged.Summary)
+		}
+		return nil
+	},
+}
+
+func init() {
+	sessionSearchCmd.Flags().String("project", "", "filter by project name")
+	sessionSearchCmd.Flags().Int("limit", 10, "maximum number of sessions")
+	sessionSearchCmd.Flags().Bool("no-stopwords", false, "search for every word of the query, stop words included")
+
+	sessionShowCmd.Flags().String("format", formatText, "output format: text, markdown")
+
+	sessionExportCmd.Flags().String("project", "", "filter by project name")
+	sessionExportCmd.Flags().String("since", "30d", "lookback window, e.g. 30d, 2w")
+	sessionExportCmd.Flags().String("format", formatMarkdown, "output format: markdown")
+
+	sessionReportCmd.Flags().String("project", "", "filter by project name")
+	sessionReportCmd.Flags().String("since", "7d", "lookback window, e.g. 7d, 2w, 36h")
+	sessionReportCmd.Flags().String("format", formatText, "output format: text, markdown")
+
+	sessionCmd.AddCommand(sessionShowCmd)
+	sessionCmd.AddCommand(sessionReportCmd)
+	sessionCmd.AddCommand(sessionSearchCmd)
+	sessionCmd.AddCommand(sessionMergeCmd)
+	sessionCmd.AddCommand(sessionExportCmd)
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+func TestParseSince(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"7d", 7 * 24 * time.Hour, false},
+		{"2w", 14 * 24 * time.Hour, false},
+		{"36h", 36 * time.Hour, false},
+		{"90m", 90 * time.Minute, false},
+		{"xd", 0, true},
+		{"soon", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseSince(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSince(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseSince(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSessionFiles(t *testing.T) {
+	s := &storage.Session{Timeline: []storage.SessionEntry{
+		{FactType: storage.FactTypeSessionEvent, Event: storage.SessionEvent{ToolName: "Edit", FilePath: "/b.go"}},
+		{FactType: storage.FactTypeSessionEvent, Event: storage.SessionEvent{ToolName: "Edit", FilePath: "/a.go"}},
+		{FactType: storage.FactTypeSessionEvent, Event: storage.SessionEvent{ToolName: "Bash", Command: "go test"}},
+		{FactType: storage.FactTypeSessionEvent, Event: storage.SessionEvent{ToolName: "Write", FilePath: "/b.go"}},
+	}}
+
+	files := sessionFiles(s)
+	if len(files) != 2 {
+		t.Fatalf("got %d files, want 2", len(files))
+	}
+	if files[0].Path != "/b.go" || files[0].Count != 2 {
+		t.Errorf("files[0] = %+v, want /b.go x2", files[0])
+	}
+	if files[1].Path != "/a.go" || files[1].Count != 1 {
+		t.Errorf("files[1] = %+v, want /a.go x1", files[1])
+	}
+}
+
+func captureSessionForReport(t *testing.T, project string) string {
+	t.Helper()
+	store, err := getStore()
+	if err != nil {
+		t.Fatalf("getStore failed: %v", err)
+	}
+	defer store.Close()
+
+	session, _ := store.CreateSession(project)
+	store.CaptureSessionTurn(session.Name, "User: add the parser")
+	store.CaptureSessionEvent(session.Name, storage.SessionEvent{ToolName: "Edit", FilePath: "/proj/parser.go"})
+	store.CaptureSessionEvent(session.Name, storage.SessionEvent{ToolName: "Bash", Command: "go test ./..."})
+	store.CompleteSession(session.Name, "Added the parser")
+	return session.Name
+}
+
+func runSessionCmd(t *testing.T, args ...string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	oldOut := out
+	out = &buf
+	defer func() { out = oldOut }()
+
+	rootCmd.SetArgs(append([]string{"session"}, args...))
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("session %v failed: %v", args, err)
+	}
+	return buf.String()
+}
+
+func TestSessionShowCommand(t *testing.T) {
+	useTestDB(t)
+	name := captureSessionForReport(t, "myapp")
+
+	t.Run("text", func(t *testing.T) {
+		got := runSessionCmd(t, "show", name, "--format", "text")
+		for _, want := range []string{name, "Added the parser", "parser.go", "Bash: go test ./...", "User: add the parser"} {
+			if !strings.Contains(got, want) {
+				t.Errorf("output missing %q:\n%s", want, got)
+			}
+		}
+	})
+
+	t.Run("markdown", func(t *testing.T) {
+		got := runSessionCmd(t, "show", name, "--format", "markdown")
+		for _, want := range []string{"# " + name, "## Files", "- `/proj/parser.go` (1)", "## Timeline"} {
+			if !strings.Contains(got, want) {
+				t.Errorf("output missing %q:\n%s", want, got)
+			}
+		}
+	})
+}
+
+func TestSessionSearchCommand(t *testing.T) {
+	useTestDB(t)
+	name := captureSessionForReport(t, "myapp")
+
+	got := runSessionCmd(t, "search", "parser.go")
+	if !strings.Contains(got, name) || !strings.Contains(got, "Added the parser") {
+		t.Errorf("expected matching session with summary:\n%s", got)
+	}
+}
+
+func TestSessionMergeCommand(t *testing.T) {
+	useTestDB(t)
+	first := captureSessionForReport(t, "myapp")
+	second := captureSessionForReport(t, "myapp")
+
+	got := runSessionCmd(t, "merge", second, first)
+	if !strings.Contains(got, "Merged 1 session(s) into") {
+		t.Errorf("unexpected output:\n%s", got)
+	}
+
+	store, err := getStore()
+	if err != nil {
+		t.Fatalf("getStore failed: %v", err)
+	}
+	defer store.Close()
+	sessions, _ := store.ListSessions("myapp", "", 10)
+	if len(sessions) != 1 || sessions[0].Name != second {
+		t.Errorf("expected only %s left, got %v", second, sessions)
+	}
+}
+
+func TestKeyCommands(t *testing.T) {
+	s := &storage.Session{}
+	for _, c := range []string{"ls -la", "go test ./...", "git status", "make build", "go test ./...", "git commit -m x", "cat a.go"} {
+		s.Timeline = append(s.Timeline, storage.SessionEntry{
+			FactType: storage.FactTypeSessionEvent,
+			Event:    storage.SessionEvent{ToolName: "Bash", Command: c},
+		})
+	}
+
+	got := keyCommands(s)
+	want := []string{"go test ./...", "make build", "git commit -m x"}
+	if !slices.Equal(got, want) {
+		t.Errorf("keyCommands = %v, want %v", got, want)
+	}
+}
+
+func TestSessionExportCommand(t *testing.T) {
+	useTestDB(t)
+	name := captureSessionForReport(t, "myapp")
+	captureSessionForReport(t, "other")
+
+	got := runSessionCmd(t, "export", "--project", "myapp", "--since", "30d")
+	for _, want := range []string{
+		"# Worklog: myapp",
+		"## " + time.Now().Format(time.DateOnly),
+		"- Added the parser _(" + name + ")_",
+		"  - Files: `/proj/parser.go`",
+		"  - Commands: `go test ./...`",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("export missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "session-other") {
+		t.Errorf("export should only include myapp sessions:\n%s", got)
+	}
+}
+
+func TestSessionReportCommand(t *testing.T) {
+	useTestDB(t)
+	captureSessionForReport(t, "myapp")
+	captureSessionForReport(t, "other")
+
+	got := runSessionCmd(t, "report", "--project", "myapp", "--since", "7d", "--format", "markdown")
+	if !strings.Contains(got, "# Session report: myapp") {
+		t.Errorf("missing report title:\n%s", got)
+	}
+	if !strings.Contains(got, "1 sessions, 2 events, 1 files") {
+		t.Errorf("missing totals:\n%s", got)
+	}
+	if strings.Contains(got, "session-other") {
+		t.Errorf("report should only include myapp sessions:\n%s", got)
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Tune this database's settings",
+	Long: `Settings stored in the database itself, so they travel with it and apply to
+every client using it: the CLI, the MCP server, and hooks. Unlike config.json
+they describe the corpus rather than the machine, e.g. how much hybrid search
+trusts vectors over keywords:
+
+  mark42 config set search.vectorWeight 0.7`,
+}
+
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List every setting with its value",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		set, err := store.ListSettings()
+		if err != nil {
+			return err
+		}
+		values := make(map[string]string, len(set))
+		for _, s := range set {
+			values[s.Key] = s.Value
+		}
+
+		if format, _ := cmd.Flags().GetString("format"); format == "json" {
+			type setting struct {
+				storage.SettingInfo
+				Value string `json:"value"`
+			}
+			list := make([]setting, len(storage.KnownSettings))
+			for i, info := range storage.KnownSettings {
+				list[i] = setting{info, values[info.Key]}
+				if list[i].Value == "" {
+					list[i].Value = info.Default
+				}
+			}
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			return enc.Encode(list)
+		}
+
+		output(titleStyle.Render("Settings"))
+		output()
+		for _, info := range storage.KnownSettings {
+			value, ok := values[info.Key]
+			if !ok {
+				value = info.Default + " " + dimStyle.Render("(default)")
+			}
+			output("  " + entityStyle.Render(info.Key) + " = " + value)
+			output("    " + dimStyle.Render(info.Description))
+		}
+		return nil
+	},
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print a setting's value",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		value, err := store.GetSetting(args[0])
+		if err != nil {
+			return err
+		}
+		output(value)
+		return nil
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Change a setting",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		if err := store.SetSetting(args[0], args[1]); err != nil {
+			return err
+		}
+		output(successStyle.Render("✓") + fmt.Sprintf(" %s = %s", args[0], args[1]))
+		return nil
+	},
+}
+
+var configUnsetCmd = &cobra.Command{
+	Use:   "unset <key>",
+	Short: "Return a setting to its default",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		if err := store.UnsetSetting(args[0]); err != nil {
+			return err
+		}
+		value, err := store.GetSetting(args[0])
+		if err != nil {
+			return err
+		}
+		output(successStyle.Render("✓") + fmt.Sprintf(" %s = %s ", args[0], value) + dimStyle.Render("(default)"))
+		return nil
+	},
+}
+
+func init() {
+	configListCmd.Flags().String("format", "default", "output format: default, json")
+
+	configCmd.AddCommand(configListCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configUnsetCmd)
+	rootCmd.AddCommand(configCmd)
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+func TestConfigCommand(t *testing.T) {
+	useTestDB(t)
+
+	if got := runRootCmd(t, "config", "set", "search.vectorWeight", "0.7"); !strings.Contains(got, "search.vectorWeight = 0.7") {
+		t.Errorf("expected the setting confirmed:\n%s", got)
+	}
+	withStore(t, func(s *storage.Store) {
+		if value, _ := s.GetSetting(storage.SettingVectorWeight); value != "0.7" {
+			t.Errorf("expected 0.7 stored, got %q", value)
+		}
+	})
+	if got := runRootCmd(t, "config", "get", "search.vectorWeight"); strings.TrimSpace(got) != "0.7" {
+		t.Errorf("config get = %q", got)
+	}
+	got := runRootCmd(t, "config", "list")
+	if !strings.Contains(got, "search.vectorWeight = 0.7") || !strings.Contains(got, "search.rrfK = 60") {
+		t.Errorf("expected every setting listed:\n%s", got)
+	}
+
+	rootCmd.SetArgs([]string{"config", "set", "search.vectorWeight", "2"})
+	if err := rootCmd.Execute(); err == nil {
+		t.Error("expected an out of range weight refused")
+	}
+
+	if got := runRootCmd(t, "config", "unset", "search.vectorWeight"); !strings.Contains(got, "search.vectorWeight = 0.5") {
+		t.Errorf("expected the default back:\n%s", got)
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+// backupDir is where snapshots are written: backup.dir in the config or
+// CLAUDE_MEMORY_BACKUP_DIR, else mark42/backups next to the database.
+func backupDir() string {
+	if dir := loadEffectiveConfig(configProjectDir()).BackupDir; dir != "" {
+		return dir
+	}
+	return filepath.Join(filepath.Dir(dbPath), "mark42", "backups")
+}
+
+// addNoSnapshotFlag lets cmd skip the snapshot it takes before changing the
+// database.
+func addNoSnapshotFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("no-snapshot", false, "don't snapshot the database first")
+}
+
+// snapshotBefore snapshots the database before cmd changes it, unless
+// --no-snapshot is given, and prints how to restore it. Hosted databases
+// are skipped with a note.
+func snapshotBefore(cmd *cobra.Command, store *storage.Store, reason string) error {
+	if skip, _ := cmd.Flags().GetBool("no-snapshot"); skip {
+		return nil
+	}
+	if store.Remote() {
+		output(dimStyle.Render("No snapshot taken: hosted databases are backed up by their server"))
+		return nil
+	}
+	snapshot, err := store.CreateSnapshot(backupDir(), reason)
+	if err != nil {
+		return fmt.Errorf("snapshot before %s (--no-snapshot skips it): %w", reason, err)
+	}
+	output(dimStyle.Render("Snapshot saved to " + snapshot.Path))
+	output(dimStyle.Render(fmt.Sprintf("  Restore with: mark42 snapshot restore %d", snapshot.ID)))
+	return nil
+}
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Manage database snapshots",
+	Long: `Snapshots are copies of the database written to the backup directory:
+backup.dir in config.json or CLAUDE_MEMORY_BACKUP_DIR, else mark42/backups
+next to the database. upgrade, decay archive, decay forget, merge, and dedupe
+run take one before changing anything unless given --no-snapshot.`,
+}
+
+var snapshotCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Snapshot the database now",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		reason, _ := cmd.Flags().GetString("reason")
+		snapshot, err := store.CreateSnapshot(backupDir(), reason)
+		if err != nil {
+			return err
+		}
+		output(successStyle.Render("✓") + " Snapshot " + dimStyle.Render(fmt.Sprintf("#%d", snapshot.ID)) +
+			" saved to " + snapshot.Path + " " + dimStyle.Render("("+formatBytes(snapshot.Size)+")"))
+		return nil
+	},
+}
+
+var snapshotListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List recorded snapshots, newest first",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		snapshots, err := store.ListSnapshots()
+		if err != nil {
+			return err
+		}
+		if format, _ := cmd.Flags().GetString("format"); format == "json" {
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			return enc.Encode(snapshots)
+		}
+		if len(snapshots) == 0 {
+			output("No snapshots")
+			return nil
+		}
+		output(titleStyle.Render("Snapshots"))
+		output()
+		for _, s := range snapshots {
+			line := "  " + dimStyle.Render(fmt.Sprintf("#%d", s.ID)) + " " + s.CreatedAt.Local().Format("2006-01-02 15:04") +
+				" " + typeStyle.Render(s.Reason) + " " + dimStyle.Render(fmt.Sprintf("v%d, %s", s.SchemaVersion, formatBytes(s.Size)))
+			if _, err := os.Stat(s.Path); err != nil {
+				line += " " + dimStyle.Render("(file missing)")
+			}
+			output(line)
+			output("    " + s.Path)
+		}
+		return nil
+	},
+}
+
+var snapshotRestoreCmd = &cobra.Command{
+	Use:   "restore <id|path>",
+	Short: "Replace the database with a snapshot",
+	Long: `Replace the database with a snapshot, given by its number in snapshot list
+or its path. The current database is snapshotted first unless --no-snapshot
+is given, so the restore can itself be undone. Stop the MCP server and other
+users of the database before restoring.
+
+The restored database only records the snapshots taken before it was; later
+snapshot files stay in the backup directory.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		path := args[0]
+		if id, err := strconv.ParseInt(strings.TrimPrefix(path, "#"), 10, 64); err == nil {
+			snapshots, err := store.ListSnapshots()
+			if err != nil {
+				return err
+			}
+			path = ""
+			for _, s := range snapshots {
+				if s.ID == id {
+					path = s.Path
+				}
+			}
+			if path == "" {
+				return &storage.NotFoundError{Kind: "snapshot", Name: args[0]}
+			}
+		}
+		if _, err := os.Stat(path); err != nil {
+			return err
+		}
+
+		if err := snapshotBefore(cmd, store, "restore"); err != nil {
+			return err
+		}
+		store.Close()
+		if err := storage.RestoreSnapshot(path, dbPath); err != nil {
+			return err
+		}
+		output(successStyle.Render("✓") + " Restored " + dbPath + " from " + path)
+		return nil
+	},
+}
+
+func init() {
+	snapshotCreateCmd.Flags().String("reason", "manual", "why the snapshot was taken, recorded with it")
+	snapshotListCmd.Flags().String("format", "default", "output format: default, json")
+	addNoSnapshotFlag(snapshotRestoreCmd)
+
+	snapshotCmd.AddCommand(snapshotCreateCmd)
+	snapshotCmd.AddCommand(snapshotListCmd)
+	snapshotCmd.AddCommand(snapshotRestoreCmd)
+	rootCmd.AddCommand(snapshotCmd)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+func TestSnapshotBeforeMaintenance(t *testing.T) {
+	useTestDB(t)
+	t.Setenv("HOME", t.TempDir())
+	backups := filepath.Join(t.TempDir(), "backups")
+	t.Setenv("CLAUDE_MEMORY_BACKUP_DIR", backups)
+	withStore(t, func(s *storage.Store) {
+		s.CreateEntity("Noisy", "project", []string{"Best", "Good", "Meh"})
+	})
+
+	defer decayArchiveCmd.Flags().Set("keep-per-entity", "0")
+	got := runRootCmd(t, "decay", "archive", "--keep-per-entity", "1")
+	if !strings.Contains(got, "Snapshot saved to "+backups) || !strings.Contains(got, "Restore with: mark42 snapshot restore 1") {
+		t.Errorf("expected a snapshot and its restore command:\n%s", got)
+	}
+	files, _ := os.ReadDir(backups)
+	if len(files) != 1 || !strings.HasSuffix(files[0].Name(), "-decay-archive.db") {
+		t.Errorf("expected one snapshot file, got %v", files)
+	}
+
+	defer decayArchiveCmd.Flags().Set("no-snapshot", "false")
+	if got := runRootCmd(t, "decay", "archive", "--no-snapshot"); strings.Contains(got, "Snapshot saved") {
+		t.Errorf("expected --no-snapshot to skip it:\n%s", got)
+	}
+	if got := runRootCmd(t, "snapshot", "list"); !strings.Contains(got, "#1") || !strings.Contains(got, "decay archive") || strings.Contains(got, "#2") {
+		t.Errorf("expected the one snapshot listed:\n%s", got)
+	}
+
+	if got := runRootCmd(t, "snapshot", "restore", "1"); !strings.Contains(got, "Restored") {
+		t.Errorf("expected the restore reported:\n%s", got)
+	}
+	withStore(t, func(s *storage.Store) {
+		entity, err := s.GetEntity("Noisy")
+		if err != nil || len(entity.Observations) != 3 {
+			t.Errorf("expected every observation back, got %+v, %v", entity, err)
+		}
+	})
+	if files, _ := os.ReadDir(backups); len(files) != 2 {
+		t.Errorf("expected the database snapshotted before the restore, got %v", files)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+var statsToolsCmd = &cobra.Command{
+	Use:   "tools",
+	Short: "Show how often each MCP tool is called, fails, and how long it takes",
+	Long: `Show the calls, errors, and latency of each MCP tool, as counted by the MCP
+server: the mean, the slowest call, and the median and 95th percentile
+estimated from a latency histogram (--format json lists its buckets).
+Counts add up across server runs until --reset clears them.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		if reset, _ := cmd.Flags().GetBool("reset"); reset {
+			if err := store.ResetToolStats(); err != nil {
+				return err
+			}
+			output(successStyle.Render("✓") + " Tool statistics cleared")
+			return nil
+		}
+
+		stats, err := store.ToolStats()
+		if err != nil {
+			return err
+		}
+		if format, _ := cmd.Flags().GetString("format"); format == "json" {
+			if stats == nil {
+				stats = []storage.ToolStat{}
+			}
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			return enc.Encode(stats)
+		}
+
+		if len(stats) == 0 {
+			output("No tool calls recorded yet")
+			return nil
+		}
+		width := len("Tool")
+		for _, s := range stats {
+			width = max(width, len(s.Tool))
+		}
+		output(titleStyle.Render("Tool Usage"))
+		output()
+		output(dimStyle.Render(fmt.Sprintf("  %-*s %7s %7s %9s %9s %9s %9s  %s",
+			width, "Tool", "Calls", "Errors", "Avg", "p50", "p95", "Max", "Last called")))
+		for _, s := range stats {
+			line := fmt.Sprintf("  %-*s %7d %7d %9s %9s %9s %9s  ", width, s.Tool, s.Calls, s.Errors,
+				formatMs(s.AvgMs), formatMs(s.P50Ms), formatMs(s.P95Ms), formatMs(s.MaxMs))
+			output(line + dimStyle.Render(s.LastCalled.Local().Format("2006-01-02 15:04")))
+		}
+		return nil
+	},
+}
+
+// formatMs renders a latency in milliseconds, in seconds from one second up
+// and to a tenth below ten milliseconds.
+func formatMs(ms float64) string {
+	switch {
+	case ms >= 1000:
+		return strings.TrimSuffix(fmt.Sprintf("%.1f", ms/1000), ".0") + "s"
+	case ms < 10:
+		return fmt.Sprintf("%.1fms", ms)
+	}
+	return fmt.Sprintf("%.0fms", ms)
+}
+
+func init() {
+	statsToolsCmd.Flags().Bool("reset", false, "clear the recorded counts")
+	statsToolsCmd.Flags().String("format", "default", "output format: default, json")
+	statsCmd.AddCommand(statsToolsCmd)
+}
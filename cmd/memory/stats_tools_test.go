@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+func TestStatsToolsCommand(t *testing.T) {
+	useTestDB(t)
+
+	if got := runRootCmd(t, "stats", "tools"); !strings.Contains(got, "No tool calls recorded") {
+		t.Errorf("expected an empty report:\n%s", got)
+	}
+
+	withStore(t, func(s *storage.Store) {
+		s.RecordToolCall("search_nodes", 20*time.Millisecond, false)
+		s.RecordToolCall("search_nodes", 3*time.Second, true)
+	})
+	got := runRootCmd(t, "stats", "tools")
+	if !strings.Contains(got, "search_nodes") || !strings.Contains(got, "3s") {
+		t.Errorf("expected the tool's counts and slowest call:\n%s", got)
+	}
+
+	defer statsToolsCmd.Flags().Set("format", "default")
+	var stats []storage.ToolStat
+	if err := json.Unmarshal([]byte(runRootCmd(t, "stats", "tools", "--format", "json")), &stats); err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 1 || stats[0].Calls != 2 || stats[0].Errors != 1 || len(stats[0].Latency) != 2 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+
+	statsToolsCmd.Flags().Set("format", "default")
+	defer statsToolsCmd.Flags().Set("reset", "false")
+	runRootCmd(t, "stats", "tools", "--reset")
+	statsToolsCmd.Flags().Set("reset", "false")
+	if got := runRootCmd(t, "stats", "tools"); !strings.Contains(got, "No tool calls recorded") {
+		t.Errorf("expected the counts cleared:\n%s", got)
+	}
+}
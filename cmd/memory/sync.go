@@ -0,0 +1,257 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+const defaultSyncFile = "memory.ndjson"
+
+// syncConfig is ~/.claude/mark42/sync.json, written by sync init.
+type syncConfig struct {
+	Dir  string `json:"dir"`  // Git repository or synced folder holding the sync file
+	File string `json:"file"` // Sync file name within Dir
+}
+
+func (c syncConfig) path() string {
+	return filepath.Join(c.Dir, c.File)
+}
+
+func syncConfigPath() string {
+	return filepath.Join(globalConfigDir(), "sync.json")
+}
+
+func loadSyncConfig() (syncConfig, error) {
+	var cfg syncConfig
+	data, err := os.ReadFile(syncConfigPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, errors.New("sync is not set up; run 'mark42 sync init <dir>' first")
+	}
+	if err != nil {
+		return cfg, err
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("%s: %w", syncConfigPath(), err)
+	}
+	if cfg.File == "" {
+		cfg.File = defaultSyncFile
+	}
+	return cfg, nil
+}
+
+func saveSyncConfig(cfg syncConfig) error {
+	if err := os.MkdirAll(globalConfigDir(), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(syncConfigPath(), append(data, '\n'), 0o644)
+}
+
+// runGit runs git in dir and returns its trimmed output.
+func runGit(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	combined, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(combined)))
+	}
+	return strings.TrimSpace(string(combined)), nil
+}
+
+func isGitRepo(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, ".git"))
+	return err == nil
+}
+
+// gitRemoteBranch returns the current branch when the repository has an origin
+// remote, and whether origin already has that branch to pull from.
+func gitRemoteBranch(dir string) (branch string, remoteHasBranch bool, err error) {
+	remotes, err := runGit(dir, "remote")
+	if err != nil || !strings.Contains("\n"+remotes+"\n", "\norigin\n") {
+		return "", false, err
+	}
+	branch, err = runGit(dir, "symbolic-ref", "--short", "HEAD")
+	if err != nil {
+		return "", false, err
+	}
+	heads, err := runGit(dir, "ls-remote", "--heads", "origin", branch)
+	if err != nil {
+		return "", false, err
+	}
+	return branch, heads != "", nil
+}
+
+// syncGraph merges the local database with the sync file and writes the result
+// to both. With git, remote changes are pulled first and the file is
+// committed afterwards; push also publishes the commit.
+func syncGraph(push bool) error {
+	cfg, err := loadSyncConfig()
+	if err != nil {
+		return err
+	}
+
+	useGit := isGitRepo(cfg.Dir)
+	var branch string
+	var hasRemote bool
+	if useGit {
+		var remoteHasBranch bool
+		branch, remoteHasBranch, err = gitRemoteBranch(cfg.Dir)
+		if err != nil {
+			return err
+		}
+		hasRemote = branch != ""
+		if remoteHasBranch {
+			// Line-level conflicts are settled by the record merge below:
+			// the file is rewritten from the merged database, which still
+			// holds every local change whatever git chose.
+			if _, err := runGit(cfg.Dir, "pull", "--no-rebase", "--allow-unrelated-histories", "-X", "theirs", "origin", branch); err != nil {
+				return err
+			}
+		}
+	}
+
+	store, err := getStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	if err := store.Migrate(); err != nil {
+		return err
+	}
+
+	remote, err := storage.ReadSyncFile(cfg.path())
+	if err != nil {
+		return err
+	}
+	store.SetSource(storage.SourceImport + ":" + filepath.Base(cfg.path()))
+	stats, err := store.MergeReplica(remote)
+	if err != nil {
+		return err
+	}
+	merged, err := store.ExportReplica()
+	if err != nil {
+		return err
+	}
+	if err := storage.WriteSyncFile(cfg.path(), merged); err != nil {
+		return err
+	}
+
+	output(successStyle.Render("✓ Synced with " + cfg.path()))
+	output(dimStyle.Render(fmt.Sprintf("  %d records; %d created, %d updated, %d deleted locally",
+		len(merged), stats.Created, stats.Updated, stats.Deleted)))
+
+	if !useGit {
+		return nil
+	}
+	if _, err := runGit(cfg.Dir, "add", cfg.File); err != nil {
+		return err
+	}
+	if changes, err := runGit(cfg.Dir, "status", "--porcelain", "--", cfg.File); err != nil {
+		return err
+	} else if changes != "" {
+		host, _ := os.Hostname()
+		if _, err := runGit(cfg.Dir, "commit", "-m", "mark42 sync from "+host, "--", cfg.File); err != nil {
+			return err
+		}
+	}
+	if push && hasRemote {
+		if _, err := runGit(cfg.Dir, "push", "-u", "origin", "HEAD"); err != nil {
+			return err
+		}
+		output(dimStyle.Render("  Pushed to origin/" + branch))
+	}
+	return nil
+}
+
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Share memory between machines through a git repo or synced folder",
+	Long: `Sync serializes the graph to a canonical NDJSON file, one record per line in a
+deterministic order, so it diffs and merges cleanly. The file holds the replica
+export (see 'mark42 merge'): each record has a stable ID and a logical
+timestamp, and each push or pull merges it into the local database per record,
+the later change winning, before writing the result back. Deletions are kept
+as tombstones so they propagate to other machines.
+
+The merge is last-writer-wins, not a three-way merge: there is no merge base,
+so when both machines change the same record between syncs, the change with
+the later timestamp is kept whole and the other is dropped. Git only carries
+the file; its pull takes the remote side of any conflicting lines, and the
+file is then rewritten from the merged database.`,
+}
+
+var syncNoGit bool
+var syncFile string
+
+var syncInitCmd = &cobra.Command{
+	Use:   "init <dir>",
+	Short: "Set up sync to a directory, initializing a git repository in it",
+	Long: `Set up sync to a directory. Unless --no-git is given or the directory is
+already a git repository, it is initialized as one; add an "origin" remote to
+share it. Use --no-git for folders synced by other tools such as Syncthing.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, err := filepath.Abs(args[0])
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+		if !syncNoGit && !isGitRepo(dir) {
+			if _, err := runGit(dir, "init"); err != nil {
+				return err
+			}
+		}
+
+		cfg := syncConfig{Dir: dir, File: syncFile}
+		if err := saveSyncConfig(cfg); err != nil {
+			return err
+		}
+
+		output(successStyle.Render("✓ Sync set up at " + cfg.path()))
+		if isGitRepo(dir) {
+			output(dimStyle.Render("  Add a remote with: git -C " + dir + " remote add origin <url>"))
+		}
+		return nil
+	},
+}
+
+var syncPushCmd = &cobra.Command{
+	Use:   "push",
+	Short: "Merge local memory into the sync file and publish it",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return syncGraph(true)
+	},
+}
+
+var syncPullCmd = &cobra.Command{
+	Use:   "pull",
+	Short: "Fetch the sync file and merge it into local memory",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return syncGraph(false)
+	},
+}
+
+func init() {
+	syncInitCmd.Flags().BoolVar(&syncNoGit, "no-git", false, "use a plain folder instead of a git repository")
+	syncInitCmd.Flags().StringVar(&syncFile, "file", defaultSyncFile, "name of the sync file")
+
+	syncCmd.AddCommand(syncInitCmd)
+	syncCmd.AddCommand(syncPushCmd)
+	syncCmd.AddCommand(syncPullCmd)
+	rootCmd.AddCommand(syncCmd)
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+// useSyncMachine points HOME and the database at a fresh directory, standing
+// in for one machine taking part in sync.
+func useSyncMachine(t *testing.T, home string) {
+	t.Helper()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	dbPath = filepath.Join(home, ".claude", "memory.db")
+}
+
+func runSyncCmd(t *testing.T, args ...string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	oldOut := out
+	out = &buf
+	defer func() { out = oldOut }()
+	syncNoGit = false
+	syncFile = defaultSyncFile
+
+	rootCmd.SetArgs(append([]string{"sync"}, args...))
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("sync %v failed: %v", args, err)
+	}
+	return buf.String()
+}
+
+func withStore(t *testing.T, fn func(*storage.Store)) {
+	t.Helper()
+	store, err := getStore()
+	if err != nil {
+		t.Fatalf("getStore failed: %v", err)
+	}
+	defer store.Close()
+	if err := store.Migrate(); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	fn(store)
+}
+
+func TestSync_FolderRoundTrip(t *testing.T) {
+	useTestDB(t)
+	shared := filepath.Join(t.TempDir(), "shared")
+	desktop, laptop := t.TempDir(), t.TempDir()
+
+	useSyncMachine(t, desktop)
+	runSyncCmd(t, "init", shared, "--no-git")
+	withStore(t, func(s *storage.Store) {
+		s.CreateEntity("Go", "language", []string{"Fast compiler", "Garbage collected"})
+	})
+	runSyncCmd(t, "push")
+
+	useSyncMachine(t, laptop)
+	runSyncCmd(t, "init", shared, "--no-git")
+	got := runSyncCmd(t, "pull")
+	if !strings.Contains(got, "3 created") {
+		t.Errorf("expected 3 records created on laptop, got:\n%s", got)
+	}
+	withStore(t, func(s *storage.Store) {
+		if _, err := s.GetEntity("Go"); err != nil {
+			t.Fatalf("Go not pulled to laptop: %v", err)
+		}
+		s.DeleteObservation("Go", "Garbage collected")
+		s.CreateEntity("Rust", "language", nil)
+	})
+	runSyncCmd(t, "push")
+
+	useSyncMachine(t, desktop)
+	runSyncCmd(t, "pull")
+	withStore(t, func(s *storage.Store) {
+		entity, err := s.GetEntity("Go")
+		if err != nil {
+			t.Fatalf("GetEntity failed: %v", err)
+		}
+		if len(entity.Observations) != 1 || entity.Observations[0] != "Fast compiler" {
+			t.Errorf("expected laptop deletion to propagate, got %v", entity.Observations)
+		}
+		if _, err := s.GetEntity("Rust"); err != nil {
+			t.Errorf("Rust not pulled to desktop: %v", err)
+		}
+	})
+
+	data, err := os.ReadFile(filepath.Join(shared, defaultSyncFile))
+	if err != nil {
+		t.Fatalf("reading sync file: %v", err)
+	}
+	// The tombstone names the observation by its content hash only
+	if !strings.Contains(string(data), `"deleted":true,"deletedAt":`) || strings.Contains(string(data), "Garbage collected") {
+		t.Errorf("expected tombstone in sync file, got:\n%s", data)
+	}
+}
+
+func TestSync_NotInitialized(t *testing.T) {
+	useTestDB(t)
+	useSyncMachine(t, t.TempDir())
+
+	rootCmd.SetArgs([]string{"sync", "pull"})
+	err := rootCmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "sync init") {
+		t.Errorf("expected error pointing to sync init, got %v", err)
+	}
+}
+
+func TestSync_GitRemote(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	useTestDB(t)
+	for _, v := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(v, "mark42 test")
+	}
+	for _, v := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(v, "test@example.com")
+	}
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	root := t.TempDir()
+	origin := filepath.Join(root, "origin.git")
+	if out, err := exec.Command("git", "init", "--bare", origin).CombinedOutput(); err != nil {
+		t.Fatalf("git init --bare: %v: %s", err, out)
+	}
+
+	machines := []string{filepath.Join(root, "desktop"), filepath.Join(root, "laptop")}
+	for i, home := range machines {
+		useSyncMachine(t, home)
+		repo := filepath.Join(home, "memory-sync")
+		runSyncCmd(t, "init", repo)
+		if _, err := runGit(repo, "remote", "add", "origin", origin); err != nil {
+			t.Fatal(err)
+		}
+		withStore(t, func(s *storage.Store) {
+			s.CreateEntity("Machine"+itoa(i), "host", nil)
+		})
+		runSyncCmd(t, "push")
+	}
+
+	useSyncMachine(t, machines[0])
+	runSyncCmd(t, "pull")
+	withStore(t, func(s *storage.Store) {
+		if _, err := s.GetEntity("Machine1"); err != nil {
+			t.Errorf("laptop entity not pulled to desktop: %v", err)
+		}
+	})
+
+	log, err := runGit(filepath.Join(machines[0], "memory-sync"), "log", "--oneline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(log, "mark42 sync from") {
+		t.Errorf("expected sync commits, got:\n%s", log)
+	}
+}
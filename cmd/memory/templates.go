@@ -0,0 +1,269 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+// starterTemplate is a knowledge pack `init --template` seeds: types to
+// register in the global config.json and example entities to start from.
+type starterTemplate struct {
+	Description   string
+	EntityTypes   map[string]string
+	RelationTypes map[string]storage.RelationTypeInfo
+	Entities      []starterEntity
+	Relations     []starterRelation
+}
+
+// starterEntity is an example entity; its observations are stored static.
+type starterEntity struct {
+	Name         string
+	Type         string
+	Observations []string
+}
+
+type starterRelation struct {
+	From, To, Type string
+}
+
+// baseEntityTypes and baseRelationTypes are registered by every template.
+var (
+	baseEntityTypes = map[string]string{
+		"project":    "A codebase or product",
+		"convention": "A rule the code follows",
+		"decision":   "A choice made, with its reasons",
+		"pattern":    "A reusable practice",
+		"tool":       "A program or service the work depends on",
+	}
+	baseRelationTypes = map[string]storage.RelationTypeInfo{
+		"uses":       {Inverse: "used_by", Description: "Depends on to work"},
+		"follows":    {Description: "Applies a convention or pattern"},
+		"supersedes": {Inverse: "superseded_by", Description: "Replaces an older decision"},
+		"related_to": {Symmetric: true},
+	}
+)
+
+var starterTemplates = map[string]starterTemplate{
+	"minimal": {
+		Description:   "Register the common entity and relation types, with no example entities",
+		EntityTypes:   baseEntityTypes,
+		RelationTypes: baseRelationTypes,
+	},
+	"go-project": {
+		Description: "Go conventions: errors, tests, packages, and tooling",
+		EntityTypes: withTypes(baseEntityTypes, map[string]string{
+			"package": "A Go package and what it owns",
+		}),
+		RelationTypes: baseRelationTypes,
+		Entities: []starterEntity{
+			{"Go Error Handling", "convention", []string{
+				"Wrap errors with fmt.Errorf and %w to keep the cause inspectable with errors.Is and errors.As",
+				"Return errors rather than panicking; panic only for programmer errors",
+				"Compare against exported sentinel errors, never against error strings",
+			}},
+			{"Go Testing", "convention", []string{
+				"Prefer table-driven tests with t.Run subtests",
+				"Use t.TempDir and t.Setenv so tests clean up after themselves",
+				"Run go test -race in CI",
+			}},
+			{"Go Package Layout", "convention", []string{
+				"Binaries live under cmd/<name>; code not meant for import lives under internal/",
+				"Name packages for what they provide, not util or common",
+			}},
+			{"Go Tooling", "tool", []string{
+				"gofmt and go vet must pass before committing",
+				"golangci-lint runs the wider linter set",
+			}},
+		},
+		Relations: []starterRelation{
+			{"Go Testing", "Go Tooling", "uses"},
+			{"Go Error Handling", "Go Testing", "related_to"},
+		},
+	},
+	"web-app": {
+		Description: "Web app conventions: API design, frontend, security, and deployment",
+		EntityTypes: withTypes(baseEntityTypes, map[string]string{
+			"component": "A UI component or page",
+			"endpoint":  "An HTTP API route",
+		}),
+		RelationTypes: withTypes(baseRelationTypes, map[string]storage.RelationTypeInfo{
+			"calls": {Inverse: "called_by", Description: "Requests data from"},
+		}),
+		Entities: []starterEntity{
+			{"API Design", "convention", []string{
+				"Resources are plural nouns; actions are HTTP methods, not verbs in the path",
+				"Errors return a JSON body with a machine-readable code and a human message",
+				"Version breaking changes under a new path prefix such as /v2",
+			}},
+			{"Frontend Conventions", "convention", []string{
+				"Components own their styles and are tested through what users see",
+				"Keep server state in a data-fetching cache, not in global UI state",
+			}},
+			{"Web Security", "convention", []string{
+				"Never log tokens, passwords, or session cookies",
+				"Validate input on the server even when the client validates it",
+				"Set cookies HttpOnly, Secure, and SameSite",
+			}},
+			{"Deployment", "decision", []string{
+				"Configuration comes from environment variables, never from committed files",
+				"Every deploy runs database migrations before the new version takes traffic",
+			}},
+		},
+		Relations: []starterRelation{
+			{"Frontend Conventions", "API Design", "follows"},
+			{"Web Security", "API Design", "related_to"},
+		},
+	},
+}
+
+// withTypes returns base with extra added.
+func withTypes[V any](base, extra map[string]V) map[string]V {
+	types := maps.Clone(base)
+	maps.Copy(types, extra)
+	return types
+}
+
+// templateNames lists the starter templates, sorted.
+func templateNames() []string {
+	return slices.Sorted(maps.Keys(starterTemplates))
+}
+
+// templateHelp lists the starter templates with their descriptions.
+func templateHelp() string {
+	var b strings.Builder
+	for _, name := range templateNames() {
+		fmt.Fprintf(&b, "\n  %-12s %s", name, starterTemplates[name].Description)
+	}
+	return b.String()
+}
+
+// templateResult reports what applyTemplate added.
+type templateResult struct {
+	EntityTypes   int // Types newly registered in config.json
+	RelationTypes int
+	Entities      int // Example entities created
+	Existing      int // Example entities already present, left as they are
+	Relations     int
+}
+
+// applyTemplate registers the template's types in the config.json in
+// configDir and creates its example entities, leaving whatever exists alone.
+func applyTemplate(store *storage.Store, name, configDir string) (templateResult, error) {
+	var result templateResult
+	tmpl, ok := starterTemplates[name]
+	if !ok {
+		return result, fmt.Errorf("unknown template %q (available: %s)", name, strings.Join(templateNames(), ", "))
+	}
+
+	var err error
+	result.EntityTypes, result.RelationTypes, err = registerTypes(filepath.Join(configDir, "config.json"), tmpl.EntityTypes, tmpl.RelationTypes)
+	if err != nil {
+		return result, err
+	}
+	store.SetEntityTypes(withTypes(tmpl.EntityTypes, store.EntityTypes()))
+	store.SetRelationTypes(withTypes(tmpl.RelationTypes, store.RelationTypes()))
+
+	created := make(map[string]bool)
+	for _, e := range tmpl.Entities {
+		if _, err := store.CreateEntity(e.Name, e.Type, nil); errors.Is(err, storage.ErrEntityExists) {
+			result.Existing++
+			continue
+		} else if err != nil {
+			return result, err
+		}
+		for _, obs := range e.Observations {
+			if err := store.AddObservationWithType(e.Name, obs, storage.FactTypeStatic); err != nil {
+				return result, err
+			}
+		}
+		created[e.Name] = true
+		result.Entities++
+	}
+	// Relations only among new entities, so a relation ended since an earlier
+	// run stays ended
+	for _, r := range tmpl.Relations {
+		if !created[r.From] || !created[r.To] {
+			continue
+		}
+		if err := store.CreateRelation(r.From, r.To, r.Type); err != nil {
+			return result, err
+		}
+		result.Relations++
+	}
+	return result, nil
+}
+
+// registerTypes adds the entity and relation types not yet registered in the
+// config.json at path, creating it if needed, and returns how many of each it
+// added. Other settings in the file are kept as they are.
+func registerTypes(path string, entityTypes map[string]string, relationTypes map[string]storage.RelationTypeInfo) (int, int, error) {
+	config := map[string]json.RawMessage{}
+	data, err := os.ReadFile(path)
+	if err == nil {
+		if err := json.Unmarshal(data, &config); err != nil {
+			return 0, 0, fmt.Errorf("%s: %w", path, err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return 0, 0, err
+	}
+
+	addedEntities, err := addMissing(config, "entityTypes", entityTypes)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s: %w", path, err)
+	}
+	addedRelations, err := addMissing(config, "relationTypes", relationTypes)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s: %w", path, err)
+	}
+	if addedEntities == 0 && addedRelations == 0 {
+		return 0, 0, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return 0, 0, err
+	}
+	data, err = json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return 0, 0, err
+	}
+	return addedEntities, addedRelations, os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// addMissing adds the entries of values missing from the map under key in
+// config, returning how many it added.
+func addMissing[V any](config map[string]json.RawMessage, key string, values map[string]V) (int, error) {
+	existing := map[string]json.RawMessage{}
+	if raw, ok := config[key]; ok {
+		if err := json.Unmarshal(raw, &existing); err != nil {
+			return 0, fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	added := 0
+	for name, v := range values {
+		if _, ok := existing[name]; ok {
+			continue
+		}
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return 0, err
+		}
+		existing[name] = raw
+		added++
+	}
+	if added > 0 {
+		raw, err := json.Marshal(existing)
+		if err != nil {
+			return 0, err
+		}
+		config[key] = raw
+	}
+	return added, nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+func TestInitTemplate(t *testing.T) {
+	useTestDB(t)
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	writeConfig(t, home, `{"user": "alice", "entityTypes": {"pattern": "Mine"}}`)
+	defer initCmd.Flags().Set("template", "")
+
+	got := runRootCmd(t, "init", "--template", "go-project")
+	if !strings.Contains(got, "Applied template go-project") || !strings.Contains(got, "4 entities and 2 relations created") {
+		t.Errorf("unexpected output:\n%s", got)
+	}
+
+	cfg := loadEffectiveConfig("")
+	if cfg.User != "alice" || cfg.EntityTypes["pattern"] != "Mine" || cfg.EntityTypes["package"] == "" {
+		t.Errorf("expected the types added and existing settings kept, got %+v", cfg)
+	}
+	if _, ok := cfg.RelationTypes["uses"]; !ok {
+		t.Errorf("expected relation types registered, got %+v", cfg.RelationTypes)
+	}
+	withStore(t, func(s *storage.Store) {
+		e, err := s.GetEntity("Go Testing")
+		if err != nil || e.Type != "convention" || len(e.Observations) != 3 {
+			t.Errorf("expected the example convention, got %+v, %v", e, err)
+		}
+	})
+
+	got = runRootCmd(t, "init", "--template", "go-project")
+	if !strings.Contains(got, "0 entity types and 0 relation types registered, 0 entities") || !strings.Contains(got, "4 entities already existed") {
+		t.Errorf("expected a second run to change nothing:\n%s", got)
+	}
+
+	rootCmd.SetArgs([]string{"init", "--template", "cobol"})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "go-project, minimal, web-app") {
+		t.Errorf("expected an unknown template to list the available ones, got %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+// undoDescriptions name each operation undo reverses.
+var undoDescriptions = map[string]string{
+	storage.OpDeleteEntity:      "entity delete",
+	storage.OpDeleteObservation: "observation delete",
+	storage.OpDeleteRelation:    "relation delete",
+	storage.OpArchive:           "decay archive",
+	storage.OpForget:            "decay forget",
+	storage.OpMerge:             "merge",
+}
+
+var undoCmd = &cobra.Command{
+	Use:   "undo",
+	Short: "Undo the last destructive operation",
+	Long: `Reverse the latest entity, observation, or relation delete, decay archive
+or forget run, or merge, putting back the rows it removed. Only operations
+within the undo window can be undone: 24 hours unless config.json sets
+undo.windowHours, where 0 stops keeping anything to undo. Running undo again
+reverses the operation before.
+
+Restored observations go back on the current version of their entity. A
+deleted entity is not restored over one created since under its name.
+Embeddings are not restored; run embed generate to recreate them.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		if err := store.Migrate(); err != nil {
+			return err
+		}
+
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		format, _ := cmd.Flags().GetString("format")
+
+		undo := store.Undo
+		if dryRun {
+			undo = store.LastUndoable
+		}
+		op, err := undo()
+		if errors.Is(err, storage.ErrNotFound) && format != "json" {
+			output("Nothing to undo")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if format == "json" {
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			return enc.Encode(op)
+		}
+		prefix := successStyle.Render("✓") + " Undid "
+		if dryRun {
+			prefix = "Would undo "
+		}
+		output(prefix + undoDescriptions[op.Operation] + " of " + entityStyle.Render(strings.Join(op.Entities, ", ")) +
+			dimStyle.Render(" ("+op.At.Local().Format("2006-01-02 15:04")+")"))
+		if op.Observations > 0 || op.Relations > 0 {
+			output(fmt.Sprintf("  %d observations, %d relations", op.Observations, op.Relations))
+		}
+		return nil
+	},
+}
+
+func init() {
+	undoCmd.Flags().Bool("dry-run", false, "show what would be undone without undoing it")
+	undoCmd.Flags().String("format", "default", "output format: default, json")
+	rootCmd.AddCommand(undoCmd)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+func TestUndoCommand(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("CLAUDE_PROJECT_DIR", t.TempDir())
+	useTestDB(t)
+	withStore(t, func(s *storage.Store) {
+		s.CreateEntity("Docker", "tool", []string{"Used for CI"})
+	})
+
+	runRootCmd(t, "entity", "delete", "Docker")
+
+	defer undoCmd.Flags().Set("dry-run", "false")
+	if got := runRootCmd(t, "undo", "--dry-run"); !strings.Contains(got, "Would undo entity delete of Docker") {
+		t.Errorf("expected a preview of the delete:\n%s", got)
+	}
+	undoCmd.Flags().Set("dry-run", "false")
+	if got := runRootCmd(t, "undo"); !strings.Contains(got, "Undid entity delete of Docker") || !strings.Contains(got, "1 observations") {
+		t.Errorf("expected the delete undone:\n%s", got)
+	}
+	withStore(t, func(s *storage.Store) {
+		if e, err := s.GetEntity("Docker"); err != nil || len(e.Observations) != 1 {
+			t.Errorf("expected Docker restored, got %+v, %v", e, err)
+		}
+	})
+	if got := runRootCmd(t, "undo"); !strings.Contains(got, "Nothing to undo") {
+		t.Errorf("expected nothing left to undo:\n%s", got)
+	}
+
+	// A zero window keeps nothing to undo
+	writeConfig(t, home, `{"undo": {"windowHours": 0}}`)
+	runRootCmd(t, "entity", "delete", "Docker")
+	if got := runRootCmd(t, "undo"); !strings.Contains(got, "Nothing to undo") {
+		t.Errorf("expected nothing to undo with no window:\n%s", got)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+func TestUserFlags(t *testing.T) {
+	useTestDB(t)
+	withStore(t, func(s *storage.Store) {
+		s.SetUser("alice")
+		s.CreateEntity("konfig", "project", []string{"Loads YAML"})
+		s.SetUser("bob")
+		s.CreateEntity("mark42", "project", []string{"Stores memories"})
+	})
+
+	t.Setenv("CLAUDE_MEMORY_USER", "alice")
+	runRootCmd(t, "obs", "add", "konfig", "Loads TOML")
+
+	defer entityListCmd.Flags().Set("user", "")
+	got := runRootCmd(t, "entity", "list", "--user", "alice")
+	if !strings.Contains(got, "konfig") || !strings.Contains(got, "@alice") || strings.Contains(got, "mark42") {
+		t.Errorf("expected only alice's entity:\n%s", got)
+	}
+
+	defer searchCmd.Flags().Set("user", "")
+	got = runRootCmd(t, "search", "", "--user", "bob")
+	if !strings.Contains(got, "mark42") || strings.Contains(got, "konfig") {
+		t.Errorf("expected only bob's entity:\n%s", got)
+	}
+
+	got = runRootCmd(t, "blame", "konfig")
+	if strings.Count(got, "@alice") != 2 {
+		t.Errorf("expected alice as the author of both observations:\n%s", got)
+	}
+}
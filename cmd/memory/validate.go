@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Audit the graph against the configured rules",
+	Long: `Check every relation valid now and every observation of a latest entity
+against the rules under "rules" in config.json (or the file CLAUDE_MEMORY_RULES
+names), listing each one that breaks a rule. Exits with status 2 when any do.
+
+Rules are also checked on write: in "error" mode a breaking write is rejected,
+in "warn" mode it is written and logged.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := getStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		if err := store.Migrate(); err != nil {
+			return err
+		}
+
+		violations, err := store.ValidateGraph()
+		if err != nil {
+			return err
+		}
+
+		if format, _ := cmd.Flags().GetString("format"); format == "json" {
+			if violations == nil {
+				violations = []storage.RuleViolation{}
+			}
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(violations); err != nil {
+				return err
+			}
+		} else if len(violations) == 0 {
+			output(successStyle.Render("✓") + " The graph follows the rules")
+		} else {
+			output(titleStyle.Render(fmt.Sprintf("Rule violations (%d)", len(violations))))
+			output()
+			for _, v := range violations {
+				output("  " + typeStyle.Render(v.Kind) + " " + entityStyle.Render(v.Subject))
+				output("      " + dimStyle.Render(v.Kind+" "+v.Reason))
+			}
+		}
+
+		if len(violations) > 0 {
+			return &storage.ValidationError{Field: "graph", Reason: fmt.Sprintf("breaks the rules %d times", len(violations))}
+		}
+		return nil
+	},
+}
+
+func init() {
+	validateCmd.Flags().String("format", "default", "output format: default, json")
+	rootCmd.AddCommand(validateCmd)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+func TestValidateCommand(t *testing.T) {
+	useTestDB(t)
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	withStore(t, func(s *storage.Store) {
+		s.CreateEntity("TDD", "pattern", nil)
+		s.CreateEntity("mark42", "project", nil)
+		s.CreateEntity("golangci-lint", "tool", nil)
+		s.CreateRelation("golangci-lint", "mark42", "used_by")
+	})
+
+	if got := runRootCmd(t, "validate"); !strings.Contains(got, "follows the rules") {
+		t.Errorf("expected no violations without rules:\n%s", got)
+	}
+
+	writeConfig(t, home, `{"rules": {"relations": [{"type": "used_by", "from": ["pattern"], "to": ["project"]}]}}`)
+
+	var buf bytes.Buffer
+	oldOut := out
+	out = &buf
+	defer func() { out = oldOut }()
+	rootCmd.SetArgs([]string{"validate"})
+	if err := rootCmd.Execute(); exitCode(err) != exitInvalidInput {
+		t.Errorf("expected violations to exit as invalid input, got %v", err)
+	}
+	if got := buf.String(); !strings.Contains(got, "golangci-lint -[used_by]-> mark42") {
+		t.Errorf("expected the violation listed:\n%s", got)
+	}
+
+	// Writes are checked too
+	rootCmd.SetArgs([]string{"rel", "create", "mark42", "TDD", "used_by"})
+	if err := rootCmd.Execute(); exitCode(err) != exitInvalidInput {
+		t.Errorf("expected the relation rejected, got %v", err)
+	}
+}
@@ -0,0 +1,12 @@
+//go:build unix
+
+package main
+
+import "syscall"
+
+// checkWritable reports why the current user cannot write path, without
+// writing to it.
+func checkWritable(path string) error {
+	const wOK = 0x2 // W_OK in access(2)
+	return syscall.Access(path, wOK)
+}
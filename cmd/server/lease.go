@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// serverLeaseHolder names the server in the database's write lease.
+const serverLeaseHolder = "mcp server"
+
+// defaultWriteLease is how long the server holds the write lease after a
+// tool call unless --write-lease says otherwise.
+const defaultWriteLease = time.Minute
+
+// touchWriteLease takes or extends the server's write lease as it handles a
+// tool call, so maintenance commands wait until the session goes quiet. The
+// lease is extended once half of it is left, not on every call. While a
+// command holds it, the call goes ahead without it.
+func (s *Server) touchWriteLease(ctx context.Context) {
+	if s.writeLease <= 0 || s.store == nil || time.Until(s.leaseUntil) > s.writeLease/2 {
+		return
+	}
+	lease, err := s.store.AcquireWriteLeaseContext(context.WithoutCancel(ctx), serverLeaseHolder, s.writeLease)
+	if err != nil {
+		s.leaseUntil = time.Time{}
+		logger.Debug("write lease not taken", "trace", s.traceID, "error", err)
+		return
+	}
+	s.leaseUntil = lease.ExpiresAt
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// parentCheckInterval is how often the server checks that its parent lives.
+const parentCheckInterval = 5 * time.Second
+
+// lockWait is how long --single-instance waits for a server it stopped to
+// release the lock.
+const lockWait = 10 * time.Second
+
+// watchParentProcess calls cancel once the process that started the server
+// exits. An orphan is adopted by init or a subreaper, so its parent pid
+// changes. Windows keeps reporting the original parent, so there the
+// watchdog never fires.
+func watchParentProcess(ctx context.Context, cancel context.CancelFunc, interval time.Duration) {
+	parent := os.Getppid()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if os.Getppid() != parent {
+				logger.Info("parent process exited, exiting", "parent", parent)
+				cancel()
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !unix
+
+package main
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+// lockInstance needs flock, which this platform lacks.
+func lockInstance(path string, wait time.Duration) (*os.File, error) {
+	return nil, errors.New("--single-instance is not supported on this platform")
+}
@@ -0,0 +1,50 @@
+//go:build unix
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// lockInstance takes the lock file at path for as long as the returned file
+// stays open, recording this process's pid in it. A server already holding
+// it is sent SIGTERM and given wait to shut down and release it.
+func lockInstance(path string, wait time.Duration) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+	lock := func() error { return syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB) }
+
+	if err := lock(); err != nil {
+		if !errors.Is(err, syscall.EWOULDBLOCK) {
+			f.Close()
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+		data, _ := io.ReadAll(f)
+		if pid, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil && pid > 0 {
+			logger.Info("stopping the server holding the lock", "pid", pid, "lock", path)
+			_ = syscall.Kill(pid, syscall.SIGTERM)
+		}
+		deadline := time.Now().Add(wait)
+		for lock() != nil {
+			if time.Now().After(deadline) {
+				f.Close()
+				return nil, fmt.Errorf("another server still holds %s after %s", path, wait)
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+
+	if err := f.Truncate(0); err == nil {
+		_, _ = f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+	return f, nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/mfenderov/mark42/internal/mcp"
+)
+
+// logger writes the server's diagnostics to stderr, which MCP clients keep
+// in their logs. See configureLogger.
+var logger = log.NewWithOptions(os.Stderr, log.Options{Prefix: "mark42"})
+
+// configureLogger applies CLAUDE_MEMORY_LOG_FORMAT (text, json, or logfmt)
+// and CLAUDE_MEMORY_LOG_LEVEL (debug, info, warn, or error; default info)
+// to the server's and the tool handlers' log. Structured formats add a
+// timestamp to each line; debug logs every request.
+func configureLogger() error {
+	switch format := os.Getenv("CLAUDE_MEMORY_LOG_FORMAT"); format {
+	case "", "text":
+	case "json":
+		logger.SetFormatter(log.JSONFormatter)
+		logger.SetReportTimestamp(true)
+		logger.SetTimeFormat(time.RFC3339)
+	case "logfmt":
+		logger.SetFormatter(log.LogfmtFormatter)
+		logger.SetReportTimestamp(true)
+		logger.SetTimeFormat(time.RFC3339)
+	default:
+		return fmt.Errorf("CLAUDE_MEMORY_LOG_FORMAT must be text, json, or logfmt, not %q", format)
+	}
+	if level := os.Getenv("CLAUDE_MEMORY_LOG_LEVEL"); level != "" {
+		l, err := log.ParseLevel(level)
+		if err != nil {
+			return fmt.Errorf("CLAUDE_MEMORY_LOG_LEVEL: %w", err)
+		}
+		logger.SetLevel(l)
+	}
+	mcp.SetLogger(logger)
+	return nil
+}
+
+// logRequest records one handled request: at debug level when it succeeded,
+// as a warning when it failed.
+func logRequest(trace, method, tool string, elapsed time.Duration, err error) {
+	keyvals := []any{"trace", trace, "method", method}
+	if tool != "" {
+		keyvals = append(keyvals, "tool", tool)
+	}
+	keyvals = append(keyvals, "durationMs", float64(elapsed.Microseconds())/1000)
+	if err != nil {
+		logger.Warn("request failed", append(keyvals, "error", err)...)
+		return
+	}
+	logger.Debug("request", keyvals...)
+}
@@ -2,11 +2,20 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/mfenderov/mark42/internal/mcp"
@@ -16,101 +25,416 @@ import (
 var Version = "dev"
 
 func main() {
-	// Determine database path
-	dbPath := os.Getenv("CLAUDE_MEMORY_DB")
+	singleInstance := flag.Bool("single-instance", false, "stop any other server on this database, then hold a lock on it while running")
+	idleTimeout := flag.Duration("idle-timeout", 0, "exit after this long without a request (default: never; or CLAUDE_MEMORY_IDLE_TIMEOUT)")
+	watchParent := flag.Bool("watch-parent", true, "exit when the process that started the server exits")
+	dbFlag := flag.String("db", "", "database path or libsql:// URL; postgres:// is for pkg/memory only (default: CLAUDE_MEMORY_DB, else ~/.claude/memory.db)")
+	backupTo := flag.String("backup-to", "", "keep a copy of the database at this path, updated as it changes (or CLAUDE_MEMORY_BACKUP_REPLICA)")
+	backupInterval := flag.Duration("backup-interval", 0, "how often to check for changes to copy with --backup-to (default 15m; or CLAUDE_MEMORY_BACKUP_INTERVAL)")
+	tracePath := flag.String("trace", "", "append every request and its response to this file as JSON lines, for debugging")
+	pluginDir := flag.String("plugins", "", "register each executable in this directory as a tool (default ~/.claude/mark42/plugins)")
+	writeLease := flag.Duration("write-lease", defaultWriteLease, "hold the database's write lease this long after each tool call, so maintenance commands wait (0 never takes it)")
+	flag.Parse()
+
+	if err := configureLogger(); err != nil {
+		fmt.Fprintln(os.Stderr, "mark42:", err)
+		os.Exit(1)
+	}
+
+	// Flags not given fall back to MARK42_<FLAG>, as in the mark42 CLI
+	given := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) { given[f.Name] = true })
+	flag.VisitAll(func(f *flag.Flag) {
+		name := "MARK42_" + strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))
+		if value := os.Getenv(name); value != "" && !given[f.Name] {
+			if err := flag.Set(f.Name, value); err != nil {
+				logger.Error("invalid flag variable", "variable", name, "error", err)
+				os.Exit(1)
+			}
+		}
+	})
+
+	// Determine database path; MCP clients pass args unexpanded, so expand ~
+	dbPath := cmp.Or(*dbFlag, os.Getenv("CLAUDE_MEMORY_DB"))
+	home, _ := os.UserHomeDir()
 	if dbPath == "" {
-		home, _ := os.UserHomeDir()
 		dbPath = filepath.Join(home, ".claude", "memory.db")
+	} else if rest, ok := strings.CutPrefix(dbPath, "~/"); ok {
+		dbPath = filepath.Join(home, rest)
 	}
 
-	// Ensure directory exists
-	if err := os.MkdirAll(filepath.Dir(dbPath), 0o755); err != nil {
-		logError("failed to create database directory: %v", err)
-		os.Exit(1)
+	// Ensure directory exists for local databases
+	if storage.IsLocalPath(dbPath) {
+		if err := os.MkdirAll(filepath.Dir(dbPath), 0o755); err != nil {
+			logger.Error("failed to create database directory", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	// Take the lock before opening the database, so a server it stops has
+	// flushed and closed the database first
+	if *singleInstance {
+		if !storage.IsLocalPath(dbPath) {
+			logger.Warn("--single-instance needs a local database, ignoring it")
+		} else {
+			lock, err := lockInstance(dbPath+".lock", lockWait)
+			if err != nil {
+				logger.Error("failed to lock the database", "error", err)
+				os.Exit(1)
+			}
+			defer lock.Close()
+		}
 	}
 
 	// Open storage
 	store, err := storage.NewStore(dbPath)
 	if err != nil {
-		logError("failed to open database: %v", err)
+		logger.Error("failed to open database", "db", dbPath, "error", err)
+		os.Exit(1)
+	}
+
+	// Opt-in case-insensitive entity names
+	if on, _ := strconv.ParseBool(os.Getenv("CLAUDE_MEMORY_CASE_INSENSITIVE_NAMES")); on {
+		store.SetCaseInsensitiveNames(true)
+	}
+	store.SetSource(storage.SourceMCP) // Tool calls record mcp:<tool>
+	if model := os.Getenv("CLAUDE_MEMORY_TOKENIZER"); model != "" {
+		// Select the tokenizer for context budgets
+		tokenizer, err := storage.TokenizerForModel(model)
+		if err != nil {
+			logger.Warn("using the default tokenizer", "error", err)
+		}
+		store.SetTokenizer(tokenizer)
+	}
+	store.SetUser(os.Getenv("CLAUDE_MEMORY_USER"))
+	if on, _ := strconv.ParseBool(os.Getenv("CLAUDE_MEMORY_AUTO_LINK_MENTIONS")); on {
+		store.SetAutoLinkMentions(storage.DefaultAutoLinkMinNameLength)
+	}
+	store.SetEntityTypes(storage.ParseEntityTypes(os.Getenv("CLAUDE_MEMORY_ENTITY_TYPES")))
+	store.SetRelationTypes(storage.ParseRelationTypes(os.Getenv("CLAUDE_MEMORY_RELATION_TYPES")))
+	if path := os.Getenv("CLAUDE_MEMORY_RULES"); path != "" {
+		rules, err := storage.LoadRules(path)
+		if err != nil {
+			logger.Error("failed to load rules", "path", path, "error", err)
+			os.Exit(1)
+		}
+		rules.Warn = func(v storage.RuleViolation) {
+			logger.Warn("rule violated", "trace", v.TraceID, "kind", v.Kind, "subject", v.Subject, "reason", v.Reason)
+		}
+		store.SetRules(rules)
+	}
+	redaction := storage.Redaction{Warn: func(r storage.Redacted) {
+		logger.Warn("secret redacted from an observation", "trace", r.TraceID, "entity", r.Entity, "types", strings.Join(r.Types, ","))
+	}}
+	if on, err := strconv.ParseBool(os.Getenv("CLAUDE_MEMORY_REDACT_SECRETS")); err == nil {
+		redaction.Presets = &on
+	}
+	store.SetRedaction(redaction) // Presets only, which always compile
+	if err := store.SetPIIMode(storage.PIIMode(os.Getenv("CLAUDE_MEMORY_PII_MODE"))); err != nil {
+		logger.Error("invalid CLAUDE_MEMORY_PII_MODE", "error", err)
 		os.Exit(1)
 	}
-	defer store.Close()
 
 	// Create handler
 	handler := mcp.NewHandler(store)
 
+	// Plugin servers run in the project; resume_work reads its hook state,
+	// and its config applies
+	projectDir := os.Getenv("CLAUDE_PROJECT_DIR")
+	if projectDir == "" {
+		projectDir, _ = os.Getwd()
+	}
+	handler.WithProjectDir(projectDir)
+
+	// The project's config sets get_context's defaults, importance scoring,
+	// and the embedder
+	profile := loadProfile(home, projectDir)
+	handler.WithContextDefaults(profile.Context)
+	store.SetImportanceConfig(profile.Importance)
+
+	// Automation policies apply to observations as tools write them
+	policies := profile.Policies
+	if path := os.Getenv("CLAUDE_MEMORY_POLICIES"); path != "" {
+		if policies, err = storage.LoadPolicies(path); err != nil {
+			logger.Error("failed to load policies", "path", path, "error", err)
+			os.Exit(1)
+		}
+	}
+	if err := store.SetPolicies(policies); err != nil {
+		logger.Error("invalid policies", "error", err)
+		os.Exit(1)
+	}
+
 	// Optionally enable semantic search with embeddings
-	embedderURL := os.Getenv("CLAUDE_MEMORY_EMBEDDER_URL")
+	embedderURL := cmp.Or(os.Getenv("CLAUDE_MEMORY_EMBEDDER_URL"), profile.Embedder.URL)
 	if embedderURL == "" {
 		embedderURL = storage.DefaultOllamaBaseURL() // Try Ollama by default
 	}
 	if embedderURL != "disabled" {
 		embedder := storage.NewEmbeddingClient(embedderURL)
+		if profile.Embedder.Model != "" {
+			embedder.SetModel(profile.Embedder.Model)
+		}
 		handler.WithEmbedder(embedder)
 
+		// Probing records the model's dimensions and input limit on first use
 		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-		if _, err := embedder.CreateEmbedding(ctx, "test"); err != nil {
-			logError("embedder unavailable at %s — semantic search disabled", embedderURL)
+		if _, err := store.PrepareEmbedder(ctx, embedder); err != nil {
+			logger.Warn("embedder unavailable, semantic search disabled", "url", embedderURL, "error", err)
 		}
 		cancel()
 	}
 
-	// Run server
-	server := &Server{handler: handler}
-	if err := server.Run(); err != nil {
-		logError("server error: %v", err)
-		os.Exit(1)
+	// Optionally use an LLM for summarize_entity, remember, and ask_memory, on the embedder's endpoint
+	if model := os.Getenv("CLAUDE_MEMORY_SUMMARY_MODEL"); model != "" {
+		summaryURL := embedderURL
+		if summaryURL == "disabled" {
+			summaryURL = storage.DefaultOllamaBaseURL()
+		}
+		client := storage.NewSummaryClient(summaryURL, model)
+		handler.WithSummarizer(client).WithExtractor(client).WithAnswerer(client)
+	}
+
+	// Executable plugins add tools; they see the database path, so the
+	// mark42 CLI they run uses the same store
+	if *pluginDir == "" {
+		*pluginDir = filepath.Join(home, ".claude", "mark42", "plugins")
+	} else if rest, ok := strings.CutPrefix(*pluginDir, "~/"); ok {
+		*pluginDir = filepath.Join(home, rest)
+	}
+	plugins, err := handler.LoadPlugins(context.Background(), *pluginDir, []string{"CLAUDE_MEMORY_DB=" + dbPath})
+	if err != nil {
+		logger.Warn("plugins skipped", "dir", *pluginDir, "error", err)
+	}
+	if len(plugins) > 0 {
+		logger.Info("plugins loaded", "dir", *pluginDir, "tools", strings.Join(plugins, ","))
+	}
+
+	// Bound each tool call, so a locked database fails the call instead of
+	// stalling the server
+	requestTimeout := defaultRequestTimeout
+	if v := os.Getenv("CLAUDE_MEMORY_REQUEST_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			requestTimeout = d
+		} else {
+			logger.Warn("invalid CLAUDE_MEMORY_REQUEST_TIMEOUT", "value", v, "using", requestTimeout)
+		}
+	}
+
+	if *idleTimeout == 0 {
+		if v := os.Getenv("CLAUDE_MEMORY_IDLE_TIMEOUT"); v != "" {
+			if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+				*idleTimeout = d
+			} else {
+				logger.Warn("invalid CLAUDE_MEMORY_IDLE_TIMEOUT, no idle timeout", "value", v)
+			}
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	ctx, cancel := context.WithCancel(ctx)
+	if *watchParent {
+		go watchParentProcess(ctx, cancel, parentCheckInterval)
+	}
+
+	// Optionally keep a copy of the database elsewhere, e.g. on an external
+	// drive or in a synced folder
+	backupDone := make(chan struct{})
+	backupPath := cmp.Or(*backupTo, os.Getenv("CLAUDE_MEMORY_BACKUP_REPLICA"))
+	if rest, ok := strings.CutPrefix(backupPath, "~/"); ok {
+		backupPath = filepath.Join(home, rest)
+	}
+	if backupPath != "" && storage.IsRemoteDSN(dbPath) {
+		logger.Warn("--backup-to needs a local database, ignoring it")
+		backupPath = ""
+	}
+	if backupPath != "" {
+		if *backupInterval == 0 {
+			*backupInterval = storage.DefaultBackupInterval
+			if v := os.Getenv("CLAUDE_MEMORY_BACKUP_INTERVAL"); v != "" {
+				if d, err := time.ParseDuration(v); err == nil && d > 0 {
+					*backupInterval = d
+				} else {
+					logger.Warn("invalid CLAUDE_MEMORY_BACKUP_INTERVAL", "value", v, "using", *backupInterval)
+				}
+			}
+		}
+		go func() {
+			store.RunContinuousBackup(ctx, backupPath, *backupInterval, func(err error) {
+				logger.Error("backup failed", "path", backupPath, "error", err)
+			})
+			close(backupDone)
+		}()
+	} else {
+		close(backupDone)
+	}
+
+	// Run server until stdin closes, a signal arrives, the parent exits, or
+	// it sits idle
+	server := &Server{handler: handler, store: store, requestTimeout: requestTimeout, idleTimeout: *idleTimeout, writeLease: *writeLease}
+	if *tracePath != "" {
+		if rest, ok := strings.CutPrefix(*tracePath, "~/"); ok {
+			*tracePath = filepath.Join(home, rest)
+		}
+		// Requests hold memory contents, so only the user may read the file
+		f, err := os.OpenFile(*tracePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			logger.Error("failed to open trace file", "path", *tracePath, "error", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		server.trace = f
+	}
+	runErr := server.Run(ctx)
+	cancel()
+	stop() // A second signal during shutdown kills the process
+
+	// The last copy is made before the store closes
+	<-backupDone
+
+	shutdown(store)
+	if runErr != nil {
+		logger.Error("server error", "error", runErr)
+		os.Exit(1) // Deferred lock release is moot: the lock goes with the process
+	}
+}
+
+// shutdown releases the write lease, flushes the write-ahead log into the
+// database file, and closes the store, so the next process opening the
+// database has no log to recover.
+func shutdown(store *storage.Store) {
+	if err := store.ReleaseWriteLease(serverLeaseHolder); err != nil {
+		logger.Warn("failed to release the write lease", "error", err)
+	}
+	if err := store.Checkpoint(); err != nil {
+		logger.Error("failed to checkpoint database", "error", err)
+	}
+	if err := store.Close(); err != nil {
+		logger.Error("failed to close database", "error", err)
 	}
 }
 
+// defaultRequestTimeout bounds a tool call unless CLAUDE_MEMORY_REQUEST_TIMEOUT is set.
+const defaultRequestTimeout = 30 * time.Second
+
 // Server handles MCP JSON-RPC communication over stdio.
 type Server struct {
-	handler     *mcp.Handler
-	initialized bool
+	handler        *mcp.Handler
+	store          *storage.Store // Optional: counts tool calls for mark42 stats tools, holds the write lease
+	initialized    bool
+	writeLease     time.Duration // Held after each tool call; 0 never takes it
+	leaseUntil     time.Time     // When the lease the server holds runs out
+	requestTimeout time.Duration // Per tool call; 0 means no limit
+	idleTimeout    time.Duration // Exit after this long without a request; 0 means never
+	trace          io.Writer     // Optional: receives each request and response; see --trace
+	traceID        string        // Of the request being handled
+	response       []byte        // Sent for the request being handled, when tracing
 }
 
-// Run starts the server's main loop.
-func (s *Server) Run() error {
-	scanner := bufio.NewScanner(os.Stdin)
-
-	// Increase buffer size for large requests
-	const maxScannerSize = 10 * 1024 * 1024 // 10MB
-	buf := make([]byte, maxScannerSize)
-	scanner.Buffer(buf, maxScannerSize)
+// Run starts the server's main loop, which ends when stdin closes, ctx is
+// canceled, or no request arrives within the idle timeout. The request in
+// flight finishes first, within the request timeout, so its writes and
+// embeddings are not cut off halfway.
+func (s *Server) Run(ctx context.Context) error {
+	lines := make(chan []byte)
+	scanErr := make(chan error, 1)
+	go func() {
+		scanner := bufio.NewScanner(os.Stdin)
 
-	for scanner.Scan() {
-		line := scanner.Bytes()
-		if len(line) == 0 {
-			continue
-		}
+		// Increase buffer size for large requests
+		const maxScannerSize = 10 * 1024 * 1024 // 10MB
+		buf := make([]byte, maxScannerSize)
+		scanner.Buffer(buf, maxScannerSize)
 
-		var req mcp.Request
-		if err := json.Unmarshal(line, &req); err != nil {
-			s.sendError(nil, mcp.ErrCodeParse, "Parse error", err)
-			continue
+		for scanner.Scan() {
+			select {
+			case lines <- bytes.Clone(scanner.Bytes()):
+			case <-ctx.Done():
+				return
+			}
 		}
+		scanErr <- scanner.Err()
+		close(lines)
+	}()
 
-		s.handleRequest(&req)
+	var idle *time.Timer
+	var idleC <-chan time.Time // nil, never ready, without an idle timeout
+	if s.idleTimeout > 0 {
+		idle = time.NewTimer(s.idleTimeout)
+		defer idle.Stop()
+		idleC = idle.C
 	}
 
-	return scanner.Err()
+	// Requests outlive a shutdown signal; only the timeout cuts them short
+	reqCtx := context.WithoutCancel(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-idleC:
+			logger.Info("idle, exiting", "idleTimeout", s.idleTimeout)
+			return nil
+		case line, ok := <-lines:
+			if !ok {
+				return <-scanErr
+			}
+			if len(line) == 0 {
+				continue
+			}
+
+			start := time.Now()
+			s.traceID, s.response = newTraceID(), nil
+			var req mcp.Request
+			if err := json.Unmarshal(line, &req); err != nil {
+				logger.Warn("unparseable request", "trace", s.traceID, "error", err)
+				s.sendError(nil, mcp.ErrCodeParse, "Parse error", err)
+			} else {
+				s.handleRequest(storage.WithTraceID(reqCtx, s.traceID), &req)
+			}
+			if s.trace != nil {
+				writeTrace(s.trace, traceRecord{
+					Trace: s.traceID, Time: start, DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+					Request: line, Response: s.response,
+				})
+			}
+			if idle != nil {
+				idle.Reset(s.idleTimeout) // Idle time counts from the last answer
+			}
+		}
+	}
 }
 
-func (s *Server) handleRequest(req *mcp.Request) {
+func (s *Server) handleRequest(ctx context.Context, req *mcp.Request) {
+	start := time.Now()
+	var tool string
+	var err error
 	switch req.Method {
 	case "initialize":
 		s.handleInitialize(req)
 	case "notifications/initialized":
 		s.initialized = true
 		// No response for notifications
+	case "ping":
+		// Health check: answering at all is the point
+		s.sendResult(req.ID, struct{}{})
 	case "tools/list":
 		s.handleToolsList(req)
 	case "tools/call":
-		s.handleToolsCall(req)
+		s.touchWriteLease(ctx)
+		tool, err = s.handleToolsCall(ctx, req)
 	default:
+		err = errors.New("method not found")
 		s.sendError(req.ID, mcp.ErrCodeMethodNotFound, "Method not found", nil)
 	}
+	elapsed := time.Since(start)
+	logRequest(s.traceID, req.Method, tool, elapsed, err)
+	// Names no tool has are not counted, so clients cannot fill the table
+	if tool != "" && !errors.Is(err, mcp.ErrUnknownTool) && s.store != nil {
+		// The request's context may have timed out; the count still belongs
+		if err := s.store.RecordToolCallContext(context.WithoutCancel(ctx), tool, elapsed, err != nil); err != nil {
+			logger.Warn("failed to record tool stats", "trace", s.traceID, "tool", tool, "error", err)
+		}
+	}
 }
 
 func (s *Server) handleInitialize(req *mcp.Request) {
@@ -135,23 +459,32 @@ func (s *Server) handleToolsList(req *mcp.Request) {
 	s.sendResult(req.ID, result)
 }
 
-func (s *Server) handleToolsCall(req *mcp.Request) {
+// handleToolsCall answers a tool call, returning the tool's name and why
+// the call failed, for the request log.
+func (s *Server) handleToolsCall(ctx context.Context, req *mcp.Request) (string, error) {
 	var params mcp.ToolCallParams
 	if err := json.Unmarshal(req.Params, &params); err != nil {
 		s.sendError(req.ID, mcp.ErrCodeInvalidParams, "Invalid params", err)
-		return
+		return "", err
+	}
+
+	if s.requestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.requestTimeout)
+		defer cancel()
 	}
 
-	result, err := s.handler.CallTool(params.Name, params.Arguments)
+	result, err := s.handler.CallToolContext(ctx, params.Name, params.Arguments)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("%s timed out after %s: %w", params.Name, s.requestTimeout, context.DeadlineExceeded)
+	}
 	if err != nil {
-		s.sendResult(req.ID, &mcp.ToolCallResult{
-			Content: []mcp.ContentBlock{{Type: "text", Text: err.Error()}},
-			IsError: true,
-		})
-		return
+		s.sendResult(req.ID, mcp.ErrorResultContext(ctx, err))
+		return params.Name, err
 	}
 
 	s.sendResult(req.ID, result)
+	return params.Name, nil
 }
 
 func (s *Server) sendResult(id, result any) {
@@ -163,7 +496,18 @@ func (s *Server) sendResult(id, result any) {
 	s.send(resp)
 }
 
-func (s *Server) sendError(id any, code int, message string, data any) {
+// errorData is the data of a JSON-RPC error: the request's trace ID, to find
+// it in the server's log, and what went wrong.
+type errorData struct {
+	TraceID string `json:"traceId"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+func (s *Server) sendError(id any, code int, message string, err error) {
+	data := errorData{TraceID: s.traceID}
+	if err != nil {
+		data.Detail = err.Error()
+	}
 	resp := mcp.Response{
 		JSONRPC: "2.0",
 		ID:      id,
@@ -179,12 +523,11 @@ func (s *Server) sendError(id any, code int, message string, data any) {
 func (s *Server) send(resp mcp.Response) {
 	data, err := json.Marshal(resp)
 	if err != nil {
-		logError("failed to marshal response: %v", err)
+		logger.Error("failed to marshal response", "error", err)
 		return
 	}
+	if s.trace != nil {
+		s.response = data
+	}
 	fmt.Println(string(data))
 }
-
-func logError(format string, args ...any) {
-	fmt.Fprintf(os.Stderr, "[mark42] "+format+"\n", args...)
-}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+// profile is what the server reads of the config files mark42 layers:
+// ~/.claude/mark42/config.json, then the project's .claude/mark42/config.json
+// and .mark42/config.json.
+type profile struct {
+	Context    storage.ContextConfig
+	Importance storage.ImportanceConfig
+	Embedder   embedderProfile
+	Policies   []storage.Policy // Of every file, global first
+}
+
+// embedderProfile sets the embedding endpoint and model; empty fields are
+// left to the environment and defaults.
+type embedderProfile struct {
+	URL   string `json:"url"`
+	Model string `json:"model"`
+}
+
+// loadProfile layers the global and projectDir's config files over the
+// defaults; unreadable files are skipped, as the CLI does.
+func loadProfile(home, projectDir string) profile {
+	p := profile{Context: storage.DefaultContextConfig(), Importance: storage.DefaultImportanceConfig()}
+	dirs := []string{filepath.Join(home, ".claude", "mark42")}
+	if projectDir != "" && projectDir != home {
+		dirs = append(dirs, filepath.Join(projectDir, ".claude", "mark42"), filepath.Join(projectDir, ".mark42"))
+	}
+	for _, dir := range dirs {
+		data, err := os.ReadFile(filepath.Join(dir, "config.json"))
+		if err != nil {
+			continue
+		}
+		var layer struct {
+			Context    storage.ContextOverrides    `json:"context"`
+			Importance storage.ImportanceOverrides `json:"importance"`
+			Embedder   embedderProfile             `json:"embedder"`
+			Policies   []storage.Policy            `json:"policies"`
+		}
+		if err := json.Unmarshal(data, &layer); err != nil {
+			logger.Warn("config file skipped", "path", filepath.Join(dir, "config.json"), "error", err)
+			continue
+		}
+		layer.Context.Apply(&p.Context)
+		layer.Importance.Apply(&p.Importance)
+		p.Policies = append(p.Policies, layer.Policies...)
+		if layer.Embedder.URL != "" {
+			p.Embedder.URL = layer.Embedder.URL
+		}
+		if layer.Embedder.Model != "" {
+			p.Embedder.Model = layer.Embedder.Model
+		}
+	}
+	return p
+}
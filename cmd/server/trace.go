@@ -0,0 +1,44 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// newTraceID returns a random correlation ID for one request.
+func newTraceID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// traceRecord is one request and its response, as --trace writes them.
+type traceRecord struct {
+	Trace      string          `json:"trace"`
+	Time       time.Time       `json:"time"`
+	DurationMs float64         `json:"durationMs"`
+	Request    json.RawMessage `json:"request"`
+	Response   json.RawMessage `json:"response"` // null for notifications
+}
+
+// writeTrace appends r to w as one JSON line. A request that is not JSON is
+// recorded as a string.
+func writeTrace(w io.Writer, r traceRecord) {
+	if !json.Valid(r.Request) {
+		r.Request, _ = json.Marshal(string(r.Request))
+	}
+	if len(r.Response) == 0 {
+		r.Response = json.RawMessage("null")
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		logger.Error("failed to marshal trace", "trace", r.Trace, "error", err)
+		return
+	}
+	if _, err := w.Write(append(data, '\n')); err != nil {
+		logger.Error("failed to write trace", "trace", r.Trace, "error", err)
+	}
+}
@@ -3,8 +3,12 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -17,20 +21,57 @@ var logger = log.NewWithOptions(os.Stderr, log.Options{
 	ReportTimestamp: false,
 })
 
+// SetLogger replaces the log tool handlers write warnings to, so a server
+// can give them its format and level.
+func SetLogger(l *log.Logger) {
+	logger = l
+}
+
+// logFor returns the log for warnings about the request ctx serves, naming
+// its trace ID when it has one.
+func logFor(ctx context.Context) *log.Logger {
+	if id := storage.TraceID(ctx); id != "" {
+		return logger.With("trace", id)
+	}
+	return logger
+}
+
 // Embedder generates vector embeddings for text.
 type Embedder interface {
 	CreateEmbedding(ctx context.Context, text string) ([]float64, error)
 }
 
+// Summarizer condenses an entity's observations into a short abstract.
+type Summarizer interface {
+	SummarizeEntity(ctx context.Context, name, entityType string, observations []string) (string, error)
+}
+
+// Extractor finds entities, observations, and relations in free text.
+type Extractor interface {
+	ExtractFacts(ctx context.Context, text string) (*storage.Extraction, error)
+}
+
+// Answerer answers a question from numbered memory excerpts, citing them.
+type Answerer interface {
+	Answer(ctx context.Context, question string, evidence []storage.Evidence) (string, error)
+}
+
 // Handler processes MCP tool calls using the storage layer.
 type Handler struct {
-	store    *storage.Store
-	embedder Embedder // Optional: enables semantic search + auto-embed on write
+	store      *storage.Store
+	embedder   Embedder   // Optional: enables semantic search + auto-embed on write
+	summarizer Summarizer // Optional: enables LLM summaries in summarize_entity
+	extractor  Extractor  // Optional: enables LLM extraction in remember
+	answerer   Answerer   // Optional: enables LLM answers in ask_memory
+	projectDir string     // Optional: project whose hook state resume_work reads
+	// Settings get_context starts from, before its arguments
+	contextDefaults storage.ContextConfig
+	custom          []customTool // Added with RegisterTool or LoadPlugins
 }
 
 // NewHandler creates a new MCP handler with the given store.
 func NewHandler(store *storage.Store) *Handler {
-	return &Handler{store: store}
+	return &Handler{store: store, contextDefaults: storage.DefaultContextConfig()}
 }
 
 // WithEmbedder adds an embedding client for semantic search and auto-embedding.
@@ -39,9 +80,45 @@ func (h *Handler) WithEmbedder(client Embedder) *Handler {
 	return h
 }
 
-// Tools returns the list of available memory tools.
+// WithSummarizer adds an LLM client that summarize_entity uses to write a
+// cached 3-5 bullet abstract of the entity.
+func (h *Handler) WithSummarizer(client Summarizer) *Handler {
+	h.summarizer = client
+	return h
+}
+
+// WithAnswerer adds an LLM client that ask_memory uses to answer from the
+// evidence it gathers.
+func (h *Handler) WithAnswerer(client Answerer) *Handler {
+	h.answerer = client
+	return h
+}
+
+// WithExtractor adds an LLM client that remember uses in place of the
+// heuristic extraction.
+func (h *Handler) WithExtractor(client Extractor) *Handler {
+	h.extractor = client
+	return h
+}
+
+// WithProjectDir sets the project directory, so resume_work can include files
+// the hooks tracked but have not captured yet.
+func (h *Handler) WithProjectDir(dir string) *Handler {
+	h.projectDir = dir
+	return h
+}
+
+// WithContextDefaults sets the settings get_context uses where its arguments
+// give none, such as the project's configured budget and excluded types.
+func (h *Handler) WithContextDefaults(cfg storage.ContextConfig) *Handler {
+	h.contextDefaults = cfg
+	return h
+}
+
+// Tools returns the list of available memory tools, then those added with
+// RegisterTool.
 func (h *Handler) Tools() []Tool {
-	return []Tool{
+	tools := []Tool{
 		{
 			Name:        "create_entities",
 			Description: "Create multiple new entities in the knowledge graph",
@@ -90,7 +167,7 @@ func (h *Handler) Tools() []Tool {
 		},
 		{
 			Name:        "create_relations",
-			Description: "Create multiple new relations between entities in the knowledge graph",
+			Description: "Create multiple new relations between entities in the knowledge graph. Relations whose endpoints don't exist fail unless autoCreateMissing is set",
 			InputSchema: InputSchema{
 				Type: "object",
 				Properties: map[string]Property{
@@ -107,6 +184,8 @@ func (h *Handler) Tools() []Tool {
 							Required: []string{"from", "to", "relationType"},
 						},
 					},
+					"resolveAliases":    {Type: "boolean", Description: "Find endpoints by alias or ignoring case when no entity has the exact name (default: false)"},
+					"autoCreateMissing": {Type: "boolean", Description: "Create endpoints that still aren't found as entities of type 'unknown' instead of failing (default: false)"},
 				},
 				Required: []string{"relations"},
 			},
@@ -145,6 +224,18 @@ func (h *Handler) Tools() []Tool {
 				Required: []string{"entityNames"},
 			},
 		},
+		{
+			Name:        "forget_entity",
+			Description: "Permanently erase every trace of an entity: all versions, observations, embeddings, relations, attributes, aliases, archived observations, and merge records. Without confirm it only reports what would be erased; call again with confirm set to the entity's name to erase it",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]Property{
+					"entityName": {Type: "string", Description: "Entity to erase"},
+					"confirm":    {Type: "string", Description: "The entity's name again, to confirm the erasure"},
+				},
+				Required: []string{"entityName"},
+			},
+		},
 		{
 			Name:        "delete_observations",
 			Description: "Delete specific observations from entities in the knowledge graph",
@@ -167,6 +258,29 @@ func (h *Handler) Tools() []Tool {
 				Required: []string{"deletions"},
 			},
 		},
+		{
+			Name:        "update_observations",
+			Description: "Edit observations in place, e.g. to fix a typo, keeping their creation time, fact type, importance and pins",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]Property{
+					"updates": {
+						Type:        "array",
+						Description: "Array of edits",
+						Items: &Items{
+							Type: "object",
+							Properties: map[string]Property{
+								"entityName": {Type: "string", Description: "Entity name"},
+								"oldContent": {Type: "string", Description: "Current observation text"},
+								"newContent": {Type: "string", Description: "Replacement text"},
+							},
+							Required: []string{"entityName", "oldContent", "newContent"},
+						},
+					},
+				},
+				Required: []string{"updates"},
+			},
+		},
 		{
 			Name:        "delete_relations",
 			Description: "Delete multiple relations from the knowledge graph",
@@ -192,10 +306,16 @@ func (h *Handler) Tools() []Tool {
 		},
 		{
 			Name:        "read_graph",
-			Description: "Read the entire knowledge graph",
+			Description: "Read the knowledge graph one page of entities at a time. Pages include the relations starting at their entities, plus Total and NextOffset (0 on the last page)",
 			InputSchema: InputSchema{
-				Type:       "object",
-				Properties: map[string]Property{},
+				Type: "object",
+				Properties: map[string]Property{
+					"offset":              {Type: "integer", Description: "Entities to skip, in name order (default: 0)"},
+					"limit":               {Type: "integer", Description: "Maximum entities to return (default: 1000)"},
+					"includeObservations": {Type: "boolean", Description: "Set to false to return entities without observations (default: true)"},
+					"includeEnded":        {Type: "boolean", Description: "Include relations that were ended, with their ValidTo (default: only relations valid now)"},
+					"asOf":                {Type: "string", Description: "Reconstruct the graph as it was at this date (YYYY-MM-DD) or RFC 3339 time: entity versions, observations and relations current then"},
+				},
 			},
 		},
 		{
@@ -204,7 +324,11 @@ func (h *Handler) Tools() []Tool {
 			InputSchema: InputSchema{
 				Type: "object",
 				Properties: map[string]Property{
-					"query": {Type: "string", Description: "Search query"},
+					"query":             {Type: "string", Description: "Search query; may be empty when filtering by attributes or user"},
+					"attributes":        {Type: "object", Description: "Only return entities having all of these attributes, e.g. {\"language\": \"Go\"} (values match ignoring case)"},
+					"user":              {Type: "string", Description: "Only return entities this user created or wrote observations on, in a shared database"},
+					"explain":           {Type: "boolean", Description: "Include how each result was scored: BM25 rank, vector similarity, RRF contribution, container tag boost, and final score"},
+					"includeSuppressed": {Type: "boolean", Description: "Also match and return observations hidden with suppress_memory (default: false)"},
 				},
 				Required: []string{"query"},
 			},
@@ -216,6 +340,7 @@ func (h *Handler) Tools() []Tool {
 				Type: "object",
 				Properties: map[string]Property{
 					"names": {Type: "array", Description: "Entity names to retrieve", Items: &Items{Type: "string"}},
+					"asOf":  {Type: "string", Description: "Return the entities as they were at this date (YYYY-MM-DD) or RFC 3339 time"},
 				},
 				Required: []string{"names"},
 			},
@@ -229,9 +354,60 @@ func (h *Handler) Tools() []Tool {
 					"projectName":   {Type: "string", Description: "Current project name for boosting relevant memories"},
 					"tokenBudget":   {Type: "integer", Description: "Maximum tokens to include (default: 2000)"},
 					"minImportance": {Type: "number", Description: "Minimum importance score (0-1, default: 0.3)"},
+					"template":      {Type: "string", Description: "Output template: 'default', 'compact', 'xml-tags', or 'markdown'"},
+					"budgetShares":  {Type: "object", Description: "Share of the token budget reserved per fact type (default: {\"static\": 0.5, \"dynamic\": 0.35, \"session_turn\": 0.15})"},
+					"user":          {Type: "string", Description: "Only include observations this user wrote, in a shared database"},
 				},
 			},
 		},
+		{
+			Name:        "pin_memory",
+			Description: "Pin an observation so it always appears first in injected context and never decays",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]Property{
+					"entityName": {Type: "string", Description: "Entity name"},
+					"content":    {Type: "string", Description: "Observation content to pin"},
+					"pinned":     {Type: "boolean", Description: "Set to false to unpin (default: true)"},
+				},
+				Required: []string{"entityName", "content"},
+			},
+		},
+		{
+			Name:        "suppress_memory",
+			Description: "Hide a stale or wrong observation from search and context without deleting its history",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]Property{
+					"entityName": {Type: "string", Description: "Entity name"},
+					"content":    {Type: "string", Description: "Observation content to suppress"},
+					"suppressed": {Type: "boolean", Description: "Set to false to restore (default: true)"},
+				},
+				Required: []string{"entityName", "content"},
+			},
+		},
+		{
+			Name:        "mark_memory_used",
+			Description: "Report that injected memories were actually helpful, so they gain importance and stop decaying",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]Property{
+					"memories": {
+						Type:        "array",
+						Description: "Memories that were useful",
+						Items: &Items{
+							Type: "object",
+							Properties: map[string]Property{
+								"entityName":   {Type: "string", Description: "Entity name"},
+								"observations": {Type: "array", Description: "Observations that were used (omit for all)", Items: &Items{Type: "string"}},
+							},
+							Required: []string{"entityName"},
+						},
+					},
+				},
+				Required: []string{"memories"},
+			},
+		},
 		{
 			Name:        "get_recent_context",
 			Description: "Get recently accessed memories, prioritizing recency over importance. For mid-session use.",
@@ -246,11 +422,12 @@ func (h *Handler) Tools() []Tool {
 		},
 		{
 			Name:        "summarize_entity",
-			Description: "Get a consolidated summary of an entity with observations grouped by fact type and metadata",
+			Description: "Get a consolidated summary of an entity with observations grouped by fact type and metadata. When an LLM is configured, leads with a cached bullet abstract that is regenerated once the observations change",
 			InputSchema: InputSchema{
 				Type: "object",
 				Properties: map[string]Property{
 					"entityName": {Type: "string", Description: "Name of the entity to summarize"},
+					"refresh":    {Type: "boolean", Description: "Regenerate the LLM abstract even if it is current (default: false)"},
 				},
 				Required: []string{"entityName"},
 			},
@@ -294,85 +471,187 @@ func (h *Handler) Tools() []Tool {
 		},
 		{
 			Name:        "recall_sessions",
-			Description: "Recall recent session summaries for a project to understand what was done in previous sessions",
+			Description: "Recall recent session summaries for a project to understand what was done in previous sessions, or search all past sessions with a query",
 			InputSchema: InputSchema{
 				Type: "object",
 				Properties: map[string]Property{
 					"projectName": {Type: "string", Description: "Project name to filter sessions"},
-					"hours":       {Type: "integer", Description: "Time window in hours (default: 72)"},
+					"hours":       {Type: "integer", Description: "Time window in hours (default: 72); ignored when query is set"},
 					"tokenBudget": {Type: "integer", Description: "Maximum tokens to include (default: 1500)"},
+					"query":       {Type: "string", Description: "Search session summaries, events, and prompts instead of recalling by recency"},
+				},
+			},
+		},
+		{
+			Name:        "resume_work",
+			Description: "Pick up where the last session stopped: the unfinished session, files in progress, and important open facts for the project",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]Property{
+					"projectName": {Type: "string", Description: "Project name (default: the current project directory name)"},
+				},
+			},
+		},
+		{
+			Name:        "remember",
+			Description: "Store facts from free text: extracts entities, observations, and relations (with a local LLM when configured, otherwise heuristically), writes them, and returns what was extracted",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]Property{
+					"text":   {Type: "string", Description: "Free text to remember, e.g. \"The parser uses the Lexer. Builds run on Go 1.25.\""},
+					"mode":   {Type: "string", Description: "auto (LLM if configured, else heuristic), heuristic, or llm (default: auto)"},
+					"dryRun": {Type: "boolean", Description: "Return the extraction without writing it (default: false)"},
+				},
+				Required: []string{"text"},
+			},
+		},
+		{
+			Name:        "set_attributes",
+			Description: "Set typed attributes (repo_url, version, language, ...) on an entity. Values are validated against the schema for the entity's type; an empty value removes the attribute",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]Property{
+					"entityName": {Type: "string", Description: "Entity to set attributes on"},
+					"attributes": {Type: "object", Description: "Attributes as key-value strings, e.g. {\"repo_url\": \"https://github.com/org/repo\", \"version\": \"1.4.0\"}"},
 				},
+				Required: []string{"entityName", "attributes"},
+			},
+		},
+		{
+			Name:        "ask_memory",
+			Description: "Answer a question from memory: runs hybrid search, gathers the best observations within a token budget, and answers with [n] citations when a local LLM is configured; otherwise returns the ranked evidence",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]Property{
+					"question":    {Type: "string", Description: "The question, e.g. \"Why did we switch the parser to Pratt parsing?\""},
+					"tokenBudget": {Type: "number", Description: "Maximum tokens of evidence to gather (default: 1500)"},
+				},
+				Required: []string{"question"},
 			},
 		},
 	}
+	for _, c := range h.custom {
+		tools = append(tools, c.tool)
+	}
+	return tools
 }
 
 // CallTool executes the named tool with the given arguments.
 func (h *Handler) CallTool(name string, args json.RawMessage) (*ToolCallResult, error) {
+	return h.CallToolContext(context.Background(), name, args)
+}
+
+// CallToolContext is CallTool with a context; storage calls stop when ctx
+// is canceled or its deadline passes. Errors from the storage layer keep
+// their sentinels, so ErrorCode can classify them. An unexplained failure on
+// a database with pending migrations is also reported as ErrSchemaOutdated.
+func (h *Handler) CallToolContext(ctx context.Context, name string, args json.RawMessage) (*ToolCallResult, error) {
+	// Observations written by the tool record it as their source
+	ctx = storage.WithSource(ctx, storage.SourceMCP+":"+name)
+	result, err := h.callTool(ctx, name, args)
+	if err != nil && ctx.Err() == nil && ErrorCode(err) == ToolErrInternal {
+		if schemaErr := h.store.CheckSchemaContext(ctx); errors.Is(schemaErr, storage.ErrSchemaOutdated) {
+			err = fmt.Errorf("%w; %w", err, schemaErr)
+		}
+	}
+	return result, err
+}
+
+func (h *Handler) callTool(ctx context.Context, name string, args json.RawMessage) (*ToolCallResult, error) {
 	switch name {
 	case "create_entities":
-		return h.createEntities(args)
+		return h.createEntities(ctx, args)
 	case "create_or_update_entities":
-		return h.createOrUpdateEntities(args)
+		return h.createOrUpdateEntities(ctx, args)
 	case "create_relations":
-		return h.createRelations(args)
+		return h.createRelations(ctx, args)
 	case "add_observations":
-		return h.addObservations(args)
+		return h.addObservations(ctx, args)
 	case "delete_entities":
-		return h.deleteEntities(args)
+		return h.deleteEntities(ctx, args)
+	case "forget_entity":
+		return h.forgetEntity(ctx, args)
 	case "delete_observations":
-		return h.deleteObservations(args)
+		return h.deleteObservations(ctx, args)
+	case "update_observations":
+		return h.updateObservations(ctx, args)
 	case "delete_relations":
-		return h.deleteRelations(args)
+		return h.deleteRelations(ctx, args)
 	case "read_graph":
-		return h.readGraph()
+		return h.readGraph(ctx, args)
 	case "search_nodes":
-		return h.searchNodes(args)
+		return h.searchNodes(ctx, args)
 	case "open_nodes":
-		return h.openNodes(args)
+		return h.openNodes(ctx, args)
 	case "get_context":
-		return h.getContext(args)
+		return h.getContext(ctx, args)
+	case "pin_memory":
+		return h.pinMemory(ctx, args)
+	case "suppress_memory":
+		return h.suppressMemory(ctx, args)
+	case "mark_memory_used":
+		return h.markMemoryUsed(ctx, args)
 	case "get_recent_context":
-		return h.getRecentContext(args)
+		return h.getRecentContext(ctx, args)
 	case "summarize_entity":
-		return h.summarizeEntity(args)
+		return h.summarizeEntity(ctx, args)
 	case "consolidate_memories":
-		return h.consolidateMemories(args)
+		return h.consolidateMemories(ctx, args)
 	case "capture_session":
-		return h.captureSession(args)
+		return h.captureSession(ctx, args)
 	case "recall_sessions":
-		return h.recallSessions(args)
+		return h.recallSessions(ctx, args)
+	case "resume_work":
+		return h.resumeWork(ctx, args)
+	case "remember":
+		return h.remember(ctx, args)
+	case "set_attributes":
+		return h.setAttributes(ctx, args)
+	case "ask_memory":
+		return h.askMemory(ctx, args)
 	default:
-		return nil, fmt.Errorf("unknown tool: %s", name)
+		if c := h.customToolNamed(name); c != nil {
+			return c.run(ctx, h.store, args)
+		}
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTool, name)
 	}
 }
 
-func (h *Handler) createEntities(args json.RawMessage) (*ToolCallResult, error) {
+func (h *Handler) createEntities(ctx context.Context, args json.RawMessage) (*ToolCallResult, error) {
 	var input CreateEntitiesInput
 	if err := json.Unmarshal(args, &input); err != nil {
 		return nil, fmt.Errorf("invalid arguments: %w", err)
 	}
 
+	specs := make([]storage.EntitySpec, len(input.Entities))
+	for i, e := range input.Entities {
+		specs[i] = storage.EntitySpec{Name: e.Name, Type: e.EntityType, Observations: e.Observations}
+	}
+	results, err := h.store.CreateEntitiesContext(ctx, specs)
+	if err != nil {
+		return nil, err
+	}
+
 	var created []string
-	for _, e := range input.Entities {
-		entity, err := h.store.CreateEntity(e.Name, e.EntityType, e.Observations)
-		if err != nil {
-			// Entity may already exist, try adding observations
-			for _, obs := range e.Observations {
-				_ = h.store.AddObservation(e.Name, obs)
-			}
+	items := make([]ItemResult, len(results))
+	for i, r := range results {
+		items[i] = ItemResult{Item: r.Name, Status: StatusCreated}
+		if r.Err != nil {
+			items[i] = itemError(r.Name, r.Err)
+			continue
+		}
+		if r.Created {
+			created = append(created, r.Name)
 		} else {
-			created = append(created, entity.Name)
+			items[i].Status = StatusExists
 		}
-		h.embedObservations(e.Name, e.Observations)
+		h.embedObservations(ctx, r.Name, input.Entities[i].Observations)
 	}
 
-	return &ToolCallResult{
-		Content: []ContentBlock{{Type: "text", Text: fmt.Sprintf("Created entities: %v", created)}},
-	}, nil
+	return batchResult(fmt.Sprintf("Created entities: %v", created), items)
 }
 
-func (h *Handler) createOrUpdateEntities(args json.RawMessage) (*ToolCallResult, error) {
+func (h *Handler) createOrUpdateEntities(ctx context.Context, args json.RawMessage) (*ToolCallResult, error) {
 	var input CreateEntitiesInput
 	if err := json.Unmarshal(args, &input); err != nil {
 		return nil, fmt.Errorf("invalid arguments: %w", err)
@@ -380,12 +659,12 @@ func (h *Handler) createOrUpdateEntities(args json.RawMessage) (*ToolCallResult,
 
 	var results []string
 	for _, e := range input.Entities {
-		entity, err := h.store.CreateOrUpdateEntity(e.Name, e.EntityType, e.Observations)
+		entity, err := h.store.CreateOrUpdateEntityContext(ctx, e.Name, e.EntityType, e.Observations)
 		if err != nil {
 			results = append(results, fmt.Sprintf("Error: %s - %v", e.Name, err))
 		} else {
 			results = append(results, fmt.Sprintf("%s (v%d)", entity.Name, entity.Version))
-			h.embedObservations(e.Name, e.Observations)
+			h.embedObservations(ctx, e.Name, e.Observations)
 		}
 	}
 
@@ -394,117 +673,298 @@ func (h *Handler) createOrUpdateEntities(args json.RawMessage) (*ToolCallResult,
 	}, nil
 }
 
-func (h *Handler) createRelations(args json.RawMessage) (*ToolCallResult, error) {
+func (h *Handler) createRelations(ctx context.Context, args json.RawMessage) (*ToolCallResult, error) {
 	var input CreateRelationsInput
 	if err := json.Unmarshal(args, &input); err != nil {
 		return nil, fmt.Errorf("invalid arguments: %w", err)
 	}
 
 	var created int
-	for _, r := range input.Relations {
-		if err := h.store.CreateRelation(r.From, r.To, r.RelationType); err == nil {
-			created++
+	items := make([]ItemResult, len(input.Relations))
+	for i, r := range input.Relations {
+		items[i] = ItemResult{Item: relationItem(r), Status: StatusCreated}
+		resolved, err := h.store.CreateRelationResolvedContext(ctx, r.From, r.To, r.RelationType, storage.ResolveOptions{
+			Aliases:       input.ResolveAliases,
+			CreateMissing: input.AutoCreateMissing,
+		})
+		if err != nil {
+			items[i] = itemError(items[i].Item, err)
+			continue
 		}
+		if resolved.From != storage.NormalizeName(r.From) || resolved.To != storage.NormalizeName(r.To) {
+			items[i].Resolved = relationItem(RelationInput{From: resolved.From, To: resolved.To, RelationType: r.RelationType})
+		}
+		items[i].Stubs = resolved.Created
+		created++
 	}
 
-	return &ToolCallResult{
-		Content: []ContentBlock{{Type: "text", Text: fmt.Sprintf("Created %d relations", created)}},
-	}, nil
+	return batchResult(fmt.Sprintf("Created %d relations", created), items)
 }
 
-func (h *Handler) addObservations(args json.RawMessage) (*ToolCallResult, error) {
+func (h *Handler) addObservations(ctx context.Context, args json.RawMessage) (*ToolCallResult, error) {
 	var input AddObservationsInput
 	if err := json.Unmarshal(args, &input); err != nil {
 		return nil, fmt.Errorf("invalid arguments: %w", err)
 	}
 
-	var added int
+	// Fact type defaults to dynamic for API compatibility
+	var specs []storage.ObservationSpec
 	for _, obs := range input.Observations {
-		// Determine fact type (default to dynamic for API compatibility)
-		factType := storage.FactTypeDynamic
-		if obs.FactType != "" {
-			factType = storage.FactType(obs.FactType)
+		for _, content := range obs.Contents {
+			specs = append(specs, storage.ObservationSpec{
+				EntityName: obs.EntityName,
+				Content:    content,
+				FactType:   storage.FactType(obs.FactType),
+			})
 		}
+	}
+	results, err := h.store.AddObservationsBatchContext(ctx, specs)
+	if err != nil {
+		return nil, err
+	}
 
-		var addedContents []string
-		for _, content := range obs.Contents {
-			var err error
-			if factType != storage.FactTypeDynamic {
-				err = h.store.AddObservationWithType(obs.EntityName, content, factType)
-			} else {
-				err = h.store.AddObservation(obs.EntityName, content)
-			}
-			if err == nil {
-				added++
-				addedContents = append(addedContents, content)
-			}
+	var added int
+	addedContents := map[string][]string{}
+	var entities []string
+	items := make([]ItemResult, len(results))
+	for i, r := range results {
+		items[i] = ItemResult{Item: r.EntityName + ": " + r.Content, Status: StatusAdded}
+		switch {
+		case r.Err != nil:
+			items[i] = itemError(items[i].Item, r.Err)
+			continue
+		case !r.Added:
+			items[i].Status = StatusDuplicate
+			continue
+		}
+		added++
+		if _, ok := addedContents[r.EntityName]; !ok {
+			entities = append(entities, r.EntityName)
 		}
-		h.embedObservations(obs.EntityName, addedContents)
+		addedContents[r.EntityName] = append(addedContents[r.EntityName], r.Content)
+	}
+	for _, name := range entities {
+		h.embedObservations(ctx, name, addedContents[name])
 	}
 
-	return &ToolCallResult{
-		Content: []ContentBlock{{Type: "text", Text: fmt.Sprintf("Added %d observations", added)}},
-	}, nil
+	return batchResult(fmt.Sprintf("Added %d observations", added), items)
 }
 
-func (h *Handler) deleteEntities(args json.RawMessage) (*ToolCallResult, error) {
+func (h *Handler) deleteEntities(ctx context.Context, args json.RawMessage) (*ToolCallResult, error) {
 	var input DeleteEntitiesInput
 	if err := json.Unmarshal(args, &input); err != nil {
 		return nil, fmt.Errorf("invalid arguments: %w", err)
 	}
 
 	var deleted int
-	for _, name := range input.EntityNames {
-		if err := h.store.DeleteEntity(name); err == nil {
-			deleted++
+	items := make([]ItemResult, len(input.EntityNames))
+	for i, name := range input.EntityNames {
+		items[i] = ItemResult{Item: name, Status: StatusDeleted}
+		if err := h.store.DeleteEntityContext(ctx, name); err != nil {
+			items[i] = itemError(name, err)
+			continue
 		}
+		deleted++
 	}
 
+	return batchResult(fmt.Sprintf("Deleted %d entities", deleted), items)
+}
+
+// forgetEntity previews the purge of an entity, and performs it once confirm
+// repeats the entity's name, so a single mistaken call erases nothing.
+func (h *Handler) forgetEntity(ctx context.Context, args json.RawMessage) (*ToolCallResult, error) {
+	var input ForgetEntityInput
+	if err := json.Unmarshal(args, &input); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	if strings.TrimSpace(input.EntityName) == "" {
+		return nil, &storage.ValidationError{Field: "entityName", Reason: "is required"}
+	}
+
+	confirmed := input.Confirm != "" && storage.NormalizeName(input.Confirm) == storage.NormalizeName(input.EntityName)
+	report, err := h.store.PurgeEntityContext(ctx, input.EntityName, storage.PurgeOptions{DryRun: !confirmed})
+	if err != nil {
+		return nil, err
+	}
+
+	text := fmt.Sprintf("Erased %s: %d versions, %d observations, %d relations", report.Entity, report.Versions, report.Observations, report.Relations)
+	if !confirmed {
+		text = fmt.Sprintf("Would erase %s: %d versions, %d observations, %d relations. Nothing was erased; call forget_entity again with confirm %q to erase it permanently",
+			report.Entity, report.Versions, report.Observations, report.Relations, report.Entity)
+	}
+	data, err := json.Marshal(report)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal report: %w", err)
+	}
 	return &ToolCallResult{
-		Content: []ContentBlock{{Type: "text", Text: fmt.Sprintf("Deleted %d entities", deleted)}},
+		Content: []ContentBlock{{Type: "text", Text: text}, {Type: "text", Text: string(data)}},
 	}, nil
 }
 
-func (h *Handler) deleteObservations(args json.RawMessage) (*ToolCallResult, error) {
+func (h *Handler) deleteObservations(ctx context.Context, args json.RawMessage) (*ToolCallResult, error) {
 	var input DeleteObservationsInput
 	if err := json.Unmarshal(args, &input); err != nil {
 		return nil, fmt.Errorf("invalid arguments: %w", err)
 	}
 
 	var deleted int
+	var items []ItemResult
 	for _, d := range input.Deletions {
 		for _, obs := range d.Observations {
-			if err := h.store.DeleteObservation(d.EntityName, obs); err == nil {
+			item := ItemResult{Item: d.EntityName + ": " + obs, Status: StatusDeleted}
+			if err := h.store.DeleteObservationContext(ctx, d.EntityName, obs); err != nil {
+				item = itemError(item.Item, err)
+			} else {
 				deleted++
 			}
+			items = append(items, item)
 		}
 	}
 
-	return &ToolCallResult{
-		Content: []ContentBlock{{Type: "text", Text: fmt.Sprintf("Deleted %d observations", deleted)}},
-	}, nil
+	return batchResult(fmt.Sprintf("Deleted %d observations", deleted), items)
+}
+
+func (h *Handler) updateObservations(ctx context.Context, args json.RawMessage) (*ToolCallResult, error) {
+	var input UpdateObservationsInput
+	if err := json.Unmarshal(args, &input); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	var updated int
+	items := make([]ItemResult, len(input.Updates))
+	for i, u := range input.Updates {
+		items[i] = ItemResult{Item: u.EntityName + ": " + u.NewContent, Status: StatusUpdated}
+		if err := h.store.UpdateObservationContext(ctx, u.EntityName, u.OldContent, u.NewContent); err != nil {
+			items[i] = itemError(u.EntityName+": "+u.OldContent, err)
+			continue
+		}
+		updated++
+		h.embedObservations(ctx, u.EntityName, []string{u.NewContent})
+	}
+
+	return batchResult(fmt.Sprintf("Updated %d observations", updated), items)
 }
 
-func (h *Handler) deleteRelations(args json.RawMessage) (*ToolCallResult, error) {
+func (h *Handler) deleteRelations(ctx context.Context, args json.RawMessage) (*ToolCallResult, error) {
 	var input DeleteRelationsInput
 	if err := json.Unmarshal(args, &input); err != nil {
 		return nil, fmt.Errorf("invalid arguments: %w", err)
 	}
 
 	var deleted int
-	for _, r := range input.Relations {
-		if err := h.store.DeleteRelation(r.From, r.To, r.RelationType); err == nil {
-			deleted++
+	items := make([]ItemResult, len(input.Relations))
+	for i, r := range input.Relations {
+		items[i] = ItemResult{Item: relationItem(r), Status: StatusDeleted}
+		if err := h.store.DeleteRelationContext(ctx, r.From, r.To, r.RelationType); err != nil {
+			items[i] = itemError(items[i].Item, err)
+			continue
 		}
+		deleted++
+	}
+
+	return batchResult(fmt.Sprintf("Deleted %d relations", deleted), items)
+}
+
+// batchResult reports a batch write: the summary line, then the outcome of
+// each item as JSON so failed items are visible. It is an error result only
+// when every item failed.
+func batchResult(summary string, items []ItemResult) (*ToolCallResult, error) {
+	failed := 0
+	for _, item := range items {
+		if item.Status == StatusError {
+			failed++
+		}
+	}
+	if failed > 0 {
+		summary += fmt.Sprintf(" (%d failed)", failed)
+	}
+
+	data, err := json.Marshal(struct {
+		Results []ItemResult `json:"results"`
+	}{items})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal results: %w", err)
 	}
 
 	return &ToolCallResult{
-		Content: []ContentBlock{{Type: "text", Text: fmt.Sprintf("Deleted %d relations", deleted)}},
+		Content: []ContentBlock{{Type: "text", Text: summary}, {Type: "text", Text: string(data)}},
+		IsError: failed > 0 && failed == len(items),
 	}, nil
 }
 
-func (h *Handler) readGraph() (*ToolCallResult, error) {
-	graph, err := h.store.ReadGraph()
+func relationItem(r RelationInput) string {
+	return fmt.Sprintf("%s -[%s]-> %s", r.From, r.RelationType, r.To)
+}
+
+// itemError marks a batch item as failed with err.
+func itemError(item string, err error) ItemResult {
+	return ItemResult{Item: item, Status: StatusError, Error: err.Error(), Code: ErrorCode(err)}
+}
+
+// ErrUnknownTool is returned by CallTool for a name Tools does not list.
+var ErrUnknownTool = errors.New("unknown tool")
+
+// ErrorCode classifies an error returned by CallTool as one of the ToolErr codes.
+func ErrorCode(err error) string {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, storage.ErrSchemaOutdated):
+		return ToolErrSchemaOutdated
+	case errors.Is(err, storage.ErrNotFound):
+		return ToolErrNotFound
+	case errors.Is(err, storage.ErrEntityExists):
+		return ToolErrAlreadyExists
+	case errors.Is(err, storage.ErrInvalidInput), errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+		return ToolErrInvalidInput
+	case errors.Is(err, ErrUnknownTool):
+		return ToolErrUnknownTool
+	case errors.Is(err, context.DeadlineExceeded):
+		return ToolErrTimeout
+	case errors.Is(err, context.Canceled):
+		return ToolErrCanceled
+	default:
+		return ToolErrInternal
+	}
+}
+
+// ErrorResult reports a failed tool call: the message, then a ToolError
+// block clients can branch on.
+func ErrorResult(err error) *ToolCallResult {
+	return ErrorResultContext(context.Background(), err)
+}
+
+// ErrorResultContext is ErrorResult for the request ctx serves; the
+// ToolError names its trace ID, to find the call in the server's log.
+func ErrorResultContext(ctx context.Context, err error) *ToolCallResult {
+	data, _ := json.Marshal(struct {
+		Error ToolError `json:"error"`
+	}{ToolError{Code: ErrorCode(err), Message: err.Error(), TraceID: storage.TraceID(ctx)}})
+	return &ToolCallResult{
+		Content: []ContentBlock{{Type: "text", Text: err.Error()}, {Type: "text", Text: string(data)}},
+		IsError: true,
+	}
+}
+
+func (h *Handler) readGraph(ctx context.Context, args json.RawMessage) (*ToolCallResult, error) {
+	var input ReadGraphInput
+	if len(args) > 0 {
+		if err := json.Unmarshal(args, &input); err != nil {
+			return nil, fmt.Errorf("invalid arguments: %w", err)
+		}
+	}
+
+	asOf, err := parseAsOf(input.AsOf)
+	if err != nil {
+		return nil, err
+	}
+	graph, err := h.store.ReadGraphPageContext(ctx, storage.GraphPageOptions{
+		Offset:              input.Offset,
+		Limit:               input.Limit,
+		ExcludeObservations: input.IncludeObservations != nil && !*input.IncludeObservations,
+		Relations:           storage.RelationFilter{IncludeEnded: input.IncludeEnded},
+		AsOf:                asOf,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to read graph: %w", err)
 	}
@@ -519,39 +979,74 @@ func (h *Handler) readGraph() (*ToolCallResult, error) {
 	}, nil
 }
 
-func (h *Handler) searchNodes(args json.RawMessage) (*ToolCallResult, error) {
+// parseAsOf parses a date (YYYY-MM-DD, UTC) or RFC 3339 time; empty is the zero time.
+func parseAsOf(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, &storage.ValidationError{Field: "asOf", Reason: "must be YYYY-MM-DD or an RFC 3339 time"}
+}
+
+func (h *Handler) searchNodes(ctx context.Context, args json.RawMessage) (*ToolCallResult, error) {
 	var input SearchNodesInput
 	if err := json.Unmarshal(args, &input); err != nil {
 		return nil, fmt.Errorf("invalid arguments: %w", err)
 	}
 
-	// Try hybrid search (FTS + vector) if embedder is a full EmbeddingClient
-	if ec, ok := h.embedder.(*storage.EmbeddingClient); ok && ec != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	// Try hybrid search (FTS + vector) if embedder is a full EmbeddingClient;
+	// attribute and user filters need the FTS path
+	if ec, ok := h.embedder.(*storage.EmbeddingClient); ok && ec != nil && len(input.Attributes) == 0 && input.User == "" {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 		defer cancel()
 
-		results, err := h.store.HybridSearchWithEmbedder(ctx, input.Query, ec, 20)
+		results, err := h.store.HybridSearchWithEmbedder(ctx, input.Query, ec, 20, storage.HybridOptions{IncludeSuppressed: input.IncludeSuppressed})
 		if err == nil && len(results) > 0 {
-			return h.formatHybridResults(results)
+			names := make([]string, len(results))
+			for i, r := range results {
+				names[i] = r.EntityName
+			}
+			_ = h.store.RecordEntityAccessContext(ctx, names)
+			return h.formatHybridResults(results, input.Explain)
 		}
 		// Fall through to FTS-only on error
 	}
 
 	// Fallback: FTS-only search
-	results, err := h.store.SearchWithLimit(input.Query, 20)
+	results, err := h.store.SearchWithOptionsContext(ctx, input.Query, storage.SearchOptions{
+		Limit:             20,
+		IncludeSuppressed: input.IncludeSuppressed,
+		Attributes:        input.Attributes,
+		User:              input.User,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("search failed: %w", err)
 	}
 
 	// Convert to entity list for output
 	entities := make([]map[string]any, len(results))
+	names := make([]string, len(results))
 	for i, r := range results {
+		names[i] = r.Name
 		entities[i] = map[string]any{
 			"name":         r.Name,
 			"entityType":   r.Type,
 			"observations": r.Observations,
 		}
+		if input.Explain {
+			// BM25 is negated so higher is better, as in hybrid results
+			entities[i]["explain"] = []scoreExplanation{{
+				Score:   -r.Score,
+				Sources: []storage.SourceExplanation{{Source: "fts", Rank: i + 1, Score: -r.Score, Contribution: -r.Score}},
+			}}
+		}
 	}
+	_ = h.store.RecordEntityAccessContext(ctx, names)
 
 	data, err := json.Marshal(entities)
 	if err != nil {
@@ -563,8 +1058,19 @@ func (h *Handler) searchNodes(args json.RawMessage) (*ToolCallResult, error) {
 	}, nil
 }
 
-// formatHybridResults converts FusedResults to MCP output format.
-func (h *Handler) formatHybridResults(results []storage.FusedResult) (*ToolCallResult, error) {
+// scoreExplanation is how one search result was scored, for search_nodes
+// with explain. Observation is empty for a keyword-only search, which ranks
+// whole entities.
+type scoreExplanation struct {
+	Observation string                      `json:"observation,omitempty"`
+	Score       float64                     `json:"score"`
+	Boost       float64                     `json:"boost,omitempty"`
+	Sources     []storage.SourceExplanation `json:"sources"`
+}
+
+// formatHybridResults converts FusedResults to MCP output format, with each
+// result's score breakdown when explain is set.
+func (h *Handler) formatHybridResults(results []storage.FusedResult, explain bool) (*ToolCallResult, error) {
 	// Group results by entity to match expected output format
 	entityMap := make(map[string]*struct {
 		Name         string
@@ -572,9 +1078,18 @@ func (h *Handler) formatHybridResults(results []storage.FusedResult) (*ToolCallR
 		Observations []string
 		Score        float64
 	})
+	explanations := make(map[string][]scoreExplanation)
 
 	for _, r := range results {
 		key := r.EntityName
+		if explain {
+			explanations[key] = append(explanations[key], scoreExplanation{
+				Observation: r.Content,
+				Score:       r.FusionScore,
+				Boost:       r.Boost,
+				Sources:     r.Explain(),
+			})
+		}
 		if existing, ok := entityMap[key]; ok {
 			// Add observation to existing entity
 			existing.Observations = append(existing.Observations, r.Content)
@@ -599,11 +1114,15 @@ func (h *Handler) formatHybridResults(results []storage.FusedResult) (*ToolCallR
 	// Convert to output format
 	entities := make([]map[string]any, 0, len(entityMap))
 	for _, e := range entityMap {
-		entities = append(entities, map[string]any{
+		entity := map[string]any{
 			"name":         e.Name,
 			"entityType":   e.Type,
 			"observations": e.Observations,
-		})
+		}
+		if explain {
+			entity["explain"] = explanations[e.Name]
+		}
+		entities = append(entities, entity)
 	}
 
 	data, err := json.Marshal(entities)
@@ -616,24 +1135,36 @@ func (h *Handler) formatHybridResults(results []storage.FusedResult) (*ToolCallR
 	}, nil
 }
 
-func (h *Handler) openNodes(args json.RawMessage) (*ToolCallResult, error) {
+func (h *Handler) openNodes(ctx context.Context, args json.RawMessage) (*ToolCallResult, error) {
 	var input OpenNodesInput
 	if err := json.Unmarshal(args, &input); err != nil {
 		return nil, fmt.Errorf("invalid arguments: %w", err)
 	}
+	asOf, err := parseAsOf(input.AsOf)
+	if err != nil {
+		return nil, err
+	}
 
 	var entities []map[string]any
+	var names []string
 	for _, name := range input.Names {
-		entity, err := h.store.GetEntity(name)
+		var entity *storage.Entity
+		if asOf.IsZero() {
+			entity, err = h.store.GetEntityContext(ctx, name)
+		} else {
+			entity, err = h.store.GetEntityAsOfContext(ctx, name, asOf)
+		}
 		if err != nil {
 			continue
 		}
+		names = append(names, entity.Name)
 		entities = append(entities, map[string]any{
 			"name":         entity.Name,
 			"entityType":   entity.Type,
 			"observations": entity.Observations,
 		})
 	}
+	_ = h.store.RecordEntityAccessContext(ctx, names)
 
 	data, err := json.Marshal(entities)
 	if err != nil {
@@ -645,7 +1176,7 @@ func (h *Handler) openNodes(args json.RawMessage) (*ToolCallResult, error) {
 	}, nil
 }
 
-func (h *Handler) getRecentContext(args json.RawMessage) (*ToolCallResult, error) {
+func (h *Handler) getRecentContext(ctx context.Context, args json.RawMessage) (*ToolCallResult, error) {
 	var input GetRecentContextInput
 	if err := json.Unmarshal(args, &input); err != nil {
 		return nil, fmt.Errorf("invalid arguments: %w", err)
@@ -660,7 +1191,7 @@ func (h *Handler) getRecentContext(args json.RawMessage) (*ToolCallResult, error
 		tokenBudget = 1000
 	}
 
-	results, err := h.store.GetRecentContext(hours, input.ProjectName, tokenBudget)
+	results, err := h.store.GetRecentContextContext(ctx, hours, input.ProjectName, tokenBudget)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get recent context: %w", err)
 	}
@@ -675,43 +1206,107 @@ func (h *Handler) getRecentContext(args json.RawMessage) (*ToolCallResult, error
 	}, nil
 }
 
-func (h *Handler) summarizeEntity(args json.RawMessage) (*ToolCallResult, error) {
+func (h *Handler) summarizeEntity(ctx context.Context, args json.RawMessage) (*ToolCallResult, error) {
 	var input SummarizeEntityInput
 	if err := json.Unmarshal(args, &input); err != nil {
 		return nil, fmt.Errorf("invalid arguments: %w", err)
 	}
 
-	entity, err := h.store.GetEntity(input.EntityName)
+	entity, err := h.store.GetEntityContext(ctx, input.EntityName)
 	if err != nil {
-		return nil, fmt.Errorf("entity not found: %w", err)
+		return nil, err
 	}
 
-	relations, _ := h.store.ListRelations(input.EntityName)
-	history, _ := h.store.GetEntityHistory(input.EntityName)
+	relations, _ := h.store.ListRelationsContext(ctx, input.EntityName)
+	history, _ := h.store.GetEntityHistoryContext(ctx, input.EntityName)
+	summary := h.entitySummary(ctx, entity, input.Refresh)
+	if summary != nil {
+		// Regenerating replaced the summary observation loaded above
+		if current, err := h.store.GetEntityContext(ctx, entity.Name); err == nil {
+			entity = current
+		}
+	}
 
 	// Build summary
 	var sb strings.Builder
 	sb.WriteString(fmt.Sprintf("# %s (%s)\n", entity.Name, entity.Type))
 	sb.WriteString(fmt.Sprintf("Version: %d | Relations: %d\n\n", entity.Version, len(relations)))
 
+	if summary != nil {
+		sb.WriteString("## Summary\n")
+		if summary.Stale {
+			sb.WriteString("(out of date: observations changed since it was written)\n")
+		}
+		sb.WriteString(summary.Content + "\n\n")
+	}
+
+	if attrs, _ := h.store.GetAttributesContext(ctx, entity.Name); len(attrs) > 0 {
+		sb.WriteString("## Attributes\n")
+		for _, key := range slices.Sorted(maps.Keys(attrs)) {
+			sb.WriteString(fmt.Sprintf("- %s: %s\n", key, attrs[key]))
+		}
+		sb.WriteString("\n")
+	}
+
 	// Group observations by fact type
-	if len(entity.Observations) > 0 {
+	var observations []string
+	for _, obs := range entity.Observations {
+		if summary == nil || obs != summary.Content {
+			observations = append(observations, obs)
+		}
+	}
+	if len(observations) > 0 {
+		sources := map[string]string{}
+		if provenance, err := h.store.GetObservationProvenanceContext(ctx, entity.Name); err == nil {
+			for _, p := range provenance {
+				sources[p.Content] = p.Source
+			}
+		}
 		sb.WriteString("## Observations\n")
-		for _, obs := range entity.Observations {
-			sb.WriteString("- " + obs + "\n")
+		for _, obs := range observations {
+			if source := sources[obs]; source != "" {
+				sb.WriteString("- " + obs + " (via " + source + ")\n")
+			} else {
+				sb.WriteString("- " + obs + "\n")
+			}
 		}
 		sb.WriteString("\n")
 	}
 
-	// Relations
-	if len(relations) > 0 {
+	// Relations, with sessions that worked on the entity listed separately
+	var other []*storage.Relation
+	var sessions []*storage.Session
+	for _, r := range relations {
+		if r.Type == storage.RelationWorkedOn && r.To == entity.Name {
+			if session, err := h.store.GetSessionContext(ctx, r.From); err == nil {
+				sessions = append(sessions, session)
+				continue
+			}
+		}
+		other = append(other, r)
+	}
+
+	if len(other) > 0 {
 		sb.WriteString("## Relations\n")
-		for _, r := range relations {
+		for _, r := range other {
+			*r = h.store.OrientRelation(*r, entity.Name)
 			sb.WriteString(fmt.Sprintf("- %s -[%s]-> %s\n", r.From, r.Type, r.To))
 		}
 		sb.WriteString("\n")
 	}
 
+	if len(sessions) > 0 {
+		sb.WriteString("## Sessions\n")
+		for _, s := range sessions {
+			sb.WriteString(fmt.Sprintf("- %s (%s)", s.Name, s.StartedAt.Format("2006-01-02")))
+			if s.Summary != "" {
+				sb.WriteString(": " + s.Summary)
+			}
+			sb.WriteString("\n")
+		}
+		sb.WriteString("\n")
+	}
+
 	// Version history
 	if len(history) > 1 {
 		sb.WriteString(fmt.Sprintf("## History (%d versions)\n", len(history)))
@@ -725,13 +1320,41 @@ func (h *Handler) summarizeEntity(args json.RawMessage) (*ToolCallResult, error)
 	}, nil
 }
 
-func (h *Handler) consolidateMemories(args json.RawMessage) (*ToolCallResult, error) {
+// entitySummary returns the entity's cached LLM summary, first regenerating
+// it when a summarizer is configured and the cache is missing, stale, or a
+// refresh was asked for. If regeneration fails, the stale summary is kept.
+func (h *Handler) entitySummary(ctx context.Context, entity *storage.Entity, refresh bool) *storage.EntitySummary {
+	cached, err := h.store.GetEntitySummaryContext(ctx, entity.Name)
+	if err != nil {
+		logFor(ctx).Warn("reading entity summary failed", "entity", entity.Name, "error", err)
+		return nil
+	}
+	if h.summarizer == nil || (cached != nil && !cached.Stale && !refresh) {
+		return cached
+	}
+
+	sources, err := h.store.EntitySummarySourcesContext(ctx, entity.Name)
+	if err != nil || len(sources) == 0 {
+		return cached
+	}
+	content, err := h.summarizer.SummarizeEntity(ctx, entity.Name, entity.Type, sources)
+	if err != nil {
+		logFor(ctx).Warn("summarizing entity failed", "entity", entity.Name, "error", err)
+		return cached
+	}
+	if err := h.store.SaveEntitySummaryContext(ctx, entity.Name, content, sources); err != nil {
+		logFor(ctx).Warn("saving entity summary failed", "entity", entity.Name, "error", err)
+	}
+	return &storage.EntitySummary{Content: content}
+}
+
+func (h *Handler) consolidateMemories(ctx context.Context, args json.RawMessage) (*ToolCallResult, error) {
 	var input ConsolidateMemoriesInput
 	if err := json.Unmarshal(args, &input); err != nil {
 		return nil, fmt.Errorf("invalid arguments: %w", err)
 	}
 
-	result, err := h.store.ConsolidateObservations(input.EntityName)
+	result, err := h.store.ConsolidateObservationsContext(ctx, input.EntityName)
 	if err != nil {
 		return nil, fmt.Errorf("consolidation failed: %w", err)
 	}
@@ -741,12 +1364,12 @@ func (h *Handler) consolidateMemories(args json.RawMessage) (*ToolCallResult, er
 	}, nil
 }
 
-func (h *Handler) embedObservations(entityName string, contents []string) {
+func (h *Handler) embedObservations(ctx context.Context, entityName string, contents []string) {
 	if h.embedder == nil {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
 	loggedWarning := false
@@ -754,7 +1377,7 @@ func (h *Handler) embedObservations(entityName string, contents []string) {
 		embedding, err := h.embedder.CreateEmbedding(ctx, content)
 		if err != nil {
 			if !loggedWarning {
-				logger.Warn("embedding failed, semantic search degraded",
+				logFor(ctx).Warn("embedding failed, semantic search degraded",
 					"entity", entityName, "error", err)
 				loggedWarning = true
 			}
@@ -766,30 +1389,54 @@ func (h *Handler) embedObservations(entityName string, contents []string) {
 			continue
 		}
 
-		_ = h.store.StoreEmbedding(obs.ID, embedding, "nomic-embed-text")
+		_ = h.store.StoreEmbeddingContext(ctx, obs.ID, embedding, h.embeddingModel())
+	}
+}
+
+// embeddingModel names the model of h's embedder, for the vectors it stores.
+func (h *Handler) embeddingModel() string {
+	if e, ok := h.embedder.(interface{ Model() string }); ok {
+		return e.Model()
 	}
+	return storage.DefaultEmbeddingModel
 }
 
-func (h *Handler) getContext(args json.RawMessage) (*ToolCallResult, error) {
+func (h *Handler) getContext(ctx context.Context, args json.RawMessage) (*ToolCallResult, error) {
 	var input GetContextInput
 	if err := json.Unmarshal(args, &input); err != nil {
 		return nil, fmt.Errorf("invalid arguments: %w", err)
 	}
 
-	cfg := storage.DefaultContextConfig()
+	cfg := h.contextDefaults
 	if input.TokenBudget > 0 {
 		cfg.TokenBudget = input.TokenBudget
 	}
 	if input.MinImportance > 0 {
 		cfg.MinImportance = input.MinImportance
 	}
+	if len(input.BudgetShares) > 0 {
+		cfg.BudgetShares = input.BudgetShares
+	}
+	cfg.User = input.User
 
-	results, err := h.store.GetContextForInjection(cfg, input.ProjectName)
+	results, err := h.store.GetContextForInjectionContext(ctx, cfg, input.ProjectName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get context: %w", err)
 	}
+	_ = h.store.RecordContextAccessContext(ctx, results)
 
-	formatted := storage.FormatContextResults(results)
+	formatted, err := storage.FormatContextResultsWithTemplate(results, input.Template)
+	if err != nil {
+		return nil, fmt.Errorf("failed to format context: %w", err)
+	}
+	_ = h.store.LogContextInjectionContext(ctx, storage.ContextCall{
+		Source:        storage.SourceMCP,
+		Project:       input.ProjectName,
+		User:          cfg.User,
+		TokenBudget:   cfg.TokenBudget,
+		MinImportance: cfg.MinImportance,
+		TokensUsed:    h.store.CountTokens(formatted),
+	}, results) // Opt-in; see the context.log setting
 	if formatted == "" {
 		formatted = "No relevant memories found."
 	}
@@ -799,19 +1446,85 @@ func (h *Handler) getContext(args json.RawMessage) (*ToolCallResult, error) {
 	}, nil
 }
 
-func (h *Handler) captureSession(args json.RawMessage) (*ToolCallResult, error) {
+func (h *Handler) pinMemory(ctx context.Context, args json.RawMessage) (*ToolCallResult, error) {
+	var input PinMemoryInput
+	if err := json.Unmarshal(args, &input); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	pinned := input.Pinned == nil || *input.Pinned
+	if err := h.store.SetObservationPinnedContext(ctx, input.EntityName, input.Content, pinned); err != nil {
+		return nil, err
+	}
+
+	action := "Pinned"
+	if !pinned {
+		action = "Unpinned"
+	}
+	return &ToolCallResult{
+		Content: []ContentBlock{{Type: "text", Text: fmt.Sprintf("%s observation on %s", action, input.EntityName)}},
+	}, nil
+}
+
+func (h *Handler) suppressMemory(ctx context.Context, args json.RawMessage) (*ToolCallResult, error) {
+	var input SuppressMemoryInput
+	if err := json.Unmarshal(args, &input); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	suppressed := input.Suppressed == nil || *input.Suppressed
+	if err := h.store.SetObservationSuppressedContext(ctx, input.EntityName, input.Content, suppressed); err != nil {
+		return nil, err
+	}
+
+	action := "Suppressed"
+	if !suppressed {
+		action = "Restored"
+	}
+	return &ToolCallResult{
+		Content: []ContentBlock{{Type: "text", Text: fmt.Sprintf("%s observation on %s", action, input.EntityName)}},
+	}, nil
+}
+
+func (h *Handler) markMemoryUsed(ctx context.Context, args json.RawMessage) (*ToolCallResult, error) {
+	var input MarkMemoryUsedInput
+	if err := json.Unmarshal(args, &input); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	var marked int
+	for _, m := range input.Memories {
+		if len(m.Observations) == 0 {
+			if err := h.store.UpdateAccessAndCountContext(ctx, m.EntityName, ""); err == nil {
+				marked++
+			}
+			continue
+		}
+		for _, obs := range m.Observations {
+			if err := h.store.UpdateAccessAndCountContext(ctx, m.EntityName, obs); err == nil {
+				marked++
+			}
+		}
+	}
+
+	return &ToolCallResult{
+		Content: []ContentBlock{{Type: "text", Text: fmt.Sprintf("Marked %d memories as used", marked)}},
+	}, nil
+}
+
+func (h *Handler) captureSession(ctx context.Context, args json.RawMessage) (*ToolCallResult, error) {
 	var input CaptureSessionInput
 	if err := json.Unmarshal(args, &input); err != nil {
 		return nil, fmt.Errorf("invalid arguments: %w", err)
 	}
 
-	session, err := h.store.CreateSession(input.ProjectName)
+	session, err := h.store.CreateSessionContext(ctx, input.ProjectName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create session: %w", err)
 	}
 
 	for _, evt := range input.Events {
-		_ = h.store.CaptureSessionEvent(session.Name, storage.SessionEvent{
+		_ = h.store.CaptureSessionEventContext(ctx, session.Name, storage.SessionEvent{
 			ToolName:  evt.ToolName,
 			FilePath:  evt.FilePath,
 			Command:   evt.Command,
@@ -819,35 +1532,257 @@ func (h *Handler) captureSession(args json.RawMessage) (*ToolCallResult, error)
 		})
 	}
 
-	if err := h.store.CompleteSession(session.Name, input.Summary); err != nil {
+	if err := h.store.CompleteSessionContext(ctx, session.Name, input.Summary); err != nil {
 		return nil, fmt.Errorf("failed to complete session: %w", err)
 	}
 
 	// Auto-embed the summary
-	h.embedObservations(session.Name, []string{input.Summary})
+	h.embedObservations(ctx, session.Name, []string{input.Summary})
 
 	return &ToolCallResult{
 		Content: []ContentBlock{{Type: "text", Text: fmt.Sprintf("Session captured: %s (%d events)", session.Name, len(input.Events))}},
 	}, nil
 }
 
-func (h *Handler) recallSessions(args json.RawMessage) (*ToolCallResult, error) {
+func (h *Handler) recallSessions(ctx context.Context, args json.RawMessage) (*ToolCallResult, error) {
 	var input RecallSessionsInput
 	if err := json.Unmarshal(args, &input); err != nil {
 		return nil, fmt.Errorf("invalid arguments: %w", err)
 	}
 
-	results, err := h.store.GetRecentSessionSummaries(input.ProjectName, input.Hours, input.TokenBudget)
+	var results []storage.ContextResult
+	var err error
+	if input.Query != "" {
+		results, err = h.store.SearchSessionSummariesContext(ctx, input.Query, input.ProjectName, input.TokenBudget)
+	} else {
+		results, err = h.store.GetRecentSessionSummariesContext(ctx, input.ProjectName, input.Hours, input.TokenBudget)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to recall sessions: %w", err)
 	}
 
 	formatted := storage.FormatSessionRecall(results)
 	if formatted == "" {
-		formatted = "No recent sessions found."
+		if input.Query != "" {
+			formatted = "No matching sessions found."
+		} else {
+			formatted = "No recent sessions found."
+		}
 	}
 
 	return &ToolCallResult{
 		Content: []ContentBlock{{Type: "text", Text: formatted}},
 	}, nil
 }
+
+func (h *Handler) resumeWork(ctx context.Context, args json.RawMessage) (*ToolCallResult, error) {
+	var input ResumeWorkInput
+	if err := json.Unmarshal(args, &input); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	project := input.ProjectName
+	var dirtyFiles []string
+	if h.projectDir != "" {
+		if project == "" {
+			project = filepath.Base(h.projectDir)
+		}
+		// Dirty files only describe this project's hook state
+		if project == filepath.Base(h.projectDir) {
+			dirtyFiles = readDirtyFiles(h.projectDir)
+		}
+	}
+
+	brief, err := h.store.GetResumeBriefContext(ctx, project, dirtyFiles)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build resume brief: %w", err)
+	}
+
+	return &ToolCallResult{
+		Content: []ContentBlock{{Type: "text", Text: storage.FormatResumeBrief(brief)}},
+	}, nil
+}
+
+// readDirtyFiles reads the files the post-tool-use hook tracked since the last capture.
+func readDirtyFiles(projectDir string) []string {
+	data, err := os.ReadFile(filepath.Join(projectDir, ".claude", "mark42", "dirty-files"))
+	if err != nil {
+		return nil
+	}
+	var files []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			files = append(files, line)
+		}
+	}
+	return files
+}
+
+func (h *Handler) remember(ctx context.Context, args json.RawMessage) (*ToolCallResult, error) {
+	var input RememberInput
+	if err := json.Unmarshal(args, &input); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	if strings.TrimSpace(input.Text) == "" {
+		return nil, &storage.ValidationError{Field: "text", Reason: "is required"}
+	}
+
+	ex, source, err := h.extract(ctx, input.Text, input.Mode)
+	if err != nil {
+		return nil, err
+	}
+
+	out := RememberOutput{Source: source, Skipped: ex.Skipped}
+	var observations int
+	for _, e := range ex.Entities {
+		out.Entities = append(out.Entities, EntityInput{Name: e.Name, EntityType: e.Type, Observations: e.Observations})
+		observations += len(e.Observations)
+	}
+	for _, r := range ex.Relations {
+		out.Relations = append(out.Relations, RelationInput{From: r.From, To: r.To, RelationType: r.Type})
+	}
+
+	summary := fmt.Sprintf("%d entities, %d observations, %d relations (%s)",
+		len(ex.Entities), observations, len(ex.Relations), source)
+	if input.DryRun {
+		summary = "Would remember " + summary
+	} else {
+		result, err := h.store.SaveExtractionContext(ctx, ex)
+		if err != nil {
+			return nil, err
+		}
+		for i, r := range result.Entities {
+			item := ItemResult{Item: r.Name, Status: StatusCreated}
+			switch {
+			case r.Err != nil:
+				item = itemError(r.Name, r.Err)
+			case !r.Created:
+				item.Status = StatusExists
+			}
+			if r.Err == nil {
+				h.embedObservations(ctx, r.Name, ex.Entities[i].Observations)
+			}
+			out.Results = append(out.Results, item)
+		}
+		for i, err := range result.Relations {
+			item := ItemResult{Item: relationItem(out.Relations[i]), Status: StatusCreated}
+			if err != nil {
+				item = itemError(item.Item, err)
+			}
+			out.Results = append(out.Results, item)
+		}
+		summary = "Remembered " + summary
+	}
+
+	data, err := json.Marshal(out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal extraction: %w", err)
+	}
+	return &ToolCallResult{
+		Content: []ContentBlock{{Type: "text", Text: summary}, {Type: "text", Text: string(data)}},
+	}, nil
+}
+
+// extract runs the extraction mode asks for and names the one used. In auto
+// mode a failing LLM falls back to the heuristic.
+func (h *Handler) extract(ctx context.Context, text, mode string) (*storage.Extraction, string, error) {
+	switch mode {
+	case "", "auto", "llm":
+		if h.extractor == nil {
+			if mode == "llm" {
+				return nil, "", &storage.ValidationError{Field: "mode", Reason: "llm needs CLAUDE_MEMORY_SUMMARY_MODEL set"}
+			}
+			break
+		}
+		ex, err := h.extractor.ExtractFacts(ctx, text)
+		if err == nil {
+			return ex, "llm", nil
+		}
+		if mode == "llm" || ctx.Err() != nil {
+			return nil, "", fmt.Errorf("extraction failed: %w", err)
+		}
+		logFor(ctx).Warn("LLM extraction failed, using heuristic", "error", err)
+	case "heuristic":
+	default:
+		return nil, "", &storage.ValidationError{Field: "mode", Reason: "must be auto, heuristic, or llm"}
+	}
+
+	ex, err := h.store.ExtractKnownFactsContext(ctx, text)
+	return ex, "heuristic", err
+}
+
+func (h *Handler) askMemory(ctx context.Context, args json.RawMessage) (*ToolCallResult, error) {
+	var input AskMemoryInput
+	if err := json.Unmarshal(args, &input); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	var queryEmbedding []float64
+	if h.embedder != nil && strings.TrimSpace(input.Question) != "" {
+		embedCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		queryEmbedding, _ = h.embedder.CreateEmbedding(embedCtx, input.Question) // Keyword search alone on failure
+		cancel()
+	}
+
+	evidence, err := h.store.GatherEvidenceContext(ctx, input.Question, queryEmbedding, input.TokenBudget)
+	if err != nil {
+		return nil, err
+	}
+	out := AskMemoryOutput{Source: "evidence", Evidence: make([]Evidence, len(evidence))}
+	for i, e := range evidence {
+		out.Evidence[i] = Evidence(e)
+	}
+
+	text := "No memories match the question."
+	if len(evidence) > 0 {
+		text = "Evidence:\n" + storage.FormatEvidence(evidence)
+		if h.answerer != nil {
+			answer, err := h.answerer.Answer(ctx, input.Question, evidence)
+			if err == nil {
+				out.Answer, out.Source = answer, "llm"
+				text = answer + "\n\nSources:\n" + storage.FormatEvidence(evidence)
+			} else if ctx.Err() != nil {
+				return nil, fmt.Errorf("answer failed: %w", err)
+			} else {
+				logFor(ctx).Warn("LLM answer failed, returning evidence", "error", err)
+			}
+		}
+	}
+
+	data, err := json.Marshal(out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal answer: %w", err)
+	}
+	return &ToolCallResult{
+		Content: []ContentBlock{{Type: "text", Text: text}, {Type: "text", Text: string(data)}},
+	}, nil
+}
+
+func (h *Handler) setAttributes(ctx context.Context, args json.RawMessage) (*ToolCallResult, error) {
+	var input SetAttributesInput
+	if err := json.Unmarshal(args, &input); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	if len(input.Attributes) == 0 {
+		return nil, &storage.ValidationError{Field: "attributes", Reason: "is required"}
+	}
+
+	if err := h.store.SetAttributesContext(ctx, input.EntityName, input.Attributes); err != nil {
+		return nil, err
+	}
+	attrs, err := h.store.GetAttributesContext(ctx, input.EntityName)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(attrs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal attributes: %w", err)
+	}
+	return &ToolCallResult{
+		Content: []ContentBlock{
+			{Type: "text", Text: fmt.Sprintf("Updated %d attributes on %s", len(input.Attributes), input.EntityName)},
+			{Type: "text", Text: string(data)},
+		},
+	}, nil
+}
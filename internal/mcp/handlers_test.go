@@ -3,10 +3,14 @@ package mcp_test
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/mfenderov/mark42/internal/mcp"
 	"github.com/mfenderov/mark42/internal/storage"
@@ -41,17 +45,26 @@ func TestHandler_Tools(t *testing.T) {
 		"create_relations",
 		"add_observations",
 		"delete_entities",
+		"forget_entity",
 		"delete_observations",
+		"update_observations",
 		"delete_relations",
 		"read_graph",
 		"search_nodes",
 		"open_nodes",
 		"get_context",
+		"pin_memory",
+		"suppress_memory",
+		"mark_memory_used",
 		"get_recent_context",
 		"summarize_entity",
 		"consolidate_memories",
 		"capture_session",
 		"recall_sessions",
+		"resume_work",
+		"remember",
+		"ask_memory",
+		"set_attributes",
 	}
 
 	if len(tools) != len(expectedTools) {
@@ -429,6 +442,53 @@ func TestHandler_CreateRelations(t *testing.T) {
 	}
 }
 
+func TestHandler_CreateRelations_Resolve(t *testing.T) {
+	handler, store := newTestHandler(t)
+	defer store.Close()
+
+	store.CreateEntity("mark42", "project", nil)
+	store.CreateEntity("TDD", "pattern", nil)
+	if err := store.AddAlias("mark42", "memory plugin"); err != nil {
+		t.Fatalf("AddAlias failed: %v", err)
+	}
+
+	args := `"relations": [
+		{"from": "tdd", "to": "memory plugin", "relationType": "used_by"},
+		{"from": "SQLite", "to": "mark42", "relationType": "stores"}
+	]`
+	result, err := handler.CallTool("create_relations", json.RawMessage(`{`+args+`}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if items := itemResults(t, result); items[0].Code != mcp.ToolErrNotFound || items[1].Code != mcp.ToolErrNotFound {
+		t.Errorf("expected unresolved endpoints to fail without options, got %+v", items)
+	}
+
+	result, err = handler.CallTool("create_relations", json.RawMessage(`{`+args+`, "resolveAliases": true, "autoCreateMissing": true}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "Created 2 relations"; result.Content[0].Text != want {
+		t.Errorf("summary = %q, want %q", result.Content[0].Text, want)
+	}
+	want := []mcp.ItemResult{
+		{Item: "tdd -[used_by]-> memory plugin", Status: mcp.StatusCreated, Resolved: "TDD -[used_by]-> mark42"},
+		{Item: "SQLite -[stores]-> mark42", Status: mcp.StatusCreated, Stubs: []string{"SQLite"}},
+	}
+	if items := itemResults(t, result); !reflect.DeepEqual(items, want) {
+		t.Errorf("results = %+v, want %+v", items, want)
+	}
+
+	stub, err := store.GetEntity("SQLite")
+	if err != nil || stub.Type != storage.StubEntityType {
+		t.Errorf("expected stub entity of type %q, got %+v (%v)", storage.StubEntityType, stub, err)
+	}
+	graph, _ := store.ReadGraph()
+	if len(graph.Relations) != 2 {
+		t.Errorf("expected 2 relations, got %+v", graph.Relations)
+	}
+}
+
 // --- add_observations tests ---
 
 func TestHandler_AddObservations(t *testing.T) {
@@ -614,6 +674,142 @@ func TestHandler_DeleteEntities(t *testing.T) {
 
 // --- delete_observations tests ---
 
+// itemResults decodes the per-item results block of a batch tool result.
+func itemResults(t *testing.T, result *mcp.ToolCallResult) []mcp.ItemResult {
+	t.Helper()
+	if len(result.Content) != 2 {
+		t.Fatalf("expected summary and results blocks, got %+v", result.Content)
+	}
+	var out struct {
+		Results []mcp.ItemResult `json:"results"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[1].Text), &out); err != nil {
+		t.Fatalf("failed to parse results: %v", err)
+	}
+	return out.Results
+}
+
+func TestHandler_AddObservations_PerItemResults(t *testing.T) {
+	handler, store := newTestHandler(t)
+	defer store.Close()
+
+	store.CreateEntity("TDD", "pattern", []string{"Red-Green-Refactor"})
+
+	result, err := handler.CallTool("add_observations", json.RawMessage(`{
+		"observations": [
+			{"entityName": "TDD", "contents": ["Write the test first", "Red-Green-Refactor"]},
+			{"entityName": "TTD", "contents": ["Typo'd entity"]}
+		]
+	}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Error("expected a partial failure not to be an error result")
+	}
+	if want := "Added 1 observations (1 failed)"; result.Content[0].Text != want {
+		t.Errorf("summary = %q, want %q", result.Content[0].Text, want)
+	}
+
+	items := itemResults(t, result)
+	want := []mcp.ItemResult{
+		{Item: "TDD: Write the test first", Status: mcp.StatusAdded},
+		{Item: "TDD: Red-Green-Refactor", Status: mcp.StatusDuplicate},
+		{Item: "TTD: Typo'd entity", Status: mcp.StatusError, Error: `entity "TTD" not found`, Code: mcp.ToolErrNotFound},
+	}
+	if len(items) != len(want) {
+		t.Fatalf("expected %d results, got %+v", len(want), items)
+	}
+	for i := range want {
+		if !reflect.DeepEqual(items[i], want[i]) {
+			t.Errorf("result %d = %+v, want %+v", i, items[i], want[i])
+		}
+	}
+}
+
+func TestHandler_CreateEntities_ValidationErrors(t *testing.T) {
+	handler, store := newTestHandler(t)
+	defer store.Close()
+
+	result, err := handler.CallTool("create_entities", json.RawMessage(`{
+		"entities": [
+			{"name": "", "entityType": "pattern"},
+			{"name": "TDD", "entityType": "pattern", "observations": ["Red-Green-Refactor"]}
+		]
+	}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	items := itemResults(t, result)
+	if items[0].Status != mcp.StatusError || items[0].Error != "entity name is required" {
+		t.Errorf("expected empty name rejected with a reason, got %+v", items[0])
+	}
+	if items[1].Status != mcp.StatusCreated {
+		t.Errorf("expected valid entity created, got %+v", items[1])
+	}
+}
+
+func TestHandler_BatchTools_AllFailedIsError(t *testing.T) {
+	handler, store := newTestHandler(t)
+	defer store.Close()
+
+	store.CreateEntity("A", "node", nil)
+
+	tests := []struct {
+		tool string
+		args string
+	}{
+		{"delete_entities", `{"entityNames": ["Missing"]}`},
+		{"create_relations", `{"relations": [{"from": "A", "to": "Missing", "relationType": "links"}]}`},
+		{"delete_relations", `{"relations": [{"from": "A", "to": "Missing", "relationType": "links"}]}`},
+		{"delete_observations", `{"deletions": [{"entityName": "A", "observations": ["never added"]}]}`},
+		{"update_observations", `{"updates": [{"entityName": "A", "oldContent": "never added", "newContent": "x"}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tool, func(t *testing.T) {
+			result, err := handler.CallTool(tt.tool, json.RawMessage(tt.args))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !result.IsError {
+				t.Error("expected isError when every item failed")
+			}
+			items := itemResults(t, result)
+			if len(items) != 1 || items[0].Status != mcp.StatusError || items[0].Error == "" {
+				t.Errorf("expected one failed item with a reason, got %+v", items)
+			}
+		})
+	}
+}
+
+func TestHandler_UpdateObservations(t *testing.T) {
+	handler, store := newTestHandler(t)
+	defer store.Close()
+	handler.WithEmbedder(&fakeEmbedder{})
+
+	store.CreateEntity("TDD", "pattern", nil)
+	store.AddObservationWithType("TDD", "Wirte tests first", storage.FactTypeStatic)
+
+	result, err := handler.CallTool("update_observations", json.RawMessage(
+		`{"updates": [{"entityName": "TDD", "oldContent": "Wirte tests first", "newContent": "Write tests first"}]}`))
+	if err != nil {
+		t.Fatalf("update_observations failed: %v", err)
+	}
+	if items := itemResults(t, result); len(items) != 1 || items[0].Status != mcp.StatusUpdated {
+		t.Errorf("expected one updated item, got %+v", items)
+	}
+
+	static, _ := store.GetObservationsByFactType(storage.FactTypeStatic)
+	if len(static) != 1 || static[0].Content != "Write tests first" {
+		t.Errorf("expected the edit to keep the fact type, got %+v", static)
+	}
+	obs := store.GetObservationWithID("TDD", "Write tests first")
+	if _, err := store.GetEmbedding(obs.ID); err != nil {
+		t.Errorf("expected the new content re-embedded: %v", err)
+	}
+}
+
 func TestHandler_DeleteObservations(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -784,6 +980,68 @@ func TestHandler_DeleteRelations(t *testing.T) {
 
 // --- read_graph tests ---
 
+func TestHandler_ReadGraph_Paginated(t *testing.T) {
+	handler, store := newTestHandler(t)
+	defer store.Close()
+
+	store.CreateEntity("A", "node", []string{"first"})
+	store.CreateEntity("B", "node", []string{"second"})
+
+	result, err := handler.CallTool("read_graph", json.RawMessage(`{"offset": 1, "limit": 1, "includeObservations": false}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var page storage.GraphPage
+	if err := json.Unmarshal([]byte(result.Content[0].Text), &page); err != nil {
+		t.Fatalf("failed to parse graph JSON: %v", err)
+	}
+	if page.Total != 2 || page.NextOffset != 0 || len(page.Entities) != 1 {
+		t.Fatalf("expected last page of 2 entities, got %+v", page)
+	}
+	if page.Entities[0].Name != "B" || len(page.Entities[0].Observations) != 0 {
+		t.Errorf("expected B without observations, got %+v", page.Entities[0])
+	}
+}
+
+func TestHandler_ReadGraph_EndedRelations(t *testing.T) {
+	handler, store := newTestHandler(t)
+	defer store.Close()
+
+	store.CreateEntity("konfig", "project", nil)
+	store.CreateEntity("X", "library", nil)
+	store.CreateRelationValidFrom("konfig", "X", "depends_on", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	store.EndRelation("konfig", "X", "depends_on", time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC))
+	store.DB().Exec(`UPDATE entities SET created_at = '2025-12-01 00:00:00'`)
+
+	relations := func(args string) []*storage.Relation {
+		t.Helper()
+		result, err := handler.CallTool("read_graph", json.RawMessage(args))
+		if err != nil {
+			t.Fatalf("read_graph failed: %v", err)
+		}
+		var page storage.GraphPage
+		if err := json.Unmarshal([]byte(result.Content[0].Text), &page); err != nil {
+			t.Fatalf("failed to parse graph JSON: %v", err)
+		}
+		return page.Relations
+	}
+
+	if got := relations(`{}`); len(got) != 0 {
+		t.Errorf("expected the ended relation hidden, got %+v", got)
+	}
+	if got := relations(`{"includeEnded": true}`); len(got) != 1 || got[0].ValidTo == nil {
+		t.Errorf("expected the ended relation with ValidTo, got %+v", got)
+	}
+	if got := relations(`{"asOf": "2026-03-01"}`); len(got) != 1 {
+		t.Errorf("expected the relation valid in March, got %+v", got)
+	}
+	_, err := handler.CallTool("read_graph", json.RawMessage(`{"asOf": "spring"}`))
+	if mcp.ErrorCode(err) != mcp.ToolErrInvalidInput {
+		t.Errorf("expected invalid_input for a bad asOf, got %v", err)
+	}
+}
+
 func TestHandler_ReadGraph(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -909,6 +1167,32 @@ func TestHandler_SearchNodes(t *testing.T) {
 			setup: func(s *storage.Store) {},
 			args:  `{"query": "anything"}`,
 		},
+		{
+			name: "explain includes the BM25 rank and score",
+			setup: func(s *storage.Store) {
+				s.CreateEntity("TDD", "pattern", []string{"Test-Driven Development"})
+			},
+			args:        `{"query": "Test-Driven", "explain": true}`,
+			wantResults: 1,
+			checkResults: func(t *testing.T, resultJSON string) {
+				var results []struct {
+					Explain []struct {
+						Score   float64 `json:"score"`
+						Sources []storage.SourceExplanation
+					} `json:"explain"`
+				}
+				if err := json.Unmarshal([]byte(resultJSON), &results); err != nil {
+					t.Fatalf("failed to parse results: %v", err)
+				}
+				if len(results[0].Explain) != 1 || len(results[0].Explain[0].Sources) != 1 {
+					t.Fatalf("expected one explanation with one source, got %+v", results[0].Explain)
+				}
+				src := results[0].Explain[0].Sources[0]
+				if src.Source != "fts" || src.Rank != 1 || src.Score <= 0 {
+					t.Errorf("expected fts rank 1 with a positive BM25 score, got %+v", src)
+				}
+			},
+		},
 		{
 			name:        "invalid JSON",
 			setup:       func(s *storage.Store) {},
@@ -1111,6 +1395,30 @@ func TestHandler_GetContext(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "get context with xml-tags template",
+			setup: func(s *storage.Store) {
+				s.Migrate()
+				s.CreateEntity("TDD", "pattern", []string{"Test-Driven Development"})
+				s.SetObservationImportance("TDD", "Test-Driven Development", 0.8)
+			},
+			args: `{"template": "xml-tags"}`,
+			checkResult: func(t *testing.T, text string) {
+				if !strings.Contains(text, `<entity name="TDD"`) {
+					t.Errorf("expected xml-tags output, got: %s", text)
+				}
+			},
+		},
+		{
+			name: "get context with unknown template",
+			setup: func(s *storage.Store) {
+				s.Migrate()
+				s.CreateEntity("TDD", "pattern", []string{"Test-Driven Development"})
+				s.SetObservationImportance("TDD", "Test-Driven Development", 0.8)
+			},
+			args:    `{"template": "nope"}`,
+			wantErr: true,
+		},
 		{
 			name:    "invalid JSON",
 			setup:   func(s *storage.Store) { s.Migrate() },
@@ -1152,158 +1460,439 @@ func TestHandler_GetContext(t *testing.T) {
 	}
 }
 
-// --- WithEmbedder tests ---
-
-func TestHandler_WithEmbedder(t *testing.T) {
+func TestHandler_GetContext_Log(t *testing.T) {
 	handler, store := newTestHandler(t)
 	defer store.Close()
+	store.Migrate()
+	store.CreateEntity("TDD", "pattern", []string{"Test-Driven Development"})
+	store.SetSetting(storage.SettingContextLog, "true")
 
-	// Create a mock embedder (nil is valid - just tests the builder pattern)
-	handler2 := handler.WithEmbedder(nil)
-
-	if handler2 == nil {
-		t.Error("WithEmbedder should return handler")
+	if _, err := handler.CallTool("get_context", json.RawMessage(`{"projectName": "mark42"}`)); err != nil {
+		t.Fatal(err)
 	}
-
-	// Should be same handler (fluent API)
-	if handler2 != handler {
-		t.Error("WithEmbedder should return same handler instance")
+	analysis, err := store.AnalyzeContextLog(time.Now().Add(-time.Hour), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if analysis.BySource[storage.SourceMCP] != 1 || len(analysis.Top) != 1 || analysis.AvgTokensUsed == 0 {
+		t.Errorf("expected the call logged, got %+v", analysis)
 	}
 }
 
-// --- Auto-embed tests ---
-
-type fakeEmbedder struct {
-	calls int
-}
-
-func (f *fakeEmbedder) CreateEmbedding(_ context.Context, _ string) ([]float64, error) {
-	f.calls++
-	return []float64{0.1, 0.2, 0.3}, nil
-}
+// --- pin_memory tests ---
 
-func TestHandler_AutoEmbed_CreateEntities(t *testing.T) {
+func TestHandler_PinMemory(t *testing.T) {
 	handler, store := newTestHandler(t)
 	defer store.Close()
+	store.Migrate()
 
-	embedder := &fakeEmbedder{}
-	handler.WithEmbedder(embedder)
+	store.CreateEntity("Rules", "convention", []string{"Never push to main"})
 
-	args := `{"entities": [{"name": "Go", "entityType": "language", "observations": ["Compiled language", "Has goroutines"]}]}`
-	_, err := handler.CallTool("create_entities", json.RawMessage(args))
+	result, err := handler.CallTool("pin_memory", json.RawMessage(`{"entityName": "Rules", "content": "Never push to main"}`))
 	if err != nil {
-		t.Fatalf("create_entities failed: %v", err)
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(result.Content[0].Text, "Pinned") {
+		t.Errorf("unexpected result: %s", result.Content[0].Text)
 	}
 
-	// Verify embeddings were generated
-	if embedder.calls != 2 {
-		t.Errorf("expected 2 embedding calls, got %d", embedder.calls)
+	pinned, _ := store.ListPinnedObservations()
+	if len(pinned) != 1 {
+		t.Errorf("expected 1 pinned observation, got %d", len(pinned))
 	}
 
-	// Verify embeddings stored in database
-	_, withEmb, err := store.EmbeddingStats()
-	if err != nil {
-		t.Fatalf("EmbeddingStats failed: %v", err)
+	if _, err := handler.CallTool("pin_memory", json.RawMessage(`{"entityName": "Rules", "content": "Never push to main", "pinned": false}`)); err != nil {
+		t.Fatalf("unpin failed: %v", err)
 	}
-	if withEmb != 2 {
-		t.Errorf("expected 2 stored embeddings, got %d", withEmb)
+	pinned, _ = store.ListPinnedObservations()
+	if len(pinned) != 0 {
+		t.Errorf("expected 0 pinned observations, got %d", len(pinned))
+	}
+
+	if _, err := handler.CallTool("pin_memory", json.RawMessage(`{"entityName": "Missing", "content": "x"}`)); err == nil {
+		t.Error("expected error for missing observation")
 	}
 }
 
-func TestHandler_AutoEmbed_AddObservations(t *testing.T) {
+// --- suppress_memory tests ---
+
+func TestHandler_SuppressMemory(t *testing.T) {
 	handler, store := newTestHandler(t)
 	defer store.Close()
+	store.Migrate()
 
-	store.CreateEntity("Go", "language", nil)
-
-	embedder := &fakeEmbedder{}
-	handler.WithEmbedder(embedder)
+	store.CreateEntity("Build", "project", []string{"Use make build", "Use task build"})
 
-	args := `{"observations": [{"entityName": "Go", "contents": ["Fast compilation"]}]}`
-	_, err := handler.CallTool("add_observations", json.RawMessage(args))
-	if err != nil {
-		t.Fatalf("add_observations failed: %v", err)
+	if _, err := handler.CallTool("suppress_memory", json.RawMessage(`{"entityName": "Build", "content": "Use make build"}`)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
 	}
 
-	if embedder.calls != 1 {
-		t.Errorf("expected 1 embedding call, got %d", embedder.calls)
+	result, err := handler.CallTool("search_nodes", json.RawMessage(`{"query": "make"}`))
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
 	}
-
-	_, withEmb, _ := store.EmbeddingStats()
-	if withEmb != 1 {
-		t.Errorf("expected 1 stored embedding, got %d", withEmb)
+	if strings.Contains(result.Content[0].Text, "Use make build") {
+		t.Errorf("suppressed observation should not appear in search, got: %s", result.Content[0].Text)
 	}
-}
-
-func TestHandler_AutoEmbed_NoEmbedder(t *testing.T) {
-	handler, store := newTestHandler(t)
-	defer store.Close()
-
-	// No embedder configured — should work fine without embeddings
-	args := `{"entities": [{"name": "Go", "entityType": "language", "observations": ["Compiled"]}]}`
-	result, err := handler.CallTool("create_entities", json.RawMessage(args))
+	result, err = handler.CallTool("search_nodes", json.RawMessage(`{"query": "make", "includeSuppressed": true}`))
 	if err != nil {
-		t.Fatalf("create_entities failed: %v", err)
+		t.Fatalf("search failed: %v", err)
 	}
-	if result == nil {
-		t.Fatal("expected result")
+	if !strings.Contains(result.Content[0].Text, "Use make build") {
+		t.Errorf("expected includeSuppressed to return the suppressed observation, got: %s", result.Content[0].Text)
 	}
 
-	_, withEmb, _ := store.EmbeddingStats()
-	if withEmb != 0 {
-		t.Errorf("expected 0 embeddings without embedder, got %d", withEmb)
+	if _, err := handler.CallTool("suppress_memory", json.RawMessage(`{"entityName": "Build", "content": "Use make build", "suppressed": false}`)); err != nil {
+		t.Fatalf("restore failed: %v", err)
+	}
+	suppressed, _ := store.ListSuppressedObservations()
+	if len(suppressed) != 0 {
+		t.Errorf("expected no suppressed observations, got %d", len(suppressed))
 	}
-}
 
-type failingEmbedder struct {
-	calls int
+	if _, err := handler.CallTool("suppress_memory", json.RawMessage(`{"entityName": "Missing", "content": "x"}`)); err == nil {
+		t.Error("expected error for missing observation")
+	}
 }
 
-func (f *failingEmbedder) CreateEmbedding(_ context.Context, _ string) ([]float64, error) {
-	f.calls++
-	return nil, fmt.Errorf("connection refused")
-}
+// --- access tracking tests ---
 
-func TestHandler_AutoEmbed_FailingEmbedder(t *testing.T) {
+func TestHandler_RecordsAccess(t *testing.T) {
 	handler, store := newTestHandler(t)
 	defer store.Close()
+	store.Migrate()
 
-	embedder := &failingEmbedder{}
-	handler.WithEmbedder(embedder)
+	store.CreateEntity("TDD", "pattern", []string{"Write tests first"})
+	store.SetObservationImportance("TDD", "Write tests first", 0.9)
 
-	args := `{"entities": [{"name": "Go", "entityType": "language", "observations": ["Compiled language", "Has goroutines"]}]}`
-	result, err := handler.CallTool("create_entities", json.RawMessage(args))
-	if err != nil {
-		t.Fatalf("create_entities should succeed even with failing embedder: %v", err)
+	calls := []struct {
+		tool string
+		args string
+	}{
+		{"search_nodes", `{"query": "tests"}`},
+		{"open_nodes", `{"names": ["TDD"]}`},
+		{"get_context", `{}`},
 	}
-	if result == nil {
-		t.Fatal("expected result")
+	for _, c := range calls {
+		if _, err := handler.CallTool(c.tool, json.RawMessage(c.args)); err != nil {
+			t.Fatalf("%s failed: %v", c.tool, err)
+		}
 	}
 
-	// Entity should still be created
-	entity, err := store.GetEntity("Go")
+	count, err := store.GetAccessCount("TDD", "Write tests first")
 	if err != nil {
-		t.Fatalf("entity not created: %v", err)
-	}
-	if len(entity.Observations) != 2 {
-		t.Errorf("expected 2 observations, got %d", len(entity.Observations))
-	}
-
-	// Embedder was called but all failed
-	if embedder.calls != 2 {
-		t.Errorf("expected 2 embedding calls, got %d", embedder.calls)
+		t.Fatalf("GetAccessCount failed: %v", err)
 	}
-
-	// No embeddings stored
-	_, withEmb, _ := store.EmbeddingStats()
-	if withEmb != 0 {
-		t.Errorf("expected 0 embeddings with failing embedder, got %d", withEmb)
+	if count != len(calls) {
+		t.Errorf("expected access count %d, got %d", len(calls), count)
 	}
 }
 
-// --- Response format tests ---
+// --- session search tests ---
 
-func TestHandler_ResponseFormat(t *testing.T) {
+func TestHandler_SessionSearch(t *testing.T) {
+	handler, store := newTestHandler(t)
+	defer store.Close()
+	store.Migrate()
+
+	fixed, _ := store.CreateSession("myapp")
+	store.CaptureSessionEvent(fixed.Name, storage.SessionEvent{ToolName: "Edit", FilePath: "/myapp/worker.go"})
+	store.CompleteSession(fixed.Name, "Fixed the race condition in the worker pool")
+
+	other, _ := store.CreateSession("myapp")
+	store.CompleteSession(other.Name, "Updated the README")
+
+	t.Run("search_nodes matches session summaries", func(t *testing.T) {
+		result, err := handler.CallTool("search_nodes", json.RawMessage(`{"query": "race"}`))
+		if err != nil {
+			t.Fatalf("search_nodes failed: %v", err)
+		}
+		if !strings.Contains(result.Content[0].Text, fixed.Name) {
+			t.Errorf("expected %s in results: %s", fixed.Name, result.Content[0].Text)
+		}
+	})
+
+	t.Run("recall_sessions with query", func(t *testing.T) {
+		result, err := handler.CallTool("recall_sessions", json.RawMessage(`{"projectName": "myapp", "query": "race condition"}`))
+		if err != nil {
+			t.Fatalf("recall_sessions failed: %v", err)
+		}
+		text := result.Content[0].Text
+		if !strings.Contains(text, "Fixed the race condition") {
+			t.Errorf("expected matching summary: %s", text)
+		}
+		if strings.Contains(text, "README") {
+			t.Errorf("unexpected non-matching session: %s", text)
+		}
+	})
+
+	t.Run("recall_sessions query matches events", func(t *testing.T) {
+		result, err := handler.CallTool("recall_sessions", json.RawMessage(`{"query": "worker.go"}`))
+		if err != nil {
+			t.Fatalf("recall_sessions failed: %v", err)
+		}
+		if !strings.Contains(result.Content[0].Text, "Fixed the race condition") {
+			t.Errorf("expected session summary for event match: %s", result.Content[0].Text)
+		}
+	})
+
+	t.Run("recall_sessions query without matches", func(t *testing.T) {
+		result, err := handler.CallTool("recall_sessions", json.RawMessage(`{"query": "kubernetes"}`))
+		if err != nil {
+			t.Fatalf("recall_sessions failed: %v", err)
+		}
+		if result.Content[0].Text != "No matching sessions found." {
+			t.Errorf("unexpected result: %s", result.Content[0].Text)
+		}
+	})
+}
+
+// --- resume_work tests ---
+
+func TestHandler_ResumeWork(t *testing.T) {
+	handler, store := newTestHandler(t)
+	defer store.Close()
+	store.Migrate()
+
+	projectDir := filepath.Join(t.TempDir(), "myapp")
+	stateDir := filepath.Join(projectDir, ".claude", "mark42")
+	os.MkdirAll(stateDir, 0o755)
+	os.WriteFile(filepath.Join(stateDir, "dirty-files"), []byte("/myapp/lexer.go\n"), 0o644)
+	handler.WithProjectDir(projectDir)
+
+	done, _ := store.CreateSession("myapp")
+	store.CompleteSession(done.Name, "Added the tokenizer")
+
+	active, _ := store.CreateSession("myapp")
+	store.CaptureSessionTurn(active.Name, "User: make the parser handle comments")
+	store.CaptureSessionEvent(active.Name, storage.SessionEvent{ToolName: "Edit", FilePath: "/myapp/parser.go"})
+
+	store.CreateEntity("Parser", "component", []string{"TODO: comments are not parsed yet"})
+	store.SetObservationImportance("Parser", "TODO: comments are not parsed yet", 0.9)
+
+	result, err := handler.CallTool("resume_work", json.RawMessage(`{}`))
+	if err != nil {
+		t.Fatalf("resume_work failed: %v", err)
+	}
+
+	text := result.Content[0].Text
+	for _, want := range []string{
+		"=== Resume Work: myapp ===",
+		"You were doing: make the parser handle comments",
+		active.Name,
+		"Before that: Added the tokenizer",
+		"- /myapp/lexer.go",
+		"- /myapp/parser.go",
+		"- [Parser] TODO: comments are not parsed yet",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("resume_work output missing %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestHandler_ResumeWork_NoSessions(t *testing.T) {
+	handler, store := newTestHandler(t)
+	defer store.Close()
+	store.Migrate()
+
+	result, err := handler.CallTool("resume_work", json.RawMessage(`{"projectName": "empty"}`))
+	if err != nil {
+		t.Fatalf("resume_work failed: %v", err)
+	}
+	if !strings.Contains(result.Content[0].Text, "No previous sessions found.") {
+		t.Errorf("unexpected output: %s", result.Content[0].Text)
+	}
+}
+
+// --- mark_memory_used tests ---
+
+func TestHandler_MarkMemoryUsed(t *testing.T) {
+	handler, store := newTestHandler(t)
+	defer store.Close()
+	store.Migrate()
+
+	store.CreateEntity("TDD", "pattern", []string{"Write tests first", "Refactor after green"})
+
+	result, err := handler.CallTool("mark_memory_used", json.RawMessage(`{
+		"memories": [
+			{"entityName": "TDD", "observations": ["Write tests first"]},
+			{"entityName": "TDD"},
+			{"entityName": "Missing"}
+		]
+	}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(result.Content[0].Text, "Marked 2 memories") {
+		t.Errorf("unexpected result: %s", result.Content[0].Text)
+	}
+
+	count, err := store.GetAccessCount("TDD", "Write tests first")
+	if err != nil {
+		t.Fatalf("GetAccessCount failed: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("expected access count 2, got %d", count)
+	}
+
+	if _, err := handler.CallTool("mark_memory_used", json.RawMessage(`{invalid}`)); err == nil {
+		t.Error("expected error for invalid JSON")
+	}
+}
+
+// --- WithEmbedder tests ---
+
+func TestHandler_WithEmbedder(t *testing.T) {
+	handler, store := newTestHandler(t)
+	defer store.Close()
+
+	// Create a mock embedder (nil is valid - just tests the builder pattern)
+	handler2 := handler.WithEmbedder(nil)
+
+	if handler2 == nil {
+		t.Error("WithEmbedder should return handler")
+	}
+
+	// Should be same handler (fluent API)
+	if handler2 != handler {
+		t.Error("WithEmbedder should return same handler instance")
+	}
+}
+
+// --- Auto-embed tests ---
+
+type fakeEmbedder struct {
+	calls int
+}
+
+func (f *fakeEmbedder) CreateEmbedding(_ context.Context, _ string) ([]float64, error) {
+	f.calls++
+	return []float64{0.1, 0.2, 0.3}, nil
+}
+
+func TestHandler_AutoEmbed_CreateEntities(t *testing.T) {
+	handler, store := newTestHandler(t)
+	defer store.Close()
+
+	embedder := &fakeEmbedder{}
+	handler.WithEmbedder(embedder)
+
+	args := `{"entities": [{"name": "Go", "entityType": "language", "observations": ["Compiled language", "Has goroutines"]}]}`
+	_, err := handler.CallTool("create_entities", json.RawMessage(args))
+	if err != nil {
+		t.Fatalf("create_entities failed: %v", err)
+	}
+
+	// Verify embeddings were generated
+	if embedder.calls != 2 {
+		t.Errorf("expected 2 embedding calls, got %d", embedder.calls)
+	}
+
+	// Verify embeddings stored in database
+	_, withEmb, err := store.EmbeddingStats()
+	if err != nil {
+		t.Fatalf("EmbeddingStats failed: %v", err)
+	}
+	if withEmb != 2 {
+		t.Errorf("expected 2 stored embeddings, got %d", withEmb)
+	}
+}
+
+func TestHandler_AutoEmbed_AddObservations(t *testing.T) {
+	handler, store := newTestHandler(t)
+	defer store.Close()
+
+	store.CreateEntity("Go", "language", nil)
+
+	embedder := &fakeEmbedder{}
+	handler.WithEmbedder(embedder)
+
+	args := `{"observations": [{"entityName": "Go", "contents": ["Fast compilation"]}]}`
+	_, err := handler.CallTool("add_observations", json.RawMessage(args))
+	if err != nil {
+		t.Fatalf("add_observations failed: %v", err)
+	}
+
+	if embedder.calls != 1 {
+		t.Errorf("expected 1 embedding call, got %d", embedder.calls)
+	}
+
+	_, withEmb, _ := store.EmbeddingStats()
+	if withEmb != 1 {
+		t.Errorf("expected 1 stored embedding, got %d", withEmb)
+	}
+}
+
+func TestHandler_AutoEmbed_NoEmbedder(t *testing.T) {
+	handler, store := newTestHandler(t)
+	defer store.Close()
+
+	// No embedder configured — should work fine without embeddings
+	args := `{"entities": [{"name": "Go", "entityType": "language", "observations": ["Compiled"]}]}`
+	result, err := handler.CallTool("create_entities", json.RawMessage(args))
+	if err != nil {
+		t.Fatalf("create_entities failed: %v", err)
+	}
+	if result == nil {
+		t.Fatal("expected result")
+	}
+
+	_, withEmb, _ := store.EmbeddingStats()
+	if withEmb != 0 {
+		t.Errorf("expected 0 embeddings without embedder, got %d", withEmb)
+	}
+}
+
+type failingEmbedder struct {
+	calls int
+}
+
+func (f *failingEmbedder) CreateEmbedding(_ context.Context, _ string) ([]float64, error) {
+	f.calls++
+	return nil, fmt.Errorf("connection refused")
+}
+
+func TestHandler_AutoEmbed_FailingEmbedder(t *testing.T) {
+	handler, store := newTestHandler(t)
+	defer store.Close()
+
+	embedder := &failingEmbedder{}
+	handler.WithEmbedder(embedder)
+
+	args := `{"entities": [{"name": "Go", "entityType": "language", "observations": ["Compiled language", "Has goroutines"]}]}`
+	result, err := handler.CallTool("create_entities", json.RawMessage(args))
+	if err != nil {
+		t.Fatalf("create_entities should succeed even with failing embedder: %v", err)
+	}
+	if result == nil {
+		t.Fatal("expected result")
+	}
+
+	// Entity should still be created
+	entity, err := store.GetEntity("Go")
+	if err != nil {
+		t.Fatalf("entity not created: %v", err)
+	}
+	if len(entity.Observations) != 2 {
+		t.Errorf("expected 2 observations, got %d", len(entity.Observations))
+	}
+
+	// Embedder was called but all failed
+	if embedder.calls != 2 {
+		t.Errorf("expected 2 embedding calls, got %d", embedder.calls)
+	}
+
+	// No embeddings stored
+	_, withEmb, _ := store.EmbeddingStats()
+	if withEmb != 0 {
+		t.Errorf("expected 0 embeddings with failing embedder, got %d", withEmb)
+	}
+}
+
+// --- Response format tests ---
+
+func TestHandler_ResponseFormat(t *testing.T) {
 	handler, store := newTestHandler(t)
 	defer store.Close()
 
@@ -1386,6 +1975,7 @@ func TestHandler_EmptyInputs(t *testing.T) {
 		{"add_observations", `{"observations": []}`},
 		{"delete_entities", `{"entityNames": []}`},
 		{"delete_observations", `{"deletions": []}`},
+		{"update_observations", `{"updates": []}`},
 		{"delete_relations", `{"relations": []}`},
 		{"open_nodes", `{"names": []}`},
 	}
@@ -1467,6 +2057,122 @@ func TestHandler_SummarizeEntity(t *testing.T) {
 	}
 }
 
+func TestHandler_SummarizeEntity_InverseRelation(t *testing.T) {
+	handler, store := newTestHandler(t)
+	defer store.Close()
+
+	store.CreateEntity("TDD", "pattern", nil)
+	store.CreateEntity("konfig", "project", nil)
+	store.CreateRelation("TDD", "konfig", "used_by")
+	store.SetRelationTypes(map[string]storage.RelationTypeInfo{"used_by": {Inverse: "uses"}})
+
+	result, err := handler.CallTool("summarize_entity", json.RawMessage(`{"entityName": "konfig"}`))
+	if err != nil {
+		t.Fatalf("summarize_entity failed: %v", err)
+	}
+	if text := result.Content[0].Text; !strings.Contains(text, "- konfig -[uses]-> TDD") {
+		t.Errorf("expected the relation read from konfig's side:\n%s", text)
+	}
+}
+
+func TestHandler_SummarizeEntity_Sessions(t *testing.T) {
+	handler, store := newTestHandler(t)
+	defer store.Close()
+	store.Migrate()
+
+	store.CreateEntity("Parser", "component", []string{"Parses config files"})
+	store.CreateEntity("Lexer", "component", []string{"Tokenizes input"})
+	store.CreateRelation("Parser", "Lexer", "uses")
+
+	session, _ := store.CreateSession("myapp")
+	store.CompleteSession(session.Name, "Reworked the parser error messages")
+
+	result, err := handler.CallTool("summarize_entity", json.RawMessage(`{"entityName": "Parser"}`))
+	if err != nil {
+		t.Fatalf("summarize_entity failed: %v", err)
+	}
+
+	text := result.Content[0].Text
+	if !strings.Contains(text, "## Sessions\n- "+session.Name) {
+		t.Errorf("expected session listed under Sessions: %s", text)
+	}
+	if !strings.Contains(text, "Reworked the parser error messages") {
+		t.Errorf("expected session summary: %s", text)
+	}
+	if !strings.Contains(text, "- Parser -[uses]-> Lexer") {
+		t.Errorf("expected other relations kept: %s", text)
+	}
+	if strings.Contains(text, "-[worked_on]->") {
+		t.Errorf("worked_on relations should only appear under Sessions: %s", text)
+	}
+}
+
+// fakeSummarizer writes one bullet per observation and counts its calls.
+type fakeSummarizer struct {
+	calls int
+	err   error
+}
+
+func (f *fakeSummarizer) SummarizeEntity(_ context.Context, name, _ string, observations []string) (string, error) {
+	if f.err != nil {
+		return "", f.err
+	}
+	f.calls++
+	return fmt.Sprintf("- %s has %d facts (call %d)", name, len(observations), f.calls), nil
+}
+
+func TestHandler_SummarizeEntity_LLM(t *testing.T) {
+	handler, store := newTestHandler(t)
+	defer store.Close()
+	store.Migrate()
+	summarizer := &fakeSummarizer{}
+	handler.WithSummarizer(summarizer)
+
+	store.CreateEntity("TDD", "pattern", []string{"Test-Driven Development", "Red-Green-Refactor"})
+
+	summarize := func(args string) string {
+		t.Helper()
+		result, err := handler.CallTool("summarize_entity", json.RawMessage(args))
+		if err != nil {
+			t.Fatalf("summarize_entity failed: %v", err)
+		}
+		return result.Content[0].Text
+	}
+
+	text := summarize(`{"entityName": "TDD"}`)
+	if !strings.Contains(text, "## Summary\n- TDD has 2 facts (call 1)") {
+		t.Errorf("expected the LLM abstract first: %s", text)
+	}
+	if strings.Contains(text, "## Observations\n- TDD has") {
+		t.Errorf("the cached summary should not be listed as an observation: %s", text)
+	}
+
+	// Cached while the observations are unchanged
+	summarize(`{"entityName": "TDD"}`)
+	if summarizer.calls != 1 {
+		t.Errorf("expected the cached summary reused, got %d calls", summarizer.calls)
+	}
+
+	store.AddObservation("TDD", "Tests document behavior")
+	text = summarize(`{"entityName": "TDD"}`)
+	if !strings.Contains(text, "- TDD has 3 facts (call 2)") || strings.Contains(text, "call 1") {
+		t.Errorf("expected the summary refreshed after observations changed: %s", text)
+	}
+
+	summarize(`{"entityName": "TDD", "refresh": true}`)
+	if summarizer.calls != 3 {
+		t.Errorf("expected refresh to regenerate, got %d calls", summarizer.calls)
+	}
+
+	// A failing LLM leaves the last summary, flagged as out of date
+	store.AddObservation("TDD", "Small steps")
+	summarizer.err = errors.New("connection refused")
+	text = summarize(`{"entityName": "TDD"}`)
+	if !strings.Contains(text, "out of date") || !strings.Contains(text, "call 3") {
+		t.Errorf("expected the stale summary kept: %s", text)
+	}
+}
+
 func TestHandler_SummarizeEntity_NotFound(t *testing.T) {
 	handler, store := newTestHandler(t)
 	defer store.Close()
@@ -1524,6 +2230,210 @@ func TestHandler_ConsolidateMemories_NothingToConsolidate(t *testing.T) {
 	}
 }
 
+func TestHandler_CallToolContext_Canceled(t *testing.T) {
+	handler, store := newTestHandler(t)
+	defer store.Close()
+	store.Migrate()
+	store.CreateEntity("Go", "language", []string{"Fast compiler"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, tc := range []struct{ tool, args string }{
+		{"read_graph", `{}`},
+		{"create_entities", `{"entities": [{"name": "Rust", "entityType": "language"}]}`},
+		{"search_nodes", `{"query": "compiler"}`},
+	} {
+		if _, err := handler.CallToolContext(ctx, tc.tool, json.RawMessage(tc.args)); !errors.Is(err, context.Canceled) {
+			t.Errorf("%s: expected context.Canceled, got %v", tc.tool, err)
+		}
+	}
+	if _, err := store.GetEntity("Rust"); err == nil {
+		t.Error("expected no entity created under a canceled context")
+	}
+}
+
+func TestErrorResult(t *testing.T) {
+	handler, store := newTestHandler(t)
+	defer store.Close()
+	store.Migrate()
+	store.CreateEntity("Go", "language", []string{"Fast compiler"})
+
+	for _, tc := range []struct {
+		tool, args, code string
+	}{
+		{"summarize_entity", `{"entityName": "Rust"}`, mcp.ToolErrNotFound},
+		{"pin_memory", `{"entityName": "Go", "content": "Slow compiler"}`, mcp.ToolErrNotFound},
+		{"read_graph", `{"limit": "ten"}`, mcp.ToolErrInvalidInput},
+		{"nonexistent_tool", `{}`, mcp.ToolErrUnknownTool},
+	} {
+		_, err := handler.CallTool(tc.tool, json.RawMessage(tc.args))
+		if err == nil {
+			t.Errorf("%s: expected error", tc.tool)
+			continue
+		}
+		result := mcp.ErrorResult(err)
+		if !result.IsError || len(result.Content) != 2 {
+			t.Fatalf("%s: expected an error result with two blocks, got %+v", tc.tool, result)
+		}
+		var payload struct {
+			Error mcp.ToolError `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(result.Content[1].Text), &payload); err != nil {
+			t.Fatalf("%s: failed to parse error block: %v", tc.tool, err)
+		}
+		if payload.Error.Code != tc.code || payload.Error.Message != err.Error() {
+			t.Errorf("%s: error = %+v, want code %q", tc.tool, payload.Error, tc.code)
+		}
+	}
+}
+
+func TestErrorResultContext(t *testing.T) {
+	ctx := storage.WithTraceID(context.Background(), "abc123")
+	result := mcp.ErrorResultContext(ctx, &storage.NotFoundError{Kind: "entity", Name: "Rust"})
+	var payload struct {
+		Error mcp.ToolError `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[1].Text), &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Error.TraceID != "abc123" || payload.Error.Code != mcp.ToolErrNotFound {
+		t.Errorf("error = %+v, want the trace ID", payload.Error)
+	}
+}
+
+func TestHandler_CallTool_SchemaOutdated(t *testing.T) {
+	handler, store := newTestHandler(t)
+	defer store.Close()
+	store.Migrate()
+	store.CreateEntity("Go", "language", []string{"Fast compiler"})
+
+	// Back to before pinned observations; the down migration keeps the column
+	if err := store.MigrateTo(9); err != nil {
+		t.Fatalf("MigrateTo failed: %v", err)
+	}
+	if _, err := store.DB().Exec("ALTER TABLE observations DROP COLUMN pinned"); err != nil {
+		t.Fatalf("dropping column failed: %v", err)
+	}
+	_, err := handler.CallTool("pin_memory", json.RawMessage(`{"entityName": "Go", "content": "Fast compiler"}`))
+	if !errors.Is(err, storage.ErrSchemaOutdated) {
+		t.Fatalf("expected ErrSchemaOutdated, got %v", err)
+	}
+	if code := mcp.ErrorCode(err); code != mcp.ToolErrSchemaOutdated {
+		t.Errorf("expected code %q, got %q", mcp.ToolErrSchemaOutdated, code)
+	}
+}
+
+// --- remember tests ---
+
+type fakeExtractor struct {
+	err error
+}
+
+func (f *fakeExtractor) ExtractFacts(_ context.Context, text string) (*storage.Extraction, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &storage.Extraction{
+		Entities: []storage.EntitySpec{{Name: "Go", Type: "language", Observations: []string{text}}},
+	}, nil
+}
+
+func rememberOutput(t *testing.T, result *mcp.ToolCallResult) mcp.RememberOutput {
+	t.Helper()
+	var out mcp.RememberOutput
+	if err := json.Unmarshal([]byte(result.Content[1].Text), &out); err != nil {
+		t.Fatalf("failed to parse output: %v", err)
+	}
+	return out
+}
+
+func TestHandler_Remember(t *testing.T) {
+	handler, store := newTestHandler(t)
+	defer store.Close()
+	store.Migrate()
+	store.CreateEntity("Parser", "component", nil)
+
+	text := `{"text": "The parser uses the Lexer. Postgres stores sessions.", "dryRun": true}`
+	result, err := handler.CallTool("remember", json.RawMessage(text))
+	if err != nil {
+		t.Fatalf("remember failed: %v", err)
+	}
+	if want := "Would remember 3 entities, 2 observations, 1 relations (heuristic)"; result.Content[0].Text != want {
+		t.Errorf("summary = %q, want %q", result.Content[0].Text, want)
+	}
+	out := rememberOutput(t, result)
+	if len(out.Relations) != 1 || out.Relations[0] != (mcp.RelationInput{From: "Parser", To: "Lexer", RelationType: "uses"}) {
+		t.Errorf("unexpected relations: %+v", out.Relations)
+	}
+	if _, err := store.GetEntity("Lexer"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("expected a dry run to write nothing, got %v", err)
+	}
+
+	result, err = handler.CallTool("remember", json.RawMessage(`{"text": "The parser uses the Lexer. Postgres stores sessions."}`))
+	if err != nil {
+		t.Fatalf("remember failed: %v", err)
+	}
+	want := []mcp.ItemResult{
+		{Item: "Parser", Status: mcp.StatusExists},
+		{Item: "Lexer", Status: mcp.StatusCreated},
+		{Item: "Postgres", Status: mcp.StatusCreated},
+		{Item: "Parser -[uses]-> Lexer", Status: mcp.StatusCreated},
+	}
+	if got := rememberOutput(t, result).Results; !reflect.DeepEqual(got, want) {
+		t.Errorf("results = %+v, want %+v", got, want)
+	}
+	if relations, _ := store.ListRelations("Lexer"); len(relations) != 1 {
+		t.Errorf("expected the relation stored, got %+v", relations)
+	}
+}
+
+func TestHandler_Remember_Modes(t *testing.T) {
+	handler, store := newTestHandler(t)
+	defer store.Close()
+	store.Migrate()
+
+	call := func(args string) (*mcp.ToolCallResult, error) {
+		return handler.CallTool("remember", json.RawMessage(args))
+	}
+
+	if _, err := call(`{"text": "Go is fast", "mode": "llm"}`); !errors.Is(err, storage.ErrInvalidInput) {
+		t.Errorf("expected llm mode without an extractor rejected, got %v", err)
+	}
+	if _, err := call(`{"text": "  "}`); !errors.Is(err, storage.ErrInvalidInput) {
+		t.Errorf("expected blank text rejected, got %v", err)
+	}
+	if _, err := call(`{"text": "Go is fast", "mode": "magic"}`); !errors.Is(err, storage.ErrInvalidInput) {
+		t.Errorf("expected unknown mode rejected, got %v", err)
+	}
+
+	dryRun := func(args string) mcp.RememberOutput {
+		t.Helper()
+		result, err := call(args)
+		if err != nil {
+			t.Fatalf("remember failed: %v", err)
+		}
+		return rememberOutput(t, result)
+	}
+
+	extractor := &fakeExtractor{}
+	handler.WithExtractor(extractor)
+	if out := dryRun(`{"text": "Go is fast", "dryRun": true}`); out.Source != "llm" || out.Entities[0].EntityType != "language" {
+		t.Errorf("expected the LLM extraction, got %+v", out)
+	}
+	if out := dryRun(`{"text": "Go is fast", "mode": "heuristic", "dryRun": true}`); out.Source != "heuristic" {
+		t.Errorf("expected heuristic mode to skip the LLM, got %q", out.Source)
+	}
+
+	extractor.err = errors.New("connection refused")
+	if out := dryRun(`{"text": "Go is fast", "dryRun": true}`); out.Source != "heuristic" {
+		t.Errorf("expected auto mode to fall back, got %q", out.Source)
+	}
+	if _, err := call(`{"text": "Go is fast", "mode": "llm"}`); err == nil {
+		t.Error("expected llm mode to report the failure")
+	}
+}
+
 // --- Tools count test update ---
 
 func TestHandler_Tools_Count(t *testing.T) {
@@ -1531,8 +2441,288 @@ func TestHandler_Tools_Count(t *testing.T) {
 	defer store.Close()
 
 	tools := handler.Tools()
-	// 14 original + 2 new (capture_session, recall_sessions)
-	if len(tools) != 16 {
-		t.Errorf("expected 16 tools, got %d", len(tools))
+	// 14 original + capture_session, recall_sessions, mark_memory_used, pin_memory, suppress_memory, resume_work, remember, ask_memory, set_attributes, update_observations, forget_entity
+	if len(tools) != 25 {
+		t.Errorf("expected 25 tools, got %d", len(tools))
+	}
+}
+
+type fakeAnswerer struct {
+	err      error
+	question string
+	evidence []storage.Evidence
+}
+
+func (f *fakeAnswerer) Answer(_ context.Context, question string, evidence []storage.Evidence) (string, error) {
+	f.question, f.evidence = question, evidence
+	if f.err != nil {
+		return "", f.err
+	}
+	return "The Lexer [1].", nil
+}
+
+func TestHandler_AskMemory(t *testing.T) {
+	handler, store := newTestHandler(t)
+	defer store.Close()
+	store.Migrate()
+	store.CreateEntity("Parser", "component", []string{"Parser uses the Lexer for tokens"})
+
+	ask := func(args string) (*mcp.ToolCallResult, mcp.AskMemoryOutput) {
+		t.Helper()
+		result, err := handler.CallTool("ask_memory", json.RawMessage(args))
+		if err != nil {
+			t.Fatalf("ask_memory failed: %v", err)
+		}
+		var out mcp.AskMemoryOutput
+		if err := json.Unmarshal([]byte(result.Content[1].Text), &out); err != nil {
+			t.Fatalf("invalid JSON block: %v", err)
+		}
+		return result, out
+	}
+
+	result, out := ask(`{"question": "What does the parser use?"}`)
+	if out.Source != "evidence" || out.Answer != "" || len(out.Evidence) == 0 || out.Evidence[0].EntityName != "Parser" {
+		t.Errorf("expected ranked evidence without an LLM, got %+v", out)
+	}
+	if !strings.Contains(result.Content[0].Text, "[1] Parser (component): Parser uses the Lexer for tokens") {
+		t.Errorf("expected numbered evidence, got %q", result.Content[0].Text)
+	}
+
+	answerer := &fakeAnswerer{}
+	handler.WithAnswerer(answerer)
+	result, out = ask(`{"question": "What does the parser use?"}`)
+	if out.Source != "llm" || out.Answer != "The Lexer [1]." || answerer.question != "What does the parser use?" {
+		t.Errorf("expected an LLM answer, got %+v", out)
+	}
+	if !strings.HasPrefix(result.Content[0].Text, "The Lexer [1].\n\nSources:\n[1] Parser") {
+		t.Errorf("expected the answer with sources, got %q", result.Content[0].Text)
+	}
+
+	answerer.err = errors.New("connection refused")
+	if _, out = ask(`{"question": "What does the parser use?"}`); out.Source != "evidence" {
+		t.Errorf("expected evidence when the LLM fails, got %+v", out)
+	}
+
+	answerer.err = nil
+	answerer.question = ""
+	if result, out = ask(`{"question": "kubernetes"}`); len(out.Evidence) != 0 || answerer.question != "" ||
+		result.Content[0].Text != "No memories match the question." {
+		t.Errorf("expected no evidence and no LLM call, got %+v", out)
+	}
+
+	_, err := handler.CallTool("ask_memory", json.RawMessage(`{"question": " "}`))
+	if mcp.ErrorCode(err) != mcp.ToolErrInvalidInput {
+		t.Errorf("expected invalid_input for an empty question, got %v", err)
+	}
+}
+
+func TestHandler_SetAttributes(t *testing.T) {
+	handler, store := newTestHandler(t)
+	defer store.Close()
+	store.Migrate()
+	store.CreateEntity("mark42", "project", []string{"Memory for coding agents"})
+	store.CreateEntity("engram", "project", []string{"Another memory project"})
+
+	result, err := handler.CallTool("set_attributes", json.RawMessage(
+		`{"entityName": "mark42", "attributes": {"repo_url": "https://github.com/mfenderov/mark42", "language": "Go"}}`))
+	if err != nil {
+		t.Fatalf("set_attributes failed: %v", err)
+	}
+	if result.Content[0].Text != "Updated 2 attributes on mark42" ||
+		result.Content[1].Text != `{"language":"Go","repo_url":"https://github.com/mfenderov/mark42"}` {
+		t.Errorf("unexpected result: %+v", result.Content)
+	}
+
+	_, err = handler.CallTool("set_attributes", json.RawMessage(`{"entityName": "mark42", "attributes": {"started": "last spring"}}`))
+	if mcp.ErrorCode(err) != mcp.ToolErrInvalidInput {
+		t.Errorf("expected invalid_input for a bad date, got %v", err)
+	}
+	_, err = handler.CallTool("set_attributes", json.RawMessage(`{"entityName": "missing", "attributes": {"language": "Go"}}`))
+	if mcp.ErrorCode(err) != mcp.ToolErrNotFound {
+		t.Errorf("expected not_found, got %v", err)
+	}
+
+	result, err = handler.CallTool("search_nodes", json.RawMessage(`{"query": "memory", "attributes": {"language": "go"}}`))
+	if err != nil {
+		t.Fatalf("search_nodes failed: %v", err)
+	}
+	if !strings.Contains(result.Content[0].Text, `"mark42"`) || strings.Contains(result.Content[0].Text, "engram") {
+		t.Errorf("expected only mark42 to match the filter, got %s", result.Content[0].Text)
+	}
+
+	result, err = handler.CallTool("summarize_entity", json.RawMessage(`{"entityName": "mark42"}`))
+	if err != nil {
+		t.Fatalf("summarize_entity failed: %v", err)
+	}
+	if !strings.Contains(result.Content[0].Text, "## Attributes\n- language: Go\n- repo_url: https://github.com/mfenderov/mark42\n") {
+		t.Errorf("expected attributes rendered, got:\n%s", result.Content[0].Text)
+	}
+}
+
+func TestHandler_AsOf(t *testing.T) {
+	handler, store := newTestHandler(t)
+	defer store.Close()
+
+	store.CreateEntity("konfig", "project", []string{"Loads YAML"})
+	store.DB().Exec(`UPDATE entities SET created_at = '2026-01-01 00:00:00'`)
+	store.DB().Exec(`UPDATE observations SET created_at = '2026-01-01 00:00:00'`)
+	store.CreateOrUpdateEntity("konfig", "library", []string{"Loads YAML and TOML"})
+	store.CreateEntity("viper", "library", nil)
+
+	result, err := handler.CallTool("open_nodes", json.RawMessage(`{"names": ["konfig", "viper"], "asOf": "2026-03-01"}`))
+	if err != nil {
+		t.Fatalf("open_nodes failed: %v", err)
+	}
+	var nodes []map[string]any
+	if err := json.Unmarshal([]byte(result.Content[0].Text), &nodes); err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 1 || nodes[0]["entityType"] != "project" || fmt.Sprint(nodes[0]["observations"]) != "[Loads YAML]" {
+		t.Errorf("expected only konfig's first version in March, got %v", nodes)
+	}
+
+	result, err = handler.CallTool("read_graph", json.RawMessage(`{"asOf": "2026-03-01"}`))
+	if err != nil {
+		t.Fatalf("read_graph failed: %v", err)
+	}
+	var page storage.GraphPage
+	if err := json.Unmarshal([]byte(result.Content[0].Text), &page); err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 1 || len(page.Entities) != 1 || page.Entities[0].Type != "project" {
+		t.Errorf("expected the graph in March to hold konfig's first version, got %+v", page)
+	}
+}
+
+func TestHandler_Provenance(t *testing.T) {
+	handler, store := newTestHandler(t)
+	defer store.Close()
+
+	if _, err := handler.CallTool("create_entities", json.RawMessage(
+		`{"entities": [{"name": "konfig", "entityType": "project", "observations": ["Loads YAML"]}]}`)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := handler.CallTool("add_observations", json.RawMessage(
+		`{"observations": [{"entityName": "konfig", "contents": ["Loads TOML"]}]}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	provenance, err := store.GetObservationProvenance("konfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, p := range provenance {
+		got[p.Content] = p.Source
+	}
+	if got["Loads YAML"] != "mcp:create_entities" || got["Loads TOML"] != "mcp:add_observations" {
+		t.Errorf("sources = %v", got)
+	}
+
+	result, err := handler.CallTool("summarize_entity", json.RawMessage(`{"entityName": "konfig"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := result.Content[0].Text; !strings.Contains(text, "- Loads TOML (via mcp:add_observations)") {
+		t.Errorf("expected summarize_entity to show sources:\n%s", text)
+	}
+}
+
+func TestHandler_UserScoping(t *testing.T) {
+	handler, store := newTestHandler(t)
+	defer store.Close()
+
+	store.SetUser("alice")
+	if _, err := handler.CallTool("create_entities", json.RawMessage(
+		`{"entities": [{"name": "konfig", "entityType": "project", "observations": ["Loads YAML"]}]}`)); err != nil {
+		t.Fatal(err)
+	}
+	store.SetUser("bob")
+	if _, err := handler.CallTool("create_entities", json.RawMessage(
+		`{"entities": [{"name": "mark42", "entityType": "project", "observations": ["Stores memories"]}]}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := handler.CallTool("search_nodes", json.RawMessage(`{"query": "", "user": "alice"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := result.Content[0].Text; !strings.Contains(text, "konfig") || strings.Contains(text, "mark42") {
+		t.Errorf("expected only alice's entity:\n%s", text)
+	}
+
+	result, err = handler.CallTool("get_context", json.RawMessage(`{"user": "bob", "minImportance": 0.01}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := result.Content[0].Text; !strings.Contains(text, "Stores memories") || strings.Contains(text, "Loads YAML") {
+		t.Errorf("expected only bob's observations:\n%s", text)
+	}
+}
+
+func TestHandler_ForgetEntity(t *testing.T) {
+	handler, store := newTestHandler(t)
+	defer store.Close()
+	store.Migrate()
+	store.CreateEntity("Alice", "person", []string{"Lives at 12 Elm Street"})
+	store.CreateEntity("mark42", "project", []string{"Memory for coding agents"})
+	store.CreateRelation("mark42", "Alice", "owned_by")
+
+	result, err := handler.CallTool("forget_entity", json.RawMessage(`{"entityName": "Alice"}`))
+	if err != nil {
+		t.Fatalf("forget_entity failed: %v", err)
+	}
+	if !strings.HasPrefix(result.Content[0].Text, "Would erase Alice: 1 versions, 1 observations, 1 relations") {
+		t.Errorf("expected a preview, got %q", result.Content[0].Text)
+	}
+	if _, err := store.GetEntity("Alice"); err != nil {
+		t.Fatalf("expected Alice kept without confirm: %v", err)
+	}
+
+	if _, err := handler.CallTool("forget_entity", json.RawMessage(`{"entityName": "Alice", "confirm": "mark42"}`)); err != nil {
+		t.Fatalf("forget_entity failed: %v", err)
+	}
+	if _, err := store.GetEntity("Alice"); err != nil {
+		t.Fatalf("expected Alice kept when confirm names another entity: %v", err)
+	}
+
+	result, err = handler.CallTool("forget_entity", json.RawMessage(`{"entityName": "Alice", "confirm": "Alice"}`))
+	if err != nil {
+		t.Fatalf("forget_entity failed: %v", err)
+	}
+	var report storage.PurgeReport
+	if err := json.Unmarshal([]byte(result.Content[1].Text), &report); err != nil {
+		t.Fatalf("invalid JSON block: %v", err)
+	}
+	if report.Observations != 1 || report.Relations != 1 {
+		t.Errorf("unexpected report %+v", report)
+	}
+	if _, err := store.GetEntity("Alice"); err == nil {
+		t.Error("expected Alice erased")
+	}
+
+	_, err = handler.CallTool("forget_entity", json.RawMessage(`{"entityName": "Alice", "confirm": "Alice"}`))
+	if mcp.ErrorCode(err) != mcp.ToolErrNotFound {
+		t.Errorf("expected not_found, got %v", err)
+	}
+}
+
+func TestHandler_GetContext_Defaults(t *testing.T) {
+	handler, store := newTestHandler(t)
+	defer store.Close()
+	store.Migrate()
+	store.CreateEntity("TDD", "pattern", []string{"Test-Driven Development"})
+	store.CreateEntity("session-1", "session", []string{"Fixed the build"})
+
+	cfg := storage.DefaultContextConfig()
+	cfg.ExcludeTypes = []string{"session"}
+	handler.WithContextDefaults(cfg)
+
+	result, err := handler.CallTool("get_context", json.RawMessage(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := result.Content[0].Text; !strings.Contains(text, "TDD") || strings.Contains(text, "Fixed the build") {
+		t.Errorf("expected the configured exclusion applied:\n%s", text)
 	}
 }
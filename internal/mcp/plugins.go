@@ -0,0 +1,207 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+// ToolStore is the part of the handler's store a custom tool may use: the
+// operations every storage backend offers, without Close.
+type ToolStore interface {
+	CreateEntityContext(ctx context.Context, name, entityType string, observations []string) (*storage.Entity, error)
+	GetEntityContext(ctx context.Context, name string) (*storage.Entity, error)
+	ListEntitiesContext(ctx context.Context, entityType string) ([]*storage.Entity, error)
+	DeleteEntityContext(ctx context.Context, name string) error
+	AddObservationContext(ctx context.Context, entityName, content string) error
+	DeleteObservationContext(ctx context.Context, entityName, content string) error
+	CreateRelationContext(ctx context.Context, fromName, toName, relationType string) error
+	ListRelationsContext(ctx context.Context, entityName string) ([]*storage.Relation, error)
+	SearchContext(ctx context.Context, query string) ([]*storage.SearchResult, error)
+	GetContextForInjectionContext(ctx context.Context, cfg storage.ContextConfig, projectName string) ([]storage.ContextResult, error)
+}
+
+var _ ToolStore = (storage.Backend)(nil)
+
+// ToolFunc runs a tool added with RegisterTool, given the handler's store.
+type ToolFunc func(ctx context.Context, store ToolStore, args json.RawMessage) (*ToolCallResult, error)
+
+// customTool is a tool added with RegisterTool.
+type customTool struct {
+	tool Tool
+	run  ToolFunc
+}
+
+// RegisterTool adds a tool to those the handler lists and calls, such as an
+// org-specific lookup that reads the same store. It fails when the name is
+// taken by a built-in or an earlier registration.
+func (h *Handler) RegisterTool(tool Tool, run ToolFunc) error {
+	if tool.Name == "" {
+		return &storage.ValidationError{Field: "tool name", Reason: "must not be empty"}
+	}
+	if run == nil {
+		return &storage.ValidationError{Field: tool.Name, Reason: "has no function to run"}
+	}
+	if h.hasTool(tool.Name) {
+		return &storage.ValidationError{Field: tool.Name, Reason: "a tool with this name exists"}
+	}
+	if tool.InputSchema.Type == "" {
+		tool.InputSchema.Type = "object"
+	}
+	h.custom = append(h.custom, customTool{tool: tool, run: run})
+	return nil
+}
+
+// hasTool reports whether Tools lists name.
+func (h *Handler) hasTool(name string) bool {
+	return slices.ContainsFunc(h.Tools(), func(t Tool) bool { return t.Name == name })
+}
+
+// customToolNamed returns the tool added with RegisterTool as name, or nil.
+func (h *Handler) customToolNamed(name string) *customTool {
+	for i := range h.custom {
+		if h.custom[i].tool.Name == name {
+			return &h.custom[i]
+		}
+	}
+	return nil
+}
+
+// pluginDescribeTimeout bounds how long a plugin may take to describe itself.
+const pluginDescribeTimeout = 5 * time.Second
+
+// Plugin exit codes with a meaning beyond failure.
+const (
+	// PluginExitInvalidInput reports arguments the plugin rejects; the
+	// call fails with ErrInvalidInput
+	PluginExitInvalidInput = 2
+	// PluginExitNotFound reports that what the arguments name does not
+	// exist; the call fails with ErrNotFound
+	PluginExitNotFound = 3
+)
+
+// LoadPlugins registers each executable file in dir as a tool, in name
+// order. A plugin speaks JSON over stdio:
+//
+//   - Run with --describe, it prints its Tool definition: name,
+//     description, and inputSchema.
+//   - Called, it reads the call's arguments object on stdin and prints its
+//     result: a ToolCallResult object, or else plain text, sent as one text
+//     block. Exit code 2 rejects the arguments and 3 reports something not
+//     found; any other failure is an internal error. The last line of
+//     stderr explains a failure.
+//
+// Plugins run with env added to the server's environment, such as the
+// database path, so they can use the mark42 CLI on the same store. A
+// missing dir loads nothing. Plugins that fail to describe themselves or
+// clash with a registered tool are skipped with an error naming them; the
+// others are registered regardless.
+func (h *Handler) LoadPlugins(ctx context.Context, dir string, env []string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var loaded []string
+	var errs []error
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0o111 == 0 {
+			continue
+		}
+		tool, err := describePlugin(ctx, path, env)
+		if err == nil {
+			err = h.RegisterTool(tool, pluginToolFunc(path, env))
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("plugin %s: %w", entry.Name(), err))
+			continue
+		}
+		loaded = append(loaded, tool.Name)
+	}
+	return loaded, errors.Join(errs...)
+}
+
+// describePlugin runs the plugin at path with --describe and reads its Tool.
+func describePlugin(ctx context.Context, path string, env []string) (Tool, error) {
+	ctx, cancel := context.WithTimeout(ctx, pluginDescribeTimeout)
+	defer cancel()
+	out, err := runPlugin(ctx, path, env, nil, "--describe")
+	if err != nil {
+		return Tool{}, err
+	}
+	var tool Tool
+	if err := json.Unmarshal(out, &tool); err != nil {
+		return Tool{}, fmt.Errorf("reading its description: %w", err)
+	}
+	return tool, nil
+}
+
+// pluginToolFunc calls the plugin at path with a tool call's arguments.
+func pluginToolFunc(path string, env []string) ToolFunc {
+	return func(ctx context.Context, _ ToolStore, args json.RawMessage) (*ToolCallResult, error) {
+		if len(args) == 0 {
+			args = json.RawMessage("{}")
+		}
+		callEnv := env
+		if id := storage.TraceID(ctx); id != "" {
+			callEnv = append(slices.Clip(env), "MARK42_TRACE_ID="+id)
+		}
+		out, err := runPlugin(ctx, path, callEnv, args)
+		if err != nil {
+			return nil, err
+		}
+		var result ToolCallResult
+		if json.Unmarshal(out, &result) == nil && len(result.Content) > 0 {
+			return &result, nil
+		}
+		return &ToolCallResult{Content: []ContentBlock{{Type: "text", Text: strings.TrimSpace(string(out))}}}, nil
+	}
+}
+
+// runPlugin runs the plugin at path with stdin and args, returning its
+// stdout. Exit codes map to storage sentinels, with the last line of stderr
+// as the message.
+func runPlugin(ctx context.Context, path string, env []string, stdin []byte, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return stdout.Bytes(), err
+	}
+
+	message := exitErr.Error()
+	lines := strings.Split(strings.TrimSpace(stderr.String()), "\n")
+	if last := strings.TrimSpace(lines[len(lines)-1]); last != "" {
+		message = last
+	}
+	switch exitErr.ExitCode() {
+	case PluginExitInvalidInput:
+		return nil, fmt.Errorf("%w: %s", storage.ErrInvalidInput, message)
+	case PluginExitNotFound:
+		return nil, fmt.Errorf("%w: %s", storage.ErrNotFound, message)
+	default:
+		return nil, errors.New(message)
+	}
+}
@@ -0,0 +1,117 @@
+package mcp_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/mfenderov/mark42/internal/mcp"
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+func TestHandler_RegisterTool(t *testing.T) {
+	handler, store := newTestHandler(t)
+	defer store.Close()
+	if _, err := store.CreateEntity("ADR-7", "decision", []string{"Use SQLite"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tool := mcp.Tool{Name: "adr_search", Description: "Find ADRs"}
+	err := handler.RegisterTool(tool, func(ctx context.Context, store mcp.ToolStore, args json.RawMessage) (*mcp.ToolCallResult, error) {
+		entities, err := store.ListEntitiesContext(ctx, "decision")
+		if err != nil {
+			return nil, err
+		}
+		return &mcp.ToolCallResult{Content: []mcp.ContentBlock{{Type: "text", Text: entities[0].Name}}}, nil
+	})
+	if err != nil {
+		t.Fatalf("RegisterTool failed: %v", err)
+	}
+
+	tools := handler.Tools()
+	if last := tools[len(tools)-1]; last.Name != "adr_search" || last.InputSchema.Type != "object" {
+		t.Errorf("expected adr_search listed last with an object schema, got %+v", last)
+	}
+	result, err := handler.CallTool("adr_search", nil)
+	if err != nil {
+		t.Fatalf("CallTool failed: %v", err)
+	}
+	if result.Content[0].Text != "ADR-7" {
+		t.Errorf("expected ADR-7, got %q", result.Content[0].Text)
+	}
+
+	run := func(context.Context, mcp.ToolStore, json.RawMessage) (*mcp.ToolCallResult, error) { return nil, nil }
+	for _, name := range []string{"adr_search", "search_nodes"} {
+		if err := handler.RegisterTool(mcp.Tool{Name: name}, run); !errors.Is(err, storage.ErrInvalidInput) {
+			t.Errorf("registering %s again: expected ErrInvalidInput, got %v", name, err)
+		}
+	}
+}
+
+// writePlugin writes an executable shell script plugin to dir.
+func writePlugin(t *testing.T, dir, name, script string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHandler_LoadPlugins(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are shell scripts")
+	}
+	handler, store := newTestHandler(t)
+	defer store.Close()
+
+	dir := t.TempDir()
+	writePlugin(t, dir, "jira", `
+if [ "$1" = "--describe" ]; then
+  echo '{"name":"jira_lookup","description":"Look up a Jira issue","inputSchema":{"type":"object","properties":{"key":{"type":"string"}},"required":["key"]}}'
+  exit 0
+fi
+input=$(cat)
+case "$input" in
+  *NOPE*) echo "no such issue" >&2; exit 3 ;;
+  *'"key"'*) echo "$input from $CLAUDE_MEMORY_DB" ;;
+  *) echo "key is required" >&2; exit 2 ;;
+esac
+`)
+	writePlugin(t, dir, "broken", "echo not json\n")
+	if err := os.WriteFile(filepath.Join(dir, "README"), []byte("not a plugin"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := handler.LoadPlugins(context.Background(), dir, []string{"CLAUDE_MEMORY_DB=/tmp/team.db"})
+	if err == nil || !strings.Contains(err.Error(), "plugin broken") {
+		t.Errorf("expected the broken plugin reported, got %v", err)
+	}
+	if len(loaded) != 1 || loaded[0] != "jira_lookup" {
+		t.Fatalf("expected jira_lookup loaded, got %v", loaded)
+	}
+
+	result, err := handler.CallTool("jira_lookup", json.RawMessage(`{"key":"MEM-1"}`))
+	if err != nil {
+		t.Fatalf("CallTool failed: %v", err)
+	}
+	if got := result.Content[0].Text; got != `{"key":"MEM-1"} from /tmp/team.db` {
+		t.Errorf("unexpected result %q", got)
+	}
+
+	_, err = handler.CallTool("jira_lookup", json.RawMessage(`{}`))
+	if !errors.Is(err, storage.ErrInvalidInput) || !strings.Contains(err.Error(), "key is required") {
+		t.Errorf("expected ErrInvalidInput with the plugin's message, got %v", err)
+	}
+	_, err = handler.CallTool("jira_lookup", json.RawMessage(`{"key":"NOPE"}`))
+	if mcp.ErrorCode(err) != mcp.ToolErrNotFound {
+		t.Errorf("expected not found, got %v", err)
+	}
+
+	if loaded, err := handler.LoadPlugins(context.Background(), filepath.Join(dir, "missing"), nil); loaded != nil || err != nil {
+		t.Errorf("expected a missing dir to load nothing, got %v, %v", loaded, err)
+	}
+}
@@ -121,6 +121,49 @@ type ContentBlock struct {
 	Text string `json:"text"`
 }
 
+// ItemResult is the outcome of one item of a batch write tool.
+type ItemResult struct {
+	Item   string `json:"item"`
+	Status string `json:"status"` // created, exists, added, duplicate, deleted, or error
+	Error  string `json:"error,omitempty"`
+	Code   string `json:"code,omitempty"` // One of the ToolErr codes when Status is error
+	// Resolved is the item as written when create_relations resolved an
+	// endpoint to another name
+	Resolved string   `json:"resolved,omitempty"`
+	Stubs    []string `json:"stubs,omitempty"` // Entities created for the item
+}
+
+// ToolError is the machine-readable part of a failed tool call, sent as a
+// JSON content block after the message: {"error":{"code":...,"message":...}}.
+type ToolError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	TraceID string `json:"traceId,omitempty"` // Of the call, as logged by the server
+}
+
+// Tool error codes
+const (
+	ToolErrNotFound       = "not_found"
+	ToolErrAlreadyExists  = "already_exists"
+	ToolErrInvalidInput   = "invalid_input"
+	ToolErrSchemaOutdated = "schema_outdated" // Run 'mark42 upgrade'
+	ToolErrUnknownTool    = "unknown_tool"
+	ToolErrTimeout        = "timeout"
+	ToolErrCanceled       = "canceled"
+	ToolErrInternal       = "internal"
+)
+
+// Item statuses
+const (
+	StatusCreated   = "created"
+	StatusExists    = "exists" // Entity existed; its new observations were added
+	StatusAdded     = "added"
+	StatusDuplicate = "duplicate"
+	StatusDeleted   = "deleted"
+	StatusUpdated   = "updated"
+	StatusError     = "error"
+)
+
 // Memory tool input types (matching @modelcontextprotocol/server-memory API)
 
 type CreateEntitiesInput struct {
@@ -135,6 +178,10 @@ type EntityInput struct {
 
 type CreateRelationsInput struct {
 	Relations []RelationInput `json:"relations"`
+	// ResolveAliases finds endpoints by alias or ignoring case
+	ResolveAliases bool `json:"resolveAliases,omitempty"`
+	// AutoCreateMissing creates endpoints that don't exist as stub entities
+	AutoCreateMissing bool `json:"autoCreateMissing,omitempty"`
 }
 
 type RelationInput struct {
@@ -157,6 +204,11 @@ type DeleteEntitiesInput struct {
 	EntityNames []string `json:"entityNames"`
 }
 
+type ForgetEntityInput struct {
+	EntityName string `json:"entityName"`
+	Confirm    string `json:"confirm,omitempty"` // The entity's name again; without it nothing is erased
+}
+
 type DeleteObservationsInput struct {
 	Deletions []DeletionInput `json:"deletions"`
 }
@@ -166,22 +218,76 @@ type DeletionInput struct {
 	Observations []string `json:"observations"`
 }
 
+type UpdateObservationsInput struct {
+	Updates []ObservationUpdate `json:"updates"`
+}
+
+type ObservationUpdate struct {
+	EntityName string `json:"entityName"`
+	OldContent string `json:"oldContent"`
+	NewContent string `json:"newContent"`
+}
+
 type DeleteRelationsInput struct {
 	Relations []RelationInput `json:"relations"`
 }
 
+type ReadGraphInput struct {
+	Offset              int    `json:"offset,omitempty"`
+	Limit               int    `json:"limit,omitempty"`               // 0 returns all entities
+	IncludeObservations *bool  `json:"includeObservations,omitempty"` // Defaults to true
+	IncludeEnded        bool   `json:"includeEnded,omitempty"`        // Relations that were ended too
+	AsOf                string `json:"asOf,omitempty"`                // The graph as it was at this date or RFC 3339 time
+}
+
 type SearchNodesInput struct {
-	Query string `json:"query"`
+	Query      string            `json:"query"`
+	Attributes map[string]string `json:"attributes,omitempty"` // Only entities with all of these
+	User       string            `json:"user,omitempty"`       // Only entities this user created or wrote on
+	Explain    bool              `json:"explain,omitempty"`    // Include how each result was scored
+	// Match and return suppressed observations too
+	IncludeSuppressed bool `json:"includeSuppressed,omitempty"`
+}
+
+type SetAttributesInput struct {
+	EntityName string            `json:"entityName"`
+	Attributes map[string]string `json:"attributes"` // An empty value removes the attribute
 }
 
 type OpenNodesInput struct {
 	Names []string `json:"names"`
+	AsOf  string   `json:"asOf,omitempty"` // The entities as they were at this date or RFC 3339 time
 }
 
 type GetContextInput struct {
 	ProjectName   string  `json:"projectName,omitempty"`
 	TokenBudget   int     `json:"tokenBudget,omitempty"`
 	MinImportance float64 `json:"minImportance,omitempty"`
+	Template      string  `json:"template,omitempty"` // Optional: "default", "compact", "xml-tags", "markdown"
+	// Optional: share of tokenBudget reserved per fact type, e.g. {"static": 0.5}
+	BudgetShares map[string]float64 `json:"budgetShares,omitempty"`
+	User         string             `json:"user,omitempty"` // Only observations this user wrote
+}
+
+type PinMemoryInput struct {
+	EntityName string `json:"entityName"`
+	Content    string `json:"content"`
+	Pinned     *bool  `json:"pinned,omitempty"` // Defaults to true; false unpins
+}
+
+type SuppressMemoryInput struct {
+	EntityName string `json:"entityName"`
+	Content    string `json:"content"`
+	Suppressed *bool  `json:"suppressed,omitempty"` // Defaults to true; false restores
+}
+
+type MarkMemoryUsedInput struct {
+	Memories []UsedMemoryInput `json:"memories"`
+}
+
+type UsedMemoryInput struct {
+	EntityName   string   `json:"entityName"`
+	Observations []string `json:"observations,omitempty"` // Empty marks every observation of the entity
 }
 
 type GetRecentContextInput struct {
@@ -192,6 +298,7 @@ type GetRecentContextInput struct {
 
 type SummarizeEntityInput struct {
 	EntityName string `json:"entityName"`
+	Refresh    bool   `json:"refresh,omitempty"` // Regenerate the LLM summary even if it is current
 }
 
 type ConsolidateMemoriesInput struct {
@@ -211,8 +318,49 @@ type CaptureSessionInput struct {
 	Events      []CaptureSessionEventInput `json:"events,omitempty"`
 }
 
+type RememberInput struct {
+	Text   string `json:"text"`
+	Mode   string `json:"mode,omitempty"`   // auto (default), heuristic, or llm
+	DryRun bool   `json:"dryRun,omitempty"` // Return the extraction without writing it
+}
+
+// RememberOutput is the JSON block of a remember result.
+type RememberOutput struct {
+	Source    string          `json:"source"` // heuristic or llm
+	Entities  []EntityInput   `json:"entities"`
+	Relations []RelationInput `json:"relations"`
+	Skipped   []string        `json:"skipped,omitempty"`
+	Results   []ItemResult    `json:"results,omitempty"`
+}
+
+type AskMemoryInput struct {
+	Question    string `json:"question"`
+	TokenBudget int    `json:"tokenBudget,omitempty"` // Evidence budget (default 1500)
+}
+
+// AskMemoryOutput is the JSON block of an ask_memory result.
+type AskMemoryOutput struct {
+	Answer   string     `json:"answer,omitempty"` // Empty without an LLM
+	Source   string     `json:"source"`           // llm or evidence
+	Evidence []Evidence `json:"evidence"`
+}
+
+// Evidence is a memory excerpt ask_memory cites as [Ref].
+type Evidence struct {
+	Ref        int     `json:"ref"`
+	EntityName string  `json:"entityName"`
+	EntityType string  `json:"entityType"`
+	Content    string  `json:"content"`
+	Score      float64 `json:"score"`
+}
+
+type ResumeWorkInput struct {
+	ProjectName string `json:"projectName,omitempty"`
+}
+
 type RecallSessionsInput struct {
 	ProjectName string `json:"projectName,omitempty"`
 	Hours       int    `json:"hours,omitempty"`
 	TokenBudget int    `json:"tokenBudget,omitempty"`
+	Query       string `json:"query,omitempty"`
 }
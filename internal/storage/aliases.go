@@ -0,0 +1,189 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// StubEntityType is the type of entities CreateRelationResolved creates for
+// endpoints that don't exist yet.
+const StubEntityType = "unknown"
+
+// EntityAlias is another name an entity goes by.
+type EntityAlias struct {
+	Alias     string    `json:"alias" db:"alias"`
+	Entity    string    `json:"entity" db:"entity_name"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+}
+
+// AddAlias records alias as another name for entity. The alias can't be the
+// name of an entity or an alias of another entity; adding an alias the
+// entity already has does nothing.
+func (s *Store) AddAlias(entity, alias string) error {
+	return s.AddAliasContext(context.Background(), entity, alias)
+}
+
+// AddAliasContext is AddAlias with a context.
+func (s *Store) AddAliasContext(ctx context.Context, entity, alias string) error {
+	if err := validateText("alias", alias, MaxNameLength, true); err != nil {
+		return err
+	}
+	alias = NormalizeName(alias)
+
+	id, err := s.entityID(ctx, s.db, entity)
+	if err != nil {
+		return err
+	}
+	var name string
+	if err := s.db.GetContext(ctx, &name, "SELECT name FROM entities WHERE id = ?", id); err != nil {
+		return err
+	}
+
+	if _, err := s.entityID(ctx, s.db, alias); err == nil {
+		return &ValidationError{"alias", "is the name of an entity"}
+	} else if !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	var owner string
+	err = s.db.GetContext(ctx, &owner, "SELECT entity_name FROM entity_aliases WHERE alias = ?", alias)
+	switch {
+	case err == nil && owner == name:
+		return nil
+	case err == nil:
+		return &ValidationError{"alias", "already names " + owner}
+	case !errors.Is(err, sql.ErrNoRows):
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, "INSERT INTO entity_aliases (alias, entity_name) VALUES (?, ?)", alias, name)
+	return err
+}
+
+// RemoveAlias deletes an alias.
+func (s *Store) RemoveAlias(alias string) error {
+	return s.RemoveAliasContext(context.Background(), alias)
+}
+
+// RemoveAliasContext is RemoveAlias with a context.
+func (s *Store) RemoveAliasContext(ctx context.Context, alias string) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM entity_aliases WHERE alias = ?", NormalizeName(alias))
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return &NotFoundError{"alias", alias}
+	}
+	return nil
+}
+
+// ListAliases returns an entity's aliases, or every alias when entity is
+// empty, ordered by entity and alias.
+func (s *Store) ListAliases(entity string) ([]EntityAlias, error) {
+	return s.ListAliasesContext(context.Background(), entity)
+}
+
+// ListAliasesContext is ListAliases with a context.
+func (s *Store) ListAliasesContext(ctx context.Context, entity string) ([]EntityAlias, error) {
+	query := "SELECT alias, entity_name, created_at FROM entity_aliases"
+	var args []any
+	if entity != "" {
+		query += " WHERE entity_name = ?"
+		args = append(args, NormalizeName(entity))
+	}
+	var aliases []EntityAlias
+	err := s.db.SelectContext(ctx, &aliases, query+" ORDER BY entity_name, alias", args...)
+	return aliases, err
+}
+
+// ResolveEntityName returns the name of the entity name refers to: the
+// entity of that name, else the entity with that alias, else the same two
+// ignoring ASCII case, newest entity first.
+func (s *Store) ResolveEntityName(name string) (string, error) {
+	return s.ResolveEntityNameContext(context.Background(), name)
+}
+
+// ResolveEntityNameContext is ResolveEntityName with a context.
+func (s *Store) ResolveEntityNameContext(ctx context.Context, name string) (string, error) {
+	normalized := NormalizeName(name)
+	lookups := []string{
+		`SELECT name FROM entities WHERE ` + s.nameMatch("name") + `
+			AND (is_latest = 1 OR is_latest IS NULL) ORDER BY id DESC LIMIT 1`,
+		`SELECT entity_name FROM entity_aliases WHERE alias = ?`,
+		`SELECT name FROM entities WHERE name = ? COLLATE NOCASE
+			AND (is_latest = 1 OR is_latest IS NULL) ORDER BY id DESC LIMIT 1`,
+		`SELECT entity_name FROM entity_aliases WHERE alias = ? COLLATE NOCASE ORDER BY created_at DESC LIMIT 1`,
+	}
+	for _, query := range lookups {
+		var resolved string
+		err := s.db.GetContext(ctx, &resolved, query, normalized)
+		if err == nil {
+			return resolved, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return "", err
+		}
+	}
+	return "", entityNotFound(name)
+}
+
+// ResolveOptions tunes CreateRelationResolved.
+type ResolveOptions struct {
+	// Aliases resolves endpoints with ResolveEntityName rather than by exact name.
+	Aliases bool
+	// CreateMissing creates endpoints that still aren't found as entities of
+	// type StubEntityType, rather than failing.
+	CreateMissing bool
+	// ValidFrom is when the relation holds from, as for CreateRelationValidFrom.
+	ValidFrom time.Time
+}
+
+// ResolvedRelation is the relation CreateRelationResolved created.
+type ResolvedRelation struct {
+	From    string   `json:"from"`
+	To      string   `json:"to"`
+	Created []string `json:"created,omitempty"` // Stub entities created for it
+}
+
+// CreateRelationResolved is CreateRelation finding its endpoints as opts
+// says, so a relation naming an alias or a not-yet-known entity isn't lost.
+func (s *Store) CreateRelationResolved(fromName, toName, relationType string, opts ResolveOptions) (*ResolvedRelation, error) {
+	return s.CreateRelationResolvedContext(context.Background(), fromName, toName, relationType, opts)
+}
+
+// CreateRelationResolvedContext is CreateRelationResolved with a context.
+func (s *Store) CreateRelationResolvedContext(ctx context.Context, fromName, toName, relationType string, opts ResolveOptions) (*ResolvedRelation, error) {
+	// Validate before creating stubs, so a bad relation leaves nothing behind
+	if err := ValidateRelation(fromName, toName, relationType); err != nil {
+		return nil, err
+	}
+
+	result := &ResolvedRelation{From: fromName, To: toName}
+	for _, end := range []*string{&result.From, &result.To} {
+		var err error
+		if opts.Aliases {
+			var resolved string
+			if resolved, err = s.ResolveEntityNameContext(ctx, *end); err == nil {
+				*end = resolved
+			}
+		} else {
+			_, err = s.entityID(ctx, s.db, *end)
+		}
+		if errors.Is(err, ErrNotFound) && opts.CreateMissing {
+			if _, err = s.CreateEntityContext(ctx, *end, StubEntityType, nil); err == nil {
+				result.Created = append(result.Created, NormalizeName(*end))
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.CreateRelationValidFromContext(ctx, result.From, result.To, relationType, opts.ValidFrom); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestAliases(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	store.CreateEntity("mark42", "project", nil)
+	store.CreateEntity("konfig", "project", nil)
+
+	if err := store.AddAlias("mark42", "memory plugin"); err != nil {
+		t.Fatalf("AddAlias: %v", err)
+	}
+	if err := store.AddAlias("mark42", "memory plugin"); err != nil {
+		t.Errorf("expected adding an alias again to do nothing, got %v", err)
+	}
+
+	var verr *ValidationError
+	if err := store.AddAlias("konfig", "memory plugin"); !errors.As(err, &verr) {
+		t.Errorf("expected an alias of another entity to be rejected, got %v", err)
+	}
+	if err := store.AddAlias("mark42", "konfig"); !errors.As(err, &verr) {
+		t.Errorf("expected an entity name to be rejected as an alias, got %v", err)
+	}
+	if err := store.AddAlias("nonexistent", "other"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a missing entity, got %v", err)
+	}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"mark42", "mark42"},
+		{"memory plugin", "mark42"},
+		{"MARK42", "mark42"},
+		{"Memory Plugin", "mark42"},
+		{"Konfig", "konfig"},
+	}
+	for _, tt := range tests {
+		if got, err := store.ResolveEntityName(tt.name); err != nil || got != tt.want {
+			t.Errorf("ResolveEntityName(%q) = %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+	}
+	if _, err := store.ResolveEntityName("unknown"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	aliases, err := store.ListAliases("mark42")
+	if err != nil || len(aliases) != 1 || aliases[0].Alias != "memory plugin" {
+		t.Errorf("ListAliases = %+v, %v", aliases, err)
+	}
+	if err := store.RemoveAlias("memory plugin"); err != nil {
+		t.Fatalf("RemoveAlias: %v", err)
+	}
+	if err := store.RemoveAlias("memory plugin"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound removing a removed alias, got %v", err)
+	}
+
+	store.AddAlias("konfig", "config lib")
+	store.DeleteEntity("konfig")
+	if aliases, _ := store.ListAliases(""); len(aliases) != 0 {
+		t.Errorf("expected deleting an entity to delete its aliases, got %+v", aliases)
+	}
+}
+
+func TestCreateRelationResolved(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	store.CreateEntity("mark42", "project", nil)
+	store.CreateEntity("Go", "language", nil)
+	store.AddAlias("mark42", "memory plugin")
+
+	if _, err := store.CreateRelationResolved("memory plugin", "Go", "written_in", ResolveOptions{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected an alias not to resolve without Aliases, got %v", err)
+	}
+
+	rel, err := store.CreateRelationResolved("memory plugin", "go", "written_in", ResolveOptions{Aliases: true})
+	if err != nil {
+		t.Fatalf("CreateRelationResolved: %v", err)
+	}
+	if rel.From != "mark42" || rel.To != "Go" || rel.Created != nil {
+		t.Errorf("expected endpoints resolved without stubs, got %+v", rel)
+	}
+
+	rel, err = store.CreateRelationResolved("mark42", "SQLite", "uses", ResolveOptions{CreateMissing: true})
+	if err != nil {
+		t.Fatalf("CreateRelationResolved: %v", err)
+	}
+	if !slices.Equal(rel.Created, []string{"SQLite"}) {
+		t.Errorf("expected a SQLite stub, got %+v", rel)
+	}
+	if e, err := store.GetEntity("SQLite"); err != nil || e.Type != StubEntityType {
+		t.Errorf("expected stub of type %q, got %+v, %v", StubEntityType, e, err)
+	}
+	if relations, _ := store.ListRelations("mark42"); len(relations) != 2 {
+		t.Errorf("expected 2 relations, got %+v", relations)
+	}
+
+	// An invalid relation creates no stubs
+	if _, err := store.CreateRelationResolved("Postgres", "Postgres", "is", ResolveOptions{CreateMissing: true}); err == nil {
+		t.Error("expected a self-relation to be rejected")
+	}
+	if _, err := store.GetEntity("Postgres"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected no stub for a rejected relation, got %v", err)
+	}
+}
+
+func TestMergeEntities_Aliases(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	store.CreateEntity("mark42", "project", nil)
+	store.CreateEntity("Mark-42", "project", nil)
+	store.AddAlias("Mark-42", "memory plugin")
+
+	merge, err := store.MergeEntities("mark42", "Mark-42")
+	if err != nil {
+		t.Fatalf("MergeEntities: %v", err)
+	}
+	for _, name := range []string{"Mark-42", "memory plugin"} {
+		if got, err := store.ResolveEntityName(name); err != nil || got != "mark42" {
+			t.Errorf("ResolveEntityName(%q) after merge = %q, %v; want mark42", name, got, err)
+		}
+	}
+
+	if _, err := store.UndoMerge(merge.ID); err != nil {
+		t.Fatalf("UndoMerge: %v", err)
+	}
+	aliases, _ := store.ListAliases("")
+	if len(aliases) != 1 || aliases[0].Alias != "memory plugin" || aliases[0].Entity != "Mark-42" {
+		t.Errorf("expected the alias back on Mark-42 after undo, got %+v", aliases)
+	}
+}
@@ -0,0 +1,183 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// compressArchived gzips archived content, returning "" and the compressed
+// bytes, or content and nil when compression would not make it smaller.
+func compressArchived(content string) (string, []byte) {
+	var buf bytes.Buffer
+	zw, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	zw.Write([]byte(content))
+	zw.Close()
+	if buf.Len() >= len(content) {
+		return content, nil
+	}
+	return "", buf.Bytes()
+}
+
+// decompressArchived returns archived content, decompressing gz when set.
+func decompressArchived(content string, gz []byte) (string, error) {
+	if gz == nil {
+		return content, nil
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(gz))
+	if err != nil {
+		return "", fmt.Errorf("corrupt archived content: %w", err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return "", fmt.Errorf("corrupt archived content: %w", err)
+	}
+	return string(data), nil
+}
+
+// archivedRow is an archived_observations row as stored.
+type archivedRow struct {
+	ArchivedObservation
+	ContentGz   []byte        `db:"content_gz"`
+	DigestCount sql.NullInt64 `db:"digest_count"`
+}
+
+// observation decompresses r.
+func (r archivedRow) observation() (ArchivedObservation, error) {
+	a := r.ArchivedObservation
+	content, err := decompressArchived(a.Content, r.ContentGz)
+	if err != nil {
+		return a, fmt.Errorf("archived observation %d: %w", a.ID, err)
+	}
+	a.Content = content
+	a.Digested = int(r.DigestCount.Int64)
+	return a, nil
+}
+
+const archivedColumns = `id, original_entity_id, entity_name, content, content_gz,
+	COALESCE(fact_type, 'dynamic') AS fact_type, COALESCE(importance, 1.0) AS importance,
+	archived_at, digest_count`
+
+// ListArchivedObservations returns archived observations, newest first, with
+// their content decompressed. entity limits them to one entity's, and limit
+// to that many (0 for all).
+func (s *Store) ListArchivedObservations(entity string, limit int) ([]ArchivedObservation, error) {
+	return s.ListArchivedObservationsContext(context.Background(), entity, limit)
+}
+
+// ListArchivedObservationsContext is ListArchivedObservations with a context.
+func (s *Store) ListArchivedObservationsContext(ctx context.Context, entity string, limit int) ([]ArchivedObservation, error) {
+	if limit <= 0 {
+		limit = -1 // SQLite: no limit
+	}
+	var rows []archivedRow
+	if err := s.db.SelectContext(ctx, &rows, `
+		SELECT `+archivedColumns+`
+		FROM archived_observations
+		WHERE ? = '' OR entity_name = ?
+		ORDER BY archived_at DESC, id DESC
+		LIMIT ?
+	`, entity, entity, limit); err != nil {
+		return nil, err
+	}
+
+	archived := make([]ArchivedObservation, len(rows))
+	for i, r := range rows {
+		a, err := r.observation()
+		if err != nil {
+			return nil, err
+		}
+		archived[i] = a
+	}
+	return archived, nil
+}
+
+// DigestResult reports what DigestArchive folded together.
+type DigestResult struct {
+	Digests int // Digest rows written
+	Folded  int // Archived observations they replace
+}
+
+// DigestArchive folds each entity's archived observations from every
+// calendar month that ended more than days ago into one compressed digest
+// row holding their text one per line. Only whole months are folded, so a
+// month is digested once. A month with a single observation is left alone.
+func (s *Store) DigestArchive(days int) (DigestResult, error) {
+	return s.DigestArchiveContext(context.Background(), days)
+}
+
+// DigestArchiveContext is DigestArchive with a context.
+func (s *Store) DigestArchiveContext(ctx context.Context, days int) (DigestResult, error) {
+	var result DigestResult
+	cutoff := time.Now().UTC().AddDate(0, 0, -days)
+	monthStart := time.Date(cutoff.Year(), cutoff.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+
+	var rows []archivedRow
+	if err := tx.SelectContext(ctx, &rows, `
+		SELECT `+archivedColumns+`
+		FROM archived_observations
+		WHERE digest_count IS NULL AND archived_at < ?
+		ORDER BY entity_name, archived_at, id
+	`, monthStart.Format("2006-01-02 15:04:05")); err != nil {
+		return result, err
+	}
+
+	type group struct {
+		entity, month string
+		rows          []ArchivedObservation
+	}
+	var groups []*group
+	for _, r := range rows {
+		a, err := r.observation()
+		if err != nil {
+			return result, err
+		}
+		month := a.ArchivedAt.UTC().Format("2006-01")
+		if len(groups) == 0 || groups[len(groups)-1].entity != a.EntityName || groups[len(groups)-1].month != month {
+			groups = append(groups, &group{entity: a.EntityName, month: month})
+		}
+		g := groups[len(groups)-1]
+		g.rows = append(g.rows, a)
+	}
+
+	for _, g := range groups {
+		if len(g.rows) < 2 {
+			continue
+		}
+		lines := make([]string, len(g.rows))
+		importance := 0.0
+		for i, a := range g.rows {
+			lines[i] = a.Content
+			importance = max(importance, a.Importance)
+		}
+		last := g.rows[len(g.rows)-1]
+		content, gz := compressArchived(strings.Join(lines, "\n"))
+		// The newest archive time keeps a digest from being forgotten earlier
+		// than the observations in it would have been
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO archived_observations (original_entity_id, entity_name, content, content_gz, fact_type, importance, archived_at, digest_count)
+			VALUES (?, ?, ?, ?, 'digest', ?, ?, ?)
+		`, last.OriginalEntityID, g.entity, content, gz, importance, last.ArchivedAt.UTC().Format("2006-01-02 15:04:05"), len(g.rows)); err != nil {
+			return result, err
+		}
+		for _, a := range g.rows {
+			if _, err := tx.ExecContext(ctx, "DELETE FROM archived_observations WHERE id = ?", a.ID); err != nil {
+				return result, err
+			}
+		}
+		result.Digests++
+		result.Folded += len(g.rows)
+	}
+	return result, tx.Commit()
+}
@@ -0,0 +1,116 @@
+package storage_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+func TestStore_ArchiveCompressesContent(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+
+	if err := store.Migrate(); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	long := strings.Repeat("The build uses goreleaser with a matrix of targets. ", 20)
+	store.CreateEntity("Old", "test", []string{long})
+	store.SetObservationImportance("Old", long, 0.05)
+	if _, err := store.DB().Exec(`UPDATE observations SET last_accessed = datetime('now', '-120 days')`); err != nil {
+		t.Fatalf("Failed to set old timestamp: %v", err)
+	}
+	if _, err := store.ArchiveOldMemories(storage.DefaultDecayConfig()); err != nil {
+		t.Fatalf("ArchiveOldMemories failed: %v", err)
+	}
+
+	var stored struct {
+		Content string `db:"content"`
+		Gz      []byte `db:"content_gz"`
+	}
+	if err := store.DB().Get(&stored, "SELECT content, content_gz FROM archived_observations"); err != nil {
+		t.Fatalf("Failed to read archive: %v", err)
+	}
+	if stored.Content != "" || len(stored.Gz) == 0 || len(stored.Gz) >= len(long) {
+		t.Errorf("expected compressed content, got %d plain and %d compressed bytes", len(stored.Content), len(stored.Gz))
+	}
+
+	archived, err := store.ListArchivedObservations("Old", 0)
+	if err != nil {
+		t.Fatalf("ListArchivedObservations failed: %v", err)
+	}
+	if len(archived) != 1 || archived[0].Content != long {
+		t.Fatalf("expected the original content back, got %+v", archived)
+	}
+
+	// Rolling back past the compression restores the plain text
+	if err := store.MigrateTo(22); err != nil {
+		t.Fatalf("MigrateTo(22) failed: %v", err)
+	}
+	if err := store.DB().Get(&stored, "SELECT content, content_gz FROM archived_observations"); err != nil {
+		t.Fatalf("Failed to read archive: %v", err)
+	}
+	if stored.Content != long || stored.Gz != nil {
+		t.Errorf("expected the content decompressed on rollback, got %d plain and %d compressed bytes", len(stored.Content), len(stored.Gz))
+	}
+}
+
+func TestStore_DigestArchive(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+
+	if err := store.Migrate(); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	for _, row := range []struct{ entity, content, at string }{
+		{"Old", "first", "datetime('now', '-400 days', 'start of month')"},
+		{"Old", "second", "datetime('now', '-400 days', 'start of month', '+1 day')"},
+		{"Old", "recent", "datetime('now')"},
+		{"Other", "alone", "datetime('now', '-400 days')"},
+	} {
+		if _, err := store.DB().Exec(`
+			INSERT INTO archived_observations (original_entity_id, entity_name, content, importance, archived_at)
+			VALUES (1, ?, ?, 0.05, `+row.at+`)
+		`, row.entity, row.content); err != nil {
+			t.Fatalf("Failed to insert archive: %v", err)
+		}
+	}
+
+	result, err := store.DigestArchive(180)
+	if err != nil {
+		t.Fatalf("DigestArchive failed: %v", err)
+	}
+	if result.Digests != 1 || result.Folded != 2 {
+		t.Errorf("expected 2 observations folded into 1 digest, got %+v", result)
+	}
+
+	archived, err := store.ListArchivedObservations("Old", 0)
+	if err != nil {
+		t.Fatalf("ListArchivedObservations failed: %v", err)
+	}
+	if len(archived) != 2 {
+		t.Fatalf("expected the recent observation and a digest, got %+v", archived)
+	}
+	digest := archived[1]
+	if digest.Content != "first\nsecond" || digest.Digested != 2 || digest.FactType != "digest" {
+		t.Errorf("unexpected digest %+v", digest)
+	}
+
+	if count, _ := store.GetArchiveCount(); count != 4 {
+		t.Errorf("expected the archive count to include digested observations, got %d", count)
+	}
+
+	again, err := store.DigestArchive(180)
+	if err != nil {
+		t.Fatalf("DigestArchive failed: %v", err)
+	}
+	if again.Digests != 0 {
+		t.Errorf("expected a digested month to be left alone, got %+v", again)
+	}
+
+	if err := store.MigrateTo(22); err == nil {
+		t.Error("expected rolling back the compression to refuse digested rows")
+	}
+}
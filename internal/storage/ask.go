@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// DefaultAskTokenBudget caps the evidence gathered for a question.
+const DefaultAskTokenBudget = 1500
+
+// Evidence is a memory excerpt gathered to answer a question. Ref is its
+// 1-based citation number, as in "[2]".
+type Evidence struct {
+	Ref        int     `json:"ref"`
+	EntityName string  `json:"entityName"`
+	EntityType string  `json:"entityType"`
+	Content    string  `json:"content"`
+	Score      float64 `json:"score"`
+}
+
+// GatherEvidence runs hybrid search for question and returns the best
+// observations that fit in tokenBudget, numbered for citation. A hit on an
+// entity name brings in that entity's observations. queryEmbedding may be
+// nil for keyword search only.
+func (s *Store) GatherEvidence(question string, queryEmbedding []float64, tokenBudget int) ([]Evidence, error) {
+	return s.GatherEvidenceContext(context.Background(), question, queryEmbedding, tokenBudget)
+}
+
+// GatherEvidenceContext is GatherEvidence with a context.
+func (s *Store) GatherEvidenceContext(ctx context.Context, question string, queryEmbedding []float64, tokenBudget int) ([]Evidence, error) {
+	if strings.TrimSpace(question) == "" {
+		return nil, &ValidationError{Field: "question", Reason: "is required"}
+	}
+	if tokenBudget <= 0 {
+		tokenBudget = DefaultAskTokenBudget
+	}
+
+	results, err := s.HybridSearch(ctx, questionKeywords(question), queryEmbedding, 30)
+	if err != nil {
+		return nil, err
+	}
+
+	var evidence []Evidence
+	seen := map[string]bool{}
+	used := 0
+	add := func(name, entityType, content string, score float64) bool {
+		key := name + "\x00" + content
+		if seen[key] {
+			return true
+		}
+		cost := s.CountTokens(name + ": " + content)
+		if used+cost > tokenBudget {
+			return false
+		}
+		seen[key] = true
+		used += cost
+		evidence = append(evidence, Evidence{len(evidence) + 1, name, entityType, content, score})
+		return true
+	}
+
+	for _, r := range results {
+		if r.Content != r.EntityName {
+			if !add(r.EntityName, r.EntityType, r.Content, r.FusionScore) {
+				break
+			}
+			continue
+		}
+		entity, err := s.GetEntityContext(ctx, r.EntityName)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, obs := range entity.Observations {
+			if !add(entity.Name, entity.Type, obs, r.FusionScore) {
+				break
+			}
+		}
+	}
+	return evidence, nil
+}
+
+// askStopWords are question words that would swamp keyword search.
+var askStopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "did": true, "do": true,
+	"does": true, "for": true, "how": true, "i": true, "in": true, "is": true,
+	"it": true, "of": true, "on": true, "or": true, "the": true, "to": true,
+	"was": true, "we": true, "what": true, "when": true, "where": true,
+	"which": true, "who": true, "why": true, "with": true,
+}
+
+// questionKeywords strips punctuation and question words from question,
+// leaving the terms worth searching for.
+func questionKeywords(question string) string {
+	words := strings.FieldsFunc(question, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '.' && r != '-' && r != '/'
+	})
+	var keep []string
+	for _, w := range words {
+		w = strings.Trim(w, ".-/")
+		if w != "" && !askStopWords[strings.ToLower(w)] {
+			keep = append(keep, w)
+		}
+	}
+	if len(keep) == 0 {
+		return question
+	}
+	return strings.Join(keep, " ")
+}
+
+// FormatEvidence renders evidence as numbered lines for a prompt or a reader.
+func FormatEvidence(evidence []Evidence) string {
+	var sb strings.Builder
+	for _, e := range evidence {
+		fmt.Fprintf(&sb, "[%d] %s (%s): %s\n", e.Ref, e.EntityName, e.EntityType, e.Content)
+	}
+	return sb.String()
+}
+
+const answerPrompt = "Answer the question using only the numbered memory excerpts. " +
+	"Cite the excerpts you rely on inline as [n]. If they do not answer the question, say so. " +
+	"Be brief; reply with the answer only."
+
+// Answer answers question from evidence, citing it as [n].
+func (c *SummaryClient) Answer(ctx context.Context, question string, evidence []Evidence) (string, error) {
+	if len(evidence) == 0 {
+		return "", errors.New("no evidence to answer from")
+	}
+	return c.complete(ctx, answerPrompt, "Memory excerpts:\n"+FormatEvidence(evidence)+"\nQuestion: "+question)
+}
@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGatherEvidence(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	store.CreateEntity("Parser", "component", []string{"Parser uses the Lexer for tokens", "Written by hand"})
+	store.CreateEntity("Deploy", "process", []string{"Deploys go through the staging cluster"})
+
+	evidence, err := store.GatherEvidence("What does the parser use?", nil, 0)
+	if err != nil {
+		t.Fatalf("GatherEvidence failed: %v", err)
+	}
+	if len(evidence) == 0 || evidence[0].Ref != 1 || evidence[0].EntityName != "Parser" {
+		t.Fatalf("expected Parser evidence first, got %+v", evidence)
+	}
+	contents := map[string]bool{}
+	for _, e := range evidence {
+		contents[e.Content] = true
+		if e.EntityName == "Deploy" {
+			t.Errorf("expected unrelated entities left out, got %+v", e)
+		}
+	}
+	if !contents["Written by hand"] {
+		t.Errorf("expected a name hit to bring in the entity's observations, got %+v", evidence)
+	}
+
+	limited, err := store.GatherEvidence("parser", nil, EstimateTokens("Parser: Parser uses the Lexer for tokens"))
+	if err != nil || len(limited) != 1 {
+		t.Errorf("expected the budget to cap evidence at 1, got %+v, %v", limited, err)
+	}
+
+	if _, err := store.GatherEvidence("  ", nil, 0); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("expected an empty question rejected, got %v", err)
+	}
+}
+
+func TestQuestionKeywords(t *testing.T) {
+	tests := map[string]string{
+		"What does the parser use?":       "parser use",
+		"Why did we pick main.go?":        "pick main.go",
+		"what is it?":                     "what is it?",
+		"How is auth/session.go tested?!": "auth/session.go tested",
+	}
+	for in, want := range tests {
+		if got := questionKeywords(in); got != want {
+			t.Errorf("questionKeywords(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSummaryClient_Answer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req chatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("invalid request body: %v", err)
+		}
+		if !strings.Contains(req.Messages[1].Content, "[1] Parser (component): Uses the Lexer\n") ||
+			!strings.HasSuffix(req.Messages[1].Content, "Question: What does the parser use?") {
+			t.Errorf("unexpected messages: %+v", req.Messages)
+		}
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "The Lexer [1]."}}]}`))
+	}))
+	defer server.Close()
+
+	client := NewSummaryClient(server.URL, "llama3.2")
+	evidence := []Evidence{{Ref: 1, EntityName: "Parser", EntityType: "component", Content: "Uses the Lexer"}}
+	answer, err := client.Answer(context.Background(), "What does the parser use?", evidence)
+	if err != nil || answer != "The Lexer [1]." {
+		t.Errorf("Answer() = %q, %v", answer, err)
+	}
+	if _, err := client.Answer(context.Background(), "?", nil); err == nil {
+		t.Error("expected error without evidence")
+	}
+}
@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// entityAsOfSQL selects, for each name, the newest version created at or
+// before the bound time (both ? placeholders), as an Entity.
+const entityAsOfSQL = `
+	SELECT e.id, e.name, e.entity_type, e.created_at,
+	       COALESCE(e.version, 1) as version,
+	       COALESCE(e.is_latest, 1) as is_latest,
+	       COALESCE(e.supersedes_id, 0) as supersedes_id
+	FROM entities e
+	WHERE e.created_at <= ? AND e.id = (
+		SELECT v.id FROM entities v WHERE v.name = e.name AND v.created_at <= ?
+		ORDER BY v.created_at DESC, v.id DESC LIMIT 1)`
+
+// GetEntityAsOf returns an entity as it was at the given time: the version
+// current then, with the observations it had by then. Deleted entities and
+// observations are gone from history and cannot be reconstructed.
+func (s *Store) GetEntityAsOf(name string, at time.Time) (*Entity, error) {
+	return s.GetEntityAsOfContext(context.Background(), name, at)
+}
+
+// GetEntityAsOfContext is GetEntityAsOf with a context.
+func (s *Store) GetEntityAsOfContext(ctx context.Context, name string, at time.Time) (*Entity, error) {
+	ts := sqlTime(at)
+	var entity Entity
+	err := s.db.GetContext(ctx, &entity, entityAsOfSQL+` AND `+s.nameMatch("e.name")+`
+		ORDER BY e.id DESC LIMIT 1`, ts, ts, NormalizeName(name))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, entityNotFound(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entity.Observations, err = s.loadObservationsAsOf(ctx, entity.ID, ts)
+	if err != nil {
+		return nil, err
+	}
+	return &entity, nil
+}
+
+// ReadGraphAsOf reconstructs the knowledge graph as it was at the given
+// time, from entity versions and relation validity windows.
+func (s *Store) ReadGraphAsOf(at time.Time) (*Graph, error) {
+	return s.ReadGraphAsOfContext(context.Background(), at)
+}
+
+// ReadGraphAsOfContext is ReadGraphAsOf with a context.
+func (s *Store) ReadGraphAsOfContext(ctx context.Context, at time.Time) (*Graph, error) {
+	return s.readGraphPages(ctx, GraphPageOptions{AsOf: at})
+}
+
+// readGraphPageAsOf is ReadGraphPage for opts.AsOf. Relations are matched by
+// entity name, since they stay attached to the version they were made on.
+func (s *Store) readGraphPageAsOf(ctx context.Context, opts GraphPageOptions) (*GraphPage, error) {
+	ts := sqlTime(opts.AsOf)
+	page := &GraphPage{}
+	if err := s.db.GetContext(ctx, &page.Total,
+		"SELECT COUNT(DISTINCT name) FROM entities WHERE created_at <= ?", ts); err != nil {
+		return nil, err
+	}
+
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultGraphPageSize
+	}
+	var entities []Entity
+	if err := s.db.SelectContext(ctx, &entities, entityAsOfSQL+`
+		ORDER BY e.name LIMIT ? OFFSET ?`, ts, ts, limit, opts.Offset); err != nil {
+		return nil, err
+	}
+	if len(entities) == 0 {
+		return page, nil
+	}
+
+	ids := make([]int64, len(entities))
+	names := make([]string, len(entities))
+	page.Entities = make([]*Entity, len(entities))
+	for i := range entities {
+		ids[i], names[i] = entities[i].ID, entities[i].Name
+		page.Entities[i] = &entities[i]
+	}
+	if !opts.ExcludeObservations {
+		observations, err := s.loadObservationsByEntity(ctx, ids, ts)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range page.Entities {
+			e.Observations = observations[e.ID]
+		}
+	}
+
+	// Relations valid then; with IncludeEnded, also those already ended by then
+	validity := "COALESCE(r.valid_from, r.created_at) <= ? AND (r.valid_to IS NULL OR r.valid_to > ?)"
+	validityArgs := []any{ts, ts}
+	if opts.Relations.IncludeEnded {
+		validity, validityArgs = "COALESCE(r.valid_from, r.created_at) <= ?", []any{ts}
+	}
+	seen := map[[3]string]bool{}
+	for chunk := range slices.Chunk(names, graphQueryChunk) {
+		query, args, err := sqlx.In(`
+			SELECT `+relationColumns+`
+			FROM relations r
+			JOIN entities e_from ON r.from_entity_id = e_from.id
+			JOIN entities e_to ON r.to_entity_id = e_to.id
+			WHERE e_from.name IN (?) AND `+validity+`
+			AND EXISTS (SELECT 1 FROM entities x WHERE x.name = e_to.name AND x.created_at <= ?)
+			ORDER BY e_from.name, r.created_at
+		`, append(append([]any{chunk}, validityArgs...), ts)...)
+		if err != nil {
+			return nil, err
+		}
+		var relList []Relation
+		if err := s.db.SelectContext(ctx, &relList, s.db.Rebind(query), args...); err != nil {
+			return nil, err
+		}
+		for i := range relList {
+			r := &relList[i]
+			key := [3]string{r.From, r.To, r.Type}
+			if seen[key] {
+				continue // The same relation made on several versions
+			}
+			seen[key] = true
+			page.Relations = append(page.Relations, r)
+		}
+	}
+
+	if next := opts.Offset + len(entities); next < page.Total {
+		page.NextOffset = next
+	}
+	return page, nil
+}
+
+func (s *Store) loadObservationsAsOf(ctx context.Context, entityID int64, ts string) ([]string, error) {
+	var observations []string
+	err := s.db.SelectContext(ctx, &observations,
+		"SELECT content FROM observations WHERE entity_id = ? AND created_at <= ? ORDER BY created_at",
+		entityID, ts)
+	return observations, err
+}
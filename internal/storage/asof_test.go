@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAsOf(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	backdate := func(query string, args ...any) {
+		t.Helper()
+		if _, err := store.db.Exec(query, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// konfig v1 and its relation to Go, ten days ago
+	if _, err := store.CreateEntity("konfig", "project", []string{"Loads YAML"}); err != nil {
+		t.Fatal(err)
+	}
+	store.CreateEntity("Go", "language", nil)
+	if err := store.CreateRelation("konfig", "Go", "built_with"); err != nil {
+		t.Fatal(err)
+	}
+	backdate(`UPDATE entities SET created_at = datetime('now', '-10 days')`)
+	backdate(`UPDATE observations SET created_at = datetime('now', '-10 days')`)
+	backdate(`UPDATE relations SET created_at = datetime('now', '-10 days')`)
+
+	// konfig v2 five days ago, and a library added since
+	if _, err := store.CreateOrUpdateEntity("konfig", "library", []string{"Loads YAML and TOML"}); err != nil {
+		t.Fatal(err)
+	}
+	backdate(`UPDATE entities SET created_at = datetime('now', '-5 days') WHERE is_latest = 1 AND name = 'konfig'`)
+	backdate(`UPDATE observations SET created_at = datetime('now', '-5 days') WHERE created_at > datetime('now', '-1 day')`)
+	store.CreateEntity("viper", "library", nil)
+	store.CreateRelation("konfig", "viper", "replaces")
+
+	week := time.Now().Add(-7 * 24 * time.Hour)
+	old, err := store.GetEntityAsOf("konfig", week)
+	if err != nil {
+		t.Fatalf("GetEntityAsOf: %v", err)
+	}
+	if old.Type != "project" || !reflect.DeepEqual(old.Observations, []string{"Loads YAML"}) {
+		t.Errorf("konfig a week ago = %s %v, want project [Loads YAML]", old.Type, old.Observations)
+	}
+	if current, _ := store.GetEntityAsOf("konfig", time.Now()); current == nil || current.Type != "library" {
+		t.Errorf("expected konfig as of now to be the latest version, got %+v", current)
+	}
+	if _, err := store.GetEntityAsOf("konfig", time.Now().Add(-30*24*time.Hour)); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound before konfig existed, got %v", err)
+	}
+	if _, err := store.GetEntityAsOf("viper", week); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for viper a week ago, got %v", err)
+	}
+
+	graph, err := store.ReadGraphAsOf(week)
+	if err != nil {
+		t.Fatalf("ReadGraphAsOf: %v", err)
+	}
+	var names []string
+	for _, e := range graph.Entities {
+		names = append(names, e.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"Go", "konfig"}) {
+		t.Errorf("entities a week ago = %v, want [Go konfig]", names)
+	}
+	if len(graph.Relations) != 1 || graph.Relations[0].Type != "built_with" {
+		t.Errorf("relations a week ago = %+v, want only built_with", graph.Relations)
+	}
+
+	// The relation made on v1 still belongs to konfig after the new version
+	now, err := store.ReadGraphAsOf(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(now.Entities) != 3 || len(now.Relations) != 2 {
+		t.Errorf("graph now = %d entities, %d relations; want 3 and 2", len(now.Entities), len(now.Relations))
+	}
+
+	page, err := store.ReadGraphPage(GraphPageOptions{AsOf: week, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 2 || page.NextOffset != 1 || len(page.Entities) != 1 || page.Entities[0].Name != "Go" {
+		t.Errorf("first page a week ago = %+v", page)
+	}
+}
@@ -0,0 +1,207 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AttributeKind is the type of value an attribute holds.
+type AttributeKind string
+
+const (
+	AttrString  AttributeKind = "string"
+	AttrURL     AttributeKind = "url"     // Absolute, with a scheme and host
+	AttrDate    AttributeKind = "date"    // YYYY-MM-DD
+	AttrVersion AttributeKind = "version" // 1.25, v2.0.1, 1.0.0-rc.1
+	AttrNumber  AttributeKind = "number"
+	AttrBool    AttributeKind = "bool" // Stored as true or false
+)
+
+// AttributeSchema maps the attribute keys an entity type accepts to their
+// kinds. Entity types without a schema accept any key as a string.
+type AttributeSchema map[string]AttributeKind
+
+// MaxAttributeValueLength bounds an attribute value, in bytes.
+const MaxAttributeValueLength = 2048
+
+// DefaultAttributeSchemas returns the built-in schemas for common entity types.
+func DefaultAttributeSchemas() map[string]AttributeSchema {
+	return map[string]AttributeSchema{
+		"project": {
+			"repo_url": AttrURL, "homepage": AttrURL, "language": AttrString,
+			"version": AttrVersion, "license": AttrString, "started": AttrDate,
+		},
+		"library": {
+			"version": AttrVersion, "repo_url": AttrURL, "docs_url": AttrURL,
+			"language": AttrString, "license": AttrString,
+		},
+		"service": {
+			"url": AttrURL, "repo_url": AttrURL, "version": AttrVersion,
+			"port": AttrNumber, "owner": AttrString,
+		},
+		"tool": {
+			"version": AttrVersion, "url": AttrURL,
+		},
+		"person": {
+			"role": AttrString, "email": AttrString, "url": AttrURL,
+		},
+	}
+}
+
+// SetAttributeSchemas replaces the schemas SetAttributes validates against.
+func (s *Store) SetAttributeSchemas(schemas map[string]AttributeSchema) {
+	s.attributeSchemas = schemas
+}
+
+// AttributeSchemas returns the schemas SetAttributes validates against.
+func (s *Store) AttributeSchemas() map[string]AttributeSchema {
+	return s.attributeSchemas
+}
+
+var (
+	attributeKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+	versionPattern      = regexp.MustCompile(`^v?\d+(\.\d+){0,3}([-+][0-9A-Za-z.-]+)?$`)
+)
+
+// ValidateAttribute checks key and value against schema, returning the value
+// as stored (booleans become true or false). A nil schema accepts any
+// well-formed key with a string value.
+func ValidateAttribute(schema AttributeSchema, key, value string) (string, error) {
+	field := "attribute " + key
+	if len(key) > MaxTypeLength || !attributeKeyPattern.MatchString(key) {
+		return "", &ValidationError{"attribute key", fmt.Sprintf("%q must be lower_snake_case", key)}
+	}
+	kind := AttrString
+	if schema != nil {
+		var ok bool
+		if kind, ok = schema[key]; !ok {
+			return "", &ValidationError{field, "is not in the schema; expected one of " + strings.Join(slices.Sorted(maps.Keys(schema)), ", ")}
+		}
+	}
+	value = strings.TrimSpace(value)
+	if err := validateText(field, value, MaxAttributeValueLength, true); err != nil {
+		return "", err
+	}
+
+	var bad bool
+	switch kind {
+	case AttrURL:
+		u, err := url.Parse(value)
+		bad = err != nil || u.Scheme == "" || u.Host == ""
+	case AttrDate:
+		_, err := time.Parse(time.DateOnly, value)
+		bad = err != nil
+	case AttrVersion:
+		bad = !versionPattern.MatchString(value)
+	case AttrNumber:
+		_, err := strconv.ParseFloat(value, 64)
+		bad = err != nil
+	case AttrBool:
+		b, err := strconv.ParseBool(value)
+		bad = err != nil
+		value = strconv.FormatBool(b)
+	}
+	if bad {
+		return "", &ValidationError{field, fmt.Sprintf("%q is not a valid %s", value, kind)}
+	}
+	return value, nil
+}
+
+// SetAttributes sets attributes on an entity, validated against the schema
+// for its type. An empty value removes the attribute. Either every attribute
+// is written or none is.
+func (s *Store) SetAttributes(entityName string, attrs map[string]string) error {
+	return s.SetAttributesContext(context.Background(), entityName, attrs)
+}
+
+// SetAttributesContext is SetAttributes with a context.
+func (s *Store) SetAttributesContext(ctx context.Context, entityName string, attrs map[string]string) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var id int64
+	var entityType string
+	err = tx.QueryRowContext(ctx, `SELECT id, entity_type FROM entities WHERE `+s.nameMatch("name")+`
+		AND (is_latest = 1 OR is_latest IS NULL) ORDER BY id DESC LIMIT 1`, NormalizeName(entityName)).Scan(&id, &entityType)
+	if errors.Is(err, sql.ErrNoRows) {
+		return entityNotFound(entityName)
+	}
+	if err != nil {
+		return err
+	}
+
+	schema := s.attributeSchemas[entityType]
+	for _, key := range slices.Sorted(maps.Keys(attrs)) {
+		if strings.TrimSpace(attrs[key]) == "" {
+			if _, err := tx.ExecContext(ctx, "DELETE FROM entity_attributes WHERE entity_id = ? AND key = ?", id, key); err != nil {
+				return err
+			}
+			continue
+		}
+		value, err := ValidateAttribute(schema, key, attrs[key])
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO entity_attributes (entity_id, key, value) VALUES (?, ?, ?)
+			ON CONFLICT(entity_id, key) DO UPDATE SET value = excluded.value, updated_at = CURRENT_TIMESTAMP`,
+			id, key, value); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetAttributes returns an entity's attributes; an entity without any
+// returns an empty map.
+func (s *Store) GetAttributes(entityName string) (map[string]string, error) {
+	return s.GetAttributesContext(context.Background(), entityName)
+}
+
+// GetAttributesContext is GetAttributes with a context.
+func (s *Store) GetAttributesContext(ctx context.Context, entityName string) (map[string]string, error) {
+	id, err := s.entityID(ctx, s.db, entityName)
+	if err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		Key   string `db:"key"`
+		Value string `db:"value"`
+	}
+	if err := s.db.SelectContext(ctx, &rows, "SELECT key, value FROM entity_attributes WHERE entity_id = ?", id); err != nil {
+		return nil, err
+	}
+	attrs := make(map[string]string, len(rows))
+	for _, r := range rows {
+		attrs[r.Key] = r.Value
+	}
+	return attrs, nil
+}
+
+// attributeFilterSQL returns a condition on column (an entity id) that holds
+// when the entity has every attribute in attrs; values match ignoring case.
+func attributeFilterSQL(column string, attrs map[string]string) (string, []any) {
+	var conds []string
+	var args []any
+	for _, key := range slices.Sorted(maps.Keys(attrs)) {
+		conds = append(conds, `EXISTS (SELECT 1 FROM entity_attributes a
+			WHERE a.entity_id = `+column+` AND a.key = ? AND a.value = ? COLLATE NOCASE)`)
+		args = append(args, key, strings.TrimSpace(attrs[key]))
+	}
+	if len(conds) == 0 {
+		return "1", nil
+	}
+	return strings.Join(conds, " AND "), args
+}
@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestValidateAttribute(t *testing.T) {
+	schema := DefaultAttributeSchemas()["service"]
+	schema["public"] = AttrBool
+	schema["launched"] = AttrDate
+
+	tests := []struct {
+		schema  AttributeSchema
+		key     string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{schema, "url", "https://api.example.com", "https://api.example.com", false},
+		{schema, "url", "api.example.com", "", true},
+		{schema, "version", " v2.0.1-rc.1 ", "v2.0.1-rc.1", false},
+		{schema, "version", "latest", "", true},
+		{schema, "port", "8080", "8080", false},
+		{schema, "port", "eighty", "", true},
+		{schema, "public", "yes", "", true},
+		{schema, "public", "1", "true", false},
+		{schema, "launched", "2026-03-01", "2026-03-01", false},
+		{schema, "launched", "March 2026", "", true},
+		{schema, "colour", "blue", "", true},
+		{nil, "colour", "blue", "blue", false},
+		{nil, "Colour", "blue", "", true},
+		{nil, "notes", "line\nbreak", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ValidateAttribute(tt.schema, tt.key, tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ValidateAttribute(%s=%q) = %q, %v", tt.key, tt.value, got, err)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("expected ErrInvalidInput, got %v", err)
+		}
+	}
+}
+
+func TestAttributes(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	store.CreateEntity("mark42", "project", []string{"Memory for coding agents"})
+	store.CreateEntity("Ollama", "tool", nil)
+
+	if err := store.SetAttributes("mark42", map[string]string{"repo_url": "https://github.com/mfenderov/mark42", "language": "Go"}); err != nil {
+		t.Fatalf("SetAttributes failed: %v", err)
+	}
+	if err := store.SetAttributes("mark42", map[string]string{"language": "Go", "version": "not a version"}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("expected an invalid version rejected, got %v", err)
+	}
+	if err := store.SetAttributes("missing", map[string]string{"language": "Go"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	got, err := store.GetAttributes("mark42")
+	want := map[string]string{"repo_url": "https://github.com/mfenderov/mark42", "language": "Go"}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("GetAttributes() = %v, %v; want %v (a failed set writes nothing)", got, err, want)
+	}
+
+	// A new version takes over the attributes; an empty value removes one
+	if _, err := store.CreateOrUpdateEntity("mark42", "project", []string{"Memory for coding agents"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetAttributes("mark42", map[string]string{"repo_url": ""}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := store.GetAttributes("mark42"); !reflect.DeepEqual(got, map[string]string{"language": "Go"}) {
+		t.Errorf("expected language kept across versions and repo_url removed, got %v", got)
+	}
+
+	results, err := store.SearchWithOptions("memory", SearchOptions{Limit: 10, Attributes: map[string]string{"language": "go"}})
+	if err != nil || len(results) != 1 || results[0].Name != "mark42" {
+		t.Errorf("expected the attribute filter to match ignoring case, got %+v, %v", results, err)
+	}
+	results, _ = store.SearchWithOptions("memory", SearchOptions{Limit: 10, Attributes: map[string]string{"language": "Rust"}})
+	if len(results) != 0 {
+		t.Errorf("expected no match for another language, got %+v", results)
+	}
+	results, err = store.SearchWithOptions("", SearchOptions{Limit: 10, Attributes: map[string]string{"language": "Go"}})
+	if err != nil || len(results) != 1 || len(results[0].Observations) != 1 {
+		t.Errorf("expected a query-less attribute search to list mark42, got %+v, %v", results, err)
+	}
+}
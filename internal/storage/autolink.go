@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"unicode/utf8"
+)
+
+// DefaultAutoLinkMinNameLength is the shortest entity name, in runes, that
+// auto-linking matches unless configured otherwise.
+const DefaultAutoLinkMinNameLength = minCaseInsensitiveName
+
+// SetAutoLinkMentions makes observation writes relate the entity to the other
+// entities a new observation names, as entity mentions other. Names shorter
+// than minNameLength runes are never matched, since short names ("Go", "UI")
+// are also ordinary words; 0 turns linking off, the default. Sessions are
+// neither linked from nor to, and pairs already related either way are left
+// as they are.
+func (s *Store) SetAutoLinkMentions(minNameLength int) {
+	s.autoLinkMinName = minNameLength
+}
+
+// addedObservation is an observation a write added, for observationsAdded.
+type addedObservation struct {
+	entityID int64
+	content  string
+}
+
+// observationsAdded runs what follows a write that added observations, in
+// its transaction: PII tagging, policies, then mention linking.
+func (s *Store) observationsAdded(ctx context.Context, q interface {
+	ExecContext(context.Context, string, ...any) (sql.Result, error)
+	QueryContext(context.Context, string, ...any) (*sql.Rows, error)
+}, added []addedObservation) error {
+	if err := s.tagPII(ctx, q, added); err != nil {
+		return err
+	}
+	if err := s.applyPoliciesOnWrite(ctx, q, added); err != nil {
+		return err
+	}
+	return s.linkMentions(ctx, q, added)
+}
+
+// linkMentions creates the mentions relations SetAutoLinkMentions asks for
+// for observations just added. It runs in the write's transaction so names
+// of entities created in the same write are found.
+func (s *Store) linkMentions(ctx context.Context, q interface {
+	ExecContext(context.Context, string, ...any) (sql.Result, error)
+	QueryContext(context.Context, string, ...any) (*sql.Rows, error)
+}, added []addedObservation) error {
+	if s.autoLinkMinName <= 0 || len(added) == 0 {
+		return nil
+	}
+
+	rows, err := q.QueryContext(ctx, `
+		SELECT id, name, entity_type FROM entities
+		WHERE is_latest = 1 OR is_latest IS NULL`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	index := newNameIndex()
+	types := map[int64]string{}
+	for rows.Next() {
+		var id int64
+		var name, entityType string
+		if err := rows.Scan(&id, &name, &entityType); err != nil {
+			return err
+		}
+		types[id] = entityType
+		if entityType != "session" && utf8.RuneCountInString(name) >= s.autoLinkMinName {
+			index.add(id, name)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	for _, obs := range added {
+		if types[obs.entityID] == "session" {
+			continue
+		}
+		for _, id := range index.find(obs.content, obs.entityID) {
+			// Links are a convenience; one the rules forbid is not made
+			if s.rules.relationViolation(RelationMentions, types[obs.entityID], types[id]) != "" {
+				continue
+			}
+			if _, err := q.ExecContext(ctx, `
+				INSERT INTO relations (from_entity_id, to_entity_id, relation_type)
+				SELECT ?, ?, ?
+				WHERE NOT EXISTS (SELECT 1 FROM relations
+					WHERE (from_entity_id = ? AND to_entity_id = ?) OR (from_entity_id = ? AND to_entity_id = ?))`,
+				obs.entityID, id, RelationMentions, obs.entityID, id, id, obs.entityID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
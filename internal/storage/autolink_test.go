@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"testing"
+)
+
+func TestAutoLinkMentions(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	store.CreateEntity("mark42", "project", nil)
+	store.CreateEntity("SQLite", "database", nil)
+	store.CreateEntity("Go", "language", nil)
+
+	// Off by default
+	store.AddObservation("mark42", "Stores memories in SQLite")
+	if relations, _ := store.ListRelations("mark42"); len(relations) != 0 {
+		t.Fatalf("expected no links while off, got %+v", relations)
+	}
+
+	store.SetAutoLinkMentions(DefaultAutoLinkMinNameLength)
+	store.AddObservation("mark42", "Uses sqlite with WAL; written in Go")
+	relations, _ := store.ListRelations("mark42")
+	if len(relations) != 1 || relations[0].To != "SQLite" || relations[0].Type != RelationMentions {
+		t.Errorf("expected mark42 mentions SQLite only (Go is too short), got %+v", relations)
+	}
+
+	// Entities created in the same batch are found, and existing pairs left alone
+	store.CreateRelation("SQLite", "Go", "written_in")
+	if _, err := store.CreateEntities([]EntitySpec{
+		{Name: "modernc", Type: "library", Observations: []string{"Pure Go SQLite driver used by mark42"}},
+		{Name: "Turso", Type: "service", Observations: []string{"Hosted libSQL, an alternative to modernc"}},
+	}); err != nil {
+		t.Fatalf("CreateEntities: %v", err)
+	}
+	for name, want := range map[string]int{"modernc": 3, "Turso": 1, "SQLite": 3} {
+		if relations, _ := store.ListRelations(name); len(relations) != want {
+			t.Errorf("expected %d relations for %s, got %+v", want, name, relations)
+		}
+	}
+
+	store.SetAutoLinkMentions(2)
+	store.AddObservation("Turso", "Client written in Go")
+	if relations, _ := store.ListRelations("Go"); len(relations) != 2 {
+		t.Errorf("expected Go linked once the minimum allows it, got %+v", relations)
+	}
+
+	// Sessions neither link nor are linked
+	store.CreateEntity("session-1", "session", nil)
+	store.AddObservation("session-1", "Worked on mark42")
+	if relations, _ := store.ListRelations("session-1"); len(relations) != 0 {
+		t.Errorf("expected no links from a session, got %+v", relations)
+	}
+}
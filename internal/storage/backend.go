@@ -0,0 +1,154 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Backend is the core memory API both database engines implement: the
+// SQLite Store, for personal and libSQL databases, and PostgresStore, for a
+// memory service many agents share. Versioning, decay, sessions, and the
+// other features beyond it are SQLite-only.
+type Backend interface {
+	CreateEntityContext(ctx context.Context, name, entityType string, observations []string) (*Entity, error)
+	GetEntityContext(ctx context.Context, name string) (*Entity, error)
+	ListEntitiesContext(ctx context.Context, entityType string) ([]*Entity, error)
+	DeleteEntityContext(ctx context.Context, name string) error
+	AddObservationContext(ctx context.Context, entityName, content string) error
+	DeleteObservationContext(ctx context.Context, entityName, content string) error
+	CreateRelationContext(ctx context.Context, fromName, toName, relationType string) error
+	ListRelationsContext(ctx context.Context, entityName string) ([]*Relation, error)
+	SearchContext(ctx context.Context, query string) ([]*SearchResult, error)
+	StoreEmbeddingContext(ctx context.Context, observationID int64, embedding []float64, model string) error
+	VectorSearchContext(ctx context.Context, queryEmbedding []float64, limit int) ([]VectorResult, error)
+	GetContextForInjectionContext(ctx context.Context, cfg ContextConfig, projectName string) ([]ContextResult, error)
+	SetTokenizer(t Tokenizer)
+	Close() error
+}
+
+var (
+	_ Backend = (*Store)(nil)
+	_ Backend = (*PostgresStore)(nil)
+)
+
+// OpenBackend opens the database at dsn with the engine it names: Postgres
+// for postgres:// URLs, SQLite otherwise, migrating its schema either way.
+func OpenBackend(dsn string) (Backend, error) {
+	if IsPostgresDSN(dsn) {
+		return NewPostgresStore(dsn)
+	}
+	store, err := NewStore(dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := store.Migrate(); err != nil {
+		store.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+// libsqlDriver is the database/sql driver name registered by the libSQL
+// client, compiled in with the libsql build tag (see driver_libsql.go).
+const libsqlDriver = "libsql"
+
+// ErrLibSQLUnavailable is returned for libsql:// DSNs when the binary was
+// built without the libSQL driver.
+var ErrLibSQLUnavailable = errors.New("libsql:// databases need a build with -tags libsql")
+
+// IsRemoteDSN reports whether path names a hosted libSQL/Turso database
+// rather than a local SQLite file.
+func IsRemoteDSN(path string) bool {
+	return strings.HasPrefix(strings.ToLower(path), "libsql://")
+}
+
+// IsLocalPath reports whether dsn names a local SQLite file, rather than a
+// hosted libSQL or Postgres database, so its directory can be created.
+func IsLocalPath(dsn string) bool {
+	return dsn != InMemory && !IsRemoteDSN(dsn) && !IsPostgresDSN(dsn)
+}
+
+// libsqlDSN adds the auth token from LIBSQL_AUTH_TOKEN or TURSO_AUTH_TOKEN
+// when the DSN does not carry one, so tokens can stay out of config files.
+func libsqlDSN(dsn string) string {
+	u, err := url.Parse(dsn)
+	if err != nil || u.Query().Get("authToken") != "" {
+		return dsn
+	}
+	token := os.Getenv("LIBSQL_AUTH_TOKEN")
+	if token == "" {
+		token = os.Getenv("TURSO_AUTH_TOKEN")
+	}
+	if token == "" {
+		return dsn
+	}
+	q := u.Query()
+	q.Set("authToken", token)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// Remote reports whether the store runs against a hosted libSQL database.
+// Remote stores skip vector search, which would load every embedding over
+// the network; embeddings are still stored.
+func (s *Store) Remote() bool {
+	return s.remote
+}
+
+// FTSEnabled reports whether the FTS5 indexes are available. Hosted libSQL
+// servers without FTS5 fall back to substring matching.
+func (s *Store) FTSEnabled() bool {
+	return s.fts
+}
+
+// observationMatchSQL returns a subquery of matching observations with
+// columns id and score (lower is better, as with bm25), and its arguments.
+// Stop words are dropped from the query first.
+func (s *Store) observationMatchSQL(query string) (string, []any) {
+	query = s.removeStopWords(query)
+	if s.trigram && hasCJK(query) {
+		return trigramMatchSQL("observations_trigram", "observations", "content", query)
+	}
+	if s.fts {
+		return `SELECT rowid AS id, bm25(observations_fts) AS score
+			FROM observations_fts WHERE observations_fts MATCH ?`, []any{prepareFTSQuery(query)}
+	}
+	return likeMatchSQL("observations", "content", query)
+}
+
+// entityMatchSQL is observationMatchSQL for entity names.
+func (s *Store) entityMatchSQL(query string) (string, []any) {
+	query = s.removeStopWords(query)
+	if s.trigram && hasCJK(query) {
+		return trigramMatchSQL("entities_trigram", "entities", "name", query)
+	}
+	if s.fts {
+		return `SELECT rowid AS id, bm25(entities_fts) AS score
+			FROM entities_fts WHERE entities_fts MATCH ?`, []any{prepareFTSQuery(query)}
+	}
+	return likeMatchSQL("entities", "name", query)
+}
+
+// likeMatchSQL matches rows containing any word of the query, scoring by the
+// number of words matched.
+func likeMatchSQL(table, column, query string) (string, []any) {
+	words := strings.Fields(query)
+	if len(words) == 0 {
+		return "SELECT id, 0.0 AS score FROM " + table + " WHERE 0", nil
+	}
+
+	var terms []string
+	var args []any
+	for _, word := range words {
+		terms = append(terms, "("+column+` LIKE ? ESCAPE '\')`)
+		args = append(args, "%"+likeEscaper.Replace(word)+"%")
+	}
+	sum := strings.Join(terms, " + ")
+	return "SELECT id, -(" + sum + ") AS score FROM " + table + " WHERE " + strings.Join(terms, " OR "),
+		append(args, args...)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestIsRemoteDSN(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"libsql://memory-team.turso.io", true},
+		{"LIBSQL://memory-team.turso.io", true},
+		{"/home/me/.claude/memory.db", false},
+		{"file:memory.db", false},
+	}
+	for _, tt := range tests {
+		if got := IsRemoteDSN(tt.path); got != tt.want {
+			t.Errorf("IsRemoteDSN(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestLibsqlDSN_AuthToken(t *testing.T) {
+	t.Setenv("LIBSQL_AUTH_TOKEN", "")
+	t.Setenv("TURSO_AUTH_TOKEN", "secret")
+
+	if got := libsqlDSN("libsql://db.turso.io"); got != "libsql://db.turso.io?authToken=secret" {
+		t.Errorf("expected token from TURSO_AUTH_TOKEN, got %q", got)
+	}
+	if got := libsqlDSN("libsql://db.turso.io?authToken=own"); got != "libsql://db.turso.io?authToken=own" {
+		t.Errorf("expected DSN token to win, got %q", got)
+	}
+}
+
+func TestNewStore_LibSQLWithoutDriver(t *testing.T) {
+	if slices.Contains(sql.Drivers(), libsqlDriver) {
+		t.Skip("built with -tags libsql")
+	}
+	_, err := NewStore("libsql://memory-team.turso.io")
+	if !errors.Is(err, ErrLibSQLUnavailable) {
+		t.Errorf("expected ErrLibSQLUnavailable, got %v", err)
+	}
+}
+
+func TestSearch_WithoutFTS(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+	store.fts = false // as on a hosted server without FTS5
+
+	store.CreateEntity("Go", "language", []string{"Uses 100% static binaries", "Fast compiler"})
+	store.CreateEntity("Rust", "language", []string{"Borrow checker"})
+	store.CreateEntity("Compiler Notes", "note", nil)
+
+	results, err := store.Search("compiler")
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected Go and Compiler Notes, got %d results", len(results))
+	}
+
+	results, _ = store.Search("100%")
+	if len(results) != 1 || results[0].Name != "Go" {
+		t.Errorf("expected literal %% match on Go, got %v", results)
+	}
+
+	results, _ = store.Search("borrow fast")
+	if len(results) != 2 {
+		t.Errorf("expected any-word match, got %d results", len(results))
+	}
+
+	fused, err := store.HybridSearch(context.Background(), "checker", nil, 10)
+	if err != nil || len(fused) != 1 || fused[0].EntityName != "Rust" {
+		t.Errorf("HybridSearch without FTS = %v, %v", fused, err)
+	}
+}
+
+func TestHybridSearch_RemoteSkipsVectors(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	store.CreateEntity("Go", "language", []string{"Fast compiler"})
+	obs, _ := store.GetObservationsWithoutEmbeddings()
+	store.StoreEmbedding(obs[0].ID, []float64{1, 0}, "test")
+
+	store.remote = true
+	results, err := store.HybridSearch(context.Background(), "", []float64{1, 0}, 10)
+	if err != nil {
+		t.Fatalf("HybridSearch failed: %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("expected vector search skipped on remote store, got %v", results)
+	}
+}
+
+func TestIsPostgresDSN(t *testing.T) {
+	tests := []struct {
+		dsn  string
+		want bool
+	}{
+		{"postgres://memory@db.internal/mark42", true},
+		{"postgresql://memory@db.internal/mark42?sslmode=require", true},
+		{"/home/me/.claude/memory.db", false},
+		{"libsql://memory-team.turso.io", false},
+	}
+	for _, tt := range tests {
+		if got := IsPostgresDSN(tt.dsn); got != tt.want {
+			t.Errorf("IsPostgresDSN(%q) = %v, want %v", tt.dsn, got, tt.want)
+		}
+	}
+}
+
+func TestOpenBackend(t *testing.T) {
+	backend, err := OpenBackend(InMemory)
+	if err != nil {
+		t.Fatalf("OpenBackend failed: %v", err)
+	}
+	defer backend.Close()
+	if _, ok := backend.(*Store); !ok {
+		t.Errorf("expected a SQLite store, got %T", backend)
+	}
+
+	if !slices.Contains(sql.Drivers(), postgresDriver) {
+		if _, err := OpenBackend("postgres://memory@db.internal/mark42"); !errors.Is(err, ErrPostgresUnavailable) {
+			t.Errorf("expected ErrPostgresUnavailable, got %v", err)
+		}
+	}
+}
+
+func TestNewStore_PostgresDSN(t *testing.T) {
+	dsn := "postgres://memory@db.internal/mark42"
+	if _, err := NewStore(dsn); !errors.Is(err, ErrPostgresStore) {
+		t.Errorf("expected ErrPostgresStore, got %v", err)
+	}
+	if _, err := NewStoreReadOnly(dsn); !errors.Is(err, ErrPostgresStore) {
+		t.Errorf("expected ErrPostgresStore read-only, got %v", err)
+	}
+	for path, want := range map[string]bool{
+		"/home/me/.claude/memory.db": true,
+		InMemory:                     false,
+		"libsql://db.turso.io":       false,
+		dsn:                          false,
+	} {
+		if got := IsLocalPath(path); got != want {
+			t.Errorf("IsLocalPath(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultBackupInterval is how often RunContinuousBackup looks for changes.
+const DefaultBackupInterval = 15 * time.Minute
+
+// BackupTo writes a consistent copy of the database to path, replacing the
+// copy there only once the new one is complete, so a crash mid-backup leaves
+// the previous copy intact.
+func (s *Store) BackupTo(path string) error {
+	return s.BackupToContext(context.Background(), path)
+}
+
+// BackupToContext is BackupTo with a context.
+func (s *Store) BackupToContext(ctx context.Context, path string) error {
+	if s.remote {
+		return &ValidationError{Field: "backup", Reason: "hosted databases are backed up by their server"}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("creating backup directory: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.Remove(tmp); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if _, err := s.db.ExecContext(ctx, "VACUUM INTO ?", tmp); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("writing backup: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// RunContinuousBackup copies the database to path now and then every
+// interval in which it changed, judged by the size and modification time of
+// the database file and its write-ahead log, until ctx is done; a last copy
+// is made then if anything changed. Failed copies are reported to onError
+// and retried on the next tick.
+func (s *Store) RunContinuousBackup(ctx context.Context, path string, interval time.Duration, onError func(error)) {
+	last := ""
+	backup := func() {
+		// The context may be done already; the last copy still runs
+		state, _, err := s.BackupIfChangedContext(context.WithoutCancel(ctx), path, last)
+		if err != nil {
+			onError(err)
+			return
+		}
+		last = state
+	}
+
+	backup()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			backup()
+			return
+		case <-ticker.C:
+			backup()
+		}
+	}
+}
+
+// BackupIfChanged copies the database to path like BackupTo unless its files
+// are still in state, a state it returned before ("" for none). It returns
+// their state as of the copy, or state unchanged when it failed, and whether
+// it copied.
+func (s *Store) BackupIfChanged(path, state string) (string, bool, error) {
+	return s.BackupIfChangedContext(context.Background(), path, state)
+}
+
+// BackupIfChangedContext is BackupIfChanged with a context.
+func (s *Store) BackupIfChangedContext(ctx context.Context, path, state string) (string, bool, error) {
+	now := s.fileState()
+	if now == state {
+		return state, false, nil
+	}
+	if err := s.BackupToContext(ctx, path); err != nil {
+		return state, false, err
+	}
+	return now, true, nil
+}
+
+// fileState summarizes the database file and its write-ahead log, so a
+// change to either changes it.
+func (s *Store) fileState() string {
+	state := ""
+	for _, name := range []string{s.path, s.path + "-wal"} {
+		if info, err := os.Stat(name); err == nil {
+			state += fmt.Sprintf("%d@%d;", info.Size(), info.ModTime().UnixNano())
+		}
+	}
+	return state
+}
+
+// BackupCount is a table's row count in the database and in a backup.
+type BackupCount struct {
+	Table  string `json:"table"`
+	Live   int    `json:"live"`
+	Backup int    `json:"backup"`
+}
+
+// BackupCheck is the result of VerifyBackup.
+type BackupCheck struct {
+	Path      string        `json:"path"`
+	Integrity string        `json:"integrity"` // "ok", or the first problem found
+	Counts    []BackupCount `json:"counts"`
+}
+
+// OK reports whether the backup passed its integrity check and holds as
+// many rows as the database.
+func (c *BackupCheck) OK() bool {
+	if c.Integrity != "ok" {
+		return false
+	}
+	for _, count := range c.Counts {
+		if count.Live != count.Backup {
+			return false
+		}
+	}
+	return true
+}
+
+// backupTables are the tables VerifyBackup compares.
+var backupTables = []string{"entities", "observations", "relations", "entity_attributes", "archived_observations"}
+
+// VerifyBackup opens the backup at path read-only, checks its integrity, and
+// compares its row counts with the database's. Writes since the backup was
+// taken show as differences.
+func (s *Store) VerifyBackup(path string) (*BackupCheck, error) {
+	return s.VerifyBackupContext(context.Background(), path)
+}
+
+// VerifyBackupContext is VerifyBackup with a context.
+func (s *Store) VerifyBackupContext(ctx context.Context, path string) (*BackupCheck, error) {
+	backup, err := NewStoreReadOnly(path)
+	if err != nil {
+		return nil, err
+	}
+	defer backup.Close()
+
+	check := &BackupCheck{Path: path}
+	if err := backup.db.GetContext(ctx, &check.Integrity, "PRAGMA quick_check"); err != nil {
+		return nil, fmt.Errorf("checking backup integrity: %w", err)
+	}
+	for _, table := range backupTables {
+		count := BackupCount{Table: table}
+		if err := s.db.GetContext(ctx, &count.Live, "SELECT COUNT(*) FROM "+table); err != nil {
+			continue // Not migrated yet
+		}
+		if err := backup.db.GetContext(ctx, &count.Backup, "SELECT COUNT(*) FROM "+table); err != nil {
+			count.Backup = -1 // Missing from the backup
+		}
+		check.Counts = append(check.Counts, count)
+	}
+	return check, nil
+}
@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBackupTo_Verify(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+	store.CreateEntity("Go", "language", []string{"Fast compiler"})
+
+	path := filepath.Join(t.TempDir(), "replica", "memory.db")
+	if err := store.BackupTo(path); err != nil {
+		t.Fatalf("BackupTo failed: %v", err)
+	}
+	check, err := store.VerifyBackup(path)
+	if err != nil {
+		t.Fatalf("VerifyBackup failed: %v", err)
+	}
+	if !check.OK() || check.Integrity != "ok" || len(check.Counts) != len(backupTables) {
+		t.Errorf("expected a matching backup, got %+v", check)
+	}
+
+	store.CreateEntity("Rust", "language", nil)
+	if check, _ := store.VerifyBackup(path); check.OK() || check.Counts[0] != (BackupCount{"entities", 2, 1}) {
+		t.Errorf("expected the new entity to show as a difference, got %+v", check)
+	}
+	if err := store.BackupTo(path); err != nil {
+		t.Fatalf("second BackupTo failed: %v", err)
+	}
+	if check, _ := store.VerifyBackup(path); !check.OK() {
+		t.Errorf("expected the replaced backup to match, got %+v", check)
+	}
+}
+
+func TestRunContinuousBackup(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	path := filepath.Join(t.TempDir(), "memory.db")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		store.RunContinuousBackup(ctx, path, time.Hour, func(err error) { t.Errorf("backup failed: %v", err) })
+		close(done)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if check, err := store.VerifyBackup(path); err == nil && check.OK() {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected a first backup at start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Changes since the last copy are copied on the way out
+	store.CreateEntity("Go", "language", []string{"Fast compiler"})
+	cancel()
+	<-done
+	if check, err := store.VerifyBackup(path); err != nil || !check.OK() {
+		t.Errorf("expected the last change backed up, got %+v, %v", check, err)
+	}
+}
+
+func TestBackupIfChanged(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+	path := filepath.Join(t.TempDir(), "memory.db")
+
+	state, copied, err := store.BackupIfChanged(path, "")
+	if err != nil || !copied || state == "" {
+		t.Fatalf("expected a first copy, got %q, %v, %v", state, copied, err)
+	}
+	if again, copied, err := store.BackupIfChanged(path, state); err != nil || copied || again != state {
+		t.Errorf("expected no copy of an unchanged database, got %q, %v, %v", again, copied, err)
+	}
+
+	store.CreateEntity("Go", "language", []string{"Fast compiler"})
+	if _, copied, err := store.BackupIfChanged(path, state); err != nil || !copied {
+		t.Errorf("expected the change copied, got %v, %v", copied, err)
+	}
+	if check, err := store.VerifyBackup(path); err != nil || !check.OK() {
+		t.Errorf("expected the backup to match, got %+v, %v", check, err)
+	}
+}
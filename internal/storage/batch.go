@@ -0,0 +1,176 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// EntitySpec describes one entity for CreateEntities.
+type EntitySpec struct {
+	Name         string
+	Type         string
+	Observations []string
+}
+
+// EntityResult reports what CreateEntities did with one spec.
+type EntityResult struct {
+	Name    string
+	Created bool  // False when the entity already existed
+	Added   int   // Observations that were new
+	Err     error // A ValidationError when the spec was rejected; other specs still apply
+}
+
+// ObservationSpec describes one observation for AddObservationsBatch.
+// An empty FactType stores a dynamic fact.
+type ObservationSpec struct {
+	EntityName string
+	Content    string
+	FactType   FactType
+}
+
+// ObservationResult reports what AddObservationsBatch did with one spec.
+type ObservationResult struct {
+	EntityName string
+	Content    string
+	Added      bool  // False for duplicates and rejected specs
+	Err        error // ErrNotFound when the entity does not exist, or a ValidationError
+}
+
+// CreateEntities creates entities and their observations in one transaction.
+// Observations for entities that already exist are added to them, as the
+// MCP create_entities tool promises. Results are in spec order.
+func (s *Store) CreateEntities(specs []EntitySpec) ([]EntityResult, error) {
+	return s.CreateEntitiesContext(context.Background(), specs)
+}
+
+// CreateEntitiesContext is CreateEntities with a context.
+func (s *Store) CreateEntitiesContext(ctx context.Context, specs []EntitySpec) ([]EntityResult, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	results := make([]EntityResult, len(specs))
+	var added []addedObservation
+	for i, spec := range specs {
+		spec.Name = NormalizeName(spec.Name)
+		spec.Type = s.CanonicalEntityType(spec.Type)
+		spec.Observations = s.redactAll(ctx, spec.Name, spec.Observations)
+		results[i].Name = spec.Name
+		if err := validateEntityWithObservations(spec.Name, spec.Type, spec.Observations); err != nil {
+			results[i].Err = err
+			continue
+		}
+		if err := s.checkObservations(ctx, spec.Name, spec.Type, spec.Observations, FactTypeDynamic); err != nil {
+			results[i].Err = err
+			continue
+		}
+
+		id, err := s.entityID(ctx, tx, spec.Name)
+		if errors.Is(err, ErrNotFound) {
+			result, err := tx.ExecContext(ctx, "INSERT INTO entities (name, entity_type, owner) VALUES (?, ?, ?)", spec.Name, spec.Type, s.userValue())
+			if err != nil {
+				return nil, err
+			}
+			if id, err = result.LastInsertId(); err != nil {
+				return nil, err
+			}
+			results[i].Created = true
+		} else if err != nil {
+			return nil, err
+		}
+
+		for _, content := range spec.Observations {
+			ok, err := s.insertObservation(ctx, tx, id, content, FactTypeDynamic)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				results[i].Added++
+				added = append(added, addedObservation{id, content})
+			}
+		}
+	}
+
+	if err := s.observationsAdded(ctx, tx, added); err != nil {
+		return nil, err
+	}
+	return results, tx.Commit()
+}
+
+// AddObservationsBatch adds observations in one transaction. Duplicates are
+// ignored and observations for unknown entities are rejected per item.
+// Results are in spec order.
+func (s *Store) AddObservationsBatch(specs []ObservationSpec) ([]ObservationResult, error) {
+	return s.AddObservationsBatchContext(context.Background(), specs)
+}
+
+// AddObservationsBatchContext is AddObservationsBatch with a context.
+func (s *Store) AddObservationsBatchContext(ctx context.Context, specs []ObservationSpec) ([]ObservationResult, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	entityIDs := map[string]int64{}
+	results := make([]ObservationResult, len(specs))
+	var added []addedObservation
+	for i, spec := range specs {
+		spec.Content = s.redact(ctx, spec.EntityName, spec.Content)
+		results[i] = ObservationResult{EntityName: spec.EntityName, Content: spec.Content}
+		if err := ValidateObservation(spec.Content); err != nil {
+			results[i].Err = err
+			continue
+		}
+
+		id, ok := entityIDs[spec.EntityName]
+		if !ok {
+			id, err = s.entityID(ctx, tx, spec.EntityName)
+			if errors.Is(err, ErrNotFound) {
+				results[i].Err = err
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			entityIDs[spec.EntityName] = id
+		}
+
+		factType := spec.FactType
+		if factType == "" {
+			factType = FactTypeDynamic
+		}
+		if err := s.checkObservation(ctx, tx, id, spec.Content, factType); errors.Is(err, ErrInvalidInput) {
+			results[i].Err = err
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if results[i].Added, err = s.insertObservation(ctx, tx, id, spec.Content, factType); err != nil {
+			return nil, err
+		}
+		if results[i].Added {
+			added = append(added, addedObservation{id, spec.Content})
+		}
+	}
+
+	if err := s.observationsAdded(ctx, tx, added); err != nil {
+		return nil, err
+	}
+	return results, tx.Commit()
+}
+
+// insertObservation adds an observation unless the entity already has it.
+func (s *Store) insertObservation(ctx context.Context, tx *sqlx.Tx, entityID int64, content string, factType FactType) (bool, error) {
+	result, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO observations (entity_id, content, content_hash, fact_type, source, author) VALUES (?, ?, ?, ?, ?, ?)",
+		entityID, content, contentHash(content), string(factType), s.sourceFor(ctx), s.userValue())
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
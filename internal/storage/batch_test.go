@@ -0,0 +1,78 @@
+package storage_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+func TestCreateEntities(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+
+	store.CreateEntity("Go", "language", []string{"Fast compiler"})
+
+	results, err := store.CreateEntities([]storage.EntitySpec{
+		{Name: "Rust", Type: "language", Observations: []string{"Borrow checker"}},
+		{Name: "Go", Type: "language", Observations: []string{"Fast compiler", "Has generics"}},
+		{Name: "", Type: "language"},
+	})
+	if err != nil {
+		t.Fatalf("CreateEntities failed: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+
+	if !results[0].Created || results[0].Added != 1 || results[0].Err != nil {
+		t.Errorf("expected Rust created with 1 observation, got %+v", results[0])
+	}
+	if results[1].Created || results[1].Added != 1 || results[1].Err != nil {
+		t.Errorf("expected existing Go to gain 1 new observation, got %+v", results[1])
+	}
+	if results[2].Err == nil {
+		t.Error("expected an error for the spec without a name")
+	}
+
+	entity, _ := store.GetEntity("Go")
+	if len(entity.Observations) != 2 {
+		t.Errorf("expected 2 observations on Go, got %v", entity.Observations)
+	}
+}
+
+func TestAddObservationsBatch(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+
+	store.CreateEntity("Go", "language", []string{"Fast compiler"})
+
+	results, err := store.AddObservationsBatch([]storage.ObservationSpec{
+		{EntityName: "Go", Content: "Has generics"},
+		{EntityName: "Go", Content: "Fast compiler"},
+		{EntityName: "Go", Content: "Go 1 compatibility", FactType: storage.FactTypeStatic},
+		{EntityName: "Missing", Content: "Nowhere to go"},
+	})
+	if err != nil {
+		t.Fatalf("AddObservationsBatch failed: %v", err)
+	}
+
+	want := []bool{true, false, true, false}
+	for i, r := range results {
+		if r.Added != want[i] {
+			t.Errorf("result %d: Added = %v, want %v", i, r.Added, want[i])
+		}
+	}
+	if !errors.Is(results[3].Err, storage.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for missing entity, got %v", results[3].Err)
+	}
+
+	entity, _ := store.GetEntity("Go")
+	if len(entity.Observations) != 3 {
+		t.Errorf("expected 3 observations, got %v", entity.Observations)
+	}
+	static, _ := store.GetObservationsByFactType(storage.FactTypeStatic)
+	if len(static) != 1 || static[0].Content != "Go 1 compatibility" {
+		t.Errorf("expected one static observation, got %+v", static)
+	}
+}
@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ClonedEntity is an entity CloneContainer copied, and the name of its copy.
+type ClonedEntity struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Type string `json:"type"`
+}
+
+// ContainerClone reports what CloneContainer copied.
+type ContainerClone struct {
+	Entities     []ClonedEntity `json:"entities"`
+	Observations int            `json:"observations"`
+	Relations    int            `json:"relations"`
+	// Entities left out because their copy's name is taken
+	Skipped []string `json:"skipped,omitempty"`
+}
+
+// cloneName is the name of the copy of entity name under tag to: name with
+// the tag from replaced, or else with to appended.
+func cloneName(name, from, to string) string {
+	if strings.Contains(name, from) {
+		return strings.ReplaceAll(name, from, to)
+	}
+	return name + " (" + to + ")"
+}
+
+// CloneContainer copies the entities tagged from, or only those of the given
+// types, to new entities tagged to, with their observations and the relations
+// among them. The copies start fresh: no version history, no usage, and
+// their own identities, so they evolve independently of the originals.
+// Suppressed observations are not copied.
+func (s *Store) CloneContainer(from, to string, types []string) (*ContainerClone, error) {
+	return s.CloneContainerContext(context.Background(), from, to, types)
+}
+
+// CloneContainerContext is CloneContainer with a context.
+func (s *Store) CloneContainerContext(ctx context.Context, from, to string, types []string) (*ContainerClone, error) {
+	if from == "" || to == "" {
+		return nil, errors.New("both container tags are required")
+	}
+	if from == to {
+		return nil, fmt.Errorf("cannot clone %q into itself", from)
+	}
+	canonical := make([]string, len(types))
+	for i, t := range types {
+		canonical[i] = s.CanonicalEntityType(t)
+	}
+
+	entities, err := s.GetEntitiesByContainerTagContext(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	clone := &ContainerClone{}
+	copies := make(map[string]int64) // Original entity name to its copy's ID
+	for _, e := range entities {
+		if len(canonical) > 0 && !slices.Contains(canonical, e.Type) {
+			continue
+		}
+		name := cloneName(e.Name, from, to)
+		var existing int64
+		err := tx.GetContext(ctx, &existing, "SELECT id FROM entities WHERE "+s.nameMatch("name"), name)
+		if err == nil {
+			clone.Skipped = append(clone.Skipped, e.Name)
+			continue
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+
+		result, err := tx.ExecContext(ctx,
+			"INSERT INTO entities (name, entity_type, container_tag, owner) VALUES (?, ?, ?, ?)",
+			name, e.Type, to, s.userValue())
+		if err != nil {
+			return nil, err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+		copies[e.Name] = id
+
+		result, err = tx.ExecContext(ctx, `
+			INSERT INTO observations (entity_id, content, content_hash, fact_type, importance, pinned, pii, source, author)
+			SELECT ?, content, content_hash, fact_type, importance, pinned, pii, ?, ?
+			FROM observations
+			WHERE entity_id = ? AND COALESCE(suppressed, 0) = 0
+			ORDER BY id
+		`, id, s.sourceFor(ctx), s.userValue(), e.ID)
+		if err != nil {
+			return nil, err
+		}
+		clone.Observations += rowsAffected(result)
+		clone.Entities = append(clone.Entities, ClonedEntity{From: e.Name, To: name, Type: e.Type})
+	}
+
+	// Relations valid now, matched by name since they stay on the entity
+	// version they were made on
+	validity, args := RelationFilter{}.sql()
+	var relations []Relation
+	if err := tx.SelectContext(ctx, &relations, `
+		SELECT `+relationColumns+`
+		FROM relations r
+		JOIN entities e_from ON r.from_entity_id = e_from.id
+		JOIN entities e_to ON r.to_entity_id = e_to.id
+		WHERE `+validity+`
+	`, args...); err != nil {
+		return nil, err
+	}
+	for _, r := range relations {
+		fromID, okFrom := copies[r.From]
+		toID, okTo := copies[r.To]
+		if !okFrom || !okTo {
+			continue
+		}
+		result, err := tx.ExecContext(ctx,
+			"INSERT OR IGNORE INTO relations (from_entity_id, to_entity_id, relation_type) VALUES (?, ?, ?)",
+			fromID, toID, r.Type)
+		if err != nil {
+			return nil, err
+		}
+		clone.Relations += rowsAffected(result)
+	}
+
+	return clone, tx.Commit()
+}
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"context"
 	"fmt"
 	"strings"
 )
@@ -10,9 +11,14 @@ import (
 // of another, the shorter one is removed (the longer one is more comprehensive).
 // Returns a summary of what was consolidated.
 func (s *Store) ConsolidateObservations(entityName string) (string, error) {
-	entity, err := s.GetEntity(entityName)
+	return s.ConsolidateObservationsContext(context.Background(), entityName)
+}
+
+// ConsolidateObservationsContext is ConsolidateObservations with a context.
+func (s *Store) ConsolidateObservationsContext(ctx context.Context, entityName string) (string, error) {
+	entity, err := s.GetEntityContext(ctx, entityName)
 	if err != nil {
-		return "", fmt.Errorf("entity not found: %w", err)
+		return "", err
 	}
 
 	if len(entity.Observations) <= 1 {
@@ -51,7 +57,7 @@ func (s *Store) ConsolidateObservations(entityName string) (string, error) {
 	// Delete the duplicates
 	deleted := 0
 	for _, obs := range uniqueDeletes {
-		if err := s.DeleteObservation(entityName, obs); err == nil {
+		if err := s.DeleteObservationContext(ctx, entityName, obs); err == nil {
 			deleted++
 		}
 	}
@@ -1,16 +1,29 @@
 package storage
 
 import (
+	"context"
 	"math"
+	"slices"
 	"strings"
+	"time"
 )
 
 // ContextConfig holds configuration for context injection.
 type ContextConfig struct {
-	TokenBudget      int      // Maximum tokens to include (estimate: 4 chars = 1 token)
+	TokenBudget      int      // Maximum tokens to include, counted with the active tokenizer
 	MinImportance    float64  // Minimum importance score to include
 	FactTypePriority []string // Priority order: static > dynamic > session_turn
 	ProjectBoost     float64  // Score multiplier for project-matching memories
+	Dedup            bool     // Drop near-identical observations before applying the budget
+	DedupSimilarity  float64  // Cosine similarity at which two embedded observations are duplicates
+	PinnedBudget     int      // Tokens reserved for pinned memories, taken from TokenBudget
+	User             string   // Only observations this user wrote; empty for everyone's
+	ExcludeTypes     []string // Entity types never injected
+
+	// BudgetShares reserves a fraction of TokenBudget per fact type so one type
+	// cannot crowd out the others. Unused share is handed to the remaining
+	// results in priority order. Nil or empty disables sub-budgets.
+	BudgetShares map[string]float64
 }
 
 // DefaultContextConfig returns the default context injection configuration.
@@ -20,50 +33,108 @@ func DefaultContextConfig() ContextConfig {
 		MinImportance:    0.3,
 		FactTypePriority: []string{"static", "dynamic", "session_turn"},
 		ProjectBoost:     1.5,
+		Dedup:            true,
+		DedupSimilarity:  0.92,
+		BudgetShares:     DefaultBudgetShares(),
+		PinnedBudget:     300,
+	}
+}
+
+// ContextOverrides overrides ContextConfig settings from a config file. Unset
+// fields keep the value they override.
+type ContextOverrides struct {
+	TokenBudget   int                `json:"tokenBudget,omitempty"`
+	BudgetShares  map[string]float64 `json:"budgetShares,omitempty"`
+	MinImportance *float64           `json:"minImportance,omitempty"`
+	ProjectBoost  *float64           `json:"projectBoost,omitempty"`
+	// Replaces the overridden list; an empty list excludes nothing
+	ExcludeTypes *[]string `json:"excludeTypes,omitempty"`
+}
+
+// Apply sets the fields of cfg that o sets.
+func (o ContextOverrides) Apply(cfg *ContextConfig) {
+	if o.TokenBudget > 0 {
+		cfg.TokenBudget = o.TokenBudget
+	}
+	if len(o.BudgetShares) > 0 {
+		cfg.BudgetShares = o.BudgetShares
+	}
+	if o.MinImportance != nil {
+		cfg.MinImportance = *o.MinImportance
+	}
+	if o.ProjectBoost != nil && *o.ProjectBoost > 0 {
+		cfg.ProjectBoost = *o.ProjectBoost
+	}
+	if o.ExcludeTypes != nil {
+		cfg.ExcludeTypes = *o.ExcludeTypes
+	}
+}
+
+// DefaultBudgetShares returns the default per-fact-type token budget shares.
+func DefaultBudgetShares() map[string]float64 {
+	return map[string]float64{
+		"static":       0.50,
+		"dynamic":      0.35,
+		"session_turn": 0.15,
 	}
 }
 
 // ContextResult represents a memory selected for context injection.
 type ContextResult struct {
+	ObservationID   int64   `db:"observation_id"`
 	EntityName      string  `db:"entity_name"`
 	EntityType      string  `db:"entity_type"`
 	Content         string  `db:"content"`
 	FactType        string  `db:"fact_type"`
 	Importance      float64 `db:"importance"`
 	DaysSinceAccess float64 `db:"days_since_access"`
+	Pinned          bool    `db:"pinned"`
 	FinalScore      float64 // After fact type priority, project boost, and recency boost
 }
 
 // GetContextForInjection retrieves memories optimized for context injection.
-// Orders by: fact type priority, then importance, respecting token budget.
+// Pinned memories come first regardless of importance, within PinnedBudget;
+// the rest are ordered by fact type priority, then importance, respecting token budget.
 func (s *Store) GetContextForInjection(cfg ContextConfig, projectName string) ([]ContextResult, error) {
-	// Build fact type priority case statement
-	var factTypeCases []string
-	for i, ft := range cfg.FactTypePriority {
-		factTypeCases = append(factTypeCases, "WHEN '"+ft+"' THEN "+formatInt(i+1))
-	}
-	factTypeOrder := "CASE fact_type " + strings.Join(factTypeCases, " ") + " ELSE 99 END"
-
-	// Query with ordering — includes days since last access for recency boost
-	query := `
-		SELECT e.name as entity_name, e.entity_type, o.content,
-		       COALESCE(o.fact_type, 'dynamic') as fact_type,
-		       COALESCE(o.importance, 1.0) as importance,
-		       COALESCE(julianday('now') - julianday(COALESCE(o.last_accessed, o.created_at)), 0) as days_since_access
-		FROM observations o
-		JOIN entities e ON e.id = o.entity_id
-		WHERE e.is_latest = 1 AND o.importance >= ?
-		ORDER BY ` + factTypeOrder + `, o.importance DESC
-	`
+	return s.GetContextForInjectionContext(context.Background(), cfg, projectName)
+}
 
+// GetContextForInjectionContext is GetContextForInjection with a context.
+func (s *Store) GetContextForInjectionContext(ctx context.Context, cfg ContextConfig, projectName string) ([]ContextResult, error) {
 	var results []ContextResult
-	err := s.db.Select(&results, query, cfg.MinImportance)
+	err := s.db.SelectContext(ctx, &results, contextInjectionQuery(factTypeOrderSQL(cfg.FactTypePriority)), julianNow(), cfg.MinImportance, cfg.User, cfg.User)
 	if err != nil {
 		return nil, err
 	}
+	if len(cfg.ExcludeTypes) > 0 {
+		results = slices.DeleteFunc(results, func(r ContextResult) bool {
+			return slices.Contains(cfg.ExcludeTypes, r.EntityType)
+		})
+	}
+	scoreContextResults(results, cfg, projectName, s.importance)
+
+	if cfg.Dedup {
+		results, err = s.dedupContextResults(ctx, results, cfg.DedupSimilarity)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return selectContextResults(s.tokenizer, results, cfg), nil
+}
+
+// factTypeOrderSQL is a CASE expression ranking fact_type by priority, for
+// ORDER BY; unlisted types sort last.
+func factTypeOrderSQL(priority []string) string {
+	var factTypeCases []string
+	for i, ft := range priority {
+		factTypeCases = append(factTypeCases, "WHEN '"+strings.ReplaceAll(ft, "'", "''")+"' THEN "+formatInt(i+1))
+	}
+	return "CASE fact_type " + strings.Join(factTypeCases, " ") + " ELSE 99 END"
+}
 
-	// Apply boosts and calculate final scores:
-	// final_score = importance × recency_boost × project_boost × fact_type_boost
+// scoreContextResults sets the FinalScore of context candidates:
+// final_score = importance × recency_boost × project_boost × fact_type_boost
+func scoreContextResults(results []ContextResult, cfg ContextConfig, projectName string, importance ImportanceConfig) {
 	for i := range results {
 		results[i].FinalScore = results[i].Importance
 
@@ -80,31 +151,115 @@ func (s *Store) GetContextForInjection(cfg ContextConfig, projectName string) ([
 			}
 		}
 
-		// Boost static facts
-		if results[i].FactType == "static" {
-			results[i].FinalScore *= 1.2
-		}
+		// Boost by fact type (static facts by default)
+		results[i].FinalScore *= importance.FactTypeBoost(results[i].FactType)
 	}
+}
 
-	// Apply token budget (estimate 4 chars per token)
+// selectContextResults picks the candidates to inject, in order, within the
+// token budget: pinned memories lead, within their reserved budget, and the
+// rest fill the remaining budget by fact type share.
+func selectContextResults(tok Tokenizer, results []ContextResult, cfg ContextConfig) []ContextResult {
+	pinnedBudget := min(cfg.PinnedBudget, cfg.TokenBudget)
 	tokenCount := 0
 	var selected []ContextResult
-	for _, r := range results {
-		// Estimate tokens for this entry
-		entryTokens := (len(r.EntityName) + len(r.Content) + 20) / 4 // +20 for formatting
-		if tokenCount+entryTokens > cfg.TokenBudget {
+	for len(results) > 0 && results[0].Pinned {
+		entryTokens := contextEntryTokens(tok, results[0])
+		if tokenCount+entryTokens <= pinnedBudget {
+			tokenCount += entryTokens
+			selected = append(selected, results[0])
+		}
+		results = results[1:]
+	}
+
+	return append(selected, applyBudgetShares(tok, results, cfg.TokenBudget-tokenCount, cfg.BudgetShares)...)
+}
+
+// contextInjectionQuery selects context candidates with days since last
+// access for the recency boost. It starts from the latest entities
+// (idx_entities_is_latest) and their observations (idx_observations_entity)
+// rather than scanning all observations. Cached entity summaries are left
+// out: they restate observations that are injected themselves. Parameters:
+// julianNow, min importance, and the user twice (empty for everyone's
+// observations).
+func contextInjectionQuery(factTypeOrder string) string {
+	return `
+		SELECT o.id as observation_id, e.name as entity_name, e.entity_type, o.content,
+		       COALESCE(o.fact_type, 'dynamic') as fact_type,
+		       COALESCE(o.importance, 1.0) as importance,
+		       COALESCE(? - julianday(COALESCE(o.last_accessed, o.created_at)), 0) as days_since_access,
+		       COALESCE(o.pinned, 0) as pinned
+		FROM entities e
+		JOIN observations o ON o.entity_id = e.id
+		WHERE e.is_latest = 1 AND (o.importance >= ? OR o.pinned = 1)
+		AND COALESCE(o.suppressed, 0) = 0
+		AND COALESCE(o.fact_type, '') != 'summary'
+		AND (? = '' OR o.author = ?)
+		ORDER BY COALESCE(o.pinned, 0) DESC, ` + factTypeOrder + `, o.importance DESC
+	`
+}
+
+// julianNow is the current time as a Julian day number, so queries compare
+// against one value instead of evaluating julianday('now') per row.
+func julianNow() float64 {
+	return float64(time.Now().UnixNano())/float64(24*time.Hour) + 2440587.5
+}
+
+// applyBudgetShares selects results within tokenBudget, preserving their order.
+// Each fact type first fills its reserved share; the leftover budget then goes
+// to the remaining results in order. Without shares it is a plain budget cut-off.
+func applyBudgetShares(tok Tokenizer, results []ContextResult, tokenBudget int, shares map[string]float64) []ContextResult {
+	picked := make([]bool, len(results))
+	tokenCount := 0
+
+	if len(shares) > 0 {
+		used := make(map[string]int)
+		full := make(map[string]bool)
+		for i, r := range results {
+			share, ok := shares[r.FactType]
+			if !ok || full[r.FactType] {
+				continue
+			}
+			entryTokens := contextEntryTokens(tok, r)
+			if used[r.FactType]+entryTokens > int(share*float64(tokenBudget)) || tokenCount+entryTokens > tokenBudget {
+				full[r.FactType] = true
+				continue
+			}
+			used[r.FactType] += entryTokens
+			tokenCount += entryTokens
+			picked[i] = true
+		}
+	}
+
+	for i, r := range results {
+		if picked[i] {
+			continue
+		}
+		entryTokens := contextEntryTokens(tok, r)
+		if tokenCount+entryTokens > tokenBudget {
 			break
 		}
 		tokenCount += entryTokens
-		selected = append(selected, r)
+		picked[i] = true
 	}
 
-	return selected, nil
+	var selected []ContextResult
+	for i, r := range results {
+		if picked[i] {
+			selected = append(selected, r)
+		}
+	}
+	return selected
 }
 
 // GetRecentContext retrieves memories ordered by recency, within the given time window.
 // Prioritizes recently accessed observations, with optional project boosting.
 func (s *Store) GetRecentContext(hours int, projectName string, tokenBudget int) ([]ContextResult, error) {
+	return s.GetRecentContextContext(context.Background(), hours, projectName, tokenBudget)
+}
+
+// GetRecentContextContext is GetRecentContext with a context.
+func (s *Store) GetRecentContextContext(ctx context.Context, hours int, projectName string, tokenBudget int) ([]ContextResult, error) {
 	if tokenBudget <= 0 {
 		tokenBudget = 1000
 	}
@@ -117,6 +272,8 @@ func (s *Store) GetRecentContext(hours int, projectName string, tokenBudget int)
 		FROM observations o
 		JOIN entities e ON e.id = o.entity_id
 		WHERE e.is_latest = 1
+		AND COALESCE(o.suppressed, 0) = 0
+		AND COALESCE(o.fact_type, '') != 'summary'
 		AND COALESCE(o.last_accessed, o.created_at) > datetime('now', ? || ' hours')
 		ORDER BY COALESCE(o.last_accessed, o.created_at) DESC
 	`
@@ -124,7 +281,7 @@ func (s *Store) GetRecentContext(hours int, projectName string, tokenBudget int)
 	hoursParam := "-" + formatInt(hours)
 
 	var results []ContextResult
-	if err := s.db.Select(&results, query, hoursParam); err != nil {
+	if err := s.db.SelectContext(ctx, &results, query, hoursParam); err != nil {
 		return nil, err
 	}
 
@@ -144,7 +301,7 @@ func (s *Store) GetRecentContext(hours int, projectName string, tokenBudget int)
 	tokenCount := 0
 	var selected []ContextResult
 	for _, r := range results {
-		entryTokens := (len(r.EntityName) + len(r.Content) + 20) / 4
+		entryTokens := contextEntryTokens(s.tokenizer, r)
 		if tokenCount+entryTokens > tokenBudget {
 			break
 		}
@@ -156,74 +313,19 @@ func (s *Store) GetRecentContext(hours int, projectName string, tokenBudget int)
 }
 
 // FormatContextResults formats context results for injection into conversation.
+// Uses the default context template; see FormatContextResultsWithTemplate for alternatives.
 func FormatContextResults(results []ContextResult) string {
-	if len(results) == 0 {
+	formatted, err := FormatContextResultsWithTemplate(results, DefaultContextTemplate)
+	if err != nil {
 		return ""
 	}
-
-	var sb strings.Builder
-	sb.WriteString("=== Relevant Memories ===\n\n")
-
-	// Group by fact type
-	staticObs := make(map[string][]string)
-	dynamicObs := make(map[string][]string)
-	sessionObs := make(map[string][]string)
-
-	for _, r := range results {
-		key := r.EntityName + " (" + r.EntityType + ")"
-		switch r.FactType {
-		case "static":
-			staticObs[key] = append(staticObs[key], r.Content)
-		case "session_turn":
-			sessionObs[key] = append(sessionObs[key], r.Content)
-		default:
-			dynamicObs[key] = append(dynamicObs[key], r.Content)
-		}
-	}
-
-	// Output static first (user preferences)
-	if len(staticObs) > 0 {
-		sb.WriteString("[STATIC] Project Conventions:\n")
-		for entity, observations := range staticObs {
-			sb.WriteString("## " + entity + "\n")
-			for _, obs := range observations {
-				sb.WriteString("- " + obs + "\n")
-			}
-		}
-		sb.WriteString("\n")
-	}
-
-	// Output dynamic (recent context)
-	if len(dynamicObs) > 0 {
-		sb.WriteString("[DYNAMIC] Recent Context:\n")
-		for entity, observations := range dynamicObs {
-			sb.WriteString("## " + entity + "\n")
-			for _, obs := range observations {
-				sb.WriteString("- " + obs + "\n")
-			}
-		}
-		sb.WriteString("\n")
-	}
-
-	// Output session turns (conversation history)
-	if len(sessionObs) > 0 {
-		sb.WriteString("[SESSION] Conversation History:\n")
-		for entity, observations := range sessionObs {
-			sb.WriteString("## " + entity + "\n")
-			for _, obs := range observations {
-				sb.WriteString("- " + obs + "\n")
-			}
-		}
-		sb.WriteString("\n")
-	}
-
-	return sb.String()
+	return formatted
 }
 
-// EstimateTokens estimates the number of tokens in the context.
-// Uses simple heuristic: 4 characters ≈ 1 token.
+// EstimateTokens estimates the number of tokens in text with
+// DefaultTokenizer; Store.CountTokens uses the store's own tokenizer.
 func EstimateTokens(text string) int {
-	return len(text) / 4
+	return DefaultTokenizer().CountTokens(text)
 }
 
 // FormatSessionRecall formats session summaries for recall injection.
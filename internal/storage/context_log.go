@@ -0,0 +1,185 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// ContextCall describes one context injection for LogContextInjection.
+type ContextCall struct {
+	Source        string // What injected it: SourceMCP for get_context, SourceHook for session start
+	Project       string
+	User          string
+	TokenBudget   int
+	MinImportance float64
+	TokensUsed    int // Estimated tokens of the injected text
+}
+
+// LogContextInjection records call and the memories it selected, when the
+// context.log setting is on; otherwise it does nothing. Calls older than
+// context.logDays are dropped as new ones are logged.
+func (s *Store) LogContextInjection(call ContextCall, results []ContextResult) error {
+	return s.LogContextInjectionContext(context.Background(), call, results)
+}
+
+// LogContextInjectionContext is LogContextInjection with a context.
+func (s *Store) LogContextInjectionContext(ctx context.Context, call ContextCall, results []ContextResult) error {
+	if on, err := s.GetSettingContext(ctx, SettingContextLog); err != nil || on != "true" {
+		return err
+	}
+	days, err := s.GetSettingContext(ctx, SettingContextLogDays)
+	if err != nil {
+		return err
+	}
+	keep, _ := strconv.Atoi(days)
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM context_log WHERE created_at < datetime('now', ?)",
+		"-"+strconv.Itoa(keep)+" days"); err != nil {
+		return err
+	}
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO context_log (source, project, user_name, token_budget, tokens_used, min_importance, results)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, call.Source, call.Project, call.User, call.TokenBudget, call.TokensUsed, call.MinImportance, len(results))
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	for i, r := range results {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO context_log_items (call_id, position, observation_id, entity_name, fact_type, importance, score)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, id, i+1, r.ObservationID, r.EntityName, r.FactType, r.Importance, r.FinalScore); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ContextLogMemory is a memory in a ContextAnalysis.
+type ContextLogMemory struct {
+	ObservationID int64   `db:"observation_id" json:"observationId"`
+	EntityName    string  `db:"entity_name" json:"entity"`
+	Content       string  `db:"content" json:"content"` // Empty when the observation is gone
+	Importance    float64 `db:"importance" json:"importance"`
+	Injected      int     `db:"injected" json:"injected"`            // Calls that selected it
+	AvgScore      float64 `db:"avg_score" json:"avgScore,omitempty"` // Mean final score when selected
+	AvgPosition   float64 `db:"avg_position" json:"avgPosition,omitempty"`
+}
+
+// ContextAnalysis summarizes the context log; see AnalyzeContextLog.
+type ContextAnalysis struct {
+	Since         time.Time          `json:"since"`
+	Calls         int                `json:"calls"`
+	BySource      map[string]int     `json:"bySource"`
+	AvgResults    float64            `json:"avgResults"`
+	AvgTokensUsed float64            `json:"avgTokensUsed"`
+	BudgetUse     float64            `json:"budgetUse"` // Mean share of the token budget used, 0-1
+	Top           []ContextLogMemory `json:"top"`       // Injected most often
+	// NeverInjected counts live observations no logged call selected, and
+	// BelowMinImportance those of them under the latest call's threshold
+	NeverInjected      int                `json:"neverInjected"`
+	BelowMinImportance int                `json:"belowMinImportance"`
+	Unsurfaced         []ContextLogMemory `json:"unsurfaced"` // The most important of them
+}
+
+// AnalyzeContextLog summarizes the calls logged since since: how much of the
+// budget they used, which memories they inject most, and which memories they
+// never surface, most important first, with limit of each.
+func (s *Store) AnalyzeContextLog(since time.Time, limit int) (*ContextAnalysis, error) {
+	return s.AnalyzeContextLogContext(context.Background(), since, limit)
+}
+
+// AnalyzeContextLogContext is AnalyzeContextLog with a context.
+func (s *Store) AnalyzeContextLogContext(ctx context.Context, since time.Time, limit int) (*ContextAnalysis, error) {
+	a := &ContextAnalysis{Since: since, BySource: map[string]int{}}
+	sinceArg := since.UTC().Format("2006-01-02 15:04:05")
+
+	var totals struct {
+		Calls      int     `db:"calls"`
+		AvgResults float64 `db:"avg_results"`
+		AvgTokens  float64 `db:"avg_tokens"`
+		BudgetUse  float64 `db:"budget_use"`
+	}
+	if err := s.db.GetContext(ctx, &totals, `
+		SELECT COUNT(*) AS calls,
+		       COALESCE(AVG(results), 0) AS avg_results,
+		       COALESCE(AVG(tokens_used), 0) AS avg_tokens,
+		       COALESCE(AVG(CASE WHEN token_budget > 0 THEN CAST(tokens_used AS REAL) / token_budget END), 0) AS budget_use
+		FROM context_log WHERE created_at >= ?
+	`, sinceArg); err != nil {
+		return nil, err
+	}
+	a.Calls, a.AvgResults, a.AvgTokensUsed, a.BudgetUse = totals.Calls, totals.AvgResults, totals.AvgTokens, totals.BudgetUse
+
+	var sources []struct {
+		Source string `db:"source"`
+		Calls  int    `db:"calls"`
+	}
+	if err := s.db.SelectContext(ctx, &sources,
+		"SELECT source, COUNT(*) AS calls FROM context_log WHERE created_at >= ? GROUP BY source", sinceArg); err != nil {
+		return nil, err
+	}
+	for _, source := range sources {
+		a.BySource[source.Source] = source.Calls
+	}
+
+	if err := s.db.SelectContext(ctx, &a.Top, `
+		SELECT i.observation_id, i.entity_name, COALESCE(o.content, '') AS content,
+		       COALESCE(o.importance, MAX(i.importance)) AS importance,
+		       COUNT(*) AS injected, AVG(i.score) AS avg_score, AVG(i.position) AS avg_position
+		FROM context_log_items i
+		JOIN context_log c ON c.id = i.call_id
+		LEFT JOIN observations o ON o.id = i.observation_id
+		WHERE c.created_at >= ?
+		GROUP BY i.observation_id
+		ORDER BY injected DESC, avg_position
+		LIMIT ?
+	`, sinceArg, limit); err != nil {
+		return nil, err
+	}
+
+	// Compared with the threshold in use, so the count explains the misses
+	minImportance := DefaultContextConfig().MinImportance
+	err := s.db.GetContext(ctx, &minImportance,
+		"SELECT min_importance FROM context_log WHERE created_at >= ? ORDER BY id DESC LIMIT 1", sinceArg)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	never := `
+		FROM observations o
+		JOIN entities e ON e.id = o.entity_id
+		WHERE e.is_latest = 1 AND COALESCE(o.suppressed, 0) = 0
+		AND o.id NOT IN (
+			SELECT i.observation_id FROM context_log_items i
+			JOIN context_log c ON c.id = i.call_id
+			WHERE c.created_at >= ?
+		)`
+	if err := s.db.GetContext(ctx, &a.NeverInjected, "SELECT COUNT(*)"+never, sinceArg); err != nil {
+		return nil, err
+	}
+	if err := s.db.GetContext(ctx, &a.BelowMinImportance,
+		"SELECT COUNT(*)"+never+" AND COALESCE(o.importance, 1.0) < ?", sinceArg, minImportance); err != nil {
+		return nil, err
+	}
+	if err := s.db.SelectContext(ctx, &a.Unsurfaced, `
+		SELECT o.id AS observation_id, e.name AS entity_name, o.content, COALESCE(o.importance, 1.0) AS importance`+never+`
+		ORDER BY COALESCE(o.importance, 1.0) DESC, o.id
+		LIMIT ?
+	`, sinceArg, limit); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
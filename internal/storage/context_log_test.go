@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestContextLog(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	store.CreateEntity("Go", "language", []string{"Fast compiler", "Garbage collected"})
+	store.CreateEntity("Docker", "tool", []string{"Used for CI"})
+	store.SetObservationImportance("Docker", "Used for CI", 0.1)
+
+	cfg := DefaultContextConfig()
+	inject := func() {
+		t.Helper()
+		results, err := store.GetContextForInjection(cfg, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		call := ContextCall{Source: SourceHook, TokenBudget: cfg.TokenBudget, MinImportance: cfg.MinImportance, TokensUsed: 500}
+		if err := store.LogContextInjection(call, results); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Off by default
+	inject()
+	since := time.Now().Add(-time.Hour)
+	if a, err := store.AnalyzeContextLog(since, 10); err != nil || a.Calls != 0 {
+		t.Fatalf("expected nothing logged while off, got %+v, %v", a, err)
+	}
+
+	store.SetSetting(SettingContextLog, "true")
+	inject()
+	inject()
+
+	a, err := store.AnalyzeContextLog(since, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Calls != 2 || a.BySource[SourceHook] != 2 || a.AvgResults != 2 || a.BudgetUse != 0.25 {
+		t.Errorf("unexpected totals %+v", a)
+	}
+	if len(a.Top) != 2 || a.Top[0].Injected != 2 || a.Top[0].EntityName != "Go" {
+		t.Errorf("expected Go's observations injected twice, got %+v", a.Top)
+	}
+	if a.NeverInjected != 1 || a.BelowMinImportance != 1 || len(a.Unsurfaced) != 1 || a.Unsurfaced[0].Content != "Used for CI" {
+		t.Errorf("expected Docker's low importance observation unsurfaced, got %+v", a)
+	}
+
+	// Purge removes an entity's entries
+	report, err := store.PurgeEntity("Go", PurgeOptions{})
+	if err != nil || report.ContextLog != 4 {
+		t.Errorf("PurgeEntity = %+v, %v", report, err)
+	}
+}
@@ -0,0 +1,208 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// DefaultContextTemplate is the name of the built-in template used by FormatContextResults.
+const DefaultContextTemplate = "default"
+
+// ContextEntityGroup holds the observations selected for one entity.
+type ContextEntityGroup struct {
+	Name         string
+	Type         string
+	Observations []string
+}
+
+// ContextTemplateData is the value passed to context templates.
+// Groups keep the order in which entities first appear in the results.
+type ContextTemplateData struct {
+	Static  []ContextEntityGroup // static facts (conventions, preferences)
+	Dynamic []ContextEntityGroup // dynamic facts and anything unclassified
+	Session []ContextEntityGroup // session_turn facts (conversation history)
+	All     []ContextEntityGroup // every entity regardless of fact type
+	Count   int                  // number of memories
+}
+
+// builtinContextTemplates maps template names to their text/template source.
+var builtinContextTemplates = map[string]string{
+	"default": `=== Relevant Memories ===
+
+{{if .Static}}[STATIC] Project Conventions:
+{{range .Static}}## {{.Name}} ({{.Type}})
+{{range .Observations}}- {{.}}
+{{end}}{{end}}
+{{end}}{{if .Dynamic}}[DYNAMIC] Recent Context:
+{{range .Dynamic}}## {{.Name}} ({{.Type}})
+{{range .Observations}}- {{.}}
+{{end}}{{end}}
+{{end}}{{if .Session}}[SESSION] Conversation History:
+{{range .Session}}## {{.Name}} ({{.Type}})
+{{range .Observations}}- {{.}}
+{{end}}{{end}}
+{{end}}`,
+
+	"compact": `Memories:
+{{range .All}}- {{.Name}}: {{join .Observations "; "}}
+{{end}}`,
+
+	"xml-tags": `<memories>
+{{if .Static}}<static>
+{{range .Static}}<entity name="{{xml .Name}}" type="{{xml .Type}}">
+{{range .Observations}}- {{xml .}}
+{{end}}</entity>
+{{end}}</static>
+{{end}}{{if .Dynamic}}<dynamic>
+{{range .Dynamic}}<entity name="{{xml .Name}}" type="{{xml .Type}}">
+{{range .Observations}}- {{xml .}}
+{{end}}</entity>
+{{end}}</dynamic>
+{{end}}{{if .Session}}<session>
+{{range .Session}}<entity name="{{xml .Name}}" type="{{xml .Type}}">
+{{range .Observations}}- {{xml .}}
+{{end}}</entity>
+{{end}}</session>
+{{end}}</memories>
+`,
+
+	"markdown": `## Relevant Memories
+
+{{if .Static}}### Project Conventions
+
+{{range .Static}}**{{.Name}}** _({{.Type}})_
+{{range .Observations}}- {{.}}
+{{end}}
+{{end}}{{end}}{{if .Dynamic}}### Recent Context
+
+{{range .Dynamic}}**{{.Name}}** _({{.Type}})_
+{{range .Observations}}- {{.}}
+{{end}}
+{{end}}{{end}}{{if .Session}}### Conversation History
+
+{{range .Session}}**{{.Name}}** _({{.Type}})_
+{{range .Observations}}- {{.}}
+{{end}}
+{{end}}{{end}}`,
+}
+
+var contextTemplateFuncs = template.FuncMap{
+	"join": strings.Join,
+	"xml": func(s string) string {
+		var buf bytes.Buffer
+		_ = xml.EscapeText(&buf, []byte(s))
+		return buf.String()
+	},
+}
+
+// ContextTemplateNames returns the names of the built-in context templates, sorted.
+func ContextTemplateNames() []string {
+	names := make([]string, 0, len(builtinContextTemplates))
+	for name := range builtinContextTemplates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseContextTemplate parses a custom context template.
+// Templates receive a ContextTemplateData value and may use the "join" and "xml" functions.
+func ParseContextTemplate(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(contextTemplateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse context template %q: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// LookupContextTemplate resolves a built-in template by name.
+// An empty name selects the default template.
+func LookupContextTemplate(name string) (*template.Template, error) {
+	if name == "" {
+		name = DefaultContextTemplate
+	}
+	text, ok := builtinContextTemplates[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown context template %q (available: %s)",
+			name, strings.Join(ContextTemplateNames(), ", "))
+	}
+	return ParseContextTemplate(name, text)
+}
+
+// LoadContextTemplate resolves a built-in template by name, falling back to
+// reading a custom template from the file at nameOrPath.
+func LoadContextTemplate(nameOrPath string) (*template.Template, error) {
+	if _, ok := builtinContextTemplates[nameOrPath]; ok || nameOrPath == "" {
+		return LookupContextTemplate(nameOrPath)
+	}
+
+	data, err := os.ReadFile(nameOrPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return LookupContextTemplate(nameOrPath)
+		}
+		return nil, fmt.Errorf("failed to read context template: %w", err)
+	}
+	return ParseContextTemplate(nameOrPath, string(data))
+}
+
+// FormatContextResultsWithTemplate renders context results using the named built-in template.
+func FormatContextResultsWithTemplate(results []ContextResult, name string) (string, error) {
+	tmpl, err := LookupContextTemplate(name)
+	if err != nil {
+		return "", err
+	}
+	return RenderContextTemplate(tmpl, results)
+}
+
+// RenderContextTemplate renders context results with a parsed template.
+// Returns an empty string when there are no results.
+func RenderContextTemplate(tmpl *template.Template, results []ContextResult) (string, error) {
+	if len(results) == 0 {
+		return "", nil
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, buildContextTemplateData(results)); err != nil {
+		return "", fmt.Errorf("failed to render context template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// buildContextTemplateData groups results by fact type and entity, preserving result order.
+func buildContextTemplateData(results []ContextResult) ContextTemplateData {
+	data := ContextTemplateData{Count: len(results)}
+
+	add := func(groups []ContextEntityGroup, r ContextResult) []ContextEntityGroup {
+		for i := range groups {
+			if groups[i].Name == r.EntityName && groups[i].Type == r.EntityType {
+				groups[i].Observations = append(groups[i].Observations, r.Content)
+				return groups
+			}
+		}
+		return append(groups, ContextEntityGroup{
+			Name:         r.EntityName,
+			Type:         r.EntityType,
+			Observations: []string{r.Content},
+		})
+	}
+
+	for _, r := range results {
+		switch r.FactType {
+		case "static":
+			data.Static = add(data.Static, r)
+		case "session_turn":
+			data.Session = add(data.Session, r)
+		default:
+			data.Dynamic = add(data.Dynamic, r)
+		}
+		data.All = add(data.All, r)
+	}
+
+	return data
+}
@@ -0,0 +1,134 @@
+package storage_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+func sampleContextResults() []storage.ContextResult {
+	return []storage.ContextResult{
+		{EntityName: "TDD", EntityType: "pattern", Content: "Write tests first", FactType: "static"},
+		{EntityName: "TDD", EntityType: "pattern", Content: "Red, green, refactor", FactType: "static"},
+		{EntityName: "konfig", EntityType: "project", Content: "Uses <generics> & maps", FactType: "dynamic"},
+		{EntityName: "session-1", EntityType: "session", Content: "Discussed auth", FactType: "session_turn"},
+	}
+}
+
+func TestContextTemplateNames(t *testing.T) {
+	names := storage.ContextTemplateNames()
+	for _, want := range []string{"compact", "default", "markdown", "xml-tags"} {
+		found := false
+		for _, n := range names {
+			if n == want {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected built-in template %q in %v", want, names)
+		}
+	}
+}
+
+func TestFormatContextResultsWithTemplate(t *testing.T) {
+	tests := []struct {
+		template string
+		contains []string
+	}{
+		{
+			template: "default",
+			contains: []string{"=== Relevant Memories ===", "[STATIC] Project Conventions:", "## TDD (pattern)", "- Red, green, refactor", "[SESSION]"},
+		},
+		{
+			template: "compact",
+			contains: []string{"- TDD: Write tests first; Red, green, refactor", "- konfig: Uses <generics> & maps"},
+		},
+		{
+			template: "xml-tags",
+			contains: []string{"<memories>", `<entity name="TDD" type="pattern">`, "Uses &lt;generics&gt; &amp; maps", "</session>"},
+		},
+		{
+			template: "markdown",
+			contains: []string{"## Relevant Memories", "### Project Conventions", "**konfig** _(project)_", "### Conversation History"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			formatted, err := storage.FormatContextResultsWithTemplate(sampleContextResults(), tt.template)
+			if err != nil {
+				t.Fatalf("FormatContextResultsWithTemplate failed: %v", err)
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(formatted, want) {
+					t.Errorf("expected output to contain %q, got:\n%s", want, formatted)
+				}
+			}
+		})
+	}
+}
+
+func TestFormatContextResultsWithTemplate_PreservesOrder(t *testing.T) {
+	results := []storage.ContextResult{
+		{EntityName: "Zeta", EntityType: "pattern", Content: "first", FactType: "static"},
+		{EntityName: "Alpha", EntityType: "pattern", Content: "second", FactType: "static"},
+	}
+
+	formatted, err := storage.FormatContextResultsWithTemplate(results, "default")
+	if err != nil {
+		t.Fatalf("FormatContextResultsWithTemplate failed: %v", err)
+	}
+	if strings.Index(formatted, "Zeta") > strings.Index(formatted, "Alpha") {
+		t.Errorf("expected entities in result order, got:\n%s", formatted)
+	}
+}
+
+func TestFormatContextResultsWithTemplate_Unknown(t *testing.T) {
+	_, err := storage.FormatContextResultsWithTemplate(sampleContextResults(), "nope")
+	if err == nil {
+		t.Fatal("expected error for unknown template")
+	}
+	if !strings.Contains(err.Error(), "available") {
+		t.Errorf("expected error to list available templates, got: %v", err)
+	}
+}
+
+func TestFormatContextResultsWithTemplate_Empty(t *testing.T) {
+	formatted, err := storage.FormatContextResultsWithTemplate(nil, "xml-tags")
+	if err != nil {
+		t.Fatalf("FormatContextResultsWithTemplate failed: %v", err)
+	}
+	if formatted != "" {
+		t.Errorf("expected empty output, got %q", formatted)
+	}
+}
+
+func TestLoadContextTemplate_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "custom.tmpl")
+	text := `{{.Count}} memories{{range .All}} [{{.Name}}]{{end}}`
+	if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	tmpl, err := storage.LoadContextTemplate(path)
+	if err != nil {
+		t.Fatalf("LoadContextTemplate failed: %v", err)
+	}
+
+	formatted, err := storage.RenderContextTemplate(tmpl, sampleContextResults())
+	if err != nil {
+		t.Fatalf("RenderContextTemplate failed: %v", err)
+	}
+	if formatted != "4 memories [TDD] [konfig] [session-1]" {
+		t.Errorf("unexpected output: %q", formatted)
+	}
+}
+
+func TestParseContextTemplate_Invalid(t *testing.T) {
+	if _, err := storage.ParseContextTemplate("bad", "{{.Missing"); err == nil {
+		t.Error("expected parse error")
+	}
+}
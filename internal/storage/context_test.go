@@ -306,3 +306,277 @@ func TestEstimateTokens(t *testing.T) {
 		}
 	}
 }
+
+func TestStore_GetContextForInjection_Dedup(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+
+	if err := store.Migrate(); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	store.CreateEntity("testing", "pattern", []string{"Use TDD"})
+	store.CreateEntity("workflow", "pattern", []string{"Use TDD for all new code"})
+	store.CreateEntity("style", "pattern", []string{"Prefer small, focused functions"})
+
+	cfg := storage.DefaultContextConfig()
+	cfg.MinImportance = 0
+
+	results, err := store.GetContextForInjection(cfg, "")
+	if err != nil {
+		t.Fatalf("GetContextForInjection failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Errorf("expected 2 results after dedup, got %d: %+v", len(results), results)
+	}
+
+	cfg.Dedup = false
+	results, err = store.GetContextForInjection(cfg, "")
+	if err != nil {
+		t.Fatalf("GetContextForInjection failed: %v", err)
+	}
+	if len(results) != 3 {
+		t.Errorf("expected 3 results without dedup, got %d", len(results))
+	}
+}
+
+func TestStore_GetContextForInjection_DedupEmbeddings(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+
+	if err := store.Migrate(); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	store.CreateEntity("a", "pattern", []string{"Always write tests before code"})
+	store.CreateEntity("b", "pattern", []string{"Tests come first, implementation second"})
+	store.CreateEntity("c", "pattern", []string{"Deploy on Fridays is forbidden"})
+
+	embeddings := map[string][]float64{
+		"Always write tests before code":          {0.9, 0.1, 0.0},
+		"Tests come first, implementation second": {0.88, 0.12, 0.01},
+		"Deploy on Fridays is forbidden":          {0.0, 0.1, 0.9},
+	}
+	for _, name := range []string{"a", "b", "c"} {
+		entity, _ := store.GetEntity(name)
+		content := entity.Observations[0]
+		obs := store.GetObservationWithID(name, content)
+		if obs == nil {
+			t.Fatalf("observation not found for %s", name)
+		}
+		if err := store.StoreEmbedding(obs.ID, embeddings[content], "test-model"); err != nil {
+			t.Fatalf("StoreEmbedding failed: %v", err)
+		}
+	}
+
+	cfg := storage.DefaultContextConfig()
+	cfg.MinImportance = 0
+
+	results, err := store.GetContextForInjection(cfg, "")
+	if err != nil {
+		t.Fatalf("GetContextForInjection failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Errorf("expected semantically similar observations to collapse to 2 results, got %d", len(results))
+	}
+}
+
+func TestStore_GetContextForInjection_BudgetShares(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+
+	if err := store.Migrate(); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	// Many high-importance dynamic facts would normally fill the whole budget
+	// before the lower-importance static conventions are reached.
+	for i := 0; i < 30; i++ {
+		name := "Dynamic" + formatIndex(i)
+		store.CreateEntity(name, "note", []string{"Recent change number " + formatIndex(i) + " in the codebase"})
+		store.SetObservationImportance(name, "Recent change number "+formatIndex(i)+" in the codebase", 0.9)
+	}
+	store.CreateEntity("Conventions", "convention", []string{"Use gofmt"})
+	store.AddObservationWithType("Conventions", "Wrap errors with context", storage.FactTypeStatic)
+
+	cfg := storage.DefaultContextConfig()
+	cfg.TokenBudget = 150
+	cfg.MinImportance = 0
+	cfg.FactTypePriority = []string{"dynamic", "static", "session_turn"}
+
+	results, err := store.GetContextForInjection(cfg, "")
+	if err != nil {
+		t.Fatalf("GetContextForInjection failed: %v", err)
+	}
+	if !containsFactType(results, "static") {
+		t.Error("expected static conventions to keep their reserved share")
+	}
+
+	cfg.BudgetShares = nil
+	results, err = store.GetContextForInjection(cfg, "")
+	if err != nil {
+		t.Fatalf("GetContextForInjection failed: %v", err)
+	}
+	if containsFactType(results, "static") {
+		t.Error("expected dynamic facts to crowd out static ones without shares")
+	}
+}
+
+func TestStore_GetContextForInjection_BudgetSharesRedistribute(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+
+	if err := store.Migrate(); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	// Only dynamic facts: their 35% share must not cap the result when
+	// the other types leave their shares unused.
+	for i := 0; i < 10; i++ {
+		name := "Entity" + formatIndex(i)
+		store.CreateEntity(name, "note", []string{"Observation " + formatIndex(i)})
+		store.SetObservationImportance(name, "Observation "+formatIndex(i), 0.8)
+	}
+
+	cfg := storage.DefaultContextConfig()
+	cfg.MinImportance = 0
+
+	results, err := store.GetContextForInjection(cfg, "")
+	if err != nil {
+		t.Fatalf("GetContextForInjection failed: %v", err)
+	}
+	if len(results) != 10 {
+		t.Errorf("expected all 10 results within budget, got %d", len(results))
+	}
+}
+
+func containsFactType(results []storage.ContextResult, factType string) bool {
+	for _, r := range results {
+		if r.FactType == factType {
+			return true
+		}
+	}
+	return false
+}
+
+func formatIndex(i int) string {
+	return string(rune('A'+i/10)) + string(rune('0'+i%10))
+}
+
+func TestStore_GetContextForInjection_Pinned(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+
+	if err := store.Migrate(); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	store.CreateEntity("Important", "pattern", []string{"High importance fact"})
+	store.CreateEntity("Rules", "convention", []string{"Never push to main"})
+	store.SetObservationImportance("Important", "High importance fact", 0.9)
+	store.SetObservationImportance("Rules", "Never push to main", 0.05) // below MinImportance
+
+	if err := store.SetObservationPinned("Rules", "Never push to main", true); err != nil {
+		t.Fatalf("SetObservationPinned failed: %v", err)
+	}
+
+	results, err := store.GetContextForInjection(storage.DefaultContextConfig(), "")
+	if err != nil {
+		t.Fatalf("GetContextForInjection failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	if !results[0].Pinned || results[0].Content != "Never push to main" {
+		t.Errorf("expected pinned memory first, got %+v", results[0])
+	}
+
+	// Pinned memories are limited to their reserved budget
+	cfg := storage.DefaultContextConfig()
+	cfg.PinnedBudget = 1
+	results, err = store.GetContextForInjection(cfg, "")
+	if err != nil {
+		t.Fatalf("GetContextForInjection failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Pinned {
+		t.Errorf("expected pinned memory to be dropped when over its budget, got %+v", results)
+	}
+}
+
+func TestStore_GetContextForInjection_ExcludesSuppressed(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+
+	if err := store.Migrate(); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	store.CreateEntity("Build", "project", []string{"Use make build", "Use task build"})
+	store.SetObservationImportance("Build", "Use make build", 0.9)
+	store.SetObservationImportance("Build", "Use task build", 0.9)
+
+	if err := store.SetObservationSuppressed("Build", "Use make build", true); err != nil {
+		t.Fatalf("SetObservationSuppressed failed: %v", err)
+	}
+
+	results, err := store.GetContextForInjection(storage.DefaultContextConfig(), "")
+	if err != nil {
+		t.Fatalf("GetContextForInjection failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Content != "Use task build" {
+		t.Errorf("expected only the unsuppressed observation, got %+v", results)
+	}
+}
+
+func TestStore_GetContextForInjection_ExcludeTypes(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+
+	if err := store.Migrate(); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	store.CreateEntity("TDD", "pattern", []string{"Test-Driven Development"})
+	store.CreateEntity("session-1", "session", []string{"Fixed the build"})
+
+	cfg := storage.DefaultContextConfig()
+	cfg.ExcludeTypes = []string{"session"}
+	results, err := store.GetContextForInjection(cfg, "")
+	if err != nil {
+		t.Fatalf("GetContextForInjection failed: %v", err)
+	}
+	if len(results) != 1 || results[0].EntityName != "TDD" {
+		t.Errorf("expected the session excluded, got %+v", results)
+	}
+}
+
+func TestStore_GetContextForInjection_ExcludesSummaries(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+
+	if err := store.Migrate(); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	store.CreateEntity("Go", "language", []string{"Fast compiler", "Has generics"})
+	sources, err := store.EntitySummarySources("Go")
+	if err != nil {
+		t.Fatalf("EntitySummarySources failed: %v", err)
+	}
+	if err := store.SaveEntitySummary("Go", "- Fast, generic", sources); err != nil {
+		t.Fatalf("SaveEntitySummary failed: %v", err)
+	}
+
+	results, err := store.GetContextForInjection(storage.DefaultContextConfig(), "")
+	if err != nil {
+		t.Fatalf("GetContextForInjection failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Errorf("expected the two source observations, got %+v", results)
+	}
+	for _, r := range results {
+		if r.FactType == string(storage.FactTypeSummary) {
+			t.Errorf("expected the cached summary left out, got %+v", r)
+		}
+	}
+}
@@ -1,7 +1,10 @@
 package storage
 
 import (
+	"context"
 	"time"
+
+	"github.com/jmoiron/sqlx"
 )
 
 // DecayConfig holds configuration for memory decay operations.
@@ -10,6 +13,11 @@ type DecayConfig struct {
 	ArchiveAfterDays    int     // Days after which to archive low-importance memories
 	ForgetAfterDays     int     // Days after which to delete expired memories
 	MinImportanceToKeep float64 // Minimum importance to avoid archival
+	DigestAfterDays     int     // Days after which archived months fold into digests; 0 never
+	// Observations kept per entity, and per container tag, the most important
+	// first; 0 keeps any number. See ArchiveExcessMemories
+	KeepPerEntity    int
+	KeepPerContainer int
 }
 
 // DefaultDecayConfig returns the default decay configuration.
@@ -30,12 +38,18 @@ type DecayResult struct {
 }
 
 // ApplySoftDecay applies decay to importance scores based on recency.
-// Observations not accessed recently have their importance reduced.
+// Observations not accessed recently have their importance reduced; pinned
+// ones and those marked useful within the last DecayConstant days are left alone.
 func (s *Store) ApplySoftDecay(threshold float64) (int, error) {
-	cfg := DefaultImportanceConfig()
+	return s.ApplySoftDecayContext(context.Background(), threshold)
+}
+
+// ApplySoftDecayContext is ApplySoftDecay with a context.
+func (s *Store) ApplySoftDecayContext(ctx context.Context, threshold float64) (int, error) {
+	cfg := s.importance
 
 	// Apply decay factor to importance based on days since last access
-	result, err := s.db.Exec(`
+	result, err := s.db.ExecContext(ctx, `
 		UPDATE observations
 		SET importance = importance * (
 			SELECT CASE
@@ -46,7 +60,9 @@ func (s *Store) ApplySoftDecay(threshold float64) (int, error) {
 		)
 		WHERE importance >= ? AND importance < 1.0
 		AND entity_id IN (SELECT id FROM entities WHERE is_latest = 1)
-	`, cfg.DecayConstant, threshold)
+		AND (last_useful IS NULL OR julianday('now') - julianday(last_useful) > ?)
+		AND COALESCE(pinned, 0) = 0
+	`, cfg.DecayConstant, threshold, cfg.DecayConstant)
 	if err != nil {
 		return 0, err
 	}
@@ -57,20 +73,28 @@ func (s *Store) ApplySoftDecay(threshold float64) (int, error) {
 
 // ArchivedObservation represents an observation that has been archived.
 type ArchivedObservation struct {
-	ID               int64     `db:"id"`
-	OriginalEntityID int64     `db:"original_entity_id"`
-	EntityName       string    `db:"entity_name"`
-	Content          string    `db:"content"`
-	FactType         string    `db:"fact_type"`
-	Importance       float64   `db:"importance"`
-	ArchivedAt       time.Time `db:"archived_at"`
+	ID               int64     `db:"id" json:"id"`
+	OriginalEntityID int64     `db:"original_entity_id" json:"originalEntityId"`
+	EntityName       string    `db:"entity_name" json:"entityName"`
+	Content          string    `db:"content" json:"content"` // Decompressed; a digest's observations one per line
+	FactType         string    `db:"fact_type" json:"factType"`
+	Importance       float64   `db:"importance" json:"importance"`
+	ArchivedAt       time.Time `db:"archived_at" json:"archivedAt"`
+	// Observations a monthly digest folds together; 0 for a single one
+	Digested int `db:"-" json:"digested,omitempty"`
 }
 
-// GetArchiveCount returns the number of archived observations.
+// GetArchiveCount returns the number of archived observations, counting
+// each observation a digest folds together.
 func (s *Store) GetArchiveCount() (int, error) {
+	return s.GetArchiveCountContext(context.Background())
+}
+
+// GetArchiveCountContext is GetArchiveCount with a context.
+func (s *Store) GetArchiveCountContext(ctx context.Context) (int, error) {
 	var count int
-	err := s.db.Get(&count, `
-		SELECT COUNT(*) FROM archived_observations
+	err := s.db.GetContext(ctx, &count, `
+		SELECT COALESCE(SUM(COALESCE(digest_count, 1)), 0) FROM archived_observations
 	`)
 	if err != nil {
 		// Table might not exist yet
@@ -79,68 +103,145 @@ func (s *Store) GetArchiveCount() (int, error) {
 	return count, nil
 }
 
-// ArchiveOldMemories moves low-importance, old observations to the archive table.
-// Returns the number of archived observations.
+// ArchiveOldMemories moves low-importance, old, unpinned observations to the
+// archive table, compressed. Returns the number of archived observations.
 func (s *Store) ArchiveOldMemories(cfg DecayConfig) (int, error) {
+	return s.ArchiveOldMemoriesContext(context.Background(), cfg)
+}
+
+// ArchiveOldMemoriesContext is ArchiveOldMemories with a context.
+func (s *Store) ArchiveOldMemoriesContext(ctx context.Context, cfg DecayConfig) (int, error) {
 	cutoffDate := time.Now().AddDate(0, 0, -cfg.ArchiveAfterDays)
 
-	// First, insert into archive (the table is created by migration)
-	result, err := s.db.Exec(`
-		INSERT INTO archived_observations (original_entity_id, entity_name, content, fact_type, importance, archived_at)
-		SELECT o.entity_id, e.name, o.content, o.fact_type, o.importance, datetime('now')
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var candidates []archiveCandidate
+	if err := tx.SelectContext(ctx, &candidates, `
+		SELECT o.id, o.entity_id, e.name, o.content,
+		       COALESCE(o.fact_type, 'dynamic') AS fact_type, COALESCE(o.importance, 1.0) AS importance
 		FROM observations o
 		JOIN entities e ON e.id = o.entity_id
 		WHERE e.is_latest = 1
 		AND o.importance < ?
-		AND COALESCE(o.last_accessed, o.created_at) < ?
+		AND COALESCE(o.last_useful, o.last_accessed, o.created_at) < ?
 		AND o.fact_type != 'static'
-	`, cfg.MinImportanceToKeep, cutoffDate.Format("2006-01-02 15:04:05"))
-	if err != nil {
+		AND COALESCE(o.pinned, 0) = 0
+	`, cfg.MinImportanceToKeep, cutoffDate.Format("2006-01-02 15:04:05")); err != nil {
 		return 0, err
 	}
-
-	archived, _ := result.RowsAffected()
-	if archived == 0 {
-		return 0, nil
+	trash := newTrashBatch(OpArchive)
+	if err := archiveObservations(ctx, tx, candidates, trash); err != nil {
+		return 0, err
+	}
+	if err := s.recordTrash(ctx, tx, trash); err != nil {
+		return 0, err
 	}
+	return len(candidates), tx.Commit()
+}
 
-	// Then delete the original observations
-	_, err = s.db.Exec(`
-		DELETE FROM observations
-		WHERE id IN (
-			SELECT o.id FROM observations o
-			JOIN entities e ON e.id = o.entity_id
-			WHERE e.is_latest = 1
-			AND o.importance < ?
-			AND COALESCE(o.last_accessed, o.created_at) < ?
-			AND o.fact_type != 'static'
-		)
-	`, cfg.MinImportanceToKeep, cutoffDate.Format("2006-01-02 15:04:05"))
+// archiveCandidate is an observation about to be archived.
+type archiveCandidate struct {
+	ID         int64   `db:"id"`
+	EntityID   int64   `db:"entity_id"`
+	EntityName string  `db:"name"`
+	Content    string  `db:"content"`
+	FactType   string  `db:"fact_type"`
+	Importance float64 `db:"importance"`
+}
 
-	return int(archived), err
+// archiveObservations moves candidates to the archive table, compressed,
+// adding what it moved to trash. The table is created by migration.
+func archiveObservations(ctx context.Context, tx *sqlx.Tx, candidates []archiveCandidate, trash *trashBatch) error {
+	for _, c := range candidates {
+		rows, err := snapshotRows(ctx, tx, "SELECT * FROM observations WHERE id = ?", c.ID)
+		if err != nil {
+			return err
+		}
+		content, gz := compressArchived(c.Content)
+		res, err := tx.ExecContext(ctx, `
+			INSERT INTO archived_observations (original_entity_id, entity_name, content, content_gz, fact_type, importance, archived_at)
+			VALUES (?, ?, ?, ?, ?, ?, datetime('now'))
+		`, c.EntityID, c.EntityName, content, gz, c.FactType, c.Importance)
+		if err != nil {
+			return err
+		}
+		archivedID, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM observations WHERE id = ?", c.ID); err != nil {
+			return err
+		}
+		snapshot := trash.entity(c.EntityName)
+		snapshot.Observations = append(snapshot.Observations, rows...)
+		snapshot.Archived = append(snapshot.Archived, archivedID)
+	}
+	return nil
 }
 
 // ForgetExpiredMemories deletes observations that have passed their forget_after date.
+// Pinned observations are kept; Undo can restore the rest within the undo window.
 // Returns the number of deleted observations.
 func (s *Store) ForgetExpiredMemories() (int, error) {
-	result, err := s.db.Exec(`
-		DELETE FROM observations
-		WHERE forget_after IS NOT NULL
-		AND forget_after < datetime('now')
-	`)
+	return s.ForgetExpiredMemoriesContext(context.Background())
+}
+
+// ForgetExpiredMemoriesContext is ForgetExpiredMemories with a context.
+func (s *Store) ForgetExpiredMemoriesContext(ctx context.Context) (int, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return 0, err
 	}
+	defer tx.Rollback()
 
-	affected, _ := result.RowsAffected()
-	return int(affected), nil
+	var expired []struct {
+		ID     int64  `db:"id"`
+		Entity string `db:"name"`
+	}
+	if err := tx.SelectContext(ctx, &expired, `
+		SELECT o.id, e.name
+		FROM observations o
+		JOIN entities e ON e.id = o.entity_id
+		WHERE o.forget_after IS NOT NULL
+		AND o.forget_after < datetime('now')
+		AND COALESCE(o.pinned, 0) = 0
+		ORDER BY o.id
+	`); err != nil {
+		return 0, err
+	}
+
+	trash := newTrashBatch(OpForget)
+	for _, o := range expired {
+		rows, err := snapshotRows(ctx, tx, "SELECT * FROM observations WHERE id = ?", o.ID)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM observations WHERE id = ?", o.ID); err != nil {
+			return 0, err
+		}
+		snapshot := trash.entity(o.Entity)
+		snapshot.Observations = append(snapshot.Observations, rows...)
+	}
+	if err := s.recordTrash(ctx, tx, trash); err != nil {
+		return 0, err
+	}
+	return len(expired), tx.Commit()
 }
 
 // ForgetOldArchivedMemories deletes archived observations older than the specified days.
 func (s *Store) ForgetOldArchivedMemories(days int) (int, error) {
+	return s.ForgetOldArchivedMemoriesContext(context.Background(), days)
+}
+
+// ForgetOldArchivedMemoriesContext is ForgetOldArchivedMemories with a context.
+func (s *Store) ForgetOldArchivedMemoriesContext(ctx context.Context, days int) (int, error) {
 	cutoffDate := time.Now().AddDate(0, 0, -days)
 
-	result, err := s.db.Exec(`
+	result, err := s.db.ExecContext(ctx, `
 		DELETE FROM archived_observations
 		WHERE archived_at < ?
 	`, cutoffDate.Format("2006-01-02 15:04:05"))
@@ -163,10 +264,15 @@ type DecayStats struct {
 
 // GetDecayStats returns statistics about memory decay status.
 func (s *Store) GetDecayStats() (*DecayStats, error) {
+	return s.GetDecayStatsContext(context.Background())
+}
+
+// GetDecayStatsContext is GetDecayStats with a context.
+func (s *Store) GetDecayStatsContext(ctx context.Context) (*DecayStats, error) {
 	var stats DecayStats
 
 	// Count observations
-	err := s.db.Get(&stats.TotalObservations, `
+	err := s.db.GetContext(ctx, &stats.TotalObservations, `
 		SELECT COUNT(*) FROM observations o
 		JOIN entities e ON e.id = o.entity_id
 		WHERE e.is_latest = 1
@@ -176,7 +282,7 @@ func (s *Store) GetDecayStats() (*DecayStats, error) {
 	}
 
 	// Count low importance
-	err = s.db.Get(&stats.LowImportance, `
+	err = s.db.GetContext(ctx, &stats.LowImportance, `
 		SELECT COUNT(*) FROM observations o
 		JOIN entities e ON e.id = o.entity_id
 		WHERE e.is_latest = 1 AND o.importance < 0.3
@@ -186,10 +292,10 @@ func (s *Store) GetDecayStats() (*DecayStats, error) {
 	}
 
 	// Count archived
-	stats.ArchivedCount, _ = s.GetArchiveCount()
+	stats.ArchivedCount, _ = s.GetArchiveCountContext(ctx)
 
 	// Count expired (past forget_after)
-	err = s.db.Get(&stats.ExpiredCount, `
+	err = s.db.GetContext(ctx, &stats.ExpiredCount, `
 		SELECT COUNT(*) FROM observations
 		WHERE forget_after IS NOT NULL AND forget_after < datetime('now')
 	`)
@@ -198,7 +304,7 @@ func (s *Store) GetDecayStats() (*DecayStats, error) {
 	}
 
 	// Average importance
-	err = s.db.Get(&stats.AvgImportance, `
+	err = s.db.GetContext(ctx, &stats.AvgImportance, `
 		SELECT COALESCE(AVG(importance), 0) FROM observations o
 		JOIN entities e ON e.id = o.entity_id
 		WHERE e.is_latest = 1
@@ -212,7 +318,12 @@ func (s *Store) GetDecayStats() (*DecayStats, error) {
 
 // SetForgetAfter sets the forget_after date for observations of an entity.
 func (s *Store) SetForgetAfter(entityName string, forgetAfter time.Time) error {
-	_, err := s.db.Exec(`
+	return s.SetForgetAfterContext(context.Background(), entityName, forgetAfter)
+}
+
+// SetForgetAfterContext is SetForgetAfter with a context.
+func (s *Store) SetForgetAfterContext(ctx context.Context, entityName string, forgetAfter time.Time) error {
+	_, err := s.db.ExecContext(ctx, `
 		UPDATE observations
 		SET forget_after = ?
 		WHERE entity_id = (SELECT id FROM entities WHERE name = ? AND is_latest = 1)
@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"sort"
+)
+
+// DecayProjection describes what happens to one observation during a decay simulation.
+type DecayProjection struct {
+	ObservationID       int64
+	EntityName          string
+	Content             string
+	FactType            string
+	Importance          float64 // Importance today
+	ProjectedImportance float64 // Importance at the end of the horizon, or when archived
+	ArchiveDay          int     // Day the observation is archived; -1 if it survives the horizon
+	ForgetDay           int     // Day the observation is deleted; -1 if it survives the horizon
+}
+
+// DecaySimulation is the result of projecting decay forward without modifying anything.
+type DecaySimulation struct {
+	Days      int
+	Total     int               // Observations considered
+	Pinned    int               // Pinned observations, exempt from decay
+	Archived  []DecayProjection // Observations archived within the horizon, by day
+	Forgotten []DecayProjection // Observations deleted within the horizon, by day
+}
+
+// SimulateDecay projects which observations would fall below the archive and
+// forget thresholds over the next days, assuming nothing is accessed again and
+// soft decay runs daily. Nothing is modified.
+func (s *Store) SimulateDecay(cfg DecayConfig, days int) (*DecaySimulation, error) {
+	return s.SimulateDecayContext(context.Background(), cfg, days)
+}
+
+// SimulateDecayContext is SimulateDecay with a context.
+func (s *Store) SimulateDecayContext(ctx context.Context, cfg DecayConfig, days int) (*DecaySimulation, error) {
+	var rows []struct {
+		ID           int64           `db:"id"`
+		EntityName   string          `db:"entity_name"`
+		Content      string          `db:"content"`
+		FactType     string          `db:"fact_type"`
+		Importance   float64         `db:"importance"`
+		Pinned       bool            `db:"pinned"`
+		IdleDays     float64         `db:"idle_days"`
+		UsefulDays   sql.NullFloat64 `db:"useful_days"`
+		ForgetInDays sql.NullFloat64 `db:"forget_in_days"`
+	}
+	err := s.db.SelectContext(ctx, &rows, `
+		SELECT o.id, e.name as entity_name, o.content,
+		       COALESCE(o.fact_type, 'dynamic') as fact_type,
+		       COALESCE(o.importance, 1.0) as importance,
+		       COALESCE(o.pinned, 0) as pinned,
+		       COALESCE(julianday('now') - julianday(COALESCE(o.last_useful, o.last_accessed, o.created_at)), 0) as idle_days,
+		       julianday('now') - julianday(o.last_useful) as useful_days,
+		       julianday(o.forget_after) - julianday('now') as forget_in_days
+		FROM observations o
+		JOIN entities e ON e.id = o.entity_id
+		WHERE e.is_latest = 1
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	sim := &DecaySimulation{Days: days, Total: len(rows)}
+	decayPerDay := math.Exp(-1 / s.importance.DecayConstant)
+
+	for _, r := range rows {
+		if r.Pinned {
+			sim.Pinned++
+			continue
+		}
+
+		p := DecayProjection{
+			ObservationID: r.ID,
+			EntityName:    r.EntityName,
+			Content:       r.Content,
+			FactType:      r.FactType,
+			Importance:    r.Importance,
+			ArchiveDay:    -1,
+			ForgetDay:     -1,
+		}
+
+		// Mirror ApplySoftDecay and ArchiveOldMemories one day at a time
+		importance := r.Importance
+		for day := 0; day <= days; day++ {
+			if day > 0 && importance >= cfg.SoftDecayThreshold && importance < 1.0 &&
+				(!r.UsefulDays.Valid || r.UsefulDays.Float64+float64(day) > s.importance.DecayConstant) {
+				importance *= decayPerDay
+			}
+			if r.FactType != string(FactTypeStatic) && importance < cfg.MinImportanceToKeep &&
+				r.IdleDays+float64(day) > float64(cfg.ArchiveAfterDays) {
+				p.ArchiveDay = day
+				break
+			}
+		}
+		p.ProjectedImportance = importance
+
+		if r.ForgetInDays.Valid {
+			if day := max(0, int(math.Ceil(r.ForgetInDays.Float64))); day <= days &&
+				(p.ArchiveDay < 0 || day < p.ArchiveDay) {
+				p.ForgetDay = day
+				p.ArchiveDay = -1
+			}
+		}
+		if p.ArchiveDay >= 0 && cfg.ForgetAfterDays > 0 && p.ArchiveDay+cfg.ForgetAfterDays <= days {
+			p.ForgetDay = p.ArchiveDay + cfg.ForgetAfterDays
+		}
+
+		if p.ArchiveDay >= 0 {
+			sim.Archived = append(sim.Archived, p)
+		}
+		if p.ForgetDay >= 0 {
+			sim.Forgotten = append(sim.Forgotten, p)
+		}
+	}
+
+	sort.SliceStable(sim.Archived, func(i, j int) bool { return sim.Archived[i].ArchiveDay < sim.Archived[j].ArchiveDay })
+	sort.SliceStable(sim.Forgotten, func(i, j int) bool { return sim.Forgotten[i].ForgetDay < sim.Forgotten[j].ForgetDay })
+
+	return sim, nil
+}
@@ -155,6 +155,46 @@ func TestStore_ArchiveOldMemories(t *testing.T) {
 	}
 }
 
+func TestStore_ArchiveOldMemories_SkipsPinned(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+
+	if err := store.Migrate(); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	store.CreateEntity("Old", "test", []string{"Pinned old memory"})
+	store.SetObservationImportance("Old", "Pinned old memory", 0.05)
+	if err := store.SetObservationPinned("Old", "Pinned old memory", true); err != nil {
+		t.Fatalf("SetObservationPinned failed: %v", err)
+	}
+
+	_, err := store.DB().Exec(`
+		UPDATE observations SET last_accessed = datetime('now', '-120 days'),
+		                        forget_after = datetime('now', '-1 day')
+		WHERE content = 'Pinned old memory'
+	`)
+	if err != nil {
+		t.Fatalf("Failed to set old timestamp: %v", err)
+	}
+
+	archived, err := store.ArchiveOldMemories(storage.DefaultDecayConfig())
+	if err != nil {
+		t.Fatalf("ArchiveOldMemories failed: %v", err)
+	}
+	if archived != 0 {
+		t.Errorf("expected pinned memory not to be archived, got %d", archived)
+	}
+
+	forgotten, err := store.ForgetExpiredMemories()
+	if err != nil {
+		t.Fatalf("ForgetExpiredMemories failed: %v", err)
+	}
+	if forgotten != 0 {
+		t.Errorf("expected pinned memory not to be forgotten, got %d", forgotten)
+	}
+}
+
 func TestStore_ForgetOldArchivedMemories(t *testing.T) {
 	store := newTestStore(t)
 	defer store.Close()
@@ -209,3 +249,47 @@ func TestStore_SetForgetAfter(t *testing.T) {
 		t.Errorf("expected 0 deleted (not expired yet), got %d", deleted)
 	}
 }
+
+func TestStore_SimulateDecay(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+
+	if err := store.Migrate(); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	store.CreateEntity("Old", "note", []string{"Stale detail", "Pinned detail"})
+	store.CreateEntity("Convention", "pattern", nil)
+	store.AddObservationWithType("Convention", "Static rule", storage.FactTypeStatic)
+	store.CreateEntity("Temp", "note", []string{"Expiring note"})
+
+	store.SetObservationImportance("Old", "Stale detail", 0.05)
+	store.SetObservationImportance("Old", "Pinned detail", 0.05)
+	store.SetObservationImportance("Convention", "Static rule", 0.05)
+	store.SetObservationPinned("Old", "Pinned detail", true)
+	store.DB().Exec(`UPDATE observations SET created_at = datetime('now', '-80 days')`)
+	store.SetForgetAfter("Temp", time.Now().Add(10*24*time.Hour))
+
+	cfg := storage.DefaultDecayConfig()
+	sim, err := store.SimulateDecay(cfg, 30)
+	if err != nil {
+		t.Fatalf("SimulateDecay failed: %v", err)
+	}
+
+	if sim.Total != 4 || sim.Pinned != 1 {
+		t.Errorf("expected 4 total and 1 pinned, got %d and %d", sim.Total, sim.Pinned)
+	}
+	// 80 days idle passes the 90-day archive cutoff on day 10; static facts are never archived
+	if len(sim.Archived) != 1 || sim.Archived[0].Content != "Stale detail" || sim.Archived[0].ArchiveDay != 10 {
+		t.Errorf("expected Stale detail archived on day 10, got %+v", sim.Archived)
+	}
+	if len(sim.Forgotten) != 1 || sim.Forgotten[0].Content != "Expiring note" || sim.Forgotten[0].ForgetDay != 10 {
+		t.Errorf("expected Expiring note forgotten on day 10, got %+v", sim.Forgotten)
+	}
+
+	// Simulation must not modify anything
+	stats, _ := store.GetDecayStats()
+	if stats.TotalObservations != 4 || stats.ArchivedCount != 0 {
+		t.Errorf("simulation modified the store: %+v", stats)
+	}
+}
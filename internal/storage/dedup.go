@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// minDedupWords is the shortest normalized observation that can absorb others
+// by substring match; single words like "Go" would otherwise match everything.
+const minDedupWords = 2
+
+// dedupContextResults drops observations that repeat an earlier, higher-ranked one.
+// Two observations are duplicates when one's normalized text contains the other's
+// on word boundaries, or when both have embeddings with cosine similarity >= similarity.
+// A similarity of 0 disables the embedding check.
+func (s *Store) dedupContextResults(ctx context.Context, results []ContextResult, similarity float64) ([]ContextResult, error) {
+	if len(results) < 2 {
+		return results, nil
+	}
+
+	var embeddings map[int64][]float64
+	if similarity > 0 {
+		var err error
+		embeddings, err = s.loadContextEmbeddings(ctx, results)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	type kept struct {
+		normalized string
+		embedding  []float64
+	}
+	var seen []kept
+	deduped := make([]ContextResult, 0, len(results))
+
+	for _, r := range results {
+		// Comparing against every kept result is quadratic; stop when the caller gives up
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		normalized := normalizeForDedup(r.Content)
+		embedding := embeddings[r.ObservationID]
+
+		duplicate := false
+		for _, k := range seen {
+			if containsNormalized(k.normalized, normalized) ||
+				(embedding != nil && k.embedding != nil && CosineSimilarity(embedding, k.embedding) >= similarity) {
+				duplicate = true
+				break
+			}
+		}
+		if duplicate {
+			continue
+		}
+
+		seen = append(seen, kept{normalized: normalized, embedding: embedding})
+		deduped = append(deduped, r)
+	}
+
+	return deduped, nil
+}
+
+// loadContextEmbeddings fetches stored embeddings for the given results, keyed by observation ID.
+func (s *Store) loadContextEmbeddings(ctx context.Context, results []ContextResult) (map[int64][]float64, error) {
+	ids := make([]int64, 0, len(results))
+	for _, r := range results {
+		if r.ObservationID != 0 {
+			ids = append(ids, r.ObservationID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	// Query in chunks: large graphs exceed SQLite's bound-variable limit
+	const chunk = 1000
+	embeddings := make(map[int64][]float64, len(ids))
+	for start := 0; start < len(ids); start += chunk {
+		query, args, err := sqlx.In(
+			"SELECT observation_id, embedding FROM observation_embeddings WHERE observation_id IN (?)",
+			ids[start:min(start+chunk, len(ids))])
+		if err != nil {
+			return nil, err
+		}
+
+		var rows []struct {
+			ObservationID int64  `db:"observation_id"`
+			Embedding     []byte `db:"embedding"`
+		}
+		if err := s.db.SelectContext(ctx, &rows, s.db.Rebind(query), args...); err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			embeddings[row.ObservationID] = decodeEmbedding(row.Embedding)
+		}
+	}
+	return embeddings, nil
+}
+
+// normalizeForDedup lowercases text and collapses punctuation and whitespace to single spaces.
+func normalizeForDedup(text string) string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(fields, " ")
+}
+
+// containsNormalized reports whether either normalized text contains the other on word boundaries.
+func containsNormalized(a, b string) bool {
+	if a == b {
+		return a != ""
+	}
+	shorter, longer := a, b
+	if len(shorter) > len(longer) {
+		shorter, longer = longer, shorter
+	}
+	if len(strings.Fields(shorter)) < minDedupWords {
+		return false
+	}
+	return strings.Contains(" "+longer+" ", " "+shorter+" ")
+}
@@ -0,0 +1,328 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DocumentSummary is what SummarizeDocument keeps of a document.
+type DocumentSummary struct {
+	Title        string   // The first top-level heading, or front matter title; "" when it has none
+	Observations []string // Lead sentences and key bullets, prefixed with their section
+}
+
+const (
+	// maxDocumentObservations bounds the observations kept of one document.
+	maxDocumentObservations = 25
+	// maxSectionBullets bounds the bullets kept of one section.
+	maxSectionBullets = 3
+	// maxDocumentObservationLen bounds one observation, in bytes.
+	maxDocumentObservationLen = 300
+)
+
+var (
+	docHeading    = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*$`)
+	docBullet     = regexp.MustCompile(`^(?:[-*+]|\d+[.)])\s+(?:\[[ xX]\]\s+)?(.*)$`)
+	docRule       = regexp.MustCompile(`^(?:[-*_]\s*){3,}$|^=+$`)
+	docStatus     = regexp.MustCompile(`(?i)^(status|date|deciders|supersedes|superseded by)\s*:\s*(.+)$`)
+	docImage      = regexp.MustCompile(`!\[[^\]]*\]\([^)]*\)`)
+	docLink       = regexp.MustCompile(`\[([^\]]+)\]\([^)]*\)`)
+	docTag        = regexp.MustCompile(`<[^>]+>`)
+	docEmphasis   = strings.NewReplacer("**", "", "__", "")
+	docWhitespace = regexp.MustCompile(`\s+`)
+)
+
+// SummarizeDocument keeps the gist of a Markdown document (plain text reads
+// as a document without headings) by heuristics: the first sentence of the
+// opening paragraph and of each section, the first few top-level bullets of
+// each section, and ADR fields like "Status: Accepted". Each observation is
+// prefixed with the heading of its section, or of the document for the
+// opening; a list of the top-level sections follows the opening. Code blocks, tables,
+// images, and HTML comments are skipped.
+func SummarizeDocument(text string) DocumentSummary {
+	var sum DocumentSummary
+	seen := map[string]bool{}
+	add := func(section, s string) {
+		s = cleanDocText(s)
+		if s == "" {
+			return
+		}
+		if section != "" && !strings.EqualFold(section, s) {
+			s = section + ": " + s
+		}
+		s = clipDocText(s)
+		if !seen[s] && len(sum.Observations) < maxDocumentObservations {
+			seen[s] = true
+			sum.Observations = append(sum.Observations, s)
+		}
+	}
+
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	start := 0
+	if len(lines) > 0 && strings.TrimSpace(lines[0]) == "---" {
+		for i := 1; i < len(lines); i++ {
+			line := strings.TrimSpace(lines[i])
+			if line == "---" {
+				start = i + 1
+				break
+			}
+			if title, ok := strings.CutPrefix(line, "title:"); ok {
+				sum.Title = strings.Trim(strings.TrimSpace(title), `"'`)
+			}
+		}
+	}
+
+	var (
+		sections []string // Top-level sections
+		top      int      // Heading level of top-level sections
+		parent   string   // The top-level section of the current one
+		leadEnd  = -1     // Observations before the first section, once known
+		section  string
+		led      bool // The section's first paragraph was taken
+		bullets  int
+		para     []string
+		fence    string
+		comment  bool
+	)
+	flush := func() {
+		if len(para) > 0 && !led {
+			add(section, firstSentence(strings.Join(para, " ")))
+			led = true
+		}
+		para = nil
+	}
+	// Sections below the top level are named under their parent, as in
+	// "Why SQLite? / Decision"
+	startSection := func(level int, heading string) {
+		flush()
+		if leadEnd < 0 {
+			leadEnd = len(sum.Observations)
+		}
+		section = cleanDocText(heading)
+		if top == 0 || level <= top {
+			top, parent = level, section
+			sections = append(sections, section)
+		} else {
+			section = parent + " / " + section
+		}
+		led, bullets = false, 0
+	}
+
+	for _, raw := range lines[start:] {
+		line := strings.TrimSpace(raw)
+		switch {
+		case fence != "":
+			if strings.HasPrefix(line, fence) {
+				fence = ""
+			}
+			continue
+		case comment:
+			comment = !strings.Contains(line, "-->")
+			continue
+		case strings.HasPrefix(line, "```"), strings.HasPrefix(line, "~~~"):
+			flush()
+			fence = line[:3]
+			continue
+		case strings.HasPrefix(line, "<!--"):
+			flush()
+			comment = !strings.Contains(line, "-->")
+			continue
+		}
+
+		if m := docHeading.FindStringSubmatch(line); m != nil {
+			if len(m[1]) == 1 && sum.Title == "" && len(sections) == 0 {
+				flush()
+				sum.Title, section = cleanDocText(m[2]), cleanDocText(m[2])
+				led = false
+				continue
+			}
+			startSection(len(m[1]), m[2])
+			continue
+		}
+		if docRule.MatchString(line) {
+			// A setext underline makes the paragraph above it a heading
+			if len(para) == 1 && (line[0] == '=' || line[0] == '-') {
+				heading := para[0]
+				para = nil
+				if line[0] == '=' && sum.Title == "" && len(sections) == 0 {
+					sum.Title, section, led = cleanDocText(heading), cleanDocText(heading), false
+				} else if line[0] == '=' {
+					startSection(1, heading)
+				} else {
+					startSection(2, heading)
+				}
+				continue
+			}
+			flush()
+			continue
+		}
+		if m := docStatus.FindStringSubmatch(line); m != nil && len(para) == 0 {
+			add("", m[1]+": "+m[2])
+			continue
+		}
+		if m := docBullet.FindStringSubmatch(line); m != nil {
+			flush()
+			indent := len(raw) - len(strings.TrimLeft(raw, " \t"))
+			if indent < 2 && bullets < maxSectionBullets {
+				if status := docStatus.FindStringSubmatch(cleanDocText(m[1])); status != nil {
+					add("", status[1]+": "+status[2])
+				} else {
+					add(section, m[1])
+					bullets++
+				}
+			}
+			continue
+		}
+		switch {
+		case line == "", strings.HasPrefix(line, "|"), strings.HasPrefix(line, "!["),
+			strings.HasPrefix(line, "[!["), strings.HasPrefix(line, "<"):
+			flush()
+		case strings.HasPrefix(line, ">"):
+			para = append(para, strings.TrimSpace(strings.TrimLeft(line, "> ")))
+		default:
+			para = append(para, line)
+		}
+	}
+	flush()
+
+	if len(sections) >= 2 {
+		if leadEnd < 0 {
+			leadEnd = len(sum.Observations)
+		}
+		list := clipDocText("Sections: " + strings.Join(sections, ", "))
+		if !seen[list] {
+			sum.Observations = slices.Insert(sum.Observations, leadEnd, list)
+			if len(sum.Observations) > maxDocumentObservations {
+				sum.Observations = sum.Observations[:maxDocumentObservations]
+			}
+		}
+	}
+	return sum
+}
+
+// cleanDocText drops Markdown images, link targets, emphasis, and HTML tags
+// from s and collapses its whitespace.
+func cleanDocText(s string) string {
+	s = docImage.ReplaceAllString(s, "")
+	s = docLink.ReplaceAllString(s, "$1")
+	s = docTag.ReplaceAllString(s, "")
+	s = docEmphasis.Replace(s)
+	return strings.TrimSpace(docWhitespace.ReplaceAllString(s, " "))
+}
+
+// firstSentence returns s up to the end of its first sentence: a ., !, or ?
+// followed by a space and a capital letter, so "e.g. this" doesn't end one.
+func firstSentence(s string) string {
+	for i := 0; i < len(s)-2; i++ {
+		if strings.IndexByte(".!?", s[i]) < 0 || s[i+1] != ' ' {
+			continue
+		}
+		if r, _ := utf8.DecodeRuneInString(s[i+2:]); unicode.IsUpper(r) {
+			return s[:i+1]
+		}
+	}
+	return s
+}
+
+// clipDocText shortens s to maxDocumentObservationLen at a word boundary.
+func clipDocText(s string) string {
+	if len(s) <= maxDocumentObservationLen {
+		return s
+	}
+	cut := maxDocumentObservationLen - len("…")
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	if space := strings.LastIndexByte(s[:cut], ' '); space > cut/2 {
+		cut = space
+	}
+	return strings.TrimRight(s[:cut], " ,;:") + "…"
+}
+
+// IndexedDocument is a document for IndexDocument to record.
+type IndexedDocument struct {
+	Name     string    // Entity name
+	Type     string    // Entity type, e.g. "document" or "decision"
+	Project  string    // Container tag
+	Path     string    // Where the document is, relative to the indexed directory
+	Modified time.Time // When the document was last changed
+	Summary  DocumentSummary
+}
+
+// DocumentMtimeLayout formats the mtime attribute IndexDocument records.
+const DocumentMtimeLayout = time.RFC3339Nano
+
+// IndexDocument creates or updates doc's entity, tagged with doc.Project and
+// with path and mtime attributes. Its observations become static facts from
+// source import:<path>; those a previous index of the path recorded that the
+// summary no longer has are deleted, while observations added any other way
+// stay. It returns how many observations it added and deleted.
+func (s *Store) IndexDocument(doc IndexedDocument) (added, removed int, err error) {
+	return s.IndexDocumentContext(context.Background(), doc)
+}
+
+// IndexDocumentContext is IndexDocument with a context.
+func (s *Store) IndexDocumentContext(ctx context.Context, doc IndexedDocument) (added, removed int, err error) {
+	if doc.Path == "" {
+		return 0, 0, &ValidationError{Field: "path", Reason: "must not be empty"}
+	}
+	source := SourceImport + ":" + doc.Path
+	ctx = WithSource(ctx, source)
+
+	if _, err := s.CreateEntityContext(ctx, doc.Name, doc.Type, nil); err != nil && !errors.Is(err, ErrEntityExists) {
+		return 0, 0, err
+	}
+
+	var previous []string
+	if err := s.db.SelectContext(ctx, &previous, `
+		SELECT o.content FROM observations o JOIN entities e ON e.id = o.entity_id
+		WHERE `+s.nameMatch("e.name")+` AND e.is_latest = 1 AND o.source = ?
+		ORDER BY o.id`, NormalizeName(doc.Name), source); err != nil {
+		return 0, 0, err
+	}
+	// Stored observations have secrets redacted; compare them that way
+	stored := func(obs string) string {
+		redacted, _ := s.RedactSecrets(obs)
+		return redacted
+	}
+	keep := make(map[string]bool, len(doc.Summary.Observations))
+	for _, obs := range doc.Summary.Observations {
+		keep[stored(obs)] = true
+	}
+	for _, obs := range previous {
+		if keep[obs] {
+			delete(keep, obs)
+			continue
+		}
+		if err := s.DeleteObservationContext(ctx, doc.Name, obs); err != nil {
+			return added, removed, err
+		}
+		removed++
+	}
+	for _, obs := range doc.Summary.Observations {
+		if !keep[stored(obs)] {
+			continue
+		}
+		if err := s.AddObservationWithTypeContext(ctx, doc.Name, obs, FactTypeStatic); err != nil {
+			return added, removed, err
+		}
+		added++
+	}
+
+	if doc.Project != "" {
+		if err := s.SetContainerTagContext(ctx, doc.Name, doc.Project); err != nil {
+			return added, removed, err
+		}
+	}
+	err = s.SetAttributesContext(ctx, doc.Name, map[string]string{
+		"path":  doc.Path,
+		"mtime": doc.Modified.UTC().Format(DocumentMtimeLayout),
+	})
+	return added, removed, err
+}
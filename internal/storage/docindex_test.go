@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSummarizeDocument(t *testing.T) {
+	doc := `# mark42
+
+[![CI](https://example.com/badge.svg)](https://example.com)
+
+Persistent memory for **Claude Code**, backed by SQLite. It runs locally.
+
+## Installation
+
+Run ` + "`make install`" + ` to build the [binary](docs/BUILD.md). It needs Go 1.25.
+
+` + "```bash\nmake install\n```" + `
+
+## Features
+
+- Knowledge graph of entities
+- Hybrid search, e.g. FTS5 and vectors
+  - nested detail is skipped
+- Context injection
+- Decay
+
+### Extras
+
+- Custom tools
+<!-- a comment
+spanning lines -->
+
+| Table | Row |
+|-------|-----|
+`
+	sum := SummarizeDocument(doc)
+	if sum.Title != "mark42" {
+		t.Errorf("expected the title mark42, got %q", sum.Title)
+	}
+	want := []string{
+		"mark42: Persistent memory for Claude Code, backed by SQLite.",
+		"Sections: Installation, Features",
+		"Installation: Run `make install` to build the binary.",
+		"Features: Knowledge graph of entities",
+		"Features: Hybrid search, e.g. FTS5 and vectors",
+		"Features: Context injection",
+		"Features / Extras: Custom tools",
+	}
+	if !slices.Equal(sum.Observations, want) {
+		t.Errorf("unexpected observations:\n%s\nwant:\n%s", strings.Join(sum.Observations, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestSummarizeDocument_ADR(t *testing.T) {
+	sum := SummarizeDocument(`---
+title: "ADR 7: Use SQLite"
+---
+Status: Accepted
+Date: 2026-01-05
+
+Context
+-------
+
+We need storage that works offline. Servers are overkill.
+
+Decision
+--------
+
+Use SQLite with WAL.
+`)
+	want := []string{
+		"Status: Accepted",
+		"Date: 2026-01-05",
+		"Sections: Context, Decision",
+		"Context: We need storage that works offline.",
+		"Decision: Use SQLite with WAL.",
+	}
+	if sum.Title != "ADR 7: Use SQLite" || !slices.Equal(sum.Observations, want) {
+		t.Errorf("unexpected summary %q:\n%s", sum.Title, strings.Join(sum.Observations, "\n"))
+	}
+
+	if sum := SummarizeDocument("Just some notes without headings. More here."); len(sum.Observations) != 1 ||
+		sum.Observations[0] != "Just some notes without headings." {
+		t.Errorf("expected plain text's first sentence, got %v", sum.Observations)
+	}
+	long := SummarizeDocument(strings.Repeat("word ", 200))
+	if len(long.Observations) != 1 || len(long.Observations[0]) > maxDocumentObservationLen || !strings.HasSuffix(long.Observations[0], "…") {
+		t.Errorf("expected a clipped observation, got %v", long.Observations)
+	}
+}
+
+func TestIndexDocument(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	modified := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	doc := IndexedDocument{
+		Name: "mark42/README.md", Type: "document", Project: "mark42", Path: "README.md", Modified: modified,
+		Summary: DocumentSummary{Observations: []string{"mark42: Memory for Claude Code.", "Features: Hybrid search"}},
+	}
+	if added, removed, err := store.IndexDocument(doc); err != nil || added != 2 || removed != 0 {
+		t.Fatalf("IndexDocument = %d, %d, %v; want 2 added", added, removed, err)
+	}
+	if err := store.AddObservation("mark42/README.md", "Reviewed by hand"); err != nil {
+		t.Fatal(err)
+	}
+
+	doc.Modified = modified.Add(time.Hour)
+	doc.Summary.Observations = []string{"mark42: Memory for Claude Code.", "Features: Decay"}
+	if added, removed, err := store.IndexDocument(doc); err != nil || added != 1 || removed != 1 {
+		t.Fatalf("re-index = %d, %d, %v; want 1 added and 1 removed", added, removed, err)
+	}
+
+	entity, err := store.GetEntity("mark42/README.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"mark42: Memory for Claude Code.", "Reviewed by hand", "Features: Decay"}
+	if !slices.Equal(entity.Observations, want) {
+		t.Errorf("expected %v, got %v", want, entity.Observations)
+	}
+	attrs, err := store.GetAttributes("mark42/README.md")
+	if err != nil || attrs["path"] != "README.md" || attrs["mtime"] != "2026-10-01T13:00:00Z" {
+		t.Errorf("unexpected attributes %v (%v)", attrs, err)
+	}
+	if tag, err := store.GetContainerTag("mark42/README.md"); err != nil || tag != "mark42" {
+		t.Errorf("expected the container tag mark42, got %q (%v)", tag, err)
+	}
+	var source, factType string
+	if err := store.db.QueryRow("SELECT source, fact_type FROM observations WHERE content = 'Features: Decay'").Scan(&source, &factType); err != nil ||
+		source != "import:README.md" || factType != "static" {
+		t.Errorf("expected a static fact from import:README.md, got %q, %q (%v)", source, factType, err)
+	}
+}
@@ -0,0 +1,6 @@
+//go:build libsql
+
+package storage
+
+// Registers the "libsql" database/sql driver for libsql:// DSNs.
+import _ "github.com/tursodatabase/libsql-client-go/libsql"
@@ -0,0 +1,21 @@
+//go:build libsql
+
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestLibSQLDriverRegistered(t *testing.T) {
+	if !slices.Contains(sql.Drivers(), libsqlDriver) {
+		t.Fatalf("expected the %q driver registered, got %v", libsqlDriver, sql.Drivers())
+	}
+	// Nothing listens on port 1, so opening fails past the driver check
+	_, err := NewStore("libsql://127.0.0.1:1?tls=0")
+	if err == nil || errors.Is(err, ErrLibSQLUnavailable) {
+		t.Errorf("expected a connection error, got %v", err)
+	}
+}
@@ -0,0 +1,6 @@
+//go:build postgres
+
+package storage
+
+// Registers the "postgres" database/sql driver for postgres:// DSNs.
+import _ "github.com/lib/pq"
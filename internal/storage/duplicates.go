@@ -0,0 +1,237 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"unicode"
+)
+
+// DuplicateCandidate is a pair of entities FindDuplicates judges likely to be
+// the same thing. Keep is the one to merge Merge into: the entity with more
+// observations, or else the older one.
+type DuplicateCandidate struct {
+	Keep      string  `json:"keep"`
+	KeepType  string  `json:"keepType"`
+	Merge     string  `json:"merge"`
+	MergeType string  `json:"mergeType"`
+	Score     float64 `json:"score"` // The higher of the two similarities
+	// 1 when the names match ignoring case, spacing, and punctuation
+	NameSimilarity float64 `json:"nameSimilarity"`
+	// Cosine similarity of the mean observation embeddings; 0 when not compared
+	EmbeddingSimilarity float64 `json:"embeddingSimilarity,omitempty"`
+}
+
+// DuplicateOptions tunes FindDuplicates.
+type DuplicateOptions struct {
+	// MinNameSimilarity is the name similarity that makes a pair a candidate.
+	// Default 0.85.
+	MinNameSimilarity float64
+	// Embeddings also compares the mean embedding of each entity's
+	// observations, catching duplicates under unrelated names.
+	Embeddings bool
+	// MinEmbeddingSimilarity is the embedding similarity that makes a pair a
+	// candidate. Default 0.92.
+	MinEmbeddingSimilarity float64
+}
+
+// duplicateEntity is an entity as FindDuplicates compares it.
+type duplicateEntity struct {
+	ID           int64  `db:"id"`
+	Name         string `db:"name"`
+	Type         string `db:"entity_type"`
+	Observations int    `db:"observations"`
+	key          []rune
+	embedding    []float64
+}
+
+// FindDuplicates returns pairs of entities whose names are nearly the same
+// or, with opts.Embeddings, whose observations say nearly the same things,
+// most similar first. Sessions are left out.
+func (s *Store) FindDuplicates(opts DuplicateOptions) ([]DuplicateCandidate, error) {
+	return s.FindDuplicatesContext(context.Background(), opts)
+}
+
+// FindDuplicatesContext is FindDuplicates with a context.
+func (s *Store) FindDuplicatesContext(ctx context.Context, opts DuplicateOptions) ([]DuplicateCandidate, error) {
+	if opts.MinNameSimilarity <= 0 {
+		opts.MinNameSimilarity = 0.85
+	}
+	if opts.MinEmbeddingSimilarity <= 0 {
+		opts.MinEmbeddingSimilarity = 0.92
+	}
+
+	var entities []*duplicateEntity
+	if err := s.db.SelectContext(ctx, &entities, `
+		SELECT e.id, e.name, e.entity_type,
+		       (SELECT COUNT(*) FROM observations o WHERE o.entity_id = e.id) as observations
+		FROM entities e
+		WHERE (e.is_latest = 1 OR e.is_latest IS NULL) AND e.entity_type != 'session'`); err != nil {
+		return nil, err
+	}
+	for _, e := range entities {
+		e.key = []rune(duplicateKey(e.Name))
+	}
+	if opts.Embeddings {
+		if err := s.loadMeanEmbeddings(ctx, entities); err != nil {
+			return nil, err
+		}
+	}
+
+	// Sorted by key length, a name can only be similar enough to the names
+	// after it until their lengths differ by more than the threshold allows
+	sort.Slice(entities, func(i, j int) bool { return len(entities[i].key) < len(entities[j].key) })
+
+	var candidates []DuplicateCandidate
+	for i, a := range entities {
+		// Comparing every pair is quadratic; stop when the caller gives up
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		for _, b := range entities[i+1:] {
+			nameSim := 0.0
+			if lengthSimilarity(len(a.key), len(b.key)) >= opts.MinNameSimilarity {
+				nameSim = nameSimilarity(a.key, b.key)
+			} else if !opts.Embeddings {
+				break
+			}
+			embeddingSim := 0.0
+			if a.embedding != nil && b.embedding != nil {
+				embeddingSim = CosineSimilarity(a.embedding, b.embedding)
+			}
+			if nameSim < opts.MinNameSimilarity && embeddingSim < opts.MinEmbeddingSimilarity {
+				continue
+			}
+
+			keep, merge := a, b
+			if merge.Observations > keep.Observations || merge.Observations == keep.Observations && merge.ID < keep.ID {
+				keep, merge = merge, keep
+			}
+			candidates = append(candidates, DuplicateCandidate{
+				Keep:                keep.Name,
+				KeepType:            keep.Type,
+				Merge:               merge.Name,
+				MergeType:           merge.Type,
+				Score:               max(nameSim, embeddingSim),
+				NameSimilarity:      nameSim,
+				EmbeddingSimilarity: embeddingSim,
+			})
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		if candidates[i].Keep != candidates[j].Keep {
+			return candidates[i].Keep < candidates[j].Keep
+		}
+		return candidates[i].Merge < candidates[j].Merge
+	})
+	return candidates, nil
+}
+
+// loadMeanEmbeddings sets each entity's embedding to the mean of its
+// observations' embeddings. Entities without any keep a nil embedding.
+func (s *Store) loadMeanEmbeddings(ctx context.Context, entities []*duplicateEntity) error {
+	byID := make(map[int64]*duplicateEntity, len(entities))
+	for _, e := range entities {
+		byID[e.ID] = e
+	}
+	counts := map[int64]int{}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT o.entity_id, oe.embedding
+		FROM observation_embeddings oe
+		JOIN observations o ON o.id = oe.observation_id`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		var blob []byte
+		if err := rows.Scan(&id, &blob); err != nil {
+			return err
+		}
+		e := byID[id]
+		if e == nil {
+			continue // An older version or a session
+		}
+		embedding := decodeEmbedding(blob)
+		if e.embedding == nil {
+			e.embedding = make([]float64, len(embedding))
+		}
+		if len(embedding) != len(e.embedding) {
+			continue // From a model with other dimensions
+		}
+		for i, v := range embedding {
+			e.embedding[i] += v
+		}
+		counts[id]++
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// Cosine similarity ignores scale, but the mean keeps the vectors readable
+	for id, n := range counts {
+		for i := range byID[id].embedding {
+			byID[id].embedding[i] /= float64(n)
+		}
+	}
+	return nil
+}
+
+// duplicateKey is the part of a name duplicates share: NFC, ASCII case
+// folded, and only letters and digits, so "Mark-42", "mark 42", and "mark42"
+// have the same key.
+func duplicateKey(name string) string {
+	var key []rune
+	for _, r := range nameKey(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			key = append(key, unicode.ToLower(r))
+		}
+	}
+	return string(key)
+}
+
+// nameSimilarity is 1 minus the edit distance between two keys over the
+// length of the longer one.
+func nameSimilarity(a, b []rune) float64 {
+	longest := max(len(a), len(b))
+	if longest == 0 {
+		return 0 // Names with no letters or digits say nothing
+	}
+	return 1 - float64(editDistance(a, b))/float64(longest)
+}
+
+// lengthSimilarity is the highest nameSimilarity two keys of these lengths
+// can have.
+func lengthSimilarity(a, b int) float64 {
+	longest := max(a, b)
+	if longest == 0 {
+		return 0
+	}
+	return 1 - float64(longest-min(a, b))/float64(longest)
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
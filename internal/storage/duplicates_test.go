@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	store.CreateEntity("mark42", "project", []string{"Memory for coding agents"})
+	store.CreateEntity("Mark-42", "project", []string{"Stores memories", "Uses SQLite"})
+	store.CreateEntity("konfig", "project", []string{"Loads YAML"})
+	store.CreateEntity("config", "project", []string{"Loads YAML files"})
+	store.CreateEntity("Go", "language", []string{"Fast compiler"})
+	store.CreateEntity("Golang", "language", []string{"Compiles fast"})
+	store.CreateEntity("Rust", "language", []string{"Borrow checker"})
+
+	candidates, err := store.FindDuplicates(DuplicateOptions{})
+	if err != nil {
+		t.Fatalf("FindDuplicates: %v", err)
+	}
+	if len(candidates) != 1 {
+		t.Fatalf("FindDuplicates = %+v, want only mark42/Mark-42", candidates)
+	}
+	// Mark-42 has more observations, so mark42 merges into it
+	if c := candidates[0]; c.Keep != "Mark-42" || c.Merge != "mark42" || c.Score != 1 || c.NameSimilarity != 1 {
+		t.Errorf("candidate = %+v", c)
+	}
+
+	candidates, _ = store.FindDuplicates(DuplicateOptions{MinNameSimilarity: 0.8})
+	if len(candidates) != 2 || candidates[1].Keep != "konfig" || candidates[1].Merge != "config" {
+		t.Errorf("with a lower threshold = %+v, want konfig/config second", candidates)
+	}
+
+	// Go and Golang share no spelling close enough, but their observations agree
+	embed := func(entity, content string, vector []float64) {
+		t.Helper()
+		e, _ := store.GetEntity(entity)
+		id, err := store.getObservationID(context.Background(), e.ID, content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		store.StoreEmbedding(id, vector, "test")
+	}
+	embed("Go", "Fast compiler", []float64{1, 0.1, 0})
+	embed("Golang", "Compiles fast", []float64{1, 0.12, 0})
+	embed("Rust", "Borrow checker", []float64{0, 0.2, 1})
+
+	candidates, _ = store.FindDuplicates(DuplicateOptions{Embeddings: true})
+	if len(candidates) != 2 || candidates[1].Keep != "Go" || candidates[1].Merge != "Golang" ||
+		candidates[1].EmbeddingSimilarity < 0.99 {
+		t.Errorf("with embeddings = %+v, want Go/Golang by embedding", candidates)
+	}
+}
+
+func TestNameSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"mark42", "Mark 42", 1},
+		{"konfig", "config", 1 - 1.0/6},
+		{"Go", "Rust", 0},
+		{"--", "__", 0},
+	}
+	for _, tt := range tests {
+		got := nameSimilarity([]rune(duplicateKey(tt.a)), []rune(duplicateKey(tt.b)))
+		if got != tt.want {
+			t.Errorf("nameSimilarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
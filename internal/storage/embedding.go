@@ -9,16 +9,34 @@ import (
 	"io"
 	"net/http"
 	"sort"
+	"strings"
 )
 
 // EmbeddingClient handles embedding generation via DMR (Docker Model Runner).
-// Uses OpenAI-compatible API at http://127.0.0.1:12434/engines/v1/
+// Uses OpenAI-compatible API at http://127.0.0.1:12434/engines/v1/, or
+// Ollama's native API for a base URL ending in /api, e.g.
+// http://localhost:11434/api.
 type EmbeddingClient struct {
 	baseURL    string
 	httpClient *http.Client
 	model      string
+	// Limits on one request; see SetBatchLimits
+	batchInputs int
+	batchTokens int
+	// Longest text embedded whole; see SetMaxInputTokens
+	maxInputTokens int
 }
 
+// Default limits on one embedding request. Providers cap the inputs per
+// request, and local servers slow down or fail on very large batches.
+const (
+	DefaultEmbeddingBatchInputs = 256
+	DefaultEmbeddingBatchTokens = 8192
+)
+
+// DefaultEmbeddingModel is the model embedding clients use unless set.
+const DefaultEmbeddingModel = "nomic-embed-text"
+
 // DefaultDMRBaseURL returns the default DMR API endpoint (Docker Desktop).
 func DefaultDMRBaseURL() string {
 	return "http://127.0.0.1:12434/engines/v1"
@@ -32,9 +50,11 @@ func DefaultOllamaBaseURL() string {
 // NewEmbeddingClient creates an embedding client for the given base URL.
 func NewEmbeddingClient(baseURL string) *EmbeddingClient {
 	return &EmbeddingClient{
-		baseURL:    baseURL,
-		httpClient: &http.Client{},
-		model:      "nomic-embed-text",
+		baseURL:     baseURL,
+		httpClient:  &http.Client{},
+		model:       DefaultEmbeddingModel,
+		batchInputs: DefaultEmbeddingBatchInputs,
+		batchTokens: DefaultEmbeddingBatchTokens,
 	}
 }
 
@@ -87,12 +107,126 @@ func (c *EmbeddingClient) CreateEmbedding(ctx context.Context, text string) ([]f
 	return embeddings[0], nil
 }
 
-// CreateBatchEmbedding generates embeddings for multiple texts in a single API call.
+// CreateBatchEmbedding generates embeddings for multiple texts, as few API
+// calls as the batch limits allow: texts are sent in order, in requests of at
+// most SetBatchLimits' inputs and estimated tokens. A text over the token
+// limit on its own is sent alone. Texts longer than SetMaxInputTokens are
+// embedded in parts and get the mean of the parts' vectors, rather than being
+// cut short by the server.
 func (c *EmbeddingClient) CreateBatchEmbedding(ctx context.Context, texts []string) ([][]float64, error) {
 	if len(texts) == 0 {
 		return [][]float64{}, nil
 	}
+	if c.maxInputTokens <= 0 {
+		return c.embedBatches(ctx, texts)
+	}
+
+	var parts []string
+	owners := make([]int, 0, len(texts))
+	for i, text := range texts {
+		for _, part := range splitForEmbedding(text, c.maxInputTokens) {
+			parts = append(parts, part)
+			owners = append(owners, i)
+		}
+	}
+	if len(parts) == len(texts) {
+		return c.embedBatches(ctx, texts)
+	}
+	vectors, err := c.embedBatches(ctx, parts)
+	if err != nil {
+		return nil, err
+	}
+
+	embeddings := make([][]float64, len(texts))
+	counts := make([]int, len(texts))
+	for i, vector := range vectors {
+		owner := owners[i]
+		if embeddings[owner] == nil {
+			embeddings[owner] = make([]float64, len(vector))
+		}
+		if len(vector) != len(embeddings[owner]) {
+			return nil, fmt.Errorf("API returned embeddings of %d and %d dimensions", len(embeddings[owner]), len(vector))
+		}
+		for j, v := range vector {
+			embeddings[owner][j] += v
+		}
+		counts[owner]++
+	}
+	for i, embedding := range embeddings {
+		for j := range embedding {
+			embedding[j] /= float64(counts[i])
+		}
+	}
+	return embeddings, nil
+}
+
+// splitForEmbedding splits text at word boundaries into parts of at most
+// maxTokens estimated tokens, less a tenth for the difference between the
+// estimate and the model's own tokenizer. A word too long for a part is cut.
+func splitForEmbedding(text string, maxTokens int) []string {
+	limit := max(maxTokens-maxTokens/10, 1)
+	if EstimateTokens(text) <= limit {
+		return []string{text}
+	}
+
+	var parts []string
+	var part []string
+	tokens := 0
+	for _, word := range strings.Fields(text) {
+		n := EstimateTokens(word)
+		if n > limit {
+			runes := []rune(word)
+			word = string(runes[:max(len(runes)*limit/n, 1)])
+			n = limit
+		}
+		if len(part) > 0 && tokens+n+1 > limit {
+			parts = append(parts, strings.Join(part, " "))
+			part, tokens = nil, 0
+		}
+		part = append(part, word)
+		tokens += n + 1
+	}
+	if len(part) > 0 {
+		parts = append(parts, strings.Join(part, " "))
+	}
+	return parts
+}
+
+// embedBatches embeds texts in requests within the batch limits.
+func (c *EmbeddingClient) embedBatches(ctx context.Context, texts []string) ([][]float64, error) {
+	embeddings := make([][]float64, 0, len(texts))
+	for start := 0; start < len(texts); {
+		end, tokens := start, 0
+		for end < len(texts) && end-start < c.batchInputs {
+			n := EstimateTokens(texts[end])
+			if end > start && tokens+n > c.batchTokens {
+				break
+			}
+			tokens += n
+			end++
+		}
+
+		batch, err := c.embed(ctx, texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		if len(batch) != end-start {
+			return nil, fmt.Errorf("API returned %d embeddings for %d texts", len(batch), end-start)
+		}
+		embeddings = append(embeddings, batch...)
+		start = end
+	}
+	return embeddings, nil
+}
+
+// ollamaEmbedResponse is the response format of Ollama's native /api/embed.
+type ollamaEmbedResponse struct {
+	Model      string      `json:"model"`
+	Embeddings [][]float64 `json:"embeddings"`
+}
 
+// embed makes one API call embedding texts, in their order.
+func (c *EmbeddingClient) embed(ctx context.Context, texts []string) ([][]float64, error) {
 	reqBody := embeddingRequest{
 		Input: texts,
 		Model: c.model,
@@ -103,7 +237,14 @@ func (c *EmbeddingClient) CreateBatchEmbedding(ctx context.Context, texts []stri
 		return nil, fmt.Errorf("marshaling request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/embeddings", bytes.NewReader(jsonBody))
+	// Ollama's native API takes the same request at /embed
+	ollama := strings.HasSuffix(strings.TrimSuffix(c.baseURL, "/"), "/api")
+	endpoint := strings.TrimSuffix(c.baseURL, "/") + "/embeddings"
+	if ollama {
+		endpoint = strings.TrimSuffix(c.baseURL, "/") + "/embed"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(jsonBody))
 	if err != nil {
 		return nil, fmt.Errorf("creating request: %w", err)
 	}
@@ -121,6 +262,14 @@ func (c *EmbeddingClient) CreateBatchEmbedding(ctx context.Context, texts []stri
 		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
 	}
 
+	if ollama {
+		var embResp ollamaEmbedResponse
+		if err := json.NewDecoder(resp.Body).Decode(&embResp); err != nil {
+			return nil, fmt.Errorf("decoding response: %w", err)
+		}
+		return embResp.Embeddings, nil
+	}
+
 	var embResp embeddingResponse
 	if err := json.NewDecoder(resp.Body).Decode(&embResp); err != nil {
 		return nil, fmt.Errorf("decoding response: %w", err)
@@ -139,7 +288,85 @@ func (c *EmbeddingClient) CreateBatchEmbedding(ctx context.Context, texts []stri
 	return embeddings, nil
 }
 
+// SetBatchLimits caps each request CreateBatchEmbedding makes at inputs
+// texts and tokens estimated tokens. Values of zero or less keep the current
+// limit.
+func (c *EmbeddingClient) SetBatchLimits(inputs, tokens int) {
+	if inputs > 0 {
+		c.batchInputs = inputs
+	}
+	if tokens > 0 {
+		c.batchTokens = tokens
+	}
+}
+
 // SetModel changes the embedding model (default: nomic-embed-text).
 func (c *EmbeddingClient) SetModel(model string) {
 	c.model = model
 }
+
+// Model returns the embedding model's name.
+func (c *EmbeddingClient) Model() string {
+	return c.model
+}
+
+// SetMaxInputTokens sets the longest text, in estimated tokens, embedded in
+// one piece; longer texts are split. Zero or less embeds every text whole.
+func (c *EmbeddingClient) SetMaxInputTokens(tokens int) {
+	c.maxInputTokens = tokens
+}
+
+// Probe embeds a short text to learn the model's dimensions, and asks
+// Ollama's /api/show for its context length. The length is left 0 when the
+// server is not Ollama or does not report it.
+func (c *EmbeddingClient) Probe(ctx context.Context) (*EmbeddingModel, error) {
+	embedding, err := c.CreateEmbedding(ctx, "dimension probe")
+	if err != nil {
+		return nil, err
+	}
+	return &EmbeddingModel{
+		Name:           c.model,
+		Dimensions:     len(embedding),
+		MaxInputTokens: c.contextLength(ctx),
+	}, nil
+}
+
+// contextLength returns the context length Ollama reports for the model, or
+// 0 when it reports none.
+func (c *EmbeddingClient) contextLength(ctx context.Context) int {
+	root := strings.TrimSuffix(c.baseURL, "/")
+	for _, suffix := range []string{"/v1", "/api"} {
+		root = strings.TrimSuffix(root, suffix)
+	}
+	body, err := json.Marshal(map[string]string{"model": c.model})
+	if err != nil {
+		return 0
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, root+"/api/show", bytes.NewReader(body))
+	if err != nil {
+		return 0
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0
+	}
+
+	// Keys are prefixed with the architecture, e.g. nomic-bert.context_length
+	var show struct {
+		ModelInfo map[string]any `json:"model_info"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&show); err != nil {
+		return 0
+	}
+	for key, value := range show.ModelInfo {
+		if n, ok := value.(float64); ok && strings.HasSuffix(key, ".context_length") {
+			return int(n)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// EmbeddingModel is what is known about an embedding model: the size of its
+// vectors, and the longest input it embeds whole.
+type EmbeddingModel struct {
+	Name           string    `db:"model" json:"model"`
+	Dimensions     int       `db:"dimensions" json:"dimensions"`
+	MaxInputTokens int       `db:"max_input_tokens" json:"maxInputTokens"` // 0 when unknown
+	CreatedAt      time.Time `db:"created_at" json:"createdAt"`
+}
+
+// GetEmbeddingModel returns the recorded metadata of the named model.
+func (s *Store) GetEmbeddingModel(name string) (*EmbeddingModel, error) {
+	return s.GetEmbeddingModelContext(context.Background(), name)
+}
+
+// GetEmbeddingModelContext is GetEmbeddingModel with a context.
+func (s *Store) GetEmbeddingModelContext(ctx context.Context, name string) (*EmbeddingModel, error) {
+	var model EmbeddingModel
+	err := s.db.GetContext(ctx, &model,
+		"SELECT model, dimensions, max_input_tokens, created_at FROM embedding_models WHERE model = ?", name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, &NotFoundError{Kind: "embedding model", Name: name}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &model, nil
+}
+
+// ListEmbeddingModels returns every recorded embedding model by name.
+func (s *Store) ListEmbeddingModels() ([]EmbeddingModel, error) {
+	return s.ListEmbeddingModelsContext(context.Background())
+}
+
+// ListEmbeddingModelsContext is ListEmbeddingModels with a context.
+func (s *Store) ListEmbeddingModelsContext(ctx context.Context) ([]EmbeddingModel, error) {
+	var models []EmbeddingModel
+	err := s.db.SelectContext(ctx, &models,
+		"SELECT model, dimensions, max_input_tokens, created_at FROM embedding_models ORDER BY model")
+	return models, err
+}
+
+// SaveEmbeddingModel records model's metadata. Changing the dimensions of a
+// model with stored embeddings is refused: they could no longer be compared
+// with new vectors. Remove them first with ClearEmbeddings.
+func (s *Store) SaveEmbeddingModel(model EmbeddingModel) error {
+	return s.SaveEmbeddingModelContext(context.Background(), model)
+}
+
+// SaveEmbeddingModelContext is SaveEmbeddingModel with a context.
+func (s *Store) SaveEmbeddingModelContext(ctx context.Context, model EmbeddingModel) error {
+	if model.Name == "" {
+		return &ValidationError{Field: "model", Reason: "must not be empty"}
+	}
+	if model.Dimensions <= 0 {
+		return &ValidationError{Field: "dimensions", Reason: "must be positive"}
+	}
+	var stored int
+	err := s.db.GetContext(ctx, &stored,
+		"SELECT COUNT(*) FROM observation_embeddings WHERE model = ? AND dimensions != ?", model.Name, model.Dimensions)
+	if err != nil {
+		return err
+	}
+	if stored > 0 {
+		return &ValidationError{Field: "dimensions", Reason: fmt.Sprintf(
+			"%d embeddings from %s have other dimensions than %d; clear them first", stored, model.Name, model.Dimensions)}
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO embedding_models (model, dimensions, max_input_tokens) VALUES (?, ?, ?)
+		ON CONFLICT(model) DO UPDATE SET dimensions = excluded.dimensions, max_input_tokens = excluded.max_input_tokens
+	`, model.Name, model.Dimensions, model.MaxInputTokens)
+	return err
+}
+
+// ClearEmbeddings removes the stored embeddings of the named model, so its
+// observations are embedded again.
+func (s *Store) ClearEmbeddings(model string) (int64, error) {
+	return s.ClearEmbeddingsContext(context.Background(), model)
+}
+
+// ClearEmbeddingsContext is ClearEmbeddings with a context.
+func (s *Store) ClearEmbeddingsContext(ctx context.Context, model string) (int64, error) {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM observation_embeddings WHERE model = ?", model)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// PrepareEmbedder looks up the metadata of client's model, probing the model
+// and recording what it finds the first time the model is used, and limits
+// the client's inputs to the model's maximum.
+func (s *Store) PrepareEmbedder(ctx context.Context, client *EmbeddingClient) (*EmbeddingModel, error) {
+	model, err := s.GetEmbeddingModelContext(ctx, client.Model())
+	if errors.Is(err, ErrNotFound) {
+		if model, err = client.Probe(ctx); err != nil {
+			return nil, err
+		}
+		err = s.SaveEmbeddingModelContext(ctx, *model)
+	}
+	if err != nil {
+		return nil, err
+	}
+	client.SetMaxInputTokens(model.MaxInputTokens)
+	return model, nil
+}
+
+// checkEmbedding validates vector against the recorded dimensions of model,
+// recording them from vector when the model is new.
+func checkEmbedding(ctx context.Context, db interface {
+	ExecContext(context.Context, string, ...any) (sql.Result, error)
+	QueryRowContext(context.Context, string, ...any) *sql.Row
+}, model string, vector []float64) error {
+	if len(vector) == 0 {
+		return &ValidationError{Field: "embedding", Reason: "must not be empty"}
+	}
+	var dimensions int
+	err := db.QueryRowContext(ctx, "SELECT dimensions FROM embedding_models WHERE model = ?", model).Scan(&dimensions)
+	if errors.Is(err, sql.ErrNoRows) {
+		_, err = db.ExecContext(ctx, "INSERT INTO embedding_models (model, dimensions) VALUES (?, ?)", model, len(vector))
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if len(vector) != dimensions {
+		return &ValidationError{Field: "embedding", Reason: fmt.Sprintf(
+			"%s vectors have %d dimensions, got %d", model, dimensions, len(vector))}
+	}
+	return nil
+}
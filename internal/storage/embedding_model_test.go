@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEmbeddingModel_Dimensions(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	store.CreateEntity("Go", "language", []string{"Fast compiler", "Garbage collected"})
+	observations, _ := store.GetObservationsWithoutEmbeddings()
+
+	// The first vector records the model's dimensions
+	if err := store.StoreEmbedding(observations[0].ID, []float64{1, 0, 0}, "test-model"); err != nil {
+		t.Fatal(err)
+	}
+	model, err := store.GetEmbeddingModel("test-model")
+	if err != nil || model.Dimensions != 3 {
+		t.Fatalf("GetEmbeddingModel = %+v, %v", model, err)
+	}
+
+	var verr *ValidationError
+	if err := store.StoreEmbedding(observations[1].ID, []float64{1, 0}, "test-model"); !errors.As(err, &verr) {
+		t.Errorf("expected a validation error for the wrong dimensions, got %v", err)
+	}
+	if err := store.BatchStoreEmbeddings(observations, [][]float64{{1, 0, 0}, {1, 0}}, "test-model"); !errors.As(err, &verr) {
+		t.Errorf("expected the batch refused, got %v", err)
+	}
+	if _, err := store.GetEmbedding(observations[1].ID); err == nil {
+		t.Error("expected nothing stored from the refused batch")
+	}
+
+	// Changing the dimensions needs the old vectors gone
+	if err := store.SaveEmbeddingModel(EmbeddingModel{Name: "test-model", Dimensions: 2}); !errors.As(err, &verr) {
+		t.Errorf("expected a validation error with vectors stored, got %v", err)
+	}
+	if n, err := store.ClearEmbeddings("test-model"); err != nil || n != 1 {
+		t.Fatalf("ClearEmbeddings = %d, %v", n, err)
+	}
+	if err := store.SaveEmbeddingModel(EmbeddingModel{Name: "test-model", Dimensions: 2, MaxInputTokens: 512}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.StoreEmbedding(observations[1].ID, []float64{1, 0}, "test-model"); err != nil {
+		t.Errorf("expected the new dimensions accepted, got %v", err)
+	}
+
+	if _, err := store.GetEmbeddingModel("other"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestPrepareEmbedder(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	probes := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1/embeddings":
+			probes++
+			w.Write([]byte(`{"data": [{"index": 0, "embedding": [0.1, 0.2, 0.3, 0.4]}]}`))
+		case "/api/show":
+			w.Write([]byte(`{"model_info": {"general.architecture": "nomic-bert", "nomic-bert.context_length": 2048}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := NewEmbeddingClient(server.URL + "/v1")
+	model, err := store.PrepareEmbedder(context.Background(), client)
+	if err != nil {
+		t.Fatalf("PrepareEmbedder failed: %v", err)
+	}
+	if model.Dimensions != 4 || model.MaxInputTokens != 2048 {
+		t.Errorf("expected 4 dims and 2048 tokens, got %+v", model)
+	}
+	if client.maxInputTokens != 2048 {
+		t.Errorf("expected the client limited to 2048 tokens, got %d", client.maxInputTokens)
+	}
+
+	// Later uses read the recorded metadata
+	if _, err := store.PrepareEmbedder(context.Background(), client); err != nil || probes != 1 {
+		t.Errorf("expected one probe, got %d (%v)", probes, err)
+	}
+}
@@ -2,8 +2,11 @@ package storage
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -151,3 +154,120 @@ func TestNewOllamaEmbeddingClient(t *testing.T) {
 		t.Errorf("expected Ollama base URL, got %q", client.baseURL)
 	}
 }
+
+func TestEmbeddingClient_OllamaNative(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/embed" {
+			t.Errorf("expected path /api/embed, got %s", r.URL.Path)
+		}
+		var req embeddingRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if len(req.Input) != 2 {
+			t.Errorf("expected both texts in one request, got %v", req.Input)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"model": "nomic-embed-text", "embeddings": [[0.1, 0.2], [0.3, 0.4]]}`))
+	}))
+	defer server.Close()
+
+	client := NewEmbeddingClient(server.URL + "/api")
+	embeddings, err := client.CreateBatchEmbedding(context.Background(), []string{"first", "second"})
+	if err != nil {
+		t.Fatalf("CreateBatchEmbedding failed: %v", err)
+	}
+	if len(embeddings) != 2 || embeddings[1][0] != 0.3 {
+		t.Errorf("unexpected embeddings %v", embeddings)
+	}
+}
+
+func TestEmbeddingClient_BatchLimits(t *testing.T) {
+	var sizes []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req embeddingRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		sizes = append(sizes, len(req.Input))
+
+		// Embed each text as its length, so order can be checked
+		resp := embeddingResponse{}
+		for i, text := range req.Input {
+			resp.Data = append(resp.Data, struct {
+				Object    string    `json:"object"`
+				Index     int       `json:"index"`
+				Embedding []float64 `json:"embedding"`
+			}{Index: i, Embedding: []float64{float64(len(text))}})
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	client := NewEmbeddingClient(server.URL)
+	client.SetBatchLimits(3, 0)
+	texts := []string{"a", "bb", "ccc", "dddd", "eeeee", "ffffff", "g"}
+	embeddings, err := client.CreateBatchEmbedding(context.Background(), texts)
+	if err != nil {
+		t.Fatalf("CreateBatchEmbedding failed: %v", err)
+	}
+	if !slices.Equal(sizes, []int{3, 3, 1}) {
+		t.Errorf("expected requests of 3, 3, 1 texts, got %v", sizes)
+	}
+	for i, text := range texts {
+		if embeddings[i][0] != float64(len(text)) {
+			t.Errorf("embedding %d out of order: %v", i, embeddings[i])
+		}
+	}
+
+	// A token budget splits further; a text over it goes alone
+	sizes = nil
+	long := strings.Repeat("word ", 100)
+	client.SetBatchLimits(10, EstimateTokens(long)+EstimateTokens("a"))
+	if _, err := client.CreateBatchEmbedding(context.Background(), []string{"a", long, long, "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(sizes, []int{2, 2}) {
+		t.Errorf("expected requests of 2, 2 texts, got %v", sizes)
+	}
+}
+
+func TestEmbeddingClient_MaxInputTokens(t *testing.T) {
+	var inputs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req embeddingRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		inputs = append(inputs, req.Input...)
+
+		// Short texts embed as [1, 0], parts of long ones as [0, 1]
+		resp := embeddingResponse{}
+		for i, text := range req.Input {
+			vector := []float64{1, 0}
+			if strings.HasPrefix(text, "word") {
+				vector = []float64{0, 1}
+			}
+			resp.Data = append(resp.Data, struct {
+				Object    string    `json:"object"`
+				Index     int       `json:"index"`
+				Embedding []float64 `json:"embedding"`
+			}{Index: i, Embedding: vector})
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	client := NewEmbeddingClient(server.URL)
+	client.SetMaxInputTokens(50)
+	long := strings.TrimSpace(strings.Repeat("word ", 200))
+	embeddings, err := client.CreateBatchEmbedding(context.Background(), []string{"short", long, "short"})
+	if err != nil {
+		t.Fatalf("CreateBatchEmbedding failed: %v", err)
+	}
+	if len(embeddings) != 3 || embeddings[0][0] != 1 || embeddings[1][1] != 1 || embeddings[2][0] != 1 {
+		t.Errorf("unexpected embeddings %v", embeddings)
+	}
+	if len(inputs) < 4 {
+		t.Fatalf("expected the long text split, got %d inputs", len(inputs))
+	}
+	for _, input := range inputs {
+		if EstimateTokens(input) > 50 {
+			t.Errorf("input of %d tokens over the limit", EstimateTokens(input))
+		}
+	}
+}
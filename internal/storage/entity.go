@@ -1,13 +1,12 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
-	"errors"
 	"time"
-)
 
-// ErrNotFound is returned when an entity is not found.
-var ErrNotFound = errors.New("not found")
+	"github.com/jmoiron/sqlx"
+)
 
 // Entity represents a node in the knowledge graph.
 type Entity struct {
@@ -20,15 +19,29 @@ type Entity struct {
 	Version      int   `db:"version"`
 	IsLatest     bool  `db:"is_latest"`
 	SupersedesID int64 `db:"supersedes_id"` // ID of previous version (0 if none)
+	// User who wrote this version, on shared databases; see SetUser
+	Owner string `db:"owner" json:",omitempty"`
 }
 
-// ErrEntityExists is returned when attempting to create an entity that already exists.
-var ErrEntityExists = errors.New("entity already exists")
-
 // CreateEntity creates a new entity with optional observations.
 // Returns ErrEntityExists if an entity with this name already exists.
 func (s *Store) CreateEntity(name, entityType string, observations []string) (*Entity, error) {
-	tx, err := s.db.Begin()
+	return s.CreateEntityContext(context.Background(), name, entityType, observations)
+}
+
+// CreateEntityContext is CreateEntity with a context.
+func (s *Store) CreateEntityContext(ctx context.Context, name, entityType string, observations []string) (*Entity, error) {
+	observations = s.redactAll(ctx, name, observations)
+	if err := validateEntityWithObservations(name, entityType, observations); err != nil {
+		return nil, err
+	}
+	name = NormalizeName(name)
+	entityType = s.CanonicalEntityType(entityType)
+	if err := s.checkObservations(ctx, name, entityType, observations, FactTypeDynamic); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -36,18 +49,18 @@ func (s *Store) CreateEntity(name, entityType string, observations []string) (*E
 
 	// Check if entity already exists (no UNIQUE constraint, must check manually)
 	var existingID int64
-	err = tx.QueryRow("SELECT id FROM entities WHERE name = ?", name).Scan(&existingID)
+	err = tx.QueryRowContext(ctx, "SELECT id FROM entities WHERE "+s.nameMatch("name"), name).Scan(&existingID)
 	if err == nil {
-		return nil, ErrEntityExists
+		return nil, entityExists(name)
 	}
 	if err != sql.ErrNoRows {
 		return nil, err
 	}
 
 	// Insert entity
-	result, err := tx.Exec(
-		"INSERT INTO entities (name, entity_type) VALUES (?, ?)",
-		name, entityType,
+	result, err := tx.ExecContext(ctx,
+		"INSERT INTO entities (name, entity_type, owner) VALUES (?, ?, ?)",
+		name, entityType, s.userValue(),
 	)
 	if err != nil {
 		return nil, err
@@ -59,14 +72,19 @@ func (s *Store) CreateEntity(name, entityType string, observations []string) (*E
 	}
 
 	// Insert observations
+	added := make([]addedObservation, 0, len(observations))
 	for _, obs := range observations {
-		_, err := tx.Exec(
-			"INSERT INTO observations (entity_id, content) VALUES (?, ?)",
-			id, obs,
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO observations (entity_id, content, content_hash, source, author) VALUES (?, ?, ?, ?, ?)",
+			id, obs, contentHash(obs), s.sourceFor(ctx), s.userValue(),
 		)
 		if err != nil {
 			return nil, err
 		}
+		added = append(added, addedObservation{id, obs})
+	}
+	if err := s.observationsAdded(ctx, tx, added); err != nil {
+		return nil, err
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -85,7 +103,22 @@ func (s *Store) CreateEntity(name, entityType string, observations []string) (*E
 // CreateOrUpdateEntity creates a new entity or a new version if one exists.
 // If an entity with the same name exists, creates a new version and marks old as not latest.
 func (s *Store) CreateOrUpdateEntity(name, entityType string, observations []string) (*Entity, error) {
-	tx, err := s.db.Begin()
+	return s.CreateOrUpdateEntityContext(context.Background(), name, entityType, observations)
+}
+
+// CreateOrUpdateEntityContext is CreateOrUpdateEntity with a context.
+func (s *Store) CreateOrUpdateEntityContext(ctx context.Context, name, entityType string, observations []string) (*Entity, error) {
+	observations = s.redactAll(ctx, name, observations)
+	if err := validateEntityWithObservations(name, entityType, observations); err != nil {
+		return nil, err
+	}
+	name = NormalizeName(name)
+	entityType = s.CanonicalEntityType(entityType)
+	if err := s.checkObservations(ctx, name, entityType, observations, FactTypeDynamic); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -94,8 +127,8 @@ func (s *Store) CreateOrUpdateEntity(name, entityType string, observations []str
 	// Check for existing entity
 	var existingID int64
 	var existingVersion int
-	err = tx.QueryRow(
-		"SELECT id, COALESCE(version, 1) FROM entities WHERE name = ? AND (is_latest = 1 OR is_latest IS NULL)",
+	err = tx.QueryRowContext(ctx,
+		"SELECT id, COALESCE(version, 1) FROM entities WHERE "+s.nameMatch("name")+" AND (is_latest = 1 OR is_latest IS NULL)",
 		name,
 	).Scan(&existingID, &existingVersion)
 
@@ -110,7 +143,7 @@ func (s *Store) CreateOrUpdateEntity(name, entityType string, observations []str
 		return nil, err
 	} else {
 		// Existing entity - mark it as not latest
-		_, err = tx.Exec(
+		_, err = tx.ExecContext(ctx,
 			"UPDATE entities SET is_latest = 0 WHERE id = ?",
 			existingID,
 		)
@@ -122,9 +155,9 @@ func (s *Store) CreateOrUpdateEntity(name, entityType string, observations []str
 	}
 
 	// Insert new entity/version
-	result, err := tx.Exec(
-		"INSERT INTO entities (name, entity_type, version, is_latest, supersedes_id) VALUES (?, ?, ?, 1, ?)",
-		name, entityType, newVersion, sql.NullInt64{Int64: supersedesID, Valid: supersedesID > 0},
+	result, err := tx.ExecContext(ctx,
+		"INSERT INTO entities (name, entity_type, version, is_latest, supersedes_id, owner) VALUES (?, ?, ?, 1, ?, ?)",
+		name, entityType, newVersion, sql.NullInt64{Int64: supersedesID, Valid: supersedesID > 0}, s.userValue(),
 	)
 	if err != nil {
 		return nil, err
@@ -135,15 +168,29 @@ func (s *Store) CreateOrUpdateEntity(name, entityType string, observations []str
 		return nil, err
 	}
 
+	// Attributes describe the entity as it is now, so they move to the new version
+	if supersedesID > 0 {
+		if _, err := tx.ExecContext(ctx,
+			"UPDATE entity_attributes SET entity_id = ? WHERE entity_id = ?",
+			id, supersedesID); err != nil {
+			return nil, err
+		}
+	}
+
 	// Insert observations
+	added := make([]addedObservation, 0, len(observations))
 	for _, obs := range observations {
-		_, err := tx.Exec(
-			"INSERT INTO observations (entity_id, content) VALUES (?, ?)",
-			id, obs,
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO observations (entity_id, content, content_hash, source, author) VALUES (?, ?, ?, ?, ?)",
+			id, obs, contentHash(obs), s.sourceFor(ctx), s.userValue(),
 		)
 		if err != nil {
 			return nil, err
 		}
+		added = append(added, addedObservation{id, obs})
+	}
+	if err := s.observationsAdded(ctx, tx, added); err != nil {
+		return nil, err
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -164,8 +211,13 @@ func (s *Store) CreateOrUpdateEntity(name, entityType string, observations []str
 
 // GetEntityHistory returns all versions of an entity, newest first.
 func (s *Store) GetEntityHistory(name string) ([]*Entity, error) {
+	return s.GetEntityHistoryContext(context.Background(), name)
+}
+
+// GetEntityHistoryContext is GetEntityHistory with a context.
+func (s *Store) GetEntityHistoryContext(ctx context.Context, name string) ([]*Entity, error) {
 	var entities []Entity
-	err := s.db.Select(&entities, `
+	err := s.db.SelectContext(ctx, &entities, `
 		SELECT id, name, entity_type, created_at,
 		       COALESCE(version, 1) as version,
 		       COALESCE(is_latest, 1) as is_latest,
@@ -179,7 +231,7 @@ func (s *Store) GetEntityHistory(name string) ([]*Entity, error) {
 	}
 
 	if len(entities) == 0 {
-		return nil, ErrNotFound
+		return nil, entityNotFound(name)
 	}
 
 	// Convert to pointer slice for API compatibility
@@ -193,25 +245,31 @@ func (s *Store) GetEntityHistory(name string) ([]*Entity, error) {
 // GetEntity retrieves an entity by name, including its observations.
 // Returns the latest version only.
 func (s *Store) GetEntity(name string) (*Entity, error) {
+	return s.GetEntityContext(context.Background(), name)
+}
+
+// GetEntityContext is GetEntity with a context.
+func (s *Store) GetEntityContext(ctx context.Context, name string) (*Entity, error) {
 	var entity Entity
-	err := s.db.Get(&entity, `
+	err := s.db.GetContext(ctx, &entity, `
 		SELECT id, name, entity_type, created_at,
 		       COALESCE(version, 1) as version,
 		       COALESCE(is_latest, 1) as is_latest,
 		       COALESCE(supersedes_id, 0) as supersedes_id
 		FROM entities
-		WHERE name = ? AND (is_latest = 1 OR is_latest IS NULL)`,
-		name)
+		WHERE `+s.nameMatch("name")+` AND (is_latest = 1 OR is_latest IS NULL)
+		ORDER BY id DESC LIMIT 1`,
+		NormalizeName(name))
 
 	if err == sql.ErrNoRows {
-		return nil, ErrNotFound
+		return nil, entityNotFound(name)
 	}
 	if err != nil {
 		return nil, err
 	}
 
 	// Load observations
-	err = s.db.Select(&entity.Observations,
+	err = s.db.SelectContext(ctx, &entity.Observations,
 		"SELECT content FROM observations WHERE entity_id = ? ORDER BY created_at",
 		entity.ID)
 	if err != nil {
@@ -224,41 +282,34 @@ func (s *Store) GetEntity(name string) (*Entity, error) {
 // ListEntities returns all entities, optionally filtered by type.
 // Only returns latest versions.
 func (s *Store) ListEntities(entityType string) ([]*Entity, error) {
-	var entities []Entity
-	var err error
+	return s.ListEntitiesContext(context.Background(), entityType)
+}
 
-	query := `SELECT id, name, entity_type, created_at,
-	                 COALESCE(version, 1) as version,
-	                 COALESCE(is_latest, 1) as is_latest,
-	                 COALESCE(supersedes_id, 0) as supersedes_id
-	          FROM entities WHERE is_latest = 1 OR is_latest IS NULL ORDER BY name`
+// ListEntitiesContext is ListEntities with a context.
+func (s *Store) ListEntitiesContext(ctx context.Context, entityType string) ([]*Entity, error) {
+	return s.ListEntitiesWithFilterContext(ctx, EntityFilter{Type: entityType})
+}
 
-	if entityType == "" {
-		err = s.db.Select(&entities, query)
-	} else {
-		query = `SELECT id, name, entity_type, created_at,
-		                COALESCE(version, 1) as version,
-		                COALESCE(is_latest, 1) as is_latest,
-		                COALESCE(supersedes_id, 0) as supersedes_id
-		         FROM entities WHERE entity_type = ? AND (is_latest = 1 OR is_latest IS NULL) ORDER BY name`
-		err = s.db.Select(&entities, query, entityType)
-	}
+// DeleteEntity removes an entity and its observations (via CASCADE) and
+// aliases. Undo can restore them within the undo window.
+func (s *Store) DeleteEntity(name string) error {
+	return s.DeleteEntityContext(context.Background(), name)
+}
 
+// DeleteEntityContext is DeleteEntity with a context.
+func (s *Store) DeleteEntityContext(ctx context.Context, name string) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	defer tx.Rollback()
 
-	// Convert to pointer slice for API compatibility
-	result := make([]*Entity, len(entities))
-	for i := range entities {
-		result[i] = &entities[i]
+	snapshot, err := snapshotEntity(ctx, tx, name)
+	if err != nil {
+		return err
 	}
-	return result, nil
-}
 
-// DeleteEntity removes an entity and its observations (via CASCADE).
-func (s *Store) DeleteEntity(name string) error {
-	result, err := s.db.Exec("DELETE FROM entities WHERE name = ?", name)
+	result, err := tx.ExecContext(ctx, "DELETE FROM entities WHERE name = ?", name)
 	if err != nil {
 		return err
 	}
@@ -269,10 +320,35 @@ func (s *Store) DeleteEntity(name string) error {
 	}
 
 	if rows == 0 {
-		return ErrNotFound
+		return entityNotFound(name)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM entity_aliases WHERE entity_name = ?", name); err != nil {
+		return err
+	}
+
+	trash := newTrashBatch(OpDeleteEntity)
+	*trash.entity(name) = trashSnapshot{Entities: snapshot.Entities, Observations: snapshot.Observations,
+		Relations: snapshot.Relations, Attributes: snapshot.Attributes, Aliases: snapshot.Aliases}
+	if err := s.recordTrash(ctx, tx, trash); err != nil {
+		return err
 	}
+	return tx.Commit()
+}
 
-	return nil
+// deleteEntityRows deletes every version of the named entity with its
+// observations and relations. Children are deleted explicitly rather than
+// left to foreign key cascades, so their delete triggers write tombstones.
+func deleteEntityRows(ctx context.Context, tx *sqlx.Tx, name string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM observations WHERE entity_id IN (SELECT id FROM entities WHERE name = ?)`, name); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM relations WHERE from_entity_id IN (SELECT id FROM entities WHERE name = ?)
+		OR to_entity_id IN (SELECT id FROM entities WHERE name = ?)`, name, name); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `DELETE FROM entities WHERE name = ?`, name)
+	return err
 }
 
 // CountObservations returns the total number of observations (for testing).
@@ -1,6 +1,8 @@
 package storage_test
 
 import (
+	"context"
+	"errors"
 	"testing"
 
 	"github.com/mfenderov/mark42/internal/storage"
@@ -74,7 +76,7 @@ func TestGetEntity_NotFound(t *testing.T) {
 	if err == nil {
 		t.Error("expected error for nonexistent entity, got nil")
 	}
-	if err != storage.ErrNotFound {
+	if !errors.Is(err, storage.ErrNotFound) {
 		t.Errorf("expected ErrNotFound, got %v", err)
 	}
 }
@@ -121,7 +123,7 @@ func TestDeleteEntity(t *testing.T) {
 	}
 
 	_, err = store.GetEntity("TDD")
-	if err != storage.ErrNotFound {
+	if !errors.Is(err, storage.ErrNotFound) {
 		t.Errorf("expected ErrNotFound after delete, got %v", err)
 	}
 }
@@ -131,7 +133,7 @@ func TestDeleteEntity_NotFound(t *testing.T) {
 	defer store.Close()
 
 	err := store.DeleteEntity("nonexistent")
-	if err != storage.ErrNotFound {
+	if !errors.Is(err, storage.ErrNotFound) {
 		t.Errorf("expected ErrNotFound, got %v", err)
 	}
 }
@@ -276,3 +278,23 @@ func TestListEntities_OnlyLatest(t *testing.T) {
 		t.Errorf("expected 2 entities (latest only), got %d", len(entities))
 	}
 }
+
+func TestEntityMethodsHonorContext(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+	store.Migrate()
+	store.CreateEntity("Go", "language", nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := store.GetEntityContext(ctx, "Go"); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetEntityContext: expected context.Canceled, got %v", err)
+	}
+	if _, err := store.CreateEntityContext(ctx, "Rust", "language", nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("CreateEntityContext: expected context.Canceled, got %v", err)
+	}
+	if _, err := store.GetEntity("Rust"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("expected no entity created under a canceled context, got %v", err)
+	}
+}
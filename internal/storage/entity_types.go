@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// SetEntityTypes registers the canonical entity types, each with a
+// description. Entity writes then store a type that differs from a
+// registered one only in case, separators, or a plural ending ("Pattern",
+// "patterns", "design-pattern" for "design_pattern") as the registered type.
+// Other types are stored as given. Nil registers none, the default.
+func (s *Store) SetEntityTypes(types map[string]string) {
+	s.entityTypes = types
+}
+
+// ParseEntityTypes reads a comma-separated list of entity types, as in
+// CLAUDE_MEMORY_ENTITY_TYPES, as types without descriptions. An empty list
+// is nil.
+func ParseEntityTypes(list string) map[string]string {
+	var types map[string]string
+	for _, t := range strings.Split(list, ",") {
+		if t = strings.TrimSpace(t); t == "" {
+			continue
+		}
+		if types == nil {
+			types = map[string]string{}
+		}
+		types[t] = ""
+	}
+	return types
+}
+
+// EntityTypes returns the registered entity types and their descriptions.
+func (s *Store) EntityTypes() map[string]string {
+	return s.entityTypes
+}
+
+// CanonicalEntityType returns the registered type entityType is a variant
+// of, or entityType itself when it matches none.
+func (s *Store) CanonicalEntityType(entityType string) string {
+	if _, ok := s.entityTypes[entityType]; ok || len(s.entityTypes) == 0 {
+		return entityType
+	}
+	key := entityTypeKey(entityType)
+	// Sorted so a type matching several registered ones maps the same way every time
+	for _, registered := range slices.Sorted(maps.Keys(s.entityTypes)) {
+		r := entityTypeKey(registered)
+		if key == r || key == r+"s" || key == r+"es" {
+			return registered
+		}
+	}
+	return entityType
+}
+
+// entityTypeKey is what variants of a type share: its letters and digits,
+// lowercased.
+func entityTypeKey(entityType string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, entityType)
+}
+
+// EntityTypeCount is an entity type with the number of entities of it.
+type EntityTypeCount struct {
+	Type        string `json:"type" db:"entity_type"`
+	Count       int    `json:"count" db:"count"`
+	Registered  bool   `json:"registered"`
+	Description string `json:"description,omitempty"`
+	// Canonical is the registered type an unregistered one is a variant of
+	Canonical string `json:"canonical,omitempty"`
+}
+
+// ListEntityTypes returns every entity type in use, counted at entities'
+// latest versions, and every registered type, used or not, most used first.
+func (s *Store) ListEntityTypes() ([]EntityTypeCount, error) {
+	return s.ListEntityTypesContext(context.Background())
+}
+
+// ListEntityTypesContext is ListEntityTypes with a context.
+func (s *Store) ListEntityTypesContext(ctx context.Context) ([]EntityTypeCount, error) {
+	var types []EntityTypeCount
+	if err := s.db.SelectContext(ctx, &types, `
+		SELECT entity_type, COUNT(*) as count FROM entities
+		WHERE is_latest = 1 OR is_latest IS NULL
+		GROUP BY entity_type`); err != nil {
+		return nil, err
+	}
+
+	used := map[string]bool{}
+	for i, t := range types {
+		used[t.Type] = true
+		if description, ok := s.entityTypes[t.Type]; ok {
+			types[i].Registered = true
+			types[i].Description = description
+		} else if canonical := s.CanonicalEntityType(t.Type); canonical != t.Type {
+			types[i].Canonical = canonical
+		}
+	}
+	for registered, description := range s.entityTypes {
+		if !used[registered] {
+			types = append(types, EntityTypeCount{Type: registered, Registered: true, Description: description})
+		}
+	}
+
+	slices.SortFunc(types, func(a, b EntityTypeCount) int {
+		if a.Count != b.Count {
+			return b.Count - a.Count
+		}
+		return strings.Compare(a.Type, b.Type)
+	})
+	return types, nil
+}
+
+// RetypeEntities changes the type of every entity of type from, all
+// versions included, to to, and returns how many entities it changed.
+func (s *Store) RetypeEntities(from, to string) (int, error) {
+	return s.RetypeEntitiesContext(context.Background(), from, to)
+}
+
+// RetypeEntitiesContext is RetypeEntities with a context.
+func (s *Store) RetypeEntitiesContext(ctx context.Context, from, to string) (int, error) {
+	if err := validateText("entity type", to, MaxTypeLength, true); err != nil {
+		return 0, err
+	}
+	if from == to {
+		return 0, nil
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var count int
+	if err := tx.GetContext(ctx, &count, "SELECT COUNT(DISTINCT name) FROM entities WHERE entity_type = ?", from); err != nil {
+		return 0, err
+	}
+	if count == 0 {
+		return 0, &NotFoundError{"entity type", from}
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE entities SET entity_type = ? WHERE entity_type = ?", to, from); err != nil {
+		return 0, err
+	}
+	return count, tx.Commit()
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCanonicalEntityType(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	if got := store.CanonicalEntityType("Patterns"); got != "Patterns" {
+		t.Errorf("expected types kept as given without a registry, got %q", got)
+	}
+
+	store.SetEntityTypes(map[string]string{"pattern": "A reusable practice", "design_pattern": "", "class": ""})
+	tests := []struct {
+		in, want string
+	}{
+		{"pattern", "pattern"},
+		{"Pattern", "pattern"},
+		{"patterns", "pattern"},
+		{"design-pattern", "design_pattern"},
+		{"Design Patterns", "design_pattern"},
+		{"classes", "class"},
+		{"project", "project"},
+		{"patternist", "patternist"},
+	}
+	for _, tt := range tests {
+		if got := store.CanonicalEntityType(tt.in); got != tt.want {
+			t.Errorf("CanonicalEntityType(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	e, err := store.CreateEntity("TDD", "Patterns", nil)
+	if err != nil || e.Type != "pattern" {
+		t.Errorf("expected the type canonicalized on write, got %+v, %v", e, err)
+	}
+	results, _ := store.CreateEntities([]EntitySpec{{Name: "Strategy", Type: "Design-Pattern"}})
+	if got, _ := store.GetEntity(results[0].Name); got.Type != "design_pattern" {
+		t.Errorf("expected CreateEntities to canonicalize, got %q", got.Type)
+	}
+}
+
+func TestListEntityTypesAndRetype(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	// Written before the registry existed
+	store.CreateEntity("TDD", "pattern", nil)
+	store.CreateEntity("DRY", "Patterns", nil)
+	store.CreateEntity("KISS", "Patterns", nil)
+	store.CreateOrUpdateEntity("KISS", "Patterns", nil)
+	store.SetEntityTypes(map[string]string{"pattern": "A reusable practice", "project": ""})
+
+	types, err := store.ListEntityTypes()
+	if err != nil {
+		t.Fatalf("ListEntityTypes: %v", err)
+	}
+	want := []EntityTypeCount{
+		{Type: "Patterns", Count: 2, Canonical: "pattern"},
+		{Type: "pattern", Count: 1, Registered: true, Description: "A reusable practice"},
+		{Type: "project", Registered: true},
+	}
+	if len(types) != len(want) {
+		t.Fatalf("types = %+v, want %+v", types, want)
+	}
+	for i := range want {
+		if types[i] != want[i] {
+			t.Errorf("type %d = %+v, want %+v", i, types[i], want[i])
+		}
+	}
+
+	n, err := store.RetypeEntities("Patterns", "pattern")
+	if err != nil || n != 2 {
+		t.Fatalf("RetypeEntities = %d, %v; want 2", n, err)
+	}
+	if history, _ := store.GetEntityHistory("KISS"); history[0].Type != "pattern" || history[1].Type != "pattern" {
+		t.Errorf("expected every version retyped, got %+v", history)
+	}
+	if _, err := store.RetypeEntities("Patterns", "pattern"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a type no entity has, got %v", err)
+	}
+	if _, err := store.RetypeEntities("pattern", ""); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("expected an empty type rejected, got %v", err)
+	}
+}
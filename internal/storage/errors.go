@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+// Errors callers can match with errors.Is. Store methods return them wrapped
+// in a NotFoundError, ValidationError, or SchemaOutdatedError, or with %w,
+// so messages name what was missing or rejected.
+var (
+	// ErrNotFound is matched by every NotFoundError.
+	ErrNotFound = errors.New("not found")
+	// ErrEntityExists is returned when creating an entity whose name is taken.
+	ErrEntityExists = errors.New("entity already exists")
+	// ErrInvalidInput is matched by every ValidationError.
+	ErrInvalidInput = errors.New("invalid input")
+	// ErrSchemaOutdated is matched by SchemaOutdatedError.
+	ErrSchemaOutdated = errors.New("database schema is outdated")
+	// ErrLeaseHeld is matched by LeaseHeldError.
+	ErrLeaseHeld = errors.New("write lease held")
+)
+
+// NotFoundError reports a missing entity, observation, relation, or session.
+type NotFoundError struct {
+	Kind string // "entity", "entity type", "observation", "relation", "session", "merge", "alias", or "operation"
+	Name string // Entity or session name, observation content, or "from -type-> to"
+}
+
+func (e *NotFoundError) Error() string {
+	name := e.Name
+	if len(name) > 80 {
+		cut := 77
+		for cut > 0 && !utf8.RuneStart(name[cut]) {
+			cut--
+		}
+		name = name[:cut] + "..."
+	}
+	return fmt.Sprintf("%s %q not found", e.Kind, name)
+}
+
+func (e *NotFoundError) Unwrap() error {
+	return ErrNotFound
+}
+
+func entityNotFound(name string) error {
+	return &NotFoundError{"entity", name}
+}
+
+func observationNotFound(content string) error {
+	return &NotFoundError{"observation", content}
+}
+
+func relationNotFound(from, to, relationType string) error {
+	return &NotFoundError{"relation", from + " -" + relationType + "-> " + to}
+}
+
+func entityExists(name string) error {
+	return fmt.Errorf("%w: %q", ErrEntityExists, name)
+}
+
+// SchemaOutdatedError reports a database whose schema is older than the
+// migrations this build knows about.
+type SchemaOutdatedError struct {
+	Current, Latest int64
+}
+
+func (e *SchemaOutdatedError) Error() string {
+	return fmt.Sprintf("database schema is at version %d, this build needs %d; run 'mark42 upgrade'", e.Current, e.Latest)
+}
+
+func (e *SchemaOutdatedError) Unwrap() error {
+	return ErrSchemaOutdated
+}
@@ -0,0 +1,51 @@
+package storage_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+func TestErrorsAreWrapped(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+
+	store.CreateEntity("TDD", "pattern", []string{"Red, green, refactor"})
+	store.CreateEntity("Go", "language", nil)
+
+	for _, tc := range []struct {
+		name string
+		err  error
+		want error
+		kind string
+	}{
+		{"GetEntity", errOnly(store.GetEntity("Missing")), storage.ErrNotFound, "entity"},
+		{"DeleteObservation", store.DeleteObservation("TDD", "Missing"), storage.ErrNotFound, "observation"},
+		{"DeleteRelation", store.DeleteRelation("TDD", "Go", "uses"), storage.ErrNotFound, "relation"},
+		{"GetSession", errOnly(store.GetSession("TDD")), storage.ErrNotFound, "session"},
+		{"CreateEntity", errOnly(store.CreateEntity("TDD", "pattern", nil)), storage.ErrEntityExists, ""},
+		{"CreateEntity invalid", errOnly(store.CreateEntity("", "pattern", nil)), storage.ErrInvalidInput, ""},
+	} {
+		if !errors.Is(tc.err, tc.want) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.want, tc.err)
+			continue
+		}
+		var notFound *storage.NotFoundError
+		if tc.kind != "" && (!errors.As(tc.err, &notFound) || notFound.Kind != tc.kind) {
+			t.Errorf("%s: expected a %s NotFoundError, got %#v", tc.name, tc.kind, tc.err)
+		}
+	}
+}
+
+func TestNotFoundErrorTruncatesLongNames(t *testing.T) {
+	err := &storage.NotFoundError{Kind: "observation", Name: strings.Repeat("x", 500)}
+	if len(err.Error()) > 120 {
+		t.Errorf("expected a short message, got %d bytes", len(err.Error()))
+	}
+}
+
+func errOnly[T any](_ T, err error) error {
+	return err
+}
@@ -0,0 +1,191 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// DefaultEvalK is the cutoff EvaluateSearch measures recall at.
+const DefaultEvalK = 5
+
+// Search modes EvaluateSearch compares.
+const (
+	EvalModeFTS    = "fts"
+	EvalModeVector = "vector"
+	EvalModeHybrid = "hybrid"
+)
+
+// EvalCase is a query and the entities a good search returns for it.
+type EvalCase struct {
+	Query    string   `json:"query"`
+	Expected []string `json:"expected"` // Entity names or aliases
+}
+
+// EvalCaseResult is how one mode did on one case.
+type EvalCaseResult struct {
+	Query  string  `json:"query"`
+	Mode   string  `json:"mode"`
+	Rank   int     `json:"rank"`   // Of the first expected entity, 1-based; 0 when none was found
+	Recall float64 `json:"recall"` // Share of the expected entities in the top k
+}
+
+// EvalModeSummary averages one mode's results over every case.
+type EvalModeSummary struct {
+	Mode    string  `json:"mode"`
+	Recall  float64 `json:"recall"` // Mean recall@k
+	MRR     float64 `json:"mrr"`    // Mean reciprocal rank
+	Skipped string  `json:"skipped,omitempty"`
+}
+
+// EvalReport is the result of EvaluateSearch.
+type EvalReport struct {
+	K       int               `json:"k"`
+	Cases   int               `json:"cases"`
+	Modes   []EvalModeSummary `json:"modes"`
+	Results []EvalCaseResult  `json:"results"`
+}
+
+// evalDepth is how many results per mode are ranked for MRR, at least.
+const evalDepth = 50
+
+// EvaluateSearch runs each case's query through keyword, vector, and hybrid
+// search, ranks the entities each returns by their best result, and reports
+// recall@k and mean reciprocal rank per mode. Expected names may be aliases.
+// Vector search is skipped when embedder is nil or fails; hybrid search then
+// uses keywords alone, as it would in use.
+func (s *Store) EvaluateSearch(ctx context.Context, cases []EvalCase, k int, embedder *EmbeddingClient) (*EvalReport, error) {
+	if len(cases) == 0 {
+		return nil, &ValidationError{Field: "cases", Reason: "need at least one case"}
+	}
+	if k <= 0 {
+		k = DefaultEvalK
+	}
+	depth := max(k*5, evalDepth)
+
+	report := &EvalReport{K: k, Cases: len(cases), Modes: []EvalModeSummary{
+		{Mode: EvalModeFTS}, {Mode: EvalModeVector}, {Mode: EvalModeHybrid},
+	}}
+	summaries := map[string]*EvalModeSummary{}
+	for i := range report.Modes {
+		summaries[report.Modes[i].Mode] = &report.Modes[i]
+	}
+	queries := make([]string, len(cases))
+	for i, c := range cases {
+		if strings.TrimSpace(c.Query) == "" || len(c.Expected) == 0 {
+			return nil, &ValidationError{Field: fmt.Sprintf("cases[%d]", i), Reason: "needs a query and expected entities"}
+		}
+		queries[i] = c.Query
+	}
+
+	// Every query is embedded up front, so all cases see the same modes
+	var embeddings [][]float64
+	switch {
+	case embedder == nil:
+		summaries[EvalModeVector].Skipped = "no embedder"
+	case s.remote:
+		summaries[EvalModeVector].Skipped = "hosted database"
+	default:
+		var err error
+		if embeddings, err = embedder.CreateBatchEmbedding(ctx, queries); err != nil {
+			summaries[EvalModeVector].Skipped = "embedder failed: " + err.Error()
+			embeddings = nil
+		}
+	}
+
+	for i, c := range cases {
+		expected := make(map[string]bool, len(c.Expected))
+		for _, name := range c.Expected {
+			if resolved, err := s.ResolveEntityNameContext(ctx, name); err == nil {
+				name = resolved
+			}
+			expected[name] = true
+		}
+
+		var embedding []float64
+		if embeddings != nil {
+			embedding = embeddings[i]
+		}
+
+		ranked := map[string][]string{}
+		fts, err := s.ftsSearch(ctx, c.Query, depth, false)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range fts {
+			ranked[EvalModeFTS] = append(ranked[EvalModeFTS], r.EntityName)
+		}
+		if embedding != nil {
+			vector, err := s.VectorSearchContext(ctx, embedding, depth)
+			if err != nil {
+				return nil, err
+			}
+			for _, r := range vector {
+				ranked[EvalModeVector] = append(ranked[EvalModeVector], r.EntityName)
+			}
+		}
+		hybrid, err := s.HybridSearch(ctx, c.Query, embedding, depth)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range hybrid {
+			ranked[EvalModeHybrid] = append(ranked[EvalModeHybrid], r.EntityName)
+		}
+
+		for _, summary := range report.Modes {
+			if summary.Skipped != "" {
+				continue
+			}
+			result := scoreEvalCase(dedupeNames(ranked[summary.Mode]), expected, k)
+			result.Query, result.Mode = c.Query, summary.Mode
+			report.Results = append(report.Results, result)
+		}
+	}
+
+	for _, result := range report.Results {
+		summary := summaries[result.Mode]
+		summary.Recall += result.Recall
+		if result.Rank > 0 {
+			summary.MRR += 1 / float64(result.Rank)
+		}
+	}
+	for i := range report.Modes {
+		if report.Modes[i].Skipped == "" {
+			report.Modes[i].Recall /= float64(len(cases))
+			report.Modes[i].MRR /= float64(len(cases))
+		}
+	}
+	return report, nil
+}
+
+// scoreEvalCase scores entity names in rank order against the expected set.
+func scoreEvalCase(names []string, expected map[string]bool, k int) EvalCaseResult {
+	var result EvalCaseResult
+	found := 0
+	for i, name := range names {
+		if !expected[name] {
+			continue
+		}
+		if result.Rank == 0 {
+			result.Rank = i + 1
+		}
+		if i < k {
+			found++
+		}
+	}
+	result.Recall = float64(found) / float64(len(expected))
+	return result
+}
+
+// dedupeNames keeps the first occurrence of each name, in order.
+func dedupeNames(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	unique := names[:0:0]
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			unique = append(unique, name)
+		}
+	}
+	return unique
+}
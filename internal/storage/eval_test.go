@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEvaluateSearch(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	for _, td := range []struct {
+		name, observation string
+		embedding         []float64
+	}{
+		{"user_editor", "uses neovim editor daily", []float64{1, 0}},
+		{"user_lang", "prefers typescript programming", []float64{0, 1}},
+	} {
+		entity, _ := store.CreateEntity(td.name, "person", []string{td.observation})
+		id, _ := store.getObservationID(context.Background(), entity.ID, td.observation)
+		store.StoreEmbedding(id, td.embedding, "test-model")
+	}
+	store.AddAlias("user_editor", "editor")
+
+	// Queries about editors embed near user_editor
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req embeddingRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		resp := embeddingResponse{}
+		for i := range req.Input {
+			resp.Data = append(resp.Data, struct {
+				Object    string    `json:"object"`
+				Index     int       `json:"index"`
+				Embedding []float64 `json:"embedding"`
+			}{Index: i, Embedding: []float64{1, 0.1}})
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	cases := []EvalCase{
+		{Query: "neovim", Expected: []string{"editor"}},
+		{Query: "text editing tool", Expected: []string{"user_editor"}},
+	}
+	report, err := store.EvaluateSearch(context.Background(), cases, 1, NewEmbeddingClient(server.URL))
+	if err != nil {
+		t.Fatalf("EvaluateSearch failed: %v", err)
+	}
+
+	modes := map[string]EvalModeSummary{}
+	for _, m := range report.Modes {
+		modes[m.Mode] = m
+	}
+	// Keywords only find the first case; vectors find both
+	if m := modes[EvalModeFTS]; m.Recall != 0.5 || m.MRR != 0.5 {
+		t.Errorf("fts = %+v, expected recall 0.5 and MRR 0.5", m)
+	}
+	if m := modes[EvalModeVector]; m.Recall != 1 || m.MRR != 1 {
+		t.Errorf("vector = %+v, expected recall 1 and MRR 1", m)
+	}
+	if m := modes[EvalModeHybrid]; m.Recall != 1 || math.Abs(m.MRR-1) > 1e-9 {
+		t.Errorf("hybrid = %+v, expected recall 1 and MRR 1", m)
+	}
+	if len(report.Results) != 6 {
+		t.Errorf("expected a result per case and mode, got %d", len(report.Results))
+	}
+
+	// Without an embedder vector search is skipped
+	report, err = store.EvaluateSearch(context.Background(), cases, 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Modes[1].Skipped == "" || len(report.Results) != 4 {
+		t.Errorf("expected vector skipped, got %+v", report)
+	}
+
+	if _, err := store.EvaluateSearch(context.Background(), []EvalCase{{Query: "neovim"}}, 1, nil); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("expected a case without expected entities refused, got %v", err)
+	}
+}
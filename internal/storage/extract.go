@@ -0,0 +1,421 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Extraction is what ExtractFacts or SummaryClient.ExtractFacts found in
+// free text, ready for SaveExtraction.
+type Extraction struct {
+	Entities  []EntitySpec
+	Relations []RelationSpec
+	Skipped   []string // Sentences that named no entity
+}
+
+// RelationSpec describes one relation of an Extraction.
+type RelationSpec struct {
+	From string
+	To   string
+	Type string
+}
+
+// ExtractionResult reports what SaveExtraction wrote.
+type ExtractionResult struct {
+	Entities  []EntityResult
+	Relations []error // Per relation in Extraction order; nil when stored or already present
+}
+
+// DefaultExtractedType is the entity type given to new entities found in text.
+const DefaultExtractedType = "concept"
+
+// relationPatterns maps the words between two entities to a relation type,
+// after leading adverbs and trailing articles are dropped.
+var relationPatterns = map[string]string{
+	"uses": "uses", "use": "uses", "is using": "uses", "using": "uses",
+	"depends on": "depends_on", "relies on": "depends_on", "requires": "depends_on",
+	"is part of": "part_of", "part of": "part_of", "belongs to": "part_of",
+	"is built on": "built_with", "built on": "built_with", "is built with": "built_with", "built with": "built_with",
+	"calls": "calls", "invokes": "calls",
+	"contains": "contains", "includes": "contains",
+	"extends": "extends", "implements": "implements", "wraps": "wraps",
+	"replaces": "replaces", "replaced": "replaces", "supersedes": "replaces",
+	"talks to": "connects_to", "connects to": "connects_to",
+	"writes to": "writes_to", "reads from": "reads_from",
+	"is configured by": "configured_by", "configured by": "configured_by",
+}
+
+// relationFillers are dropped from the start of the words between entities
+// ("X also uses Y"), relationArticles from their end ("X uses the Y").
+var (
+	relationFillers  = wordSet("also now still only mainly mostly heavily directly internally always will should must can may")
+	relationArticles = wordSet("the a an our its their")
+	// Capitalized words that start sentences without naming anything
+	sentenceStarters = wordSet("the a an this that these those it its we i you they he she our my your their there here " +
+		"when if then also but and so because always never don't do use make note remember prefer avoid " +
+		"after before while since today yesterday now every each all some no not")
+)
+
+func wordSet(words string) map[string]bool {
+	set := map[string]bool{}
+	for _, w := range strings.Fields(words) {
+		set[w] = true
+	}
+	return set
+}
+
+var (
+	sentenceEnd = regexp.MustCompile(`[.!?]+(\s+|$)|\n+`)
+	tokenRe     = regexp.MustCompile("`[^`]+`|[\\p{L}\\p{N}_][\\p{L}\\p{N}_./+#-]*[\\p{L}\\p{N}_+#]|[\\p{L}\\p{N}_]")
+	fileLike    = regexp.MustCompile(`/|\.[a-z]{1,5}$`)
+)
+
+// span is an entity mention within a sentence.
+type span struct {
+	start, end int
+	name       string
+	kind       string // Entity type for new entities
+}
+
+// ExtractFacts finds entities, observations, and relations in free text
+// without an LLM. Each sentence becomes an observation on the first entity
+// it names; "A uses B" style phrases between two entities become relations.
+// Entities are names in known (matched case-insensitively), `backticked`
+// terms, code-like tokens (main.go, HTTPServer, pkg/storage), and runs of
+// capitalized words.
+func ExtractFacts(text string, known []string) *Extraction {
+	ex := &Extraction{}
+	index := map[string]int{} // nameKey -> position in ex.Entities
+	addEntity := func(sp span) int {
+		key := nameKey(sp.name)
+		if i, ok := index[key]; ok {
+			return i
+		}
+		index[key] = len(ex.Entities)
+		ex.Entities = append(ex.Entities, EntitySpec{Name: sp.name, Type: sp.kind})
+		return index[key]
+	}
+	seenRelation := map[RelationSpec]bool{}
+
+	for _, sentence := range splitSentences(text) {
+		spans := findEntities(sentence, known)
+		if len(spans) == 0 {
+			ex.Skipped = append(ex.Skipped, sentence)
+			continue
+		}
+
+		subject := addEntity(spans[0])
+		entity := &ex.Entities[subject]
+		if !containsString(entity.Observations, sentence) {
+			entity.Observations = append(entity.Observations, sentence)
+		}
+
+		if len(spans) < 2 {
+			continue
+		}
+		relType := relationPatterns[relationPhrase(sentence[spans[0].end:spans[1].start])]
+		if relType == "" {
+			continue
+		}
+		// "A uses B, C and D" relates A to each
+		for i := 1; i < len(spans); i++ {
+			if i > 1 && !isListSeparator(sentence[spans[i-1].end:spans[i].start]) {
+				break
+			}
+			object := addEntity(spans[i])
+			rel := RelationSpec{From: ex.Entities[subject].Name, To: ex.Entities[object].Name, Type: relType}
+			if rel.From != rel.To && !seenRelation[rel] {
+				seenRelation[rel] = true
+				ex.Relations = append(ex.Relations, rel)
+			}
+		}
+	}
+	return ex
+}
+
+// ExtractKnownFacts is ExtractFacts with the names of every entity in the
+// store as known names, so mentions of them are found whatever their case.
+func (s *Store) ExtractKnownFacts(text string) (*Extraction, error) {
+	return s.ExtractKnownFactsContext(context.Background(), text)
+}
+
+// ExtractKnownFactsContext is ExtractKnownFacts with a context.
+func (s *Store) ExtractKnownFactsContext(ctx context.Context, text string) (*Extraction, error) {
+	var names []string
+	if err := s.db.SelectContext(ctx, &names,
+		"SELECT DISTINCT name FROM entities WHERE is_latest = 1 OR is_latest IS NULL"); err != nil {
+		return nil, err
+	}
+	return ExtractFacts(text, names), nil
+}
+
+// splitSentences splits text at sentence punctuation and line breaks,
+// dropping list markers.
+func splitSentences(text string) []string {
+	var sentences []string
+	last := 0
+	add := func(s string) {
+		s = strings.TrimSpace(s)
+		s = strings.TrimSpace(strings.TrimLeft(s, "-*•"))
+		if s != "" {
+			sentences = append(sentences, s)
+		}
+	}
+	for _, m := range sentenceEnd.FindAllStringIndex(text, -1) {
+		// Keep the punctuation with its sentence
+		end := m[0] + len(strings.TrimRightFunc(text[m[0]:m[1]], unicode.IsSpace))
+		add(text[last:end])
+		last = m[1]
+	}
+	add(text[last:])
+	return sentences
+}
+
+// findEntities returns the non-overlapping entity mentions in a sentence, in order.
+func findEntities(sentence string, known []string) []span {
+	var spans []span
+	taken := make([]bool, len(sentence))
+	claim := func(sp span) {
+		for i := sp.start; i < sp.end; i++ {
+			if taken[i] {
+				return
+			}
+		}
+		for i := sp.start; i < sp.end; i++ {
+			taken[i] = true
+		}
+		spans = append(spans, sp)
+	}
+
+	// Known names first, longest first so "Go Modules" beats "Go"
+	lower := strings.ToLower(sentence)
+	names := append([]string(nil), known...)
+	sort.Slice(names, func(i, j int) bool { return len(names[i]) > len(names[j]) })
+	for _, name := range names {
+		needle := strings.ToLower(name)
+		if needle == "" {
+			continue
+		}
+		for from := 0; ; {
+			i := strings.Index(lower[from:], needle)
+			if i < 0 {
+				break
+			}
+			start, end := from+i, from+i+len(needle)
+			if isWordBoundary(lower, start-1) && isWordBoundary(lower, end) {
+				claim(span{start, end, name, DefaultExtractedType})
+			}
+			from = end
+		}
+	}
+
+	tokens := tokenRe.FindAllStringIndex(sentence, -1)
+	for i := 0; i < len(tokens); i++ {
+		start, end := tokens[i][0], tokens[i][1]
+		tok := sentence[start:end]
+		switch {
+		case strings.HasPrefix(tok, "`"):
+			if name := strings.TrimSpace(strings.Trim(tok, "`")); name != "" {
+				claim(span{start, end, name, kindOf(name)})
+			}
+		case isCodeLike(tok):
+			claim(span{start, end, tok, kindOf(tok)})
+		case isCapitalized(tok):
+			// Extend over following capitalized words: "Docker Desktop"
+			j := i
+			for j+1 < len(tokens) && isCapitalized(sentence[tokens[j+1][0]:tokens[j+1][1]]) &&
+				strings.TrimSpace(sentence[tokens[j][1]:tokens[j+1][0]]) == "" {
+				j++
+			}
+			// Drop sentence starters: "The Parser" names the Parser
+			for i <= j && sentenceStarters[strings.ToLower(sentence[tokens[i][0]:tokens[i][1]])] {
+				i++
+			}
+			// A lone gerund opening the sentence is an activity: "Parsing is slow"
+			if i == j && tokens[i][0] == 0 && strings.HasSuffix(sentence[tokens[i][0]:tokens[i][1]], "ing") {
+				continue
+			}
+			if i <= j {
+				claim(span{tokens[i][0], tokens[j][1], sentence[tokens[i][0]:tokens[j][1]], DefaultExtractedType})
+			}
+			i = j
+		}
+	}
+
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	return spans
+}
+
+func isWordBoundary(s string, i int) bool {
+	if i < 0 || i >= len(s) {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(s[i:])
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+}
+
+func isCapitalized(tok string) bool {
+	r, _ := utf8.DecodeRuneInString(tok)
+	return unicode.IsUpper(r)
+}
+
+// isCodeLike reports tokens that read as identifiers, paths, or acronyms:
+// internal punctuation, a capital after the first letter, or letters mixed
+// with digits.
+func isCodeLike(tok string) bool {
+	if strings.ContainsAny(tok, "._/#+") {
+		return true
+	}
+	var letters, digits, innerUpper int
+	for i, r := range tok {
+		switch {
+		case unicode.IsDigit(r):
+			digits++
+		case unicode.IsLetter(r):
+			letters++
+			if i > 0 && unicode.IsUpper(r) {
+				innerUpper++
+			}
+		}
+	}
+	return letters > 0 && (innerUpper > 0 || digits > 0)
+}
+
+func kindOf(name string) string {
+	if fileLike.MatchString(name) {
+		return "file"
+	}
+	return DefaultExtractedType
+}
+
+// relationPhrase normalizes the words between two entity mentions.
+func relationPhrase(between string) string {
+	words := strings.Fields(strings.ToLower(strings.Trim(between, " ,;:")))
+	for len(words) > 0 && relationFillers[words[0]] {
+		words = words[1:]
+	}
+	for len(words) > 0 && relationArticles[words[len(words)-1]] {
+		words = words[:len(words)-1]
+	}
+	return strings.Join(words, " ")
+}
+
+func isListSeparator(between string) bool {
+	switch relationPhrase(between) {
+	case "", "and", "or":
+		return true
+	}
+	return false
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+const extractPrompt = `Extract facts for a knowledge graph from the user's text. Reply with JSON only, in this shape:
+{"entities": [{"name": "...", "type": "...", "observations": ["..."]}], "relations": [{"from": "...", "to": "...", "type": "..."}]}
+Entities are specific things (projects, components, tools, people, decisions). Observations are short standalone facts about one entity.
+Relation types are snake_case verbs such as uses, depends_on, part_of. Every relation endpoint must be listed in entities.`
+
+// ExtractFacts asks the LLM for the entities, observations, and relations in
+// text. Entities it leaves untyped get DefaultExtractedType.
+func (c *SummaryClient) ExtractFacts(ctx context.Context, text string) (*Extraction, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, errors.New("empty text")
+	}
+	reply, err := c.complete(ctx, extractPrompt, text)
+	if err != nil {
+		return nil, err
+	}
+	return parseExtraction(reply)
+}
+
+// parseExtraction reads the LLM's JSON reply, tolerating a Markdown code fence.
+func parseExtraction(reply string) (*Extraction, error) {
+	if i, j := strings.Index(reply, "{"), strings.LastIndex(reply, "}"); i >= 0 && j > i {
+		reply = reply[i : j+1]
+	}
+	var raw struct {
+		Entities []struct {
+			Name         string   `json:"name"`
+			Type         string   `json:"type"`
+			Observations []string `json:"observations"`
+		} `json:"entities"`
+		Relations []struct {
+			From string `json:"from"`
+			To   string `json:"to"`
+			Type string `json:"type"`
+		} `json:"relations"`
+	}
+	if err := json.Unmarshal([]byte(reply), &raw); err != nil {
+		return nil, fmt.Errorf("decoding extraction: %w", err)
+	}
+
+	ex := &Extraction{}
+	index := map[string]bool{}
+	addEntity := func(name, entityType string, observations []string) {
+		if index[nameKey(name)] {
+			return
+		}
+		index[nameKey(name)] = true
+		if entityType == "" {
+			entityType = DefaultExtractedType
+		}
+		ex.Entities = append(ex.Entities, EntitySpec{Name: name, Type: entityType, Observations: observations})
+	}
+	for _, e := range raw.Entities {
+		if name := strings.TrimSpace(e.Name); name != "" {
+			addEntity(name, strings.TrimSpace(e.Type), e.Observations)
+		}
+	}
+	for _, r := range raw.Relations {
+		from, to, relType := strings.TrimSpace(r.From), strings.TrimSpace(r.To), strings.TrimSpace(r.Type)
+		if from == "" || to == "" || relType == "" {
+			continue
+		}
+		addEntity(from, "", nil)
+		addEntity(to, "", nil)
+		ex.Relations = append(ex.Relations, RelationSpec{From: from, To: to, Type: relType})
+	}
+	if len(ex.Entities) == 0 {
+		return nil, errors.New("no entities extracted")
+	}
+	return ex, nil
+}
+
+// SaveExtraction writes an extraction: its entities and observations as
+// CreateEntities does, then its relations. Entities that already exist keep
+// their type and gain the new observations.
+func (s *Store) SaveExtraction(ex *Extraction) (*ExtractionResult, error) {
+	return s.SaveExtractionContext(context.Background(), ex)
+}
+
+// SaveExtractionContext is SaveExtraction with a context.
+func (s *Store) SaveExtractionContext(ctx context.Context, ex *Extraction) (*ExtractionResult, error) {
+	entities, err := s.CreateEntitiesContext(ctx, ex.Entities)
+	if err != nil {
+		return nil, err
+	}
+	result := &ExtractionResult{Entities: entities, Relations: make([]error, len(ex.Relations))}
+	for i, r := range ex.Relations {
+		if err := s.CreateRelationContext(ctx, r.From, r.To, r.Type); err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			result.Relations[i] = err
+		}
+	}
+	return result, nil
+}
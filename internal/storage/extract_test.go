@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestExtractFacts(t *testing.T) {
+	ex := ExtractFacts("The Parser uses the Lexer. We moved settings to `config.yaml`.\n"+
+		"- mark42 depends on SQLite and Goose\nParsing is slow. the api server also talks to redis!",
+		[]string{"API server", "redis"})
+
+	var names []string
+	for _, e := range ex.Entities {
+		names = append(names, e.Name+":"+e.Type)
+	}
+	want := []string{"Parser:concept", "Lexer:concept", "config.yaml:file", "mark42:concept",
+		"SQLite:concept", "Goose:concept", "API server:concept", "redis:concept"}
+	if !slices.Equal(names, want) {
+		t.Errorf("entities = %q, want %q", names, want)
+	}
+	if obs := ex.Entities[0].Observations; !slices.Equal(obs, []string{"The Parser uses the Lexer."}) {
+		t.Errorf("expected the sentence observed on its subject, got %q", obs)
+	}
+	if obs := ex.Entities[1].Observations; len(obs) != 0 {
+		t.Errorf("expected nothing observed on a relation target, got %q", obs)
+	}
+
+	wantRelations := []RelationSpec{
+		{"Parser", "Lexer", "uses"},
+		{"mark42", "SQLite", "depends_on"},
+		{"mark42", "Goose", "depends_on"},
+		{"API server", "redis", "connects_to"},
+	}
+	if !slices.Equal(ex.Relations, wantRelations) {
+		t.Errorf("relations = %+v, want %+v", ex.Relations, wantRelations)
+	}
+	if !slices.Equal(ex.Skipped, []string{"Parsing is slow."}) {
+		t.Errorf("skipped = %q", ex.Skipped)
+	}
+}
+
+func TestSummaryClient_ExtractFacts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "` +
+			"```json\\n" + `{\"entities\": [{\"name\": \"Go\", \"type\": \"language\", \"observations\": [\"Compiles fast\"]}],` +
+			` \"relations\": [{\"from\": \"mark42\", \"to\": \"Go\", \"type\": \"built_with\"}, {\"from\": \"\", \"to\": \"Go\", \"type\": \"x\"}]}` +
+			"\\n```" + `"}}]}`))
+	}))
+	defer server.Close()
+
+	ex, err := NewSummaryClient(server.URL, "llama3.2").ExtractFacts(context.Background(), "mark42 is written in Go")
+	if err != nil {
+		t.Fatalf("ExtractFacts failed: %v", err)
+	}
+	if len(ex.Entities) != 2 || ex.Entities[0].Name != "Go" || ex.Entities[1].Name != "mark42" ||
+		ex.Entities[1].Type != DefaultExtractedType {
+		t.Errorf("expected Go plus the untyped relation endpoint, got %+v", ex.Entities)
+	}
+	if !slices.Equal(ex.Relations, []RelationSpec{{"mark42", "Go", "built_with"}}) {
+		t.Errorf("expected incomplete relations dropped, got %+v", ex.Relations)
+	}
+
+	if _, err := parseExtraction("I could not find any facts."); err == nil {
+		t.Error("expected an error for a reply without JSON")
+	}
+}
+
+func TestSaveExtraction(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+	store.CreateEntity("Parser", "component", []string{"Reads config"})
+
+	ex, err := store.ExtractKnownFacts("The parser uses the Lexer. Lexer uses itself: Lexer uses Lexer.")
+	if err != nil {
+		t.Fatalf("ExtractKnownFacts failed: %v", err)
+	}
+	result, err := store.SaveExtraction(ex)
+	if err != nil {
+		t.Fatalf("SaveExtraction failed: %v", err)
+	}
+	if result.Entities[0].Name != "Parser" || result.Entities[0].Created || result.Entities[0].Added != 1 {
+		t.Errorf("expected the existing entity matched and extended, got %+v", result.Entities[0])
+	}
+	if len(result.Relations) != 1 || result.Relations[0] != nil {
+		t.Errorf("expected one stored relation, got %v", result.Relations)
+	}
+
+	entity, _ := store.GetEntity("Parser")
+	if entity.Type != "component" {
+		t.Errorf("expected the existing type kept, got %q", entity.Type)
+	}
+	relations, _ := store.ListRelations("Lexer")
+	if len(relations) != 1 || relations[0].From != "Parser" {
+		t.Errorf("expected Parser -> Lexer, got %+v", relations)
+	}
+
+	bad := &Extraction{Relations: []RelationSpec{{"Parser", "Missing", "uses"}}}
+	result, err = store.SaveExtraction(bad)
+	if err != nil {
+		t.Fatalf("SaveExtraction failed: %v", err)
+	}
+	if !errors.Is(result.Relations[0], ErrNotFound) {
+		t.Errorf("expected the relation rejected per item, got %v", result.Relations[0])
+	}
+}
@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// FTSTokenizer configures how the full-text indexes split and normalize
+// text, as options of SQLite's unicode61 tokenizer.
+type FTSTokenizer struct {
+	// Porter stems words, so "testing" and "tests" both match "test"
+	Porter bool `json:"porter"`
+	// RemoveDiacritics is unicode61's remove_diacritics: 0 keeps them, 1
+	// removes them from most characters, 2 from all ("café" matches "cafe")
+	RemoveDiacritics int `json:"removeDiacritics"`
+	// Separators are extra characters that split tokens
+	Separators string `json:"separators,omitempty"`
+	// TokenChars are extra characters kept inside tokens ("-_" keeps
+	// "read-only" one token)
+	TokenChars string `json:"tokenChars,omitempty"`
+}
+
+// DefaultFTSTokenizer returns the tokenizer new databases are created with.
+func DefaultFTSTokenizer() FTSTokenizer {
+	return FTSTokenizer{Porter: true, RemoveDiacritics: 1}
+}
+
+// Validate checks that t describes a tokenizer FTS5 accepts.
+func (t FTSTokenizer) Validate() error {
+	if t.RemoveDiacritics < 0 || t.RemoveDiacritics > 2 {
+		return &ValidationError{"removeDiacritics", "must be 0, 1, or 2"}
+	}
+	return nil
+}
+
+// Spec returns the FTS5 tokenize option for t, e.g. "porter unicode61
+// remove_diacritics 2".
+func (t FTSTokenizer) Spec() string {
+	spec := "unicode61"
+	if t.Porter {
+		spec = "porter " + spec
+	}
+	// 1 is unicode61's default; leaving it out keeps the default spec unchanged
+	if t.RemoveDiacritics != 1 {
+		spec += fmt.Sprintf(" remove_diacritics %d", t.RemoveDiacritics)
+	}
+	if t.Separators != "" {
+		spec += " separators " + ftsQuote(t.Separators)
+	}
+	if t.TokenChars != "" {
+		spec += " tokenchars " + ftsQuote(t.TokenChars)
+	}
+	return spec
+}
+
+// ftsQuote quotes a tokenizer argument, doubling quotes inside it.
+func ftsQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// tokenizeOption matches the tokenize option in an FTS5 table's SQL.
+var tokenizeOption = regexp.MustCompile(`tokenize\s*=\s*'((?:[^']|'')*)'`)
+
+// FTSTokenizerSpec returns the tokenize option the observation index was
+// built with, or "" without FTS5.
+func (s *Store) FTSTokenizerSpec() (string, error) {
+	return s.FTSTokenizerSpecContext(context.Background())
+}
+
+// FTSTokenizerSpecContext is FTSTokenizerSpec with a context.
+func (s *Store) FTSTokenizerSpecContext(ctx context.Context) (string, error) {
+	if !s.fts {
+		return "", nil
+	}
+	var schema string
+	if err := s.db.GetContext(ctx, &schema,
+		"SELECT sql FROM sqlite_master WHERE type = 'table' AND name = 'observations_fts'"); err != nil {
+		return "", err
+	}
+	m := tokenizeOption.FindStringSubmatch(schema)
+	if m == nil {
+		return "unicode61", nil // FTS5's default
+	}
+	return strings.ReplaceAll(m[1], "''", "'"), nil
+}
+
+// ReindexFTS rebuilds the observation and entity indexes with tokenizer t.
+// Searches match with the tokenizer an index was built with, so changing
+// the tokenizer takes a reindex.
+func (s *Store) ReindexFTS(t FTSTokenizer) error {
+	return s.ReindexFTSContext(context.Background(), t)
+}
+
+// ReindexFTSContext is ReindexFTS with a context.
+func (s *Store) ReindexFTSContext(ctx context.Context, t FTSTokenizer) error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+	if !s.fts {
+		return fmt.Errorf("full-text search is not available on this database")
+	}
+	tokenize := strings.ReplaceAll(t.Spec(), "'", "''")
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// The sync triggers name the indexes rather than depend on them, so
+	// they keep working once the indexes are recreated
+	if _, err := tx.ExecContext(ctx, `
+		DROP TABLE IF EXISTS observations_fts;
+		DROP TABLE IF EXISTS entities_fts;
+
+		CREATE VIRTUAL TABLE observations_fts USING fts5(
+			content,
+			content='observations',
+			content_rowid='id',
+			tokenize='`+tokenize+`'
+		);
+
+		CREATE VIRTUAL TABLE entities_fts USING fts5(
+			name,
+			entity_type,
+			content='entities',
+			content_rowid='id',
+			tokenize='`+tokenize+`'
+		);
+
+		INSERT INTO observations_fts(observations_fts) VALUES('rebuild');
+		INSERT INTO entities_fts(entities_fts) VALUES('rebuild');
+	`); err != nil {
+		return fmt.Errorf("failed to rebuild FTS indexes: %w", err)
+	}
+	return tx.Commit()
+}
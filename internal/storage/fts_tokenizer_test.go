@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFTSTokenizerSpec(t *testing.T) {
+	tests := []struct {
+		tokenizer FTSTokenizer
+		want      string
+	}{
+		{DefaultFTSTokenizer(), "porter unicode61"},
+		{FTSTokenizer{RemoveDiacritics: 2}, "unicode61 remove_diacritics 2"},
+		{FTSTokenizer{Porter: true, RemoveDiacritics: 1, TokenChars: "-'"}, "porter unicode61 tokenchars '-'''"},
+		{FTSTokenizer{RemoveDiacritics: 1, Separators: "/"}, "unicode61 separators '/'"},
+	}
+	for _, tt := range tests {
+		if got := tt.tokenizer.Spec(); got != tt.want {
+			t.Errorf("Spec(%+v) = %q, want %q", tt.tokenizer, got, tt.want)
+		}
+	}
+}
+
+func TestReindexFTS(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	if spec, err := store.FTSTokenizerSpec(); err != nil || spec != DefaultFTSTokenizer().Spec() {
+		t.Fatalf("expected new databases built with the default tokenizer, got %q, %v", spec, err)
+	}
+
+	store.CreateEntity("Cafe", "place", []string{"Serves crème brûlée", "Has read-only wifi"})
+	if results, _ := store.Search("creme"); len(results) != 1 {
+		t.Fatalf("expected diacritics removed by default, got %+v", results)
+	}
+
+	tokenizer := FTSTokenizer{Porter: true, RemoveDiacritics: 0, TokenChars: "-"}
+	if err := store.ReindexFTS(tokenizer); err != nil {
+		t.Fatalf("ReindexFTS: %v", err)
+	}
+	if spec, _ := store.FTSTokenizerSpec(); spec != tokenizer.Spec() {
+		t.Errorf("FTSTokenizerSpec = %q, want %q", spec, tokenizer.Spec())
+	}
+	if results, _ := store.Search("creme"); len(results) != 0 {
+		t.Errorf("expected creme not to match crème once diacritics are kept, got %+v", results)
+	}
+	if results, _ := store.Search("only"); len(results) != 0 {
+		t.Errorf("expected read-only kept as one token, got %+v", results)
+	}
+
+	// Writes after the reindex still reach the index
+	store.CreateEntity("Bistro", "place", []string{"Sells pâté"})
+	if results, _ := store.Search("pâté"); len(results) != 1 {
+		t.Errorf("expected new observations indexed, got %+v", results)
+	}
+
+	if err := store.ReindexFTS(FTSTokenizer{RemoveDiacritics: 3}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("expected an invalid option rejected, got %v", err)
+	}
+}
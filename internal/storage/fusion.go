@@ -23,11 +23,47 @@ type FusedResult struct {
 	FusionScore  float64            // Combined score after fusion
 	SourceScores map[string]float64 // Original scores from each source
 	SourceRanks  map[string]int     // Rank in each source's results
+	// SourceContributions is what each source added to FusionScore
+	SourceContributions map[string]float64 `json:",omitempty"`
+	// Boost is the container tag multiplier FusionScore includes; 0 when
+	// none was applied
+	Boost float64 `json:",omitempty"`
+}
+
+// SourceExplanation is one strategy's part in a fused result's score.
+type SourceExplanation struct {
+	Source       string  `json:"source"`
+	Rank         int     `json:"rank,omitempty"`
+	Score        float64 `json:"score"`        // BM25 (higher is better) or cosine similarity
+	Contribution float64 `json:"contribution"` // What the source added to FusionScore
+}
+
+// Explain breaks r's FusionScore down by source, in source name order.
+func (r FusedResult) Explain() []SourceExplanation {
+	sources := make([]string, 0, len(r.SourceScores))
+	for source := range r.SourceScores {
+		sources = append(sources, source)
+	}
+	slices.Sort(sources)
+
+	parts := make([]SourceExplanation, len(sources))
+	for i, source := range sources {
+		parts[i] = SourceExplanation{
+			Source:       source,
+			Rank:         r.SourceRanks[source],
+			Score:        r.SourceScores[source],
+			Contribution: r.SourceContributions[source],
+		}
+	}
+	return parts
 }
 
 // RRFConfig holds configuration for Reciprocal Rank Fusion.
 type RRFConfig struct {
 	K int // Smoothing parameter (default: 60)
+	// Weights multiplies each source's contribution; sources not in it
+	// weigh 1
+	Weights map[string]float64
 }
 
 // DefaultRRFConfig returns the default RRF configuration.
@@ -62,6 +98,8 @@ func FuseRRF(strategyResults map[string][]RankedItem, config RRFConfig) []FusedR
 					FusionScore:  r.Score,
 					SourceScores: map[string]float64{source: r.Score},
 					SourceRanks:  map[string]int{source: i + 1},
+					// With nothing to fuse, the score passes through
+					SourceContributions: map[string]float64{source: r.Score},
 				}
 			}
 			return fused
@@ -75,6 +113,7 @@ func FuseRRF(strategyResults map[string][]RankedItem, config RRFConfig) []FusedR
 		Content      string
 		SourceScores map[string]float64
 		SourceRanks  map[string]int
+		Contribution map[string]float64
 		FusionScore  float64
 	}
 
@@ -92,16 +131,21 @@ func FuseRRF(strategyResults map[string][]RankedItem, config RRFConfig) []FusedR
 					Content:      result.Content,
 					SourceScores: make(map[string]float64),
 					SourceRanks:  make(map[string]int),
+					Contribution: make(map[string]float64),
 				}
 			}
 
 			// RRF formula: 1 / (k + rank)
 			// rank starts at 1 for the first result
 			rrfScore := 1.0 / float64(k+rank+1)
+			if weight, ok := config.Weights[source]; ok {
+				rrfScore *= weight
+			}
 
 			docScores[docID].FusionScore += rrfScore
 			docScores[docID].SourceScores[source] = result.Score
 			docScores[docID].SourceRanks[source] = rank + 1
+			docScores[docID].Contribution[source] = rrfScore
 		}
 	}
 
@@ -109,12 +153,13 @@ func FuseRRF(strategyResults map[string][]RankedItem, config RRFConfig) []FusedR
 	results := make([]FusedResult, 0, len(docScores))
 	for _, doc := range docScores {
 		results = append(results, FusedResult{
-			EntityName:   doc.EntityName,
-			EntityType:   doc.EntityType,
-			Content:      doc.Content,
-			FusionScore:  doc.FusionScore,
-			SourceScores: doc.SourceScores,
-			SourceRanks:  doc.SourceRanks,
+			EntityName:          doc.EntityName,
+			EntityType:          doc.EntityType,
+			Content:             doc.Content,
+			FusionScore:         doc.FusionScore,
+			SourceScores:        doc.SourceScores,
+			SourceRanks:         doc.SourceRanks,
+			SourceContributions: doc.Contribution,
 		})
 	}
 
@@ -149,6 +194,7 @@ func FuseWeighted(strategyResults map[string][]RankedItem, config WeightedConfig
 		EntityType   string
 		Content      string
 		SourceScores map[string]float64
+		Contribution map[string]float64
 		FusionScore  float64
 	}
 
@@ -169,22 +215,25 @@ func FuseWeighted(strategyResults map[string][]RankedItem, config WeightedConfig
 					EntityType:   result.EntityType,
 					Content:      result.Content,
 					SourceScores: make(map[string]float64),
+					Contribution: make(map[string]float64),
 				}
 			}
 
 			docScores[docID].FusionScore += result.Score * weight
 			docScores[docID].SourceScores[source] = result.Score
+			docScores[docID].Contribution[source] = result.Score * weight
 		}
 	}
 
 	results := make([]FusedResult, 0, len(docScores))
 	for _, doc := range docScores {
 		results = append(results, FusedResult{
-			EntityName:   doc.EntityName,
-			EntityType:   doc.EntityType,
-			Content:      doc.Content,
-			FusionScore:  doc.FusionScore,
-			SourceScores: doc.SourceScores,
+			EntityName:          doc.EntityName,
+			EntityType:          doc.EntityType,
+			Content:             doc.Content,
+			FusionScore:         doc.FusionScore,
+			SourceScores:        doc.SourceScores,
+			SourceContributions: doc.Contribution,
 		})
 	}
 
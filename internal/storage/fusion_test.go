@@ -115,6 +115,35 @@ func TestFuseRRF_RankingMath(t *testing.T) {
 	}
 }
 
+func TestFusedResult_Explain(t *testing.T) {
+	input := map[string][]RankedItem{
+		"vector": {{Content: "doc1", Score: 0.9}},
+		"fts":    {{Content: "doc2", Score: 4.0}, {Content: "doc1", Score: 2.5}},
+	}
+
+	results := FuseRRF(input, RRFConfig{K: 60})
+	var doc1 FusedResult
+	for _, r := range results {
+		if r.Content == "doc1" {
+			doc1 = r
+		}
+	}
+
+	parts := doc1.Explain()
+	if len(parts) != 2 || parts[0].Source != "fts" || parts[1].Source != "vector" {
+		t.Fatalf("expected fts then vector, got %+v", parts)
+	}
+	if parts[0].Rank != 2 || parts[0].Score != 2.5 || parts[0].Contribution != 1.0/62.0 {
+		t.Errorf("fts part = %+v, want rank 2, score 2.5, contribution 1/62", parts[0])
+	}
+	if parts[1].Rank != 1 || parts[1].Score != 0.9 || parts[1].Contribution != 1.0/61.0 {
+		t.Errorf("vector part = %+v, want rank 1, score 0.9, contribution 1/61", parts[1])
+	}
+	if sum := parts[0].Contribution + parts[1].Contribution; sum != doc1.FusionScore {
+		t.Errorf("contributions sum to %f, fusion score is %f", sum, doc1.FusionScore)
+	}
+}
+
 func TestFuseWeighted_EmptyInput(t *testing.T) {
 	results := FuseWeighted(nil, WeightedConfig{})
 	if len(results) != 0 {
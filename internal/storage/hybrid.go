@@ -5,15 +5,27 @@ import (
 	"strings"
 )
 
-// HybridSearch combines FTS5 keyword search with vector semantic search using RRF fusion.
+// HybridOptions controls hybrid search.
+type HybridOptions struct {
+	IncludeSuppressed bool // Match suppressed observations too
+}
+
+// HybridSearch combines FTS5 keyword search with vector semantic search using RRF fusion,
+// weighted by the search.vectorWeight and search.rrfK settings.
 // If queryEmbedding is nil, only FTS search is performed.
 // If query is empty, only vector search is performed.
 func (s *Store) HybridSearch(ctx context.Context, query string, queryEmbedding []float64, limit int) ([]FusedResult, error) {
+	return s.HybridSearchWithOptions(ctx, query, queryEmbedding, limit, HybridOptions{})
+}
+
+// HybridSearchWithOptions is HybridSearch with options. Suppressed
+// observations are skipped unless opts.IncludeSuppressed is set.
+func (s *Store) HybridSearchWithOptions(ctx context.Context, query string, queryEmbedding []float64, limit int, opts HybridOptions) ([]FusedResult, error) {
 	strategyResults := make(map[string][]RankedItem)
 
 	// FTS search if query provided
 	if strings.TrimSpace(query) != "" {
-		ftsResults, err := s.ftsSearch(query, limit*2) // Get more results for better fusion
+		ftsResults, err := s.ftsSearch(ctx, query, limit*2, opts.IncludeSuppressed) // Get more results for better fusion
 		if err != nil {
 			return nil, err
 		}
@@ -22,9 +34,9 @@ func (s *Store) HybridSearch(ctx context.Context, query string, queryEmbedding [
 		}
 	}
 
-	// Vector search if embedding provided
-	if len(queryEmbedding) > 0 {
-		vectorResults, err := s.VectorSearch(queryEmbedding, limit*2)
+	// Vector search if embedding provided; skipped on remote stores
+	if len(queryEmbedding) > 0 && !s.remote {
+		vectorResults, err := s.vectorSearch(ctx, queryEmbedding, limit*2, opts.IncludeSuppressed)
 		if err != nil {
 			return nil, err
 		}
@@ -48,8 +60,8 @@ func (s *Store) HybridSearch(ctx context.Context, query string, queryEmbedding [
 		return []FusedResult{}, nil
 	}
 
-	// Fuse results using RRF
-	results := FuseRRF(strategyResults, DefaultRRFConfig())
+	// Fuse results using RRF, balanced by the database's search settings
+	results := FuseRRF(strategyResults, s.searchRRFConfig(ctx))
 
 	// Apply limit
 	if limit > 0 && len(results) > limit {
@@ -60,21 +72,23 @@ func (s *Store) HybridSearch(ctx context.Context, query string, queryEmbedding [
 }
 
 // ftsSearch performs FTS5 search and returns RankedItems.
-func (s *Store) ftsSearch(query string, limit int) ([]RankedItem, error) {
-	ftsQuery := prepareFTSQuery(query)
+func (s *Store) ftsSearch(ctx context.Context, query string, limit int, includeSuppressed bool) ([]RankedItem, error) {
+	obsMatches, obsArgs := s.observationMatchSQL(query)
+	entityMatches, entityArgs := s.entityMatchSQL(query)
+	args := append(obsArgs, includeSuppressed)
+	args = append(args, entityArgs...)
 
-	rows, err := s.db.Query(`
+	rows, err := s.db.QueryContext(ctx, `
 		WITH observation_matches AS (
-			SELECT DISTINCT o.entity_id, o.content, bm25(observations_fts) as score
-			FROM observations_fts f
-			JOIN observations o ON o.id = f.rowid
-			WHERE observations_fts MATCH ?
+			SELECT DISTINCT o.entity_id, o.content, f.score
+			FROM (`+obsMatches+`) f
+			JOIN observations o ON o.id = f.id
+			WHERE ? OR COALESCE(o.suppressed, 0) = 0
 		),
 		entity_matches AS (
-			SELECT e.id as entity_id, e.name as content, bm25(entities_fts) as score
-			FROM entities_fts f
-			JOIN entities e ON e.id = f.rowid
-			WHERE entities_fts MATCH ?
+			SELECT e.id as entity_id, e.name as content, f.score
+			FROM (`+entityMatches+`) f
+			JOIN entities e ON e.id = f.id
 		),
 		combined AS (
 			SELECT entity_id, content, MIN(score) as score
@@ -90,7 +104,7 @@ func (s *Store) ftsSearch(query string, limit int) ([]RankedItem, error) {
 		JOIN entities e ON e.id = c.entity_id
 		ORDER BY c.score
 		LIMIT ?
-	`, ftsQuery, ftsQuery, limit)
+	`, append(args, limit)...)
 	if err != nil {
 		// If FTS query fails, return empty
 		if strings.Contains(err.Error(), "fts5") {
@@ -117,7 +131,7 @@ func (s *Store) ftsSearch(query string, limit int) ([]RankedItem, error) {
 
 // HybridSearchWithEmbedder combines search with automatic embedding generation.
 // Uses the provided embedder to generate query embeddings on the fly.
-func (s *Store) HybridSearchWithEmbedder(ctx context.Context, query string, embedder *EmbeddingClient, limit int) ([]FusedResult, error) {
+func (s *Store) HybridSearchWithEmbedder(ctx context.Context, query string, embedder *EmbeddingClient, limit int, opts HybridOptions) ([]FusedResult, error) {
 	var queryEmbedding []float64
 
 	// Generate embedding for query if embedder is available
@@ -126,10 +140,10 @@ func (s *Store) HybridSearchWithEmbedder(ctx context.Context, query string, embe
 		if err != nil {
 			// Log but continue with FTS-only search
 			// Vector search is enhancement, not requirement
-			return s.HybridSearch(ctx, query, nil, limit)
+			return s.HybridSearchWithOptions(ctx, query, nil, limit, opts)
 		}
 		queryEmbedding = emb
 	}
 
-	return s.HybridSearch(ctx, query, queryEmbedding, limit)
+	return s.HybridSearchWithOptions(ctx, query, queryEmbedding, limit, opts)
 }
@@ -38,7 +38,7 @@ func TestHybridSearch(t *testing.T) {
 		if err != nil {
 			t.Fatalf("failed to create entity: %v", err)
 		}
-		obsID, err := store.getObservationID(entity.ID, td.observation)
+		obsID, err := store.getObservationID(context.Background(), entity.ID, td.observation)
 		if err != nil {
 			t.Fatalf("failed to get observation ID: %v", err)
 		}
@@ -130,7 +130,7 @@ func TestHybridSearch_VectorOnly(t *testing.T) {
 		t.Fatalf("failed to create entity: %v", err)
 	}
 
-	obsID, err := store.getObservationID(entity.ID, "prefers typescript")
+	obsID, err := store.getObservationID(context.Background(), entity.ID, "prefers typescript")
 	if err != nil {
 		t.Fatalf("failed to get observation ID: %v", err)
 	}
@@ -150,3 +150,44 @@ func TestHybridSearch_VectorOnly(t *testing.T) {
 		t.Fatalf("expected 1 result, got %d", len(results))
 	}
 }
+
+func TestHybridSearch_IncludeSuppressed(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	entity, err := store.CreateEntity("Build", "project", []string{"Use make build"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	obsID, err := store.getObservationID(context.Background(), entity.ID, "Use make build")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.StoreEmbedding(obsID, []float64{1, 0, 0}, "test-model"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetObservationSuppressed("Build", "Use make build", true); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	for _, query := range []string{"make", ""} {
+		results, err := store.HybridSearch(ctx, query, []float64{1, 0, 0}, 10)
+		if err != nil {
+			t.Fatalf("HybridSearch(%q) failed: %v", query, err)
+		}
+		for _, r := range results {
+			if r.Content == "Use make build" {
+				t.Errorf("HybridSearch(%q): expected the suppressed observation skipped, got %+v", query, r)
+			}
+		}
+
+		results, err = store.HybridSearchWithOptions(ctx, query, []float64{1, 0, 0}, 10, HybridOptions{IncludeSuppressed: true})
+		if err != nil {
+			t.Fatalf("HybridSearchWithOptions(%q) failed: %v", query, err)
+		}
+		if len(results) == 0 || results[0].Content != "Use make build" {
+			t.Errorf("HybridSearchWithOptions(%q): expected the suppressed observation, got %+v", query, results)
+		}
+	}
+}
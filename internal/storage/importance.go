@@ -1,8 +1,14 @@
 package storage
 
 import (
+	"context"
+	"database/sql"
+	"maps"
 	"math"
+	"slices"
 	"time"
+
+	"github.com/jmoiron/sqlx"
 )
 
 // ImportanceConfig holds configuration for importance scoring.
@@ -11,6 +17,11 @@ type ImportanceConfig struct {
 	RecencyWeight    float64 // Weight for recency factor (0-1)
 	FrequencyWeight  float64 // Weight for access frequency (0-1)
 	CentralityWeight float64 // Weight for relation centrality (0-1)
+
+	// FactTypeBoosts multiplies context ranking scores per fact type.
+	FactTypeBoosts map[string]float64
+	// RelationWeights is what a relation of each type adds to centrality; 1.0 if unset.
+	RelationWeights map[string]float64
 }
 
 // DefaultImportanceConfig returns the default importance scoring configuration.
@@ -20,7 +31,96 @@ func DefaultImportanceConfig() ImportanceConfig {
 		RecencyWeight:    0.4, // 40% weight on recency
 		FrequencyWeight:  0.3, // 30% weight on frequency
 		CentralityWeight: 0.3, // 30% weight on centrality
+		FactTypeBoosts: map[string]float64{
+			string(FactTypeStatic): 1.2,
+		},
+	}
+}
+
+// ImportanceOverrides is a config file's importance settings. Nil fields
+// keep the value from the previous layer; boosts and relation weights merge
+// per type.
+type ImportanceOverrides struct {
+	DecayConstant    *float64           `json:"decayConstant,omitempty"`
+	RecencyWeight    *float64           `json:"recencyWeight,omitempty"`
+	FrequencyWeight  *float64           `json:"frequencyWeight,omitempty"`
+	CentralityWeight *float64           `json:"centralityWeight,omitempty"`
+	FactTypeBoosts   map[string]float64 `json:"factTypeBoosts,omitempty"`
+	RelationWeights  map[string]float64 `json:"relationWeights,omitempty"`
+}
+
+// Apply sets the fields of cfg that o sets.
+func (o ImportanceOverrides) Apply(cfg *ImportanceConfig) {
+	if o.DecayConstant != nil && *o.DecayConstant > 0 {
+		cfg.DecayConstant = *o.DecayConstant
 	}
+	if o.RecencyWeight != nil {
+		cfg.RecencyWeight = *o.RecencyWeight
+	}
+	if o.FrequencyWeight != nil {
+		cfg.FrequencyWeight = *o.FrequencyWeight
+	}
+	if o.CentralityWeight != nil {
+		cfg.CentralityWeight = *o.CentralityWeight
+	}
+	if len(o.FactTypeBoosts) > 0 {
+		boosts := maps.Clone(cfg.FactTypeBoosts)
+		if boosts == nil {
+			boosts = make(map[string]float64, len(o.FactTypeBoosts))
+		}
+		maps.Copy(boosts, o.FactTypeBoosts)
+		cfg.FactTypeBoosts = boosts
+	}
+	if len(o.RelationWeights) > 0 {
+		weights := maps.Clone(cfg.RelationWeights)
+		if weights == nil {
+			weights = make(map[string]float64, len(o.RelationWeights))
+		}
+		maps.Copy(weights, o.RelationWeights)
+		cfg.RelationWeights = weights
+	}
+}
+
+// FactTypeBoost returns the context ranking multiplier for a fact type (1.0 if unset).
+func (c ImportanceConfig) FactTypeBoost(factType string) float64 {
+	if boost, ok := c.FactTypeBoosts[factType]; ok && boost > 0 {
+		return boost
+	}
+	return 1.0
+}
+
+// RelationWeight returns what a relation of relationType adds to its
+// entities' centrality (1.0 if unset).
+func (c ImportanceConfig) RelationWeight(relationType string) float64 {
+	if weight, ok := c.RelationWeights[relationType]; ok && weight >= 0 {
+		return weight
+	}
+	return 1.0
+}
+
+// relationWeightSQL returns an expression for the weight of relations r.
+func (c ImportanceConfig) relationWeightSQL() (string, []any) {
+	var args []any
+	expr := "CASE r.relation_type"
+	for _, t := range slices.Sorted(maps.Keys(c.RelationWeights)) {
+		expr += " WHEN ? THEN ?"
+		args = append(args, t, c.RelationWeight(t))
+	}
+	if args == nil {
+		return "1.0", nil
+	}
+	return expr + " ELSE 1.0 END", args
+}
+
+// ImportanceConfig returns the importance configuration used by this store.
+func (s *Store) ImportanceConfig() ImportanceConfig {
+	return s.importance
+}
+
+// SetImportanceConfig overrides the importance configuration used for
+// recalculation, soft decay, and context ranking.
+func (s *Store) SetImportanceConfig(cfg ImportanceConfig) {
+	s.importance = cfg
 }
 
 // CalculateRecencyDecay calculates exponential decay based on days since last access.
@@ -46,15 +146,16 @@ func CalculateFrequencyScore(accessCount int) float64 {
 	return 1.0 + math.Log(float64(1+accessCount))/10.0
 }
 
-// CalculateCentralityScore returns a score based on relation count relative to max.
-// Well-connected entities are more likely to be relevant.
-// Formula: 0.5 + 0.5 * (relationCount / maxRelations)
+// CalculateCentralityScore returns a score based on an entity's summed
+// relation weight relative to the largest. With every weight 1.0 the sums
+// are relation counts. Well-connected entities are more likely to be relevant.
+// Formula: 0.5 + 0.5 * (relationWeight / maxRelationWeight)
 // Returns 0.5 for isolated nodes, 1.0 for most connected.
-func CalculateCentralityScore(relationCount, maxRelations int) float64 {
-	if maxRelations <= 0 {
+func CalculateCentralityScore(relationWeight, maxRelationWeight float64) float64 {
+	if maxRelationWeight <= 0 {
 		return 0.75 // Default for empty graph
 	}
-	ratio := float64(relationCount) / float64(maxRelations)
+	ratio := relationWeight / maxRelationWeight
 	if ratio > 1 {
 		ratio = 1
 	}
@@ -67,13 +168,13 @@ func CalculateImportance(
 	baseScore float64,
 	daysSinceAccess float64,
 	accessCount int,
-	relationCount int,
-	maxRelations int,
+	relationWeight float64,
+	maxRelationWeight float64,
 	cfg ImportanceConfig,
 ) float64 {
 	recency := CalculateRecencyDecay(daysSinceAccess, cfg.DecayConstant)
 	frequency := CalculateFrequencyScore(accessCount)
-	centrality := CalculateCentralityScore(relationCount, maxRelations)
+	centrality := CalculateCentralityScore(relationWeight, maxRelationWeight)
 
 	// Weighted combination
 	combined := (cfg.RecencyWeight * recency) +
@@ -85,7 +186,12 @@ func CalculateImportance(
 
 // UpdateLastAccessed updates the last_accessed timestamp for all observations of an entity.
 func (s *Store) UpdateLastAccessed(entityName string) error {
-	_, err := s.db.Exec(`
+	return s.UpdateLastAccessedContext(context.Background(), entityName)
+}
+
+// UpdateLastAccessedContext is UpdateLastAccessed with a context.
+func (s *Store) UpdateLastAccessedContext(ctx context.Context, entityName string) error {
+	_, err := s.db.ExecContext(ctx, `
 		UPDATE observations
 		SET last_accessed = CURRENT_TIMESTAMP
 		WHERE entity_id = (SELECT id FROM entities WHERE name = ? AND is_latest = 1)
@@ -93,10 +199,126 @@ func (s *Store) UpdateLastAccessed(entityName string) error {
 	return err
 }
 
+// UpdateAccessAndCount records that a memory was actually useful: it bumps
+// access_count and refreshes last_accessed and last_useful. An empty content
+// marks every observation of the entity. Returns ErrNotFound if nothing matched.
+func (s *Store) UpdateAccessAndCount(entityName, content string) error {
+	return s.UpdateAccessAndCountContext(context.Background(), entityName, content)
+}
+
+// UpdateAccessAndCountContext is UpdateAccessAndCount with a context.
+func (s *Store) UpdateAccessAndCountContext(ctx context.Context, entityName, content string) error {
+	query := `
+		UPDATE observations
+		SET access_count = COALESCE(access_count, 0) + 1,
+		    last_accessed = CURRENT_TIMESTAMP,
+		    last_useful = CURRENT_TIMESTAMP
+		WHERE entity_id = (SELECT id FROM entities WHERE name = ? AND is_latest = 1)
+	`
+	args := []any{entityName}
+	if content != "" {
+		query += " AND content = ?"
+		args = append(args, content)
+	}
+
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		if content == "" {
+			return entityNotFound(entityName)
+		}
+		return observationNotFound(content)
+	}
+	return nil
+}
+
+// RecordEntityAccess counts a retrieval of the named entities, e.g. a search
+// hit or open_nodes lookup: it bumps access_count and refreshes last_accessed
+// on their unsuppressed observations.
+func (s *Store) RecordEntityAccess(names []string) error {
+	return s.RecordEntityAccessContext(context.Background(), names)
+}
+
+// RecordEntityAccessContext is RecordEntityAccess with a context.
+func (s *Store) RecordEntityAccessContext(ctx context.Context, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	query, args, err := sqlx.In(`
+		UPDATE observations
+		SET access_count = COALESCE(access_count, 0) + 1,
+		    last_accessed = CURRENT_TIMESTAMP
+		WHERE entity_id IN (SELECT id FROM entities WHERE name IN (?) AND is_latest = 1)
+		AND COALESCE(suppressed, 0) = 0
+	`, names)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, s.db.Rebind(query), args...)
+	return err
+}
+
+// RecordContextAccess counts observations selected for context injection.
+// Only access_count moves; last_accessed is left alone so injected memories
+// do not show up as recent work in GetRecentContext.
+func (s *Store) RecordContextAccess(results []ContextResult) error {
+	return s.RecordContextAccessContext(context.Background(), results)
+}
+
+// RecordContextAccessContext is RecordContextAccess with a context.
+func (s *Store) RecordContextAccessContext(ctx context.Context, results []ContextResult) error {
+	ids := make([]int64, 0, len(results))
+	for _, r := range results {
+		if r.ObservationID != 0 {
+			ids = append(ids, r.ObservationID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	query, args, err := sqlx.In(`
+		UPDATE observations
+		SET access_count = COALESCE(access_count, 0) + 1
+		WHERE id IN (?)
+	`, ids)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, s.db.Rebind(query), args...)
+	return err
+}
+
+// GetAccessCount returns how many times an observation was retrieved or marked useful.
+func (s *Store) GetAccessCount(entityName, content string) (int, error) {
+	return s.GetAccessCountContext(context.Background(), entityName, content)
+}
+
+// GetAccessCountContext is GetAccessCount with a context.
+func (s *Store) GetAccessCountContext(ctx context.Context, entityName, content string) (int, error) {
+	var count int
+	err := s.db.GetContext(ctx, &count, `
+		SELECT COALESCE(o.access_count, 0)
+		FROM observations o
+		JOIN entities e ON e.id = o.entity_id
+		WHERE e.name = ? AND e.is_latest = 1 AND o.content = ?
+	`, entityName, content)
+	if err == sql.ErrNoRows {
+		return 0, observationNotFound(content)
+	}
+	return count, err
+}
+
 // GetLastAccessed returns the last_accessed time for an entity's observations.
 func (s *Store) GetLastAccessed(entityName string) (time.Time, error) {
+	return s.GetLastAccessedContext(context.Background(), entityName)
+}
+
+// GetLastAccessedContext is GetLastAccessed with a context.
+func (s *Store) GetLastAccessedContext(ctx context.Context, entityName string) (time.Time, error) {
 	var accessedStr string
-	err := s.db.Get(&accessedStr, `
+	err := s.db.GetContext(ctx, &accessedStr, `
 		SELECT COALESCE(MAX(last_accessed), created_at) as last_accessed
 		FROM observations
 		WHERE entity_id = (SELECT id FROM entities WHERE name = ? AND is_latest = 1)
@@ -126,48 +348,55 @@ type ObservationImportance struct {
 // RecalculateImportance recalculates importance scores for all observations.
 // Returns the number of observations updated.
 func (s *Store) RecalculateImportance() (int, error) {
-	cfg := DefaultImportanceConfig()
+	return s.RecalculateImportanceContext(context.Background())
+}
 
-	// Get max relations for centrality calculation
-	var maxRelations int
-	err := s.db.Get(&maxRelations, `
-		SELECT COALESCE(MAX(rel_count), 0)
+// RecalculateImportanceContext is RecalculateImportance with a context.
+func (s *Store) RecalculateImportanceContext(ctx context.Context) (int, error) {
+	cfg := s.importance
+
+	// Get max relation weight for centrality calculation; a symmetric
+	// relation starts at both of its ends
+	weight, weightArgs := cfg.relationWeightSQL()
+	symmetric, symmetricArgs := s.symmetricTypesSQL()
+	var maxRelations float64
+	err := s.db.GetContext(ctx, &maxRelations, `
+		SELECT COALESCE(MAX(rel_weight), 0)
 		FROM (
-			SELECT COUNT(*) as rel_count
-			FROM relations
-			GROUP BY from_entity_id
+			SELECT SUM(weight) as rel_weight
+			FROM (
+				SELECT r.from_entity_id as entity_id, `+weight+` as weight FROM relations r
+				UNION ALL
+				SELECT r.to_entity_id, `+weight+` FROM relations r WHERE `+symmetric+` AND r.to_entity_id != r.from_entity_id
+			)
+			GROUP BY entity_id
 		)
-	`)
-	if err != nil {
+	`, slices.Concat(weightArgs, weightArgs, symmetricArgs)...)
+	if err != nil || maxRelations <= 0 {
 		maxRelations = 1 // Avoid division by zero
 	}
-	if maxRelations == 0 {
-		maxRelations = 1
-	}
 
 	// Get all observations with their metadata
-	rows, err := s.db.Query(`
-		SELECT o.id, o.importance, o.fact_type,
-		       COALESCE(julianday('now') - julianday(COALESCE(o.last_accessed, o.created_at)), 0) as days_since,
-		       (SELECT COUNT(*) FROM relations WHERE from_entity_id = o.entity_id OR to_entity_id = o.entity_id) as relation_count
-		FROM observations o
-		JOIN entities e ON e.id = o.entity_id
-		WHERE e.is_latest = 1
-	`)
+	rows, err := s.db.QueryContext(ctx, importanceInputsQuery(weight),
+		slices.Concat([]any{julianNow()}, weightArgs, weightArgs)...)
 	if err != nil {
 		return 0, err
 	}
-	defer rows.Close()
 
-	updated := 0
+	type change struct {
+		id         int64
+		importance float64
+	}
+	var changes []change
 	for rows.Next() {
 		var id int64
 		var baseImportance float64
 		var factType string
+		var accessCount int
 		var daysSince float64
-		var relationCount int
+		var relationWeight float64
 
-		if err := rows.Scan(&id, &baseImportance, &factType, &daysSince, &relationCount); err != nil {
+		if err := rows.Scan(&id, &baseImportance, &factType, &accessCount, &daysSince, &relationWeight); err != nil {
 			continue
 		}
 
@@ -177,34 +406,82 @@ func (s *Store) RecalculateImportance() (int, error) {
 			baseScore = math.Max(baseScore, 0.8) // Minimum 0.8 for static facts
 		}
 
-		// Calculate new importance (access count not tracked separately, use 0)
+		// Memories that are retrieved often or reported useful score higher on frequency
 		newImportance := CalculateImportance(
 			baseScore,
 			daysSince,
-			0, // Access count (could be added to schema if needed)
-			relationCount,
+			accessCount,
+			relationWeight,
 			maxRelations,
 			cfg,
 		)
 
 		// Update if changed significantly (avoid unnecessary writes)
 		if math.Abs(newImportance-baseImportance) > 0.01 {
-			_, err := s.db.Exec(
-				"UPDATE observations SET importance = ? WHERE id = ?",
-				newImportance, id,
-			)
-			if err == nil {
-				updated++
-			}
+			changes = append(changes, change{id, newImportance})
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(changes) == 0 {
+		return 0, nil
+	}
+
+	// One transaction for all updates rather than a commit per observation
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.PrepareContext(ctx, "UPDATE observations SET importance = ? WHERE id = ?")
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+	for _, c := range changes {
+		if _, err := stmt.ExecContext(ctx, c.importance, c.id); err != nil {
+			return 0, err
 		}
 	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(changes), nil
+}
 
-	return updated, nil
+// importanceInputsQuery selects what RecalculateImportance scores each
+// observation of a latest entity on, with weight the expression for a
+// relation's weight. Relation weights are summed once per entity rather than
+// per observation; a self-relation counts once.
+// Parameters: julianNow, then weight's arguments twice.
+func importanceInputsQuery(weight string) string {
+	return `
+	SELECT o.id, o.importance, o.fact_type, COALESCE(o.access_count, 0) as access_count,
+	       COALESCE(? - julianday(COALESCE(o.last_useful, o.last_accessed, o.created_at)), 0) as days_since,
+	       COALESCE(rc.relation_weight, 0) as relation_weight
+	FROM entities e
+	JOIN observations o ON o.entity_id = e.id
+	LEFT JOIN (
+		SELECT entity_id, SUM(weight) as relation_weight FROM (
+			SELECT r.from_entity_id as entity_id, ` + weight + ` as weight FROM relations r
+			UNION ALL
+			SELECT r.to_entity_id, ` + weight + ` FROM relations r WHERE r.to_entity_id != r.from_entity_id
+		) GROUP BY entity_id
+	) rc ON rc.entity_id = o.entity_id
+	WHERE e.is_latest = 1
+`
 }
 
 // SetObservationImportance sets the importance score for a specific observation.
 func (s *Store) SetObservationImportance(entityName, content string, importance float64) error {
-	_, err := s.db.Exec(`
+	return s.SetObservationImportanceContext(context.Background(), entityName, content, importance)
+}
+
+// SetObservationImportanceContext is SetObservationImportance with a context.
+func (s *Store) SetObservationImportanceContext(ctx context.Context, entityName, content string, importance float64) error {
+	_, err := s.db.ExecContext(ctx, `
 		UPDATE observations
 		SET importance = ?
 		WHERE entity_id = (SELECT id FROM entities WHERE name = ?)
@@ -215,8 +492,13 @@ func (s *Store) SetObservationImportance(entityName, content string, importance
 
 // GetObservationsByImportance returns observations with importance above the threshold.
 func (s *Store) GetObservationsByImportance(minImportance float64) ([]ObservationWithMeta, error) {
+	return s.GetObservationsByImportanceContext(context.Background(), minImportance)
+}
+
+// GetObservationsByImportanceContext is GetObservationsByImportance with a context.
+func (s *Store) GetObservationsByImportanceContext(ctx context.Context, minImportance float64) ([]ObservationWithMeta, error) {
 	var results []ObservationWithMeta
-	err := s.db.Select(&results, `
+	err := s.db.SelectContext(ctx, &results, `
 		SELECT e.name as entity_name, e.entity_type, o.content,
 		       COALESCE(o.fact_type, 'dynamic') as fact_type
 		FROM observations o
@@ -1,6 +1,7 @@
 package storage_test
 
 import (
+	"errors"
 	"testing"
 	"time"
 
@@ -117,8 +118,8 @@ func TestAccessFrequencyScore(t *testing.T) {
 func TestCentralityScore(t *testing.T) {
 	tests := []struct {
 		name          string
-		relationCount int
-		maxRelations  int
+		relationCount float64
+		maxRelations  float64
 		wantMin       float64
 		wantMax       float64
 	}{
@@ -171,8 +172,8 @@ func TestCalculateImportance(t *testing.T) {
 		baseScore     float64
 		daysSince     float64
 		accessCount   int
-		relationCount int
-		maxRelations  int
+		relationCount float64
+		maxRelations  float64
 		wantMin       float64
 		wantMax       float64
 	}{
@@ -284,6 +285,50 @@ func TestStore_RecalculateImportance(t *testing.T) {
 	}
 }
 
+func TestStore_RecalculateImportance_RelationWeights(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+
+	if err := store.Migrate(); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	// Hub has three mentions, Core one dependency
+	store.CreateEntity("Hub", "project", []string{"Mentioned a lot"})
+	store.CreateEntity("Core", "project", []string{"Depended on"})
+	for _, name := range []string{"a", "b", "c"} {
+		store.CreateEntity(name, "note", nil)
+		store.CreateRelation(name, "Hub", "mentions")
+	}
+	store.CreateEntity("app", "project", nil)
+	store.CreateRelation("app", "Core", "depends_on")
+
+	top := func() string {
+		t.Helper()
+		store.SetObservationImportance("Hub", "Mentioned a lot", 0.5)
+		store.SetObservationImportance("Core", "Depended on", 0.5)
+		if _, err := store.RecalculateImportance(); err != nil {
+			t.Fatalf("RecalculateImportance failed: %v", err)
+		}
+		observations, err := store.GetObservationsByImportance(0)
+		if err != nil || len(observations) != 2 {
+			t.Fatalf("GetObservationsByImportance = %+v, %v", observations, err)
+		}
+		return observations[0].EntityName
+	}
+
+	if got := top(); got != "Hub" {
+		t.Errorf("expected relation counts to favor Hub, got %s first", got)
+	}
+
+	cfg := storage.DefaultImportanceConfig()
+	cfg.RelationWeights = map[string]float64{"depends_on": 2, "mentions": 0.5}
+	store.SetImportanceConfig(cfg)
+	if got := top(); got != "Core" {
+		t.Errorf("expected relation weights to favor Core, got %s first", got)
+	}
+}
+
 func TestStore_GetObservationsByImportance(t *testing.T) {
 	store := newTestStore(t)
 	defer store.Close()
@@ -315,3 +360,172 @@ func TestStore_GetObservationsByImportance(t *testing.T) {
 		t.Errorf("expected 'Important fact', got %q", observations[0].Content)
 	}
 }
+
+func TestStore_UpdateAccessAndCount(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+
+	if err := store.Migrate(); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	store.CreateEntity("Useful", "pattern", []string{"Helpful fact", "Other fact"})
+
+	for i := 0; i < 3; i++ {
+		if err := store.UpdateAccessAndCount("Useful", "Helpful fact"); err != nil {
+			t.Fatalf("UpdateAccessAndCount failed: %v", err)
+		}
+	}
+	if err := store.UpdateAccessAndCount("Useful", ""); err != nil {
+		t.Fatalf("UpdateAccessAndCount (all) failed: %v", err)
+	}
+
+	count, err := store.GetAccessCount("Useful", "Helpful fact")
+	if err != nil {
+		t.Fatalf("GetAccessCount failed: %v", err)
+	}
+	if count != 4 {
+		t.Errorf("expected access count 4, got %d", count)
+	}
+
+	count, _ = store.GetAccessCount("Useful", "Other fact")
+	if count != 1 {
+		t.Errorf("expected access count 1, got %d", count)
+	}
+
+	if err := store.UpdateAccessAndCount("Missing", ""); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for missing entity, got %v", err)
+	}
+	if err := store.UpdateAccessAndCount("Useful", "No such fact"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for missing observation, got %v", err)
+	}
+}
+
+func TestStore_RecalculateImportance_UsesAccessCount(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+
+	if err := store.Migrate(); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	store.CreateEntity("Used", "pattern", []string{"Frequently used"})
+	store.CreateEntity("Unused", "pattern", []string{"Never used"})
+	store.SetObservationImportance("Used", "Frequently used", 0.5)
+	store.SetObservationImportance("Unused", "Never used", 0.5)
+
+	for i := 0; i < 20; i++ {
+		store.UpdateAccessAndCount("Used", "Frequently used")
+	}
+
+	if _, err := store.RecalculateImportance(); err != nil {
+		t.Fatalf("RecalculateImportance failed: %v", err)
+	}
+
+	observations, err := store.GetObservationsByImportance(0)
+	if err != nil {
+		t.Fatalf("GetObservationsByImportance failed: %v", err)
+	}
+	if len(observations) != 2 || observations[0].Content != "Frequently used" {
+		t.Errorf("expected used memory to rank first, got %+v", observations)
+	}
+}
+
+func TestStore_RecordEntityAccess(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+
+	if err := store.Migrate(); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	store.CreateEntity("Build", "project", []string{"Use make build", "Stale build step"})
+	store.CreateEntity("Other", "project", []string{"Untouched"})
+	store.SetObservationSuppressed("Build", "Stale build step", true)
+
+	for i := 0; i < 2; i++ {
+		if err := store.RecordEntityAccess([]string{"Build", "Missing"}); err != nil {
+			t.Fatalf("RecordEntityAccess failed: %v", err)
+		}
+	}
+
+	if count, _ := store.GetAccessCount("Build", "Use make build"); count != 2 {
+		t.Errorf("expected access count 2, got %d", count)
+	}
+	if count, _ := store.GetAccessCount("Build", "Stale build step"); count != 0 {
+		t.Errorf("suppressed observation should not count accesses, got %d", count)
+	}
+	if count, _ := store.GetAccessCount("Other", "Untouched"); count != 0 {
+		t.Errorf("expected untouched entity to stay at 0, got %d", count)
+	}
+
+	if err := store.RecordEntityAccess(nil); err != nil {
+		t.Errorf("RecordEntityAccess with no names failed: %v", err)
+	}
+}
+
+func TestStore_RecordContextAccess(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+
+	if err := store.Migrate(); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	store.CreateEntity("Rules", "convention", []string{"Never push to main"})
+	store.SetObservationImportance("Rules", "Never push to main", 0.9)
+
+	results, err := store.GetContextForInjection(storage.DefaultContextConfig(), "")
+	if err != nil {
+		t.Fatalf("GetContextForInjection failed: %v", err)
+	}
+	if err := store.RecordContextAccess(results); err != nil {
+		t.Fatalf("RecordContextAccess failed: %v", err)
+	}
+
+	if count, _ := store.GetAccessCount("Rules", "Never push to main"); count != 1 {
+		t.Errorf("expected access count 1, got %d", count)
+	}
+}
+
+func TestStore_SetImportanceConfig_FactTypeBoosts(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+
+	if err := store.Migrate(); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	store.CreateEntity("Convention", "pattern", nil)
+	store.CreateEntity("Decision", "pattern", nil)
+	store.AddObservationWithType("Convention", "Static fact", storage.FactTypeStatic)
+	store.AddObservationWithType("Decision", "Dynamic fact", storage.FactTypeDynamic)
+	store.SetObservationImportance("Convention", "Static fact", 0.5)
+	store.SetObservationImportance("Decision", "Dynamic fact", 0.5)
+
+	scores := func() map[string]float64 {
+		results, err := store.GetContextForInjection(storage.DefaultContextConfig(), "")
+		if err != nil {
+			t.Fatalf("GetContextForInjection failed: %v", err)
+		}
+		byType := make(map[string]float64)
+		for _, r := range results {
+			byType[r.FactType] = r.FinalScore
+		}
+		return byType
+	}
+
+	got := scores()
+	if got["static"] <= got["dynamic"] {
+		t.Errorf("expected static boost by default, got %v", got)
+	}
+
+	cfg := storage.DefaultImportanceConfig()
+	cfg.FactTypeBoosts = map[string]float64{"dynamic": 2.0}
+	store.SetImportanceConfig(cfg)
+
+	got = scores()
+	if got["dynamic"] <= got["static"] {
+		t.Errorf("expected configured dynamic boost, got %v", got)
+	}
+}
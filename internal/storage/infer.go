@@ -0,0 +1,233 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Relation types InferRelations proposes.
+const (
+	RelationMentions  = "mentions"   // An observation of From names To
+	RelationRelatedTo = "related_to" // Another entity's observations name both
+)
+
+// InferredRelation is a relation InferRelations proposes.
+type InferredRelation struct {
+	From     string
+	To       string
+	Type     string
+	Count    int    // Observations supporting it
+	Evidence string // The first of those observations
+}
+
+// InferOptions tunes InferRelations.
+type InferOptions struct {
+	// MinCoMentions is how many observations must name two entities together
+	// before they are proposed as related_to. Default 2.
+	MinCoMentions int
+}
+
+// minCaseInsensitiveName is the shortest name matched regardless of case;
+// shorter names ("Go", "UI") must match exactly, or they would match words.
+const minCaseInsensitiveName = 4
+
+// InferRelations scans observations for the names of other entities and
+// proposes relations the graph lacks: A mentions B when an observation of A
+// names B, and B related_to C when observations of other entities name B and
+// C together. Pairs already related in either direction are skipped, as are
+// session entities, whose worked_on links come from session capture.
+// Proposals are sorted by supporting observations, most first.
+func (s *Store) InferRelations(opts InferOptions) ([]InferredRelation, error) {
+	return s.InferRelationsContext(context.Background(), opts)
+}
+
+// InferRelationsContext is InferRelations with a context.
+func (s *Store) InferRelationsContext(ctx context.Context, opts InferOptions) ([]InferredRelation, error) {
+	if opts.MinCoMentions <= 0 {
+		opts.MinCoMentions = 2
+	}
+
+	var entities []struct {
+		ID   int64  `db:"id"`
+		Name string `db:"name"`
+		Type string `db:"entity_type"`
+	}
+	if err := s.db.SelectContext(ctx, &entities, `
+		SELECT id, name, entity_type FROM entities
+		WHERE is_latest = 1 OR is_latest IS NULL`); err != nil {
+		return nil, err
+	}
+	names := map[int64]string{}
+	index := newNameIndex()
+	for _, e := range entities {
+		names[e.ID] = e.Name
+		if e.Type != "session" {
+			index.add(e.ID, e.Name)
+		}
+	}
+
+	related := map[[2]int64]bool{}
+	var links []struct {
+		From int64 `db:"from_entity_id"`
+		To   int64 `db:"to_entity_id"`
+	}
+	if err := s.db.SelectContext(ctx, &links, "SELECT from_entity_id, to_entity_id FROM relations"); err != nil {
+		return nil, err
+	}
+	for _, l := range links {
+		related[pairKey(l.From, l.To)] = true
+	}
+
+	rows, err := s.db.QueryxContext(ctx, `
+		SELECT o.entity_id, o.content FROM observations o
+		JOIN entities e ON e.id = o.entity_id
+		WHERE (e.is_latest = 1 OR e.is_latest IS NULL) AND e.entity_type != 'session'
+		AND COALESCE(o.suppressed, 0) = 0
+		ORDER BY o.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type tally struct {
+		count    int
+		evidence string
+	}
+	mentions := map[[2]int64]*tally{} // Directed: owner, mentioned
+	coMentions := map[[2]int64]*tally{}
+	count := func(m map[[2]int64]*tally, key [2]int64, evidence string) {
+		if t, ok := m[key]; ok {
+			t.count++
+		} else {
+			m[key] = &tally{1, evidence}
+		}
+	}
+	for rows.Next() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var owner int64
+		var content string
+		if err := rows.Scan(&owner, &content); err != nil {
+			return nil, err
+		}
+		found := index.find(content, owner)
+		for i, id := range found {
+			count(mentions, [2]int64{owner, id}, content)
+			for _, other := range found[i+1:] {
+				count(coMentions, pairKey(id, other), content)
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var proposals []InferredRelation
+	for key, t := range mentions {
+		if related[pairKey(key[0], key[1])] {
+			continue
+		}
+		proposals = append(proposals, InferredRelation{names[key[0]], names[key[1]], RelationMentions, t.count, t.evidence})
+	}
+	for key, t := range coMentions {
+		_, mentioned := mentions[key]
+		_, mentionedBack := mentions[[2]int64{key[1], key[0]}]
+		if t.count < opts.MinCoMentions || related[key] || mentioned || mentionedBack {
+			continue
+		}
+		proposals = append(proposals, InferredRelation{names[key[0]], names[key[1]], RelationRelatedTo, t.count, t.evidence})
+	}
+	sort.Slice(proposals, func(i, j int) bool {
+		a, b := proposals[i], proposals[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		return a.To < b.To
+	})
+	return proposals, nil
+}
+
+func pairKey(a, b int64) [2]int64 {
+	if a > b {
+		a, b = b, a
+	}
+	return [2]int64{a, b}
+}
+
+// nameIndex finds entity names in text, keyed by each name's lowercased
+// first word so text is scanned once whatever the number of entities.
+type nameIndex struct {
+	byFirstWord map[string][]indexedName
+}
+
+type indexedName struct {
+	id   int64
+	name string
+}
+
+func newNameIndex() *nameIndex {
+	return &nameIndex{byFirstWord: map[string][]indexedName{}}
+}
+
+func (x *nameIndex) add(id int64, name string) {
+	word := strings.ToLower(firstWord(name))
+	if word == "" {
+		return
+	}
+	x.byFirstWord[word] = append(x.byFirstWord[word], indexedName{id, name})
+}
+
+// find returns the entities other than self named in text, in order of
+// first mention, preferring the longest name at each position.
+func (x *nameIndex) find(text string, self int64) []int64 {
+	var found []int64
+	seen := map[int64]bool{self: true}
+	for pos := 0; pos < len(text); {
+		r, size := utf8.DecodeRuneInString(text[pos:])
+		if !isWordRune(r) || !isWordBoundary(text, pos-1) {
+			pos += size
+			continue
+		}
+		word := firstWord(text[pos:])
+		best := indexedName{}
+		for _, cand := range x.byFirstWord[strings.ToLower(word)] {
+			end := pos + len(cand.name)
+			if end > len(text) || !isWordBoundary(text, end) || len(cand.name) <= len(best.name) {
+				continue
+			}
+			match := text[pos:end]
+			if match == cand.name || (utf8.RuneCountInString(cand.name) >= minCaseInsensitiveName && strings.EqualFold(match, cand.name)) {
+				best = cand
+			}
+		}
+		if best.name != "" {
+			if !seen[best.id] {
+				seen[best.id] = true
+				found = append(found, best.id)
+			}
+			pos += len(best.name)
+			continue
+		}
+		pos += len(word)
+	}
+	return found
+}
+
+func firstWord(s string) string {
+	end := strings.IndexFunc(s, func(r rune) bool { return !isWordRune(r) })
+	if end < 0 {
+		return s
+	}
+	return s[:end]
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
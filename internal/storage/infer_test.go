@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInferRelations(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	create := func(name, typ string, obs ...string) {
+		t.Helper()
+		if _, err := store.CreateEntity(name, typ, obs); err != nil {
+			t.Fatalf("CreateEntity(%s): %v", name, err)
+		}
+	}
+	create("Parser", "component", "Feeds tokens from the lexer into the AST builder")
+	create("Lexer", "component", "Emits tokens")
+	create("AST builder", "component")
+	create("Go", "language")
+	create("Compiler", "project",
+		"Pipeline runs Lexer then Parser",
+		"A bug in the lexer broke the parser",
+		"Written in Go; we go fast",
+		"Owned by the Compiler team")
+	create("session-1", "session", "Touched Parser and Lexer")
+	if err := store.CreateRelation("Compiler", "Go", "built_with"); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := store.InferRelations(InferOptions{})
+	if err != nil {
+		t.Fatalf("InferRelations: %v", err)
+	}
+	type rel struct {
+		From, To, Type string
+		Count          int
+	}
+	var simple []rel
+	for _, r := range got {
+		simple = append(simple, rel{r.From, r.To, r.Type, r.Count})
+	}
+	want := []rel{
+		{"Compiler", "Lexer", RelationMentions, 2},
+		{"Compiler", "Parser", RelationMentions, 2},
+		{"Parser", "AST builder", RelationMentions, 1},
+		{"Parser", "Lexer", RelationMentions, 1},
+	}
+	if !reflect.DeepEqual(simple, want) {
+		t.Errorf("InferRelations() =\n%+v\nwant\n%+v", simple, want)
+	}
+	if got[0].Evidence != "Pipeline runs Lexer then Parser" {
+		t.Errorf("expected the first observation as evidence, got %q", got[0].Evidence)
+	}
+}
+
+func TestInferRelations_RelatedTo(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	for _, name := range []string{"Cache", "Database", "Queue"} {
+		if _, err := store.CreateEntity(name, "component", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := store.CreateEntity("Design", "note", []string{
+		"Cache sits in front of the Database",
+		"Cache invalidation is driven by Database triggers",
+		"Queue is separate",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := store.InferRelations(InferOptions{MinCoMentions: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var related []InferredRelation
+	for _, r := range got {
+		if r.Type == RelationRelatedTo {
+			related = append(related, r)
+		}
+	}
+	if len(related) != 1 || related[0].From != "Cache" || related[0].To != "Database" || related[0].Count != 2 {
+		t.Errorf("expected Cache related_to Database, got %+v", related)
+	}
+
+	got, err = store.InferRelations(InferOptions{MinCoMentions: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range got {
+		if r.Type == RelationRelatedTo {
+			t.Errorf("expected no related_to below the threshold, got %+v", r)
+		}
+	}
+}
+
+func TestNameIndex_Find(t *testing.T) {
+	index := newNameIndex()
+	index.add(1, "Go")
+	index.add(2, "main.go")
+	index.add(3, "Auth Service")
+	index.add(4, "Auth")
+
+	tests := []struct {
+		text string
+		want []int64
+	}{
+		{"we go home", nil},
+		{"Go is compiled", []int64{1}},
+		{"edit main.go first", []int64{2}},
+		{"the auth service calls Auth", []int64{3, 4}},
+		{"Authentication is hard", nil},
+	}
+	for _, tt := range tests {
+		if got := index.find(tt.text, 0); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("find(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+	if got := index.find("Go and main.go", 1); !reflect.DeepEqual(got, []int64{2}) {
+		t.Errorf("expected self excluded, got %v", got)
+	}
+}
@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"slices"
+)
+
+// instructionRow is an observation ProjectInstructions may keep.
+type instructionRow struct {
+	ID         int64   `db:"id"`
+	EntityName string  `db:"entity_name"`
+	EntityType string  `db:"entity_type"`
+	Content    string  `db:"content"`
+	Importance float64 `db:"importance"`
+	Pinned     bool    `db:"pinned"`
+}
+
+// ProjectInstructions returns what a project's static instructions, like its
+// CLAUDE.md, should say: the static facts and the convention entities'
+// observations of the entities tagged with project, at minImportance or
+// pinned. Observations imported from the project's own files (sources
+// import:<...>) are left out, since the files already say them. With a
+// positive limit, the pinned and most important are kept.
+//
+// The entities are ordered conventions first, then by name, and observations
+// in the order they were added, so the result only changes when the memories
+// do, not when their scores shift.
+func (s *Store) ProjectInstructions(project string, minImportance float64, limit int) ([]*Entity, error) {
+	return s.ProjectInstructionsContext(context.Background(), project, minImportance, limit)
+}
+
+// ProjectInstructionsContext is ProjectInstructions with a context.
+func (s *Store) ProjectInstructionsContext(ctx context.Context, project string, minImportance float64, limit int) ([]*Entity, error) {
+	if project == "" {
+		return nil, &ValidationError{Field: "project", Reason: "must not be empty"}
+	}
+	var rows []instructionRow
+	if err := s.db.SelectContext(ctx, &rows, `
+		SELECT o.id, e.name AS entity_name, e.entity_type, o.content,
+		       COALESCE(o.importance, 1.0) AS importance, COALESCE(o.pinned, 0) AS pinned
+		FROM entities e JOIN observations o ON o.entity_id = e.id
+		WHERE e.is_latest = 1 AND e.container_tag = ?
+		AND (COALESCE(o.fact_type, 'dynamic') = 'static' OR e.entity_type = 'convention')
+		AND (COALESCE(o.importance, 1.0) >= ? OR o.pinned = 1)
+		AND COALESCE(o.suppressed, 0) = 0
+		AND COALESCE(o.source, '') NOT LIKE ?`,
+		project, minImportance, SourceImport+":%"); err != nil {
+		return nil, err
+	}
+
+	if limit > 0 && len(rows) > limit {
+		slices.SortStableFunc(rows, func(a, b instructionRow) int {
+			if a.Pinned != b.Pinned {
+				if a.Pinned {
+					return -1
+				}
+				return 1
+			}
+			return cmp.Or(cmp.Compare(b.Importance, a.Importance), cmp.Compare(a.ID, b.ID))
+		})
+		rows = rows[:limit]
+	}
+	slices.SortFunc(rows, func(a, b instructionRow) int {
+		aConv, bConv := a.EntityType == "convention", b.EntityType == "convention"
+		if aConv != bConv {
+			if aConv {
+				return -1
+			}
+			return 1
+		}
+		return cmp.Or(cmp.Compare(a.EntityName, b.EntityName), cmp.Compare(a.ID, b.ID))
+	})
+
+	var entities []*Entity
+	for _, r := range rows {
+		if len(entities) == 0 || entities[len(entities)-1].Name != r.EntityName {
+			entities = append(entities, &Entity{Name: r.EntityName, Type: r.EntityType})
+		}
+		last := entities[len(entities)-1]
+		last.Observations = append(last.Observations, r.Content)
+	}
+	return entities, nil
+}
@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestProjectInstructions(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	for _, e := range []struct{ name, typ, tag string }{
+		{"Go Errors", "convention", "api"},
+		{"API Server", "project", "api"},
+		{"Billing", "project", "billing"},
+		{"api/README.md", "document", "api"},
+	} {
+		if _, err := store.CreateEntityWithContainer(e.name, e.typ, nil, e.tag); err != nil {
+			t.Fatal(err)
+		}
+	}
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	must(store.AddObservation("Go Errors", "Wrap errors with %w"))
+	must(store.AddObservationWithType("API Server", "Listens on port 8080", FactTypeStatic))
+	must(store.AddObservationWithType("API Server", "Uses chi for routing", FactTypeStatic))
+	must(store.AddObservation("API Server", "Was slow on Tuesday"))
+	must(store.AddObservationWithType("Billing", "Bills monthly", FactTypeStatic))
+	must(store.AddObservationWithTypeContext(WithSource(context.Background(), SourceImport+":README.md"),
+		"api/README.md", "API: Serves the graph", FactTypeStatic))
+	if _, err := store.db.Exec("UPDATE observations SET importance = 0.2 WHERE content = 'Uses chi for routing'"); err != nil {
+		t.Fatal(err)
+	}
+
+	entities, err := store.ProjectInstructions("api", 0.7, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range entities {
+		for _, obs := range e.Observations {
+			got = append(got, e.Name+": "+obs)
+		}
+	}
+	want := []string{"Go Errors: Wrap errors with %w", "API Server: Listens on port 8080"}
+	if !slices.Equal(got, want) {
+		t.Errorf("ProjectInstructions = %v, want %v", got, want)
+	}
+
+	// Pinned memories count whatever their importance, and lead under a limit
+	must(store.SetObservationPinned("API Server", "Uses chi for routing", true))
+	entities, err = store.ProjectInstructions("api", 0.7, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entities) != 1 || !slices.Equal(entities[0].Observations, []string{"Uses chi for routing"}) {
+		t.Errorf("expected only the pinned observation, got %+v", entities)
+	}
+
+	if _, err := store.ProjectInstructions("", 0.7, 0); err == nil {
+		t.Error("expected a project required")
+	}
+}
@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+)
+
+// IntegrityReport is the result of CheckIntegrity.
+type IntegrityReport struct {
+	ForeignKeys bool           // Foreign key enforcement is on for this connection
+	Problems    []string       // PRAGMA quick_check findings; empty when the file is sound
+	Orphans     map[string]int // Rows whose referenced row is missing, by table
+	// Entity names differing only in case or Unicode normalization; reported
+	// but not counted against OK, since merging them is the user's call
+	NameCollisions [][]string
+	// Entities holding observations tagged as personal data; reported but
+	// not counted against OK
+	PIIExposure []PIIExposure
+}
+
+// OK reports whether no problems or orphans were found.
+func (r *IntegrityReport) OK() bool {
+	return r.ForeignKeys && len(r.Problems) == 0 && len(r.Orphans) == 0
+}
+
+// orphanRow is one row of PRAGMA foreign_key_check.
+type orphanRow struct {
+	Table  string `db:"table"`
+	RowID  int64  `db:"rowid"`
+	Parent string `db:"parent"`
+	FKID   int    `db:"fkid"`
+}
+
+func (s *Store) foreignKeyViolations(ctx context.Context) ([]orphanRow, error) {
+	var rows []orphanRow
+	err := s.db.SelectContext(ctx, &rows, "PRAGMA foreign_key_check")
+	return rows, err
+}
+
+// CheckIntegrity checks the database file and its referential integrity:
+// observations, embeddings, and relations whose entity or observation is
+// gone, left behind when deletes ran without foreign keys.
+func (s *Store) CheckIntegrity() (*IntegrityReport, error) {
+	return s.CheckIntegrityContext(context.Background())
+}
+
+// CheckIntegrityContext is CheckIntegrity with a context.
+func (s *Store) CheckIntegrityContext(ctx context.Context) (*IntegrityReport, error) {
+	report := &IntegrityReport{Orphans: map[string]int{}}
+
+	var fk int
+	if err := s.db.GetContext(ctx, &fk, "PRAGMA foreign_keys"); err != nil {
+		return nil, fmt.Errorf("reading foreign_keys: %w", err)
+	}
+	report.ForeignKeys = fk == 1
+
+	var problems []string
+	if err := s.db.SelectContext(ctx, &problems, "PRAGMA quick_check"); err != nil {
+		return nil, fmt.Errorf("quick_check: %w", err)
+	}
+	for _, p := range problems {
+		if p != "ok" {
+			report.Problems = append(report.Problems, p)
+		}
+	}
+
+	violations, err := s.foreignKeyViolations(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("foreign_key_check: %w", err)
+	}
+	for _, v := range violations {
+		report.Orphans[v.Table]++
+	}
+
+	if report.NameCollisions, err = s.NameCollisionsContext(ctx); err != nil {
+		return nil, fmt.Errorf("name collisions: %w", err)
+	}
+	if report.PIIExposure, err = s.GetPIIExposureContext(ctx); err != nil {
+		return nil, fmt.Errorf("pii exposure: %w", err)
+	}
+	return report, nil
+}
+
+// DeleteOrphans removes rows reported by CheckIntegrity as orphaned and
+// returns how many it fixed. Entities pointing at a deleted earlier version
+// are kept and lose the link instead.
+func (s *Store) DeleteOrphans() (int, error) {
+	return s.DeleteOrphansContext(context.Background())
+}
+
+// DeleteOrphansContext is DeleteOrphans with a context.
+func (s *Store) DeleteOrphansContext(ctx context.Context) (int, error) {
+	violations, err := s.foreignKeyViolations(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	fixed := 0
+	for _, v := range violations {
+		var query string
+		switch v.Table {
+		case "entities":
+			query = "UPDATE entities SET supersedes_id = NULL WHERE id = ?"
+		case "observations", "observation_embeddings", "relations":
+			query = "DELETE FROM " + v.Table + " WHERE rowid = ?"
+		default:
+			continue
+		}
+		result, err := tx.ExecContext(ctx, query, v.RowID)
+		if err != nil {
+			return 0, fmt.Errorf("fixing %s row %d: %w", v.Table, v.RowID, err)
+		}
+		if n, _ := result.RowsAffected(); n > 0 {
+			fixed++
+		}
+	}
+	return fixed, tx.Commit()
+}
@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"context"
+	"testing"
+)
+
+// withoutForeignKeys runs fn on a connection with foreign keys off, the way
+// writes behaved on connections the pragma never reached.
+func withoutForeignKeys(t *testing.T, s *Store, fn func(exec func(query string, args ...any))) {
+	t.Helper()
+	ctx := context.Background()
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("Conn failed: %v", err)
+	}
+	defer conn.Close()
+	exec := func(query string, args ...any) {
+		t.Helper()
+		if _, err := conn.ExecContext(ctx, query, args...); err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+	}
+	exec("PRAGMA foreign_keys=OFF")
+	defer exec("PRAGMA foreign_keys=ON")
+	fn(exec)
+}
+
+func TestForeignKeysOnEveryConnection(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	ctx := context.Background()
+	for i := range 3 {
+		// Hold each connection so the next one is new
+		conn, err := store.db.Conn(ctx)
+		if err != nil {
+			t.Fatalf("Conn failed: %v", err)
+		}
+		defer conn.Close()
+		var fk int
+		if err := conn.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&fk); err != nil {
+			t.Fatalf("PRAGMA foreign_keys failed: %v", err)
+		}
+		if fk != 1 {
+			t.Errorf("connection %d: foreign_keys = %d, want 1", i, fk)
+		}
+	}
+}
+
+func TestDeleteEntity_CascadesToEmbeddings(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	entity, _ := store.CreateEntity("Go", "language", []string{"Fast compiler", "Has generics"})
+	store.CreateEntity("Rust", "language", []string{"Borrow checker"})
+	store.CreateRelation("Go", "Rust", "compared_to")
+	for _, content := range []string{"Fast compiler", "Has generics"} {
+		id, _ := store.getObservationID(context.Background(), entity.ID, content)
+		store.StoreEmbedding(id, []float64{1, 0}, "test-model")
+	}
+
+	// Delete through a second connection while another is in use
+	ctx := context.Background()
+	busy, err := store.db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("Conn failed: %v", err)
+	}
+	defer busy.Close()
+	if err := store.DeleteEntity("Go"); err != nil {
+		t.Fatalf("DeleteEntity failed: %v", err)
+	}
+
+	var embeddings, relations int
+	store.db.Get(&embeddings, "SELECT COUNT(*) FROM observation_embeddings")
+	store.db.Get(&relations, "SELECT COUNT(*) FROM relations")
+	if embeddings != 0 || relations != 0 {
+		t.Errorf("expected cascade to embeddings and relations, %d embeddings and %d relations left", embeddings, relations)
+	}
+	if n := store.CountObservations(); n != 1 {
+		t.Errorf("expected only Rust's observation left, got %d", n)
+	}
+}
+
+func TestCheckIntegrity_FindsAndDeletesOrphans(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	entity, _ := store.CreateEntity("Go", "language", []string{"Fast compiler"})
+	store.CreateEntity("Rust", "language", nil)
+	store.CreateRelation("Go", "Rust", "compared_to")
+	id, _ := store.getObservationID(context.Background(), entity.ID, "Fast compiler")
+	store.StoreEmbedding(id, []float64{1, 0}, "test-model")
+
+	report, err := store.CheckIntegrity()
+	if err != nil {
+		t.Fatalf("CheckIntegrity failed: %v", err)
+	}
+	if !report.OK() {
+		t.Fatalf("expected a clean database, got %+v", report)
+	}
+
+	withoutForeignKeys(t, store, func(exec func(string, ...any)) {
+		exec("DELETE FROM entities WHERE name = 'Go'")
+	})
+
+	report, err = store.CheckIntegrity()
+	if err != nil {
+		t.Fatalf("CheckIntegrity failed: %v", err)
+	}
+	if report.OK() || report.Orphans["observations"] != 1 || report.Orphans["relations"] != 1 {
+		t.Fatalf("expected orphaned observation and relation, got %+v", report.Orphans)
+	}
+
+	fixed, err := store.DeleteOrphans()
+	if err != nil {
+		t.Fatalf("DeleteOrphans failed: %v", err)
+	}
+	if fixed != 2 {
+		t.Errorf("expected 2 rows fixed, got %d", fixed)
+	}
+	report, _ = store.CheckIntegrity()
+	if !report.OK() {
+		t.Errorf("expected a clean database after fixing, got %+v", report.Orphans)
+	}
+	var embeddings int
+	store.db.Get(&embeddings, "SELECT COUNT(*) FROM observation_embeddings")
+	if embeddings != 0 {
+		t.Errorf("expected the orphan's embedding deleted with it, %d left", embeddings)
+	}
+}
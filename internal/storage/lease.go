@@ -0,0 +1,173 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// leasePollInterval is how often WaitWriteLease tries to take the lease.
+const leasePollInterval = 250 * time.Millisecond
+
+// WriteLease is the database's advisory write lease. Maintenance commands
+// take it so they don't contend with an active MCP server, which takes it
+// while handling tool calls. Writes don't check it: it only coordinates
+// processes that ask for it.
+type WriteLease struct {
+	Holder     string    `json:"holder"` // What holds it, e.g. "mcp server" or a command
+	PID        int       `json:"pid"`
+	AcquiredAt time.Time `json:"acquiredAt"`
+	ExpiresAt  time.Time `json:"expiresAt"` // Unless renewed first
+}
+
+// LeaseHeldError reports the write lease another process holds.
+type LeaseHeldError struct {
+	Lease WriteLease
+}
+
+func (e *LeaseHeldError) Error() string {
+	return fmt.Sprintf("%s (pid %d) holds the write lease until %s",
+		e.Lease.Holder, e.Lease.PID, e.Lease.ExpiresAt.Local().Format(time.TimeOnly))
+}
+
+func (e *LeaseHeldError) Unwrap() error {
+	return ErrLeaseHeld
+}
+
+// GetWriteLease returns the write lease in force, or nil when none is.
+func (s *Store) GetWriteLease() (*WriteLease, error) {
+	return s.GetWriteLeaseContext(context.Background())
+}
+
+// GetWriteLeaseContext is GetWriteLease with a context.
+func (s *Store) GetWriteLeaseContext(ctx context.Context) (*WriteLease, error) {
+	var row struct {
+		Holder     string `db:"holder"`
+		PID        int    `db:"pid"`
+		AcquiredAt int64  `db:"acquired_at"`
+		ExpiresAt  int64  `db:"expires_at"`
+	}
+	err := s.db.GetContext(ctx, &row,
+		"SELECT holder, pid, acquired_at, expires_at FROM write_lease WHERE id = 1 AND expires_at > ?", time.Now().UnixMilli())
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &WriteLease{
+		Holder:     row.Holder,
+		PID:        row.PID,
+		AcquiredAt: time.UnixMilli(row.AcquiredAt),
+		ExpiresAt:  time.UnixMilli(row.ExpiresAt),
+	}, nil
+}
+
+// AcquireWriteLease takes the write lease for holder in this process until
+// ttl from now, or extends it when they hold it already. While another
+// holds it, it fails with a LeaseHeldError.
+func (s *Store) AcquireWriteLease(holder string, ttl time.Duration) (*WriteLease, error) {
+	return s.AcquireWriteLeaseContext(context.Background(), holder, ttl)
+}
+
+// AcquireWriteLeaseContext is AcquireWriteLease with a context.
+func (s *Store) AcquireWriteLeaseContext(ctx context.Context, holder string, ttl time.Duration) (*WriteLease, error) {
+	if holder == "" {
+		return nil, &ValidationError{Field: "holder", Reason: "must not be empty"}
+	}
+	if ttl <= 0 {
+		return nil, &ValidationError{Field: "ttl", Reason: "must be positive"}
+	}
+	// The lease may be released between a failed take and the look at who
+	// holds it; taking it again settles that
+	for range 3 {
+		now := time.Now()
+		res, err := s.db.ExecContext(ctx, `
+			INSERT INTO write_lease (id, holder, pid, acquired_at, expires_at) VALUES (1, ?, ?, ?, ?)
+			ON CONFLICT(id) DO UPDATE SET
+				acquired_at = CASE WHEN holder = excluded.holder AND pid = excluded.pid
+					THEN acquired_at ELSE excluded.acquired_at END,
+				holder = excluded.holder,
+				pid = excluded.pid,
+				expires_at = excluded.expires_at
+			WHERE (holder = excluded.holder AND pid = excluded.pid) OR expires_at <= excluded.acquired_at
+		`, holder, os.Getpid(), now.UnixMilli(), now.Add(ttl).UnixMilli())
+		if err != nil {
+			return nil, err
+		}
+		taken, err := res.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		lease, err := s.GetWriteLeaseContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if taken > 0 {
+			return lease, nil
+		}
+		if lease != nil {
+			return nil, &LeaseHeldError{Lease: *lease}
+		}
+	}
+	return nil, fmt.Errorf("write lease changed hands while taking it")
+}
+
+// WaitWriteLease takes the write lease like AcquireWriteLease, retrying
+// while another holds it for up to wait. It then fails with the
+// LeaseHeldError of the last try.
+func (s *Store) WaitWriteLease(ctx context.Context, holder string, ttl, wait time.Duration) (*WriteLease, error) {
+	deadline := time.Now().Add(wait)
+	for {
+		lease, err := s.AcquireWriteLeaseContext(ctx, holder, ttl)
+		if !errors.Is(err, ErrLeaseHeld) || time.Now().Add(leasePollInterval).After(deadline) {
+			return lease, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(leasePollInterval):
+		}
+	}
+}
+
+// KeepWriteLease renews holder's write lease every third of ttl, so it
+// lasts as long as the work it guards and no more than ttl past a crash.
+// The returned function stops renewing and releases it.
+func (s *Store) KeepWriteLease(ctx context.Context, holder string, ttl time.Duration) (release func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				_, _ = s.AcquireWriteLeaseContext(ctx, holder, ttl)
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+		_ = s.ReleaseWriteLeaseContext(context.WithoutCancel(ctx), holder)
+	}
+}
+
+// ReleaseWriteLease gives up holder's write lease in this process, if they
+// hold it.
+func (s *Store) ReleaseWriteLease(holder string) error {
+	return s.ReleaseWriteLeaseContext(context.Background(), holder)
+}
+
+// ReleaseWriteLeaseContext is ReleaseWriteLease with a context.
+func (s *Store) ReleaseWriteLeaseContext(ctx context.Context, holder string) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM write_lease WHERE id = 1 AND holder = ? AND pid = ?", holder, os.Getpid())
+	return err
+}
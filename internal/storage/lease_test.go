@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWriteLease(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	if lease, err := store.GetWriteLease(); err != nil || lease != nil {
+		t.Fatalf("expected no lease, got %+v, %v", lease, err)
+	}
+
+	lease, err := store.AcquireWriteLease("mcp server", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lease.Holder != "mcp server" || time.Until(lease.ExpiresAt) < 50*time.Second {
+		t.Errorf("unexpected lease: %+v", lease)
+	}
+
+	// The holder extends it; the start stays
+	again, err := store.AcquireWriteLease("mcp server", 2*time.Minute)
+	if err != nil || !again.AcquiredAt.Equal(lease.AcquiredAt) || !again.ExpiresAt.After(lease.ExpiresAt) {
+		t.Errorf("expected the lease extended, got %+v, %v", again, err)
+	}
+
+	_, err = store.AcquireWriteLease("mark42 decay apply", time.Minute)
+	var held *LeaseHeldError
+	if !errors.Is(err, ErrLeaseHeld) || !errors.As(err, &held) || held.Lease.Holder != "mcp server" {
+		t.Fatalf("expected the lease held by the server, got %v", err)
+	}
+	if _, err := store.WaitWriteLease(context.Background(), "mark42 decay apply", time.Minute, 300*time.Millisecond); !errors.Is(err, ErrLeaseHeld) {
+		t.Errorf("expected waiting to time out, got %v", err)
+	}
+
+	// Another holder can't release it; once its holder does, it is free
+	if err := store.ReleaseWriteLease("mark42 decay apply"); err != nil {
+		t.Fatal(err)
+	}
+	if lease, _ := store.GetWriteLease(); lease == nil {
+		t.Fatal("expected the lease kept")
+	}
+	if err := store.ReleaseWriteLease("mcp server"); err != nil {
+		t.Fatal(err)
+	}
+
+	// An expired lease is free to take, so a crashed holder doesn't block
+	if _, err := store.AcquireWriteLease("mcp server", 100*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	lease, err = store.WaitWriteLease(context.Background(), "mark42 decay apply", time.Minute, 2*time.Second)
+	if err != nil || lease.Holder != "mark42 decay apply" {
+		t.Fatalf("expected the expired lease taken, got %+v, %v", lease, err)
+	}
+
+	release := store.KeepWriteLease(context.Background(), "mark42 decay apply", time.Minute)
+	release()
+	if lease, _ := store.GetWriteLease(); lease != nil {
+		t.Errorf("expected the lease released, got %+v", lease)
+	}
+}
@@ -0,0 +1,408 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// EntityMerge describes a merge of one entity into another.
+type EntityMerge struct {
+	ID     int64  `json:"id"` // 0 for a preview
+	Keep   string `json:"keep"`
+	Merged string `json:"merged"`
+	// Observations moved to Keep, and those dropped because Keep had them
+	ObservationsMoved   int `json:"observationsMoved"`
+	ObservationsDropped int `json:"observationsDropped"`
+	// Relations moved to Keep, and those dropped because Keep had them or
+	// they linked the two entities
+	RelationsMoved   int `json:"relationsMoved"`
+	RelationsDropped int `json:"relationsDropped"`
+	AttributesCopied int `json:"attributesCopied"` // Attributes Keep lacked
+}
+
+// mergeSnapshot is what UndoMerge needs to restore a merged entity: its rows
+// as they were, every version included, its aliases, and the attributes the
+// merge copied onto the kept entity.
+type mergeSnapshot struct {
+	KeepID           int64             `json:"keepId"`
+	Entities         []map[string]any  `json:"entities"`
+	Observations     []map[string]any  `json:"observations"`
+	Relations        []map[string]any  `json:"relations"`
+	Attributes       []map[string]any  `json:"attributes"`
+	Aliases          []map[string]any  `json:"aliases,omitempty"`
+	CopiedAttributes map[string]string `json:"copiedAttributes,omitempty"`
+}
+
+// MergeEntities merges the entity named merge into keep and deletes it.
+// keep gains merge's observations, relations, and the attributes it lacks;
+// what keep already has is dropped, as are relations between the two. Every
+// version of merge is deleted, and its name and aliases become aliases of
+// keep. The merge is recorded so UndoMerge can reverse it.
+func (s *Store) MergeEntities(keep, merge string) (*EntityMerge, error) {
+	return s.MergeEntitiesContext(context.Background(), keep, merge)
+}
+
+// MergeEntitiesContext is MergeEntities with a context.
+func (s *Store) MergeEntitiesContext(ctx context.Context, keep, merge string) (*EntityMerge, error) {
+	return s.mergeEntities(ctx, keep, merge, true)
+}
+
+// PreviewMerge returns what MergeEntities would do without changing anything.
+func (s *Store) PreviewMerge(keep, merge string) (*EntityMerge, error) {
+	return s.PreviewMergeContext(context.Background(), keep, merge)
+}
+
+// PreviewMergeContext is PreviewMerge with a context.
+func (s *Store) PreviewMergeContext(ctx context.Context, keep, merge string) (*EntityMerge, error) {
+	return s.mergeEntities(ctx, keep, merge, false)
+}
+
+// mergeEntities merges in a transaction it commits only when apply is set.
+func (s *Store) mergeEntities(ctx context.Context, keep, merge string, apply bool) (*EntityMerge, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	keepID, err := s.entityID(ctx, tx, keep)
+	if err != nil {
+		return nil, err
+	}
+	mergeID, err := s.entityID(ctx, tx, merge)
+	if err != nil {
+		return nil, err
+	}
+	if keepID == mergeID {
+		return nil, &ValidationError{Field: "merge", Reason: "cannot merge an entity into itself"}
+	}
+	result := &EntityMerge{}
+	if err := tx.GetContext(ctx, &result.Keep, "SELECT name FROM entities WHERE id = ?", keepID); err != nil {
+		return nil, err
+	}
+	if err := tx.GetContext(ctx, &result.Merged, "SELECT name FROM entities WHERE id = ?", mergeID); err != nil {
+		return nil, err
+	}
+
+	snapshot, err := snapshotEntity(ctx, tx, result.Merged)
+	if err != nil {
+		return nil, err
+	}
+	snapshot.KeepID = keepID
+
+	moved, err := tx.ExecContext(ctx, `
+		UPDATE observations SET entity_id = ?
+		WHERE entity_id = ? AND content NOT IN (SELECT content FROM observations WHERE entity_id = ?)`,
+		keepID, mergeID, keepID)
+	if err != nil {
+		return nil, err
+	}
+	result.ObservationsMoved = rowsAffected(moved)
+	if err := tx.GetContext(ctx, &result.ObservationsDropped,
+		"SELECT COUNT(*) FROM observations WHERE entity_id = ?", mergeID); err != nil {
+		return nil, err
+	}
+
+	// OR IGNORE leaves relations keep already has on merge, to be dropped with it
+	for _, update := range []string{
+		"UPDATE OR IGNORE relations SET from_entity_id = ? WHERE from_entity_id = ? AND to_entity_id != ?",
+		"UPDATE OR IGNORE relations SET to_entity_id = ? WHERE to_entity_id = ? AND from_entity_id != ?",
+	} {
+		res, err := tx.ExecContext(ctx, update, keepID, mergeID, keepID)
+		if err != nil {
+			return nil, err
+		}
+		result.RelationsMoved += rowsAffected(res)
+	}
+	if err := tx.GetContext(ctx, &result.RelationsDropped,
+		"SELECT COUNT(*) FROM relations WHERE from_entity_id = ? OR to_entity_id = ?", mergeID, mergeID); err != nil {
+		return nil, err
+	}
+
+	var copied []struct {
+		Key   string `db:"key"`
+		Value string `db:"value"`
+	}
+	if err := tx.SelectContext(ctx, &copied, `
+		SELECT key, value FROM entity_attributes
+		WHERE entity_id = ? AND key NOT IN (SELECT key FROM entity_attributes WHERE entity_id = ?)`,
+		mergeID, keepID); err != nil {
+		return nil, err
+	}
+	if len(copied) > 0 {
+		snapshot.CopiedAttributes = make(map[string]string, len(copied))
+	}
+	for _, a := range copied {
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO entity_attributes (entity_id, key, value) VALUES (?, ?, ?)", keepID, a.Key, a.Value); err != nil {
+			return nil, err
+		}
+		snapshot.CopiedAttributes[a.Key] = a.Value
+	}
+	result.AttributesCopied = len(copied)
+
+	if !apply {
+		return result, nil
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM entities WHERE name = ?", result.Merged); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE entity_aliases SET entity_name = ? WHERE entity_name = ?",
+		result.Keep, result.Merged); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO entity_aliases (alias, entity_name) VALUES (?, ?)",
+		result.Merged, result.Keep); err != nil {
+		return nil, err
+	}
+	encoded, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	res, err := tx.ExecContext(ctx, "INSERT INTO entity_merges (keep, merged, snapshot) VALUES (?, ?, ?)",
+		result.Keep, result.Merged, string(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("recording merge: %w", err)
+	}
+	if result.ID, err = res.LastInsertId(); err != nil {
+		return nil, err
+	}
+	return result, tx.Commit()
+}
+
+// UndoMerge restores the entity a merge deleted: its versions, observations,
+// relations, attributes, and aliases, taking back what moved to the kept entity and
+// the attributes copied onto it. An id of 0 undoes the latest merge not yet
+// undone. Embeddings of observations the merge dropped are not restored.
+func (s *Store) UndoMerge(id int64) (*EntityMerge, error) {
+	return s.UndoMergeContext(context.Background(), id)
+}
+
+// UndoMergeContext is UndoMerge with a context.
+func (s *Store) UndoMergeContext(ctx context.Context, id int64) (*EntityMerge, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var record struct {
+		ID       int64      `db:"id"`
+		Keep     string     `db:"keep"`
+		Merged   string     `db:"merged"`
+		Snapshot string     `db:"snapshot"`
+		Undone   *time.Time `db:"undone_at"`
+	}
+	query, args := "SELECT id, keep, merged, snapshot, undone_at FROM entity_merges WHERE id = ?", []any{id}
+	if id == 0 {
+		query, args = "SELECT id, keep, merged, snapshot, undone_at FROM entity_merges WHERE undone_at IS NULL ORDER BY id DESC LIMIT 1", nil
+	}
+	if err := tx.GetContext(ctx, &record, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			if id == 0 {
+				return nil, &NotFoundError{"merge", "latest"}
+			}
+			return nil, &NotFoundError{"merge", fmt.Sprintf("#%d", id)}
+		}
+		return nil, err
+	}
+	if record.Undone != nil {
+		return nil, &ValidationError{Field: "merge", Reason: fmt.Sprintf("merge #%d was already undone", record.ID)}
+	}
+	if _, err := s.entityID(ctx, tx, record.Merged); err == nil {
+		return nil, &ValidationError{Field: "merge", Reason: fmt.Sprintf("an entity named %q exists again", record.Merged)}
+	} else if !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+
+	var snapshot mergeSnapshot
+	dec := json.NewDecoder(strings.NewReader(record.Snapshot))
+	dec.UseNumber()
+	if err := dec.Decode(&snapshot); err != nil {
+		return nil, fmt.Errorf("reading merge #%d: %w", record.ID, err)
+	}
+
+	var uids []any
+	for _, row := range snapshot.Entities {
+		if err := insertSnapshotRow(ctx, tx, "entities", row, false); err != nil {
+			return nil, err
+		}
+		uids = append(uids, row["uid"])
+	}
+	// Moved rows still exist under their IDs; dropped ones are inserted again
+	for _, row := range snapshot.Observations {
+		res, err := tx.ExecContext(ctx, "UPDATE OR IGNORE observations SET entity_id = ? WHERE id = ?",
+			snapshotValue(row["entity_id"]), snapshotValue(row["id"]))
+		if err != nil {
+			return nil, err
+		}
+		if rowsAffected(res) == 0 {
+			if err := insertSnapshotRow(ctx, tx, "observations", row, true); err != nil {
+				return nil, err
+			}
+		}
+		uids = append(uids, row["uid"])
+	}
+	for _, row := range snapshot.Relations {
+		res, err := tx.ExecContext(ctx, "UPDATE OR IGNORE relations SET from_entity_id = ?, to_entity_id = ? WHERE id = ?",
+			snapshotValue(row["from_entity_id"]), snapshotValue(row["to_entity_id"]), snapshotValue(row["id"]))
+		if err != nil {
+			return nil, err
+		}
+		if rowsAffected(res) == 0 {
+			if err := insertSnapshotRow(ctx, tx, "relations", row, true); err != nil {
+				return nil, err
+			}
+		}
+		uids = append(uids, row["uid"])
+	}
+	for _, row := range snapshot.Attributes {
+		if err := insertSnapshotRow(ctx, tx, "entity_attributes", row, true); err != nil {
+			return nil, err
+		}
+	}
+	// The merged name stops being an alias; the merged entity's aliases go back
+	if _, err := tx.ExecContext(ctx, "DELETE FROM entity_aliases WHERE alias = ? AND entity_name = ?",
+		record.Merged, record.Keep); err != nil {
+		return nil, err
+	}
+	for _, row := range snapshot.Aliases {
+		if _, err := tx.ExecContext(ctx, "UPDATE entity_aliases SET entity_name = ? WHERE alias = ? AND entity_name = ?",
+			record.Merged, row["alias"], record.Keep); err != nil {
+			return nil, err
+		}
+	}
+	for key, value := range snapshot.CopiedAttributes {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM entity_attributes WHERE entity_id = ? AND key = ? AND value = ?",
+			snapshot.KeepID, key, value); err != nil {
+			return nil, err
+		}
+	}
+
+	// Deleting the merged rows left tombstones that would delete them on sync
+	for chunk := range slices.Chunk(uids, 500) {
+		query, args, err := sqlx.In("DELETE FROM tombstones WHERE uid IN (?)", chunk)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tx.ExecContext(ctx, tx.Rebind(query), args...); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE entity_merges SET undone_at = ? WHERE id = ?",
+		sqlTime(time.Now()), record.ID); err != nil {
+		return nil, err
+	}
+	return &EntityMerge{ID: record.ID, Keep: record.Keep, Merged: record.Merged}, tx.Commit()
+}
+
+// snapshotEntity reads every row belonging to the entities named name.
+func snapshotEntity(ctx context.Context, tx *sqlx.Tx, name string) (*mergeSnapshot, error) {
+	snapshot := &mergeSnapshot{}
+	var err error
+	if snapshot.Entities, err = snapshotRows(ctx, tx, "SELECT * FROM entities WHERE name = ? ORDER BY id", name); err != nil {
+		return nil, err
+	}
+	for _, q := range []struct {
+		dst   *[]map[string]any
+		query string
+		args  int
+	}{
+		{&snapshot.Observations, "SELECT * FROM observations WHERE entity_id IN (SELECT id FROM entities WHERE name = ?) ORDER BY id", 1},
+		{&snapshot.Relations, `SELECT * FROM relations WHERE from_entity_id IN (SELECT id FROM entities WHERE name = ?)
+			OR to_entity_id IN (SELECT id FROM entities WHERE name = ?) ORDER BY id`, 2},
+		{&snapshot.Attributes, "SELECT * FROM entity_attributes WHERE entity_id IN (SELECT id FROM entities WHERE name = ?)", 1},
+		{&snapshot.Aliases, "SELECT * FROM entity_aliases WHERE entity_name = ?", 1},
+	} {
+		args := make([]any, q.args)
+		for i := range args {
+			args[i] = name
+		}
+		if *q.dst, err = snapshotRows(ctx, tx, q.query, args...); err != nil {
+			return nil, err
+		}
+	}
+	return snapshot, nil
+}
+
+// snapshotRows reads rows as column maps that survive a JSON round trip:
+// times become text in the format SQLite stores.
+func snapshotRows(ctx context.Context, tx *sqlx.Tx, query string, args ...any) ([]map[string]any, error) {
+	rows, err := tx.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []map[string]any
+	for rows.Next() {
+		row := map[string]any{}
+		if err := rows.MapScan(row); err != nil {
+			return nil, err
+		}
+		for column, v := range row {
+			switch v := v.(type) {
+			case time.Time:
+				row[column] = sqlTime(v)
+			case []byte:
+				row[column] = string(v)
+			}
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+// insertSnapshotRow inserts a row read by snapshotRows. With ignore, a row
+// that conflicts with an existing one is skipped.
+func insertSnapshotRow(ctx context.Context, tx *sqlx.Tx, table string, row map[string]any, ignore bool) error {
+	columns := make([]string, 0, len(row))
+	for column := range row {
+		columns = append(columns, column)
+	}
+	slices.Sort(columns)
+	values := make([]any, len(columns))
+	for i, column := range columns {
+		values[i] = snapshotValue(row[column])
+	}
+
+	verb := "INSERT"
+	if ignore {
+		verb = "INSERT OR IGNORE"
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+	_, err := tx.ExecContext(ctx, verb+" INTO "+table+" ("+strings.Join(columns, ", ")+") VALUES ("+placeholders+")", values...)
+	if err != nil {
+		return fmt.Errorf("restoring %s: %w", table, err)
+	}
+	return nil
+}
+
+// snapshotValue turns a value decoded from a snapshot back into one SQLite
+// stores as it was: JSON numbers become integers where they are whole.
+func snapshotValue(v any) any {
+	n, ok := v.(json.Number)
+	if !ok {
+		return v
+	}
+	if i, err := n.Int64(); err == nil {
+		return i
+	}
+	f, _ := n.Float64()
+	return f
+}
+
+// rowsAffected is the row count of an Exec; SQLite always reports it.
+func rowsAffected(res sql.Result) int {
+	n, _ := res.RowsAffected()
+	return int(n)
+}
@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestMergeEntities(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	store.CreateEntity("mark42", "project", []string{"Memory for coding agents", "Uses SQLite"})
+	store.CreateEntity("Mark-42", "project", []string{"Uses SQLite", "Written in Go"})
+	store.CreateEntity("Go", "language", nil)
+	store.CreateEntity("konfig", "project", nil)
+	store.CreateRelation("Mark-42", "Go", "written_in")
+	store.CreateRelation("konfig", "Mark-42", "uses")
+	store.CreateRelation("mark42", "Go", "written_in")   // Duplicate once merged
+	store.CreateRelation("Mark-42", "mark42", "same_as") // Between the two
+	store.SetAttributes("Mark-42", map[string]string{"language": "Go", "version": "1.4.0"})
+	store.SetAttributes("mark42", map[string]string{"version": "1.5.0"})
+
+	preview, err := store.PreviewMerge("mark42", "Mark-42")
+	if err != nil {
+		t.Fatalf("PreviewMerge: %v", err)
+	}
+	want := EntityMerge{Keep: "mark42", Merged: "Mark-42", ObservationsMoved: 1, ObservationsDropped: 1,
+		RelationsMoved: 1, RelationsDropped: 2, AttributesCopied: 1}
+	if *preview != want {
+		t.Errorf("PreviewMerge = %+v, want %+v", *preview, want)
+	}
+	if _, err := store.GetEntity("Mark-42"); err != nil {
+		t.Errorf("expected the preview to change nothing: %v", err)
+	}
+
+	merge, err := store.MergeEntities("mark42", "Mark-42")
+	if err != nil {
+		t.Fatalf("MergeEntities: %v", err)
+	}
+	want.ID = merge.ID
+	if merge.ID == 0 || *merge != want {
+		t.Errorf("MergeEntities = %+v, want %+v", *merge, want)
+	}
+	if _, err := store.GetEntity("Mark-42"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected Mark-42 deleted, got %v", err)
+	}
+	kept, _ := store.GetEntity("mark42")
+	if !slices.Contains(kept.Observations, "Written in Go") || len(kept.Observations) != 3 {
+		t.Errorf("kept observations = %v", kept.Observations)
+	}
+	relations, _ := store.ListRelations("mark42")
+	if len(relations) != 2 {
+		t.Errorf("kept relations = %+v, want written_in Go and konfig uses", relations)
+	}
+	attrs, _ := store.GetAttributes("mark42")
+	if attrs["version"] != "1.5.0" || attrs["language"] != "Go" {
+		t.Errorf("kept attributes = %v", attrs)
+	}
+
+	undone, err := store.UndoMerge(0)
+	if err != nil {
+		t.Fatalf("UndoMerge: %v", err)
+	}
+	if undone.ID != merge.ID || undone.Merged != "Mark-42" {
+		t.Errorf("UndoMerge = %+v", undone)
+	}
+	restored, err := store.GetEntity("Mark-42")
+	if err != nil || len(restored.Observations) != 2 {
+		t.Fatalf("restored = %+v, %v", restored, err)
+	}
+	kept, _ = store.GetEntity("mark42")
+	if len(kept.Observations) != 2 {
+		t.Errorf("expected the moved observation taken back, got %v", kept.Observations)
+	}
+	if relations, _ := store.ListRelations("Mark-42"); len(relations) != 3 {
+		t.Errorf("restored relations = %+v, want all three", relations)
+	}
+	if attrs, _ := store.GetAttributes("mark42"); len(attrs) != 1 {
+		t.Errorf("expected the copied attribute removed from mark42, got %v", attrs)
+	}
+	if attrs, _ := store.GetAttributes("Mark-42"); len(attrs) != 2 {
+		t.Errorf("restored attributes = %v", attrs)
+	}
+	var tombstones int
+	store.DB().Get(&tombstones, "SELECT COUNT(*) FROM tombstones")
+	if tombstones != 0 {
+		t.Errorf("expected the restored rows' tombstones cleared, got %d", tombstones)
+	}
+
+	if _, err := store.UndoMerge(merge.ID); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("expected a second undo rejected, got %v", err)
+	}
+	if _, err := store.UndoMerge(0); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected nothing left to undo, got %v", err)
+	}
+	if _, err := store.MergeEntities("mark42", "mark42"); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("expected merging into itself rejected, got %v", err)
+	}
+}
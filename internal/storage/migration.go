@@ -18,33 +18,54 @@ func init() {
 	_ = goose.SetDialect("sqlite3")
 }
 
+// withMigrationDB runs fn with the handle goose migrates through. Local
+// databases get a separate handle without foreign keys: migrations that
+// rebuild a table drop the old one, and with foreign keys on that drop
+// cascades to every row referencing it.
+func (s *Store) withMigrationDB(fn func(db *sql.DB) error) error {
+	if s.remote {
+		return fn(s.db.DB)
+	}
+	db, err := sql.Open("sqlite", s.path)
+	if err != nil {
+		return fmt.Errorf("failed to open database for migration: %w", err)
+	}
+	defer db.Close()
+	return fn(db)
+}
+
 // Migrate runs all pending migrations using goose.
 func (s *Store) Migrate() error {
-	// Get the underlying *sql.DB for goose
-	db := s.db.DB
+	// A read-only store can only report pending migrations
+	if s.readOnly {
+		return s.CheckSchema()
+	}
 
 	// Set logger
 	goose.SetLogger(goose.NopLogger())
 
 	// Run migrations
-	if err := goose.Up(db, "."); err != nil {
-		return fmt.Errorf("goose migration failed: %w", err)
-	}
-
-	return nil
+	return s.withMigrationDB(func(db *sql.DB) error {
+		if err := goose.Up(db, "."); err != nil {
+			return fmt.Errorf("goose migration failed: %w", err)
+		}
+		return nil
+	})
 }
 
 // MigrateWithLogging runs migrations with logging enabled.
 func (s *Store) MigrateWithLogging() error {
-	db := s.db.DB
-
-	goose.SetLogger(log.Default())
-
-	if err := goose.Up(db, "."); err != nil {
-		return fmt.Errorf("goose migration failed: %w", err)
+	if s.readOnly {
+		return s.CheckSchema()
 	}
+	goose.SetLogger(log.Default())
 
-	return nil
+	return s.withMigrationDB(func(db *sql.DB) error {
+		if err := goose.Up(db, "."); err != nil {
+			return fmt.Errorf("goose migration failed: %w", err)
+		}
+		return nil
+	})
 }
 
 // GetSchemaVersion returns the current schema version.
@@ -57,37 +78,63 @@ func (s *Store) GetSchemaVersion() (int64, error) {
 	return version, nil
 }
 
-// MigrateDown rolls back the last migration.
-func (s *Store) MigrateDown() error {
-	db := s.db.DB
+// CheckSchema returns a SchemaOutdatedError if the database has migrations
+// pending. Commands that don't migrate on open call it to explain failures
+// caused by missing tables or columns.
+func (s *Store) CheckSchema() error {
+	return s.CheckSchemaContext(context.Background())
+}
 
-	if err := goose.Down(db, "."); err != nil {
-		return fmt.Errorf("goose rollback failed: %w", err)
+// CheckSchemaContext is CheckSchema with a context.
+func (s *Store) CheckSchemaContext(ctx context.Context) error {
+	current, err := goose.GetDBVersionContext(ctx, s.db.DB)
+	if err != nil {
+		return fmt.Errorf("failed to get schema version: %w", err)
+	}
+	migrations, err := goose.CollectMigrations(".", 0, goose.MaxVersion)
+	if err != nil {
+		return err
+	}
+	last, err := migrations.Last()
+	if err != nil {
+		return err
+	}
+	if current < last.Version {
+		return &SchemaOutdatedError{Current: current, Latest: last.Version}
 	}
-
 	return nil
 }
 
+// MigrateDown rolls back the last migration.
+func (s *Store) MigrateDown() error {
+	return s.withMigrationDB(func(db *sql.DB) error {
+		if err := goose.Down(db, "."); err != nil {
+			return fmt.Errorf("goose rollback failed: %w", err)
+		}
+		return nil
+	})
+}
+
 // MigrateTo migrates to a specific version.
 func (s *Store) MigrateTo(version int64) error {
-	db := s.db.DB
-
-	current, err := goose.GetDBVersion(db)
-	if err != nil {
-		return fmt.Errorf("failed to get current version: %w", err)
-	}
-
-	if version > current {
-		if err := goose.UpTo(db, ".", version); err != nil {
-			return fmt.Errorf("goose migrate up failed: %w", err)
+	return s.withMigrationDB(func(db *sql.DB) error {
+		current, err := goose.GetDBVersion(db)
+		if err != nil {
+			return fmt.Errorf("failed to get current version: %w", err)
 		}
-	} else if version < current {
-		if err := goose.DownTo(db, ".", version); err != nil {
-			return fmt.Errorf("goose migrate down failed: %w", err)
+
+		if version > current {
+			if err := goose.UpTo(db, ".", version); err != nil {
+				return fmt.Errorf("goose migrate up failed: %w", err)
+			}
+		} else if version < current {
+			if err := goose.DownTo(db, ".", version); err != nil {
+				return fmt.Errorf("goose migrate down failed: %w", err)
+			}
 		}
-	}
 
-	return nil
+		return nil
+	})
 }
 
 // MigrateStatus returns the status of all migrations.
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -8,7 +9,7 @@ import (
 
 // ExpectedMigrationCount is the total number of goose migrations.
 // Update this when adding new migrations.
-const ExpectedMigrationCount int64 = 8
+const ExpectedMigrationCount int64 = 35
 
 func TestMigrate_CreatesSchemaVersion(t *testing.T) {
 	tmpDir := t.TempDir()
@@ -170,6 +171,33 @@ func TestMigrate_PersistsAcrossRestart(t *testing.T) {
 	}
 }
 
+func TestCheckSchema(t *testing.T) {
+	store, err := NewStore(filepath.Join(t.TempDir(), "test_check_schema.db"))
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	if err := store.MigrateTo(ExpectedMigrationCount - 1); err != nil {
+		t.Fatalf("migration failed: %v", err)
+	}
+	err = store.CheckSchema()
+	if !errors.Is(err, ErrSchemaOutdated) {
+		t.Fatalf("expected ErrSchemaOutdated, got %v", err)
+	}
+	var outdated *SchemaOutdatedError
+	if !errors.As(err, &outdated) || outdated.Latest != ExpectedMigrationCount {
+		t.Errorf("expected latest version %d, got %+v", ExpectedMigrationCount, outdated)
+	}
+
+	if err := store.Migrate(); err != nil {
+		t.Fatalf("migration failed: %v", err)
+	}
+	if err := store.CheckSchema(); err != nil {
+		t.Errorf("expected current schema, got %v", err)
+	}
+}
+
 func TestMain(m *testing.M) {
 	os.Exit(m.Run())
 }
@@ -0,0 +1,41 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddAccessTracking, downAddAccessTracking)
+}
+
+func upAddAccessTracking(ctx context.Context, tx *sql.Tx) error {
+	columns := map[string]string{
+		"access_count": `ALTER TABLE observations ADD COLUMN access_count INTEGER DEFAULT 0`,
+		"last_useful":  `ALTER TABLE observations ADD COLUMN last_useful TIMESTAMP`,
+	}
+
+	for _, col := range []string{"access_count", "last_useful"} {
+		var count int
+		err := tx.QueryRowContext(ctx, `
+			SELECT COUNT(*) FROM pragma_table_info('observations') WHERE name=?
+		`, col).Scan(&count)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			continue // Column already exists
+		}
+
+		if _, err := tx.ExecContext(ctx, columns[col]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func downAddAccessTracking(ctx context.Context, tx *sql.Tx) error {
+	return nil
+}
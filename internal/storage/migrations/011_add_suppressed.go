@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddSuppressed, downAddSuppressed)
+}
+
+func upAddSuppressed(ctx context.Context, tx *sql.Tx) error {
+	var count int
+	err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM pragma_table_info('observations') WHERE name='suppressed'
+	`).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil // Column already exists
+	}
+
+	_, err = tx.ExecContext(ctx, `ALTER TABLE observations ADD COLUMN suppressed INTEGER DEFAULT 0`)
+	return err
+}
+
+func downAddSuppressed(ctx context.Context, tx *sql.Tx) error {
+	return nil
+}
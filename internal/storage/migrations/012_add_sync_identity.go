@@ -0,0 +1,221 @@
+package migrations
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddSyncIdentity, downAddSyncIdentity)
+}
+
+// syncTables get a stable uid, a Lamport clock of their last change, and the
+// node that made it, so diverged databases can be merged record by record.
+var syncTables = []struct {
+	table string
+	kind  string
+	// Columns whose change counts as a new write for last-writer-wins
+	tracked string
+}{
+	{"entities", "entity", "entity_type, container_tag"},
+	{"observations", "observation", "entity_id, fact_type, pinned, suppressed"},
+	{"relations", "relation", ""},
+}
+
+// uuidSQL generates a random (version 4) UUID in SQL, for rows inserted by
+// code that does not set one.
+const uuidSQL = `lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' ||
+	substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + (abs(random()) % 4), 1) ||
+	substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))`
+
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+func upAddSyncIdentity(ctx context.Context, tx *sql.Tx) error {
+	nodeID, err := newUUID()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		-- This database's identity and Lamport clock
+		CREATE TABLE IF NOT EXISTS sync_state (
+			id INTEGER PRIMARY KEY CHECK (id = 1),
+			node_id TEXT NOT NULL,
+			clock INTEGER NOT NULL DEFAULT 0
+		);
+
+		-- Deleted records, so deletions survive a merge
+		CREATE TABLE IF NOT EXISTS tombstones (
+			uid TEXT PRIMARY KEY,
+			kind TEXT NOT NULL,
+			clock INTEGER NOT NULL,
+			origin TEXT NOT NULL
+		);
+	`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO sync_state (id, node_id) VALUES (1, ?)`, nodeID); err != nil {
+		return err
+	}
+
+	if err := narrowFTSUpdateTriggers(ctx, tx); err != nil {
+		return err
+	}
+
+	for _, t := range syncTables {
+		for _, col := range []string{"uid TEXT", "clock INTEGER DEFAULT 0", "origin TEXT"} {
+			name := strings.Fields(col)[0]
+			var count int
+			if err := tx.QueryRowContext(ctx,
+				`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, t.table, name).Scan(&count); err != nil {
+				return err
+			}
+			if count > 0 {
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, `ALTER TABLE `+t.table+` ADD COLUMN `+col); err != nil {
+				return err
+			}
+		}
+
+		if err := backfillUIDs(ctx, tx, t.table, nodeID); err != nil {
+			return err
+		}
+
+		stmts := `
+			CREATE UNIQUE INDEX IF NOT EXISTS idx_{table}_uid ON {table}(uid);
+
+			DROP TRIGGER IF EXISTS {table}_sync_ai;
+			CREATE TRIGGER {table}_sync_ai AFTER INSERT ON {table} WHEN new.uid IS NULL BEGIN
+				UPDATE sync_state SET clock = clock + 1;
+				UPDATE {table} SET uid = ` + uuidSQL + `,
+					clock = (SELECT clock FROM sync_state), origin = (SELECT node_id FROM sync_state)
+				WHERE id = new.id;
+			END;
+
+			DROP TRIGGER IF EXISTS {table}_sync_ad;
+			CREATE TRIGGER {table}_sync_ad AFTER DELETE ON {table} WHEN old.uid IS NOT NULL BEGIN
+				UPDATE sync_state SET clock = clock + 1;
+				INSERT OR REPLACE INTO tombstones (uid, kind, clock, origin)
+				SELECT old.uid, '{kind}', clock, node_id FROM sync_state;
+			END;
+		`
+		if t.tracked != "" {
+			// Merges set clock and origin themselves; only local edits tick the clock
+			stmts += `
+			DROP TRIGGER IF EXISTS {table}_sync_au;
+			CREATE TRIGGER {table}_sync_au AFTER UPDATE OF {tracked} ON {table}
+			WHEN new.clock IS old.clock AND new.origin IS old.origin BEGIN
+				UPDATE sync_state SET clock = clock + 1;
+				UPDATE {table} SET clock = (SELECT clock FROM sync_state),
+					origin = (SELECT node_id FROM sync_state)
+				WHERE id = new.id;
+			END;
+			`
+		}
+		stmts = strings.NewReplacer("{table}", t.table, "{kind}", t.kind, "{tracked}", t.tracked).Replace(stmts)
+		if _, err := tx.ExecContext(ctx, stmts); err != nil {
+			return fmt.Errorf("%s: %w", t.table, err)
+		}
+	}
+	return nil
+}
+
+// narrowFTSUpdateTriggers limits the FTS update triggers to the indexed
+// columns. The sync triggers update rows from inside their insert triggers,
+// before the FTS insert trigger has run, and re-indexing such a row would
+// remove an FTS entry that does not exist yet.
+func narrowFTSUpdateTriggers(ctx context.Context, tx *sql.Tx) error {
+	var count int
+	if err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'observations_fts'
+	`).Scan(&count); err != nil || count == 0 {
+		return err // No FTS5 (hosted libSQL without it)
+	}
+
+	_, err := tx.ExecContext(ctx, `
+		DROP TRIGGER IF EXISTS observations_au;
+		CREATE TRIGGER observations_au AFTER UPDATE OF content ON observations BEGIN
+			INSERT INTO observations_fts(observations_fts, rowid, content)
+			VALUES('delete', old.id, old.content);
+			INSERT INTO observations_fts(rowid, content) VALUES (new.id, new.content);
+		END;
+
+		DROP TRIGGER IF EXISTS entities_au;
+		CREATE TRIGGER entities_au AFTER UPDATE OF name, entity_type ON entities BEGIN
+			INSERT INTO entities_fts(entities_fts, rowid, name, entity_type)
+			VALUES('delete', old.id, old.name, old.entity_type);
+			INSERT INTO entities_fts(rowid, name, entity_type)
+			VALUES (new.id, new.name, new.entity_type);
+		END;
+	`)
+	return err
+}
+
+// backfillUIDs gives existing rows a uid and a clock tick each.
+func backfillUIDs(ctx context.Context, tx *sql.Tx, table, nodeID string) error {
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM `+table+` WHERE uid IS NULL ORDER BY id`)
+	if err != nil {
+		return err
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		uid, err := newUUID()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE sync_state SET clock = clock + 1`); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE `+table+` SET uid = ?, clock = (SELECT clock FROM sync_state), origin = ?
+			WHERE id = ?`, uid, nodeID, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func downAddSyncIdentity(ctx context.Context, tx *sql.Tx) error {
+	for _, t := range syncTables {
+		if _, err := tx.ExecContext(ctx, strings.ReplaceAll(`
+			DROP TRIGGER IF EXISTS {table}_sync_ai;
+			DROP TRIGGER IF EXISTS {table}_sync_ad;
+			DROP TRIGGER IF EXISTS {table}_sync_au;
+			DROP INDEX IF EXISTS idx_{table}_uid;
+		`, "{table}", t.table)); err != nil {
+			return err
+		}
+	}
+	_, err := tx.ExecContext(ctx, `
+		DROP TABLE IF EXISTS tombstones;
+		DROP TABLE IF EXISTS sync_state;
+	`)
+	return err
+}
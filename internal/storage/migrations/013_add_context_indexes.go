@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddContextIndexes, downAddContextIndexes)
+}
+
+// Indexes for context injection, importance recalculation, and decay, which
+// otherwise scan every observation.
+func upAddContextIndexes(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+		CREATE INDEX IF NOT EXISTS idx_entities_is_latest ON entities(is_latest);
+		CREATE INDEX IF NOT EXISTS idx_observations_importance ON observations(importance);
+		CREATE INDEX IF NOT EXISTS idx_observations_fact_type ON observations(fact_type);
+		CREATE INDEX IF NOT EXISTS idx_observations_last_accessed ON observations(last_accessed);
+		CREATE INDEX IF NOT EXISTS idx_observations_pinned ON observations(pinned) WHERE pinned = 1;
+	`)
+	return err
+}
+
+func downAddContextIndexes(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+		DROP INDEX IF EXISTS idx_entities_is_latest;
+		DROP INDEX IF EXISTS idx_observations_importance;
+		DROP INDEX IF EXISTS idx_observations_last_accessed;
+		DROP INDEX IF EXISTS idx_observations_pinned;
+	`)
+	return err
+}
@@ -0,0 +1,26 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddEntityNameNocaseIndex, downAddEntityNameNocaseIndex)
+}
+
+// Index for case-insensitive entity lookups, which can't use idx_entities_name.
+// Names that differ only in case or normalization are reported by doctor.
+func upAddEntityNameNocaseIndex(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+		CREATE INDEX IF NOT EXISTS idx_entities_name_nocase ON entities(name COLLATE NOCASE);
+	`)
+	return err
+}
+
+func downAddEntityNameNocaseIndex(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `DROP INDEX IF EXISTS idx_entities_name_nocase;`)
+	return err
+}
@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddSummarySource, downAddSummarySource)
+}
+
+// upAddSummarySource records, on cached entity summaries (fact_type
+// 'summary'), a hash of the observations they were generated from, so a
+// summary can be recognized as stale once those change.
+func upAddSummarySource(ctx context.Context, tx *sql.Tx) error {
+	var count int
+	err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM pragma_table_info('observations') WHERE name='summary_source'
+	`).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil // Column already exists
+	}
+
+	_, err = tx.ExecContext(ctx, `ALTER TABLE observations ADD COLUMN summary_source TEXT`)
+	return err
+}
+
+func downAddSummarySource(ctx context.Context, tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddEntityAttributes, downAddEntityAttributes)
+}
+
+// upAddEntityAttributes adds typed key-value attributes (repo_url, version,
+// ...) to entities, for structure that observations-as-strings lose.
+func upAddEntityAttributes(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS entity_attributes (
+			entity_id INTEGER NOT NULL REFERENCES entities(id) ON DELETE CASCADE,
+			key TEXT NOT NULL,
+			value TEXT NOT NULL,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (entity_id, key)
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_entity_attributes_key ON entity_attributes(key, value);
+	`)
+	return err
+}
+
+func downAddEntityAttributes(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+		DROP TABLE IF EXISTS entity_attributes;
+	`)
+	return err
+}
@@ -0,0 +1,38 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddRelationValidity, downAddRelationValidity)
+}
+
+// upAddRelationValidity gives relations a validity window, so a relation
+// that stops being true can be ended rather than deleted. NULL valid_from
+// means since creation; NULL valid_to means still valid.
+func upAddRelationValidity(ctx context.Context, tx *sql.Tx) error {
+	for _, column := range []string{"valid_from", "valid_to"} {
+		var count int
+		err := tx.QueryRowContext(ctx, `
+			SELECT COUNT(*) FROM pragma_table_info('relations') WHERE name = ?
+		`, column).Scan(&count)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			continue // Column already exists
+		}
+		if _, err := tx.ExecContext(ctx, `ALTER TABLE relations ADD COLUMN `+column+` TIMESTAMP`); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func downAddRelationValidity(ctx context.Context, tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddObservationSource, downAddObservationSource)
+}
+
+// upAddObservationSource records how each observation entered the system:
+// the MCP tool, CLI, hook, import, or session that wrote it. Existing
+// observations keep a NULL source.
+func upAddObservationSource(ctx context.Context, tx *sql.Tx) error {
+	var count int
+	err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM pragma_table_info('observations') WHERE name='source'
+	`).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil // Column already exists
+	}
+
+	_, err = tx.ExecContext(ctx, `ALTER TABLE observations ADD COLUMN source TEXT`)
+	return err
+}
+
+func downAddObservationSource(ctx context.Context, tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddOwnership, downAddOwnership)
+}
+
+// upAddOwnership records who wrote what on databases a team shares: the
+// owner of each entity version and the author of each observation. NULL
+// means anonymous, as everything written before was.
+func upAddOwnership(ctx context.Context, tx *sql.Tx) error {
+	for _, c := range []struct{ table, column string }{
+		{"entities", "owner"},
+		{"observations", "author"},
+	} {
+		var count int
+		err := tx.QueryRowContext(ctx, `
+			SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?
+		`, c.table, c.column).Scan(&count)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			continue // Column already exists
+		}
+		if _, err := tx.ExecContext(ctx, `ALTER TABLE `+c.table+` ADD COLUMN `+c.column+` TEXT`); err != nil {
+			return err
+		}
+	}
+	_, err := tx.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS idx_observations_author ON observations(author)`)
+	return err
+}
+
+func downAddOwnership(ctx context.Context, tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddEntityMerges, downAddEntityMerges)
+}
+
+// upAddEntityMerges records each merge of one entity into another with a
+// snapshot of the merged entity, so the merge can be undone.
+func upAddEntityMerges(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS entity_merges (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			keep TEXT NOT NULL,
+			merged TEXT NOT NULL,
+			snapshot TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			undone_at TIMESTAMP
+		);
+	`)
+	return err
+}
+
+func downAddEntityMerges(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+		DROP TABLE IF EXISTS entity_merges;
+	`)
+	return err
+}
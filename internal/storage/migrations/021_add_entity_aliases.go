@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddEntityAliases, downAddEntityAliases)
+}
+
+// upAddEntityAliases adds other names an entity goes by, so relations and
+// lookups naming an alias find the entity. Aliases point at entity names,
+// which survive new versions.
+func upAddEntityAliases(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS entity_aliases (
+			alias TEXT PRIMARY KEY,
+			entity_name TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_entity_aliases_entity ON entity_aliases(entity_name);
+	`)
+	return err
+}
+
+func downAddEntityAliases(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+		DROP TABLE IF EXISTS entity_aliases;
+	`)
+	return err
+}
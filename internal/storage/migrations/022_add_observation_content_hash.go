@@ -0,0 +1,75 @@
+package migrations
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddObservationContentHash, downAddObservationContentHash)
+}
+
+// upAddObservationContentHash adds a hash of each observation's content, to
+// find the same text stored under several entities, and canonical_id, which
+// links such a copy to the one observation it repeats. Existing observations
+// are hashed here.
+func upAddObservationContentHash(ctx context.Context, tx *sql.Tx) error {
+	for _, c := range []struct{ column, definition string }{
+		{"content_hash", "TEXT"},
+		{"canonical_id", "INTEGER REFERENCES observations(id) ON DELETE SET NULL"},
+	} {
+		var count int
+		err := tx.QueryRowContext(ctx, `
+			SELECT COUNT(*) FROM pragma_table_info('observations') WHERE name = ?
+		`, c.column).Scan(&count)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			continue // Column already exists
+		}
+		if _, err := tx.ExecContext(ctx, `ALTER TABLE observations ADD COLUMN `+c.column+` `+c.definition); err != nil {
+			return err
+		}
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT id, content FROM observations WHERE content_hash IS NULL`)
+	if err != nil {
+		return err
+	}
+	hashes := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var content string
+		if err := rows.Scan(&id, &content); err != nil {
+			rows.Close()
+			return err
+		}
+		// Matches storage.contentHash
+		sum := sha256.Sum256([]byte(content))
+		hashes[id] = hex.EncodeToString(sum[:])
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for id, hash := range hashes {
+		if _, err := tx.ExecContext(ctx, `UPDATE observations SET content_hash = ? WHERE id = ?`, hash, id); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		CREATE INDEX IF NOT EXISTS idx_observations_content_hash ON observations(content_hash);
+		CREATE INDEX IF NOT EXISTS idx_observations_canonical ON observations(canonical_id);
+	`)
+	return err
+}
+
+func downAddObservationContentHash(ctx context.Context, tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,122 @@
+package migrations
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upCompressArchive, downCompressArchive)
+}
+
+// upCompressArchive stores archived observations gzipped in content_gz,
+// leaving content empty, and adds digest_count for rows that fold a month
+// of an entity's archive into one. Existing archived rows are compressed
+// here when that makes them smaller.
+func upCompressArchive(ctx context.Context, tx *sql.Tx) error {
+	for _, c := range []struct{ column, definition string }{
+		{"content_gz", "BLOB"},
+		{"digest_count", "INTEGER"},
+	} {
+		var count int
+		err := tx.QueryRowContext(ctx, `
+			SELECT COUNT(*) FROM pragma_table_info('archived_observations') WHERE name = ?
+		`, c.column).Scan(&count)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			continue // Column already exists
+		}
+		if _, err := tx.ExecContext(ctx, `ALTER TABLE archived_observations ADD COLUMN `+c.column+` `+c.definition); err != nil {
+			return err
+		}
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT id, content FROM archived_observations WHERE content_gz IS NULL`)
+	if err != nil {
+		return err
+	}
+	compressed := make(map[int64][]byte)
+	for rows.Next() {
+		var id int64
+		var content string
+		if err := rows.Scan(&id, &content); err != nil {
+			rows.Close()
+			return err
+		}
+		// Matches storage.compressArchived
+		var buf bytes.Buffer
+		zw, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+		zw.Write([]byte(content))
+		zw.Close()
+		if buf.Len() < len(content) {
+			compressed[id] = buf.Bytes()
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for id, gz := range compressed {
+		if _, err := tx.ExecContext(ctx, `UPDATE archived_observations SET content = '', content_gz = ? WHERE id = ?`, gz, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// downCompressArchive decompresses archived observations back into content.
+// Digest rows fold many observations into one and cannot be split again, so
+// their presence fails the rollback rather than keep them in a form older
+// code misreads.
+func downCompressArchive(ctx context.Context, tx *sql.Tx) error {
+	var digests int
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM archived_observations WHERE digest_count IS NOT NULL`).Scan(&digests); err != nil {
+		return err
+	}
+	if digests > 0 {
+		return fmt.Errorf("%d digested archive rows cannot be restored to individual observations", digests)
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT id, content_gz FROM archived_observations WHERE content_gz IS NOT NULL`)
+	if err != nil {
+		return err
+	}
+	decompressed := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var gz []byte
+		if err := rows.Scan(&id, &gz); err != nil {
+			rows.Close()
+			return err
+		}
+		zr, err := gzip.NewReader(bytes.NewReader(gz))
+		if err != nil {
+			rows.Close()
+			return fmt.Errorf("archived observation %d: %w", id, err)
+		}
+		content, err := io.ReadAll(zr)
+		if err != nil {
+			rows.Close()
+			return fmt.Errorf("archived observation %d: %w", id, err)
+		}
+		decompressed[id] = string(content)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for id, content := range decompressed {
+		if _, err := tx.ExecContext(ctx, `UPDATE archived_observations SET content = ?, content_gz = NULL WHERE id = ?`, content, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddObservationPII, downAddObservationPII)
+}
+
+// upAddObservationPII adds the kinds of probable personal data an
+// observation was found to hold when written in PII warn mode. Existing
+// observations are left untagged.
+func upAddObservationPII(ctx context.Context, tx *sql.Tx) error {
+	var count int
+	err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM pragma_table_info('observations') WHERE name = 'pii'
+	`).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		if _, err := tx.ExecContext(ctx, `ALTER TABLE observations ADD COLUMN pii TEXT`); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		CREATE INDEX IF NOT EXISTS idx_observations_pii ON observations(pii) WHERE pii IS NOT NULL
+	`)
+	return err
+}
+
+func downAddObservationPII(ctx context.Context, tx *sql.Tx) error {
+	return nil
+}
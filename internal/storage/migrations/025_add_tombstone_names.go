@@ -0,0 +1,77 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddTombstoneNames, downAddTombstoneNames)
+}
+
+// tombstoneTriggers record, with each deletion, what the sync file knows the
+// record by: the entity's name, an observation's entity and content hash,
+// and a relation's ends and type. Never the content itself. An observation
+// deleted along with its entity may leave no entity name; the entity's own
+// tombstone covers it.
+var tombstoneTriggers = map[string]string{
+	"entities": `
+		INSERT OR REPLACE INTO tombstones (uid, kind, clock, origin, entity, deleted_at)
+		SELECT old.uid, 'entity', clock, node_id, old.name, datetime('now') FROM sync_state;`,
+	"observations": `
+		INSERT OR REPLACE INTO tombstones (uid, kind, clock, origin, entity, content_hash, deleted_at)
+		SELECT old.uid, 'observation', clock, node_id,
+			(SELECT name FROM entities WHERE id = old.entity_id), old.content_hash, datetime('now')
+		FROM sync_state;`,
+	"relations": `
+		INSERT OR REPLACE INTO tombstones (uid, kind, clock, origin, entity, target, relation_type, deleted_at)
+		SELECT old.uid, 'relation', clock, node_id,
+			(SELECT name FROM entities WHERE id = old.from_entity_id),
+			(SELECT name FROM entities WHERE id = old.to_entity_id), old.relation_type, datetime('now')
+		FROM sync_state;`,
+}
+
+// upAddTombstoneNames adds the name, type, and deletion time to tombstones,
+// so deletions can be exported to the sync file as well as to replicas,
+// which match records by UID. Existing tombstones stay nameless.
+func upAddTombstoneNames(ctx context.Context, tx *sql.Tx) error {
+	for _, c := range []struct{ column, definition string }{
+		{"entity", "TEXT"}, // For a relation, its from end
+		{"target", "TEXT"}, // A relation's to end
+		{"relation_type", "TEXT"},
+		{"content_hash", "TEXT"},
+		{"deleted_at", "TIMESTAMP"},
+	} {
+		var count int
+		err := tx.QueryRowContext(ctx, `
+			SELECT COUNT(*) FROM pragma_table_info('tombstones') WHERE name = ?
+		`, c.column).Scan(&count)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			continue // Column already exists
+		}
+		if _, err := tx.ExecContext(ctx, `ALTER TABLE tombstones ADD COLUMN `+c.column+` `+c.definition); err != nil {
+			return err
+		}
+	}
+
+	for table, insert := range tombstoneTriggers {
+		if _, err := tx.ExecContext(ctx, `
+			DROP TRIGGER IF EXISTS `+table+`_sync_ad;
+			CREATE TRIGGER `+table+`_sync_ad AFTER DELETE ON `+table+` WHEN old.uid IS NOT NULL BEGIN
+				UPDATE sync_state SET clock = clock + 1;`+insert+`
+			END;
+		`); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func downAddTombstoneNames(ctx context.Context, tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,38 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddTrash, downAddTrash)
+}
+
+// upAddTrash keeps the rows destructive operations remove, per entity, so
+// the latest operation can be undone within the undo window.
+func upAddTrash(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS trash (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			op INTEGER NOT NULL,
+			operation TEXT NOT NULL,
+			entity TEXT NOT NULL,
+			snapshot TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			undone_at TIMESTAMP
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_trash_op ON trash(op);
+	`)
+	return err
+}
+
+func downAddTrash(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+		DROP TABLE IF EXISTS trash;
+	`)
+	return err
+}
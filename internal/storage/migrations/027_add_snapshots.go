@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddSnapshots, downAddSnapshots)
+}
+
+// upAddSnapshots records the copies of the database taken before risky
+// operations. The base schema creates the table too, so upgrade can record
+// the snapshot it takes before this migration runs.
+func upAddSnapshots(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS snapshots (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			path TEXT NOT NULL,
+			reason TEXT NOT NULL,
+			schema_version INTEGER NOT NULL,
+			size_bytes INTEGER NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+	`)
+	return err
+}
+
+func downAddSnapshots(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+		DROP TABLE IF EXISTS snapshots;
+	`)
+	return err
+}
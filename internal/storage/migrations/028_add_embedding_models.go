@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddEmbeddingModels, downAddEmbeddingModels)
+}
+
+// upAddEmbeddingModels records each embedding model's dimensions and input
+// limit, seeded from the embeddings already stored.
+func upAddEmbeddingModels(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS embedding_models (
+			model TEXT PRIMARY KEY,
+			dimensions INTEGER NOT NULL,
+			max_input_tokens INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		INSERT OR IGNORE INTO embedding_models (model, dimensions)
+		SELECT model, MAX(dimensions) FROM observation_embeddings GROUP BY model;
+	`)
+	return err
+}
+
+func downAddEmbeddingModels(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+		DROP TABLE IF EXISTS embedding_models;
+	`)
+	return err
+}
@@ -0,0 +1,54 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddContextLog, downAddContextLog)
+}
+
+// upAddContextLog records context injections and the memories each one
+// selected, when the context.log setting is on.
+func upAddContextLog(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS context_log (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			source TEXT NOT NULL,
+			project TEXT NOT NULL DEFAULT '',
+			user_name TEXT NOT NULL DEFAULT '',
+			token_budget INTEGER NOT NULL,
+			tokens_used INTEGER NOT NULL,
+			min_importance REAL NOT NULL,
+			results INTEGER NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_context_log_created ON context_log(created_at);
+
+		CREATE TABLE IF NOT EXISTS context_log_items (
+			call_id INTEGER NOT NULL REFERENCES context_log(id) ON DELETE CASCADE,
+			position INTEGER NOT NULL,
+			observation_id INTEGER NOT NULL,
+			entity_name TEXT NOT NULL,
+			fact_type TEXT,
+			importance REAL,
+			score REAL,
+			PRIMARY KEY (call_id, position)
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_context_log_items_observation ON context_log_items(observation_id);
+	`)
+	return err
+}
+
+func downAddContextLog(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+		DROP TABLE IF EXISTS context_log_items;
+		DROP TABLE IF EXISTS context_log;
+	`)
+	return err
+}
@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddToolStats, downAddToolStats)
+}
+
+// upAddToolStats counts the MCP server's tool calls, errors, and latencies,
+// one row per tool and latency bucket.
+func upAddToolStats(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS tool_stats (
+			tool TEXT NOT NULL,
+			le_ms INTEGER NOT NULL,
+			calls INTEGER NOT NULL DEFAULT 0,
+			errors INTEGER NOT NULL DEFAULT 0,
+			total_ms REAL NOT NULL DEFAULT 0,
+			max_ms REAL NOT NULL DEFAULT 0,
+			last_called TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (tool, le_ms)
+		);
+	`)
+	return err
+}
+
+func downAddToolStats(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, "DROP TABLE IF EXISTS tool_stats")
+	return err
+}
@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddWriteLease, downAddWriteLease)
+}
+
+// upAddWriteLease adds the advisory write lease maintenance commands and the
+// MCP server take in turn. It has at most one row.
+func upAddWriteLease(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS write_lease (
+			id INTEGER PRIMARY KEY CHECK (id = 1),
+			holder TEXT NOT NULL,
+			pid INTEGER NOT NULL,
+			acquired_at INTEGER NOT NULL,
+			expires_at INTEGER NOT NULL
+		);
+	`)
+	return err
+}
+
+func downAddWriteLease(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, "DROP TABLE IF EXISTS write_lease")
+	return err
+}
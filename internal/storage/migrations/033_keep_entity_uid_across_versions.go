@@ -0,0 +1,55 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upKeepEntityUIDAcrossVersions, downKeepEntityUIDAcrossVersions)
+}
+
+// entitiesSyncInsert gives a new entity a UID. A new version of an entity
+// takes over the UID of the version it supersedes, so replicas see one
+// entity changing rather than a new one appearing; the superseded version
+// is left without a UID, so pruning it writes no tombstone. The UID moves
+// through a temporary value because UIDs are unique.
+const entitiesSyncInsert = `
+	DROP TRIGGER IF EXISTS entities_sync_ai;
+	CREATE TRIGGER entities_sync_ai AFTER INSERT ON entities WHEN new.uid IS NULL BEGIN
+		UPDATE sync_state SET clock = clock + 1;
+		UPDATE entities SET uid = 'superseded:' || uid WHERE id = new.supersedes_id AND uid IS NOT NULL;
+		UPDATE entities SET uid = COALESCE(
+				(SELECT substr(uid, 12) FROM entities WHERE id = new.supersedes_id AND uid LIKE 'superseded:%'),
+				` + uuidSQL + `),
+			clock = (SELECT clock FROM sync_state), origin = (SELECT node_id FROM sync_state)
+		WHERE id = new.id;
+		UPDATE entities SET uid = NULL WHERE id = new.supersedes_id;
+	END;
+`
+
+// upKeepEntityUIDAcrossVersions keeps an entity's UID stable across
+// versions. Versions already superseded lose their UIDs; replicas only
+// ever saw the latest one.
+func upKeepEntityUIDAcrossVersions(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, entitiesSyncInsert); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `UPDATE entities SET uid = NULL WHERE is_latest = 0`)
+	return err
+}
+
+func downKeepEntityUIDAcrossVersions(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+		DROP TRIGGER IF EXISTS entities_sync_ai;
+		CREATE TRIGGER entities_sync_ai AFTER INSERT ON entities WHEN new.uid IS NULL BEGIN
+			UPDATE sync_state SET clock = clock + 1;
+			UPDATE entities SET uid = `+uuidSQL+`,
+				clock = (SELECT clock FROM sync_state), origin = (SELECT node_id FROM sync_state)
+			WHERE id = new.id;
+		END;
+	`)
+	return err
+}
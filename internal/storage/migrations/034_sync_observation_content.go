@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upSyncObservationContent, downSyncObservationContent)
+}
+
+// observationsSyncUpdate ticks the clock of an observation changed locally,
+// so the change wins over older copies when replicas merge. Merges set clock
+// and origin themselves.
+const observationsSyncUpdate = `
+	DROP TRIGGER IF EXISTS observations_sync_au;
+	CREATE TRIGGER observations_sync_au AFTER UPDATE OF {tracked} ON observations
+	WHEN new.clock IS old.clock AND new.origin IS old.origin BEGIN
+		UPDATE sync_state SET clock = clock + 1;
+		UPDATE observations SET clock = (SELECT clock FROM sync_state),
+			origin = (SELECT node_id FROM sync_state)
+		WHERE id = new.id;
+	END;
+`
+
+// upSyncObservationContent counts an edit of an observation's content as a
+// change, so edits reach other replicas.
+func upSyncObservationContent(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, replaceTracked(observationsSyncUpdate, "entity_id, content, fact_type, pinned, suppressed"))
+	return err
+}
+
+func downSyncObservationContent(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, replaceTracked(observationsSyncUpdate, "entity_id, fact_type, pinned, suppressed"))
+	return err
+}
+
+func replaceTracked(stmts, tracked string) string {
+	return strings.ReplaceAll(stmts, "{tracked}", tracked)
+}
@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upSyncRelationValidity, downSyncRelationValidity)
+}
+
+// upSyncRelationValidity counts ending or reopening a relation as a change,
+// so it reaches other replicas. Relations had no update trigger: nothing
+// else about them changes. Merges set clock and origin themselves.
+func upSyncRelationValidity(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+		DROP TRIGGER IF EXISTS relations_sync_au;
+		CREATE TRIGGER relations_sync_au AFTER UPDATE OF valid_from, valid_to ON relations
+		WHEN new.clock IS old.clock AND new.origin IS old.origin BEGIN
+			UPDATE sync_state SET clock = clock + 1;
+			UPDATE relations SET clock = (SELECT clock FROM sync_state),
+				origin = (SELECT node_id FROM sync_state)
+			WHERE id = new.id;
+		END;
+	`)
+	return err
+}
+
+func downSyncRelationValidity(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, "DROP TRIGGER IF EXISTS relations_sync_au")
+	return err
+}
@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizeName returns name in Unicode Normalization Form C, so that
+// "Café" typed with a precomposed é and with e plus a combining accent is
+// stored and looked up as the same entity.
+func NormalizeName(name string) string {
+	return norm.NFC.String(name)
+}
+
+// SetCaseInsensitiveNames makes entity creation, GetEntity, AddObservation,
+// and CreateRelation match names regardless of case, so "Café" and "café"
+// resolve to the same entity. Matching uses SQLite's NOCASE collation, which
+// folds ASCII letters only. Off by default; check NameCollisions first, since
+// a lookup matching several existing entities picks the newest.
+func (s *Store) SetCaseInsensitiveNames(on bool) {
+	s.caseInsensitiveNames = on
+}
+
+// nameMatch is the condition matching an entity's name column to a
+// normalized name parameter.
+func (s *Store) nameMatch(column string) string {
+	if s.caseInsensitiveNames {
+		return column + " = ? COLLATE NOCASE"
+	}
+	return column + " = ?"
+}
+
+// entityID returns the newest latest-version entity matching name.
+func (s *Store) entityID(ctx context.Context, q interface {
+	QueryRowContext(context.Context, string, ...any) *sql.Row
+}, name string) (int64, error) {
+	var id int64
+	err := q.QueryRowContext(ctx, `SELECT id FROM entities WHERE `+s.nameMatch("name")+`
+		AND (is_latest = 1 OR is_latest IS NULL) ORDER BY id DESC LIMIT 1`, NormalizeName(name)).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, entityNotFound(name)
+	}
+	return id, err
+}
+
+// nameKey is what two names must share to collide: NFC with ASCII case folded.
+func nameKey(name string) string {
+	return strings.Map(func(r rune) rune {
+		if 'A' <= r && r <= 'Z' {
+			return r + 'a' - 'A'
+		}
+		return r
+	}, NormalizeName(name))
+}
+
+// NameCollisions returns groups of distinct entity names that differ only in
+// Unicode normalization or case. Names written before normalization was
+// added can collide this way; case-insensitive lookups then can't tell the
+// entities apart.
+func (s *Store) NameCollisions() ([][]string, error) {
+	return s.NameCollisionsContext(context.Background())
+}
+
+// NameCollisionsContext is NameCollisions with a context.
+func (s *Store) NameCollisionsContext(ctx context.Context) ([][]string, error) {
+	var names []string
+	if err := s.db.SelectContext(ctx, &names,
+		"SELECT DISTINCT name FROM entities WHERE is_latest = 1 OR is_latest IS NULL ORDER BY name"); err != nil {
+		return nil, err
+	}
+
+	groups := map[string][]string{}
+	for _, name := range names {
+		key := nameKey(name)
+		groups[key] = append(groups[key], name)
+	}
+
+	var collisions [][]string
+	for _, group := range groups {
+		if len(group) > 1 {
+			collisions = append(collisions, group)
+		}
+	}
+	sort.Slice(collisions, func(i, j int) bool { return collisions[i][0] < collisions[j][0] })
+	return collisions, nil
+}
+
+// NormalizeEntityNames rewrites entity names that are not in NFC, unless the
+// normalized name already belongs to another entity, and returns how many
+// it renamed. Those collisions are left for the user; see NameCollisions.
+func (s *Store) NormalizeEntityNames() (int, error) {
+	return s.NormalizeEntityNamesContext(context.Background())
+}
+
+// NormalizeEntityNamesContext is NormalizeEntityNames with a context.
+func (s *Store) NormalizeEntityNamesContext(ctx context.Context) (int, error) {
+	var names []string
+	if err := s.db.SelectContext(ctx, &names, "SELECT DISTINCT name FROM entities"); err != nil {
+		return 0, err
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	renamed := 0
+	for _, name := range names {
+		normalized := NormalizeName(name)
+		if normalized == name {
+			continue
+		}
+		var taken int
+		if err := tx.GetContext(ctx, &taken, "SELECT COUNT(*) FROM entities WHERE name = ?", normalized); err != nil {
+			return 0, err
+		}
+		if taken > 0 {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE entities SET name = ? WHERE name = ?", normalized, name); err != nil {
+			return 0, fmt.Errorf("renaming %q: %w", name, err)
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE entity_aliases SET entity_name = ? WHERE entity_name = ?", normalized, name); err != nil {
+			return 0, fmt.Errorf("renaming %q: %w", name, err)
+		}
+		renamed++
+	}
+	return renamed, tx.Commit()
+}
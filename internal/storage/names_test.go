@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeName(t *testing.T) {
+	for _, tc := range []struct {
+		in, want string
+	}{
+		{"Go", "Go"},
+		{"Cafe\u0301", "Caf\u00e9"},
+		{"Caf\u00e9", "Caf\u00e9"},
+		{"\u212b", "\u00c5"},              // Angstrom sign is a singleton
+		{"a\u0302\u0323", "\u1ead"},       // Marks reordered, then composed
+		{"\u0958", "\u0915\u093c"},        // Composition exclusion stays decomposed
+		{"\u1100\u1161\u11a8", "\uac01"},  // Hangul jamo
+		{"e\u0301\u0301", "\u00e9\u0301"}, // Second mark is blocked
+		{"\u0301e", "\u0301e"},            // Leading mark has no starter
+		{"Stra\u00dfe \u65e5\u672c", "Stra\u00dfe \u65e5\u672c"},
+	} {
+		if got := NormalizeName(tc.in); got != tc.want {
+			t.Errorf("NormalizeName(%+q) = %+q, want %+q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestEntityNamesNormalized(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	entity, err := store.CreateEntity("Cafe\u0301", "place", nil)
+	if err != nil {
+		t.Fatalf("CreateEntity failed: %v", err)
+	}
+	if entity.Name != "Caf\u00e9" {
+		t.Errorf("expected name stored in NFC, got %+q", entity.Name)
+	}
+	if _, err := store.CreateEntity("Caf\u00e9", "place", nil); !errors.Is(err, ErrEntityExists) {
+		t.Errorf("expected the precomposed spelling to be the same entity, got %v", err)
+	}
+	if err := store.AddObservation("Cafe\u0301", "Serves espresso"); err != nil {
+		t.Errorf("AddObservation with the decomposed spelling failed: %v", err)
+	}
+	if _, err := store.GetEntity("Caf\u00e9"); err != nil {
+		t.Errorf("GetEntity failed: %v", err)
+	}
+
+	// Case still matters by default
+	if _, err := store.GetEntity("caf\u00e9"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected case-sensitive lookup by default, got %v", err)
+	}
+}
+
+func TestCaseInsensitiveNames(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+	store.SetCaseInsensitiveNames(true)
+
+	store.CreateEntity("Caf\u00e9", "place", nil)
+	store.CreateEntity("Espresso", "drink", nil)
+
+	entity, err := store.GetEntity("CAFe\u0301")
+	if err != nil {
+		t.Fatalf("GetEntity failed: %v", err)
+	}
+	if entity.Name != "Caf\u00e9" {
+		t.Errorf("expected the stored spelling, got %q", entity.Name)
+	}
+	if _, err := store.CreateEntity("caf\u00e9", "place", nil); !errors.Is(err, ErrEntityExists) {
+		t.Errorf("expected a differently cased name to be the same entity, got %v", err)
+	}
+	if err := store.AddObservation("caf\u00e9", "Serves espresso"); err != nil {
+		t.Errorf("AddObservation failed: %v", err)
+	}
+	if err := store.CreateRelation("caf\u00e9", "espresso", "serves"); err != nil {
+		t.Errorf("CreateRelation failed: %v", err)
+	}
+	if err := store.CreateRelation("caf\u00e9", "Caf\u00e9", "is"); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("expected spellings of one entity to be a self-relation, got %v", err)
+	}
+
+	relations, _ := store.ListRelations("Caf\u00e9")
+	if len(relations) != 1 || relations[0].To != "Espresso" {
+		t.Errorf("expected relation to Espresso, got %+v", relations)
+	}
+
+	plan := strings.Join(queryPlan(t, store,
+		"SELECT id FROM entities WHERE "+store.nameMatch("name"), "caf\u00e9"), "\n")
+	if !strings.Contains(plan, "idx_entities_name_nocase") {
+		t.Errorf("expected case-insensitive lookup to use its index, plan:\n%s", plan)
+	}
+}
+
+func TestNameCollisions(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	// Written before normalization, bypassing it
+	for _, name := range []string{"Caf\u00e9", "Cafe\u0301", "Go", "go", "Re\u0301sume\u0301", "Rust"} {
+		if _, err := store.db.Exec("INSERT INTO entities (name, entity_type) VALUES (?, 'test')", name); err != nil {
+			t.Fatalf("insert %q failed: %v", name, err)
+		}
+	}
+
+	collisions, err := store.NameCollisions()
+	if err != nil {
+		t.Fatalf("NameCollisions failed: %v", err)
+	}
+	want := [][]string{{"Cafe\u0301", "Caf\u00e9"}, {"Go", "go"}}
+	if !slices.EqualFunc(collisions, want, slices.Equal[[]string]) {
+		t.Errorf("collisions = %+q, want %+q", collisions, want)
+	}
+
+	renamed, err := store.NormalizeEntityNames()
+	if err != nil {
+		t.Fatalf("NormalizeEntityNames failed: %v", err)
+	}
+	if renamed != 1 {
+		t.Errorf("expected only the non-colliding name renamed, got %d", renamed)
+	}
+	if _, err := store.GetEntity("R\u00e9sum\u00e9"); err != nil {
+		t.Errorf("expected the decomposed name normalized: %v", err)
+	}
+	if collisions, _ := store.NameCollisions(); len(collisions) != 2 {
+		t.Errorf("expected collisions left for the user, got %+q", collisions)
+	}
+}
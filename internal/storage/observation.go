@@ -1,6 +1,11 @@
 package storage
 
-import "strings"
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+)
 
 // FactType represents the type of a fact/observation.
 type FactType string
@@ -11,6 +16,7 @@ const (
 	FactTypeSessionTurn    FactType = "session_turn"
 	FactTypeSessionEvent   FactType = "session_event"
 	FactTypeSessionSummary FactType = "session_summary"
+	FactTypeSummary        FactType = "summary" // Cached LLM abstract of an entity; see SaveEntitySummary
 )
 
 // ObservationWithMeta represents an observation with metadata.
@@ -30,46 +36,80 @@ type ContextByFactType struct {
 
 // AddObservation adds an observation to an existing entity.
 func (s *Store) AddObservation(entityName, content string) error {
-	// Get entity ID
-	var entityID int64
-	err := s.db.QueryRow(
-		"SELECT id FROM entities WHERE name = ?",
-		entityName,
-	).Scan(&entityID)
+	return s.AddObservationContext(context.Background(), entityName, content)
+}
+
+// AddObservationContext is AddObservation with a context.
+func (s *Store) AddObservationContext(ctx context.Context, entityName, content string) error {
+	content = s.redact(ctx, entityName, content)
+	if err := ValidateObservation(content); err != nil {
+		return err
+	}
+
+	entityID, err := s.entityID(ctx, s.db, entityName)
 	if err != nil {
-		return ErrNotFound
+		return err
+	}
+	if err := s.checkObservation(ctx, s.db, entityID, content, FactTypeDynamic); err != nil {
+		return err
 	}
 
 	// Insert observation (ignore duplicate via INSERT OR IGNORE)
-	_, err = s.db.Exec(
-		"INSERT OR IGNORE INTO observations (entity_id, content) VALUES (?, ?)",
-		entityID, content,
+	result, err := s.db.ExecContext(ctx,
+		"INSERT OR IGNORE INTO observations (entity_id, content, content_hash, source, author) VALUES (?, ?, ?, ?, ?)",
+		entityID, content, contentHash(content), s.sourceFor(ctx), s.userValue(),
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	if rowsAffected(result) == 0 {
+		return nil
+	}
+	return s.observationsAdded(ctx, s.db, []addedObservation{{entityID, content}})
 }
 
 // AddObservationWithType adds an observation with a specific fact type.
 func (s *Store) AddObservationWithType(entityName, content string, factType FactType) error {
-	var entityID int64
-	err := s.db.QueryRow(
-		"SELECT id FROM entities WHERE name = ?",
-		entityName,
-	).Scan(&entityID)
+	return s.AddObservationWithTypeContext(context.Background(), entityName, content, factType)
+}
+
+// AddObservationWithTypeContext is AddObservationWithType with a context.
+func (s *Store) AddObservationWithTypeContext(ctx context.Context, entityName, content string, factType FactType) error {
+	content = s.redact(ctx, entityName, content)
+	if err := ValidateObservation(content); err != nil {
+		return err
+	}
+
+	entityID, err := s.entityID(ctx, s.db, entityName)
 	if err != nil {
-		return ErrNotFound
+		return err
+	}
+	if err := s.checkObservation(ctx, s.db, entityID, content, factType); err != nil {
+		return err
 	}
 
-	_, err = s.db.Exec(
-		"INSERT OR IGNORE INTO observations (entity_id, content, fact_type) VALUES (?, ?, ?)",
-		entityID, content, string(factType),
+	result, err := s.db.ExecContext(ctx,
+		"INSERT OR IGNORE INTO observations (entity_id, content, content_hash, fact_type, source, author) VALUES (?, ?, ?, ?, ?, ?)",
+		entityID, content, contentHash(content), string(factType), s.sourceFor(ctx), s.userValue(),
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	if rowsAffected(result) == 0 {
+		return nil
+	}
+	return s.observationsAdded(ctx, s.db, []addedObservation{{entityID, content}})
 }
 
 // GetObservationsByFactType returns all observations of a specific fact type.
 func (s *Store) GetObservationsByFactType(factType FactType) ([]ObservationWithMeta, error) {
+	return s.GetObservationsByFactTypeContext(context.Background(), factType)
+}
+
+// GetObservationsByFactTypeContext is GetObservationsByFactType with a context.
+func (s *Store) GetObservationsByFactTypeContext(ctx context.Context, factType FactType) ([]ObservationWithMeta, error) {
 	var results []ObservationWithMeta
-	err := s.db.Select(&results, `
+	err := s.db.SelectContext(ctx, &results, `
 		SELECT e.name as entity_name, e.entity_type, o.content,
 		       COALESCE(o.fact_type, 'dynamic') as fact_type
 		FROM observations o
@@ -82,8 +122,13 @@ func (s *Store) GetObservationsByFactType(factType FactType) ([]ObservationWithM
 
 // GetContextByFactType returns all observations grouped by fact type for context injection.
 func (s *Store) GetContextByFactType() (*ContextByFactType, error) {
+	return s.GetContextByFactTypeContext(context.Background())
+}
+
+// GetContextByFactTypeContext is GetContextByFactType with a context.
+func (s *Store) GetContextByFactTypeContext(ctx context.Context) (*ContextByFactType, error) {
 	var observations []ObservationWithMeta
-	err := s.db.Select(&observations, `
+	err := s.db.SelectContext(ctx, &observations, `
 		SELECT e.name as entity_name, e.entity_type, o.content,
 		       COALESCE(o.fact_type, 'dynamic') as fact_type
 		FROM observations o
@@ -176,17 +221,29 @@ func (s *Store) GetObservationWithID(entityName, content string) *ObservationWit
 
 // DeleteObservation removes a specific observation from an entity.
 func (s *Store) DeleteObservation(entityName, content string) error {
-	// Get entity ID
-	var entityID int64
-	err := s.db.QueryRow(
-		"SELECT id FROM entities WHERE name = ?",
-		entityName,
-	).Scan(&entityID)
+	return s.DeleteObservationContext(context.Background(), entityName, content)
+}
+
+// DeleteObservationContext is DeleteObservation with a context.
+func (s *Store) DeleteObservationContext(ctx context.Context, entityName, content string) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
 	if err != nil {
-		return ErrNotFound
+		return err
 	}
+	defer tx.Rollback()
 
-	result, err := s.db.Exec(
+	entityID, err := s.entityID(ctx, tx, entityName)
+	if err != nil {
+		return err
+	}
+
+	trash := newTrashBatch(OpDeleteObservation)
+	if trash.entity(entityName).Observations, err = snapshotRows(ctx, tx,
+		"SELECT * FROM observations WHERE entity_id = ? AND content = ?", entityID, content); err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx,
 		"DELETE FROM observations WHERE entity_id = ? AND content = ?",
 		entityID, content,
 	)
@@ -200,8 +257,196 @@ func (s *Store) DeleteObservation(entityName, content string) error {
 	}
 
 	if rows == 0 {
-		return ErrNotFound
+		return observationNotFound(content)
+	}
+
+	if err := s.recordTrash(ctx, tx, trash); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateObservation replaces an observation's content in place, keeping its
+// creation time, fact type, importance and other metadata. The embedding of
+// the old content is dropped; callers with an embedder re-embed the new one.
+// Copies that LinkDuplicateObservations pointed at this observation change
+// with it (and lose their embeddings too); editing a copy unlinks it.
+func (s *Store) UpdateObservation(entityName, oldContent, newContent string) error {
+	return s.UpdateObservationContext(context.Background(), entityName, oldContent, newContent)
+}
+
+// UpdateObservationContext is UpdateObservation with a context.
+func (s *Store) UpdateObservationContext(ctx context.Context, entityName, oldContent, newContent string) error {
+	newContent = s.redact(ctx, entityName, newContent)
+	if err := ValidateObservation(newContent); err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	entityID, err := s.entityID(ctx, tx, entityName)
+	if err != nil {
+		return err
+	}
+
+	var obs struct {
+		ID       int64  `db:"id"`
+		FactType string `db:"fact_type"`
+	}
+	err = tx.GetContext(ctx, &obs, "SELECT id, COALESCE(fact_type, 'dynamic') as fact_type FROM observations WHERE entity_id = ? AND content = ?",
+		entityID, oldContent)
+	if errors.Is(err, sql.ErrNoRows) {
+		return observationNotFound(oldContent)
+	}
+	if err != nil {
+		return err
+	}
+	if newContent == oldContent {
+		return nil
+	}
+	if err := s.checkObservation(ctx, tx, entityID, newContent, FactType(obs.FactType)); err != nil {
+		return err
 	}
+	id := obs.ID
 
+	var exists bool
+	if err := tx.GetContext(ctx, &exists,
+		"SELECT EXISTS (SELECT 1 FROM observations WHERE entity_id = ? AND content = ?)", entityID, newContent); err != nil {
+		return err
+	}
+	if exists {
+		return &ValidationError{"observation", "the entity already has this observation"}
+	}
+
+	// Copies linked to this observation follow it, except where their entity
+	// already has the new content
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE observations SET canonical_id = NULL
+		WHERE canonical_id = ? AND entity_id IN (SELECT entity_id FROM observations WHERE content = ?)
+	`, id, newContent); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM observation_embeddings
+		WHERE observation_id IN (SELECT id FROM observations WHERE canonical_id = ?)
+	`, id); err != nil {
+		return err
+	}
+	pii := s.piiTag(newContent)
+	if _, err := tx.ExecContext(ctx, "UPDATE observations SET content = ?, content_hash = ?, pii = ? WHERE canonical_id = ?",
+		newContent, contentHash(newContent), pii, id); err != nil {
+		return err
+	}
+
+	// An edited copy no longer repeats its canonical observation
+	if _, err := tx.ExecContext(ctx, "UPDATE observations SET content = ?, content_hash = ?, pii = ?, canonical_id = NULL WHERE id = ?",
+		newContent, contentHash(newContent), pii, id); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM observation_embeddings WHERE observation_id = ?", id); err != nil {
+		return err
+	}
+	// Policies and mention links apply to the new content as to a new observation
+	if err := s.observationsAdded(ctx, tx, []addedObservation{{entityID, newContent}}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetObservationPinned pins or unpins an observation. Pinned observations are
+// exempt from decay and archival and lead the context injected at session start.
+func (s *Store) SetObservationPinned(entityName, content string, pinned bool) error {
+	return s.SetObservationPinnedContext(context.Background(), entityName, content, pinned)
+}
+
+// SetObservationPinnedContext is SetObservationPinned with a context.
+func (s *Store) SetObservationPinnedContext(ctx context.Context, entityName, content string, pinned bool) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE observations
+		SET pinned = ?
+		WHERE entity_id = (SELECT id FROM entities WHERE name = ? AND is_latest = 1)
+		AND content = ?
+	`, pinned, entityName, content)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return observationNotFound(content)
+	}
+	return nil
+}
+
+// ListPinnedObservations returns all pinned observations.
+func (s *Store) ListPinnedObservations() ([]ObservationWithMeta, error) {
+	return s.ListPinnedObservationsContext(context.Background())
+}
+
+// ListPinnedObservationsContext is ListPinnedObservations with a context.
+func (s *Store) ListPinnedObservationsContext(ctx context.Context) ([]ObservationWithMeta, error) {
+	var results []ObservationWithMeta
+	err := s.db.SelectContext(ctx, &results, `
+		SELECT e.name as entity_name, e.entity_type, o.content,
+		       COALESCE(o.fact_type, 'dynamic') as fact_type
+		FROM observations o
+		JOIN entities e ON e.id = o.entity_id
+		WHERE e.is_latest = 1 AND o.pinned = 1
+		ORDER BY e.name, o.id
+	`)
+	return results, err
+}
+
+// SetObservationSuppressed marks an observation as stale or wrong without deleting it.
+// Suppressed observations are hidden from search and context injection.
+func (s *Store) SetObservationSuppressed(entityName, content string, suppressed bool) error {
+	return s.SetObservationSuppressedContext(context.Background(), entityName, content, suppressed)
+}
+
+// SetObservationSuppressedContext is SetObservationSuppressed with a context.
+func (s *Store) SetObservationSuppressedContext(ctx context.Context, entityName, content string, suppressed bool) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE observations
+		SET suppressed = ?
+		WHERE entity_id = (SELECT id FROM entities WHERE name = ? AND is_latest = 1)
+		AND content = ?
+	`, suppressed, entityName, content)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return observationNotFound(content)
+	}
 	return nil
 }
+
+// ListSuppressedObservations returns all suppressed observations.
+func (s *Store) ListSuppressedObservations() ([]ObservationWithMeta, error) {
+	return s.ListSuppressedObservationsContext(context.Background())
+}
+
+// ListSuppressedObservationsContext is ListSuppressedObservations with a context.
+func (s *Store) ListSuppressedObservationsContext(ctx context.Context) ([]ObservationWithMeta, error) {
+	var results []ObservationWithMeta
+	err := s.db.SelectContext(ctx, &results, `
+		SELECT e.name as entity_name, e.entity_type, o.content,
+		       COALESCE(o.fact_type, 'dynamic') as fact_type
+		FROM observations o
+		JOIN entities e ON e.id = o.entity_id
+		WHERE e.is_latest = 1 AND o.suppressed = 1
+		ORDER BY e.name, o.id
+	`)
+	return results, err
+}
@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+)
+
+// contentHash is the content_hash of an observation with this content.
+func contentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// ObservationDuplicate is one observation text stored under several entities.
+type ObservationDuplicate struct {
+	Hash    string `json:"hash"`
+	Content string `json:"content"`
+	// Entities holding the text, oldest copy first; that copy is the one
+	// LinkDuplicateObservations makes canonical
+	Entities []string `json:"entities"`
+	// Linked counts the copies already linked to the oldest one
+	Linked int `json:"linked"`
+}
+
+// DuplicateObservations lists observation texts that more than one current
+// entity holds word for word, most copies first.
+func (s *Store) DuplicateObservations() ([]ObservationDuplicate, error) {
+	return s.DuplicateObservationsContext(context.Background())
+}
+
+// DuplicateObservationsContext is DuplicateObservations with a context.
+func (s *Store) DuplicateObservationsContext(ctx context.Context) ([]ObservationDuplicate, error) {
+	if err := s.fillContentHashes(ctx); err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		ID          int64  `db:"id"`
+		Hash        string `db:"content_hash"`
+		Content     string `db:"content"`
+		Entity      string `db:"name"`
+		CanonicalID *int64 `db:"canonical_id"`
+	}
+	if err := s.db.SelectContext(ctx, &rows, `
+		SELECT o.id, o.content_hash, o.content, e.name, o.canonical_id
+		FROM observations o
+		JOIN entities e ON e.id = o.entity_id
+		WHERE e.is_latest = 1 AND o.content_hash IN (
+			SELECT o2.content_hash
+			FROM observations o2
+			JOIN entities e2 ON e2.id = o2.entity_id
+			WHERE e2.is_latest = 1 AND o2.content_hash IS NOT NULL
+			GROUP BY o2.content_hash
+			HAVING COUNT(DISTINCT o2.entity_id) > 1
+		)
+		ORDER BY o.content_hash, o.id
+	`); err != nil {
+		return nil, err
+	}
+
+	var duplicates []ObservationDuplicate
+	var canonical int64
+	for _, r := range rows {
+		if len(duplicates) == 0 || duplicates[len(duplicates)-1].Hash != r.Hash {
+			duplicates = append(duplicates, ObservationDuplicate{Hash: r.Hash, Content: r.Content})
+			canonical = r.ID
+		}
+		d := &duplicates[len(duplicates)-1]
+		d.Entities = append(d.Entities, r.Entity)
+		if r.CanonicalID != nil && *r.CanonicalID == canonical {
+			d.Linked++
+		}
+	}
+
+	slices.SortStableFunc(duplicates, func(a, b ObservationDuplicate) int {
+		return cmp.Compare(len(b.Entities), len(a.Entities))
+	})
+	return duplicates, nil
+}
+
+// LinkDuplicateObservations links every copy of a duplicated observation
+// text to the oldest copy, its canonical observation. Each copy keeps its
+// entity, importance, and other metadata, but editing the canonical
+// observation edits every linked copy; see UpdateObservation. It returns the
+// number of copies newly linked.
+func (s *Store) LinkDuplicateObservations() (int, error) {
+	return s.LinkDuplicateObservationsContext(context.Background())
+}
+
+// LinkDuplicateObservationsContext is LinkDuplicateObservations with a context.
+func (s *Store) LinkDuplicateObservationsContext(ctx context.Context) (int, error) {
+	if err := s.fillContentHashes(ctx); err != nil {
+		return 0, err
+	}
+	result, err := s.db.ExecContext(ctx, `
+		WITH current AS (
+			SELECT o.id, o.content_hash
+			FROM observations o
+			JOIN entities e ON e.id = o.entity_id
+			WHERE e.is_latest = 1 AND o.content_hash IS NOT NULL
+		),
+		canonical AS (
+			SELECT content_hash, MIN(id) AS id
+			FROM current
+			GROUP BY content_hash
+			HAVING COUNT(*) > 1
+		)
+		UPDATE observations
+		SET canonical_id = (SELECT c.id FROM canonical c WHERE c.content_hash = observations.content_hash)
+		WHERE id IN (SELECT cur.id FROM current cur JOIN canonical c USING (content_hash) WHERE cur.id != c.id)
+		AND canonical_id IS NOT (SELECT c.id FROM canonical c WHERE c.content_hash = observations.content_hash)
+	`)
+	if err != nil {
+		return 0, err
+	}
+	return rowsAffected(result), nil
+}
+
+// fillContentHashes hashes observations written without a content_hash, by
+// an older build or another tool.
+func (s *Store) fillContentHashes(ctx context.Context) error {
+	if s.readOnly {
+		return nil
+	}
+	var missing []struct {
+		ID      int64  `db:"id"`
+		Content string `db:"content"`
+	}
+	if err := s.db.SelectContext(ctx, &missing,
+		"SELECT id, content FROM observations WHERE content_hash IS NULL"); err != nil {
+		return err
+	}
+	for _, m := range missing {
+		if _, err := s.db.ExecContext(ctx, "UPDATE observations SET content_hash = ? WHERE id = ?",
+			contentHash(m.Content), m.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
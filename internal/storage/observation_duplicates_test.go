@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDuplicateObservations(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	convention := "Use table-driven tests"
+	store.CreateEntity("api", "project", []string{convention, "Serves JSON"})
+	store.CreateEntity("cli", "project", []string{convention})
+	store.CreateEntity("web", "project", []string{convention, "Serves JSON"})
+	store.CreateEntity("docs", "project", []string{"Use table-driven tests for parsers"})
+
+	duplicates, err := store.DuplicateObservations()
+	if err != nil {
+		t.Fatalf("DuplicateObservations failed: %v", err)
+	}
+	if len(duplicates) != 2 {
+		t.Fatalf("expected 2 duplicated texts, got %+v", duplicates)
+	}
+	first := duplicates[0]
+	if first.Content != convention || len(first.Entities) != 3 || first.Entities[0] != "api" {
+		t.Errorf("expected the convention under api, cli, and web first, got %+v", first)
+	}
+	if first.Hash != contentHash(convention) {
+		t.Errorf("expected the content hash, got %q", first.Hash)
+	}
+
+	t.Run("link", func(t *testing.T) {
+		linked, err := store.LinkDuplicateObservations()
+		if err != nil {
+			t.Fatalf("LinkDuplicateObservations failed: %v", err)
+		}
+		if linked != 3 {
+			t.Errorf("expected 3 copies linked, got %d", linked)
+		}
+		if again, _ := store.LinkDuplicateObservations(); again != 0 {
+			t.Errorf("expected linking again to change nothing, got %d", again)
+		}
+
+		duplicates, _ := store.DuplicateObservations()
+		if duplicates[0].Linked != 2 {
+			t.Errorf("expected 2 copies linked to the canonical one, got %+v", duplicates[0])
+		}
+	})
+
+	t.Run("editing the canonical observation edits its copies", func(t *testing.T) {
+		updated := "Use table-driven tests with t.Run"
+		if err := store.UpdateObservation("api", convention, updated); err != nil {
+			t.Fatalf("UpdateObservation failed: %v", err)
+		}
+		for _, name := range []string{"api", "cli", "web"} {
+			entity, err := store.GetEntity(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Contains(entity.Observations, updated) {
+				t.Errorf("expected %s to have the edit, got %v", name, entity.Observations)
+			}
+		}
+	})
+
+	t.Run("editing a copy unlinks it", func(t *testing.T) {
+		if err := store.UpdateObservation("web", "Serves JSON", "Serves HTML"); err != nil {
+			t.Fatalf("UpdateObservation failed: %v", err)
+		}
+		api, _ := store.GetEntity("api")
+		if !slices.Contains(api.Observations, "Serves JSON") {
+			t.Errorf("expected api to keep its text, got %v", api.Observations)
+		}
+	})
+}
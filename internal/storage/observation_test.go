@@ -1,6 +1,7 @@
 package storage_test
 
 import (
+	"errors"
 	"strings"
 	"testing"
 
@@ -35,7 +36,7 @@ func TestAddObservation_EntityNotFound(t *testing.T) {
 	defer store.Close()
 
 	err := store.AddObservation("nonexistent", "some observation")
-	if err != storage.ErrNotFound {
+	if !errors.Is(err, storage.ErrNotFound) {
 		t.Errorf("expected ErrNotFound, got %v", err)
 	}
 }
@@ -85,11 +86,54 @@ func TestDeleteObservation_NotFound(t *testing.T) {
 	store.CreateEntity("TDD", "pattern", []string{"obs1"})
 
 	err := store.DeleteObservation("TDD", "nonexistent")
-	if err != storage.ErrNotFound {
+	if !errors.Is(err, storage.ErrNotFound) {
 		t.Errorf("expected ErrNotFound, got %v", err)
 	}
 }
 
+func TestUpdateObservation(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+
+	store.CreateEntity("TDD", "pattern", []string{"obs2"})
+	store.AddObservationWithType("TDD", "Wirte tests first", storage.FactTypeStatic)
+	store.SetObservationPinned("TDD", "Wirte tests first", true)
+	before := store.GetObservationWithID("TDD", "Wirte tests first")
+	store.StoreEmbedding(before.ID, []float64{1, 0}, "test")
+
+	if err := store.UpdateObservation("TDD", "Wirte tests first", "Write tests first"); err != nil {
+		t.Fatalf("UpdateObservation failed: %v", err)
+	}
+
+	after := store.GetObservationWithID("TDD", "Write tests first")
+	if after == nil || after.ID != before.ID {
+		t.Fatalf("expected the observation edited in place, got %+v", after)
+	}
+	static, _ := store.GetObservationsByFactType(storage.FactTypeStatic)
+	if len(static) != 1 || static[0].Content != "Write tests first" {
+		t.Errorf("expected the fact type kept, got %+v", static)
+	}
+	if pinned, _ := store.ListPinnedObservations(); len(pinned) != 1 {
+		t.Errorf("expected the pin kept, got %+v", pinned)
+	}
+	if _, err := store.GetEmbedding(after.ID); err == nil {
+		t.Error("expected the stale embedding dropped")
+	}
+	if results, _ := store.Search("Write"); len(results) != 1 {
+		t.Errorf("expected the new content searchable, got %d results", len(results))
+	}
+
+	if err := store.UpdateObservation("TDD", "Wirte tests first", "x"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for the old content, got %v", err)
+	}
+	if err := store.UpdateObservation("TDD", "Write tests first", "obs2"); !errors.Is(err, storage.ErrInvalidInput) {
+		t.Errorf("expected a clash with an existing observation rejected, got %v", err)
+	}
+	if err := store.UpdateObservation("TDD", "obs2", " "); !errors.Is(err, storage.ErrInvalidInput) {
+		t.Errorf("expected empty content rejected, got %v", err)
+	}
+}
+
 func TestAddObservationWithType(t *testing.T) {
 	store := newTestStore(t)
 	defer store.Close()
@@ -236,3 +280,71 @@ func TestFormatContextForInjection(t *testing.T) {
 		t.Error("missing dynamic content")
 	}
 }
+
+func TestSetObservationPinned(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+
+	store.CreateEntity("Rules", "convention", []string{"Never push to main", "Squash merges"})
+
+	if err := store.SetObservationPinned("Rules", "Never push to main", true); err != nil {
+		t.Fatalf("SetObservationPinned failed: %v", err)
+	}
+
+	pinned, err := store.ListPinnedObservations()
+	if err != nil {
+		t.Fatalf("ListPinnedObservations failed: %v", err)
+	}
+	if len(pinned) != 1 || pinned[0].Content != "Never push to main" {
+		t.Errorf("expected one pinned observation, got %+v", pinned)
+	}
+
+	if err := store.SetObservationPinned("Rules", "Never push to main", false); err != nil {
+		t.Fatalf("unpin failed: %v", err)
+	}
+	pinned, _ = store.ListPinnedObservations()
+	if len(pinned) != 0 {
+		t.Errorf("expected no pinned observations, got %d", len(pinned))
+	}
+
+	if err := store.SetObservationPinned("Rules", "missing", true); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestSetObservationSuppressed(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+
+	store.CreateEntity("Build", "project", []string{"Use make build", "Use task build"})
+
+	if err := store.SetObservationSuppressed("Build", "Use make build", true); err != nil {
+		t.Fatalf("SetObservationSuppressed failed: %v", err)
+	}
+
+	suppressed, err := store.ListSuppressedObservations()
+	if err != nil {
+		t.Fatalf("ListSuppressedObservations failed: %v", err)
+	}
+	if len(suppressed) != 1 || suppressed[0].Content != "Use make build" {
+		t.Errorf("expected one suppressed observation, got %+v", suppressed)
+	}
+
+	// Suppressed observations stay in the graph
+	graph, _ := store.ReadGraph()
+	if len(graph.Entities) != 1 || len(graph.Entities[0].Observations) != 2 {
+		t.Errorf("expected suppressed observation to remain in the graph, got %+v", graph.Entities)
+	}
+
+	if err := store.SetObservationSuppressed("Build", "Use make build", false); err != nil {
+		t.Fatalf("unsuppress failed: %v", err)
+	}
+	suppressed, _ = store.ListSuppressedObservations()
+	if len(suppressed) != 0 {
+		t.Errorf("expected no suppressed observations, got %d", len(suppressed))
+	}
+
+	if err := store.SetObservationSuppressed("Build", "missing", true); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
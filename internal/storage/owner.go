@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"context"
+	"strings"
+)
+
+// SetUser sets the user recorded as the owner of the entities and the author
+// of the observations this store writes, for databases a team shares. Empty
+// leaves writes anonymous.
+func (s *Store) SetUser(user string) {
+	s.user = strings.TrimSpace(user)
+}
+
+// User returns the user set with SetUser.
+func (s *Store) User() string {
+	return s.user
+}
+
+// userValue is the user for a write, or nil (NULL) when anonymous.
+func (s *Store) userValue() any {
+	if s.user == "" {
+		return nil
+	}
+	return s.user
+}
+
+// userFilterSQL returns a condition on the entity row alias that holds when
+// user created the entity or wrote one of its observations. An empty user
+// matches every entity.
+func userFilterSQL(alias, user string) (string, []any) {
+	if user == "" {
+		return "1", nil
+	}
+	return `(` + alias + `.owner = ? OR EXISTS (SELECT 1 FROM observations uo
+		WHERE uo.entity_id = ` + alias + `.id AND uo.author = ?))`, []any{user, user}
+}
+
+// EntityFilter selects entities for ListEntitiesWithFilter.
+type EntityFilter struct {
+	Type string // Empty: every type
+	User string // Empty: every user; see userFilterSQL
+}
+
+// ListEntitiesWithFilter returns the latest version of each entity matching
+// filter, by name.
+func (s *Store) ListEntitiesWithFilter(filter EntityFilter) ([]*Entity, error) {
+	return s.ListEntitiesWithFilterContext(context.Background(), filter)
+}
+
+// ListEntitiesWithFilterContext is ListEntitiesWithFilter with a context.
+func (s *Store) ListEntitiesWithFilterContext(ctx context.Context, filter EntityFilter) ([]*Entity, error) {
+	userFilter, args := userFilterSQL("e", filter.User)
+	typeFilter := "1"
+	if filter.Type != "" {
+		typeFilter = "e.entity_type = ?"
+		args = append(args, filter.Type)
+	}
+
+	var entities []Entity
+	if err := s.db.SelectContext(ctx, &entities, `
+		SELECT e.id, e.name, e.entity_type, e.created_at,
+		       COALESCE(e.version, 1) as version,
+		       COALESCE(e.is_latest, 1) as is_latest,
+		       COALESCE(e.supersedes_id, 0) as supersedes_id,
+		       COALESCE(e.owner, '') as owner
+		FROM entities e
+		WHERE (e.is_latest = 1 OR e.is_latest IS NULL) AND `+userFilter+` AND `+typeFilter+`
+		ORDER BY e.name`, args...); err != nil {
+		return nil, err
+	}
+
+	result := make([]*Entity, len(entities))
+	for i := range entities {
+		result[i] = &entities[i]
+	}
+	return result, nil
+}
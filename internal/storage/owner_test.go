@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUserScoping(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+
+	store.SetUser("alice")
+	store.CreateEntity("konfig", "project", []string{"Loads YAML config"})
+	store.SetUser("bob")
+	store.AddObservation("konfig", "Loads TOML config")
+	store.CreateEntity("viper", "library", []string{"Reads config files"})
+	store.SetUser("")
+	store.CreateEntity("anon", "concept", []string{"Nobody's config"})
+
+	names := func(entities []*Entity) []string {
+		var out []string
+		for _, e := range entities {
+			out = append(out, e.Name)
+		}
+		return out
+	}
+	for _, tt := range []struct {
+		user string
+		want []string
+	}{
+		{"alice", []string{"konfig"}},
+		{"bob", []string{"konfig", "viper"}},
+		{"carol", nil},
+		{"", []string{"anon", "konfig", "viper"}},
+	} {
+		got, err := store.ListEntitiesWithFilter(EntityFilter{User: tt.user})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := names(got); !reflect.DeepEqual(n, tt.want) {
+			t.Errorf("entities for %q = %v, want %v", tt.user, n, tt.want)
+		}
+	}
+
+	konfig, _ := store.ListEntitiesWithFilter(EntityFilter{User: "alice"})
+	if len(konfig) != 1 || konfig[0].Owner != "alice" {
+		t.Errorf("expected konfig owned by alice, got %+v", konfig)
+	}
+
+	results, err := store.SearchWithOptions("config", SearchOptions{Limit: 10, User: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Name != "konfig" {
+		t.Errorf("search for alice = %d results, want konfig only", len(results))
+	}
+	if results, _ := store.SearchWithOptions("", SearchOptions{Limit: 10, User: "bob"}); len(results) != 2 {
+		t.Errorf("filter-only search for bob = %d results, want 2", len(results))
+	}
+
+	cfg := DefaultContextConfig()
+	cfg.MinImportance = 0
+	cfg.User = "bob"
+	context, err := store.GetContextForInjection(cfg, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(context) != 2 {
+		t.Errorf("context for bob = %+v, want his 2 observations", context)
+	}
+
+	provenance, _ := store.GetObservationProvenance("konfig")
+	if len(provenance) != 2 || provenance[0].Author != "alice" || provenance[1].Author != "bob" {
+		t.Errorf("authors = %+v", provenance)
+	}
+}
@@ -0,0 +1,208 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// PIIMode decides what an observation write holding probable personal data
+// does.
+type PIIMode string
+
+const (
+	PIIModeOff    PIIMode = "off"    // Don't look; the default
+	PIIModeWarn   PIIMode = "warn"   // Write it, tagged with the kinds found
+	PIIModeStrict PIIMode = "strict" // Reject the write
+)
+
+// Kinds of personal data DetectPII finds.
+const (
+	PIIEmail      = "email"
+	PIIPhone      = "phone"
+	PIICreditCard = "credit_card"
+)
+
+// cardLike matches runs of 13 to 19 digits, optionally grouped.
+var cardLike = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+
+// piiDetectors find candidates for each kind in content with skip's matches
+// blanked out; valid, when set, weeds out candidates that only look the part.
+var piiDetectors = []struct {
+	kind  string
+	re    *regexp.Regexp
+	skip  *regexp.Regexp
+	valid func(string) bool
+}{
+	{PIIEmail, regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`), nil, nil},
+	{PIICreditCard, cardLike, nil, luhnValid},
+	// Separators are required, so version numbers, IDs, and timestamps
+	// don't match; IP addresses fall short of the ten digits. Card numbers
+	// would match in part
+	{PIIPhone, regexp.MustCompile(`(?:\+\d{1,3}[\s.-]?)?(?:\(\d{2,4}\)[\s.-]?|\b\d{2,4}[\s.-])\d{3,4}[\s.-]\d{3,4}\b`), cardLike, phoneValid},
+}
+
+// DetectPII returns the kinds of probable personal data in content: email
+// addresses, phone numbers, and card numbers that pass the Luhn check.
+func DetectPII(content string) []string {
+	var kinds []string
+	for _, d := range piiDetectors {
+		text := content
+		if d.skip != nil {
+			text = d.skip.ReplaceAllLiteralString(content, " ")
+		}
+		for _, m := range d.re.FindAllString(text, -1) {
+			if d.valid == nil || d.valid(m) {
+				kinds = append(kinds, d.kind)
+				break
+			}
+		}
+	}
+	return kinds
+}
+
+// digits returns the decimal digits in s.
+func digits(s string) []byte {
+	var ds []byte
+	for i := 0; i < len(s); i++ {
+		if s[i] >= '0' && s[i] <= '9' {
+			ds = append(ds, s[i]-'0')
+		}
+	}
+	return ds
+}
+
+func phoneValid(s string) bool {
+	n := len(digits(s))
+	return n >= 10 && n <= 15
+}
+
+// luhnValid reports whether the digits in s form a card number: 13 to 19
+// digits passing the Luhn checksum.
+func luhnValid(s string) bool {
+	ds := digits(s)
+	if len(ds) < 13 || len(ds) > 19 {
+		return false
+	}
+	sum := 0
+	for i := range ds {
+		d := int(ds[len(ds)-1-i])
+		if i%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+// SetPIIMode sets what observation writes holding probable personal data
+// do. Imports, replica merges, and entity merges are not checked.
+func (s *Store) SetPIIMode(mode PIIMode) error {
+	switch mode {
+	case "", PIIModeOff, PIIModeWarn, PIIModeStrict:
+	default:
+		return &ValidationError{"pii mode", fmt.Sprintf("must be off, warn, or strict, not %q", mode)}
+	}
+	s.piiMode = mode
+	return nil
+}
+
+// checkPII rejects contents holding probable personal data in strict mode.
+func (s *Store) checkPII(contents ...string) error {
+	if s.piiMode != PIIModeStrict {
+		return nil
+	}
+	for _, content := range contents {
+		if kinds := DetectPII(content); len(kinds) > 0 {
+			return &ValidationError{"observation", "contains probable personal data (" + strings.Join(kinds, ", ") + ")"}
+		}
+	}
+	return nil
+}
+
+// piiTag is the pii column value for content in warn mode: the kinds found,
+// comma-separated, or NULL.
+func (s *Store) piiTag(content string) any {
+	if s.piiMode != PIIModeWarn {
+		return nil
+	}
+	if kinds := DetectPII(content); len(kinds) > 0 {
+		return strings.Join(kinds, ",")
+	}
+	return nil
+}
+
+// tagPII tags observations just added with the personal data they hold, in
+// warn mode.
+func (s *Store) tagPII(ctx context.Context, q interface {
+	ExecContext(context.Context, string, ...any) (sql.Result, error)
+}, added []addedObservation) error {
+	if s.piiMode != PIIModeWarn {
+		return nil
+	}
+	for _, a := range added {
+		tag := s.piiTag(a.content)
+		if tag == nil {
+			continue
+		}
+		if _, err := q.ExecContext(ctx, "UPDATE observations SET pii = ? WHERE entity_id = ? AND content = ?",
+			tag, a.entityID, a.content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PIIExposure is an entity holding observations tagged as personal data.
+type PIIExposure struct {
+	Entity       string   `json:"entity"`
+	Observations int      `json:"observations"`
+	Kinds        []string `json:"kinds"`
+}
+
+// GetPIIExposure returns the entities whose observations were tagged as
+// holding personal data when written in warn mode, most exposed first.
+func (s *Store) GetPIIExposure() ([]PIIExposure, error) {
+	return s.GetPIIExposureContext(context.Background())
+}
+
+// GetPIIExposureContext is GetPIIExposure with a context.
+func (s *Store) GetPIIExposureContext(ctx context.Context) ([]PIIExposure, error) {
+	var rows []struct {
+		Entity string `db:"entity"`
+		PII    string `db:"pii"`
+	}
+	if err := s.db.SelectContext(ctx, &rows, `
+		SELECT e.name AS entity, o.pii
+		FROM observations o
+		JOIN entities e ON e.id = o.entity_id
+		WHERE o.pii IS NOT NULL AND (e.is_latest = 1 OR e.is_latest IS NULL)
+		ORDER BY e.name
+	`); err != nil {
+		return nil, err
+	}
+
+	var exposure []PIIExposure
+	for _, r := range rows {
+		if len(exposure) == 0 || exposure[len(exposure)-1].Entity != r.Entity {
+			exposure = append(exposure, PIIExposure{Entity: r.Entity})
+		}
+		e := &exposure[len(exposure)-1]
+		e.Observations++
+		for _, kind := range strings.Split(r.PII, ",") {
+			if !slices.Contains(e.Kinds, kind) {
+				e.Kinds = append(e.Kinds, kind)
+			}
+		}
+	}
+	for i := range exposure {
+		slices.Sort(exposure[i].Kinds)
+	}
+	slices.SortStableFunc(exposure, func(a, b PIIExposure) int { return b.Observations - a.Observations })
+	return exposure, nil
+}
@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestDetectPII(t *testing.T) {
+	tests := []struct {
+		content string
+		want    []string
+	}{
+		{"Ask jane.doe@example.com for access", []string{PIIEmail}},
+		{"Call +1 415-555-0132 after six", []string{PIIPhone}},
+		{"On-call phone (020) 7946 0958", []string{PIIPhone}},
+		{"Test card 4111 1111 1111 1111 expires soon", []string{PIICreditCard}},
+		{"Mail ops@corp.io or ring 415.555.0132", []string{PIIEmail, PIIPhone}},
+		{"Card 4111 1111 1111 1112 fails the checksum", nil},
+		{"Upgraded to v1.22.3 on 2024-05-01 at 10.0.0.12", nil},
+		{"Issue 12345678 in build 9876543210123", nil},
+		{"Import github.com/jmoiron/sqlx@v1.3.5", nil},
+	}
+	for _, tt := range tests {
+		if got := DetectPII(tt.content); !slices.Equal(got, tt.want) {
+			t.Errorf("DetectPII(%q) = %v, want %v", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestPIIMode(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	if err := store.SetPIIMode("loud"); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("expected an unknown mode rejected as invalid input, got %v", err)
+	}
+
+	// Off by default: written untagged
+	store.CreateEntity("Support", "process", []string{"Escalate to help@example.com"})
+
+	if err := store.SetPIIMode(PIIModeStrict); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.AddObservation("Support", "Customer phone 415-555-0132"); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("expected strict mode to reject PII, got %v", err)
+	}
+	if _, err := store.CreateEntity("Customer", "person", []string{"Lives nearby", "Card 4111111111111111"}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("expected strict mode to reject a new entity holding PII, got %v", err)
+	}
+	results, err := store.AddObservationsBatch([]ObservationSpec{
+		{EntityName: "Support", Content: "Tickets close in a day"},
+		{EntityName: "Support", Content: "Owner is bob@example.com"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !results[0].Added || !errors.Is(results[1].Err, ErrInvalidInput) {
+		t.Errorf("expected only the PII item rejected, got %+v", results)
+	}
+	if err := store.UpdateObservation("Support", "Tickets close in a day", "Call 415-555-0132"); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("expected strict mode to reject an edit adding PII, got %v", err)
+	}
+
+	if err := store.SetPIIMode(PIIModeWarn); err != nil {
+		t.Fatal(err)
+	}
+	store.AddObservation("Support", "Customer phone 415-555-0132")
+	store.CreateEntity("Customer", "person", []string{"Lives nearby", "Card 4111111111111111, mail c@example.com"})
+	store.UpdateObservation("Support", "Tickets close in a day", "Tickets go to triage@example.com")
+
+	stats, err := store.GetDatabaseStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.PII != 3 {
+		t.Errorf("PII = %d, want 3", stats.PII)
+	}
+	wantKinds := []StatCount{{PIIEmail, 2}, {PIICreditCard, 1}, {PIIPhone, 1}}
+	if !slices.Equal(stats.PIIByKind, wantKinds) {
+		t.Errorf("PIIByKind = %v, want %v", stats.PIIByKind, wantKinds)
+	}
+
+	report, err := store.CheckIntegrity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []PIIExposure{
+		{Entity: "Support", Observations: 2, Kinds: []string{PIIEmail, PIIPhone}},
+		{Entity: "Customer", Observations: 1, Kinds: []string{PIICreditCard, PIIEmail}},
+	}
+	if len(report.PIIExposure) != len(want) {
+		t.Fatalf("PIIExposure = %+v, want %+v", report.PIIExposure, want)
+	}
+	for i, e := range report.PIIExposure {
+		if e.Entity != want[i].Entity || e.Observations != want[i].Observations || !slices.Equal(e.Kinds, want[i].Kinds) {
+			t.Errorf("PIIExposure[%d] = %+v, want %+v", i, e, want[i])
+		}
+	}
+	if !report.OK() {
+		t.Error("expected PII exposure not to fail the check")
+	}
+
+	// Editing the PII away clears the tag
+	store.UpdateObservation("Customer", "Card 4111111111111111, mail c@example.com", "Pays by invoice")
+	if stats, _ := store.GetDatabaseStats(); stats.PII != 2 {
+		t.Errorf("PII = %d after the edit, want 2", stats.PII)
+	}
+}
@@ -0,0 +1,313 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Policy is an automation rule: observations its When condition matches get
+// its Set changes, as they are written and whenever ApplyPolicies sweeps the
+// store. See policy_expr.go for the condition language.
+type Policy struct {
+	Name string        `json:"name"`
+	When string        `json:"when"` // e.g. "fact_type == 'dynamic' && content contains 'TODO'"
+	Set  PolicyChanges `json:"set"`
+
+	when policyExpr
+}
+
+// PolicyChanges are what a policy sets on the observations it matches.
+// Unset fields are left alone.
+type PolicyChanges struct {
+	// When to forget the observation, relative to when it was written:
+	// "+14d", "+36h", or "never" to keep it
+	ForgetAfter string   `json:"forgetAfter,omitempty"`
+	Pinned      *bool    `json:"pinned,omitempty"`
+	Importance  *float64 `json:"importance,omitempty"`
+	FactType    FactType `json:"factType,omitempty"`
+}
+
+// PolicyChange is an observation ApplyPolicies changed, or would change.
+type PolicyChange struct {
+	Entity   string   `json:"entity"`
+	Content  string   `json:"content"`
+	Policies []string `json:"policies"` // Names of the policies that changed it
+	Set      []string `json:"set"`      // e.g. "pinned=true", "forget_after=2026-10-30 09:00:00"
+}
+
+// compile parses the policy's condition and checks its changes.
+func (p *Policy) compile() error {
+	name := cmp.Or(p.Name, p.When)
+	if p.When == "" {
+		return &ValidationError{"policy " + name, "needs a when condition"}
+	}
+	when, err := parsePolicyExpr(p.When)
+	if err != nil {
+		return &ValidationError{"policy " + name, "condition " + err.Error()}
+	}
+	set := p.Set
+	if set.ForgetAfter == "" && set.Pinned == nil && set.Importance == nil && set.FactType == "" {
+		return &ValidationError{"policy " + name, "sets nothing"}
+	}
+	if set.ForgetAfter != "" {
+		if _, _, err := parsePolicyOffset(set.ForgetAfter); err != nil {
+			return &ValidationError{"policy " + name, err.Error()}
+		}
+	}
+	if set.Importance != nil && (*set.Importance < 0 || *set.Importance > 1) {
+		return &ValidationError{"policy " + name, "importance must be from 0 to 1"}
+	}
+	if set.FactType != "" && !slices.Contains(factTypes, set.FactType) {
+		return &ValidationError{"policy " + name, fmt.Sprintf("sets unknown fact type %q", set.FactType)}
+	}
+	p.when = when
+	return nil
+}
+
+// parsePolicyOffset reads a forgetAfter value: "+14d", "+36h", a Go
+// duration, or "never", which clears it.
+func parsePolicyOffset(value string) (offset time.Duration, never bool, err error) {
+	if value == "never" {
+		return 0, true, nil
+	}
+	v := strings.TrimPrefix(value, "+")
+	if days, ok := strings.CutSuffix(v, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, false, fmt.Errorf("forgetAfter %q: days must be a positive whole number", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, false, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, false, fmt.Errorf("forgetAfter %q must be like +14d, +36h, or never", value)
+	}
+	return d, false, nil
+}
+
+// LoadPolicies reads a JSON array of policies from a file.
+func LoadPolicies(path string) ([]Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var policies []Policy
+	if err := json.Unmarshal(data, &policies); err != nil {
+		return nil, fmt.Errorf("reading policies %s: %w", path, err)
+	}
+	return policies, nil
+}
+
+// SetPolicies replaces the policies applied to observations as they are
+// written, after checking them; on error the previous policies stay.
+func (s *Store) SetPolicies(policies []Policy) error {
+	compiled := slices.Clone(policies)
+	for i := range compiled {
+		if err := compiled[i].compile(); err != nil {
+			return err
+		}
+	}
+	s.policies = compiled
+	return nil
+}
+
+// Policies returns the policies SetPolicies set.
+func (s *Store) Policies() []Policy {
+	return s.policies
+}
+
+// policyRow is an observation policies are evaluated against.
+type policyRow struct {
+	ID          int64        `db:"id"`
+	Entity      string       `db:"entity"`
+	EntityType  string       `db:"entity_type"`
+	Content     string       `db:"content"`
+	FactType    string       `db:"fact_type"`
+	Source      string       `db:"source"`
+	Importance  float64      `db:"importance"`
+	Pinned      bool         `db:"pinned"`
+	CreatedAt   time.Time    `db:"created_at"`
+	ForgetAfter sql.NullTime `db:"forget_after"`
+}
+
+// policyRowColumns selects a policyRow from observations o joined to entities e.
+const policyRowColumns = `o.id, e.name AS entity, e.entity_type, o.content,
+	COALESCE(o.fact_type, 'dynamic') AS fact_type, COALESCE(o.source, '') AS source,
+	COALESCE(o.importance, 1.0) AS importance, COALESCE(o.pinned, 0) AS pinned,
+	o.created_at, o.forget_after`
+
+// policyUpdate evaluates the policies against row and returns the change
+// that brings it in line with those matching, or nil when it is already.
+// Later policies win where they set the same field.
+func (s *Store) policyUpdate(row policyRow, now time.Time) *PolicyChange {
+	subject := policySubject{
+		Entity: row.Entity, EntityType: row.EntityType, Content: row.Content, FactType: row.FactType,
+		Source: row.Source, Importance: row.Importance, Pinned: row.Pinned,
+		AgeDays: now.Sub(row.CreatedAt).Hours() / 24,
+	}
+	set := map[string]string{}
+	var by []string
+	for _, p := range s.policies {
+		if !p.when.eval(subject).(bool) {
+			continue
+		}
+		changed := false
+		if p.Set.ForgetAfter != "" {
+			offset, never, _ := parsePolicyOffset(p.Set.ForgetAfter)
+			switch {
+			case never && row.ForgetAfter.Valid:
+				set["forget_after"], changed = "", true
+			case !never:
+				at := sqlTime(row.CreatedAt.Add(offset))
+				if !row.ForgetAfter.Valid || sqlTime(row.ForgetAfter.Time) != at {
+					set["forget_after"], changed = at, true
+				}
+			}
+		}
+		if p.Set.Pinned != nil && *p.Set.Pinned != row.Pinned {
+			set["pinned"], changed = strconv.FormatBool(*p.Set.Pinned), true
+		}
+		if p.Set.Importance != nil && *p.Set.Importance != row.Importance {
+			set["importance"], changed = strconv.FormatFloat(*p.Set.Importance, 'g', -1, 64), true
+		}
+		if p.Set.FactType != "" && string(p.Set.FactType) != row.FactType {
+			set["fact_type"], changed = string(p.Set.FactType), true
+		}
+		if changed {
+			by = append(by, cmp.Or(p.Name, p.When))
+		}
+	}
+	if len(set) == 0 {
+		return nil
+	}
+	change := &PolicyChange{Entity: row.Entity, Content: row.Content, Policies: by}
+	for _, column := range slices.Sorted(maps.Keys(set)) {
+		change.Set = append(change.Set, column+"="+cmp.Or(set[column], "never"))
+	}
+	return change
+}
+
+// applyPolicyChange writes change to the observation with id.
+func applyPolicyChange(ctx context.Context, q interface {
+	ExecContext(context.Context, string, ...any) (sql.Result, error)
+}, id int64, change *PolicyChange) error {
+	var sets []string
+	var args []any
+	for _, s := range change.Set {
+		column, value, _ := strings.Cut(s, "=")
+		sets = append(sets, column+" = ?")
+		switch {
+		case column == "forget_after" && value == "never":
+			args = append(args, nil)
+		case column == "pinned":
+			args = append(args, value == "true")
+		case column == "importance":
+			importance, _ := strconv.ParseFloat(value, 64)
+			args = append(args, importance)
+		default:
+			args = append(args, value)
+		}
+	}
+	_, err := q.ExecContext(ctx, "UPDATE observations SET "+strings.Join(sets, ", ")+" WHERE id = ?", append(args, id)...)
+	return err
+}
+
+// applyPoliciesOnWrite applies the policies to observations just added, in
+// the write's transaction.
+func (s *Store) applyPoliciesOnWrite(ctx context.Context, q interface {
+	ExecContext(context.Context, string, ...any) (sql.Result, error)
+	QueryContext(context.Context, string, ...any) (*sql.Rows, error)
+}, added []addedObservation) error {
+	if len(s.policies) == 0 {
+		return nil
+	}
+	now := time.Now()
+	for _, a := range added {
+		rows, err := q.QueryContext(ctx, `
+			SELECT `+policyRowColumns+`
+			FROM observations o JOIN entities e ON e.id = o.entity_id
+			WHERE o.entity_id = ? AND o.content = ?`, a.entityID, a.content)
+		if err != nil {
+			return err
+		}
+		var found []policyRow
+		for rows.Next() {
+			var row policyRow
+			if err := rows.Scan(&row.ID, &row.Entity, &row.EntityType, &row.Content, &row.FactType,
+				&row.Source, &row.Importance, &row.Pinned, &row.CreatedAt, &row.ForgetAfter); err != nil {
+				rows.Close()
+				return err
+			}
+			found = append(found, row)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		for _, row := range found {
+			if change := s.policyUpdate(row, now); change != nil {
+				if err := applyPolicyChange(ctx, q, row.ID, change); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// ApplyPolicies evaluates the policies against every observation of the
+// latest entities and brings those they match in line, returning what
+// changed. Run periodically, it applies conditions on age_days and policies
+// added after observations were written. With dryRun it only reports.
+func (s *Store) ApplyPolicies(dryRun bool) ([]PolicyChange, error) {
+	return s.ApplyPoliciesContext(context.Background(), dryRun)
+}
+
+// ApplyPoliciesContext is ApplyPolicies with a context.
+func (s *Store) ApplyPoliciesContext(ctx context.Context, dryRun bool) ([]PolicyChange, error) {
+	if len(s.policies) == 0 {
+		return nil, nil
+	}
+	var rows []policyRow
+	if err := s.db.SelectContext(ctx, &rows, `
+		SELECT `+policyRowColumns+`
+		FROM observations o JOIN entities e ON e.id = o.entity_id
+		WHERE e.is_latest = 1 AND COALESCE(o.suppressed, 0) = 0
+		ORDER BY e.name, o.id`); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	var changes []PolicyChange
+	for _, row := range rows {
+		change := s.policyUpdate(row, now)
+		if change == nil {
+			continue
+		}
+		changes = append(changes, *change)
+		if !dryRun {
+			if err := applyPolicyChange(ctx, tx, row.ID, change); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if dryRun {
+		return changes, nil
+	}
+	return changes, tx.Commit()
+}
@@ -0,0 +1,354 @@
+package storage
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Policy conditions are small expressions over an observation's fields:
+//
+//	fact_type == 'dynamic' && content contains 'TODO'
+//	entity_type == "decision" || (importance >= 0.8 && !pinned)
+//	age_days > 30 and content matches '(?i)\bwip\b'
+//
+// Operators, loosest first: || (or), && (and), ! (not), then the
+// comparisons == != < <= > >= and the string tests contains, startswith,
+// and matches (a Go regular expression). Operands are fields, 'single' or
+// "double" quoted strings, numbers, and true and false. Types are checked
+// when the condition is parsed, so evaluating one cannot fail.
+
+// Value types of policy expressions.
+const (
+	policyString = "string"
+	policyNumber = "number"
+	policyBool   = "bool"
+)
+
+// policyFields are the fields conditions can name, with their types.
+var policyFields = map[string]string{
+	"entity":      policyString, // Entity name
+	"entity_type": policyString,
+	"content":     policyString,
+	"fact_type":   policyString,
+	"source":      policyString, // What wrote the observation, e.g. "mcp:remember"
+	"importance":  policyNumber,
+	"pinned":      policyBool,
+	"age_days":    policyNumber, // Days since the observation was written
+}
+
+// policySubject is the observation a condition is evaluated against.
+type policySubject struct {
+	Entity     string
+	EntityType string
+	Content    string
+	FactType   string
+	Source     string
+	Importance float64
+	Pinned     bool
+	AgeDays    float64
+}
+
+func (s policySubject) field(name string) any {
+	switch name {
+	case "entity":
+		return s.Entity
+	case "entity_type":
+		return s.EntityType
+	case "content":
+		return s.Content
+	case "fact_type":
+		return s.FactType
+	case "source":
+		return s.Source
+	case "importance":
+		return s.Importance
+	case "pinned":
+		return s.Pinned
+	case "age_days":
+		return s.AgeDays
+	}
+	return nil
+}
+
+// policyExpr is a parsed policy expression.
+type policyExpr interface {
+	eval(s policySubject) any
+	typ() string
+}
+
+type policyLiteral struct{ value any }
+
+func (e policyLiteral) eval(policySubject) any { return e.value }
+func (e policyLiteral) typ() string {
+	switch e.value.(type) {
+	case string:
+		return policyString
+	case float64:
+		return policyNumber
+	}
+	return policyBool
+}
+
+type policyField struct{ name string }
+
+func (e policyField) eval(s policySubject) any { return s.field(e.name) }
+func (e policyField) typ() string              { return policyFields[e.name] }
+
+type policyNot struct{ x policyExpr }
+
+func (e policyNot) eval(s policySubject) any { return !e.x.eval(s).(bool) }
+func (e policyNot) typ() string              { return policyBool }
+
+type policyLogic struct {
+	and         bool
+	left, right policyExpr
+}
+
+func (e policyLogic) eval(s policySubject) any {
+	if e.and {
+		return e.left.eval(s).(bool) && e.right.eval(s).(bool)
+	}
+	return e.left.eval(s).(bool) || e.right.eval(s).(bool)
+}
+func (e policyLogic) typ() string { return policyBool }
+
+type policyCompare struct {
+	op          string
+	left, right policyExpr
+	re          *regexp.Regexp // For matches
+}
+
+func (e policyCompare) eval(s policySubject) any {
+	l, r := e.left.eval(s), e.right.eval(s)
+	switch e.op {
+	case "==":
+		return l == r
+	case "!=":
+		return l != r
+	case "contains":
+		return strings.Contains(l.(string), r.(string))
+	case "startswith":
+		return strings.HasPrefix(l.(string), r.(string))
+	case "matches":
+		return e.re.MatchString(l.(string))
+	}
+	a, b := l.(float64), r.(float64)
+	switch e.op {
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	default:
+		return a >= b
+	}
+}
+func (e policyCompare) typ() string { return policyBool }
+
+// parsePolicyExpr parses a condition, which must be true or false.
+func parsePolicyExpr(src string) (policyExpr, error) {
+	tokens, err := lexPolicyExpr(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &policyParser{tokens: tokens}
+	expr, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q", p.tokens[p.pos].text)
+	}
+	if expr.typ() != policyBool {
+		return nil, fmt.Errorf("is a %s, not true or false", expr.typ())
+	}
+	return expr, nil
+}
+
+// policyToken is a token of a policy expression; quoted strings keep their
+// value in text and are marked str.
+type policyToken struct {
+	text string
+	str  bool
+}
+
+// lexPolicyExpr splits src into tokens.
+func lexPolicyExpr(src string) ([]policyToken, error) {
+	var tokens []policyToken
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(src[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			tokens = append(tokens, policyToken{text: src[i+1 : i+1+end], str: true})
+			i += end + 2
+		case strings.HasPrefix(src[i:], "&&"), strings.HasPrefix(src[i:], "||"),
+			strings.HasPrefix(src[i:], "=="), strings.HasPrefix(src[i:], "!="),
+			strings.HasPrefix(src[i:], "<="), strings.HasPrefix(src[i:], ">="):
+			tokens = append(tokens, policyToken{text: src[i : i+2]})
+			i += 2
+		case strings.IndexByte("()!<>", c) >= 0:
+			tokens = append(tokens, policyToken{text: src[i : i+1]})
+			i++
+		case c == '-' || c == '.' || c == '_' || c < 0x80 && (unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c))):
+			j := i + 1
+			for j < len(src) && (src[j] == '.' || src[j] == '_' || src[j] < 0x80 && (unicode.IsLetter(rune(src[j])) || unicode.IsDigit(rune(src[j])))) {
+				j++
+			}
+			tokens = append(tokens, policyToken{text: src[i:j]})
+			i = j
+		default:
+			return nil, fmt.Errorf("unexpected %q at %d", c, i)
+		}
+	}
+	return tokens, nil
+}
+
+// policyParser parses tokens by recursive descent.
+type policyParser struct {
+	tokens []policyToken
+	pos    int
+}
+
+// accept consumes the next token if it is an operator or keyword among ops.
+func (p *policyParser) accept(ops ...string) (string, bool) {
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].str {
+		return "", false
+	}
+	for _, op := range ops {
+		if p.tokens[p.pos].text == op {
+			p.pos++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *policyParser) or() (policyExpr, error) {
+	left, err := p.and()
+	for err == nil {
+		if _, ok := p.accept("||", "or"); !ok {
+			break
+		}
+		var right policyExpr
+		if right, err = p.and(); err == nil {
+			left, err = logicalExpr(false, left, right)
+		}
+	}
+	return left, err
+}
+
+func (p *policyParser) and() (policyExpr, error) {
+	left, err := p.not()
+	for err == nil {
+		if _, ok := p.accept("&&", "and"); !ok {
+			break
+		}
+		var right policyExpr
+		if right, err = p.not(); err == nil {
+			left, err = logicalExpr(true, left, right)
+		}
+	}
+	return left, err
+}
+
+func logicalExpr(and bool, left, right policyExpr) (policyExpr, error) {
+	if left.typ() != policyBool || right.typ() != policyBool {
+		return nil, fmt.Errorf("and/or need true or false on both sides")
+	}
+	return policyLogic{and: and, left: left, right: right}, nil
+}
+
+func (p *policyParser) not() (policyExpr, error) {
+	if _, ok := p.accept("!", "not"); ok {
+		x, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		if x.typ() != policyBool {
+			return nil, fmt.Errorf("! needs true or false")
+		}
+		return policyNot{x}, nil
+	}
+	return p.compare()
+}
+
+func (p *policyParser) compare() (policyExpr, error) {
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.accept("==", "!=", "<", "<=", ">", ">=", "contains", "startswith", "matches")
+	if !ok {
+		return left, nil
+	}
+	right, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+
+	expr := policyCompare{op: op, left: left, right: right}
+	switch op {
+	case "==", "!=":
+		if left.typ() != right.typ() {
+			return nil, fmt.Errorf("%s compares a %s with a %s", op, left.typ(), right.typ())
+		}
+	case "contains", "startswith", "matches":
+		if left.typ() != policyString || right.typ() != policyString {
+			return nil, fmt.Errorf("%s needs strings", op)
+		}
+		if op == "matches" {
+			lit, ok := right.(policyLiteral)
+			if !ok {
+				return nil, fmt.Errorf("matches needs a quoted pattern")
+			}
+			if expr.re, err = regexp.Compile(lit.value.(string)); err != nil {
+				return nil, fmt.Errorf("matches: %w", err)
+			}
+		}
+	default:
+		if left.typ() != policyNumber || right.typ() != policyNumber {
+			return nil, fmt.Errorf("%s needs numbers", op)
+		}
+	}
+	return expr, nil
+}
+
+func (p *policyParser) operand() (policyExpr, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("ends early")
+	}
+	tok := p.tokens[p.pos]
+	p.pos++
+	switch {
+	case tok.str:
+		return policyLiteral{tok.text}, nil
+	case tok.text == "(":
+		expr, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := p.accept(")"); !ok {
+			return nil, fmt.Errorf("missing )")
+		}
+		return expr, nil
+	case tok.text == "true" || tok.text == "false":
+		return policyLiteral{tok.text == "true"}, nil
+	}
+	if n, err := strconv.ParseFloat(tok.text, 64); err == nil {
+		return policyLiteral{n}, nil
+	}
+	if _, ok := policyFields[tok.text]; ok {
+		return policyField{tok.text}, nil
+	}
+	return nil, fmt.Errorf("unknown field %q", tok.text)
+}
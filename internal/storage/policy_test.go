@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePolicyExpr(t *testing.T) {
+	subject := policySubject{
+		Entity: "mark42", EntityType: "project", Content: "TODO: ship the daemon",
+		FactType: "dynamic", Importance: 0.6, AgeDays: 40,
+	}
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"fact_type == 'dynamic' && content contains 'TODO'", true},
+		{`entity_type == "decision" || (importance >= 0.5 && !pinned)`, true},
+		{"age_days > 30 and content matches '(?i)ship'", true},
+		{"not pinned and content startswith 'TODO'", true},
+		{"importance < 0.5 or entity != 'mark42'", false},
+		{"pinned == false && age_days <= 40", true},
+	}
+	for _, tt := range tests {
+		expr, err := parsePolicyExpr(tt.expr)
+		if err != nil {
+			t.Errorf("parsePolicyExpr(%q) failed: %v", tt.expr, err)
+			continue
+		}
+		if got := expr.eval(subject).(bool); got != tt.want {
+			t.Errorf("%q = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{
+		"content",                   // Not true or false
+		"importance == 'high'",      // Mixed types
+		"content > 3",               // Numbers only
+		"owner == 'me'",             // Unknown field
+		"content contains 'TODO",    // Unterminated
+		"(pinned",                   // Unbalanced
+		"content matches '('",       // Bad pattern
+		"pinned && importance",      // Not a condition
+		"fact_type == 'dynamic' ++", // Stray operator
+	} {
+		if _, err := parsePolicyExpr(bad); err == nil {
+			t.Errorf("parsePolicyExpr(%q) should fail", bad)
+		}
+	}
+}
+
+func TestSetPolicies_Invalid(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	yes := true
+	for _, p := range []Policy{
+		{Name: "no condition", Set: PolicyChanges{Pinned: &yes}},
+		{Name: "no changes", When: "pinned"},
+		{Name: "bad offset", When: "pinned", Set: PolicyChanges{ForgetAfter: "soon"}},
+		{Name: "bad type", When: "pinned", Set: PolicyChanges{FactType: "rumor"}},
+	} {
+		if err := store.SetPolicies([]Policy{p}); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("%s: expected ErrInvalidInput, got %v", p.Name, err)
+		}
+	}
+}
+
+func TestPolicies_OnWrite(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	yes := true
+	if err := store.SetPolicies([]Policy{
+		{Name: "expire todos", When: "fact_type == 'dynamic' && content contains 'TODO'", Set: PolicyChanges{ForgetAfter: "+14d"}},
+		{Name: "pin decisions", When: "entity_type == 'decision'", Set: PolicyChanges{Pinned: &yes}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.CreateEntity("Use SQLite", "decision", []string{"Chosen for zero setup"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.CreateEntity("mark42", "project", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.AddObservation("mark42", "TODO: write the daemon"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.AddObservation("mark42", "Written in Go"); err != nil {
+		t.Fatal(err)
+	}
+
+	var pinned bool
+	if err := store.db.Get(&pinned, "SELECT pinned FROM observations WHERE content = 'Chosen for zero setup'"); err != nil || !pinned {
+		t.Errorf("expected the decision pinned, got %v (%v)", pinned, err)
+	}
+	var days float64
+	if err := store.db.Get(&days, `SELECT julianday(forget_after) - julianday(created_at)
+		FROM observations WHERE content = 'TODO: write the daemon'`); err != nil || days < 13.99 || days > 14.01 {
+		t.Errorf("expected the TODO forgotten in 14 days, got %v (%v)", days, err)
+	}
+	var untouched int
+	if err := store.db.Get(&untouched, `SELECT COUNT(*) FROM observations
+		WHERE content = 'Written in Go' AND forget_after IS NULL AND COALESCE(pinned, 0) = 0`); err != nil || untouched != 1 {
+		t.Errorf("expected other observations untouched, got %d (%v)", untouched, err)
+	}
+
+	if err := store.UpdateObservation("mark42", "Written in Go", "TODO: port the CLI"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.db.Get(&days, `SELECT julianday(forget_after) - julianday(created_at)
+		FROM observations WHERE content = 'TODO: port the CLI'`); err != nil || days < 13.99 {
+		t.Errorf("expected policies applied to edited content, got %v (%v)", days, err)
+	}
+}
+
+func TestApplyPolicies(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	if _, err := store.CreateEntity("mark42", "project", []string{"WIP: session notes", "Written in Go"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.db.Exec("UPDATE observations SET created_at = datetime('now', '-40 days') WHERE content LIKE 'WIP%'"); err != nil {
+		t.Fatal(err)
+	}
+
+	low := 0.1
+	if err := store.SetPolicies([]Policy{
+		{Name: "fade stale wip", When: "age_days > 30 && content startswith 'WIP'", Set: PolicyChanges{Importance: &low, ForgetAfter: "+60d"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := store.ApplyPolicies(true)
+	if err != nil {
+		t.Fatalf("ApplyPolicies dry run failed: %v", err)
+	}
+	if len(changes) != 1 || changes[0].Content != "WIP: session notes" || changes[0].Policies[0] != "fade stale wip" {
+		t.Fatalf("expected the stale WIP observation, got %+v", changes)
+	}
+	if !strings.HasPrefix(changes[0].Set[0], "forget_after=") || changes[0].Set[1] != "importance=0.1" {
+		t.Errorf("unexpected changes %v", changes[0].Set)
+	}
+	var importance float64
+	if err := store.db.Get(&importance, "SELECT importance FROM observations WHERE content LIKE 'WIP%'"); err != nil || importance != 1 {
+		t.Errorf("dry run should change nothing, importance %v (%v)", importance, err)
+	}
+
+	if _, err := store.ApplyPolicies(false); err != nil {
+		t.Fatalf("ApplyPolicies failed: %v", err)
+	}
+	if err := store.db.Get(&importance, "SELECT importance FROM observations WHERE content LIKE 'WIP%'"); err != nil || importance != 0.1 {
+		t.Errorf("expected importance 0.1, got %v (%v)", importance, err)
+	}
+	var forgetAfter time.Time
+	if err := store.db.Get(&forgetAfter, "SELECT forget_after FROM observations WHERE content LIKE 'WIP%'"); err != nil ||
+		time.Until(forgetAfter) < 19*24*time.Hour || time.Until(forgetAfter) > 21*24*time.Hour {
+		t.Errorf("expected forget_after 60 days after writing, got %v (%v)", forgetAfter, err)
+	}
+
+	if changes, err := store.ApplyPolicies(false); err != nil || len(changes) != 0 {
+		t.Errorf("expected a second sweep to change nothing, got %+v (%v)", changes, err)
+	}
+}
@@ -0,0 +1,460 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// postgresDriver is the database/sql driver name registered by lib/pq,
+// compiled in with the postgres build tag (see driver_postgres.go).
+const postgresDriver = "postgres"
+
+// ErrPostgresUnavailable is returned for postgres:// DSNs when the binary
+// was built without the Postgres driver.
+var ErrPostgresUnavailable = errors.New("postgres:// databases need a build with -tags postgres")
+
+// ErrPostgresStore is returned when a postgres:// DSN is opened as a SQLite
+// Store. Postgres databases open with OpenBackend, which serves the core API
+// of pkg/memory only; the CLI and MCP server, built on the SQLite features,
+// refuse them.
+var ErrPostgresStore = errors.New("postgres:// databases are supported only through the pkg/memory Go API, not the mark42 CLI or MCP server")
+
+// IsPostgresDSN reports whether dsn names a Postgres database.
+func IsPostgresDSN(dsn string) bool {
+	lower := strings.ToLower(dsn)
+	return strings.HasPrefix(lower, "postgres://") || strings.HasPrefix(lower, "postgresql://")
+}
+
+// postgresSchema creates the Postgres schema: the graph tables of the SQLite
+// schema, with generated tsvector columns for full-text search and a
+// pgvector column for embeddings. Observations are unique per entity by
+// content hash, as long contents exceed a btree key.
+const postgresSchema = `
+CREATE EXTENSION IF NOT EXISTS vector;
+
+CREATE TABLE IF NOT EXISTS entities (
+	id BIGSERIAL PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE,
+	entity_type TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	search tsvector GENERATED ALWAYS AS (to_tsvector('simple', name)) STORED
+);
+CREATE INDEX IF NOT EXISTS idx_entities_search ON entities USING GIN (search);
+CREATE INDEX IF NOT EXISTS idx_entities_type ON entities (entity_type);
+
+CREATE TABLE IF NOT EXISTS observations (
+	id BIGSERIAL PRIMARY KEY,
+	entity_id BIGINT NOT NULL REFERENCES entities(id) ON DELETE CASCADE,
+	content TEXT NOT NULL,
+	fact_type TEXT NOT NULL DEFAULT 'dynamic',
+	importance DOUBLE PRECISION NOT NULL DEFAULT 1.0,
+	pinned BOOLEAN NOT NULL DEFAULT false,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	last_accessed TIMESTAMPTZ,
+	search tsvector GENERATED ALWAYS AS (to_tsvector('english', content)) STORED
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_observations_content ON observations (entity_id, md5(content));
+CREATE INDEX IF NOT EXISTS idx_observations_search ON observations USING GIN (search);
+
+CREATE TABLE IF NOT EXISTS relations (
+	from_entity_id BIGINT NOT NULL REFERENCES entities(id) ON DELETE CASCADE,
+	to_entity_id BIGINT NOT NULL REFERENCES entities(id) ON DELETE CASCADE,
+	relation_type TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	PRIMARY KEY (from_entity_id, to_entity_id, relation_type)
+);
+CREATE INDEX IF NOT EXISTS idx_relations_to ON relations (to_entity_id);
+
+CREATE TABLE IF NOT EXISTS observation_embeddings (
+	observation_id BIGINT PRIMARY KEY REFERENCES observations(id) ON DELETE CASCADE,
+	model TEXT NOT NULL,
+	embedding vector NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+`
+
+// PostgresStore keeps memory in a Postgres database, so many agents on many
+// machines can share one memory service: Postgres serializes their writes,
+// tsvector columns back full-text search, and pgvector backs vector search.
+// It implements Backend; the features beyond it are SQLite-only, and context
+// injection skips cfg.Dedup and cfg.User.
+type PostgresStore struct {
+	db         *sqlx.DB
+	importance ImportanceConfig
+	tokenizer  Tokenizer
+}
+
+// NewPostgresStore opens the Postgres database at dsn, creating the schema
+// and the pgvector extension when missing.
+func NewPostgresStore(dsn string) (*PostgresStore, error) {
+	if !slices.Contains(sql.Drivers(), postgresDriver) {
+		return nil, ErrPostgresUnavailable
+	}
+	db, err := sqlx.Open(postgresDriver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("opening postgres database: %w", err)
+	}
+	if _, err := db.Exec(postgresSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creating postgres schema: %w", err)
+	}
+	return &PostgresStore{db: db, importance: DefaultImportanceConfig(), tokenizer: DefaultTokenizer()}, nil
+}
+
+// SetTokenizer selects the tokenizer context budgets are counted with.
+// A nil tokenizer restores DefaultTokenizer.
+func (p *PostgresStore) SetTokenizer(t Tokenizer) {
+	if t == nil {
+		t = DefaultTokenizer()
+	}
+	p.tokenizer = t
+}
+
+// Close closes the database.
+func (p *PostgresStore) Close() error {
+	return p.db.Close()
+}
+
+// CreateEntityContext creates a new entity with optional observations.
+// Returns ErrEntityExists if an entity with this name already exists.
+func (p *PostgresStore) CreateEntityContext(ctx context.Context, name, entityType string, observations []string) (*Entity, error) {
+	if err := validateEntityWithObservations(name, entityType, observations); err != nil {
+		return nil, err
+	}
+	name = NormalizeName(name)
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	entity := &Entity{Name: name, Type: entityType, Observations: observations, Version: 1, IsLatest: true}
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO entities (name, entity_type) VALUES ($1, $2)
+		ON CONFLICT (name) DO NOTHING
+		RETURNING id, created_at`, name, entityType).Scan(&entity.ID, &entity.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, entityExists(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, obs := range observations {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO observations (entity_id, content) VALUES ($1, $2)
+			ON CONFLICT (entity_id, md5(content)) DO NOTHING`, entity.ID, obs); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return entity, nil
+}
+
+// GetEntityContext retrieves an entity by name, including its observations.
+func (p *PostgresStore) GetEntityContext(ctx context.Context, name string) (*Entity, error) {
+	entity := Entity{Version: 1, IsLatest: true}
+	err := p.db.GetContext(ctx, &entity,
+		"SELECT id, name, entity_type, created_at FROM entities WHERE name = $1", NormalizeName(name))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, entityNotFound(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = p.db.SelectContext(ctx, &entity.Observations,
+		"SELECT content FROM observations WHERE entity_id = $1 ORDER BY created_at, id", entity.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &entity, nil
+}
+
+// ListEntitiesContext returns all entities with their observations by name,
+// optionally filtered by type.
+func (p *PostgresStore) ListEntitiesContext(ctx context.Context, entityType string) ([]*Entity, error) {
+	var entities []*Entity
+	err := p.db.SelectContext(ctx, &entities, `
+		SELECT id, name, entity_type, created_at FROM entities
+		WHERE $1 = '' OR entity_type = $1
+		ORDER BY name`, entityType)
+	if err != nil {
+		return nil, err
+	}
+
+	var observations []struct {
+		EntityID int64  `db:"entity_id"`
+		Content  string `db:"content"`
+	}
+	err = p.db.SelectContext(ctx, &observations, `
+		SELECT o.entity_id, o.content FROM observations o
+		JOIN entities e ON e.id = o.entity_id
+		WHERE $1 = '' OR e.entity_type = $1
+		ORDER BY o.created_at, o.id`, entityType)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int64]*Entity, len(entities))
+	for _, entity := range entities {
+		entity.Version, entity.IsLatest = 1, true
+		byID[entity.ID] = entity
+	}
+	for _, obs := range observations {
+		if entity := byID[obs.EntityID]; entity != nil {
+			entity.Observations = append(entity.Observations, obs.Content)
+		}
+	}
+	return entities, nil
+}
+
+// DeleteEntityContext removes an entity with its observations, embeddings,
+// and relations.
+func (p *PostgresStore) DeleteEntityContext(ctx context.Context, name string) error {
+	result, err := p.db.ExecContext(ctx, "DELETE FROM entities WHERE name = $1", NormalizeName(name))
+	if err != nil {
+		return err
+	}
+	if rowsAffected(result) == 0 {
+		return entityNotFound(name)
+	}
+	return nil
+}
+
+// entityID returns the ID of the named entity.
+func (p *PostgresStore) entityID(ctx context.Context, name string) (int64, error) {
+	var id int64
+	err := p.db.GetContext(ctx, &id, "SELECT id FROM entities WHERE name = $1", NormalizeName(name))
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, entityNotFound(name)
+	}
+	return id, err
+}
+
+// AddObservationContext adds an observation to an existing entity; adding
+// one it has already is a no-op.
+func (p *PostgresStore) AddObservationContext(ctx context.Context, entityName, content string) error {
+	if err := ValidateObservation(content); err != nil {
+		return err
+	}
+	entityID, err := p.entityID(ctx, entityName)
+	if err != nil {
+		return err
+	}
+	_, err = p.db.ExecContext(ctx, `
+		INSERT INTO observations (entity_id, content) VALUES ($1, $2)
+		ON CONFLICT (entity_id, md5(content)) DO NOTHING`, entityID, content)
+	return err
+}
+
+// DeleteObservationContext removes a specific observation from an entity.
+func (p *PostgresStore) DeleteObservationContext(ctx context.Context, entityName, content string) error {
+	entityID, err := p.entityID(ctx, entityName)
+	if err != nil {
+		return err
+	}
+	result, err := p.db.ExecContext(ctx,
+		"DELETE FROM observations WHERE entity_id = $1 AND content = $2", entityID, content)
+	if err != nil {
+		return err
+	}
+	if rowsAffected(result) == 0 {
+		return observationNotFound(content)
+	}
+	return nil
+}
+
+// CreateRelationContext creates a relation between two entities; creating
+// one that exists is a no-op.
+func (p *PostgresStore) CreateRelationContext(ctx context.Context, fromName, toName, relationType string) error {
+	if err := ValidateRelation(fromName, toName, relationType); err != nil {
+		return err
+	}
+	fromID, err := p.entityID(ctx, fromName)
+	if err != nil {
+		return err
+	}
+	toID, err := p.entityID(ctx, toName)
+	if err != nil {
+		return err
+	}
+	if fromID == toID {
+		return &ValidationError{"relation", "links an entity to itself"}
+	}
+	_, err = p.db.ExecContext(ctx, `
+		INSERT INTO relations (from_entity_id, to_entity_id, relation_type) VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING`, fromID, toID, relationType)
+	return err
+}
+
+// ListRelationsContext returns the relations involving an entity, in both
+// directions.
+func (p *PostgresStore) ListRelationsContext(ctx context.Context, entityName string) ([]*Relation, error) {
+	entityID, err := p.entityID(ctx, entityName)
+	if err != nil {
+		return nil, err
+	}
+	relations := []*Relation{}
+	err = p.db.SelectContext(ctx, &relations, `
+		SELECT e_from.name AS from_name, e_to.name AS to_name, r.relation_type, r.created_at
+		FROM relations r
+		JOIN entities e_from ON r.from_entity_id = e_from.id
+		JOIN entities e_to ON r.to_entity_id = e_to.id
+		WHERE r.from_entity_id = $1 OR r.to_entity_id = $1
+		ORDER BY r.created_at`, entityID)
+	return relations, err
+}
+
+// SearchContext finds up to 20 entities whose names or observations match
+// any word of query, best first. Name matches weigh double, as in the
+// SQLite store.
+func (p *PostgresStore) SearchContext(ctx context.Context, query string) ([]*SearchResult, error) {
+	tsquery := postgresTSQuery(query)
+	if tsquery == "" {
+		return nil, nil
+	}
+	var rows []struct {
+		Entity
+		Score float64 `db:"score"`
+	}
+	err := p.db.SelectContext(ctx, &rows, `
+		WITH hits AS (
+			SELECT entity_id, ts_rank(search, to_tsquery('english', $1)) AS score
+			FROM observations WHERE search @@ to_tsquery('english', $1)
+			UNION ALL
+			SELECT id, 2 * ts_rank(search, to_tsquery('simple', $1))
+			FROM entities WHERE search @@ to_tsquery('simple', $1)
+		)
+		SELECT e.id, e.name, e.entity_type, e.created_at, SUM(h.score) AS score
+		FROM hits h JOIN entities e ON e.id = h.entity_id
+		GROUP BY e.id
+		ORDER BY score DESC, e.name
+		LIMIT 20`, tsquery)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*SearchResult, 0, len(rows))
+	for _, row := range rows {
+		entity := row.Entity
+		entity.Version, entity.IsLatest = 1, true
+		err := p.db.SelectContext(ctx, &entity.Observations,
+			"SELECT content FROM observations WHERE entity_id = $1 ORDER BY created_at, id", entity.ID)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, &SearchResult{Entity: &entity, Score: row.Score})
+	}
+	return results, nil
+}
+
+// postgresTSQuery turns free text into a to_tsquery expression matching any
+// of its words. Punctuation separates words, so the expression is always
+// well-formed; text without words gives "".
+func postgresTSQuery(query string) string {
+	words := strings.FieldsFunc(query, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, " | ")
+}
+
+// StoreEmbeddingContext stores an embedding vector for an observation,
+// replacing any it had. Vector search compares only vectors of the query's
+// dimensions, so models can be mixed.
+func (p *PostgresStore) StoreEmbeddingContext(ctx context.Context, observationID int64, embedding []float64, model string) error {
+	if len(embedding) == 0 {
+		return &ValidationError{Field: "embedding", Reason: "must not be empty"}
+	}
+	_, err := p.db.ExecContext(ctx, `
+		INSERT INTO observation_embeddings (observation_id, model, embedding) VALUES ($1, $2, $3::vector)
+		ON CONFLICT (observation_id) DO UPDATE
+		SET model = excluded.model, embedding = excluded.embedding, created_at = now()`,
+		observationID, model, pgvectorLiteral(embedding))
+	if err != nil {
+		return fmt.Errorf("storing embedding: %w", err)
+	}
+	return nil
+}
+
+// VectorSearchContext finds the observations whose embeddings are closest to
+// queryEmbedding by cosine similarity, using pgvector.
+func (p *PostgresStore) VectorSearchContext(ctx context.Context, queryEmbedding []float64, limit int) ([]VectorResult, error) {
+	if len(queryEmbedding) == 0 {
+		return nil, nil
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT e.name, e.entity_type, o.content, 1 - (v.embedding <=> $1::vector) AS score
+		FROM observation_embeddings v
+		JOIN observations o ON o.id = v.observation_id
+		JOIN entities e ON e.id = o.entity_id
+		WHERE vector_dims(v.embedding) = $2
+		ORDER BY v.embedding <=> $1::vector
+		LIMIT $3`, pgvectorLiteral(queryEmbedding), len(queryEmbedding), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []VectorResult
+	for rows.Next() {
+		var r VectorResult
+		if err := rows.Scan(&r.EntityName, &r.EntityType, &r.Content, &r.Score); err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+// pgvectorLiteral formats v as a pgvector text literal, e.g. "[1,0.5]".
+func pgvectorLiteral(v []float64) string {
+	var sb strings.Builder
+	sb.WriteByte('[')
+	for i, x := range v {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(strconv.FormatFloat(x, 'g', -1, 32))
+	}
+	sb.WriteByte(']')
+	return sb.String()
+}
+
+// GetContextForInjectionContext selects memories for context injection like
+// the SQLite store: pinned memories first within PinnedBudget, then the rest
+// by fact type priority and score within the token budget.
+func (p *PostgresStore) GetContextForInjectionContext(ctx context.Context, cfg ContextConfig, projectName string) ([]ContextResult, error) {
+	var results []ContextResult
+	err := p.db.SelectContext(ctx, &results, `
+		SELECT o.id AS observation_id, e.name AS entity_name, e.entity_type, o.content,
+		       o.fact_type, o.importance, o.pinned,
+		       EXTRACT(EPOCH FROM now() - COALESCE(o.last_accessed, o.created_at)) / 86400 AS days_since_access
+		FROM entities e
+		JOIN observations o ON o.entity_id = e.id
+		WHERE (o.importance >= $1 OR o.pinned) AND COALESCE(o.fact_type, '') <> 'summary'
+		ORDER BY o.pinned DESC, `+factTypeOrderSQL(cfg.FactTypePriority)+`, o.importance DESC`,
+		cfg.MinImportance)
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.ExcludeTypes) > 0 {
+		results = slices.DeleteFunc(results, func(r ContextResult) bool {
+			return slices.Contains(cfg.ExcludeTypes, r.EntityType)
+		})
+	}
+	scoreContextResults(results, cfg, projectName, p.importance)
+	return selectContextResults(p.tokenizer, results, cfg), nil
+}
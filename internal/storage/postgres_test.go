@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestPostgresTSQuery(t *testing.T) {
+	tests := map[string]string{
+		"go testing":          "go | testing",
+		"it's a (test) & co!": "it | s | a | test | co",
+		"  ":                  "",
+	}
+	for query, want := range tests {
+		if got := postgresTSQuery(query); got != want {
+			t.Errorf("postgresTSQuery(%q) = %q, want %q", query, got, want)
+		}
+	}
+}
+
+func TestPgvectorLiteral(t *testing.T) {
+	if got := pgvectorLiteral([]float64{1, 0.5, -0.25}); got != "[1,0.5,-0.25]" {
+		t.Errorf("pgvectorLiteral = %q", got)
+	}
+}
+
+// newTestPostgresStore opens the Postgres database named by
+// MARK42_TEST_POSTGRES, which must have pgvector, with empty tables. The
+// test is skipped without one.
+func newTestPostgresStore(t *testing.T) *PostgresStore {
+	t.Helper()
+	dsn := os.Getenv("MARK42_TEST_POSTGRES")
+	if dsn == "" {
+		t.Skip("MARK42_TEST_POSTGRES not set")
+	}
+	store, err := NewPostgresStore(dsn)
+	if errors.Is(err, ErrPostgresUnavailable) {
+		t.Skip("built without -tags postgres")
+	}
+	if err != nil {
+		t.Fatalf("NewPostgresStore failed: %v", err)
+	}
+	if _, err := store.db.Exec("TRUNCATE entities, observations, relations, observation_embeddings"); err != nil {
+		t.Fatalf("truncating tables: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestPostgresStore(t *testing.T) {
+	store := newTestPostgresStore(t)
+	ctx := context.Background()
+
+	if _, err := store.CreateEntityContext(ctx, "mark42", "project", []string{"Stores memory in SQLite", "Written in Go"}); err != nil {
+		t.Fatalf("CreateEntity failed: %v", err)
+	}
+	if _, err := store.CreateEntityContext(ctx, "mark42", "project", nil); !errors.Is(err, ErrEntityExists) {
+		t.Errorf("expected ErrEntityExists, got %v", err)
+	}
+	if _, err := store.CreateEntityContext(ctx, "Go", "language", nil); err != nil {
+		t.Fatalf("CreateEntity failed: %v", err)
+	}
+	if err := store.AddObservationContext(ctx, "mark42", "Written in Go"); err != nil {
+		t.Errorf("duplicate observation should be ignored, got %v", err)
+	}
+	if err := store.CreateRelationContext(ctx, "mark42", "Go", "uses"); err != nil {
+		t.Fatalf("CreateRelation failed: %v", err)
+	}
+
+	entity, err := store.GetEntityContext(ctx, "mark42")
+	if err != nil {
+		t.Fatalf("GetEntity failed: %v", err)
+	}
+	if len(entity.Observations) != 2 {
+		t.Errorf("expected 2 observations, got %v", entity.Observations)
+	}
+	relations, err := store.ListRelationsContext(ctx, "Go")
+	if err != nil || len(relations) != 1 || relations[0].From != "mark42" {
+		t.Errorf("expected mark42 uses Go, got %v (%v)", relations, err)
+	}
+
+	results, err := store.SearchContext(ctx, "sqlite storage")
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) == 0 || results[0].Name != "mark42" {
+		t.Errorf("expected mark42 first, got %v", results)
+	}
+
+	var obsID int64
+	if err := store.db.Get(&obsID, "SELECT id FROM observations WHERE content = 'Written in Go'"); err != nil {
+		t.Fatalf("finding observation: %v", err)
+	}
+	if err := store.StoreEmbeddingContext(ctx, obsID, []float64{1, 0, 0}, "test"); err != nil {
+		t.Fatalf("StoreEmbedding failed: %v", err)
+	}
+	vectors, err := store.VectorSearchContext(ctx, []float64{0.9, 0.1, 0}, 5)
+	if err != nil || len(vectors) != 1 || vectors[0].Content != "Written in Go" {
+		t.Errorf("expected the embedded observation, got %v (%v)", vectors, err)
+	}
+
+	memories, err := store.GetContextForInjectionContext(ctx, DefaultContextConfig(), "mark42")
+	if err != nil {
+		t.Fatalf("GetContextForInjection failed: %v", err)
+	}
+	if len(memories) != 2 {
+		t.Errorf("expected 2 memories, got %v", memories)
+	}
+
+	if err := store.DeleteEntityContext(ctx, "mark42"); err != nil {
+		t.Fatalf("DeleteEntity failed: %v", err)
+	}
+	if _, err := store.GetEntityContext(ctx, "mark42"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// Provenance kinds. A source is a kind, optionally followed by ":" and a
+// detail: "mcp:add_observations", "hook:stop", "import:memory.json",
+// "session:session-2026-01-05-konfig".
+const (
+	SourceCLI     = "cli"
+	SourceMCP     = "mcp"
+	SourceGRPC    = "grpc"
+	SourceHook    = "hook"
+	SourceImport  = "import"
+	SourceSession = "session"
+)
+
+type sourceKey struct{}
+
+// WithSource returns a context whose writes record source as the provenance
+// of the observations they add, overriding the store's default source.
+func WithSource(ctx context.Context, source string) context.Context {
+	return context.WithValue(ctx, sourceKey{}, source)
+}
+
+type traceKey struct{}
+
+// WithTraceID returns a context carrying id, the correlation ID of the request
+// it serves, so warnings raised while serving it can name the request.
+func WithTraceID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, traceKey{}, id)
+}
+
+// TraceID returns the correlation ID ctx carries, or "".
+func TraceID(ctx context.Context) string {
+	id, _ := ctx.Value(traceKey{}).(string)
+	return id
+}
+
+// SetSource sets the provenance recorded on observations written without a
+// source in their context, such as "cli" or "hook:stop".
+func (s *Store) SetSource(source string) {
+	s.source = source
+}
+
+// sourceFor returns the provenance for a write under ctx, or nil (NULL) when
+// it is unknown.
+func (s *Store) sourceFor(ctx context.Context) any {
+	if source, _ := ctx.Value(sourceKey{}).(string); source != "" {
+		return source
+	}
+	if s.source != "" {
+		return s.source
+	}
+	return nil
+}
+
+// sourceValue stores an empty source, owner, or author as NULL.
+func sourceValue(source string) any {
+	if source == "" {
+		return nil
+	}
+	return source
+}
+
+// ObservationProvenance is an observation with where and when it came from.
+type ObservationProvenance struct {
+	Content    string    `json:"content" db:"content"`
+	FactType   string    `json:"factType" db:"fact_type"`
+	Source     string    `json:"source" db:"source"` // Empty for observations written before provenance was tracked
+	Author     string    `json:"author,omitempty" db:"author"`
+	CreatedAt  time.Time `json:"createdAt" db:"created_at"`
+	Pinned     bool      `json:"pinned,omitempty" db:"pinned"`
+	Suppressed bool      `json:"suppressed,omitempty" db:"suppressed"`
+}
+
+// GetObservationProvenance returns the observations of an entity's latest
+// version with their sources, oldest first.
+func (s *Store) GetObservationProvenance(entityName string) ([]ObservationProvenance, error) {
+	return s.GetObservationProvenanceContext(context.Background(), entityName)
+}
+
+// GetObservationProvenanceContext is GetObservationProvenance with a context.
+func (s *Store) GetObservationProvenanceContext(ctx context.Context, entityName string) ([]ObservationProvenance, error) {
+	id, err := s.entityID(ctx, s.db, entityName)
+	if err != nil {
+		return nil, err
+	}
+	var observations []ObservationProvenance
+	err = s.db.SelectContext(ctx, &observations, `
+		SELECT content, COALESCE(fact_type, 'dynamic') as fact_type, COALESCE(source, '') as source,
+		       COALESCE(author, '') as author, created_at, COALESCE(pinned, 0) as pinned, COALESCE(suppressed, 0) as suppressed
+		FROM observations WHERE entity_id = ? ORDER BY created_at, id`, id)
+	return observations, err
+}
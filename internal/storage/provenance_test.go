@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"context"
+	"testing"
+)
+
+func TestObservationProvenance(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	sources := func(name string) map[string]string {
+		t.Helper()
+		provenance, err := store.GetObservationProvenance(name)
+		if err != nil {
+			t.Fatalf("GetObservationProvenance: %v", err)
+		}
+		got := map[string]string{}
+		for _, p := range provenance {
+			got[p.Content] = p.Source
+		}
+		return got
+	}
+
+	store.CreateEntity("konfig", "project", []string{"untracked"})
+	store.SetSource(SourceCLI)
+	store.AddObservation("konfig", "from the CLI")
+	ctx := WithSource(context.Background(), SourceMCP+":add_observations")
+	store.AddObservationWithTypeContext(ctx, "konfig", "from a tool", FactTypeStatic)
+	store.AddObservationsBatchContext(WithSource(context.Background(), "import:memory.json"),
+		[]ObservationSpec{{EntityName: "konfig", Content: "imported"}})
+
+	want := map[string]string{
+		"untracked":    "",
+		"from the CLI": "cli",
+		"from a tool":  "mcp:add_observations",
+		"imported":     "import:memory.json",
+	}
+	got := sources("konfig")
+	for content, source := range want {
+		if got[content] != source {
+			t.Errorf("source of %q = %q, want %q", content, got[content], source)
+		}
+	}
+
+	session, err := store.CreateSession("konfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.CaptureSessionTurnContext(ctx, session.Name, "Fix the loader")
+	if got := sources(session.Name)["Fix the loader"]; got != SourceSession+":"+session.Name {
+		t.Errorf("session turn source = %q, want the session", got)
+	}
+
+	// Replicas carry the source; records without one take the merging store's
+	records, err := store.ExportReplica()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := newTestStoreWithMigrations(t)
+	other.SetSource("import:replica.ndjson")
+	for i := range records {
+		if records[i].Content == "untracked" {
+			records[i].Source = ""
+		}
+	}
+	if _, err := other.MergeReplica(records); err != nil {
+		t.Fatal(err)
+	}
+	provenance, _ := other.GetObservationProvenance("konfig")
+	merged := map[string]string{}
+	for _, p := range provenance {
+		merged[p.Content] = p.Source
+	}
+	if merged["from a tool"] != "mcp:add_observations" || merged["untracked"] != "import:replica.ndjson" {
+		t.Errorf("merged sources = %v", merged)
+	}
+}
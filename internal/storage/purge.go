@@ -0,0 +1,202 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// PurgeOptions controls PurgeEntity.
+type PurgeOptions struct {
+	DryRun bool // Count what would be erased without erasing it
+	Vacuum bool // Rebuild the database file afterwards, so no freed page keeps a copy
+}
+
+// PurgeReport counts the rows PurgeEntity erased, or would erase.
+type PurgeReport struct {
+	Entity       string `json:"entity"`
+	Versions     int    `json:"versions"`
+	Observations int    `json:"observations"`
+	Embeddings   int    `json:"embeddings"`
+	Relations    int    `json:"relations"`
+	Attributes   int    `json:"attributes"`
+	Aliases      int    `json:"aliases"`
+	Archived     int    `json:"archived"`
+	Merges       int    `json:"merges"`     // Merge records naming the entity, with their snapshots
+	Trash        int    `json:"trash"`      // Snapshots kept for undo of operations on the entity
+	ContextLog   int    `json:"contextLog"` // Logged context injections of its observations
+	Vacuumed     bool   `json:"vacuumed,omitempty"`
+}
+
+// ftsIndexes are the full-text indexes PurgeEntity optimizes, so terms from
+// deleted rows do not linger in their segments. The trigram indexes may not
+// exist; see initTrigram.
+var ftsIndexes = []string{"entities_fts", "observations_fts", "entities_trigram", "observations_trigram"}
+
+// PurgeEntity erases every trace of the named entity: all its versions, their
+// observations, embeddings, relations, attributes, and aliases, its archived
+// observations, and the merge records, undo snapshots, and context log
+// entries naming it, which hold copies or traces. Copies other entities hold
+// of its observations stay, unlinked.
+// The purge is verified before it commits; the full-text indexes are then
+// optimized so no segment keeps its terms. Deleted pages are zeroed as they are freed, and
+// opts.Vacuum also rebuilds the file. Tombstones are kept, stripped of the
+// entity's name, so the deletion still reaches replicas by UID.
+func (s *Store) PurgeEntity(name string, opts PurgeOptions) (*PurgeReport, error) {
+	return s.PurgeEntityContext(context.Background(), name, opts)
+}
+
+// PurgeEntityContext is PurgeEntity with a context.
+func (s *Store) PurgeEntityContext(ctx context.Context, name string, opts PurgeOptions) (*PurgeReport, error) {
+	name = NormalizeName(name)
+	report := &PurgeReport{Entity: name}
+
+	// A connection of its own, so secure_delete applies to the purge's writes
+	conn, err := s.db.Connx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if !opts.DryRun && !s.remote {
+		if _, err := conn.ExecContext(ctx, "PRAGMA secure_delete = ON"); err != nil {
+			return nil, err
+		}
+		defer conn.ExecContext(context.Background(), "PRAGMA secure_delete = OFF")
+	}
+
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var ids []int64
+	if err := tx.SelectContext(ctx, &ids, "SELECT id FROM entities WHERE "+s.nameMatch("name"), name); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, entityNotFound(name)
+	}
+	report.Versions = len(ids)
+
+	in, args, err := sqlx.In("(?)", ids)
+	if err != nil {
+		return nil, err
+	}
+	var observationIDs []int64
+	if err := tx.SelectContext(ctx, &observationIDs, "SELECT id FROM observations WHERE entity_id IN "+in, args...); err != nil {
+		return nil, err
+	}
+	observations := "SELECT id FROM observations WHERE entity_id IN " + in
+	steps := []struct {
+		count *int
+		query string
+		args  []any
+	}{
+		// Copies elsewhere stop pointing at observations about to go
+		{nil, "UPDATE observations SET canonical_id = NULL WHERE canonical_id IN (" + observations + ")", args},
+		{&report.Embeddings, "DELETE FROM observation_embeddings WHERE observation_id IN (" + observations + ")", args},
+		{&report.Observations, "DELETE FROM observations WHERE entity_id IN " + in, args},
+		{&report.Relations, "DELETE FROM relations WHERE from_entity_id IN " + in + " OR to_entity_id IN " + in, append(append([]any{}, args...), args...)},
+		{&report.Attributes, "DELETE FROM entity_attributes WHERE entity_id IN " + in, args},
+		{&report.Aliases, "DELETE FROM entity_aliases WHERE " + s.nameMatch("entity_name") + " OR " + s.nameMatch("alias"), []any{name, name}},
+		{&report.Archived, "DELETE FROM archived_observations WHERE " + s.nameMatch("entity_name"), []any{name}},
+		{&report.Merges, "DELETE FROM entity_merges WHERE " + s.nameMatch("keep") + " OR " + s.nameMatch("merged"), []any{name, name}},
+		{&report.Trash, "DELETE FROM trash WHERE " + s.nameMatch("entity"), []any{name}},
+		{&report.ContextLog, "DELETE FROM context_log_items WHERE " + s.nameMatch("entity_name"), []any{name}},
+		{nil, "DELETE FROM entities WHERE id IN " + in, args},
+		// After the deletes, whose triggers write the tombstones
+		{nil, "UPDATE tombstones SET entity = NULL, target = NULL, relation_type = NULL, content_hash = NULL WHERE " +
+			s.nameMatch("entity") + " OR " + s.nameMatch("target"), []any{name, name}},
+	}
+	for _, step := range steps {
+		result, err := tx.ExecContext(ctx, step.query, step.args...)
+		if err != nil {
+			return nil, err
+		}
+		if step.count != nil {
+			*step.count = rowsAffected(result)
+		}
+	}
+
+	if err := s.verifyPurged(ctx, tx, name, ids, observationIDs); err != nil {
+		return nil, err
+	}
+	if opts.DryRun {
+		return report, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	for _, index := range ftsIndexes {
+		// A missing trigram index is not an error here
+		if _, err := conn.ExecContext(ctx, "INSERT INTO "+index+"("+index+") VALUES('optimize')"); err != nil && !strings.Contains(err.Error(), "no such table") {
+			return report, fmt.Errorf("optimizing %s: %w", index, err)
+		}
+	}
+	if opts.Vacuum && !s.remote {
+		if _, err := conn.ExecContext(ctx, "VACUUM"); err != nil {
+			return report, fmt.Errorf("vacuum: %w", err)
+		}
+		report.Vacuumed = true
+	}
+	return report, nil
+}
+
+// purgeCheck counts rows left in table after a purge.
+type purgeCheck struct {
+	table string
+	query string
+	args  []any
+}
+
+// verifyPurged checks that no table still refers to the entity by name, its
+// version IDs, or its observation IDs, and that the full-text indexes hold
+// no document for them (an FTS5 docsize row exists per indexed row).
+func (s *Store) verifyPurged(ctx context.Context, tx *sqlx.Tx, name string, ids, observationIDs []int64) error {
+	in, args, err := sqlx.In("(?)", ids)
+	if err != nil {
+		return err
+	}
+	twice := append(append([]any{}, args...), args...)
+	checks := []purgeCheck{
+		{"entities", "SELECT COUNT(*) FROM entities WHERE " + s.nameMatch("name") + " OR id IN " + in, append([]any{name}, args...)},
+		{"observations", "SELECT COUNT(*) FROM observations WHERE entity_id IN " + in, args},
+		{"relations", "SELECT COUNT(*) FROM relations WHERE from_entity_id IN " + in + " OR to_entity_id IN " + in, twice},
+		{"entity_attributes", "SELECT COUNT(*) FROM entity_attributes WHERE entity_id IN " + in, args},
+		{"entity_aliases", "SELECT COUNT(*) FROM entity_aliases WHERE " + s.nameMatch("entity_name") + " OR " + s.nameMatch("alias"), []any{name, name}},
+		{"archived_observations", "SELECT COUNT(*) FROM archived_observations WHERE " + s.nameMatch("entity_name"), []any{name}},
+		{"entity_merges", "SELECT COUNT(*) FROM entity_merges WHERE " + s.nameMatch("keep") + " OR " + s.nameMatch("merged"), []any{name, name}},
+		{"trash", "SELECT COUNT(*) FROM trash WHERE " + s.nameMatch("entity"), []any{name}},
+		{"context_log_items", "SELECT COUNT(*) FROM context_log_items WHERE " + s.nameMatch("entity_name"), []any{name}},
+		{"tombstones", "SELECT COUNT(*) FROM tombstones WHERE " + s.nameMatch("entity") + " OR " + s.nameMatch("target"), []any{name, name}},
+		{"entities_fts", "SELECT COUNT(*) FROM entities_fts_docsize WHERE id IN " + in, args},
+	}
+	if len(observationIDs) > 0 {
+		in, args, err := sqlx.In("(?)", observationIDs)
+		if err != nil {
+			return err
+		}
+		checks = append(checks,
+			purgeCheck{"observation_embeddings", "SELECT COUNT(*) FROM observation_embeddings WHERE observation_id IN " + in, args},
+			purgeCheck{"observations_fts", "SELECT COUNT(*) FROM observations_fts_docsize WHERE id IN " + in, args})
+	}
+
+	var remaining []string
+	for _, c := range checks {
+		var count int
+		if err := tx.GetContext(ctx, &count, c.query, c.args...); err != nil {
+			return fmt.Errorf("verifying %s: %w", c.table, err)
+		}
+		if count > 0 {
+			remaining = append(remaining, fmt.Sprintf("%s (%d)", c.table, count))
+		}
+	}
+	if len(remaining) > 0 {
+		return fmt.Errorf("purge of %q incomplete, rows remain in %s", name, strings.Join(remaining, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package storage
+
+import "testing"
+
+func TestPurgeEntity(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	store.CreateOrUpdateEntity("Alice", "person", []string{"Lives at 12 Elm Street"})
+	store.CreateOrUpdateEntity("Alice", "person", []string{"Phone 555-0100"})
+	store.CreateEntity("Project", "project", []string{"Phone 555-0100"})
+	store.CreateRelation("Project", "Alice", "owned_by")
+	store.SetAttributes("Alice", map[string]string{"email": "alice@example.com"})
+	store.AddAlias("Alice", "Ally")
+	store.AddObservation("Alice", "Works at Initech")
+	store.DeleteObservation("Alice", "Works at Initech") // Kept in the trash for undo
+	if n, _ := store.LinkDuplicateObservations(); n != 1 {
+		t.Fatalf("expected one duplicate linked, got %d", n)
+	}
+	var obsID int64
+	store.db.Get(&obsID, "SELECT o.id FROM observations o JOIN entities e ON e.id = o.entity_id WHERE e.name = 'Alice' AND is_latest = 1")
+	store.StoreEmbedding(obsID, []float64{1, 2}, "test")
+	store.db.Exec(`INSERT INTO archived_observations (original_entity_id, entity_name, content) VALUES (1, 'Alice', 'Old address')`)
+
+	preview, err := store.PurgeEntity("Alice", PurgeOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("PurgeEntity dry run failed: %v", err)
+	}
+	if preview.Versions != 2 || preview.Embeddings != 1 || preview.Relations != 1 || preview.Attributes != 1 ||
+		preview.Aliases != 1 || preview.Archived != 1 || preview.Trash != 1 {
+		t.Errorf("unexpected preview %+v", preview)
+	}
+	if _, err := store.GetEntity("Alice"); err != nil {
+		t.Fatalf("expected a dry run to keep the entity: %v", err)
+	}
+
+	report, err := store.PurgeEntity("Alice", PurgeOptions{Vacuum: true})
+	if err != nil {
+		t.Fatalf("PurgeEntity failed: %v", err)
+	}
+	if *report != (PurgeReport{Entity: "Alice", Versions: 2, Observations: preview.Observations, Embeddings: 1, Relations: 1,
+		Attributes: 1, Aliases: 1, Archived: 1, Trash: 1, Vacuumed: true}) {
+		t.Errorf("expected the report to match the preview, got %+v and %+v", report, preview)
+	}
+
+	results, err := store.Search("Elm")
+	if err != nil || len(results) != 0 {
+		t.Errorf("expected no search hits, got %+v, %v", results, err)
+	}
+	project, err := store.GetEntity("Project")
+	if err != nil || len(project.Observations) != 1 {
+		t.Errorf("expected other entities' copies kept, got %+v, %v", project, err)
+	}
+	var linked int
+	store.db.Get(&linked, "SELECT COUNT(*) FROM observations WHERE canonical_id IS NOT NULL")
+	if linked != 0 {
+		t.Errorf("expected copies unlinked from purged observations, %d still linked", linked)
+	}
+
+	var tombstones, named int
+	store.db.Get(&tombstones, "SELECT COUNT(*) FROM tombstones")
+	store.db.Get(&named, "SELECT COUNT(*) FROM tombstones WHERE entity IS NOT NULL OR content_hash IS NOT NULL")
+	if tombstones == 0 || named != 0 {
+		t.Errorf("expected tombstones kept without names, %d of %d named", named, tombstones)
+	}
+
+	if _, err := store.PurgeEntity("Alice", PurgeOptions{}); err == nil {
+		t.Error("expected an error purging an entity that does not exist")
+	}
+}
@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+)
+
+// queryPlan returns the EXPLAIN QUERY PLAN details of a query, one per step.
+func queryPlan(t *testing.T, s *Store, query string, args ...any) []string {
+	t.Helper()
+	// EXPLAIN does not check the schema version, so load the schema first in
+	// case migrations ran on another connection
+	if _, err := s.db.Exec("SELECT COUNT(*) FROM sqlite_master"); err != nil {
+		t.Fatalf("loading schema failed: %v", err)
+	}
+	var steps []struct {
+		ID      int    `db:"id"`
+		Parent  int    `db:"parent"`
+		NotUsed int    `db:"notused"`
+		Detail  string `db:"detail"`
+	}
+	if err := s.db.Select(&steps, "EXPLAIN QUERY PLAN "+query, args...); err != nil {
+		t.Fatalf("EXPLAIN QUERY PLAN failed: %v", err)
+	}
+	details := make([]string, len(steps))
+	for i, step := range steps {
+		details[i] = step.Detail
+	}
+	return details
+}
+
+func TestContextQueriesUseIndexes(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	for _, q := range []struct {
+		name  string
+		query string
+		args  []any
+	}{
+		{
+			name:  "context injection",
+			query: contextInjectionQuery("CASE fact_type WHEN 'static' THEN 1 ELSE 99 END"),
+			args:  []any{julianNow(), 0.3, "", ""},
+		},
+		{
+			name:  "importance recalculation",
+			query: importanceInputsQuery("1.0"),
+			args:  []any{julianNow()},
+		},
+	} {
+		t.Run(q.name, func(t *testing.T) {
+			plan := queryPlan(t, store, q.query, q.args...)
+			joined := strings.Join(plan, "\n")
+			for _, step := range plan {
+				if strings.HasPrefix(step, "SCAN o") || strings.HasPrefix(step, "SCAN e") {
+					t.Errorf("full table scan %q in plan:\n%s", step, joined)
+				}
+			}
+			for _, search := range []string{"SEARCH o USING", "SEARCH e USING"} {
+				if !strings.Contains(joined, search) {
+					t.Errorf("expected %q in plan:\n%s", search, joined)
+				}
+			}
+		})
+	}
+}
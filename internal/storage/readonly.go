@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// NewStoreReadOnly opens an existing local SQLite database without the
+// ability to write to it, for inspecting a database another process is
+// using. Unlike NewStore it neither creates the file nor its schema, and
+// every write fails. Readers of a WAL database (the mode NewStore sets) never
+// block its writer.
+func NewStoreReadOnly(path string) (*Store, error) {
+	if IsPostgresDSN(path) {
+		return nil, ErrPostgresStore
+	}
+	if IsRemoteDSN(path) {
+		return nil, &ValidationError{Field: "path", Reason: "read-only mode needs a local SQLite file"}
+	}
+
+	db, err := sqlx.Open("sqlite", readOnlyDSN(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	// Fails here, rather than on the first query, if the file is missing
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open database read-only: %w", err)
+	}
+
+	store := &Store{
+		db:                  db,
+		path:                path,
+		importance:          DefaultImportanceConfig(),
+		readOnly:            true,
+		maxVectorCandidates: DefaultMaxVectorCandidates,
+		attributeSchemas:    DefaultAttributeSchemas(),
+		tokenizer:           DefaultTokenizer(),
+	}
+	store.SetStopWords(DefaultStopWords)
+	if err := db.Get(&store.fts, `
+		SELECT COUNT(*) > 0 FROM sqlite_master
+		WHERE type='table' AND name='observations_fts'`); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open database read-only: %w", err)
+	}
+	if store.fts {
+		store.initTrigram() // Only finds existing indexes
+	}
+	return store, nil
+}
+
+// ReadOnly reports whether the store was opened with NewStoreReadOnly.
+func (s *Store) ReadOnly() bool {
+	return s.readOnly
+}
+
+// readOnlyDSN opens path as a URI with mode=ro, so SQLite neither creates nor
+// writes the file, and query_only as a second guard. The characters SQLite
+// URIs reserve are escaped.
+func readOnlyDSN(path string) string {
+	escaped := strings.NewReplacer("%", "%25", "#", "%23", "?", "%3f").Replace(path)
+	return "file:" + escaped + "?mode=ro&_pragma=foreign_keys(1)&_pragma=query_only(1)"
+}
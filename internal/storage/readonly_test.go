@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewStoreReadOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "team #1 (100%)", "memory.db")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writer, err := NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer writer.Close()
+	if err := writer.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	writer.CreateEntity("konfig", "project", []string{"Loads YAML"})
+
+	reader, err := NewStoreReadOnly(path)
+	if err != nil {
+		t.Fatalf("NewStoreReadOnly: %v", err)
+	}
+	defer reader.Close()
+	if !reader.ReadOnly() || writer.ReadOnly() {
+		t.Error("expected only the reader to be read-only")
+	}
+	if err := reader.Migrate(); err != nil {
+		t.Errorf("Migrate on an up-to-date read-only store: %v", err)
+	}
+
+	results, err := reader.Search("YAML")
+	if err != nil || len(results) != 1 {
+		t.Fatalf("Search = %v, %v; want konfig", results, err)
+	}
+	if _, err := reader.CreateEntity("mark42", "project", nil); err == nil {
+		t.Error("expected a write through the read-only store to fail")
+	}
+
+	// The writer keeps writing while the reader is open
+	if err := writer.AddObservation("konfig", "Loads TOML"); err != nil {
+		t.Fatalf("write alongside a reader: %v", err)
+	}
+	entity, err := reader.GetEntity("konfig")
+	if err != nil || len(entity.Observations) != 2 {
+		t.Errorf("GetEntity = %+v, %v; want the writer's new observation", entity, err)
+	}
+
+	missing := filepath.Join(t.TempDir(), "missing.db")
+	if _, err := NewStoreReadOnly(missing); err == nil {
+		t.Error("expected opening a missing database read-only to fail")
+	}
+	if _, err := os.Stat(missing); !os.IsNotExist(err) {
+		t.Error("expected NewStoreReadOnly not to create the file")
+	}
+}
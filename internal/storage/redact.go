@@ -0,0 +1,170 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// SecretPattern is a kind of secret redacted from observations before they
+// are stored. When Pattern has a capturing group, only what the first group
+// matches is replaced, so "password=hunter2" keeps its key.
+type SecretPattern struct {
+	Type    string `json:"type"` // Named in the replacement, [REDACTED:<type>]
+	Pattern string `json:"pattern"`
+}
+
+// DefaultSecretPatterns are the built-in redaction presets for common
+// credential formats. Values exclude quotes and backslashes so a secret in
+// a JSON-encoded session event is replaced without breaking the JSON, and
+// password values may not start with "[" so a key already redacted by an
+// earlier preset keeps its type.
+var DefaultSecretPatterns = []SecretPattern{
+	{"private_key", `-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`},
+	{"aws_access_key", `\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`},
+	{"github_token", `\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})`},
+	{"anthropic_key", `\bsk-ant-[A-Za-z0-9_-]{20,}`},
+	{"openai_key", `\bsk-(?:proj-)?[A-Za-z0-9_-]{20,}`},
+	{"stripe_key", `\b[rs]k_(?:live|test)_[A-Za-z0-9]{16,}`},
+	{"slack_token", `\bxox[abprs]-[A-Za-z0-9-]{10,}`},
+	{"google_api_key", `\bAIza[0-9A-Za-z_-]{35}`},
+	{"jwt", `\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`},
+	{"bearer_token", `(?i)\bbearer\s+([A-Za-z0-9._~+/-]{20,}=*)`},
+	{"url_password", `\b[a-z][a-z0-9+.-]*://[^/\s:@"\\]+:([^/\s@"\\]+)@`},
+	{"password", `(?i)\b(?:password|passwd|pwd|secret|api[_-]?key|access[_-]?token|auth[_-]?token|client[_-]?secret)\b["'\\]*\s*[:=]\s*["'\\]*([^\s"'\\,;&\[][^\s"'\\,;&]{3,})`},
+}
+
+// defaultSecretPatterns are DefaultSecretPatterns compiled.
+var defaultSecretPatterns = mustCompileSecretPatterns(DefaultSecretPatterns)
+
+// secretPattern is a SecretPattern compiled.
+type secretPattern struct {
+	typ string
+	re  *regexp.Regexp
+}
+
+func compileSecretPatterns(patterns []SecretPattern) ([]secretPattern, error) {
+	compiled := make([]secretPattern, 0, len(patterns))
+	for _, p := range patterns {
+		if strings.TrimSpace(p.Type) == "" {
+			return nil, &ValidationError{"secret pattern", "needs a type"}
+		}
+		re, err := regexp.Compile(p.Pattern)
+		if err != nil {
+			return nil, &ValidationError{"secret pattern", fmt.Sprintf("%s: %v", p.Type, err)}
+		}
+		compiled = append(compiled, secretPattern{p.Type, re})
+	}
+	return compiled, nil
+}
+
+func mustCompileSecretPatterns(patterns []SecretPattern) []secretPattern {
+	compiled, err := compileSecretPatterns(patterns)
+	if err != nil {
+		panic(err)
+	}
+	return compiled
+}
+
+// Redaction configures the secret redaction applied to observations on
+// every write path: entity creation, observation adds and edits, session
+// capture, summaries, imports, and replica merges.
+type Redaction struct {
+	// Presets turns DefaultSecretPatterns on or off; nil keeps them on
+	Presets  *bool           `json:"presets,omitempty"`
+	Patterns []SecretPattern `json:"patterns,omitempty"` // Applied after the presets
+	// Warn receives each redacted write; nil drops them
+	Warn func(Redacted) `json:"-"`
+}
+
+// Redacted reports an observation that had secrets replaced. It never holds
+// the secrets themselves.
+type Redacted struct {
+	Entity  string
+	Types   []string // Kinds of secret replaced, in pattern order
+	TraceID string   // Of the request that wrote it; see WithTraceID
+}
+
+// SetRedaction replaces the patterns secrets are redacted with, and returns
+// a ValidationError for a pattern that does not compile. Stores start with
+// the presets on and no warning.
+func (s *Store) SetRedaction(r Redaction) error {
+	custom, err := compileSecretPatterns(r.Patterns)
+	if err != nil {
+		return err
+	}
+	var patterns []secretPattern
+	if r.Presets == nil || *r.Presets {
+		patterns = append(patterns, defaultSecretPatterns...)
+	}
+	s.secretPatterns = append(patterns, custom...)
+	s.redactWarn = r.Warn
+	return nil
+}
+
+// RedactSecrets replaces the secrets the store's patterns find in content
+// with [REDACTED:<type>], returning the kinds it replaced.
+func (s *Store) RedactSecrets(content string) (string, []string) {
+	var types []string
+	for _, p := range s.secretPatterns {
+		redacted := replaceSecret(p, content)
+		if redacted != content {
+			content = redacted
+			types = append(types, p.typ)
+		}
+	}
+	return content, types
+}
+
+// replaceSecret replaces p's matches in content, or only their first
+// group when p has one.
+func replaceSecret(p secretPattern, content string) string {
+	placeholder := "[REDACTED:" + p.typ + "]"
+	if p.re.NumSubexp() == 0 {
+		return p.re.ReplaceAllLiteralString(content, placeholder)
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range p.re.FindAllStringSubmatchIndex(content, -1) {
+		if m[2] < 0 {
+			continue
+		}
+		b.WriteString(content[last:m[2]])
+		b.WriteString(placeholder)
+		last = m[3]
+	}
+	if last == 0 {
+		return content
+	}
+	b.WriteString(content[last:])
+	return b.String()
+}
+
+// redact is RedactSecrets for an observation about to be written to entity,
+// passing what it replaced to the warning hook.
+func (s *Store) redact(ctx context.Context, entity, content string) string {
+	redacted, types := s.RedactSecrets(content)
+	if len(types) > 0 && s.redactWarn != nil {
+		s.redactWarn(Redacted{Entity: entity, Types: types, TraceID: TraceID(ctx)})
+	}
+	return redacted
+}
+
+// redactAll is redact for each of contents, copying the slice only when a
+// secret was found so the caller's is left alone.
+func (s *Store) redactAll(ctx context.Context, entity string, contents []string) []string {
+	copied := false
+	for i, content := range contents {
+		redacted := s.redact(ctx, entity, content)
+		if redacted == content {
+			continue
+		}
+		if !copied {
+			contents, copied = slices.Clone(contents), true
+		}
+		contents[i] = redacted
+	}
+	return contents
+}
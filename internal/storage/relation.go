@@ -1,84 +1,245 @@
 package storage
 
-import "time"
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
 
 // Relation represents an edge between two entities.
 type Relation struct {
-	From      string    `db:"from_name"`
-	To        string    `db:"to_name"`
-	Type      string    `db:"relation_type"`
-	CreatedAt time.Time `db:"created_at"`
+	From      string     `db:"from_name"`
+	To        string     `db:"to_name"`
+	Type      string     `db:"relation_type"`
+	CreatedAt time.Time  `db:"created_at"`
+	ValidFrom *time.Time `db:"valid_from" json:",omitempty"` // Nil: since CreatedAt
+	ValidTo   *time.Time `db:"valid_to" json:",omitempty"`   // Set once the relation is ended
+}
+
+// relationColumns selects a Relation from relations r joined to e_from and e_to.
+const relationColumns = `e_from.name as from_name, e_to.name as to_name,
+		       r.relation_type, r.created_at, r.valid_from, r.valid_to`
+
+// RelationFilter selects relations by validity. The zero value selects the
+// relations valid now.
+type RelationFilter struct {
+	AsOf         time.Time // Select relations valid at this time instead
+	IncludeEnded bool      // Select every relation, whatever its validity
+}
+
+// sql returns a condition on relations r implementing f.
+func (f RelationFilter) sql() (string, []any) {
+	if f.IncludeEnded {
+		return "1", nil
+	}
+	at := f.AsOf
+	if at.IsZero() {
+		at = time.Now()
+	}
+	return "COALESCE(r.valid_from, r.created_at) <= ? AND (r.valid_to IS NULL OR r.valid_to > ?)",
+		[]any{sqlTime(at), sqlTime(at)}
+}
+
+// sqlTime formats t like SQLite's CURRENT_TIMESTAMP, so stored times compare as text.
+func sqlTime(t time.Time) string {
+	return t.UTC().Format(time.DateTime)
 }
 
 // CreateRelation creates a relation between two entities.
 func (s *Store) CreateRelation(fromName, toName, relationType string) error {
-	// Get entity IDs
-	var fromID, toID int64
+	return s.CreateRelationContext(context.Background(), fromName, toName, relationType)
+}
 
-	err := s.db.QueryRow("SELECT id FROM entities WHERE name = ?", fromName).Scan(&fromID)
-	if err != nil {
-		return ErrNotFound
+// CreateRelationContext is CreateRelation with a context.
+func (s *Store) CreateRelationContext(ctx context.Context, fromName, toName, relationType string) error {
+	return s.CreateRelationValidFromContext(ctx, fromName, toName, relationType, time.Time{})
+}
+
+// CreateRelationValidFrom creates a relation that holds from validFrom; a
+// zero validFrom means from now. Creating a relation that was ended reopens
+// it with the new start.
+func (s *Store) CreateRelationValidFrom(fromName, toName, relationType string, validFrom time.Time) error {
+	return s.CreateRelationValidFromContext(context.Background(), fromName, toName, relationType, validFrom)
+}
+
+// CreateRelationValidFromContext is CreateRelationValidFrom with a context.
+func (s *Store) CreateRelationValidFromContext(ctx context.Context, fromName, toName, relationType string, validFrom time.Time) error {
+	if err := ValidateRelation(fromName, toName, relationType); err != nil {
+		return err
 	}
 
-	err = s.db.QueryRow("SELECT id FROM entities WHERE name = ?", toName).Scan(&toID)
+	fromID, err := s.entityID(ctx, s.db, fromName)
+	if err != nil {
+		return err
+	}
+	toID, err := s.entityID(ctx, s.db, toName)
 	if err != nil {
-		return ErrNotFound
+		return err
+	}
+	// Different spellings can resolve to one entity
+	if fromID == toID {
+		return &ValidationError{"relation", "links an entity to itself"}
+	}
+	if err := s.checkRelation(ctx, s.db, fromID, toID, relationType); err != nil {
+		return err
+	}
+	if fromID, toID, err = s.storedRelationEnds(ctx, s.db, fromID, toID, relationType); err != nil {
+		return err
 	}
 
-	// Insert relation (ignore duplicate)
-	_, err = s.db.Exec(
-		"INSERT OR IGNORE INTO relations (from_entity_id, to_entity_id, relation_type) VALUES (?, ?, ?)",
-		fromID, toID, relationType,
+	var from sql.NullString
+	if !validFrom.IsZero() {
+		from = sql.NullString{String: sqlTime(validFrom), Valid: true}
+	}
+
+	// Insert relation; a duplicate is ignored unless it was ended
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO relations (from_entity_id, to_entity_id, relation_type, valid_from) VALUES (?, ?, ?, ?)
+		ON CONFLICT(from_entity_id, to_entity_id, relation_type) DO UPDATE
+		SET valid_from = COALESCE(excluded.valid_from, CURRENT_TIMESTAMP), valid_to = NULL
+		WHERE relations.valid_to IS NOT NULL`,
+		fromID, toID, relationType, from,
 	)
 	return err
 }
 
-// ListRelations returns all relations involving an entity (both directions).
+// EndRelation closes a relation's validity window at endAt (now if zero),
+// keeping it for history instead of deleting it.
+func (s *Store) EndRelation(fromName, toName, relationType string, endAt time.Time) error {
+	return s.EndRelationContext(context.Background(), fromName, toName, relationType, endAt)
+}
+
+// EndRelationContext is EndRelation with a context.
+func (s *Store) EndRelationContext(ctx context.Context, fromName, toName, relationType string, endAt time.Time) error {
+	fromID, err := s.entityID(ctx, s.db, fromName)
+	if err != nil {
+		return err
+	}
+	toID, err := s.entityID(ctx, s.db, toName)
+	if err != nil {
+		return err
+	}
+	if fromID, toID, err = s.storedRelationEnds(ctx, s.db, fromID, toID, relationType); err != nil {
+		return err
+	}
+	if endAt.IsZero() {
+		endAt = time.Now()
+	}
+
+	var ended, startsLater bool
+	err = s.db.QueryRowContext(ctx, `
+		SELECT valid_to IS NOT NULL, COALESCE(valid_from, created_at) > ? FROM relations
+		WHERE from_entity_id = ? AND to_entity_id = ? AND relation_type = ?`,
+		sqlTime(endAt), fromID, toID, relationType).Scan(&ended, &startsLater)
+	if errors.Is(err, sql.ErrNoRows) {
+		return relationNotFound(fromName, toName, relationType)
+	}
+	if err != nil {
+		return err
+	}
+	if ended {
+		return &ValidationError{"relation", "has already ended"}
+	}
+	if startsLater {
+		return &ValidationError{"end time", "is before the relation became valid"}
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		UPDATE relations SET valid_to = ?
+		WHERE from_entity_id = ? AND to_entity_id = ? AND relation_type = ?`,
+		sqlTime(endAt), fromID, toID, relationType)
+	return err
+}
+
+// ListRelations returns the relations valid now involving an entity (both
+// directions). Render them with OrientRelation to read each from the entity.
 func (s *Store) ListRelations(entityName string) ([]*Relation, error) {
-	var entityID int64
-	err := s.db.QueryRow("SELECT id FROM entities WHERE name = ?", entityName).Scan(&entityID)
+	return s.ListRelationsContext(context.Background(), entityName)
+}
+
+// ListRelationsContext is ListRelations with a context.
+func (s *Store) ListRelationsContext(ctx context.Context, entityName string) ([]*Relation, error) {
+	return s.ListRelationsWithFilterContext(ctx, entityName, RelationFilter{})
+}
+
+// ListRelationsWithFilter returns the relations involving an entity (both
+// directions) that filter selects.
+func (s *Store) ListRelationsWithFilter(entityName string, filter RelationFilter) ([]*Relation, error) {
+	return s.ListRelationsWithFilterContext(context.Background(), entityName, filter)
+}
+
+// ListRelationsWithFilterContext is ListRelationsWithFilter with a context.
+func (s *Store) ListRelationsWithFilterContext(ctx context.Context, entityName string, filter RelationFilter) ([]*Relation, error) {
+	entityID, err := s.entityID(ctx, s.db, entityName)
 	if err != nil {
-		return nil, ErrNotFound
+		return nil, err
 	}
 
 	// Query both outgoing and incoming relations using sqlx
+	validity, args := filter.sql()
 	var relations []Relation
-	err = s.db.Select(&relations, `
-		SELECT e_from.name as from_name, e_to.name as to_name,
-		       r.relation_type, r.created_at
+	err = s.db.SelectContext(ctx, &relations, `
+		SELECT `+relationColumns+`
 		FROM relations r
 		JOIN entities e_from ON r.from_entity_id = e_from.id
 		JOIN entities e_to ON r.to_entity_id = e_to.id
-		WHERE r.from_entity_id = ? OR r.to_entity_id = ?
+		WHERE (r.from_entity_id = ? OR r.to_entity_id = ?) AND `+validity+`
 		ORDER BY r.created_at
-	`, entityID, entityID)
+	`, append([]any{entityID, entityID}, args...)...)
 	if err != nil {
 		return nil, err
 	}
 
-	// Convert to pointer slice for API compatibility
-	result := make([]*Relation, len(relations))
+	// Convert to pointer slice for API compatibility; a symmetric relation
+	// stored both ways, from before its type was registered, is listed once
+	result := make([]*Relation, 0, len(relations))
+	listed := map[[3]string]bool{}
 	for i := range relations {
-		result[i] = &relations[i]
+		r := &relations[i]
+		if s.SymmetricRelationType(r.Type) && listed[[3]string{r.To, r.From, r.Type}] {
+			continue
+		}
+		listed[[3]string{r.From, r.To, r.Type}] = true
+		result = append(result, r)
 	}
 	return result, nil
 }
 
 // DeleteRelation removes a specific relation.
 func (s *Store) DeleteRelation(fromName, toName, relationType string) error {
-	var fromID, toID int64
+	return s.DeleteRelationContext(context.Background(), fromName, toName, relationType)
+}
 
-	err := s.db.QueryRow("SELECT id FROM entities WHERE name = ?", fromName).Scan(&fromID)
+// DeleteRelationContext is DeleteRelation with a context.
+func (s *Store) DeleteRelationContext(ctx context.Context, fromName, toName, relationType string) error {
+	fromID, err := s.entityID(ctx, s.db, fromName)
 	if err != nil {
-		return ErrNotFound
+		return err
+	}
+	toID, err := s.entityID(ctx, s.db, toName)
+	if err != nil {
+		return err
 	}
 
-	err = s.db.QueryRow("SELECT id FROM entities WHERE name = ?", toName).Scan(&toID)
+	if fromID, toID, err = s.storedRelationEnds(ctx, s.db, fromID, toID, relationType); err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
 	if err != nil {
-		return ErrNotFound
+		return err
+	}
+	defer tx.Rollback()
+
+	trash := newTrashBatch(OpDeleteRelation)
+	if trash.entity(fromName).Relations, err = snapshotRows(ctx, tx,
+		"SELECT * FROM relations WHERE from_entity_id = ? AND to_entity_id = ? AND relation_type = ?",
+		fromID, toID, relationType); err != nil {
+		return err
 	}
 
-	result, err := s.db.Exec(
+	result, err := tx.ExecContext(ctx,
 		"DELETE FROM relations WHERE from_entity_id = ? AND to_entity_id = ? AND relation_type = ?",
 		fromID, toID, relationType,
 	)
@@ -92,8 +253,11 @@ func (s *Store) DeleteRelation(fromName, toName, relationType string) error {
 	}
 
 	if rows == 0 {
-		return ErrNotFound
+		return relationNotFound(fromName, toName, relationType)
 	}
 
-	return nil
+	if err := s.recordTrash(ctx, tx, trash); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
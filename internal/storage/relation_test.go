@@ -1,7 +1,9 @@
 package storage_test
 
 import (
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/mfenderov/mark42/internal/storage"
 )
@@ -117,7 +119,7 @@ func TestDeleteRelation_NotFound(t *testing.T) {
 	store.CreateEntity("Simple Design", "pattern", nil)
 
 	err := store.DeleteRelation("TDD", "Simple Design", "nonexistent")
-	if err != storage.ErrNotFound {
+	if !errors.Is(err, storage.ErrNotFound) {
 		t.Errorf("expected ErrNotFound, got %v", err)
 	}
 }
@@ -138,3 +140,70 @@ func TestDeleteEntity_CascadesRelations(t *testing.T) {
 		t.Errorf("expected 0 relations after cascade delete, got %d", len(relations))
 	}
 }
+
+func TestEndRelation(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+
+	store.CreateEntity("konfig", "project", nil)
+	store.CreateEntity("X", "library", nil)
+	if err := store.CreateRelationValidFrom("konfig", "X", "depends_on", time.Now().Add(-48*time.Hour)); err != nil {
+		t.Fatalf("CreateRelationValidFrom failed: %v", err)
+	}
+	endAt := time.Now().Add(-24 * time.Hour)
+	if err := store.EndRelation("konfig", "X", "depends_on", endAt); err != nil {
+		t.Fatalf("EndRelation failed: %v", err)
+	}
+
+	if relations, _ := store.ListRelations("konfig"); len(relations) != 0 {
+		t.Errorf("expected an ended relation hidden by default, got %+v", relations)
+	}
+	graph, _ := store.ReadGraph()
+	if len(graph.Relations) != 0 {
+		t.Errorf("expected an ended relation left out of the graph, got %+v", graph.Relations)
+	}
+
+	all, err := store.ListRelationsWithFilter("konfig", storage.RelationFilter{IncludeEnded: true})
+	if err != nil || len(all) != 1 || all[0].ValidTo == nil || all[0].ValidFrom == nil {
+		t.Fatalf("expected the ended relation with its window, got %+v, %v", all, err)
+	}
+	if d := all[0].ValidTo.Sub(endAt); d < -time.Second || d > time.Second {
+		t.Errorf("ValidTo = %v, want %v", all[0].ValidTo, endAt)
+	}
+
+	asOf, _ := store.ListRelationsWithFilter("konfig", storage.RelationFilter{AsOf: time.Now().Add(-36 * time.Hour)})
+	if len(asOf) != 1 {
+		t.Errorf("expected the relation valid 36h ago, got %+v", asOf)
+	}
+	before, _ := store.ListRelationsWithFilter("konfig", storage.RelationFilter{AsOf: time.Now().Add(-72 * time.Hour)})
+	if len(before) != 0 {
+		t.Errorf("expected no relation before it became valid, got %+v", before)
+	}
+
+	if err := store.EndRelation("konfig", "X", "depends_on", time.Time{}); !errors.Is(err, storage.ErrInvalidInput) {
+		t.Errorf("expected ending twice rejected, got %v", err)
+	}
+	if err := store.EndRelation("konfig", "X", "uses", time.Time{}); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	// Creating it again reopens it
+	if err := store.CreateRelation("konfig", "X", "depends_on"); err != nil {
+		t.Fatal(err)
+	}
+	if relations, _ := store.ListRelations("konfig"); len(relations) != 1 || relations[0].ValidTo != nil {
+		t.Errorf("expected the relation reopened, got %+v", relations)
+	}
+}
+
+func TestEndRelation_BeforeStart(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+
+	store.CreateEntity("A", "concept", nil)
+	store.CreateEntity("B", "concept", nil)
+	store.CreateRelation("A", "B", "uses")
+	if err := store.EndRelation("A", "B", "uses", time.Now().Add(-time.Hour)); !errors.Is(err, storage.ErrInvalidInput) {
+		t.Errorf("expected an end before the start rejected, got %v", err)
+	}
+}
@@ -0,0 +1,208 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// RelationTypeInfo describes a registered relation type.
+type RelationTypeInfo struct {
+	// Inverse is the type read from the other end: "uses" for "used_by"
+	Inverse string `json:"inverse,omitempty"`
+	// Symmetric types read the same from either end ("related_to"), so one
+	// relation links both ways; an Inverse equal to the type means the same
+	Symmetric   bool   `json:"symmetric,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// SetRelationTypes registers relation types with their inverses and
+// descriptions. Nil registers none, the default.
+func (s *Store) SetRelationTypes(types map[string]RelationTypeInfo) {
+	s.relationTypes = types
+}
+
+// RelationTypes returns the registered relation types.
+func (s *Store) RelationTypes() map[string]RelationTypeInfo {
+	return s.relationTypes
+}
+
+// ParseRelationTypes reads a comma-separated list of relation types, each
+// optionally followed by a colon and its inverse ("used_by:uses,depends_on"),
+// as in CLAUDE_MEMORY_RELATION_TYPES. A type that is its own inverse
+// ("related_to:related_to") is symmetric. An empty list is nil.
+func ParseRelationTypes(list string) map[string]RelationTypeInfo {
+	var types map[string]RelationTypeInfo
+	for _, entry := range strings.Split(list, ",") {
+		relationType, inverse, _ := strings.Cut(entry, ":")
+		if relationType = strings.TrimSpace(relationType); relationType == "" {
+			continue
+		}
+		if types == nil {
+			types = map[string]RelationTypeInfo{}
+		}
+		info := RelationTypeInfo{Inverse: strings.TrimSpace(inverse)}
+		if info.Inverse == relationType {
+			info = RelationTypeInfo{Symmetric: true}
+		}
+		types[relationType] = info
+	}
+	return types
+}
+
+// SymmetricRelationType reports whether relationType is registered as
+// symmetric.
+func (s *Store) SymmetricRelationType(relationType string) bool {
+	info, ok := s.relationTypes[relationType]
+	return ok && (info.Symmetric || info.Inverse == relationType)
+}
+
+// symmetricTypesSQL returns a condition on relations r selecting those of a
+// symmetric type.
+func (s *Store) symmetricTypesSQL() (string, []any) {
+	var args []any
+	for _, t := range slices.Sorted(maps.Keys(s.relationTypes)) {
+		if s.SymmetricRelationType(t) {
+			args = append(args, t)
+		}
+	}
+	if len(args) == 0 {
+		return "0", nil
+	}
+	return "r.relation_type IN (?" + strings.Repeat(", ?", len(args)-1) + ")", args
+}
+
+// storedRelationEnds returns the endpoints a relation of relationType between
+// fromID and toID is stored under: reversed when the type is symmetric and
+// the relation is stored the other way round, so one row serves both.
+func (s *Store) storedRelationEnds(ctx context.Context, q interface {
+	QueryRowContext(context.Context, string, ...any) *sql.Row
+}, fromID, toID int64, relationType string) (int64, int64, error) {
+	if !s.SymmetricRelationType(relationType) {
+		return fromID, toID, nil
+	}
+	var reversed bool
+	err := q.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM relations WHERE from_entity_id = ? AND to_entity_id = ? AND relation_type = ?)
+		AND NOT EXISTS (SELECT 1 FROM relations WHERE from_entity_id = ? AND to_entity_id = ? AND relation_type = ?)`,
+		toID, fromID, relationType, fromID, toID, relationType).Scan(&reversed)
+	if err != nil {
+		return 0, 0, err
+	}
+	if reversed {
+		return toID, fromID, nil
+	}
+	return fromID, toID, nil
+}
+
+// InverseRelationType returns the registered inverse of relationType, in
+// either direction of the registration, relationType itself when it is
+// symmetric, or "" when it has none.
+func (s *Store) InverseRelationType(relationType string) string {
+	if s.SymmetricRelationType(relationType) {
+		return relationType
+	}
+	if info, ok := s.relationTypes[relationType]; ok && info.Inverse != "" {
+		return info.Inverse
+	}
+	for _, registered := range slices.Sorted(maps.Keys(s.relationTypes)) {
+		if s.relationTypes[registered].Inverse == relationType {
+			return registered
+		}
+	}
+	return ""
+}
+
+// OrientRelation returns r as read from entity: when entity is its target
+// and its type has an inverse, r reversed under the inverse type, so "B
+// used_by A" listed for A reads "A uses B", and a symmetric relation simply
+// reversed. Otherwise r unchanged.
+func (s *Store) OrientRelation(r Relation, entity string) Relation {
+	if r.To != NormalizeName(entity) || r.From == r.To {
+		return r
+	}
+	if inverse := s.InverseRelationType(r.Type); inverse != "" {
+		r.From, r.To, r.Type = r.To, r.From, inverse
+	}
+	return r
+}
+
+// RelationTypeCount is a relation type with the number of relations of it.
+type RelationTypeCount struct {
+	Type        string `json:"type" db:"relation_type"`
+	Count       int    `json:"count" db:"count"`
+	Registered  bool   `json:"registered"`
+	Inverse     string `json:"inverse,omitempty"`
+	Symmetric   bool   `json:"symmetric,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Suggestion is the type an unregistered one is probably a typo of
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// maxRelationTypoDistance is the edit distance within which an unregistered
+// relation type is taken for a typo of another.
+const maxRelationTypoDistance = 2
+
+// ListRelationTypes returns every relation type in use, counting relations
+// valid now, and every registered type, used or not, most used first. An
+// unregistered type within two edits of a registered type, or of a type
+// used more, gets that type as its suggestion.
+func (s *Store) ListRelationTypes() ([]RelationTypeCount, error) {
+	return s.ListRelationTypesContext(context.Background())
+}
+
+// ListRelationTypesContext is ListRelationTypes with a context.
+func (s *Store) ListRelationTypesContext(ctx context.Context) ([]RelationTypeCount, error) {
+	validity, args := RelationFilter{}.sql()
+	var types []RelationTypeCount
+	if err := s.db.SelectContext(ctx, &types, `
+		SELECT r.relation_type, COUNT(*) as count FROM relations r
+		WHERE `+validity+`
+		GROUP BY r.relation_type`, args...); err != nil {
+		return nil, err
+	}
+
+	used := map[string]bool{}
+	for i, t := range types {
+		used[t.Type] = true
+		if info, ok := s.relationTypes[t.Type]; ok {
+			types[i].Registered = true
+			types[i].Inverse = info.Inverse
+			types[i].Symmetric = s.SymmetricRelationType(t.Type)
+			types[i].Description = info.Description
+		}
+	}
+	for registered, info := range s.relationTypes {
+		if !used[registered] {
+			types = append(types, RelationTypeCount{Type: registered, Registered: true, Inverse: info.Inverse,
+				Symmetric: s.SymmetricRelationType(registered), Description: info.Description})
+		}
+	}
+
+	slices.SortFunc(types, func(a, b RelationTypeCount) int {
+		if a.Count != b.Count {
+			return b.Count - a.Count
+		}
+		return strings.Compare(a.Type, b.Type)
+	})
+
+	// Inverses are known types too, though only registered under their pair
+	known := func(t RelationTypeCount) bool { return t.Registered || s.InverseRelationType(t.Type) != "" }
+	for i := range types {
+		if known(types[i]) {
+			continue
+		}
+		best := maxRelationTypoDistance + 1
+		for _, other := range types {
+			if other.Type == types[i].Type || !known(other) && other.Count <= types[i].Count {
+				continue
+			}
+			if d := editDistance([]rune(types[i].Type), []rune(other.Type)); d < best && d < len(types[i].Type)/2 {
+				best, types[i].Suggestion = d, other.Type
+			}
+		}
+	}
+	return types, nil
+}
@@ -0,0 +1,108 @@
+package storage
+
+import "testing"
+
+func TestParseRelationTypes(t *testing.T) {
+	got := ParseRelationTypes(" used_by:uses , depends_on,,")
+	if len(got) != 2 || got["used_by"].Inverse != "uses" || got["depends_on"].Inverse != "" {
+		t.Errorf("ParseRelationTypes = %+v", got)
+	}
+	if ParseRelationTypes("") != nil {
+		t.Error("expected an empty list to register none")
+	}
+}
+
+func TestOrientRelation(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+	store.SetRelationTypes(map[string]RelationTypeInfo{"used_by": {Inverse: "uses"}, "depends_on": {}})
+
+	if got := store.InverseRelationType("uses"); got != "used_by" {
+		t.Errorf("expected the inverse found from either end, got %q", got)
+	}
+
+	r := Relation{From: "TDD", To: "mark42", Type: "used_by"}
+	if got := store.OrientRelation(r, "TDD"); got != r {
+		t.Errorf("expected a relation read from its source unchanged, got %+v", got)
+	}
+	if got := store.OrientRelation(r, "mark42"); got.From != "mark42" || got.To != "TDD" || got.Type != "uses" {
+		t.Errorf("expected mark42 uses TDD, got %+v", got)
+	}
+	d := Relation{From: "api", To: "db", Type: "depends_on"}
+	if got := store.OrientRelation(d, "db"); got != d {
+		t.Errorf("expected a type without an inverse unchanged, got %+v", got)
+	}
+}
+
+func TestListRelationTypes(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	for _, name := range []string{"api", "db", "cache", "TDD", "mark42"} {
+		store.CreateEntity(name, "project", nil)
+	}
+	store.CreateRelation("api", "db", "depends_on")
+	store.CreateRelation("api", "cache", "depends_on")
+	store.CreateRelation("cache", "db", "depnds_on")
+	store.CreateRelation("TDD", "mark42", "used_by")
+	store.SetRelationTypes(map[string]RelationTypeInfo{
+		"used_by":    {Inverse: "uses"},
+		"depends_on": {Description: "Needs to work"},
+		"replaces":   {},
+	})
+
+	types, err := store.ListRelationTypes()
+	if err != nil {
+		t.Fatalf("ListRelationTypes: %v", err)
+	}
+	want := []RelationTypeCount{
+		{Type: "depends_on", Count: 2, Registered: true, Description: "Needs to work"},
+		{Type: "depnds_on", Count: 1, Suggestion: "depends_on"},
+		{Type: "used_by", Count: 1, Registered: true, Inverse: "uses"},
+		{Type: "replaces", Registered: true},
+	}
+	if len(types) != len(want) {
+		t.Fatalf("types = %+v, want %+v", types, want)
+	}
+	for i := range want {
+		if types[i] != want[i] {
+			t.Errorf("type %d = %+v, want %+v", i, types[i], want[i])
+		}
+	}
+}
+
+func TestSymmetricRelations(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+	store.SetRelationTypes(ParseRelationTypes("related_to:related_to"))
+	for _, name := range []string{"TDD", "BDD"} {
+		store.CreateEntity(name, "pattern", nil)
+	}
+
+	if err := store.CreateRelation("TDD", "BDD", "related_to"); err != nil {
+		t.Fatalf("CreateRelation: %v", err)
+	}
+	if err := store.CreateRelation("BDD", "TDD", "related_to"); err != nil {
+		t.Fatalf("CreateRelation reversed: %v", err)
+	}
+	var rows int
+	store.DB().Get(&rows, "SELECT COUNT(*) FROM relations")
+	if rows != 1 {
+		t.Errorf("expected one row for both directions, got %d", rows)
+	}
+
+	relations, _ := store.ListRelations("BDD")
+	if len(relations) != 1 {
+		t.Fatalf("expected the relation listed from either end, got %+v", relations)
+	}
+	if got := store.OrientRelation(*relations[0], "BDD"); got.From != "BDD" || got.To != "TDD" || got.Type != "related_to" {
+		t.Errorf("expected BDD related_to TDD, got %+v", got)
+	}
+
+	if err := store.DeleteRelation("BDD", "TDD", "related_to"); err != nil {
+		t.Errorf("expected a symmetric relation deleted from its other end, got %v", err)
+	}
+	if relations, _ := store.ListRelations("BDD"); len(relations) != 0 {
+		t.Errorf("expected no relations left for BDD, got %+v", relations)
+	}
+}
@@ -0,0 +1,585 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ReplicaRecord is one entity, observation, relation, or tombstone in a
+// replica export. Records carry a stable UID, the Lamport clock of their last
+// change, and the database (origin) that made it, so two diverged databases
+// can be merged deterministically: per record, the later (Clock, Origin) wins.
+type ReplicaRecord struct {
+	UID     string `json:"uid" db:"uid"`
+	Kind    string `json:"kind" db:"kind"` // SyncEntity, SyncObservation, or SyncRelation
+	Clock   int64  `json:"clock" db:"clock"`
+	Origin  string `json:"origin" db:"origin"`
+	Deleted bool   `json:"deleted,omitempty" db:"-"`
+	// Tombstones: when the record was deleted (RFC 3339, UTC), if known. A
+	// tombstone names what it deleted like a live record does, but an
+	// observation by its content hash alone.
+	DeletedAt string `json:"deletedAt,omitempty" db:"-"`
+
+	// Entities
+	Name         string `json:"name,omitempty" db:"name"`
+	EntityType   string `json:"entityType,omitempty" db:"entity_type"`
+	ContainerTag string `json:"containerTag,omitempty" db:"container_tag"`
+	Owner        string `json:"owner,omitempty" db:"owner"`
+
+	// Observations; the entity name resolves entities created independently
+	EntityUID   string `json:"entityUid,omitempty" db:"entity_uid"`
+	Entity      string `json:"entity,omitempty" db:"entity"`
+	Content     string `json:"content,omitempty" db:"content"`
+	FactType    string `json:"factType,omitempty" db:"fact_type"`
+	Pinned      bool   `json:"pinned,omitempty" db:"pinned"`
+	Suppressed  bool   `json:"suppressed,omitempty" db:"suppressed"`
+	Source      string `json:"source,omitempty" db:"source"` // Provenance; the merging store's source when empty
+	Author      string `json:"author,omitempty" db:"author"`
+	ContentHash string `json:"contentHash,omitempty" db:"-"` // Tombstones only
+
+	// Relations
+	FromUID      string `json:"fromUid,omitempty" db:"from_uid"`
+	From         string `json:"from,omitempty" db:"from_name"`
+	ToUID        string `json:"toUid,omitempty" db:"to_uid"`
+	To           string `json:"to,omitempty" db:"to_name"`
+	RelationType string `json:"relationType,omitempty" db:"relation_type"`
+	// Validity window (RFC 3339, UTC); empty when open
+	ValidFrom string `json:"validFrom,omitempty" db:"valid_from"`
+	ValidTo   string `json:"validTo,omitempty" db:"valid_to"`
+
+	// Observations, when exported with AddReplicaEmbeddings
+	Embedding *ReplicaEmbedding `json:"embedding,omitempty" db:"-"`
+}
+
+// ReplicaEmbedding is an observation's embedding in a replica export, so the
+// merging database can search it semantically without re-embedding.
+type ReplicaEmbedding struct {
+	Model  string `json:"model" db:"model"`
+	Dims   int    `json:"dims" db:"dimensions"`
+	Vector []byte `json:"vector" db:"embedding"` // Little-endian float64s; base64 in JSON
+}
+
+// replicaVersion is the (clock, origin) pair compared for last-writer-wins.
+type replicaVersion struct {
+	Clock  int64  `db:"clock"`
+	Origin string `db:"origin"`
+}
+
+func (v replicaVersion) newerThan(o replicaVersion) bool {
+	return v.Clock > o.Clock || v.Clock == o.Clock && v.Origin > o.Origin
+}
+
+func (r ReplicaRecord) version() replicaVersion {
+	return replicaVersion{Clock: r.Clock, Origin: r.Origin}
+}
+
+// ReplicaMergeStats counts what MergeReplica changed locally.
+type ReplicaMergeStats struct {
+	Created int
+	Updated int
+	Deleted int
+	Skipped int // Observations and relations whose entities do not exist here
+	// Embeddings stored for observations that had none here
+	Embedded int
+}
+
+// NodeID returns this database's replica identity.
+func (s *Store) NodeID() (string, error) {
+	return s.NodeIDContext(context.Background())
+}
+
+// NodeIDContext is NodeID with a context.
+func (s *Store) NodeIDContext(ctx context.Context) (string, error) {
+	var id string
+	err := s.db.GetContext(ctx, &id, `SELECT node_id FROM sync_state WHERE id = 1`)
+	return id, err
+}
+
+// ExportReplica returns the latest entities, their observations and
+// relations, and all tombstones, ordered by kind and UID.
+func (s *Store) ExportReplica() ([]ReplicaRecord, error) {
+	return s.ExportReplicaContext(context.Background())
+}
+
+// ExportReplicaContext is ExportReplica with a context.
+func (s *Store) ExportReplicaContext(ctx context.Context) ([]ReplicaRecord, error) {
+	var records []ReplicaRecord
+
+	var entities []ReplicaRecord
+	if err := s.db.SelectContext(ctx, &entities, `
+		SELECT uid, clock, COALESCE(origin, '') as origin, name,
+		       entity_type, COALESCE(container_tag, '') as container_tag,
+		       COALESCE(owner, '') as owner
+		FROM entities
+		WHERE uid IS NOT NULL AND (is_latest = 1 OR is_latest IS NULL)
+	`); err != nil {
+		return nil, err
+	}
+	for _, r := range entities {
+		r.Kind = SyncEntity
+		records = append(records, r)
+	}
+
+	var observations []ReplicaRecord
+	if err := s.db.SelectContext(ctx, &observations, `
+		SELECT o.uid, o.clock, COALESCE(o.origin, '') as origin,
+		       e.uid as entity_uid, e.name as entity, o.content,
+		       COALESCE(o.fact_type, 'dynamic') as fact_type,
+		       COALESCE(o.pinned, 0) as pinned, COALESCE(o.suppressed, 0) as suppressed,
+		       COALESCE(o.source, '') as source, COALESCE(o.author, '') as author
+		FROM observations o
+		JOIN entities e ON e.id = o.entity_id
+		WHERE o.uid IS NOT NULL AND (e.is_latest = 1 OR e.is_latest IS NULL)
+	`); err != nil {
+		return nil, err
+	}
+	for _, r := range observations {
+		r.Kind = SyncObservation
+		records = append(records, r)
+	}
+
+	var relations []ReplicaRecord
+	if err := s.db.SelectContext(ctx, &relations, `
+		SELECT r.uid, r.clock, COALESCE(r.origin, '') as origin,
+		       f.uid as from_uid, f.name as from_name, t.uid as to_uid, t.name as to_name, r.relation_type,
+		       COALESCE(strftime('%Y-%m-%dT%H:%M:%SZ', r.valid_from), '') as valid_from,
+		       COALESCE(strftime('%Y-%m-%dT%H:%M:%SZ', r.valid_to), '') as valid_to
+		FROM relations r
+		JOIN entities f ON f.id = r.from_entity_id
+		JOIN entities t ON t.id = r.to_entity_id
+		WHERE r.uid IS NOT NULL
+	`); err != nil {
+		return nil, err
+	}
+	for _, r := range relations {
+		r.Kind = SyncRelation
+		records = append(records, r)
+	}
+
+	var tombstones []struct {
+		replicaVersion
+		UID          string         `db:"uid"`
+		Kind         string         `db:"kind"`
+		Entity       sql.NullString `db:"entity"`
+		Target       sql.NullString `db:"target"`
+		RelationType sql.NullString `db:"relation_type"`
+		ContentHash  sql.NullString `db:"content_hash"`
+		DeletedAt    sql.NullTime   `db:"deleted_at"`
+	}
+	if err := s.db.SelectContext(ctx, &tombstones, `
+		SELECT uid, kind, clock, origin, entity, target, relation_type, content_hash, deleted_at
+		FROM tombstones
+	`); err != nil {
+		return nil, err
+	}
+	for _, t := range tombstones {
+		r := ReplicaRecord{UID: t.UID, Kind: t.Kind, Clock: t.Clock, Origin: t.Origin, Deleted: true}
+		if t.DeletedAt.Valid {
+			r.DeletedAt = t.DeletedAt.Time.UTC().Format(time.RFC3339)
+		}
+		switch t.Kind {
+		case SyncEntity:
+			r.Name = t.Entity.String
+		case SyncObservation:
+			r.Entity, r.ContentHash = t.Entity.String, t.ContentHash.String
+		case SyncRelation:
+			r.From, r.To, r.RelationType = t.Entity.String, t.Target.String, t.RelationType.String
+		}
+		records = append(records, r)
+	}
+
+	sortReplicaRecords(records)
+	return records, nil
+}
+
+// AddReplicaEmbeddings sets the embedding of each observation record that
+// has one stored.
+func (s *Store) AddReplicaEmbeddings(records []ReplicaRecord) error {
+	return s.AddReplicaEmbeddingsContext(context.Background(), records)
+}
+
+// AddReplicaEmbeddingsContext is AddReplicaEmbeddings with a context.
+func (s *Store) AddReplicaEmbeddingsContext(ctx context.Context, records []ReplicaRecord) error {
+	var rows []struct {
+		UID string `db:"uid"`
+		ReplicaEmbedding
+	}
+	if err := s.db.SelectContext(ctx, &rows, `
+		SELECT o.uid, oe.model, oe.dimensions, oe.embedding
+		FROM observation_embeddings oe
+		JOIN observations o ON o.id = oe.observation_id
+		WHERE o.uid IS NOT NULL
+	`); err != nil {
+		return err
+	}
+	embeddings := make(map[string]ReplicaEmbedding, len(rows))
+	for _, r := range rows {
+		embeddings[r.UID] = r.ReplicaEmbedding
+	}
+	for i, r := range records {
+		if e, ok := embeddings[r.UID]; ok && r.Kind == SyncObservation && !r.Deleted {
+			records[i].Embedding = &e
+		}
+	}
+	return nil
+}
+
+func sortReplicaRecords(records []ReplicaRecord) {
+	sort.Slice(records, func(i, j int) bool {
+		a, b := records[i], records[j]
+		if a.Deleted != b.Deleted {
+			return !a.Deleted
+		}
+		if a.Kind != b.Kind {
+			return kindOrder[a.Kind] < kindOrder[b.Kind]
+		}
+		return a.UID < b.UID
+	})
+}
+
+// MergeReplica merges records exported from another database. For each UID
+// the write with the later (clock, origin) wins, whether it is a change or a
+// tombstone. Records matching a local record by name (entities), content
+// (observations), or endpoints (relations) under a different UID are the same
+// record created on both sides; both databases settle on the smaller UID.
+// Afterwards the local clock is at least the highest clock merged.
+func (s *Store) MergeReplica(records []ReplicaRecord) (ReplicaMergeStats, error) {
+	return s.MergeReplicaContext(context.Background(), records)
+}
+
+// MergeReplicaContext is MergeReplica with a context.
+func (s *Store) MergeReplicaContext(ctx context.Context, records []ReplicaRecord) (ReplicaMergeStats, error) {
+	var stats ReplicaMergeStats
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return stats, err
+	}
+	defer tx.Rollback()
+
+	var rows []struct {
+		UID string `db:"uid"`
+		replicaVersion
+	}
+	if err := tx.SelectContext(ctx, &rows, `SELECT uid, clock, origin FROM tombstones`); err != nil {
+		return stats, err
+	}
+	tombstones := make(map[string]replicaVersion, len(rows))
+	for _, r := range rows {
+		tombstones[r.UID] = r.replicaVersion
+	}
+
+	sorted := append([]ReplicaRecord(nil), records...)
+	sortReplicaRecords(sorted)
+
+	// Live records parents first; tombstones children first
+	var live, deleted []ReplicaRecord
+	var maxClock int64
+	fallbackSource, _ := s.sourceFor(ctx).(string)
+	for _, r := range sorted {
+		maxClock = max(maxClock, r.Clock)
+		if r.Kind == SyncObservation {
+			if r.Source == "" {
+				r.Source = fallbackSource
+			}
+			// An embedding of a secret would still carry it
+			if content := s.redact(ctx, r.Entity, r.Content); content != r.Content {
+				r.Content, r.Embedding = content, nil
+			}
+		}
+		if r.Deleted {
+			deleted = append(deleted, r)
+		} else {
+			live = append(live, r)
+		}
+	}
+	sort.SliceStable(deleted, func(i, j int) bool {
+		return kindOrder[deleted[i].Kind] > kindOrder[deleted[j].Kind]
+	})
+
+	for _, r := range live {
+		if ts, ok := tombstones[r.UID]; ok && !r.version().newerThan(ts) {
+			continue
+		}
+		if err := mergeReplicaRecord(ctx, tx, r, &stats); err != nil {
+			return stats, err
+		}
+	}
+	for _, r := range deleted {
+		if err := mergeReplicaTombstone(ctx, tx, r, tombstones, &stats); err != nil {
+			return stats, err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE sync_state SET clock = MAX(clock, ?)`, maxClock); err != nil {
+		return stats, err
+	}
+	return stats, tx.Commit()
+}
+
+var replicaTables = map[string]string{
+	SyncEntity:      "entities",
+	SyncObservation: "observations",
+	SyncRelation:    "relations",
+}
+
+// localReplicaRow is the local row a record merges into.
+type localReplicaRow struct {
+	ID   int64  `db:"id"`
+	UID  string `db:"uid"`
+	Name string `db:"name"` // Entities only
+	replicaVersion
+}
+
+// findReplicaRow looks a record up by UID, then by its natural key. A match by
+// natural key adopts the smaller of the two UIDs.
+func findReplicaRow(ctx context.Context, tx *sqlx.Tx, r ReplicaRecord, naturalKey string, args ...any) (*localReplicaRow, error) {
+	table := replicaTables[r.Kind]
+	row, err := replicaRowByUID(ctx, tx, table, r.UID)
+	if err == nil {
+		return row, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	row = &localReplicaRow{}
+	err = tx.GetContext(ctx, row, `SELECT id, uid, clock, COALESCE(origin, '') as origin FROM `+table+` WHERE `+naturalKey, args...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if r.UID < row.UID {
+		if _, err := tx.ExecContext(ctx, `UPDATE `+table+` SET uid = ? WHERE id = ?`, r.UID, row.ID); err != nil {
+			return nil, err
+		}
+		row.UID = r.UID
+	}
+	return row, nil
+}
+
+// replicaRowByUID returns the row of table with uid. Only the latest version
+// of an entity has one, but the filter keeps a superseded version from ever
+// standing in for it.
+func replicaRowByUID(ctx context.Context, tx *sqlx.Tx, table, uid string) (*localReplicaRow, error) {
+	query := `SELECT id, uid, clock, COALESCE(origin, '') as origin FROM ` + table + ` WHERE uid = ?`
+	if table == "entities" {
+		query = `SELECT id, uid, name, clock, COALESCE(origin, '') as origin FROM entities
+			WHERE uid = ? AND (is_latest = 1 OR is_latest IS NULL)`
+	}
+	var row localReplicaRow
+	if err := tx.GetContext(ctx, &row, query, uid); err != nil {
+		return nil, err
+	}
+	return &row, nil
+}
+
+// resolveReplicaEntity finds the local entity for a reference by UID, falling
+// back to the latest entity with the name.
+func resolveReplicaEntity(ctx context.Context, tx *sqlx.Tx, uid, name string) (int64, bool, error) {
+	var id int64
+	err := tx.GetContext(ctx, &id, `SELECT id FROM entities WHERE uid = ? AND (is_latest = 1 OR is_latest IS NULL)`, uid)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = tx.GetContext(ctx, &id, `SELECT id FROM entities WHERE name = ? AND (is_latest = 1 OR is_latest IS NULL)
+			ORDER BY id DESC LIMIT 1`, name)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	return id, err == nil, err
+}
+
+func mergeReplicaRecord(ctx context.Context, tx *sqlx.Tx, r ReplicaRecord, stats *ReplicaMergeStats) error {
+	var containerTag any
+	if r.ContainerTag != "" {
+		containerTag = r.ContainerTag
+	}
+
+	switch r.Kind {
+	case SyncEntity:
+		row, err := findReplicaRow(ctx, tx, r, `name = ? AND (is_latest = 1 OR is_latest IS NULL) ORDER BY id DESC LIMIT 1`, r.Name)
+		if err != nil {
+			return err
+		}
+		if row == nil {
+			_, err := tx.ExecContext(ctx, `INSERT INTO entities (name, entity_type, container_tag, uid, clock, origin, owner)
+				VALUES (?, ?, ?, ?, ?, ?, ?)`, r.Name, r.EntityType, containerTag, r.UID, r.Clock, r.Origin, sourceValue(r.Owner))
+			stats.Created++
+			return err
+		}
+		if r.version().newerThan(row.replicaVersion) {
+			_, err := tx.ExecContext(ctx, `UPDATE entities SET entity_type = ?, container_tag = ?, clock = ?, origin = ? WHERE id = ?`,
+				r.EntityType, containerTag, r.Clock, r.Origin, row.ID)
+			stats.Updated++
+			return err
+		}
+
+	case SyncObservation:
+		entityID, ok, err := resolveReplicaEntity(ctx, tx, r.EntityUID, r.Entity)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			stats.Skipped++
+			return nil
+		}
+		row, err := findReplicaRow(ctx, tx, r, `entity_id = ? AND content = ?`, entityID, r.Content)
+		if err != nil {
+			return err
+		}
+		if row == nil {
+			result, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO observations (entity_id, content, content_hash, fact_type, pinned, suppressed, uid, clock, origin, source, author)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, entityID, r.Content, contentHash(r.Content), r.FactType, r.Pinned, r.Suppressed, r.UID, r.Clock, r.Origin,
+				sourceValue(r.Source), sourceValue(r.Author))
+			if err != nil {
+				return err
+			}
+			stats.Created++
+			if rowsAffected(result) == 0 {
+				return nil
+			}
+			id, err := result.LastInsertId()
+			if err != nil {
+				return err
+			}
+			return mergeReplicaEmbedding(ctx, tx, id, r, stats)
+		}
+		if r.version().newerThan(row.replicaVersion) {
+			// An edit elsewhere replaces the content, and with it the embedding
+			if _, err := tx.ExecContext(ctx, `DELETE FROM observation_embeddings
+				WHERE observation_id = ? AND (SELECT content FROM observations WHERE id = ?) != ?`, row.ID, row.ID, r.Content); err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, `UPDATE OR IGNORE observations SET entity_id = ?, content = ?, content_hash = ?, fact_type = ?,
+				pinned = ?, suppressed = ?, clock = ?, origin = ? WHERE id = ?`, entityID, r.Content, contentHash(r.Content), r.FactType,
+				r.Pinned, r.Suppressed, r.Clock, r.Origin, row.ID); err != nil {
+				return err
+			}
+			stats.Updated++
+		}
+		return mergeReplicaEmbedding(ctx, tx, row.ID, r, stats)
+
+	case SyncRelation:
+		fromID, okFrom, err := resolveReplicaEntity(ctx, tx, r.FromUID, r.From)
+		if err != nil {
+			return err
+		}
+		toID, okTo, err := resolveReplicaEntity(ctx, tx, r.ToUID, r.To)
+		if err != nil {
+			return err
+		}
+		if !okFrom || !okTo {
+			stats.Skipped++
+			return nil
+		}
+		row, err := findReplicaRow(ctx, tx, r, `from_entity_id = ? AND to_entity_id = ? AND relation_type = ?`,
+			fromID, toID, r.RelationType)
+		if err != nil {
+			return err
+		}
+		if row == nil {
+			_, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO relations (from_entity_id, to_entity_id, relation_type, valid_from, valid_to, uid, clock, origin)
+				VALUES (?, ?, ?, datetime(?), datetime(?), ?, ?, ?)`, fromID, toID, r.RelationType, nullIfEmpty(r.ValidFrom), nullIfEmpty(r.ValidTo),
+				r.UID, r.Clock, r.Origin)
+			stats.Created++
+			return err
+		}
+		if r.version().newerThan(row.replicaVersion) {
+			_, err := tx.ExecContext(ctx, `UPDATE relations SET valid_from = datetime(?), valid_to = datetime(?), clock = ?, origin = ? WHERE id = ?`,
+				nullIfEmpty(r.ValidFrom), nullIfEmpty(r.ValidTo), r.Clock, r.Origin, row.ID)
+			stats.Updated++
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeReplicaEmbedding stores the record's embedding for observation id
+// unless it already has one. A merged edit of the content drops the old
+// embedding first.
+func mergeReplicaEmbedding(ctx context.Context, tx *sqlx.Tx, id int64, r ReplicaRecord, stats *ReplicaMergeStats) error {
+	e := r.Embedding
+	if e == nil {
+		return nil
+	}
+	if e.Dims <= 0 || len(e.Vector) != e.Dims*8 {
+		return fmt.Errorf("observation %s: embedding has %d bytes for %d dimensions", r.UID, len(e.Vector), e.Dims)
+	}
+	result, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO observation_embeddings (observation_id, embedding, model, dimensions)
+		VALUES (?, ?, ?, ?)`, id, e.Vector, e.Model, e.Dims)
+	if err != nil {
+		return err
+	}
+	stats.Embedded += rowsAffected(result)
+	return nil
+}
+
+// mergeReplicaTombstone deletes the local record if the tombstone is newer, and
+// keeps the tombstone so the deletion also wins against later merges.
+func mergeReplicaTombstone(ctx context.Context, tx *sqlx.Tx, r ReplicaRecord, tombstones map[string]replicaVersion, stats *ReplicaMergeStats) error {
+	table, ok := replicaTables[r.Kind]
+	if !ok {
+		return nil
+	}
+
+	row, err := replicaRowByUID(ctx, tx, table, r.UID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		if ts, ok := tombstones[r.UID]; ok && !r.version().newerThan(ts) {
+			return nil
+		}
+	case err != nil:
+		return err
+	default:
+		if !r.version().newerThan(row.replicaVersion) {
+			return nil // Changed here after the deletion; the change wins
+		}
+		if r.Kind == SyncEntity {
+			err = deleteEntityRows(ctx, tx, row.Name)
+		} else {
+			_, err = tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE id = ?`, row.ID)
+		}
+		if err != nil {
+			return err
+		}
+		stats.Deleted++
+	}
+
+	// Give the tombstone the delete trigger wrote the original deletion's
+	// version and time, keeping the names it recorded
+	entity, deletedAt := r.Name, any(nil)
+	switch r.Kind {
+	case SyncObservation:
+		entity = r.Entity
+	case SyncRelation:
+		entity = r.From
+	}
+	if at, err := time.Parse(time.RFC3339, r.DeletedAt); err == nil {
+		deletedAt = sqlTime(at)
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO tombstones (uid, kind, clock, origin, entity, target, relation_type, content_hash, deleted_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (uid) DO UPDATE SET kind = excluded.kind, clock = excluded.clock, origin = excluded.origin,
+			deleted_at = COALESCE(excluded.deleted_at, deleted_at)`,
+		r.UID, r.Kind, r.Clock, r.Origin, nullIfEmpty(entity), nullIfEmpty(r.To), nullIfEmpty(r.RelationType),
+		nullIfEmpty(r.ContentHash), deletedAt)
+	tombstones[r.UID] = r.version()
+	return err
+}
+
+// nullIfEmpty stores an empty tombstone name as NULL.
+func nullIfEmpty(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
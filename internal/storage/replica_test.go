@@ -0,0 +1,297 @@
+package storage
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+)
+
+// liveReplica returns the live records of an export, for comparing databases.
+func liveReplica(t *testing.T, s *Store) []ReplicaRecord {
+	t.Helper()
+	records, err := s.ExportReplica()
+	if err != nil {
+		t.Fatalf("ExportReplica failed: %v", err)
+	}
+	return slices.DeleteFunc(records, func(r ReplicaRecord) bool { return r.Deleted })
+}
+
+func mergeInto(t *testing.T, dst, src *Store) ReplicaMergeStats {
+	t.Helper()
+	records, err := src.ExportReplica()
+	if err != nil {
+		t.Fatalf("ExportReplica failed: %v", err)
+	}
+	stats, err := dst.MergeReplica(records)
+	if err != nil {
+		t.Fatalf("MergeReplica failed: %v", err)
+	}
+	return stats
+}
+
+func TestExportReplica_StableIdentity(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	store.CreateEntity("Go", "language", []string{"Fast compiler"})
+	first := liveReplica(t, store)
+	if len(first) != 2 {
+		t.Fatalf("expected entity and observation records, got %+v", first)
+	}
+	for _, r := range first {
+		if len(r.UID) != 36 || r.Clock == 0 || r.Origin == "" {
+			t.Errorf("record missing identity: %+v", r)
+		}
+	}
+
+	// Pinning is a new write: same UID, later clock
+	store.SetObservationPinned("Go", "Fast compiler", true)
+	second := liveReplica(t, store)
+	if second[1].UID != first[1].UID || second[1].Clock <= first[1].Clock || !second[1].Pinned {
+		t.Errorf("expected pinned observation with same UID and later clock, was %+v now %+v", first[1], second[1])
+	}
+
+	store.DeleteObservation("Go", "Fast compiler")
+	all, _ := store.ExportReplica()
+	last := all[len(all)-1]
+	if !last.Deleted || last.UID != first[1].UID || last.Clock <= second[1].Clock {
+		t.Errorf("expected tombstone for the observation, got %+v", last)
+	}
+}
+
+func TestMergeReplica_DivergedDatabasesConverge(t *testing.T) {
+	desktop := newTestStoreWithMigrations(t)
+	defer desktop.Close()
+	laptop := newTestStoreWithMigrations(t)
+	defer laptop.Close()
+
+	desktop.CreateEntity("Go", "language", []string{"Fast compiler", "Has generics"})
+	mergeInto(t, laptop, desktop)
+
+	// Diverge
+	desktop.DeleteObservation("Go", "Has generics")
+	desktop.CreateEntity("Docker", "tool", []string{"Used for CI"})
+	laptop.AddObservation("Go", "GC pauses are short")
+	laptop.CreateEntity("Docker", "tool", nil) // Same entity, created independently
+	laptop.CreateRelation("Docker", "Go", "builds")
+
+	mergeInto(t, desktop, laptop)
+	mergeInto(t, laptop, desktop)
+
+	a, b := liveReplica(t, desktop), liveReplica(t, laptop)
+	if !slices.Equal(a, b) {
+		t.Fatalf("databases differ after merging both ways:\n%+v\n%+v", a, b)
+	}
+
+	entity, err := laptop.GetEntity("Go")
+	if err != nil {
+		t.Fatalf("GetEntity failed: %v", err)
+	}
+	slices.Sort(entity.Observations)
+	if want := []string{"Fast compiler", "GC pauses are short"}; !slices.Equal(entity.Observations, want) {
+		t.Errorf("observations = %v, want %v", entity.Observations, want)
+	}
+
+	dockers, _ := desktop.ListEntities("tool")
+	if len(dockers) != 1 {
+		t.Errorf("expected independently created Docker entities to merge, got %d", len(dockers))
+	}
+	docker, _ := laptop.GetEntity("Docker")
+	if len(docker.Observations) != 1 {
+		t.Errorf("expected desktop's Docker observation on laptop, got %v", docker.Observations)
+	}
+}
+
+func TestMergeReplica_LastWriterWins(t *testing.T) {
+	desktop := newTestStoreWithMigrations(t)
+	defer desktop.Close()
+	laptop := newTestStoreWithMigrations(t)
+	defer laptop.Close()
+
+	desktop.CreateEntity("Go", "language", []string{"Fast compiler"})
+	mergeInto(t, laptop, desktop)
+
+	// Both change the same observation; the laptop's change is later
+	desktop.SetObservationPinned("Go", "Fast compiler", true)
+	laptop.AddObservation("Go", "Unrelated")
+	laptop.SetObservationSuppressed("Go", "Fast compiler", true)
+
+	mergeInto(t, desktop, laptop)
+	mergeInto(t, laptop, desktop)
+
+	for _, s := range []*Store{desktop, laptop} {
+		pinned, _ := s.ListPinnedObservations()
+		suppressed, _ := s.ListSuppressedObservations()
+		if len(pinned) != 0 || len(suppressed) != 1 {
+			t.Errorf("expected laptop's write to win: %d pinned, %d suppressed", len(pinned), len(suppressed))
+		}
+	}
+
+	// A deletion later than an edit wins too, and is not undone by re-merging
+	desktop.DeleteEntity("Go")
+	mergeInto(t, laptop, desktop)
+	mergeInto(t, desktop, laptop)
+	for _, s := range []*Store{desktop, laptop} {
+		if _, err := s.GetEntity("Go"); err == nil {
+			t.Error("expected deleted entity to stay deleted after merging")
+		}
+	}
+	if n := laptop.CountObservations(); n != 0 {
+		t.Errorf("expected the entity's observations deleted with it, %d left", n)
+	}
+}
+
+func TestMergeReplica_Embeddings(t *testing.T) {
+	src := newTestStoreWithMigrations(t)
+	defer src.Close()
+	dst := newTestStoreWithMigrations(t)
+	defer dst.Close()
+
+	src.CreateEntity("Go", "language", []string{"Fast compiler"})
+	pending, err := src.GetObservationsWithoutEmbeddings()
+	if err != nil || len(pending) != 1 {
+		t.Fatalf("expected one observation to embed, got %+v, %v", pending, err)
+	}
+	vector := []float64{0.25, -1, 3.5}
+	if err := src.StoreEmbedding(pending[0].ID, vector, "test-model"); err != nil {
+		t.Fatalf("StoreEmbedding failed: %v", err)
+	}
+
+	records, err := src.ExportReplica()
+	if err != nil {
+		t.Fatalf("ExportReplica failed: %v", err)
+	}
+	if err := src.AddReplicaEmbeddings(records); err != nil {
+		t.Fatalf("AddReplicaEmbeddings failed: %v", err)
+	}
+	data, err := json.Marshal(records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded []ReplicaRecord
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := dst.MergeReplica(decoded)
+	if err != nil {
+		t.Fatalf("MergeReplica failed: %v", err)
+	}
+	if stats.Embedded != 1 {
+		t.Errorf("expected 1 embedding imported, got %+v", stats)
+	}
+	var id int64
+	if err := dst.db.Get(&id, "SELECT id FROM observations WHERE content = 'Fast compiler'"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := dst.GetEmbedding(id)
+	if err != nil || !slices.Equal(got, vector) {
+		t.Errorf("expected the embedding %v, got %v, %v", vector, got, err)
+	}
+
+	// Merging again keeps the embedding already stored
+	if stats, _ := dst.MergeReplica(decoded); stats.Embedded != 0 {
+		t.Errorf("expected no embeddings imported twice, got %+v", stats)
+	}
+
+	other := newTestStoreWithMigrations(t)
+	defer other.Close()
+	decoded[1].Embedding.Dims = 4
+	if _, err := other.MergeReplica(decoded); err == nil {
+		t.Error("expected an error for an embedding of the wrong size")
+	}
+}
+
+func TestMergeReplica_NewVersionKeepsUID(t *testing.T) {
+	desktop := newTestStoreWithMigrations(t)
+	defer desktop.Close()
+	laptop := newTestStoreWithMigrations(t)
+	defer laptop.Close()
+
+	desktop.CreateEntity("Go", "language", nil)
+	mergeInto(t, laptop, desktop)
+	first := liveReplica(t, desktop)
+
+	if _, err := desktop.CreateOrUpdateEntity("Go", "tool", nil); err != nil {
+		t.Fatalf("CreateOrUpdateEntity failed: %v", err)
+	}
+	second := liveReplica(t, desktop)
+	if len(second) != 1 || second[0].UID != first[0].UID || second[0].Clock <= first[0].Clock {
+		t.Fatalf("expected the new version under the same UID with a later clock, was %+v now %+v", first, second)
+	}
+
+	stats := mergeInto(t, laptop, desktop)
+	if stats.Created != 0 || stats.Updated != 1 {
+		t.Errorf("expected the entity updated in place, got %+v", stats)
+	}
+	if tools, _ := laptop.ListEntities("tool"); len(tools) != 1 {
+		t.Errorf("expected the new type on laptop, got %+v", tools)
+	}
+
+	// Deleting every version leaves one tombstone, which deletes it everywhere
+	desktop.DeleteEntity("Go")
+	all, _ := desktop.ExportReplica()
+	if len(all) != 1 || !all[0].Deleted || all[0].UID != first[0].UID {
+		t.Fatalf("expected a single tombstone, got %+v", all)
+	}
+	mergeInto(t, laptop, desktop)
+	if _, err := laptop.GetEntity("Go"); err == nil {
+		t.Error("expected the entity deleted on laptop")
+	}
+}
+
+func TestMergeReplica_ObservationEdit(t *testing.T) {
+	a := newTestStoreWithMigrations(t)
+	defer a.Close()
+	b := newTestStoreWithMigrations(t)
+	defer b.Close()
+
+	a.CreateEntity("Go", "language", []string{"old text"})
+	mergeInto(t, b, a)
+
+	if err := a.UpdateObservation("Go", "old text", "new text"); err != nil {
+		t.Fatalf("UpdateObservation failed: %v", err)
+	}
+	if stats := mergeInto(t, b, a); stats.Updated != 1 {
+		t.Errorf("expected the edit merged as an update, got %+v", stats)
+	}
+	entity, err := b.GetEntity("Go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(entity.Observations, []string{"new text"}) {
+		t.Errorf("expected the peer to hold the edited text, got %q", entity.Observations)
+	}
+}
+
+func TestMergeReplica_RelationEnded(t *testing.T) {
+	a := newTestStoreWithMigrations(t)
+	defer a.Close()
+	b := newTestStoreWithMigrations(t)
+	defer b.Close()
+
+	a.CreateEntity("Alice", "person", nil)
+	a.CreateEntity("Acme", "company", nil)
+	if err := a.CreateRelation("Alice", "Acme", "works_at"); err != nil {
+		t.Fatal(err)
+	}
+	mergeInto(t, b, a)
+
+	if err := a.EndRelation("Alice", "Acme", "works_at", time.Time{}); err != nil {
+		t.Fatalf("EndRelation failed: %v", err)
+	}
+	if stats := mergeInto(t, b, a); stats.Updated != 1 {
+		t.Errorf("expected the ended relation merged as an update, got %+v", stats)
+	}
+	if relations, _ := b.ListRelations("Alice"); len(relations) != 0 {
+		t.Errorf("expected no current relations on the peer, got %+v", relations)
+	}
+	history, err := b.ListRelationsWithFilter("Alice", RelationFilter{IncludeEnded: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].ValidTo == nil {
+		t.Errorf("expected the relation kept with its end, got %+v", history)
+	}
+}
@@ -0,0 +1,195 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	resumeMaxFiles      = 10
+	resumeMaxFacts      = 5
+	resumeMinImportance = 0.5
+)
+
+// ResumeBrief is what a new session needs to pick up where the last one stopped.
+type ResumeBrief struct {
+	Project     string
+	Session     *Session // Most recent incomplete session; nil when all are complete
+	LastSession *Session // Most recent completed session, used when Session is nil
+	LastPrompt  string   // Last recorded user prompt of Session
+	Files       []string // Files modified but not yet captured, then files the session touched
+	Facts       []ContextResult
+}
+
+// GetResumeBrief combines the most recent incomplete session for the project,
+// the files still in progress, and the highest-importance dynamic facts.
+// dirtyFiles are the tracked-but-uncaptured files from the hook state, if known.
+func (s *Store) GetResumeBrief(project string, dirtyFiles []string) (*ResumeBrief, error) {
+	return s.GetResumeBriefContext(context.Background(), project, dirtyFiles)
+}
+
+// GetResumeBriefContext is GetResumeBrief with a context.
+func (s *Store) GetResumeBriefContext(ctx context.Context, project string, dirtyFiles []string) (*ResumeBrief, error) {
+	brief := &ResumeBrief{Project: project}
+
+	sessions, err := s.ListSessionsSinceContext(ctx, project, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	for i := len(sessions) - 1; i >= 0; i-- {
+		session := sessions[i]
+		if session.Status == "active" && brief.Session == nil {
+			brief.Session = session
+		}
+		if session.Status == "completed" && brief.LastSession == nil {
+			brief.LastSession = session
+		}
+	}
+
+	for _, f := range dirtyFiles {
+		f = strings.SplitN(f, " [", 2)[0] // strip hook annotations
+		if f != "" && !slices.Contains(brief.Files, f) {
+			brief.Files = append(brief.Files, f)
+		}
+	}
+	if brief.Session != nil {
+		for i := len(brief.Session.Timeline) - 1; i >= 0; i-- {
+			entry := brief.Session.Timeline[i]
+			if entry.FactType == FactTypeSessionTurn && brief.LastPrompt == "" {
+				brief.LastPrompt = entry.Content
+			}
+			if f := entry.Event.FilePath; f != "" && !slices.Contains(brief.Files, f) {
+				brief.Files = append(brief.Files, f)
+			}
+		}
+	}
+	if len(brief.Files) > resumeMaxFiles {
+		brief.Files = brief.Files[:resumeMaxFiles]
+	}
+
+	brief.Facts, err = s.projectDynamicFacts(ctx, project, resumeMaxFacts)
+	if err != nil {
+		return nil, err
+	}
+	return brief, nil
+}
+
+// projectDynamicFacts returns the most important dynamic facts, preferring
+// entities that mention the project or that its sessions worked on.
+func (s *Store) projectDynamicFacts(ctx context.Context, project string, limit int) ([]ContextResult, error) {
+	var results []ContextResult
+	err := s.db.SelectContext(ctx, &results, `
+		SELECT o.id as observation_id, e.name as entity_name, e.entity_type, o.content,
+		       COALESCE(o.fact_type, 'dynamic') as fact_type,
+		       COALESCE(o.importance, 1.0) as importance
+		FROM observations o
+		JOIN entities e ON e.id = o.entity_id
+		WHERE e.is_latest = 1 AND e.entity_type != 'session'
+		AND COALESCE(o.fact_type, 'dynamic') = 'dynamic'
+		AND COALESCE(o.importance, 1.0) >= ?
+		AND COALESCE(o.suppressed, 0) = 0
+		ORDER BY o.importance DESC
+	`, resumeMinImportance)
+	if err != nil {
+		return nil, err
+	}
+
+	worked := map[string]bool{}
+	if project != "" {
+		var names []string
+		if err := s.db.SelectContext(ctx, &names, `
+			SELECT DISTINCT e_to.name
+			FROM relations r
+			JOIN entities e_from ON e_from.id = r.from_entity_id
+			JOIN entities e_to ON e_to.id = r.to_entity_id
+			WHERE r.relation_type = ? AND e_from.entity_type = 'session'
+			AND json_extract(e_from.container_tag, '$.project') = ?
+		`, RelationWorkedOn, project); err != nil {
+			return nil, err
+		}
+		for _, n := range names {
+			worked[n] = true
+		}
+	}
+
+	lowerProject := strings.ToLower(project)
+	for i := range results {
+		results[i].FinalScore = results[i].Importance
+		if project == "" {
+			continue
+		}
+		if worked[results[i].EntityName] ||
+			strings.Contains(strings.ToLower(results[i].EntityName), lowerProject) ||
+			strings.Contains(strings.ToLower(results[i].Content), lowerProject) {
+			results[i].FinalScore *= DefaultContextConfig().ProjectBoost
+		}
+	}
+	slices.SortStableFunc(results, func(a, b ContextResult) int {
+		switch {
+		case a.FinalScore > b.FinalScore:
+			return -1
+		case a.FinalScore < b.FinalScore:
+			return 1
+		}
+		return 0
+	})
+
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// FormatResumeBrief renders the brief as a short block for the start of a session.
+func FormatResumeBrief(b *ResumeBrief) string {
+	var sb strings.Builder
+	title := "Resume Work"
+	if b.Project != "" {
+		title += ": " + b.Project
+	}
+	sb.WriteString("=== " + title + " ===\n\n")
+
+	switch {
+	case b.Session != nil:
+		doing := b.Session.Summary
+		if doing == "" && b.LastPrompt != "" {
+			doing = strings.TrimPrefix(b.LastPrompt, "User: ")
+		}
+		if doing == "" {
+			doing = "an unfinished session"
+		}
+		sb.WriteString("You were doing: " + doing + "\n")
+		sb.WriteString("  (" + b.Session.Name + ", started " + b.Session.StartedAt.Format("2006-01-02 15:04") +
+			", " + formatInt(b.Session.EventCount) + " events, not completed)\n")
+		if b.LastPrompt != "" && b.Session.Summary != "" {
+			sb.WriteString("Last request: " + strings.TrimPrefix(b.LastPrompt, "User: ") + "\n")
+		}
+		if b.LastSession != nil && b.LastSession.Summary != "" {
+			sb.WriteString("Before that: " + b.LastSession.Summary + "\n")
+		}
+	case b.LastSession != nil:
+		sb.WriteString("Last session: " + b.LastSession.Summary + "\n")
+		sb.WriteString("  (" + b.LastSession.Name + ", ended " + b.LastSession.EndedAt.Format("2006-01-02 15:04") + ")\n")
+	default:
+		sb.WriteString("No previous sessions found.\n")
+	}
+
+	if len(b.Files) > 0 {
+		sb.WriteString("\nFiles in progress:\n")
+		for _, f := range b.Files {
+			sb.WriteString("- " + filepath.ToSlash(f) + "\n")
+		}
+	}
+
+	if len(b.Facts) > 0 {
+		sb.WriteString("\nNext steps and open facts:\n")
+		for _, f := range b.Facts {
+			sb.WriteString("- [" + f.EntityName + "] " + f.Content + "\n")
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,40 @@
+package storage
+
+import "testing"
+
+func TestGetResumeBrief_PrefersProjectFacts(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	store.CreateEntity("Billing", "component", []string{"Invoices are generated nightly"})
+	store.SetObservationImportance("Billing", "Invoices are generated nightly", 0.9)
+	store.CreateEntity("Parser", "component", []string{"Comments are not parsed yet"})
+	store.SetObservationImportance("Parser", "Comments are not parsed yet", 0.7)
+	store.CreateEntity("Static", "component", nil)
+	store.AddObservationWithType("Static", "Written in Go", FactTypeStatic)
+
+	// A myapp session worked on Parser, so its facts outrank more important ones elsewhere
+	session, _ := store.CreateSession("myapp")
+	store.CompleteSession(session.Name, "Worked on the parser")
+
+	brief, err := store.GetResumeBrief("myapp", []string{"/myapp/a.go [modified]", "/myapp/a.go"})
+	if err != nil {
+		t.Fatalf("GetResumeBrief failed: %v", err)
+	}
+
+	if brief.Session != nil {
+		t.Errorf("expected no incomplete session, got %s", brief.Session.Name)
+	}
+	if brief.LastSession == nil || brief.LastSession.Name != session.Name {
+		t.Errorf("expected last session %s, got %+v", session.Name, brief.LastSession)
+	}
+	if len(brief.Files) != 1 || brief.Files[0] != "/myapp/a.go" {
+		t.Errorf("expected deduplicated dirty file, got %v", brief.Files)
+	}
+	if len(brief.Facts) != 2 {
+		t.Fatalf("expected 2 dynamic facts, got %d", len(brief.Facts))
+	}
+	if brief.Facts[0].EntityName != "Parser" {
+		t.Errorf("expected project fact first, got %s", brief.Facts[0].EntityName)
+	}
+}
@@ -0,0 +1,95 @@
+package storage
+
+import "context"
+
+// retentionScopes are the groups DecayConfig's keep limits apply to: each
+// entity, then each container tag, with sessions counting under their
+// project. Entities without a tag are not limited by KeepPerContainer.
+var retentionScopes = []struct {
+	key   string // Partitions observations into groups
+	where string // Leaves out observations no group holds
+	limit func(DecayConfig) int
+}{
+	{"o.entity_id", "", func(cfg DecayConfig) int { return cfg.KeepPerEntity }},
+	{containerKeySQL, "AND COALESCE(" + containerKeySQL + ", '') != ''", func(cfg DecayConfig) int { return cfg.KeepPerContainer }},
+}
+
+// ArchiveExcessMemories enforces count-based retention: beyond the
+// KeepPerEntity most important observations of an entity, and the
+// KeepPerContainer most important under a container tag, the rest are
+// archived, compressed, as ArchiveOldMemories does. Importance is ranked as
+// stored, ties going to the most recently used. Pinned and static
+// observations are never archived and rank first, so they take places in the
+// limit. Returns the number of archived observations.
+func (s *Store) ArchiveExcessMemories(cfg DecayConfig) (int, error) {
+	return s.ArchiveExcessMemoriesContext(context.Background(), cfg)
+}
+
+// ArchiveExcessMemoriesContext is ArchiveExcessMemories with a context.
+func (s *Store) ArchiveExcessMemoriesContext(ctx context.Context, cfg DecayConfig) (int, error) {
+	return s.archiveExcess(ctx, cfg, true)
+}
+
+// CountExcessMemories returns the number of observations
+// ArchiveExcessMemories would archive, without archiving them.
+func (s *Store) CountExcessMemories(cfg DecayConfig) (int, error) {
+	return s.CountExcessMemoriesContext(context.Background(), cfg)
+}
+
+// CountExcessMemoriesContext is CountExcessMemories with a context.
+func (s *Store) CountExcessMemoriesContext(ctx context.Context, cfg DecayConfig) (int, error) {
+	return s.archiveExcess(ctx, cfg, false)
+}
+
+// archiveExcess archives what exceeds each scope's limit in turn, so an
+// observation archived for its entity no longer counts against its tag. It
+// commits only when asked; a count rolls back.
+func (s *Store) archiveExcess(ctx context.Context, cfg DecayConfig, commit bool) (int, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	archived := 0
+	trash := newTrashBatch(OpArchive)
+	for _, scope := range retentionScopes {
+		limit := scope.limit(cfg)
+		if limit <= 0 {
+			continue
+		}
+		var candidates []archiveCandidate
+		if err := tx.SelectContext(ctx, &candidates, `
+			SELECT id, entity_id, name, content, fact_type, importance
+			FROM (
+				SELECT o.id, o.entity_id, e.name, o.content,
+				       COALESCE(o.fact_type, 'dynamic') AS fact_type, COALESCE(o.importance, 1.0) AS importance,
+				       COALESCE(o.pinned, 0) = 1 OR o.fact_type = 'static' AS exempt,
+				       ROW_NUMBER() OVER (
+				           PARTITION BY `+scope.key+`
+				           ORDER BY COALESCE(o.pinned, 0) = 1 OR o.fact_type = 'static' DESC,
+				                    COALESCE(o.importance, 1.0) DESC,
+				                    COALESCE(o.last_useful, o.last_accessed, o.created_at) DESC, o.id DESC
+				       ) AS rank
+				FROM observations o
+				JOIN entities e ON e.id = o.entity_id
+				WHERE e.is_latest = 1 `+scope.where+`
+			)
+			WHERE rank > ? AND NOT exempt
+			ORDER BY id
+		`, limit); err != nil {
+			return 0, err
+		}
+		if err := archiveObservations(ctx, tx, candidates, trash); err != nil {
+			return 0, err
+		}
+		archived += len(candidates)
+	}
+	if !commit {
+		return archived, nil
+	}
+	if err := s.recordTrash(ctx, tx, trash); err != nil {
+		return 0, err
+	}
+	return archived, tx.Commit()
+}
@@ -0,0 +1,71 @@
+package storage_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+func TestStore_ArchiveExcessMemories(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+	if err := store.Migrate(); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	store.CreateEntity("Noisy", "project", []string{"a", "b", "c", "d", "e"})
+	for content, importance := range map[string]float64{"a": 0.9, "b": 0.2, "c": 0.7, "d": 0.1, "e": 0.5} {
+		store.SetObservationImportance("Noisy", content, importance)
+	}
+	// Exempt memories take places in the limit but are never archived
+	store.SetObservationPinned("Noisy", "d", true)
+	store.CreateEntity("Quiet", "project", []string{"only"})
+	store.SetContainerTag("Noisy", "app")
+	store.SetContainerTag("Quiet", "app")
+
+	cfg := storage.DefaultDecayConfig()
+	cfg.KeepPerEntity = 3
+	count, err := store.CountExcessMemories(cfg)
+	if err != nil {
+		t.Fatalf("CountExcessMemories failed: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 over the limit, got %d", count)
+	}
+	if archived, _ := store.GetArchiveCount(); archived != 0 {
+		t.Errorf("expected counting to archive nothing, got %d archived", archived)
+	}
+
+	archived, err := store.ArchiveExcessMemories(cfg)
+	if err != nil {
+		t.Fatalf("ArchiveExcessMemories failed: %v", err)
+	}
+	if archived != 2 {
+		t.Errorf("expected 2 archived, got %d", archived)
+	}
+	entity, _ := store.GetEntity("Noisy")
+	kept := slices.Sorted(slices.Values(entity.Observations))
+	if want := []string{"a", "c", "d"}; !slices.Equal(kept, want) {
+		t.Errorf("kept %v, want %v", kept, want)
+	}
+
+	// The tag's limit counts Quiet's memory too; the least important goes
+	cfg = storage.DefaultDecayConfig()
+	cfg.KeepPerContainer = 3
+	if archived, err = store.ArchiveExcessMemories(cfg); err != nil {
+		t.Fatalf("ArchiveExcessMemories failed: %v", err)
+	}
+	entity, _ = store.GetEntity("Noisy")
+	kept = slices.Sorted(slices.Values(entity.Observations))
+	if want := []string{"a", "d"}; archived != 1 || !slices.Equal(kept, want) {
+		t.Errorf("archived %d, kept %v; want 1, %v", archived, kept, want)
+	}
+	if quiet, _ := store.GetEntity("Quiet"); len(quiet.Observations) != 1 {
+		t.Errorf("expected Quiet's memory kept, got %v", quiet.Observations)
+	}
+
+	if archived, _ := store.ArchiveExcessMemories(storage.DefaultDecayConfig()); archived != 0 {
+		t.Errorf("expected no limits by default, archived %d", archived)
+	}
+}
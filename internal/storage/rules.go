@@ -0,0 +1,263 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// RuleMode decides what a write that breaks a rule does.
+type RuleMode string
+
+const (
+	RuleModeError RuleMode = "error" // Reject the write; the default
+	RuleModeWarn  RuleMode = "warn"  // Write it and pass the violation to Rules.Warn
+)
+
+// RelationRule restricts the entity types a relation type may link. An empty
+// From or To allows any type at that end.
+type RelationRule struct {
+	Type string   `json:"type"`
+	From []string `json:"from,omitempty"`
+	To   []string `json:"to,omitempty"`
+}
+
+// ObservationRule keeps fact types off entities of a type.
+type ObservationRule struct {
+	EntityType    string     `json:"entityType"`
+	DenyFactTypes []FactType `json:"denyFactTypes"`
+}
+
+// Rules are graph consistency rules: relation and observation writes that
+// break them are rejected, or in warn mode reported, and ValidateGraph
+// audits what is already stored.
+type Rules struct {
+	Mode         RuleMode          `json:"mode,omitempty"`
+	Relations    []RelationRule    `json:"relations,omitempty"`
+	Observations []ObservationRule `json:"observations,omitempty"`
+	// Warn receives violations in warn mode; nil drops them
+	Warn func(RuleViolation) `json:"-"`
+}
+
+// RuleViolation is a relation or observation that breaks a rule.
+type RuleViolation struct {
+	Kind    string `json:"kind"`    // "relation" or "observation"
+	Subject string `json:"subject"` // "from -[type]-> to", or "entity: content"
+	Reason  string `json:"reason"`
+	TraceID string `json:"traceId,omitempty"` // Of the request that broke it, in warn mode
+}
+
+// factTypes are the fact types observation rules can name.
+var factTypes = []FactType{FactTypeStatic, FactTypeDynamic, FactTypeSessionTurn,
+	FactTypeSessionEvent, FactTypeSessionSummary, FactTypeSummary}
+
+// Validate checks that the rules are well formed.
+func (r Rules) Validate() error {
+	switch r.Mode {
+	case "", RuleModeError, RuleModeWarn:
+	default:
+		return &ValidationError{"rules mode", fmt.Sprintf("must be error or warn, not %q", r.Mode)}
+	}
+	for _, rule := range r.Relations {
+		if rule.Type == "" {
+			return &ValidationError{"relation rule", "needs a type"}
+		}
+	}
+	for _, rule := range r.Observations {
+		if rule.EntityType == "" {
+			return &ValidationError{"observation rule", "needs an entityType"}
+		}
+		for _, ft := range rule.DenyFactTypes {
+			if !slices.Contains(factTypes, ft) {
+				return &ValidationError{"observation rule", fmt.Sprintf("denies unknown fact type %q", ft)}
+			}
+		}
+	}
+	return nil
+}
+
+// LoadRules reads rules from a JSON file.
+func LoadRules(path string) (Rules, error) {
+	var rules Rules
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return rules, err
+	}
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return rules, fmt.Errorf("reading rules %s: %w", path, err)
+	}
+	return rules, rules.Validate()
+}
+
+// SetRules replaces the rules writes are checked against. Imports, replicas,
+// and merges are not checked; run ValidateGraph after them.
+func (s *Store) SetRules(rules Rules) {
+	s.rules = rules
+}
+
+// relationViolation is why a relation of relationType from an entity of
+// fromType to one of toType breaks the rules, or "" when it doesn't.
+func (r Rules) relationViolation(relationType, fromType, toType string) string {
+	for _, rule := range r.Relations {
+		if rule.Type != relationType {
+			continue
+		}
+		if len(rule.From) > 0 && !slices.Contains(rule.From, fromType) || len(rule.To) > 0 && !slices.Contains(rule.To, toType) {
+			return fmt.Sprintf("%s must go from %s to %s, not %s to %s",
+				relationType, typeList(rule.From), typeList(rule.To), fromType, toType)
+		}
+	}
+	return ""
+}
+
+// observationViolation is why a factType observation on an entity of
+// entityType breaks the rules, or "" when it doesn't.
+func (r Rules) observationViolation(entityType string, factType FactType) string {
+	for _, rule := range r.Observations {
+		if rule.EntityType == entityType && slices.Contains(rule.DenyFactTypes, factType) {
+			return fmt.Sprintf("of a %s may not be a %s fact", entityType, factType)
+		}
+	}
+	return ""
+}
+
+func typeList(types []string) string {
+	if len(types) == 0 {
+		return "any type"
+	}
+	return strings.Join(types, " or ")
+}
+
+// enforce turns a violation into what the mode asks for: an error, or a
+// warning and nil.
+func (s *Store) enforce(ctx context.Context, v RuleViolation) error {
+	if v.Reason == "" {
+		return nil
+	}
+	if s.rules.Mode == RuleModeWarn {
+		if s.rules.Warn != nil {
+			v.TraceID = TraceID(ctx)
+			s.rules.Warn(v)
+		}
+		return nil
+	}
+	return &ValidationError{v.Kind, v.Reason}
+}
+
+// checkRelation enforces the rules on a relation about to be created.
+func (s *Store) checkRelation(ctx context.Context, q interface {
+	QueryRowContext(context.Context, string, ...any) *sql.Row
+}, fromID, toID int64, relationType string) error {
+	if len(s.rules.Relations) == 0 {
+		return nil
+	}
+	var fromName, fromType, toName, toType string
+	if err := q.QueryRowContext(ctx, "SELECT name, entity_type FROM entities WHERE id = ?", fromID).Scan(&fromName, &fromType); err != nil {
+		return err
+	}
+	if err := q.QueryRowContext(ctx, "SELECT name, entity_type FROM entities WHERE id = ?", toID).Scan(&toName, &toType); err != nil {
+		return err
+	}
+	return s.enforce(ctx, RuleViolation{
+		Kind:    "relation",
+		Subject: fromName + " -[" + relationType + "]-> " + toName,
+		Reason:  s.rules.relationViolation(relationType, fromType, toType),
+	})
+}
+
+// checkObservations enforces the rules, and the PII mode, on observations
+// about to be added to an entity of entityType.
+func (s *Store) checkObservations(ctx context.Context, entityName, entityType string, contents []string, factType FactType) error {
+	if err := s.checkPII(contents...); err != nil {
+		return err
+	}
+	for _, content := range contents {
+		if err := s.enforce(ctx, RuleViolation{
+			Kind:    "observation",
+			Subject: entityName + ": " + content,
+			Reason:  s.rules.observationViolation(entityType, factType),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkObservation is checkObservations for one observation on an entity
+// known by ID.
+func (s *Store) checkObservation(ctx context.Context, q interface {
+	QueryRowContext(context.Context, string, ...any) *sql.Row
+}, entityID int64, content string, factType FactType) error {
+	if len(s.rules.Observations) == 0 {
+		return s.checkPII(content)
+	}
+	var name, entityType string
+	if err := q.QueryRowContext(ctx, "SELECT name, entity_type FROM entities WHERE id = ?", entityID).Scan(&name, &entityType); err != nil {
+		return err
+	}
+	return s.checkObservations(ctx, name, entityType, []string{content}, factType)
+}
+
+// ValidateGraph audits the stored graph against the rules, returning every
+// relation valid now and every observation of a latest entity that breaks
+// one, relations first.
+func (s *Store) ValidateGraph() ([]RuleViolation, error) {
+	return s.ValidateGraphContext(context.Background())
+}
+
+// ValidateGraphContext is ValidateGraph with a context.
+func (s *Store) ValidateGraphContext(ctx context.Context) ([]RuleViolation, error) {
+	var violations []RuleViolation
+
+	if len(s.rules.Relations) > 0 {
+		validity, args := RelationFilter{}.sql()
+		var relations []struct {
+			From     string `db:"from_name"`
+			FromType string `db:"from_type"`
+			To       string `db:"to_name"`
+			ToType   string `db:"to_type"`
+			Type     string `db:"relation_type"`
+		}
+		if err := s.db.SelectContext(ctx, &relations, `
+			SELECT f.name as from_name, f.entity_type as from_type, t.name as to_name, t.entity_type as to_type, r.relation_type
+			FROM relations r
+			JOIN entities f ON f.id = r.from_entity_id
+			JOIN entities t ON t.id = r.to_entity_id
+			WHERE `+validity+`
+			ORDER BY f.name, r.relation_type, t.name`, args...); err != nil {
+			return nil, err
+		}
+		for _, r := range relations {
+			if reason := s.rules.relationViolation(r.Type, r.FromType, r.ToType); reason != "" {
+				violations = append(violations, RuleViolation{Kind: "relation", Subject: r.From + " -[" + r.Type + "]-> " + r.To, Reason: reason})
+			}
+		}
+	}
+
+	if len(s.rules.Observations) > 0 {
+		var observations []struct {
+			Entity   string   `db:"name"`
+			Type     string   `db:"entity_type"`
+			Content  string   `db:"content"`
+			FactType FactType `db:"fact_type"`
+		}
+		if err := s.db.SelectContext(ctx, &observations, `
+			SELECT e.name, e.entity_type, o.content, COALESCE(o.fact_type, 'dynamic') as fact_type
+			FROM observations o
+			JOIN entities e ON e.id = o.entity_id
+			WHERE e.is_latest = 1 OR e.is_latest IS NULL
+			ORDER BY e.name, o.id`); err != nil {
+			return nil, err
+		}
+		for _, o := range observations {
+			if reason := s.rules.observationViolation(o.Type, o.FactType); reason != "" {
+				violations = append(violations, RuleViolation{Kind: "observation", Subject: o.Entity + ": " + o.Content, Reason: reason})
+			}
+		}
+	}
+	return violations, nil
+}
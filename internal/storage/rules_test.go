@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testRules() Rules {
+	return Rules{
+		Relations:    []RelationRule{{Type: "used_by", From: []string{"pattern"}, To: []string{"project"}}},
+		Observations: []ObservationRule{{EntityType: "session", DenyFactTypes: []FactType{FactTypeStatic}}},
+	}
+}
+
+func TestRules_ErrorMode(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	store.CreateEntity("TDD", "pattern", nil)
+	store.CreateEntity("mark42", "project", nil)
+	store.CreateEntity("golangci-lint", "tool", nil)
+	store.CreateEntity("session-1", "session", nil)
+	store.SetRules(testRules())
+
+	if err := store.CreateRelation("TDD", "mark42", "used_by"); err != nil {
+		t.Errorf("expected an allowed relation, got %v", err)
+	}
+	err := store.CreateRelation("golangci-lint", "mark42", "used_by")
+	if !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("expected the relation rejected, got %v", err)
+	}
+	if want := "relation used_by must go from pattern to project, not tool to project"; err.Error() != want {
+		t.Errorf("error = %q, want %q", err, want)
+	}
+	if err := store.CreateRelation("golangci-lint", "mark42", "checks"); err != nil {
+		t.Errorf("expected other relation types unrestricted, got %v", err)
+	}
+
+	if err := store.AddObservationWithType("session-1", "Prefers tabs", FactTypeStatic); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("expected a static fact on a session rejected, got %v", err)
+	}
+	if err := store.AddObservationWithType("session-1", "Edited main.go", FactTypeSessionEvent); err != nil {
+		t.Errorf("expected other fact types allowed, got %v", err)
+	}
+	results, err := store.AddObservationsBatch([]ObservationSpec{
+		{EntityName: "session-1", Content: "Always use tabs", FactType: FactTypeStatic},
+		{EntityName: "mark42", Content: "Always use tabs", FactType: FactTypeStatic},
+	})
+	if err != nil {
+		t.Fatalf("AddObservationsBatch: %v", err)
+	}
+	if !errors.Is(results[0].Err, ErrInvalidInput) || !results[1].Added {
+		t.Errorf("expected only the session observation rejected, got %+v", results)
+	}
+}
+
+func TestRules_WarnMode(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	store.CreateEntity("golangci-lint", "tool", nil)
+	store.CreateEntity("mark42", "project", nil)
+	rules := testRules()
+	rules.Mode = RuleModeWarn
+	var warned []RuleViolation
+	rules.Warn = func(v RuleViolation) { warned = append(warned, v) }
+	store.SetRules(rules)
+
+	if err := store.CreateRelation("golangci-lint", "mark42", "used_by"); err != nil {
+		t.Fatalf("expected warn mode to write, got %v", err)
+	}
+	if len(warned) != 1 || warned[0].Subject != "golangci-lint -[used_by]-> mark42" {
+		t.Errorf("expected one warning, got %+v", warned)
+	}
+
+	// The warning names the request that broke the rule
+	ctx := WithTraceID(context.Background(), "abc123")
+	if err := store.CreateRelationContext(ctx, "golangci-lint", "mark42", "used_by"); err != nil {
+		t.Fatal(err)
+	}
+	if len(warned) != 2 || warned[1].TraceID != "abc123" {
+		t.Errorf("expected the trace ID on the warning, got %+v", warned)
+	}
+}
+
+func TestValidateGraph(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	store.CreateEntity("TDD", "pattern", nil)
+	store.CreateEntity("mark42", "project", nil)
+	store.CreateEntity("golangci-lint", "tool", nil)
+	store.CreateEntity("session-1", "session", nil)
+	store.CreateRelation("TDD", "mark42", "used_by")
+	store.CreateRelation("golangci-lint", "mark42", "used_by")
+	store.AddObservationWithType("session-1", "Prefers tabs", FactTypeStatic)
+
+	if violations, err := store.ValidateGraph(); err != nil || len(violations) != 0 {
+		t.Fatalf("expected no violations without rules, got %+v, %v", violations, err)
+	}
+
+	store.SetRules(testRules())
+	violations, err := store.ValidateGraph()
+	if err != nil {
+		t.Fatalf("ValidateGraph: %v", err)
+	}
+	want := []RuleViolation{
+		{Kind: "relation", Subject: "golangci-lint -[used_by]-> mark42", Reason: "used_by must go from pattern to project, not tool to project"},
+		{Kind: "observation", Subject: "session-1: Prefers tabs", Reason: "of a session may not be a static fact"},
+	}
+	if len(violations) != len(want) {
+		t.Fatalf("violations = %+v, want %+v", violations, want)
+	}
+	for i := range want {
+		if violations[i] != want[i] {
+			t.Errorf("violation %d = %+v, want %+v", i, violations[i], want[i])
+		}
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	os.WriteFile(path, []byte(`{"mode": "warn", "relations": [{"type": "used_by", "to": ["project"]}]}`), 0o644)
+	rules, err := LoadRules(path)
+	if err != nil || rules.Mode != RuleModeWarn || len(rules.Relations) != 1 {
+		t.Errorf("LoadRules = %+v, %v", rules, err)
+	}
+
+	for _, body := range []string{
+		`{"mode": "strict"}`,
+		`{"relations": [{"from": ["pattern"]}]}`,
+		`{"observations": [{"entityType": "session", "denyFactTypes": ["permanent"]}]}`,
+	} {
+		os.WriteFile(path, []byte(body), 0o644)
+		if _, err := LoadRules(path); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("expected %s rejected, got %v", body, err)
+		}
+	}
+}
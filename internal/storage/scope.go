@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"context"
+	"slices"
+)
+
+// GraphScope selects part of the graph for a partial export: the entities
+// matching it, their observations, and the relations among them.
+type GraphScope struct {
+	ContainerTag string // Empty: any container
+	Type         string // Empty: every type
+}
+
+// matches reports whether an entity with this container tag and type is in
+// scope.
+func (sc GraphScope) matches(containerTag, entityType string) bool {
+	return (sc.ContainerTag == "" || sc.ContainerTag == containerTag) &&
+		(sc.Type == "" || sc.Type == entityType)
+}
+
+// entitiesInScope returns the names of the latest entities in scope.
+func (s *Store) entitiesInScope(ctx context.Context, scope GraphScope) (map[string]bool, error) {
+	var entities []struct {
+		Name         string `db:"name"`
+		EntityType   string `db:"entity_type"`
+		ContainerTag string `db:"container_tag"`
+	}
+	if err := s.db.SelectContext(ctx, &entities, `
+		SELECT name, entity_type, COALESCE(container_tag, '') as container_tag
+		FROM entities WHERE is_latest = 1 OR is_latest IS NULL
+	`); err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool)
+	for _, e := range entities {
+		if scope.matches(e.ContainerTag, e.EntityType) {
+			names[e.Name] = true
+		}
+	}
+	return names, nil
+}
+
+// ReadGraphScoped returns the entities in scope with their observations, and
+// the relations filter selects between two of them. With filter.AsOf set,
+// entities are read as they were then but scoped by their current tag and
+// type.
+func (s *Store) ReadGraphScoped(scope GraphScope, filter RelationFilter) (*Graph, error) {
+	return s.ReadGraphScopedContext(context.Background(), scope, filter)
+}
+
+// ReadGraphScopedContext is ReadGraphScoped with a context.
+func (s *Store) ReadGraphScopedContext(ctx context.Context, scope GraphScope, filter RelationFilter) (*Graph, error) {
+	var graph *Graph
+	if filter.AsOf.IsZero() {
+		var err error
+		if graph, err = s.ReadGraphWithFilterContext(ctx, filter); err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		if graph, err = s.readGraphPages(ctx, GraphPageOptions{AsOf: filter.AsOf, Relations: filter}); err != nil {
+			return nil, err
+		}
+	}
+	if scope == (GraphScope{}) {
+		return graph, nil
+	}
+
+	names, err := s.entitiesInScope(ctx, scope)
+	if err != nil {
+		return nil, err
+	}
+	graph.Entities = slices.DeleteFunc(graph.Entities, func(e *Entity) bool { return !names[e.Name] })
+	graph.Relations = slices.DeleteFunc(graph.Relations, func(r *Relation) bool { return !names[r.From] || !names[r.To] })
+	return graph, nil
+}
+
+// ExportReplicaScoped is ExportReplica for the entities in scope, their
+// observations, and the relations among them. Tombstones are left out of a
+// scoped export, since a deleted record's entity is no longer known, so
+// merging one never deletes anything. The zero scope exports everything.
+func (s *Store) ExportReplicaScoped(scope GraphScope) ([]ReplicaRecord, error) {
+	return s.ExportReplicaScopedContext(context.Background(), scope)
+}
+
+// ExportReplicaScopedContext is ExportReplicaScoped with a context.
+func (s *Store) ExportReplicaScopedContext(ctx context.Context, scope GraphScope) ([]ReplicaRecord, error) {
+	records, err := s.ExportReplicaContext(ctx)
+	if err != nil || scope == (GraphScope{}) {
+		return records, err
+	}
+	entities := make(map[string]bool)
+	for _, r := range records {
+		if r.Kind == SyncEntity && !r.Deleted && scope.matches(r.ContainerTag, r.EntityType) {
+			entities[r.UID] = true
+		}
+	}
+	return slices.DeleteFunc(records, func(r ReplicaRecord) bool {
+		switch {
+		case r.Deleted:
+			return true
+		case r.Kind == SyncObservation:
+			return !entities[r.EntityUID]
+		case r.Kind == SyncRelation:
+			return !entities[r.FromUID] || !entities[r.ToUID]
+		default:
+			return !entities[r.UID]
+		}
+	}), nil
+}
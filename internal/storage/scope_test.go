@@ -0,0 +1,59 @@
+package storage
+
+import "testing"
+
+func TestReadGraphScoped(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	store.CreateEntityWithContainer("api", "project", []string{"REST API"}, "my-app")
+	store.CreateEntityWithContainer("tdd", "pattern", []string{"Tests first"}, "my-app")
+	store.CreateEntityWithContainer("other", "pattern", []string{"Elsewhere"}, "other-app")
+	store.CreateRelation("api", "tdd", "uses")
+	store.CreateRelation("api", "other", "uses")
+
+	graph, err := store.ReadGraphScoped(GraphScope{ContainerTag: "my-app"}, RelationFilter{})
+	if err != nil {
+		t.Fatalf("ReadGraphScoped failed: %v", err)
+	}
+	if len(graph.Entities) != 2 || len(graph.Relations) != 1 || graph.Relations[0].To != "tdd" {
+		t.Errorf("expected my-app's 2 entities and the relation between them, got %+v, %+v", graph.Entities, graph.Relations)
+	}
+	if len(graph.Entities[0].Observations) != 1 {
+		t.Errorf("expected observations with the entities, got %+v", graph.Entities[0])
+	}
+
+	graph, err = store.ReadGraphScoped(GraphScope{ContainerTag: "my-app", Type: "pattern"}, RelationFilter{})
+	if err != nil {
+		t.Fatalf("ReadGraphScoped failed: %v", err)
+	}
+	if len(graph.Entities) != 1 || graph.Entities[0].Name != "tdd" || len(graph.Relations) != 0 {
+		t.Errorf("expected only tdd, got %+v, %+v", graph.Entities, graph.Relations)
+	}
+}
+
+func TestExportReplicaScoped(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	store.CreateEntityWithContainer("api", "project", []string{"REST API", "Old"}, "my-app")
+	store.CreateEntityWithContainer("other", "project", []string{"Elsewhere"}, "other-app")
+	store.CreateRelation("api", "other", "uses")
+	store.DeleteObservation("api", "Old")
+
+	records, err := store.ExportReplicaScoped(GraphScope{ContainerTag: "my-app"})
+	if err != nil {
+		t.Fatalf("ExportReplicaScoped failed: %v", err)
+	}
+	if len(records) != 2 || records[0].Name != "api" || records[1].Content != "REST API" {
+		t.Errorf("expected api and its live observation only, got %+v", records)
+	}
+
+	all, err := store.ExportReplicaScoped(GraphScope{})
+	if err != nil {
+		t.Fatalf("ExportReplicaScoped failed: %v", err)
+	}
+	if full, _ := store.ExportReplica(); len(all) != len(full) {
+		t.Errorf("expected the zero scope to export everything, got %d of %d records", len(all), len(full))
+	}
+}
@@ -1,7 +1,12 @@
 package storage
 
 import (
+	"context"
+	"slices"
 	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
 )
 
 // SearchResult represents an entity found by search.
@@ -16,30 +21,68 @@ type Graph struct {
 	Relations []*Relation
 }
 
+// SearchOptions controls FTS5 entity search.
+type SearchOptions struct {
+	Limit             int
+	IncludeSuppressed bool              // Match and return suppressed observations too
+	Attributes        map[string]string // Only entities with all of these attributes
+	User              string            // Only entities this user created or wrote observations on
+}
+
 // Search finds entities matching the query using FTS5.
 func (s *Store) Search(query string) ([]*SearchResult, error) {
-	return s.SearchWithLimit(query, 20)
+	return s.SearchContext(context.Background(), query)
+}
+
+// SearchContext is Search with a context.
+func (s *Store) SearchContext(ctx context.Context, query string) ([]*SearchResult, error) {
+	return s.SearchWithLimitContext(ctx, query, 20)
 }
 
 // SearchWithLimit finds entities with a result limit.
 func (s *Store) SearchWithLimit(query string, limit int) ([]*SearchResult, error) {
-	// Escape FTS5 special characters and prepare query
-	ftsQuery := prepareFTSQuery(query)
+	return s.SearchWithLimitContext(context.Background(), query, limit)
+}
+
+// SearchWithLimitContext is SearchWithLimit with a context.
+func (s *Store) SearchWithLimitContext(ctx context.Context, query string, limit int) ([]*SearchResult, error) {
+	return s.SearchWithOptionsContext(ctx, query, SearchOptions{Limit: limit})
+}
+
+// SearchWithOptions finds entities matching the query.
+// Suppressed observations are skipped unless opts.IncludeSuppressed is set.
+func (s *Store) SearchWithOptions(query string, opts SearchOptions) ([]*SearchResult, error) {
+	return s.SearchWithOptionsContext(context.Background(), query, opts)
+}
+
+// SearchWithOptionsContext is SearchWithOptions with a context.
+func (s *Store) SearchWithOptionsContext(ctx context.Context, query string, opts SearchOptions) ([]*SearchResult, error) {
+	if strings.TrimSpace(query) == "" && (len(opts.Attributes) > 0 || opts.User != "") {
+		return s.searchByFilters(ctx, opts)
+	}
+
+	obsMatches, obsArgs := s.observationMatchSQL(query)
+	entityMatches, entityArgs := s.entityMatchSQL(query)
+	attrFilter, attrArgs := attributeFilterSQL("e.id", opts.Attributes)
+	userFilter, userArgs := userFilterSQL("e", opts.User)
+	args := append(obsArgs, opts.IncludeSuppressed)
+	args = append(args, entityArgs...)
+	args = append(args, attrArgs...)
+	args = append(args, userArgs...)
 
 	// Search both observations and entity names
 	// Union results and rank by BM25 score
-	rows, err := s.db.Query(`
+	rows, err := s.db.QueryContext(ctx, `
 		WITH observation_matches AS (
-			SELECT DISTINCT o.entity_id, bm25(observations_fts) as score
-			FROM observations_fts f
-			JOIN observations o ON o.id = f.rowid
-			WHERE observations_fts MATCH ?
+			SELECT DISTINCT o.entity_id, f.score
+			FROM (`+obsMatches+`) f
+			JOIN observations o ON o.id = f.id
+			WHERE (? OR COALESCE(o.suppressed, 0) = 0)
 		),
 		entity_matches AS (
-			SELECT e.id as entity_id, bm25(entities_fts) as score
-			FROM entities_fts f
-			JOIN entities e ON e.id = f.rowid
-			WHERE entities_fts MATCH ?
+			SELECT e.id as entity_id, f.score
+			FROM (`+entityMatches+`) f
+			JOIN entities e ON e.id = f.id
 		),
 		combined AS (
 			SELECT entity_id, MIN(score) as score
@@ -53,9 +96,10 @@ func (s *Store) SearchWithLimit(query string, limit int) ([]*SearchResult, error
 		SELECT e.id, e.name, e.entity_type, e.created_at, c.score
 		FROM combined c
 		JOIN entities e ON e.id = c.entity_id
+		WHERE `+attrFilter+` AND `+userFilter+`
 		ORDER BY c.score
 		LIMIT ?
-	`, ftsQuery, ftsQuery, limit)
+	`, append(args, opts.Limit)...)
 	if err != nil {
 		// If FTS query fails (invalid syntax), return empty results
 		if strings.Contains(err.Error(), "fts5") {
@@ -75,44 +119,103 @@ func (s *Store) SearchWithLimit(query string, limit int) ([]*SearchResult, error
 		results = append(results, &r)
 	}
 
-	// Load observations for each result
-	for _, r := range results {
-		obs, err := s.loadObservations(r.ID)
-		if err != nil {
+	if err := s.loadSearchObservations(ctx, results, opts.IncludeSuppressed); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// searchByFilters lists the latest entities matching opts.Attributes and
+// opts.User, for a search without a query.
+func (s *Store) searchByFilters(ctx context.Context, opts SearchOptions) ([]*SearchResult, error) {
+	attrFilter, args := attributeFilterSQL("e.id", opts.Attributes)
+	userFilter, userArgs := userFilterSQL("e", opts.User)
+	args = append(args, userArgs...)
+	var results []*SearchResult
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT e.id, e.name, e.entity_type, e.created_at FROM entities e
+		WHERE (e.is_latest = 1 OR e.is_latest IS NULL) AND `+attrFilter+` AND `+userFilter+`
+		ORDER BY e.name
+		LIMIT ?`, append(args, opts.Limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		r := &SearchResult{Entity: &Entity{}}
+		if err := rows.Scan(&r.ID, &r.Name, &r.Type, &r.CreatedAt); err != nil {
 			return nil, err
 		}
-		r.Observations = obs
+		results = append(results, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
+	if err := s.loadSearchObservations(ctx, results, opts.IncludeSuppressed); err != nil {
+		return nil, err
+	}
 	return results, nil
 }
 
-// ReadGraph returns the entire knowledge graph.
+// loadSearchObservations loads observations for each result.
+func (s *Store) loadSearchObservations(ctx context.Context, results []*SearchResult, includeSuppressed bool) error {
+	for _, r := range results {
+		obs, err := s.loadObservations(ctx, r.ID, includeSuppressed)
+		if err != nil {
+			return err
+		}
+		r.Observations = obs
+	}
+	return nil
+}
+
+// ReadGraph returns the entire knowledge graph, with the relations valid now.
 func (s *Store) ReadGraph() (*Graph, error) {
-	entities, err := s.ListEntities("")
+	return s.ReadGraphContext(context.Background())
+}
+
+// ReadGraphContext is ReadGraph with a context.
+func (s *Store) ReadGraphContext(ctx context.Context) (*Graph, error) {
+	return s.ReadGraphWithFilterContext(ctx, RelationFilter{})
+}
+
+// ReadGraphWithFilter returns the entire knowledge graph, with the relations
+// filter selects.
+func (s *Store) ReadGraphWithFilter(filter RelationFilter) (*Graph, error) {
+	return s.ReadGraphWithFilterContext(context.Background(), filter)
+}
+
+// ReadGraphWithFilterContext is ReadGraphWithFilter with a context.
+func (s *Store) ReadGraphWithFilterContext(ctx context.Context, filter RelationFilter) (*Graph, error) {
+	entities, err := s.ListEntitiesContext(ctx, "")
 	if err != nil {
 		return nil, err
 	}
 
-	// Load observations for each entity
+	ids := make([]int64, len(entities))
+	for i, e := range entities {
+		ids[i] = e.ID
+	}
+	observations, err := s.loadObservationsByEntity(ctx, ids, "")
+	if err != nil {
+		return nil, err
+	}
 	for _, e := range entities {
-		obs, err := s.loadObservations(e.ID)
-		if err != nil {
-			return nil, err
-		}
-		e.Observations = obs
+		e.Observations = observations[e.ID]
 	}
 
 	// Load all relations using sqlx
+	validity, args := filter.sql()
 	var relList []Relation
-	err = s.db.Select(&relList, `
-		SELECT e_from.name as from_name, e_to.name as to_name,
-		       r.relation_type, r.created_at
+	err = s.db.SelectContext(ctx, &relList, `
+		SELECT `+relationColumns+`
 		FROM relations r
 		JOIN entities e_from ON r.from_entity_id = e_from.id
 		JOIN entities e_to ON r.to_entity_id = e_to.id
+		WHERE `+validity+`
 		ORDER BY r.created_at
-	`)
+	`, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -129,11 +232,164 @@ func (s *Store) ReadGraph() (*Graph, error) {
 	}, nil
 }
 
-func (s *Store) loadObservations(entityID int64) ([]string, error) {
+// GraphPageOptions selects a window of the graph for ReadGraphPage.
+type GraphPageOptions struct {
+	Offset              int // Entities to skip, in name order
+	Limit               int // 0 uses DefaultGraphPageSize
+	ExcludeObservations bool
+	Relations           RelationFilter // Zero: the relations valid now
+	AsOf                time.Time      // Reconstruct the graph as it was then; Relations.AsOf is ignored
+}
+
+// DefaultGraphPageSize is the number of entities in a page when
+// GraphPageOptions.Limit is not set.
+const DefaultGraphPageSize = 1000
+
+// graphQueryChunk bounds the entity IDs or names bound into one IN list,
+// keeping queries well under SQLite's limit on bound variables.
+const graphQueryChunk = 500
+
+// GraphPage is a window of entities, ordered by name, with the relations
+// that start at them, so paging through the graph yields each relation once.
+type GraphPage struct {
+	Graph
+	Total      int // Entities in the whole graph
+	NextOffset int // 0 on the last page
+}
+
+// ReadGraphPage returns one page of the knowledge graph. Unlike ReadGraph
+// its size is bounded by the limit, for graphs too large to load at once.
+func (s *Store) ReadGraphPage(opts GraphPageOptions) (*GraphPage, error) {
+	return s.ReadGraphPageContext(context.Background(), opts)
+}
+
+// ReadGraphPageContext is ReadGraphPage with a context.
+func (s *Store) ReadGraphPageContext(ctx context.Context, opts GraphPageOptions) (*GraphPage, error) {
+	if !opts.AsOf.IsZero() {
+		return s.readGraphPageAsOf(ctx, opts)
+	}
+
+	page := &GraphPage{}
+	if err := s.db.GetContext(ctx, &page.Total,
+		"SELECT COUNT(*) FROM entities WHERE is_latest = 1 OR is_latest IS NULL"); err != nil {
+		return nil, err
+	}
+
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultGraphPageSize
+	}
+	var entities []Entity
+	if err := s.db.SelectContext(ctx, &entities, `
+		SELECT id, name, entity_type, created_at,
+		       COALESCE(version, 1) as version,
+		       COALESCE(is_latest, 1) as is_latest,
+		       COALESCE(supersedes_id, 0) as supersedes_id
+		FROM entities WHERE is_latest = 1 OR is_latest IS NULL
+		ORDER BY name LIMIT ? OFFSET ?
+	`, limit, opts.Offset); err != nil {
+		return nil, err
+	}
+	if len(entities) == 0 {
+		return page, nil
+	}
+
+	ids := make([]int64, len(entities))
+	page.Entities = make([]*Entity, len(entities))
+	for i := range entities {
+		ids[i] = entities[i].ID
+		page.Entities[i] = &entities[i]
+	}
+	if !opts.ExcludeObservations {
+		observations, err := s.loadObservationsByEntity(ctx, ids, "")
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range page.Entities {
+			e.Observations = observations[e.ID]
+		}
+	}
+
+	// Entities are in name order, so relations come out in it chunk by chunk
+	validity, validityArgs := opts.Relations.sql()
+	for chunk := range slices.Chunk(ids, graphQueryChunk) {
+		query, args, err := sqlx.In(`
+			SELECT `+relationColumns+`
+			FROM relations r
+			JOIN entities e_from ON r.from_entity_id = e_from.id
+			JOIN entities e_to ON r.to_entity_id = e_to.id
+			WHERE r.from_entity_id IN (?) AND `+validity+`
+			ORDER BY e_from.name, r.created_at
+		`, append([]any{chunk}, validityArgs...)...)
+		if err != nil {
+			return nil, err
+		}
+		var relList []Relation
+		if err := s.db.SelectContext(ctx, &relList, s.db.Rebind(query), args...); err != nil {
+			return nil, err
+		}
+		for i := range relList {
+			page.Relations = append(page.Relations, &relList[i])
+		}
+	}
+
+	if next := opts.Offset + len(entities); next < page.Total {
+		page.NextOffset = next
+	}
+	return page, nil
+}
+
+// readGraphPages reads every page opts selects from its offset on, for
+// callers that need the whole graph.
+func (s *Store) readGraphPages(ctx context.Context, opts GraphPageOptions) (*Graph, error) {
+	graph := &Graph{}
+	for {
+		page, err := s.ReadGraphPageContext(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		graph.Entities = append(graph.Entities, page.Entities...)
+		graph.Relations = append(graph.Relations, page.Relations...)
+		if page.NextOffset == 0 {
+			return graph, nil
+		}
+		opts.Offset = page.NextOffset
+	}
+}
+
+// loadObservationsByEntity loads the observations, suppressed ones included,
+// of many entities in a query per chunk of IDs, keyed by entity ID. With
+// asOf set, only those created by then are loaded.
+func (s *Store) loadObservationsByEntity(ctx context.Context, ids []int64, asOf string) (map[int64][]string, error) {
+	observations := make(map[int64][]string, len(ids))
+	for chunk := range slices.Chunk(ids, graphQueryChunk) {
+		query, args, err := sqlx.In(`
+			SELECT entity_id, content FROM observations
+			WHERE entity_id IN (?) AND (? = '' OR created_at <= ?)
+			ORDER BY created_at, id
+		`, chunk, asOf, asOf)
+		if err != nil {
+			return nil, err
+		}
+		var rows []struct {
+			EntityID int64  `db:"entity_id"`
+			Content  string `db:"content"`
+		}
+		if err := s.db.SelectContext(ctx, &rows, s.db.Rebind(query), args...); err != nil {
+			return nil, err
+		}
+		for _, r := range rows {
+			observations[r.EntityID] = append(observations[r.EntityID], r.Content)
+		}
+	}
+	return observations, nil
+}
+
+func (s *Store) loadObservations(ctx context.Context, entityID int64, includeSuppressed bool) ([]string, error) {
 	var observations []string
-	err := s.db.Select(&observations,
-		"SELECT content FROM observations WHERE entity_id = ? ORDER BY created_at",
-		entityID)
+	err := s.db.SelectContext(ctx, &observations,
+		"SELECT content FROM observations WHERE entity_id = ? AND (? OR COALESCE(suppressed, 0) = 0) ORDER BY created_at",
+		entityID, includeSuppressed)
 	return observations, err
 }
 
@@ -2,6 +2,8 @@ package storage_test
 
 import (
 	"testing"
+
+	"github.com/mfenderov/mark42/internal/storage"
 )
 
 func TestSearch_ByObservationContent(t *testing.T) {
@@ -146,6 +148,114 @@ func TestSearch_IncludesObservations(t *testing.T) {
 	}
 }
 
+func TestSearch_ExcludesSuppressed(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+
+	store.CreateEntity("Build", "project", []string{"Use make build", "Run linters before commit"})
+	store.SetObservationSuppressed("Build", "Use make build", true)
+
+	results, err := store.Search("make")
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	for _, r := range results {
+		for _, obs := range r.Observations {
+			if obs == "Use make build" {
+				t.Errorf("suppressed observation returned by Search: %+v", r)
+			}
+		}
+	}
+
+	results, err = store.SearchWithOptions("make", storage.SearchOptions{Limit: 10, IncludeSuppressed: true})
+	if err != nil {
+		t.Fatalf("SearchWithOptions failed: %v", err)
+	}
+	if len(results) != 1 || len(results[0].Observations) != 2 {
+		t.Errorf("expected suppressed observation with IncludeSuppressed, got %+v", results)
+	}
+}
+
+func TestReadGraphPage(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+
+	store.CreateEntity("A", "node", []string{"first"})
+	store.CreateEntity("B", "node", []string{"second"})
+	store.CreateEntity("C", "node", []string{"third"})
+	store.CreateRelation("A", "C", "links")
+	store.CreateRelation("C", "A", "links")
+
+	page, err := store.ReadGraphPage(storage.GraphPageOptions{Limit: 2, ExcludeObservations: true})
+	if err != nil {
+		t.Fatalf("ReadGraphPage failed: %v", err)
+	}
+	if page.Total != 3 || page.NextOffset != 2 || len(page.Entities) != 2 {
+		t.Fatalf("expected first 2 of 3 entities, got total=%d next=%d entities=%d",
+			page.Total, page.NextOffset, len(page.Entities))
+	}
+	if page.Entities[0].Name != "A" || page.Entities[0].Observations != nil {
+		t.Errorf("expected A without observations, got %+v", page.Entities[0])
+	}
+	if len(page.Relations) != 1 || page.Relations[0].From != "A" {
+		t.Errorf("expected only the relation from A, got %+v", page.Relations)
+	}
+
+	page, err = store.ReadGraphPage(storage.GraphPageOptions{Offset: 2, Limit: 2})
+	if err != nil {
+		t.Fatalf("ReadGraphPage failed: %v", err)
+	}
+	if page.NextOffset != 0 || len(page.Entities) != 1 || page.Entities[0].Observations[0] != "third" {
+		t.Errorf("expected last page with C and its observation, got %+v", page)
+	}
+	if len(page.Relations) != 1 || page.Relations[0].From != "C" {
+		t.Errorf("expected only the relation from C, got %+v", page.Relations)
+	}
+}
+
+func TestReadGraphPage_BeyondVariableLimit(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+
+	// More entities than SQLite binds variables in one statement (32,766)
+	const n = 33000
+	if _, err := store.DB().Exec(`
+		WITH RECURSIVE seq(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM seq WHERE i < ?)
+		INSERT INTO entities (name, entity_type) SELECT printf('E%05d', i), 'node' FROM seq`, n); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.DB().Exec(`INSERT INTO observations (entity_id, content) SELECT id, 'about ' || name FROM entities`); err != nil {
+		t.Fatal(err)
+	}
+	store.CreateRelation("E32999", "E00001", "links")
+
+	page, err := store.ReadGraphPage(storage.GraphPageOptions{})
+	if err != nil {
+		t.Fatalf("ReadGraphPage failed: %v", err)
+	}
+	if page.Total != n || len(page.Entities) != storage.DefaultGraphPageSize || page.NextOffset != storage.DefaultGraphPageSize {
+		t.Errorf("expected a default-sized first page, got total=%d next=%d entities=%d",
+			page.Total, page.NextOffset, len(page.Entities))
+	}
+
+	page, err = store.ReadGraphPage(storage.GraphPageOptions{Limit: n})
+	if err != nil {
+		t.Fatalf("ReadGraphPage with a limit past the variable limit failed: %v", err)
+	}
+	if len(page.Entities) != n || page.NextOffset != 0 || len(page.Relations) != 1 {
+		t.Fatalf("expected the whole graph, got %d entities, %d relations, next=%d",
+			len(page.Entities), len(page.Relations), page.NextOffset)
+	}
+	if last := page.Entities[n-1]; last.Name != "E33000" || len(last.Observations) != 1 || last.Observations[0] != "about E33000" {
+		t.Errorf("expected the last entity with its observation, got %+v", last)
+	}
+
+	graph, err := store.ReadGraph()
+	if err != nil || len(graph.Entities) != n {
+		t.Errorf("ReadGraph: %v", err)
+	}
+}
+
 func TestReadGraph(t *testing.T) {
 	store := newTestStore(t)
 	defer store.Close()
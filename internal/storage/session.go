@@ -1,8 +1,13 @@
 package storage
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -12,8 +17,18 @@ type Session struct {
 	Status     string
 	Summary    string
 	EventCount int
+	TurnCount  int
 	StartedAt  time.Time
 	EndedAt    time.Time
+	Timeline   []SessionEntry // Events and turns in capture order; filled by GetSession
+}
+
+// SessionEntry is one event or conversation turn in a session's timeline.
+type SessionEntry struct {
+	FactType FactType     // FactTypeSessionEvent or FactTypeSessionTurn
+	Event    SessionEvent // Set for events
+	Content  string       // Set for turns
+	At       time.Time
 }
 
 type SessionEvent struct {
@@ -31,8 +46,13 @@ type SessionMetadata struct {
 }
 
 func (s *Store) CreateSession(project string) (*Session, error) {
+	return s.CreateSessionContext(context.Background(), project)
+}
+
+// CreateSessionContext is CreateSession with a context.
+func (s *Store) CreateSessionContext(ctx context.Context, project string) (*Session, error) {
 	now := time.Now()
-	name := fmt.Sprintf("session-%s-%s", project, now.Format("20060102-150405.000"))
+	name := sessionName(project, now)
 
 	meta := SessionMetadata{
 		Project:   project,
@@ -44,12 +64,12 @@ func (s *Store) CreateSession(project string) (*Session, error) {
 		return nil, fmt.Errorf("failed to marshal session metadata: %w", err)
 	}
 
-	_, err = s.CreateEntity(name, "session", nil)
+	_, err = s.CreateEntityContext(ctx, name, "session", nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create session entity: %w", err)
 	}
 
-	if err := s.SetContainerTag(name, string(metaJSON)); err != nil {
+	if err := s.SetContainerTagContext(ctx, name, string(metaJSON)); err != nil {
 		return nil, fmt.Errorf("failed to set session metadata: %w", err)
 	}
 
@@ -61,23 +81,48 @@ func (s *Store) CreateSession(project string) (*Session, error) {
 	}, nil
 }
 
+// sessionName names the session of project started at startedAt.
+func sessionName(project string, startedAt time.Time) string {
+	return fmt.Sprintf("session-%s-%s", project, startedAt.Format("20060102-150405.000"))
+}
+
 func (s *Store) CaptureSessionEvent(sessionName string, event SessionEvent) error {
+	return s.CaptureSessionEventContext(context.Background(), sessionName, event)
+}
+
+// CaptureSessionEventContext is CaptureSessionEvent with a context.
+func (s *Store) CaptureSessionEventContext(ctx context.Context, sessionName string, event SessionEvent) error {
 	content, err := json.Marshal(event)
 	if err != nil {
 		return fmt.Errorf("failed to marshal event: %w", err)
 	}
 
-	return s.AddObservationWithType(sessionName, string(content), FactTypeSessionEvent)
+	return s.AddObservationWithTypeContext(WithSource(ctx, SourceSession+":"+sessionName), sessionName, string(content), FactTypeSessionEvent)
+}
+
+// CaptureSessionTurn records a conversation turn (e.g. a user prompt) on the session.
+func (s *Store) CaptureSessionTurn(sessionName, content string) error {
+	return s.CaptureSessionTurnContext(context.Background(), sessionName, content)
+}
+
+// CaptureSessionTurnContext is CaptureSessionTurn with a context.
+func (s *Store) CaptureSessionTurnContext(ctx context.Context, sessionName, content string) error {
+	return s.AddObservationWithTypeContext(WithSource(ctx, SourceSession+":"+sessionName), sessionName, content, FactTypeSessionTurn)
 }
 
 func (s *Store) CompleteSession(sessionName, summary string) error {
+	return s.CompleteSessionContext(context.Background(), sessionName, summary)
+}
+
+// CompleteSessionContext is CompleteSession with a context.
+func (s *Store) CompleteSessionContext(ctx context.Context, sessionName, summary string) error {
 	// Store the summary as a session_summary observation
-	if err := s.AddObservationWithType(sessionName, summary, FactTypeSessionSummary); err != nil {
+	if err := s.AddObservationWithTypeContext(WithSource(ctx, SourceSession+":"+sessionName), sessionName, summary, FactTypeSessionSummary); err != nil {
 		return fmt.Errorf("failed to store session summary: %w", err)
 	}
 
 	// Update metadata to mark as completed
-	tag, err := s.GetContainerTag(sessionName)
+	tag, err := s.GetContainerTagContext(ctx, sessionName)
 	if err != nil {
 		return fmt.Errorf("failed to get session metadata: %w", err)
 	}
@@ -95,34 +140,72 @@ func (s *Store) CompleteSession(sessionName, summary string) error {
 		return fmt.Errorf("failed to marshal session metadata: %w", err)
 	}
 
-	return s.SetContainerTag(sessionName, string(metaJSON))
+	if err := s.SetContainerTagContext(ctx, sessionName, string(metaJSON)); err != nil {
+		return err
+	}
+
+	// Cross-linking is best-effort; the session is complete either way
+	_, _ = s.LinkSessionEntitiesContext(ctx, sessionName)
+	return nil
 }
 
 func (s *Store) GetSession(sessionName string) (*Session, error) {
-	entity, err := s.GetEntity(sessionName)
+	return s.GetSessionContext(context.Background(), sessionName)
+}
+
+// GetSessionContext is GetSession with a context.
+func (s *Store) GetSessionContext(ctx context.Context, sessionName string) (*Session, error) {
+	entity, err := s.GetEntityContext(ctx, sessionName)
 	if err != nil {
 		return nil, err
 	}
 	if entity.Type != "session" {
-		return nil, ErrNotFound
+		return nil, &NotFoundError{"session", sessionName}
 	}
 
-	tag, _ := s.GetContainerTag(sessionName)
+	tag, _ := s.GetContainerTagContext(ctx, sessionName)
 	var meta SessionMetadata
 	if tag != "" {
 		_ = json.Unmarshal([]byte(tag), &meta)
 	}
 
-	// Count events and find summary
+	var observations []struct {
+		Content   string    `db:"content"`
+		FactType  string    `db:"fact_type"`
+		CreatedAt time.Time `db:"created_at"`
+	}
+	if err := s.db.SelectContext(ctx, &observations, `
+		SELECT content, COALESCE(fact_type, '') as fact_type, created_at
+		FROM observations WHERE entity_id = ? ORDER BY created_at, id
+	`, entity.ID); err != nil {
+		return nil, err
+	}
+
+	// Count events and turns, and find summary
 	var summary string
-	var eventCount int
-	for _, obs := range entity.Observations {
-		// Try to parse as event JSON
-		var evt SessionEvent
-		if err := json.Unmarshal([]byte(obs), &evt); err == nil && evt.ToolName != "" {
+	var eventCount, turnCount int
+	var timeline []SessionEntry
+	for _, obs := range observations {
+		switch FactType(obs.FactType) {
+		case FactTypeSessionTurn:
+			turnCount++
+			timeline = append(timeline, SessionEntry{FactType: FactTypeSessionTurn, Content: obs.Content, At: obs.CreatedAt})
+		case FactTypeSessionEvent:
 			eventCount++
-		} else {
-			summary = obs
+			var evt SessionEvent
+			_ = json.Unmarshal([]byte(obs.Content), &evt)
+			timeline = append(timeline, sessionEventEntry(evt, obs.CreatedAt))
+		case FactTypeSessionSummary:
+			summary = obs.Content
+		default:
+			// Untyped rows: events are JSON, anything else is the summary
+			var evt SessionEvent
+			if err := json.Unmarshal([]byte(obs.Content), &evt); err == nil && evt.ToolName != "" {
+				eventCount++
+				timeline = append(timeline, sessionEventEntry(evt, obs.CreatedAt))
+			} else {
+				summary = obs.Content
+			}
 		}
 	}
 
@@ -132,7 +215,9 @@ func (s *Store) GetSession(sessionName string) (*Session, error) {
 		Status:     meta.Status,
 		Summary:    summary,
 		EventCount: eventCount,
+		TurnCount:  turnCount,
 		StartedAt:  entity.CreatedAt,
+		Timeline:   timeline,
 	}
 
 	if meta.EndedAt != "" {
@@ -142,15 +227,62 @@ func (s *Store) GetSession(sessionName string) (*Session, error) {
 	return session, nil
 }
 
+// sessionEventEntry prefers the event's own timestamp over the capture time,
+// since hooks buffer events and store them when the session ends.
+func sessionEventEntry(evt SessionEvent, capturedAt time.Time) SessionEntry {
+	at := capturedAt
+	if t, err := time.Parse(time.RFC3339, evt.Timestamp); err == nil {
+		at = t
+	}
+	return SessionEntry{FactType: FactTypeSessionEvent, Event: evt, At: at}
+}
+
+// ListSessionsSince returns the full sessions for a project (all projects when
+// empty) started at or after since, oldest first.
+func (s *Store) ListSessionsSince(project string, since time.Time) ([]*Session, error) {
+	return s.ListSessionsSinceContext(context.Background(), project, since)
+}
+
+// ListSessionsSinceContext is ListSessionsSince with a context.
+func (s *Store) ListSessionsSinceContext(ctx context.Context, project string, since time.Time) ([]*Session, error) {
+	entities, err := s.ListEntitiesContext(ctx, "session")
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions []*Session
+	for _, entity := range entities {
+		if entity.CreatedAt.Before(since) {
+			continue
+		}
+		session, err := s.GetSessionContext(ctx, entity.Name)
+		if err != nil {
+			return nil, err
+		}
+		if project != "" && session.Project != project {
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+
+	sort.SliceStable(sessions, func(i, j int) bool { return sessions[i].StartedAt.Before(sessions[j].StartedAt) })
+	return sessions, nil
+}
+
 func (s *Store) ListSessions(project, status string, limit int) ([]*Session, error) {
-	entities, err := s.ListEntities("session")
+	return s.ListSessionsContext(context.Background(), project, status, limit)
+}
+
+// ListSessionsContext is ListSessions with a context.
+func (s *Store) ListSessionsContext(ctx context.Context, project, status string, limit int) ([]*Session, error) {
+	entities, err := s.ListEntitiesContext(ctx, "session")
 	if err != nil {
 		return nil, err
 	}
 
 	var sessions []*Session
 	for _, entity := range entities {
-		tag, _ := s.GetContainerTag(entity.Name)
+		tag, _ := s.GetContainerTagContext(ctx, entity.Name)
 		var meta SessionMetadata
 		if tag != "" {
 			_ = json.Unmarshal([]byte(tag), &meta)
@@ -179,6 +311,11 @@ func (s *Store) ListSessions(project, status string, limit int) ([]*Session, err
 }
 
 func (s *Store) GetRecentSessionSummaries(project string, hours, tokenBudget int) ([]ContextResult, error) {
+	return s.GetRecentSessionSummariesContext(context.Background(), project, hours, tokenBudget)
+}
+
+// GetRecentSessionSummariesContext is GetRecentSessionSummaries with a context.
+func (s *Store) GetRecentSessionSummariesContext(ctx context.Context, project string, hours, tokenBudget int) ([]ContextResult, error) {
 	if hours <= 0 {
 		hours = 72
 	}
@@ -202,7 +339,7 @@ func (s *Store) GetRecentSessionSummaries(project string, hours, tokenBudget int
 	`
 
 	var results []ContextResult
-	if err := s.db.Select(&results, query, hoursParam); err != nil {
+	if err := s.db.SelectContext(ctx, &results, query, hoursParam); err != nil {
 		return nil, err
 	}
 
@@ -210,7 +347,7 @@ func (s *Store) GetRecentSessionSummaries(project string, hours, tokenBudget int
 	if project != "" {
 		var filtered []ContextResult
 		for _, r := range results {
-			tag, _ := s.GetContainerTag(r.EntityName)
+			tag, _ := s.GetContainerTagContext(ctx, r.EntityName)
 			var meta SessionMetadata
 			if tag != "" {
 				_ = json.Unmarshal([]byte(tag), &meta)
@@ -226,7 +363,143 @@ func (s *Store) GetRecentSessionSummaries(project string, hours, tokenBudget int
 	tokenCount := 0
 	var selected []ContextResult
 	for _, r := range results {
-		entryTokens := (len(r.EntityName) + len(r.Content) + 20) / 4
+		entryTokens := contextEntryTokens(s.tokenizer, r)
+		if tokenCount+entryTokens > tokenBudget {
+			break
+		}
+		tokenCount += entryTokens
+		selected = append(selected, r)
+	}
+
+	return selected, nil
+}
+
+// SessionMatch is a session whose summary, events, or turns matched a search.
+type SessionMatch struct {
+	Name      string
+	Project   string
+	Summary   string
+	StartedAt time.Time
+	Matches   []string // Matching observation contents, best first
+	Score     float64  // Best BM25 score; lower is better
+}
+
+// maxSessionMatches caps the matching observations kept per session.
+const maxSessionMatches = 3
+
+// SearchSessions finds sessions whose summaries, events, or turns match the
+// query using FTS5, best match first. Project filters by session project when set.
+func (s *Store) SearchSessions(query, project string, limit int) ([]*SessionMatch, error) {
+	return s.SearchSessionsContext(context.Background(), query, project, limit)
+}
+
+// SearchSessionsContext is SearchSessions with a context.
+func (s *Store) SearchSessionsContext(ctx context.Context, query, project string, limit int) ([]*SessionMatch, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+
+	var rows []struct {
+		EntityName string    `db:"entity_name"`
+		CreatedAt  time.Time `db:"created_at"`
+		Content    string    `db:"content"`
+		FactType   string    `db:"fact_type"`
+		Score      float64   `db:"score"`
+	}
+	matchSQL, args := s.observationMatchSQL(query)
+	err := s.db.SelectContext(ctx, &rows, `
+		SELECT e.name as entity_name, e.created_at, o.content,
+		       COALESCE(o.fact_type, '') as fact_type, f.score
+		FROM (`+matchSQL+`) f
+		JOIN observations o ON o.id = f.id
+		JOIN entities e ON e.id = o.entity_id
+		WHERE e.entity_type = 'session'
+		ORDER BY f.score
+	`, args...)
+	if err != nil {
+		// Invalid FTS syntax matches nothing, as in SearchWithOptions
+		if strings.Contains(err.Error(), "fts5") {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var matches []*SessionMatch
+	byName := map[string]*SessionMatch{}
+	for _, r := range rows {
+		m, ok := byName[r.EntityName]
+		if !ok {
+			tag, _ := s.GetContainerTagContext(ctx, r.EntityName)
+			var meta SessionMetadata
+			if tag != "" {
+				_ = json.Unmarshal([]byte(tag), &meta)
+			}
+			if project != "" && meta.Project != project {
+				byName[r.EntityName] = nil
+				continue
+			}
+			m = &SessionMatch{Name: r.EntityName, Project: meta.Project, StartedAt: r.CreatedAt, Score: r.Score}
+			byName[r.EntityName] = m
+			matches = append(matches, m)
+		}
+		if m == nil {
+			continue
+		}
+		if FactType(r.FactType) == FactTypeSessionSummary {
+			m.Summary = r.Content
+		}
+		if len(m.Matches) < maxSessionMatches {
+			m.Matches = append(m.Matches, r.Content)
+		}
+	}
+
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	// Sessions matched only on events still need their summary for display
+	for _, m := range matches {
+		if m.Summary == "" {
+			if session, err := s.GetSessionContext(ctx, m.Name); err == nil {
+				m.Summary = session.Summary
+			}
+		}
+	}
+
+	return matches, nil
+}
+
+// SearchSessionSummaries returns the summaries of sessions matching the query,
+// best match first, within the token budget. Used by recall when a query is given.
+func (s *Store) SearchSessionSummaries(query, project string, tokenBudget int) ([]ContextResult, error) {
+	return s.SearchSessionSummariesContext(context.Background(), query, project, tokenBudget)
+}
+
+// SearchSessionSummariesContext is SearchSessionSummaries with a context.
+func (s *Store) SearchSessionSummariesContext(ctx context.Context, query, project string, tokenBudget int) ([]ContextResult, error) {
+	if tokenBudget <= 0 {
+		tokenBudget = 1500
+	}
+
+	matches, err := s.SearchSessionsContext(ctx, query, project, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenCount := 0
+	var selected []ContextResult
+	for _, m := range matches {
+		content := m.Summary
+		if content == "" {
+			content = m.Matches[0]
+		}
+		r := ContextResult{
+			EntityName: m.Name,
+			EntityType: "session",
+			Content:    content,
+			FactType:   string(FactTypeSessionSummary),
+		}
+		entryTokens := contextEntryTokens(s.tokenizer, r)
 		if tokenCount+entryTokens > tokenBudget {
 			break
 		}
@@ -236,3 +509,120 @@ func (s *Store) GetRecentSessionSummaries(project string, hours, tokenBudget int
 
 	return selected, nil
 }
+
+// MergeSessions folds the source sessions into target, for one logical unit of
+// work split across restarts. Events and turns move to the target in their
+// original order, summaries are combined oldest first, and the target spans
+// from the earliest start to the latest end. Source sessions are deleted.
+func (s *Store) MergeSessions(target string, sources ...string) (*Session, error) {
+	return s.MergeSessionsContext(context.Background(), target, sources...)
+}
+
+// MergeSessionsContext is MergeSessions with a context.
+func (s *Store) MergeSessionsContext(ctx context.Context, target string, sources ...string) (*Session, error) {
+	if len(sources) == 0 {
+		return nil, errors.New("no sessions to merge")
+	}
+
+	targetSession, err := s.GetSessionContext(ctx, target)
+	if err != nil {
+		return nil, fmt.Errorf("session %s: %w", target, err)
+	}
+	all := []*Session{targetSession}
+	for _, name := range sources {
+		if name == target {
+			return nil, fmt.Errorf("cannot merge session %s into itself", name)
+		}
+		session, err := s.GetSessionContext(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("session %s: %w", name, err)
+		}
+		all = append(all, session)
+	}
+	// Names embed a millisecond timestamp, which breaks ties in the per-second created_at
+	sort.SliceStable(all, func(i, j int) bool {
+		if !all[i].StartedAt.Equal(all[j].StartedAt) {
+			return all[i].StartedAt.Before(all[j].StartedAt)
+		}
+		return all[i].Name < all[j].Name
+	})
+
+	meta := SessionMetadata{
+		Project:   targetSession.Project,
+		Status:    "completed",
+		StartedAt: all[0].StartedAt.Format(time.RFC3339),
+	}
+	var summaries []string
+	var endedAt time.Time
+	for _, session := range all {
+		if session.Status == "active" {
+			meta.Status = "active"
+		}
+		if session.EndedAt.After(endedAt) {
+			endedAt = session.EndedAt
+		}
+		if session.Summary != "" && !slices.Contains(summaries, session.Summary) {
+			summaries = append(summaries, session.Summary)
+		}
+	}
+	if meta.Status == "completed" && !endedAt.IsZero() {
+		meta.EndedAt = endedAt.Format(time.RFC3339)
+	}
+	metaJSON, err := json.Marshal(meta)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal session metadata: %w", err)
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var targetID int64
+	if err := tx.GetContext(ctx, &targetID, "SELECT id FROM entities WHERE name = ? AND entity_type = 'session'", target); err != nil {
+		return nil, err
+	}
+
+	for _, name := range sources {
+		var sourceID int64
+		if err := tx.GetContext(ctx, &sourceID, "SELECT id FROM entities WHERE name = ? AND entity_type = 'session'", name); err != nil {
+			return nil, err
+		}
+		// Identical events already on the target are left behind and deleted with the source
+		if _, err := tx.ExecContext(ctx, "UPDATE OR IGNORE observations SET entity_id = ? WHERE entity_id = ?", targetID, sourceID); err != nil {
+			return nil, fmt.Errorf("moving observations from %s: %w", name, err)
+		}
+		if err := deleteEntityRows(ctx, tx, name); err != nil {
+			return nil, err
+		}
+	}
+
+	// Replace the individual summaries with the combined one
+	if _, err := tx.ExecContext(ctx, "DELETE FROM observations WHERE entity_id = ? AND fact_type = ?", targetID, string(FactTypeSessionSummary)); err != nil {
+		return nil, err
+	}
+	if len(summaries) > 0 {
+		combined := strings.Join(summaries, " ")
+		if _, err := tx.ExecContext(ctx,
+			"INSERT OR IGNORE INTO observations (entity_id, content, content_hash, fact_type, source, author) VALUES (?, ?, ?, ?, ?, ?)",
+			targetID, combined, contentHash(combined), string(FactTypeSessionSummary), SourceSession+":"+target, s.userValue(),
+		); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE entities SET container_tag = ?, created_at = ? WHERE id = ?",
+		string(metaJSON), all[0].StartedAt.UTC().Format(time.DateTime), targetID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	// Relations from the deleted sources are gone; relink the combined session
+	_, _ = s.LinkSessionEntitiesContext(ctx, target)
+
+	return s.GetSessionContext(ctx, target)
+}
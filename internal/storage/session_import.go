@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ImportedSession is a past session for ImportSession to record, such as one
+// read from a conversation transcript.
+type ImportedSession struct {
+	Project    string
+	StartedAt  time.Time
+	EndedAt    time.Time
+	Timeline   []SessionEntry    // Turns and events, oldest first, with when they happened
+	Summary    string            // Recorded as the session summary when set
+	Attributes map[string]string // Set on the session entity, e.g. where it came from
+}
+
+// ImportSession records a completed session as the session hooks would have
+// captured it, dated when it happened rather than now: the session starts at
+// StartedAt, and its turns, events, and summary are dated by their entries
+// and EndedAt. The session is named like CreateSession names one started at
+// StartedAt, so importing it again returns ErrEntityExists. Entities the
+// session touched are linked as CompleteSession links them.
+func (s *Store) ImportSession(imp ImportedSession) (*Session, error) {
+	return s.ImportSessionContext(context.Background(), imp)
+}
+
+// ImportSessionContext is ImportSession with a context.
+func (s *Store) ImportSessionContext(ctx context.Context, imp ImportedSession) (*Session, error) {
+	if imp.Project == "" {
+		return nil, &ValidationError{Field: "project", Reason: "must not be empty"}
+	}
+	if imp.StartedAt.IsZero() {
+		return nil, &ValidationError{Field: "startedAt", Reason: "must be set"}
+	}
+	if imp.EndedAt.Before(imp.StartedAt) {
+		imp.EndedAt = imp.StartedAt
+	}
+	name := sessionName(imp.Project, imp.StartedAt.Local())
+	ctx = WithSource(ctx, SourceSession+":"+name)
+
+	entity, err := s.CreateEntityContext(ctx, name, "session", nil)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.db.ExecContext(ctx, "UPDATE entities SET created_at = ? WHERE id = ?", sqlTime(imp.StartedAt), entity.ID); err != nil {
+		return nil, err
+	}
+	if len(imp.Attributes) > 0 {
+		if err := s.SetAttributesContext(ctx, name, imp.Attributes); err != nil {
+			return nil, err
+		}
+	}
+	metaJSON, err := json.Marshal(SessionMetadata{
+		Project:   imp.Project,
+		Status:    "completed",
+		StartedAt: imp.StartedAt.Format(time.RFC3339),
+		EndedAt:   imp.EndedAt.Format(time.RFC3339),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal session metadata: %w", err)
+	}
+	if err := s.SetContainerTagContext(ctx, name, string(metaJSON)); err != nil {
+		return nil, fmt.Errorf("failed to set session metadata: %w", err)
+	}
+
+	// add records an observation dated at; a repeated one keeps its first date
+	add := func(content string, factType FactType, at time.Time) error {
+		if err := s.AddObservationWithTypeContext(ctx, name, content, factType); err != nil {
+			return err
+		}
+		stored, _ := s.RedactSecrets(content)
+		_, err := s.db.ExecContext(ctx, `
+			UPDATE observations SET created_at = ?
+			WHERE entity_id = ? AND content = ? AND created_at > ?`,
+			sqlTime(at), entity.ID, stored, sqlTime(at))
+		return err
+	}
+	for _, entry := range imp.Timeline {
+		at := entry.At
+		if at.IsZero() {
+			at = imp.StartedAt
+		}
+		content := entry.Content
+		if entry.FactType == FactTypeSessionEvent {
+			if entry.Event.Timestamp == "" {
+				entry.Event.Timestamp = at.UTC().Format(time.RFC3339)
+			}
+			raw, err := json.Marshal(entry.Event)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal event: %w", err)
+			}
+			content = string(raw)
+		}
+		if content == "" {
+			continue
+		}
+		if err := add(content, entry.FactType, at); err != nil {
+			return nil, err
+		}
+	}
+	if imp.Summary != "" {
+		if err := add(imp.Summary, FactTypeSessionSummary, imp.EndedAt); err != nil {
+			return nil, fmt.Errorf("failed to store session summary: %w", err)
+		}
+	}
+
+	// Cross-linking is best-effort, as for captured sessions
+	_, _ = s.LinkSessionEntitiesContext(ctx, name)
+	return s.GetSessionContext(ctx, name)
+}
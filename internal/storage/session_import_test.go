@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestImportSession(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+	if _, err := store.CreateEntity("store.go", "file", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2025, 3, 4, 10, 0, 0, 0, time.UTC)
+	imp := ImportedSession{
+		Project:   "api",
+		StartedAt: start,
+		EndedAt:   start.Add(30 * time.Minute),
+		Timeline: []SessionEntry{
+			{FactType: FactTypeSessionTurn, Content: "User: Speed up the store", At: start},
+			{FactType: FactTypeSessionEvent, Event: SessionEvent{ToolName: "Edit", FilePath: "/src/api/store.go"}, At: start.Add(5 * time.Minute)},
+			{FactType: FactTypeSessionTurn, Content: "Assistant: Added an index.", At: start.Add(10 * time.Minute)},
+		},
+		Summary:    "Sped up the store with an index.",
+		Attributes: map[string]string{"transcript": "abc-123"},
+	}
+	session, err := store.ImportSession(imp)
+	if err != nil {
+		t.Fatalf("ImportSession failed: %v", err)
+	}
+	if session.Status != "completed" || session.Project != "api" || session.Summary != imp.Summary {
+		t.Errorf("unexpected session %+v", session)
+	}
+	if !session.StartedAt.Equal(start) || !session.EndedAt.Equal(imp.EndedAt) {
+		t.Errorf("expected the session dated %v to %v, got %v to %v", start, imp.EndedAt, session.StartedAt, session.EndedAt)
+	}
+	if session.TurnCount != 2 || session.EventCount != 1 || len(session.Timeline) != 3 {
+		t.Fatalf("expected 2 turns and 1 event, got %+v", session)
+	}
+	if entry := session.Timeline[1]; entry.Event.FilePath != "/src/api/store.go" || !entry.At.Equal(start.Add(5*time.Minute)) {
+		t.Errorf("unexpected event entry %+v", entry)
+	}
+	if got := session.Timeline[2].At; !got.Equal(start.Add(10 * time.Minute)) {
+		t.Errorf("expected the reply dated when it happened, got %v", got)
+	}
+	if attrs, _ := store.GetAttributes(session.Name); attrs["transcript"] != "abc-123" {
+		t.Errorf("expected the transcript attribute, got %v", attrs)
+	}
+	relations, err := store.ListRelations(session.Name)
+	if err != nil || len(relations) != 1 || relations[0].To != "store.go" {
+		t.Errorf("expected the touched file linked, got %+v (%v)", relations, err)
+	}
+
+	// Recent summaries leave out a session from long ago
+	recent, err := store.GetRecentSessionSummaries("api", 24, 1000)
+	if err != nil || len(recent) != 0 {
+		t.Errorf("expected no recent summaries, got %+v (%v)", recent, err)
+	}
+
+	if _, err := store.ImportSession(imp); !errors.Is(err, ErrEntityExists) {
+		t.Errorf("expected a second import to find the session, got %v", err)
+	}
+	if _, err := store.ImportSession(ImportedSession{Project: "api"}); err == nil {
+		t.Error("expected a start time required")
+	}
+}
@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"context"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// RelationWorkedOn links a session to an entity it touched.
+const RelationWorkedOn = "worked_on"
+
+// minLinkNameLen skips very short entity names, which match too much prose.
+const minLinkNameLen = 3
+
+// LinkSessionEntities creates worked_on relations from the session to known
+// entities named in its summary or matching files its events touched.
+// Returns the names of the linked entities.
+func (s *Store) LinkSessionEntities(sessionName string) ([]string, error) {
+	return s.LinkSessionEntitiesContext(context.Background(), sessionName)
+}
+
+// LinkSessionEntitiesContext is LinkSessionEntities with a context.
+func (s *Store) LinkSessionEntitiesContext(ctx context.Context, sessionName string) ([]string, error) {
+	session, err := s.GetSessionContext(ctx, sessionName)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	if err := s.db.SelectContext(ctx, &names, `
+		SELECT name FROM entities
+		WHERE entity_type != 'session' AND (is_latest = 1 OR is_latest IS NULL)
+	`); err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, entry := range session.Timeline {
+		if entry.Event.FilePath != "" {
+			files = append(files, entry.Event.FilePath)
+		}
+	}
+
+	var linked []string
+	for _, name := range names {
+		if len(name) < minLinkNameLen {
+			continue
+		}
+		if !mentionsName(session.Summary, name) && !touchesEntityFile(files, name) {
+			continue
+		}
+		if err := s.CreateRelationContext(ctx, sessionName, name, RelationWorkedOn); err != nil {
+			return linked, err
+		}
+		linked = append(linked, name)
+	}
+	return linked, nil
+}
+
+// mentionsName reports whether text mentions name as a whole word, ignoring case.
+func mentionsName(text, name string) bool {
+	if text == "" {
+		return false
+	}
+	re, err := regexp.Compile(`(?i)(^|[^\pL\pN_])` + regexp.QuoteMeta(name) + `($|[^\pL\pN_])`)
+	if err != nil {
+		return false
+	}
+	return re.MatchString(text)
+}
+
+// touchesEntityFile reports whether any file path matches the entity name:
+// the name equals the file name with or without extension, or names a run of
+// path segments such as a package directory ("internal/storage").
+func touchesEntityFile(files []string, name string) bool {
+	nameSegments := strings.Split(strings.Trim(strings.ToLower(strings.ReplaceAll(name, `\`, "/")), "/"), "/")
+
+	for _, f := range files {
+		segments := strings.Split(strings.ToLower(strings.ReplaceAll(f, `\`, "/")), "/")
+		base := segments[len(segments)-1]
+		if len(nameSegments) == 1 && strings.TrimSuffix(base, path.Ext(base)) == nameSegments[0] {
+			return true
+		}
+		for i := 0; i+len(nameSegments) <= len(segments); i++ {
+			if slices.Equal(segments[i:i+len(nameSegments)], nameSegments) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMentionsName(t *testing.T) {
+	tests := []struct {
+		text, name string
+		want       bool
+	}{
+		{"Fixed the Parser bug", "parser", true},
+		{"Reworked mark42 hooks", "mark42", true},
+		{"Updated go.mod for the build", "go.mod", true},
+		{"Fixed the parsers", "parser", false},
+		{"subparser cleanup", "parser", false},
+		{"", "parser", false},
+	}
+	for _, tt := range tests {
+		if got := mentionsName(tt.text, tt.name); got != tt.want {
+			t.Errorf("mentionsName(%q, %q) = %v, want %v", tt.text, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTouchesEntityFile(t *testing.T) {
+	files := []string{"/proj/internal/storage/session.go", `C:\proj\cmd\memory\Main.go`}
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"session", true},
+		{"session.go", true},
+		{"storage", true},
+		{"internal/storage", true},
+		{"main", true},
+		{"cmd/memory", true},
+		{"sessions", false},
+		{"storage/internal", false},
+		{"proj/cmd/storage", false},
+	}
+	for _, tt := range tests {
+		if got := touchesEntityFile(files, tt.name); got != tt.want {
+			t.Errorf("touchesEntityFile(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCompleteSession_LinksEntities(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	store.CreateEntity("Parser", "component", nil)
+	store.CreateEntity("storage", "package", nil)
+	store.CreateEntity("Unrelated", "concept", nil)
+	store.CreateEntity("db", "concept", nil) // too short to link
+
+	session, _ := store.CreateSession("myapp")
+	store.CaptureSessionEvent(session.Name, SessionEvent{ToolName: "Edit", FilePath: "/myapp/internal/storage/db.go"})
+	if err := store.CompleteSession(session.Name, "Improved parser errors and db handling"); err != nil {
+		t.Fatalf("CompleteSession failed: %v", err)
+	}
+
+	relations, err := store.ListRelations(session.Name)
+	if err != nil {
+		t.Fatalf("ListRelations failed: %v", err)
+	}
+	var linked []string
+	for _, r := range relations {
+		if r.Type == RelationWorkedOn && r.From == session.Name {
+			linked = append(linked, r.To)
+		}
+	}
+	slices.Sort(linked)
+	if want := []string{"Parser", "storage"}; !slices.Equal(linked, want) {
+		t.Errorf("linked = %v, want %v", linked, want)
+	}
+}
+
+func TestMergeSessions_RelinksEntities(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	store.CreateEntity("Parser", "component", nil)
+
+	first, _ := store.CreateSession("myapp")
+	store.CompleteSession(first.Name, "Started the parser.")
+	second, _ := store.CreateSession("myapp")
+	store.CompleteSession(second.Name, "Wrote docs.")
+
+	if _, err := store.MergeSessions(second.Name, first.Name); err != nil {
+		t.Fatalf("MergeSessions failed: %v", err)
+	}
+
+	relations, _ := store.ListRelations("Parser")
+	if len(relations) != 1 || relations[0].From != second.Name || relations[0].Type != RelationWorkedOn {
+		t.Errorf("expected Parser linked to merged session, got %+v", relations)
+	}
+}
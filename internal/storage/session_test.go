@@ -2,8 +2,11 @@ package storage
 
 import (
 	"encoding/json"
+	"errors"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func newTestStoreWithMigrations(t *testing.T) *Store {
@@ -97,6 +100,38 @@ func TestCaptureSessionEvent(t *testing.T) {
 	}
 }
 
+func TestCaptureSessionTurn(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	session, err := store.CreateSession("test-project")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	if err := store.CaptureSessionTurn(session.Name, "User: add a retry to the fetcher"); err != nil {
+		t.Fatalf("CaptureSessionTurn failed: %v", err)
+	}
+	store.CaptureSessionEvent(session.Name, SessionEvent{ToolName: "Edit", FilePath: "/fetch.go"})
+	store.CompleteSession(session.Name, "Added retries")
+
+	got, err := store.GetSession(session.Name)
+	if err != nil {
+		t.Fatalf("GetSession failed: %v", err)
+	}
+	if got.TurnCount != 1 || got.EventCount != 1 {
+		t.Errorf("expected 1 turn and 1 event, got %d and %d", got.TurnCount, got.EventCount)
+	}
+	if got.Summary != "Added retries" {
+		t.Errorf("turns must not be mistaken for the summary, got %q", got.Summary)
+	}
+
+	obs, _ := store.GetObservationsByFactType(FactTypeSessionTurn)
+	if len(obs) != 1 {
+		t.Errorf("expected 1 session_turn observation, got %d", len(obs))
+	}
+}
+
 func TestCompleteSession(t *testing.T) {
 	store := newTestStoreWithMigrations(t)
 	defer store.Close()
@@ -162,7 +197,7 @@ func TestGetSession_NotFound(t *testing.T) {
 	defer store.Close()
 
 	_, err := store.GetSession("nonexistent-session")
-	if err != ErrNotFound {
+	if !errors.Is(err, ErrNotFound) {
 		t.Errorf("expected ErrNotFound, got %v", err)
 	}
 }
@@ -246,3 +281,193 @@ func TestGetRecentSessionSummaries(t *testing.T) {
 		t.Error("expected to find session summaries in results")
 	}
 }
+
+func TestGetSession_Timeline(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	session, _ := store.CreateSession("test-project")
+	store.CaptureSessionTurn(session.Name, "User: fix the build")
+	store.CaptureSessionEvent(session.Name, SessionEvent{ToolName: "Edit", FilePath: "/a.go", Timestamp: "2026-01-02T03:04:05Z"})
+	store.CaptureSessionEvent(session.Name, SessionEvent{ToolName: "Bash", Command: "go test"})
+	store.CompleteSession(session.Name, "Fixed the build")
+
+	s, err := store.GetSession(session.Name)
+	if err != nil {
+		t.Fatalf("GetSession failed: %v", err)
+	}
+
+	if len(s.Timeline) != 3 {
+		t.Fatalf("expected 3 timeline entries, got %d", len(s.Timeline))
+	}
+	if s.Timeline[0].FactType != FactTypeSessionTurn || s.Timeline[0].Content != "User: fix the build" {
+		t.Errorf("unexpected first entry: %+v", s.Timeline[0])
+	}
+	if s.Timeline[1].Event.FilePath != "/a.go" {
+		t.Errorf("unexpected second entry: %+v", s.Timeline[1])
+	}
+	if want := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC); !s.Timeline[1].At.Equal(want) {
+		t.Errorf("expected event timestamp %v, got %v", want, s.Timeline[1].At)
+	}
+	if s.Timeline[2].Event.Command != "go test" || s.Timeline[2].At.IsZero() {
+		t.Errorf("unexpected third entry: %+v", s.Timeline[2])
+	}
+}
+
+func TestListSessionsSince(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	old, _ := store.CreateSession("project-a")
+	store.CompleteSession(old.Name, "Old work")
+	_, err := store.db.Exec(`UPDATE entities SET created_at = datetime('now', '-10 days') WHERE name = ?`, old.Name)
+	if err != nil {
+		t.Fatalf("backdating session: %v", err)
+	}
+
+	s1, _ := store.CreateSession("project-a")
+	store.CompleteSession(s1.Name, "Recent work")
+	s2, _ := store.CreateSession("project-b")
+	store.CompleteSession(s2.Name, "Other project")
+
+	sessions, err := store.ListSessionsSince("", time.Now().Add(-7*24*time.Hour))
+	if err != nil {
+		t.Fatalf("ListSessionsSince failed: %v", err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("expected 2 recent sessions, got %d", len(sessions))
+	}
+
+	sessions, err = store.ListSessionsSince("project-a", time.Now().Add(-7*24*time.Hour))
+	if err != nil {
+		t.Fatalf("ListSessionsSince by project failed: %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].Name != s1.Name {
+		t.Fatalf("expected only %s, got %v", s1.Name, sessions)
+	}
+	if sessions[0].Summary != "Recent work" {
+		t.Errorf("expected full session with summary, got %q", sessions[0].Summary)
+	}
+}
+
+func TestSearchSessions(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	s1, _ := store.CreateSession("project-a")
+	store.CaptureSessionTurn(s1.Name, "User: why does the worker deadlock?")
+	store.CaptureSessionEvent(s1.Name, SessionEvent{ToolName: "Edit", FilePath: "/a/worker.go"})
+	store.CompleteSession(s1.Name, "Fixed the race condition in the worker pool")
+
+	s2, _ := store.CreateSession("project-b")
+	store.CompleteSession(s2.Name, "Another race condition fix")
+
+	s3, _ := store.CreateSession("project-a")
+	store.CompleteSession(s3.Name, "Docs update")
+
+	store.CreateEntity("Race", "concept", []string{"race condition notes"})
+
+	matches, err := store.SearchSessions("race condition", "", 10)
+	if err != nil {
+		t.Fatalf("SearchSessions failed: %v", err)
+	}
+	if len(matches) != 2 {
+		t.Fatalf("expected 2 matching sessions, got %d", len(matches))
+	}
+
+	matches, err = store.SearchSessions("race", "project-a", 10)
+	if err != nil {
+		t.Fatalf("SearchSessions by project failed: %v", err)
+	}
+	if len(matches) != 1 || matches[0].Name != s1.Name {
+		t.Fatalf("expected only %s, got %+v", s1.Name, matches)
+	}
+	if matches[0].Summary != "Fixed the race condition in the worker pool" {
+		t.Errorf("unexpected summary: %q", matches[0].Summary)
+	}
+
+	// Matching only an event or turn still returns the session summary
+	matches, err = store.SearchSessions("deadlock", "", 10)
+	if err != nil {
+		t.Fatalf("SearchSessions on turns failed: %v", err)
+	}
+	if len(matches) != 1 || matches[0].Summary != "Fixed the race condition in the worker pool" {
+		t.Fatalf("expected s1 with summary, got %+v", matches)
+	}
+	if len(matches[0].Matches) != 1 || !strings.Contains(matches[0].Matches[0], "deadlock") {
+		t.Errorf("unexpected matches: %v", matches[0].Matches)
+	}
+}
+
+func TestMergeSessions(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	first, _ := store.CreateSession("myapp")
+	store.CaptureSessionEvent(first.Name, SessionEvent{ToolName: "Edit", FilePath: "/a.go"})
+	store.CaptureSessionEvent(first.Name, SessionEvent{ToolName: "Bash", Command: "go test"})
+	store.CompleteSession(first.Name, "Started the parser.")
+
+	second, _ := store.CreateSession("myapp")
+	store.CaptureSessionTurn(second.Name, "User: continue the parser")
+	store.CaptureSessionEvent(second.Name, SessionEvent{ToolName: "Edit", FilePath: "/b.go"})
+	store.CaptureSessionEvent(second.Name, SessionEvent{ToolName: "Bash", Command: "go test"})
+	store.CompleteSession(second.Name, "Finished the parser.")
+
+	merged, err := store.MergeSessions(second.Name, first.Name)
+	if err != nil {
+		t.Fatalf("MergeSessions failed: %v", err)
+	}
+
+	if merged.Name != second.Name {
+		t.Errorf("expected merged session %s, got %s", second.Name, merged.Name)
+	}
+	// The duplicate "go test" event collapses into one
+	if merged.EventCount != 3 {
+		t.Errorf("expected 3 events, got %d", merged.EventCount)
+	}
+	if merged.TurnCount != 1 {
+		t.Errorf("expected 1 turn, got %d", merged.TurnCount)
+	}
+	if merged.Summary != "Started the parser. Finished the parser." {
+		t.Errorf("unexpected combined summary: %q", merged.Summary)
+	}
+	if merged.Status != "completed" {
+		t.Errorf("expected completed, got %q", merged.Status)
+	}
+	if merged.StartedAt.After(first.StartedAt.Add(time.Second)) {
+		t.Errorf("expected start %v to cover first session %v", merged.StartedAt, first.StartedAt)
+	}
+	if merged.Timeline[0].Event.FilePath != "/a.go" {
+		t.Errorf("expected first session's events first, got %+v", merged.Timeline[0])
+	}
+
+	if _, err := store.GetSession(first.Name); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected source session deleted, got %v", err)
+	}
+	sessions, _ := store.ListSessions("myapp", "", 10)
+	if len(sessions) != 1 {
+		t.Errorf("expected 1 session left, got %d", len(sessions))
+	}
+}
+
+func TestMergeSessions_Errors(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	s1, _ := store.CreateSession("myapp")
+	store.CreateEntity("NotASession", "concept", nil)
+
+	if _, err := store.MergeSessions(s1.Name); err == nil {
+		t.Error("expected error with no sources")
+	}
+	if _, err := store.MergeSessions(s1.Name, s1.Name); err == nil {
+		t.Error("expected error merging a session into itself")
+	}
+	if _, err := store.MergeSessions(s1.Name, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for missing source, got %v", err)
+	}
+	if _, err := store.MergeSessions(s1.Name, "NotASession"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for non-session source, got %v", err)
+	}
+}
@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Setting keys stored in the database, tuning it for its own corpus.
+const (
+	// SettingVectorWeight balances hybrid search between keywords (0) and
+	// vectors (1); 0.5 weighs them equally
+	SettingVectorWeight = "search.vectorWeight"
+	// SettingRRFK is the smoothing constant of hybrid search's rank fusion;
+	// lower values favor each source's top results more
+	SettingRRFK = "search.rrfK"
+	// SettingContextLog turns on logging of context injections for
+	// AnalyzeContextLog
+	SettingContextLog = "context.log"
+	// SettingContextLogDays is how long logged context injections are kept
+	SettingContextLogDays = "context.logDays"
+)
+
+// SettingInfo describes a database setting.
+type SettingInfo struct {
+	Key         string `json:"key"`
+	Default     string `json:"default"`
+	Description string `json:"description"`
+	parse       func(string) error
+}
+
+// KnownSettings lists the settings SetSetting accepts, by key.
+var KnownSettings = []SettingInfo{
+	{
+		Key:         SettingVectorWeight,
+		Default:     "0.5",
+		Description: "hybrid search balance from keywords (0) to vectors (1)",
+		parse: func(value string) error {
+			w, err := strconv.ParseFloat(value, 64)
+			if err != nil || w < 0 || w > 1 {
+				return errors.New("must be a number from 0 to 1")
+			}
+			return nil
+		},
+	},
+	{
+		Key:         SettingRRFK,
+		Default:     strconv.Itoa(DefaultRRFConfig().K),
+		Description: "rank fusion smoothing; lower favors each source's top results",
+		parse: func(value string) error {
+			if k, err := strconv.Atoi(value); err != nil || k < 1 {
+				return errors.New("must be a positive whole number")
+			}
+			return nil
+		},
+	},
+	{
+		Key:         SettingContextLog,
+		Default:     "false",
+		Description: "log each context injection for mark42 context analyze",
+		parse: func(value string) error {
+			if value != "true" && value != "false" {
+				return errors.New("must be true or false")
+			}
+			return nil
+		},
+	},
+	{
+		Key:         SettingContextLogDays,
+		Default:     "30",
+		Description: "days logged context injections are kept",
+		parse: func(value string) error {
+			if days, err := strconv.Atoi(value); err != nil || days < 1 {
+				return errors.New("must be a positive whole number")
+			}
+			return nil
+		},
+	},
+}
+
+// Setting is a setting's value in the database.
+type Setting struct {
+	Key       string    `db:"key" json:"key"`
+	Value     string    `db:"value" json:"value"`
+	UpdatedAt time.Time `db:"updated_at" json:"updatedAt"`
+}
+
+// knownSetting returns the description of key.
+func knownSetting(key string) (SettingInfo, error) {
+	i := slices.IndexFunc(KnownSettings, func(info SettingInfo) bool { return info.Key == key })
+	if i < 0 {
+		return SettingInfo{}, &ValidationError{Field: "key", Reason: fmt.Sprintf("unknown setting %q", key)}
+	}
+	return KnownSettings[i], nil
+}
+
+// GetSetting returns the value of key set in the database, or its default.
+func (s *Store) GetSetting(key string) (string, error) {
+	return s.GetSettingContext(context.Background(), key)
+}
+
+// GetSettingContext is GetSetting with a context.
+func (s *Store) GetSettingContext(ctx context.Context, key string) (string, error) {
+	info, err := knownSetting(key)
+	if err != nil {
+		return "", err
+	}
+	var value string
+	err = s.db.GetContext(ctx, &value, "SELECT value FROM settings WHERE key = ?", key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return info.Default, nil
+	}
+	return value, err
+}
+
+// ListSettings returns the settings set in the database, by key.
+func (s *Store) ListSettings() ([]Setting, error) {
+	return s.ListSettingsContext(context.Background())
+}
+
+// ListSettingsContext is ListSettings with a context.
+func (s *Store) ListSettingsContext(ctx context.Context) ([]Setting, error) {
+	var settings []Setting
+	err := s.db.SelectContext(ctx, &settings, "SELECT key, value, updated_at FROM settings ORDER BY key")
+	return settings, err
+}
+
+// SetSetting sets key to value in the database, after checking both.
+func (s *Store) SetSetting(key, value string) error {
+	return s.SetSettingContext(context.Background(), key, value)
+}
+
+// SetSettingContext is SetSetting with a context.
+func (s *Store) SetSettingContext(ctx context.Context, key, value string) error {
+	info, err := knownSetting(key)
+	if err != nil {
+		return err
+	}
+	if err := info.parse(value); err != nil {
+		return &ValidationError{Field: key, Reason: err.Error()}
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO settings (key, value, updated_at) VALUES (?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at
+	`, key, value)
+	return err
+}
+
+// UnsetSetting returns key to its default.
+func (s *Store) UnsetSetting(key string) error {
+	return s.UnsetSettingContext(context.Background(), key)
+}
+
+// UnsetSettingContext is UnsetSetting with a context.
+func (s *Store) UnsetSettingContext(ctx context.Context, key string) error {
+	if _, err := knownSetting(key); err != nil {
+		return err
+	}
+	_, err := s.db.ExecContext(ctx, "DELETE FROM settings WHERE key = ?", key)
+	return err
+}
+
+// searchRRFConfig is the rank fusion configuration of the search settings:
+// the vector weight w weighs vectors 2w and keywords 2(1-w), so the default
+// of 0.5 leaves scores as plain RRF. Unreadable settings fall back to the
+// defaults.
+func (s *Store) searchRRFConfig(ctx context.Context) RRFConfig {
+	config := DefaultRRFConfig()
+	if value, err := s.GetSettingContext(ctx, SettingRRFK); err == nil {
+		if k, err := strconv.Atoi(value); err == nil && k > 0 {
+			config.K = k
+		}
+	}
+	if value, err := s.GetSettingContext(ctx, SettingVectorWeight); err == nil {
+		if w, err := strconv.ParseFloat(value, 64); err == nil && w != 0.5 {
+			config.Weights = map[string]float64{"vector": 2 * w, "fts": 2 * (1 - w)}
+		}
+	}
+	return config
+}
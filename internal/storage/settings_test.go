@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSettings(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	if value, err := store.GetSetting(SettingVectorWeight); err != nil || value != "0.5" {
+		t.Errorf("expected the default 0.5, got %q, %v", value, err)
+	}
+	if err := store.SetSetting(SettingVectorWeight, "0.7"); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := store.GetSetting(SettingVectorWeight); value != "0.7" {
+		t.Errorf("expected 0.7, got %q", value)
+	}
+
+	for _, tc := range []struct{ key, value string }{
+		{SettingVectorWeight, "1.5"},
+		{SettingVectorWeight, "lots"},
+		{SettingRRFK, "0"},
+		{"search.unknown", "1"},
+	} {
+		if err := store.SetSetting(tc.key, tc.value); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("SetSetting(%q, %q): expected invalid input, got %v", tc.key, tc.value, err)
+		}
+	}
+
+	settings, err := store.ListSettings()
+	if err != nil || len(settings) != 1 || settings[0].Key != SettingVectorWeight {
+		t.Errorf("ListSettings = %+v, %v", settings, err)
+	}
+
+	if err := store.UnsetSetting(SettingVectorWeight); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := store.GetSetting(SettingVectorWeight); value != "0.5" {
+		t.Errorf("expected the default back, got %q", value)
+	}
+}
+
+func TestHybridSearch_VectorWeight(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	// One observation matches the keywords, the other the vector
+	for _, td := range []struct {
+		name, observation string
+		embedding         []float64
+	}{
+		{"keywords", "prefers typescript programming", []float64{0, 1}},
+		{"vectors", "likes static types", []float64{1, 0}},
+	} {
+		entity, _ := store.CreateEntity(td.name, "person", []string{td.observation})
+		id, _ := store.getObservationID(context.Background(), entity.ID, td.observation)
+		store.StoreEmbedding(id, td.embedding, "test-model")
+	}
+
+	top := func() string {
+		t.Helper()
+		results, err := store.HybridSearch(context.Background(), "typescript", []float64{1, 0}, 10)
+		if err != nil || len(results) != 2 {
+			t.Fatalf("HybridSearch = %+v, %v", results, err)
+		}
+		return results[0].EntityName
+	}
+
+	// Both rank in the vector results, so only the extremes reorder them
+	store.SetSetting(SettingVectorWeight, "1")
+	if name := top(); name != "vectors" {
+		t.Errorf("expected the vector match first with weight 1, got %s", name)
+	}
+	store.SetSetting(SettingVectorWeight, "0")
+	if name := top(); name != "keywords" {
+		t.Errorf("expected the keyword match first with weight 0, got %s", name)
+	}
+}
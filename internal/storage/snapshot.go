@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Snapshot is a copy of the database taken before a risky operation.
+type Snapshot struct {
+	ID            int64     `db:"id" json:"id"`
+	Path          string    `db:"path" json:"path"`
+	Reason        string    `db:"reason" json:"reason"` // The operation it was taken before
+	SchemaVersion int64     `db:"schema_version" json:"schemaVersion"`
+	Size          int64     `db:"size_bytes" json:"size"`
+	CreatedAt     time.Time `db:"created_at" json:"createdAt"`
+}
+
+// snapshotReasonChars are replaced in reasons used in snapshot file names.
+var snapshotReasonChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// CreateSnapshot writes a consistent copy of the database to a new file in
+// dir, named for the time and reason, and records it. The copy is made with
+// VACUUM INTO, so it is compact and safe to take while others write. Hosted
+// databases cannot be snapshotted.
+func (s *Store) CreateSnapshot(dir, reason string) (*Snapshot, error) {
+	return s.CreateSnapshotContext(context.Background(), dir, reason)
+}
+
+// CreateSnapshotContext is CreateSnapshot with a context.
+func (s *Store) CreateSnapshotContext(ctx context.Context, dir, reason string) (*Snapshot, error) {
+	if s.remote {
+		return nil, &ValidationError{Field: "snapshot", Reason: "hosted databases are backed up by their server"}
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("creating backup directory: %w", err)
+	}
+
+	name := "memory-" + time.Now().UTC().Format("20060102-150405")
+	if slug := strings.Trim(snapshotReasonChars.ReplaceAllString(strings.ToLower(reason), "-"), "-"); slug != "" {
+		name += "-" + slug
+	}
+	path := filepath.Join(dir, name+".db")
+	for i := 2; ; i++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			break
+		}
+		path = filepath.Join(dir, fmt.Sprintf("%s-%d.db", name, i))
+	}
+
+	snapshot := &Snapshot{Path: path, Reason: reason}
+	var err error
+	if snapshot.SchemaVersion, err = s.GetSchemaVersion(); err != nil {
+		return nil, err
+	}
+	if _, err := s.db.ExecContext(ctx, "VACUUM INTO ?", path); err != nil {
+		return nil, fmt.Errorf("writing snapshot: %w", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	snapshot.Size = info.Size()
+
+	res, err := s.db.ExecContext(ctx, "INSERT INTO snapshots (path, reason, schema_version, size_bytes) VALUES (?, ?, ?, ?)",
+		snapshot.Path, snapshot.Reason, snapshot.SchemaVersion, snapshot.Size)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot.ID, err = res.LastInsertId(); err != nil {
+		return nil, err
+	}
+	snapshot.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	return snapshot, nil
+}
+
+// ListSnapshots returns the recorded snapshots, newest first. A snapshot
+// whose file was removed is still listed.
+func (s *Store) ListSnapshots() ([]Snapshot, error) {
+	return s.ListSnapshotsContext(context.Background())
+}
+
+// ListSnapshotsContext is ListSnapshots with a context.
+func (s *Store) ListSnapshotsContext(ctx context.Context) ([]Snapshot, error) {
+	var snapshots []Snapshot
+	err := s.db.SelectContext(ctx, &snapshots,
+		"SELECT id, path, reason, schema_version, size_bytes, created_at FROM snapshots ORDER BY id DESC")
+	return snapshots, err
+}
+
+// RestoreSnapshot replaces the database at dbPath with the snapshot at
+// snapshotPath. No store may have the database open; its WAL and shared
+// memory files are removed so they don't replay over the snapshot. The
+// snapshot is copied, not moved, so it can be restored again.
+func RestoreSnapshot(snapshotPath, dbPath string) error {
+	if IsRemoteDSN(dbPath) {
+		return &ValidationError{Field: "path", Reason: "hosted databases are restored by their server"}
+	}
+	src, err := os.Open(snapshotPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(src, header); err != nil || string(header) != "SQLite format 3\x00" {
+		return &ValidationError{Field: "snapshot", Reason: snapshotPath + " is not a SQLite database"}
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	// Copy next to the database, then rename over it, so a failed copy
+	// leaves the database as it was
+	tmp, err := os.CreateTemp(filepath.Dir(dbPath), filepath.Base(dbPath)+".restore-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, src); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Remove(dbPath + suffix); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.Rename(tmp.Name(), dbPath)
+}
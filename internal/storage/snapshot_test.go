@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSnapshot_CreateAndRestore(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "memory.db")
+	store, err := NewStore(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	store.CreateEntity("Go", "language", []string{"Fast compiler"})
+
+	backups := filepath.Join(dir, "backups")
+	first, err := store.CreateSnapshot(backups, "Dedupe run")
+	if err != nil {
+		t.Fatalf("CreateSnapshot failed: %v", err)
+	}
+	if !strings.HasSuffix(first.Path, "-dedupe-run.db") || first.Size == 0 || first.SchemaVersion != ExpectedMigrationCount {
+		t.Errorf("unexpected snapshot %+v", first)
+	}
+	second, err := store.CreateSnapshot(backups, "Dedupe run")
+	if err != nil || second.Path == first.Path {
+		t.Fatalf("expected a second file, got %+v, %v", second, err)
+	}
+	snapshots, err := store.ListSnapshots()
+	if err != nil || len(snapshots) != 2 || snapshots[0].ID != second.ID || snapshots[1].Path != first.Path {
+		t.Fatalf("ListSnapshots = %+v, %v", snapshots, err)
+	}
+
+	store.DeleteEntity("Go")
+	store.Close()
+	if err := RestoreSnapshot(first.Path, dbPath); err != nil {
+		t.Fatalf("RestoreSnapshot failed: %v", err)
+	}
+	if _, err := os.Stat(first.Path); err != nil {
+		t.Errorf("expected the snapshot kept: %v", err)
+	}
+	restored, err := NewStore(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer restored.Close()
+	if _, err := restored.GetEntity("Go"); err != nil {
+		t.Errorf("expected Go back after the restore: %v", err)
+	}
+
+	notDB := filepath.Join(dir, "notes.txt")
+	os.WriteFile(notDB, []byte("not a database at all"), 0o644)
+	if err := RestoreSnapshot(notDB, dbPath); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("expected a non-database refused, got %v", err)
+	}
+}
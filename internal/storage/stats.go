@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// StatCount is the number of records sharing a key.
+type StatCount struct {
+	Key   string `json:"key" db:"key"`
+	Count int    `json:"count" db:"count"`
+}
+
+// EntityDegree is an entity with the number of relations it takes part in.
+type EntityDegree struct {
+	Name      string `json:"name" db:"name"`
+	Relations int    `json:"relations" db:"relations"`
+}
+
+// DatabaseStats describes what the graph holds. Entities are counted at
+// their latest version and relations only while valid, as ReadGraph returns
+// them.
+type DatabaseStats struct {
+	Entities               int            `json:"entities"`
+	Observations           int            `json:"observations"`
+	Relations              int            `json:"relations"`
+	Embedded               int            `json:"embedded"` // Observations with an embedding
+	PII                    int            `json:"pii"`      // Observations tagged as personal data; see SetPIIMode
+	SizeBytes              int64          `json:"sizeBytes"`
+	EntitiesByType         []StatCount    `json:"entitiesByType"`
+	ObservationsByFactType []StatCount    `json:"observationsByFactType"`
+	EntitiesByContainer    []StatCount    `json:"entitiesByContainer"` // Sessions count under their project
+	PIIByKind              []StatCount    `json:"piiByKind"`
+	MostConnected          []EntityDegree `json:"mostConnected"` // Top 10
+}
+
+// containerKeySQL is an entity's container tag, or the project of a session,
+// whose tag is JSON metadata. Entities without one are left out.
+const containerKeySQL = `CASE WHEN json_valid(e.container_tag)
+	THEN json_extract(e.container_tag, '$.project') ELSE e.container_tag END`
+
+// GetDatabaseStats returns counts and breakdowns of the graph.
+func (s *Store) GetDatabaseStats() (*DatabaseStats, error) {
+	return s.GetDatabaseStatsContext(context.Background())
+}
+
+// GetDatabaseStatsContext is GetDatabaseStats with a context.
+func (s *Store) GetDatabaseStatsContext(ctx context.Context) (*DatabaseStats, error) {
+	stats := &DatabaseStats{}
+	validity, validityArgs := RelationFilter{}.sql()
+
+	counts := []struct {
+		dst   *int
+		query string
+		args  []any
+	}{
+		{&stats.Entities, "SELECT COUNT(*) FROM entities WHERE is_latest = 1 OR is_latest IS NULL", nil},
+		{&stats.Observations, `SELECT COUNT(*) FROM observations o JOIN entities e ON e.id = o.entity_id
+			WHERE e.is_latest = 1 OR e.is_latest IS NULL`, nil},
+		{&stats.Relations, "SELECT COUNT(*) FROM relations r WHERE " + validity, validityArgs},
+		{&stats.Embedded, `SELECT COUNT(*) FROM observation_embeddings oe
+			JOIN observations o ON o.id = oe.observation_id JOIN entities e ON e.id = o.entity_id
+			WHERE e.is_latest = 1 OR e.is_latest IS NULL`, nil},
+		{&stats.PII, `SELECT COUNT(*) FROM observations o JOIN entities e ON e.id = o.entity_id
+			WHERE o.pii IS NOT NULL AND (e.is_latest = 1 OR e.is_latest IS NULL)`, nil},
+	}
+	for _, c := range counts {
+		if err := s.db.GetContext(ctx, c.dst, c.query, c.args...); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.db.GetContext(ctx, &stats.SizeBytes,
+		"SELECT page_count * page_size FROM pragma_page_count(), pragma_page_size()"); err != nil {
+		return nil, err
+	}
+
+	breakdowns := []struct {
+		dst   *[]StatCount
+		query string
+	}{
+		{&stats.EntitiesByType, `SELECT e.entity_type as key, COUNT(*) as count FROM entities e
+			WHERE e.is_latest = 1 OR e.is_latest IS NULL
+			GROUP BY key ORDER BY count DESC, key`},
+		{&stats.ObservationsByFactType, `SELECT COALESCE(o.fact_type, 'dynamic') as key, COUNT(*) as count
+			FROM observations o JOIN entities e ON e.id = o.entity_id
+			WHERE e.is_latest = 1 OR e.is_latest IS NULL
+			GROUP BY key ORDER BY count DESC, key`},
+		{&stats.EntitiesByContainer, `SELECT ` + containerKeySQL + ` as key, COUNT(*) as count FROM entities e
+			WHERE (e.is_latest = 1 OR e.is_latest IS NULL) AND COALESCE(` + containerKeySQL + `, '') != ''
+			GROUP BY key ORDER BY count DESC, key`},
+		{&stats.PIIByKind, `SELECT k.key, COUNT(*) as count
+			FROM observations o JOIN entities e ON e.id = o.entity_id
+			JOIN (SELECT '` + PIIEmail + `' as key UNION ALL SELECT '` + PIIPhone + `' UNION ALL SELECT '` + PIICreditCard + `') k
+				ON ',' || o.pii || ',' LIKE '%,' || k.key || ',%'
+			WHERE e.is_latest = 1 OR e.is_latest IS NULL
+			GROUP BY k.key ORDER BY count DESC, k.key`},
+	}
+	for _, b := range breakdowns {
+		if err := s.db.SelectContext(ctx, b.dst, b.query); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.db.SelectContext(ctx, &stats.MostConnected, `
+		SELECT e.name, COUNT(*) as relations
+		FROM (
+			SELECT r.from_entity_id as id FROM relations r WHERE `+validity+`
+			UNION ALL
+			SELECT r.to_entity_id FROM relations r WHERE `+validity+`
+		) ends
+		JOIN entities e ON e.id = ends.id
+		GROUP BY e.name
+		ORDER BY relations DESC, e.name
+		LIMIT 10`, append(validityArgs, validityArgs...)...); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// GrowthWeek counts the records added in the week starting on Week, a Monday
+// (YYYY-MM-DD, UTC). Every new version of an entity counts as added.
+type GrowthWeek struct {
+	Week         string `json:"week" db:"week"`
+	Entities     int    `json:"entities" db:"entities"`
+	Observations int    `json:"observations" db:"observations"`
+	Relations    int    `json:"relations" db:"relations"`
+}
+
+// GetGrowth returns the records added per week since the given time, oldest
+// week first. Weeks in which nothing was added are left out.
+func (s *Store) GetGrowth(since time.Time) ([]GrowthWeek, error) {
+	return s.GetGrowthContext(context.Background(), since)
+}
+
+// GetGrowthContext is GetGrowth with a context.
+func (s *Store) GetGrowthContext(ctx context.Context, since time.Time) ([]GrowthWeek, error) {
+	ts := sqlTime(since)
+	var weeks []GrowthWeek
+	err := s.db.SelectContext(ctx, &weeks, `
+		SELECT date(created_at, '-6 days', 'weekday 1') as week,
+		       SUM(kind = 'e') as entities, SUM(kind = 'o') as observations, SUM(kind = 'r') as relations
+		FROM (
+			SELECT 'e' as kind, created_at FROM entities WHERE created_at >= ?
+			UNION ALL
+			SELECT 'o', created_at FROM observations WHERE created_at >= ?
+			UNION ALL
+			SELECT 'r', created_at FROM relations WHERE created_at >= ?
+		)
+		GROUP BY week
+		ORDER BY week`, ts, ts, ts)
+	return weeks, err
+}
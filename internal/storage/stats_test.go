@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetDatabaseStats(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	store.CreateEntity("konfig", "project", []string{"Loads YAML"})
+	store.CreateEntity("mark42", "project", []string{"Stores memories"})
+	store.CreateEntity("Go", "language", nil)
+	store.AddObservationWithType("Go", "Has generics", FactTypeStatic)
+	store.CreateRelation("konfig", "Go", "written_in")
+	store.CreateRelation("mark42", "Go", "written_in")
+	store.CreateRelation("mark42", "konfig", "uses")
+	store.SetContainerTag("konfig", "/work/konfig")
+	if _, err := store.CreateSession("konfig"); err != nil {
+		t.Fatal(err)
+	}
+	obs, _ := store.GetObservationsWithoutEmbeddings()
+	store.StoreEmbedding(obs[0].ID, []float64{1, 0}, "test")
+
+	stats, err := store.GetDatabaseStats()
+	if err != nil {
+		t.Fatalf("GetDatabaseStats: %v", err)
+	}
+	if stats.Entities != 4 || stats.Observations != 3 || stats.Relations != 3 || stats.Embedded != 1 {
+		t.Errorf("totals = %d entities, %d observations, %d relations, %d embedded; want 4, 3, 3, 1",
+			stats.Entities, stats.Observations, stats.Relations, stats.Embedded)
+	}
+	if stats.SizeBytes <= 0 {
+		t.Errorf("SizeBytes = %d, want > 0", stats.SizeBytes)
+	}
+
+	counts := func(c []StatCount) map[string]int {
+		m := map[string]int{}
+		for _, sc := range c {
+			m[sc.Key] = sc.Count
+		}
+		return m
+	}
+	if got := counts(stats.EntitiesByType); got["project"] != 2 || got["language"] != 1 || got["session"] != 1 {
+		t.Errorf("EntitiesByType = %v", got)
+	}
+	if got := counts(stats.ObservationsByFactType); got["dynamic"] != 2 || got["static"] != 1 {
+		t.Errorf("ObservationsByFactType = %v", got)
+	}
+	if got := counts(stats.EntitiesByContainer); got["/work/konfig"] != 1 || got["konfig"] != 1 || len(got) != 2 {
+		t.Errorf("EntitiesByContainer = %v, want the workdir and the session's project", got)
+	}
+	// Each entity takes part in two relations; ties go by name
+	if len(stats.MostConnected) != 3 || stats.MostConnected[0] != (EntityDegree{Name: "Go", Relations: 2}) {
+		t.Errorf("MostConnected = %+v", stats.MostConnected)
+	}
+}
+
+func TestGetGrowth(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	store.CreateEntity("konfig", "project", []string{"Loads YAML", "Loads TOML"})
+	store.CreateEntity("mark42", "project", nil)
+	store.CreateRelation("mark42", "konfig", "uses")
+	// Wednesday and Sunday of the week of Monday 2026-03-02, and the next Monday
+	store.DB().Exec(`UPDATE entities SET created_at = '2026-03-04 10:00:00' WHERE name = 'konfig'`)
+	store.DB().Exec(`UPDATE observations SET created_at = '2026-03-08 23:00:00'`)
+	store.DB().Exec(`UPDATE entities SET created_at = '2026-03-09 00:00:00' WHERE name = 'mark42'`)
+	store.DB().Exec(`UPDATE relations SET created_at = '2026-03-09 08:00:00'`)
+
+	weeks, err := store.GetGrowth(time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("GetGrowth: %v", err)
+	}
+	want := []GrowthWeek{
+		{Week: "2026-03-02", Entities: 1, Observations: 2},
+		{Week: "2026-03-09", Entities: 1, Relations: 1},
+	}
+	if len(weeks) != len(want) || weeks[0] != want[0] || weeks[1] != want[1] {
+		t.Errorf("GetGrowth = %+v, want %+v", weeks, want)
+	}
+
+	weeks, _ = store.GetGrowth(time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC))
+	if len(weeks) != 1 || weeks[0].Week != "2026-03-09" {
+		t.Errorf("GetGrowth since the second week = %+v", weeks)
+	}
+}
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// DefaultStopWords are common English words dropped from search queries:
+// nearly every observation contains them, so they only dilute BM25 scores.
+var DefaultStopWords = []string{
+	"a", "an", "and", "are", "as", "at", "be", "by", "for", "from", "has",
+	"how", "in", "is", "it", "of", "on", "or", "that", "the", "this", "to",
+	"was", "what", "when", "where", "which", "who", "why", "with",
+}
+
+// SetStopWords replaces the words dropped from search queries, matched
+// ignoring case. Nil or empty keeps every word. The indexes are unaffected,
+// so changing the list needs no reindex.
+func (s *Store) SetStopWords(words []string) {
+	s.stopWords = nil
+	for _, w := range words {
+		if w = strings.ToLower(strings.TrimSpace(w)); w != "" {
+			if s.stopWords == nil {
+				s.stopWords = map[string]bool{}
+			}
+			s.stopWords[w] = true
+		}
+	}
+}
+
+// StopWords returns the words dropped from search queries, sorted.
+func (s *Store) StopWords() []string {
+	return slices.Sorted(maps.Keys(s.stopWords))
+}
+
+// removeStopWords drops stop words from a search query. A query of nothing
+// but stop words is kept whole, so searching for "the" still finds it.
+func (s *Store) removeStopWords(query string) string {
+	if len(s.stopWords) == 0 {
+		return query
+	}
+	words := strings.Fields(query)
+	kept := slices.DeleteFunc(slices.Clone(words), func(w string) bool {
+		return s.stopWords[strings.ToLower(strings.TrimFunc(w, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}))]
+	})
+	if len(kept) == 0 || len(kept) == len(words) {
+		return query
+	}
+	return strings.Join(kept, " ")
+}
@@ -0,0 +1,52 @@
+package storage
+
+import "testing"
+
+func TestRemoveStopWords(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	tests := []struct {
+		query, want string
+	}{
+		{"the cache of sessions", "cache sessions"},
+		{"The Cache", "Cache"},
+		{"what is it?", "what is it?"}, // Nothing but stop words: kept whole
+		{"cache invalidation", "cache invalidation"},
+		{"(the) cache", "cache"},
+	}
+	for _, tt := range tests {
+		if got := store.removeStopWords(tt.query); got != tt.want {
+			t.Errorf("removeStopWords(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+
+	store.SetStopWords([]string{" Cache "})
+	if got := store.removeStopWords("the cache layer"); got != "the layer" {
+		t.Errorf("expected a custom list to replace the default, got %q", got)
+	}
+	if got := store.StopWords(); len(got) != 1 || got[0] != "cache" {
+		t.Errorf("StopWords = %v", got)
+	}
+}
+
+func TestSearchDropsStopWords(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	store.CreateEntity("redis", "tool", []string{"Cache for the API"})
+	store.CreateEntity("postgres", "tool", []string{"Stores the orders"})
+
+	results, err := store.Search("the cache")
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(results) != 1 || results[0].Name != "redis" {
+		t.Errorf("expected only redis, got %+v", results)
+	}
+
+	store.SetStopWords(nil)
+	if results, _ := store.Search("the cache"); len(results) != 2 {
+		t.Errorf("expected every word searched without stop words, got %+v", results)
+	}
+}
@@ -1,7 +1,13 @@
 package storage
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	_ "modernc.org/sqlite"
@@ -9,8 +15,31 @@ import (
 
 // Store manages the SQLite database for memory storage.
 type Store struct {
-	db   *sqlx.DB
-	path string
+	db         *sqlx.DB
+	path       string
+	importance ImportanceConfig
+	remote     bool      // Hosted libSQL database rather than a local file
+	pinned     *sql.Conn // Keeps an in-memory database alive; see InMemory
+	readOnly   bool      // Opened with NewStoreReadOnly
+	fts        bool      // FTS5 indexes available; see FTSEnabled
+	trigram    bool      // Trigram indexes for CJK queries available; see initTrigram
+	// Embeddings VectorSearch scores at most; see SetMaxVectorCandidates
+	maxVectorCandidates  int
+	caseInsensitiveNames bool                        // See SetCaseInsensitiveNames
+	attributeSchemas     map[string]AttributeSchema  // See SetAttributeSchemas
+	source               string                      // Default provenance; see SetSource
+	user                 string                      // Owner and author of writes; see SetUser
+	autoLinkMinName      int                         // See SetAutoLinkMentions
+	rules                Rules                       // See SetRules
+	policies             []Policy                    // See SetPolicies
+	entityTypes          map[string]string           // See SetEntityTypes
+	relationTypes        map[string]RelationTypeInfo // See SetRelationTypes
+	stopWords            map[string]bool             // See SetStopWords
+	secretPatterns       []secretPattern             // See SetRedaction
+	redactWarn           func(Redacted)
+	piiMode              PIIMode       // See SetPIIMode
+	undoWindow           time.Duration // See SetUndoWindow
+	tokenizer            Tokenizer     // See SetTokenizer
 }
 
 // DB returns the underlying sqlx.DB for direct access when needed.
@@ -18,26 +47,78 @@ func (s *Store) DB() *sqlx.DB {
 	return s.db
 }
 
+// InMemory is the path NewStore opens as a private in-memory database,
+// shared by the store's connections and gone once it closes.
+const InMemory = ":memory:"
+
+// inMemoryCount numbers in-memory databases, keeping each store's apart.
+var inMemoryCount atomic.Int64
+
 // NewStore creates a new Store, initializing the database and schema.
+// path is a SQLite file, InMemory, or a libsql:// DSN for a hosted
+// libSQL/Turso database.
 func NewStore(path string) (*Store, error) {
-	db, err := sqlx.Open("sqlite", path)
+	if IsPostgresDSN(path) {
+		return nil, ErrPostgresStore
+	}
+	// Plain :memory: gives each pooled connection, and the handle migrations
+	// open, a database of its own; the memdb VFS shares one by name within
+	// the process
+	inMemory := path == InMemory
+	if inMemory {
+		path = fmt.Sprintf("file:/mark42-%d?vfs=memdb", inMemoryCount.Add(1))
+	}
+	driver, dsn := "sqlite", sqliteDSN(path)
+	remote := IsRemoteDSN(path)
+	if remote {
+		if !slices.Contains(sql.Drivers(), libsqlDriver) {
+			return nil, ErrLibSQLUnavailable
+		}
+		driver, dsn = libsqlDriver, libsqlDSN(path)
+	}
+
+	db, err := sqlx.Open(driver, dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
+	// A memdb database lasts while a connection to it is open
+	var pinned *sql.Conn
+	if inMemory {
+		if pinned, err = db.Conn(context.Background()); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to open database: %w", err)
+		}
+	}
 
-	// Enable WAL mode for better concurrency
-	if _, err := db.Exec("PRAGMA journal_mode=WAL"); err != nil {
-		db.Close()
-		return nil, fmt.Errorf("failed to enable WAL mode: %w", err)
+	// Enable WAL mode for better concurrency; the server manages its own journal
+	if !remote {
+		if _, err := db.Exec("PRAGMA journal_mode=WAL"); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to enable WAL mode: %w", err)
+		}
 	}
 
-	// Enable foreign keys
+	// Local connections enable foreign keys through the DSN; this covers the
+	// remote connection and fails early if the pragma is not accepted
 	if _, err := db.Exec("PRAGMA foreign_keys=ON"); err != nil {
 		db.Close()
 		return nil, fmt.Errorf("failed to enable foreign keys: %w", err)
 	}
 
-	store := &Store{db: db, path: path}
+	store := &Store{
+		db:                  db,
+		path:                path,
+		importance:          DefaultImportanceConfig(),
+		remote:              remote,
+		pinned:              pinned,
+		fts:                 true,
+		maxVectorCandidates: DefaultMaxVectorCandidates,
+		attributeSchemas:    DefaultAttributeSchemas(),
+		secretPatterns:      defaultSecretPatterns,
+		undoWindow:          DefaultUndoWindow,
+		tokenizer:           DefaultTokenizer(),
+	}
+	store.SetStopWords(DefaultStopWords)
 
 	if err := store.initSchema(); err != nil {
 		db.Close()
@@ -47,11 +128,49 @@ func NewStore(path string) (*Store, error) {
 	return store, nil
 }
 
+// sqliteDSN enables foreign keys on every pooled connection. The pragma is
+// per connection, so running it once with Exec only reaches one of them and
+// ON DELETE CASCADE silently does nothing on the others.
+func sqliteDSN(path string) string {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	return path + sep + "_pragma=foreign_keys(1)"
+}
+
 // Close closes the database connection.
 func (s *Store) Close() error {
+	if s.pinned != nil {
+		s.pinned.Close()
+	}
 	return s.db.Close()
 }
 
+// Checkpoint copies the write-ahead log into the database file and empties
+// it, so a process exiting leaves every committed write in the file itself.
+// Remote and read-only stores have no log to flush.
+func (s *Store) Checkpoint() error {
+	return s.CheckpointContext(context.Background())
+}
+
+// CheckpointContext is Checkpoint with a context.
+func (s *Store) CheckpointContext(ctx context.Context) error {
+	if s.remote || s.readOnly {
+		return nil
+	}
+	// wal_checkpoint reports a checkpoint another connection blocked in its
+	// first column rather than as an error
+	var busy, logFrames, checkpointed int
+	if err := s.db.QueryRowContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)").Scan(&busy, &logFrames, &checkpointed); err != nil {
+		return fmt.Errorf("failed to checkpoint WAL: %w", err)
+	}
+	if busy != 0 {
+		return fmt.Errorf("failed to checkpoint WAL: database is busy")
+	}
+	return nil
+}
+
 // ListTables returns all table names in the database.
 func (s *Store) ListTables() []string {
 	rows, err := s.db.Query(`
@@ -87,7 +206,9 @@ func (s *Store) initSchema() error {
 		is_latest BOOLEAN DEFAULT 1,
 		version INTEGER DEFAULT 1,
 		-- Multi-project scoping (Phase 2)
-		container_tag TEXT
+		container_tag TEXT,
+		-- User who wrote this version, on shared databases
+		owner TEXT
 	);
 
 	CREATE INDEX IF NOT EXISTS idx_entities_name ON entities(name);
@@ -107,6 +228,25 @@ func (s *Store) initSchema() error {
 		importance REAL DEFAULT 1.0,
 		forget_after TIMESTAMP,
 		last_accessed TIMESTAMP,
+		-- Usage feedback: how often a memory was reported useful, and when
+		access_count INTEGER DEFAULT 0,
+		last_useful TIMESTAMP,
+		-- Pinned memories skip decay and always lead the injected context
+		pinned INTEGER DEFAULT 0,
+		-- Suppressed memories are hidden from search and context but kept for history
+		suppressed INTEGER DEFAULT 0,
+		-- Hash of the observations a cached LLM summary was generated from
+		summary_source TEXT,
+		-- Provenance: how the observation entered the system (mcp:<tool>, cli, hook:<name>, ...)
+		source TEXT,
+		-- User who wrote the observation, on shared databases
+		author TEXT,
+		-- SHA-256 of content, to find the same text under several entities
+		content_hash TEXT,
+		-- The observation this one is a linked copy of; see LinkDuplicateObservations
+		canonical_id INTEGER REFERENCES observations(id) ON DELETE SET NULL,
+		-- Kinds of probable personal data found when written in PII warn mode
+		pii TEXT,
 		UNIQUE(entity_id, content)
 	);
 
@@ -124,6 +264,71 @@ func (s *Store) initSchema() error {
 
 	CREATE INDEX IF NOT EXISTS idx_embeddings_model ON observation_embeddings(model);
 
+	-- Per-database tuning, e.g. search.vectorWeight
+	CREATE TABLE IF NOT EXISTS settings (
+		key TEXT PRIMARY KEY,
+		value TEXT NOT NULL,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	-- Context injections and the memories they selected, when context.log is on
+	CREATE TABLE IF NOT EXISTS context_log (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		source TEXT NOT NULL,
+		project TEXT NOT NULL DEFAULT '',
+		user_name TEXT NOT NULL DEFAULT '',
+		token_budget INTEGER NOT NULL,
+		tokens_used INTEGER NOT NULL,
+		min_importance REAL NOT NULL,
+		results INTEGER NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_context_log_created ON context_log(created_at);
+
+	CREATE TABLE IF NOT EXISTS context_log_items (
+		call_id INTEGER NOT NULL REFERENCES context_log(id) ON DELETE CASCADE,
+		position INTEGER NOT NULL,
+		observation_id INTEGER NOT NULL,
+		entity_name TEXT NOT NULL,
+		fact_type TEXT,
+		importance REAL,
+		score REAL,
+		PRIMARY KEY (call_id, position)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_context_log_items_observation ON context_log_items(observation_id);
+
+	-- MCP tool calls, errors, and latencies per tool and latency bucket;
+	-- le_ms is the bucket's upper bound, 0 past the last
+	CREATE TABLE IF NOT EXISTS tool_stats (
+		tool TEXT NOT NULL,
+		le_ms INTEGER NOT NULL,
+		calls INTEGER NOT NULL DEFAULT 0,
+		errors INTEGER NOT NULL DEFAULT 0,
+		total_ms REAL NOT NULL DEFAULT 0,
+		max_ms REAL NOT NULL DEFAULT 0,
+		last_called TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (tool, le_ms)
+	);
+
+	-- Advisory write lease, one row at most; times are Unix milliseconds
+	CREATE TABLE IF NOT EXISTS write_lease (
+		id INTEGER PRIMARY KEY CHECK (id = 1),
+		holder TEXT NOT NULL,
+		pid INTEGER NOT NULL,
+		acquired_at INTEGER NOT NULL,
+		expires_at INTEGER NOT NULL
+	);
+
+	-- Dimensions and input limit of each embedding model used
+	CREATE TABLE IF NOT EXISTS embedding_models (
+		model TEXT PRIMARY KEY,
+		dimensions INTEGER NOT NULL,
+		max_input_tokens INTEGER NOT NULL DEFAULT 0,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
 	-- Relations between entities
 	CREATE TABLE IF NOT EXISTS relations (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -131,20 +336,83 @@ func (s *Store) initSchema() error {
 		to_entity_id INTEGER NOT NULL REFERENCES entities(id) ON DELETE CASCADE,
 		relation_type TEXT NOT NULL,
 		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		-- Validity window: NULL valid_from means since creation, NULL valid_to means still valid
+		valid_from TIMESTAMP,
+		valid_to TIMESTAMP,
 		UNIQUE(from_entity_id, to_entity_id, relation_type)
 	);
 
 	CREATE INDEX IF NOT EXISTS idx_relations_from ON relations(from_entity_id);
 	CREATE INDEX IF NOT EXISTS idx_relations_to ON relations(to_entity_id);
+
+	-- Typed key-value attributes, validated against per-type schemas
+	CREATE TABLE IF NOT EXISTS entity_attributes (
+		entity_id INTEGER NOT NULL REFERENCES entities(id) ON DELETE CASCADE,
+		key TEXT NOT NULL,
+		value TEXT NOT NULL,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (entity_id, key)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_entity_attributes_key ON entity_attributes(key, value);
+
+	-- Entity merges, with a snapshot of the merged entity for undo
+	CREATE TABLE IF NOT EXISTS entity_merges (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		keep TEXT NOT NULL,
+		merged TEXT NOT NULL,
+		snapshot TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		undone_at TIMESTAMP
+	);
+
+	-- Other names entities go by
+	CREATE TABLE IF NOT EXISTS entity_aliases (
+		alias TEXT PRIMARY KEY,
+		entity_name TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_entity_aliases_entity ON entity_aliases(entity_name);
+
+	-- Rows destructive operations removed, per entity, for undo
+	CREATE TABLE IF NOT EXISTS trash (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		op INTEGER NOT NULL,
+		operation TEXT NOT NULL,
+		entity TEXT NOT NULL,
+		snapshot TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		undone_at TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_trash_op ON trash(op);
+
+	-- Copies of the database taken before risky operations
+	CREATE TABLE IF NOT EXISTS snapshots (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		path TEXT NOT NULL,
+		reason TEXT NOT NULL,
+		schema_version INTEGER NOT NULL,
+		size_bytes INTEGER NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
 	`
 
 	if _, err := s.db.Exec(schema); err != nil {
 		return fmt.Errorf("failed to create base schema: %w", err)
 	}
 
-	// Create FTS5 virtual tables separately (they can't use IF NOT EXISTS)
+	// Create FTS5 virtual tables separately (they can't use IF NOT EXISTS).
+	// Hosted servers may not ship FTS5; search falls back to substring matching.
 	if err := s.initFTS(); err != nil {
-		return err
+		if !s.remote {
+			return err
+		}
+		s.fts = false
+	}
+	if s.fts {
+		s.initTrigram()
 	}
 
 	return nil
@@ -193,7 +461,7 @@ func (s *Store) initFTS() error {
 		VALUES('delete', old.id, old.content);
 	END;
 
-	CREATE TRIGGER observations_au AFTER UPDATE ON observations BEGIN
+	CREATE TRIGGER observations_au AFTER UPDATE OF content ON observations BEGIN
 		INSERT INTO observations_fts(observations_fts, rowid, content)
 		VALUES('delete', old.id, old.content);
 		INSERT INTO observations_fts(rowid, content) VALUES (new.id, new.content);
@@ -210,7 +478,7 @@ func (s *Store) initFTS() error {
 		VALUES('delete', old.id, old.name, old.entity_type);
 	END;
 
-	CREATE TRIGGER entities_au AFTER UPDATE ON entities BEGIN
+	CREATE TRIGGER entities_au AFTER UPDATE OF name, entity_type ON entities BEGIN
 		INSERT INTO entities_fts(entities_fts, rowid, name, entity_type)
 		VALUES('delete', old.id, old.name, old.entity_type);
 		INSERT INTO entities_fts(rowid, name, entity_type)
@@ -63,6 +63,29 @@ func TestStore_Close(t *testing.T) {
 	}
 }
 
+func TestStore_Checkpoint(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	store, err := storage.NewStore(dbPath)
+	if err != nil {
+		t.Fatalf("NewStore failed: %v", err)
+	}
+	defer store.Close()
+
+	if _, err := store.CreateEntity("Go", "language", []string{"Fast compiler"}); err != nil {
+		t.Fatalf("CreateEntity failed: %v", err)
+	}
+	if info, err := os.Stat(dbPath + "-wal"); err != nil || info.Size() == 0 {
+		t.Fatalf("expected the write in the WAL first, got %v", err)
+	}
+
+	if err := store.Checkpoint(); err != nil {
+		t.Fatalf("Checkpoint failed: %v", err)
+	}
+	if info, err := os.Stat(dbPath + "-wal"); err == nil && info.Size() != 0 {
+		t.Errorf("expected an empty WAL after the checkpoint, got %d bytes", info.Size())
+	}
+}
+
 // Helper to create a test store
 func newTestStore(t *testing.T) *storage.Store {
 	t.Helper()
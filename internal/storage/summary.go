@@ -0,0 +1,234 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// SummaryClient generates session summaries with a local LLM.
+// Uses the OpenAI-compatible chat completions API (Ollama, DMR).
+type SummaryClient struct {
+	baseURL    string
+	httpClient *http.Client
+	model      string
+}
+
+// NewSummaryClient creates a summary client for the given base URL and model.
+func NewSummaryClient(baseURL, model string) *SummaryClient {
+	return &SummaryClient{
+		baseURL:    baseURL,
+		httpClient: &http.Client{},
+		model:      model,
+	}
+}
+
+const summaryPrompt = "Summarize this coding session in 2-3 sentences for a future session. " +
+	"Focus on what was changed, decisions made, and anything left unfinished. Reply with the summary only."
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatRequest struct {
+	Model    string        `json:"model"`
+	Messages []chatMessage `json:"messages"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+const entitySummaryPrompt = "Summarize what is known about this entity as 3 to 5 short Markdown bullets " +
+	"(lines starting with \"- \"), most important first. Merge overlapping facts and drop trivia. " +
+	"Reply with the bullets only."
+
+// Summarize condenses session notes (tracked activity, transcript digest) into a short summary.
+func (c *SummaryClient) Summarize(ctx context.Context, notes string) (string, error) {
+	if notes == "" {
+		return "", errors.New("empty session notes")
+	}
+	return c.complete(ctx, summaryPrompt, notes)
+}
+
+// SummarizeEntity condenses an entity's observations into a 3-5 bullet abstract.
+func (c *SummaryClient) SummarizeEntity(ctx context.Context, name, entityType string, observations []string) (string, error) {
+	if len(observations) == 0 {
+		return "", errors.New("no observations to summarize")
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Entity: %s (%s)\nObservations:\n", name, entityType)
+	for _, obs := range observations {
+		sb.WriteString("- " + obs + "\n")
+	}
+	return c.complete(ctx, entitySummaryPrompt, sb.String())
+}
+
+// complete sends one system and one user message and returns the reply.
+func (c *SummaryClient) complete(ctx context.Context, system, user string) (string, error) {
+	jsonBody, err := json.Marshal(chatRequest{
+		Model: c.model,
+		Messages: []chatMessage{
+			{Role: "system", Content: system},
+			{Role: "user", Content: user},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("marshaling request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/chat/completions", bytes.NewReader(jsonBody))
+	if err != nil {
+		return "", fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var chatResp chatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+		return "", fmt.Errorf("decoding response: %w", err)
+	}
+	if len(chatResp.Choices) == 0 {
+		return "", errors.New("no summary returned")
+	}
+
+	summary := strings.TrimSpace(chatResp.Choices[0].Message.Content)
+	if summary == "" {
+		return "", errors.New("no summary returned")
+	}
+	return summary, nil
+}
+
+// EntitySummary is an entity's cached LLM summary.
+type EntitySummary struct {
+	Content string
+	Stale   bool // The entity's observations changed since it was generated
+}
+
+// GetEntitySummary returns the entity's cached summary, or nil if it has none.
+func (s *Store) GetEntitySummary(name string) (*EntitySummary, error) {
+	return s.GetEntitySummaryContext(context.Background(), name)
+}
+
+// GetEntitySummaryContext is GetEntitySummary with a context.
+func (s *Store) GetEntitySummaryContext(ctx context.Context, name string) (*EntitySummary, error) {
+	id, err := s.entityID(ctx, s.db, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var row struct {
+		Content string         `db:"content"`
+		Source  sql.NullString `db:"summary_source"`
+	}
+	err = s.db.GetContext(ctx, &row, `
+		SELECT content, summary_source FROM observations
+		WHERE entity_id = ? AND fact_type = ?
+		ORDER BY id DESC LIMIT 1`, id, string(FactTypeSummary))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sources, err := s.summarySources(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &EntitySummary{Content: row.Content, Stale: row.Source.String != summarySourceHash(sources)}, nil
+}
+
+// EntitySummarySources returns the observations an entity's summary is made
+// from: all but suppressed ones and the summary itself, oldest first.
+func (s *Store) EntitySummarySources(name string) ([]string, error) {
+	return s.EntitySummarySourcesContext(context.Background(), name)
+}
+
+// EntitySummarySourcesContext is EntitySummarySources with a context.
+func (s *Store) EntitySummarySourcesContext(ctx context.Context, name string) ([]string, error) {
+	id, err := s.entityID(ctx, s.db, name)
+	if err != nil {
+		return nil, err
+	}
+	return s.summarySources(ctx, id)
+}
+
+func (s *Store) summarySources(ctx context.Context, entityID int64) ([]string, error) {
+	var sources []string
+	err := s.db.SelectContext(ctx, &sources, `
+		SELECT content FROM observations
+		WHERE entity_id = ? AND COALESCE(fact_type, '') != ? AND COALESCE(suppressed, 0) = 0
+		ORDER BY id`, entityID, string(FactTypeSummary))
+	return sources, err
+}
+
+// summarySourceHash fingerprints a summary's sources, ignoring their order.
+func summarySourceHash(sources []string) string {
+	sorted := append([]string(nil), sources...)
+	sort.Strings(sorted)
+	h := sha256.New()
+	for _, src := range sorted {
+		h.Write([]byte(src))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// SaveEntitySummary caches content as the entity's summary, replacing any
+// previous one. sources are the observations it was generated from, as
+// returned by EntitySummarySources; the summary reads as stale once they change.
+func (s *Store) SaveEntitySummary(name, content string, sources []string) error {
+	return s.SaveEntitySummaryContext(context.Background(), name, content, sources)
+}
+
+// SaveEntitySummaryContext is SaveEntitySummary with a context.
+func (s *Store) SaveEntitySummaryContext(ctx context.Context, name, content string, sources []string) error {
+	content = s.redact(ctx, name, content)
+	if err := ValidateObservation(content); err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	id, err := s.entityID(ctx, tx, name)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM observations WHERE entity_id = ? AND fact_type = ?",
+		id, string(FactTypeSummary)); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO observations (entity_id, content, content_hash, fact_type, summary_source, source, author) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		id, content, contentHash(content), string(FactTypeSummary), summarySourceHash(sources), s.sourceFor(ctx), s.userValue()); err != nil {
+		return fmt.Errorf("failed to save summary: %w", err)
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSummaryClient_Summarize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" {
+			t.Errorf("expected path /chat/completions, got %s", r.URL.Path)
+		}
+
+		var req chatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("invalid request body: %v", err)
+		}
+		if req.Model != "llama3.2" {
+			t.Errorf("expected model llama3.2, got %s", req.Model)
+		}
+		if len(req.Messages) != 2 || !strings.Contains(req.Messages[1].Content, "main.go") {
+			t.Errorf("expected notes in user message, got %+v", req.Messages)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "  Refactored main.go.\n"}}]}`))
+	}))
+	defer server.Close()
+
+	client := NewSummaryClient(server.URL, "llama3.2")
+	summary, err := client.Summarize(context.Background(), "Modified 1 files: main.go")
+	if err != nil {
+		t.Fatalf("Summarize failed: %v", err)
+	}
+	if summary != "Refactored main.go." {
+		t.Errorf("unexpected summary: %q", summary)
+	}
+}
+
+func TestSummaryClient_Errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"choices": []}`))
+	}))
+	defer server.Close()
+
+	client := NewSummaryClient(server.URL, "llama3.2")
+	if _, err := client.Summarize(context.Background(), ""); err == nil {
+		t.Error("expected error for empty notes")
+	}
+	if _, err := client.Summarize(context.Background(), "notes"); err == nil {
+		t.Error("expected error when no choices are returned")
+	}
+}
+
+func TestSummaryClient_SummarizeEntity(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req chatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("invalid request body: %v", err)
+		}
+		if len(req.Messages) != 2 || !strings.Contains(req.Messages[0].Content, "bullets") ||
+			!strings.Contains(req.Messages[1].Content, "Go (language)\nObservations:\n- Fast compiler\n") {
+			t.Errorf("unexpected messages: %+v", req.Messages)
+		}
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "- Compiles fast\n"}}]}`))
+	}))
+	defer server.Close()
+
+	client := NewSummaryClient(server.URL, "llama3.2")
+	summary, err := client.SummarizeEntity(context.Background(), "Go", "language", []string{"Fast compiler"})
+	if err != nil {
+		t.Fatalf("SummarizeEntity failed: %v", err)
+	}
+	if summary != "- Compiles fast" {
+		t.Errorf("unexpected summary: %q", summary)
+	}
+	if _, err := client.SummarizeEntity(context.Background(), "Go", "language", nil); err == nil {
+		t.Error("expected error without observations")
+	}
+}
+
+func TestEntitySummaryCache(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	store.CreateEntity("Go", "language", []string{"Fast compiler", "Has generics"})
+	if summary, err := store.GetEntitySummary("Go"); err != nil || summary != nil {
+		t.Fatalf("expected no summary yet, got %+v, %v", summary, err)
+	}
+
+	sources, err := store.EntitySummarySources("Go")
+	if err != nil {
+		t.Fatalf("EntitySummarySources failed: %v", err)
+	}
+	if err := store.SaveEntitySummary("Go", "- Fast, generic", sources); err != nil {
+		t.Fatalf("SaveEntitySummary failed: %v", err)
+	}
+	summary, _ := store.GetEntitySummary("Go")
+	if summary == nil || summary.Content != "- Fast, generic" || summary.Stale {
+		t.Fatalf("expected a current summary, got %+v", summary)
+	}
+	if again, _ := store.EntitySummarySources("Go"); len(again) != 2 {
+		t.Errorf("expected the summary excluded from its own sources, got %q", again)
+	}
+
+	store.SetObservationSuppressed("Go", "Has generics", true)
+	if summary, _ := store.GetEntitySummary("Go"); !summary.Stale {
+		t.Error("expected suppressing a source to make the summary stale")
+	}
+	store.SetObservationSuppressed("Go", "Has generics", false)
+	if summary, _ := store.GetEntitySummary("Go"); summary.Stale {
+		t.Error("expected the summary current again")
+	}
+
+	store.DeleteObservation("Go", "Fast compiler")
+	if summary, _ := store.GetEntitySummary("Go"); !summary.Stale {
+		t.Error("expected deleting a source to make the summary stale")
+	}
+
+	sources, _ = store.EntitySummarySources("Go")
+	store.SaveEntitySummary("Go", "- Generic", sources)
+	var count int
+	store.db.Get(&count, "SELECT COUNT(*) FROM observations WHERE fact_type = 'summary'")
+	if count != 1 {
+		t.Errorf("expected saving to replace the summary, got %d", count)
+	}
+	if _, err := store.GetEntitySummary("Missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Replica and sync record kinds. The sync file holds a store's replica
+// export, so machines sharing it merge per record by UID and clock.
+const (
+	SyncEntity      = "entity"
+	SyncObservation = "observation"
+	SyncRelation    = "relation"
+)
+
+// kindOrder sorts entities before their observations and relations.
+var kindOrder = map[string]int{SyncEntity: 0, SyncObservation: 1, SyncRelation: 2}
+
+// ReadSyncFile reads the replica records of an NDJSON sync file. A missing
+// file reads as empty. Records without a UID, written before the sync file
+// held replica records, are skipped; the machine that wrote them exports
+// them again on its next sync.
+func ReadSyncFile(path string) ([]ReplicaRecord, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var records []ReplicaRecord
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var rec ReplicaRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		if rec.UID != "" {
+			records = append(records, rec)
+		}
+	}
+	return records, scanner.Err()
+}
+
+// WriteSyncFile writes records as canonical NDJSON: sorted like ExportReplica
+// sorts them, one per line, without embeddings.
+func WriteSyncFile(path string, records []ReplicaRecord) error {
+	sortReplicaRecords(records)
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	for _, rec := range records {
+		rec.Embedding = nil
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
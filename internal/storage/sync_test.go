@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSyncFile_ReadWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "memory.ndjson")
+
+	missing, err := ReadSyncFile(path)
+	if err != nil || missing != nil {
+		t.Fatalf("missing file: got %v, %v", missing, err)
+	}
+
+	records := []ReplicaRecord{
+		{UID: "b", Kind: SyncObservation, Clock: 2, Origin: "n1", EntityUID: "a", Entity: "Go", Content: "<fast> & simple", FactType: "static",
+			Embedding: &ReplicaEmbedding{Model: "m", Dims: 1, Vector: make([]byte, 8)}},
+		{UID: "a", Kind: SyncEntity, Clock: 1, Origin: "n1", Name: "Go", EntityType: "language"},
+	}
+	if err := WriteSyncFile(path, records); err != nil {
+		t.Fatalf("WriteSyncFile failed: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	want := `{"uid":"a","kind":"entity","clock":1,"origin":"n1","name":"Go","entityType":"language"}` + "\n" +
+		`{"uid":"b","kind":"observation","clock":2,"origin":"n1","entityUid":"a","entity":"Go","content":"<fast> & simple","factType":"static"}` + "\n"
+	if string(data) != want {
+		t.Errorf("file contents:\n%s\nwant:\n%s", data, want)
+	}
+
+	// Records from before the file held replica records have no UID
+	legacy := `{"kind":"entity","entity":"Rust","entityType":"language"}` + "\n"
+	if err := os.WriteFile(path, append(data, legacy...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadSyncFile(path)
+	if err != nil {
+		t.Fatalf("ReadSyncFile failed: %v", err)
+	}
+	if len(got) != 2 || got[0].UID != "a" || got[1].Content != "<fast> & simple" || got[1].Embedding != nil {
+		t.Errorf("round trip = %+v", got)
+	}
+}
+
+func TestSyncFile_Tombstones(t *testing.T) {
+	desktop := newTestStoreWithMigrations(t)
+	defer desktop.Close()
+	laptop := newTestStoreWithMigrations(t)
+	defer laptop.Close()
+
+	desktop.CreateEntity("Go", "language", []string{"Fast compiler", "Stale fact"})
+	desktop.CreateEntity("Docker", "tool", []string{"Used for CI"})
+	desktop.CreateRelation("Docker", "Go", "builds")
+	mergeInto(t, laptop, desktop)
+
+	desktop.DeleteObservation("Go", "Stale fact")
+	desktop.DeleteRelation("Docker", "Go", "builds")
+	desktop.DeleteEntity("Docker")
+
+	path := filepath.Join(t.TempDir(), "memory.ndjson")
+	records, err := desktop.ExportReplica()
+	if err != nil {
+		t.Fatalf("ExportReplica failed: %v", err)
+	}
+	if err := WriteSyncFile(path, records); err != nil {
+		t.Fatalf("WriteSyncFile failed: %v", err)
+	}
+	records, err = ReadSyncFile(path)
+	if err != nil {
+		t.Fatalf("ReadSyncFile failed: %v", err)
+	}
+
+	i := slices.IndexFunc(records, func(r ReplicaRecord) bool { return r.Deleted && r.ContentHash == contentHash("Stale fact") })
+	if i < 0 {
+		t.Fatalf("expected a tombstone naming the observation by hash, got %+v", records)
+	}
+	stale := records[i]
+	if stale.Content != "" || stale.Entity != "Go" || stale.Origin == "" {
+		t.Errorf("expected the tombstone to name the entity but not the content, got %+v", stale)
+	}
+	if _, err := time.Parse(time.RFC3339, stale.DeletedAt); err != nil {
+		t.Errorf("expected the deletion's time, got %+v", stale)
+	}
+	if !slices.ContainsFunc(records, func(r ReplicaRecord) bool {
+		return r.Deleted && r.Kind == SyncRelation && r.From == "Docker" && r.To == "Go" && r.RelationType == "builds"
+	}) {
+		t.Errorf("expected a tombstone naming the relation, got %+v", records)
+	}
+
+	stats, err := laptop.MergeReplica(records)
+	if err != nil {
+		t.Fatalf("MergeReplica failed: %v", err)
+	}
+	if stats.Deleted != 4 { // Docker's observation goes with it
+		t.Errorf("stats = %+v, want 4 deleted", stats)
+	}
+	graph, _ := laptop.ReadGraph()
+	if len(graph.Entities) != 1 || !slices.Equal(graph.Entities[0].Observations, []string{"Fast compiler"}) || len(graph.Relations) != 0 {
+		t.Errorf("expected only Go with its live observation left, got %+v", graph.Entities)
+	}
+
+	// The laptop passes the deletion on as it was made
+	again, _ := laptop.ExportReplica()
+	j := slices.IndexFunc(again, func(r ReplicaRecord) bool { return r.UID == stale.UID })
+	if j < 0 || !again[j].Deleted || again[j].DeletedAt != stale.DeletedAt || again[j].Clock != stale.Clock {
+		t.Errorf("expected the laptop to keep the deletion's version and time, got %+v", again)
+	}
+}
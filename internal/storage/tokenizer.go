@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkoukk/tiktoken-go"
+	tiktokenloader "github.com/pkoukk/tiktoken-go-loader"
+)
+
+// Tokenizer counts the tokens a piece of text occupies in a model's context window.
+type Tokenizer interface {
+	Name() string
+	CountTokens(text string) int
+}
+
+// Tokenizer encodings.
+const (
+	EncodingChars      = "chars"       // legacy heuristic: 4 characters ≈ 1 token
+	EncodingCL100KBase = "cl100k_base" // GPT-4 / GPT-3.5 family; closest public match for Claude models
+	EncodingO200KBase  = "o200k_base"  // GPT-4o / o-series family
+)
+
+// DefaultTokenizerModel is the model whose tokenizer is used when none is configured.
+const DefaultTokenizerModel = "claude"
+
+// contextEntryOverhead is the per-entry token cost of formatting ("## ", type, bullet, newlines).
+const contextEntryOverhead = 5
+
+// useEmbeddedRanks points tiktoken at the BPE rank files compiled into the
+// binary, so counting never downloads them.
+var useEmbeddedRanks = sync.OnceFunc(func() {
+	tiktoken.SetBpeLoader(tiktokenloader.NewOfflineLoader())
+})
+
+// bpeTokenizer counts tokens with tiktoken's byte-pair encoding and the
+// encoding's merge ranks. The ranks take a moment to load, so they load on
+// first use; a tokenizer that fails to load counts with the chars heuristic.
+type bpeTokenizer struct {
+	name string
+	load func() (*tiktoken.Tiktoken, error)
+}
+
+func newBPETokenizer(name string) bpeTokenizer {
+	return bpeTokenizer{name: name, load: sync.OnceValues(func() (*tiktoken.Tiktoken, error) {
+		useEmbeddedRanks()
+		return tiktoken.GetEncoding(name)
+	})}
+}
+
+func (t bpeTokenizer) Name() string { return t.name }
+
+func (t bpeTokenizer) CountTokens(text string) int {
+	if text == "" {
+		return 0
+	}
+	enc, err := t.load()
+	if err != nil {
+		return charsTokenizer{}.CountTokens(text)
+	}
+	// Special tokens such as <|endoftext|> count as the plain text they are.
+	return len(enc.Encode(text, nil, nil))
+}
+
+// charsTokenizer is the original 4-characters-per-token heuristic.
+type charsTokenizer struct{}
+
+func (charsTokenizer) Name() string { return EncodingChars }
+
+func (charsTokenizer) CountTokens(text string) int { return len(text) / 4 }
+
+var tokenizers = map[string]Tokenizer{
+	EncodingChars:      charsTokenizer{},
+	EncodingCL100KBase: newBPETokenizer(EncodingCL100KBase),
+	EncodingO200KBase:  newBPETokenizer(EncodingO200KBase),
+}
+
+// modelEncodings maps model name prefixes to encodings, longest prefix wins.
+var modelEncodings = map[string]string{
+	"claude":         EncodingCL100KBase,
+	"gpt-4":          EncodingCL100KBase,
+	"gpt-3.5":        EncodingCL100KBase,
+	"text-embedding": EncodingCL100KBase,
+	"gpt-4o":         EncodingO200KBase,
+	"gpt-4.1":        EncodingO200KBase,
+	"gpt-5":          EncodingO200KBase,
+	"o1":             EncodingO200KBase,
+	"o3":             EncodingO200KBase,
+	"o4":             EncodingO200KBase,
+}
+
+// TokenizerForModel returns the tokenizer for a model or encoding name.
+// Model names are matched by prefix (e.g. "gpt-4o-mini" uses o200k_base).
+func TokenizerForModel(model string) (Tokenizer, error) {
+	key := strings.ToLower(strings.TrimSpace(model))
+	if key == "" {
+		key = DefaultTokenizerModel
+	}
+	if t, ok := tokenizers[key]; ok {
+		return t, nil
+	}
+
+	best := ""
+	for prefix := range modelEncodings {
+		if strings.HasPrefix(key, prefix) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	if best == "" {
+		return nil, fmt.Errorf("unknown tokenizer model %q (encodings: %s)",
+			model, strings.Join(TokenizerEncodings(), ", "))
+	}
+	return tokenizers[modelEncodings[best]], nil
+}
+
+// DefaultTokenizer returns the tokenizer for DefaultTokenizerModel.
+func DefaultTokenizer() Tokenizer {
+	return tokenizers[modelEncodings[DefaultTokenizerModel]]
+}
+
+// TokenizerEncodings returns the names of the available encodings, sorted.
+func TokenizerEncodings() []string {
+	names := make([]string, 0, len(tokenizers))
+	for name := range tokenizers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetTokenizer selects the tokenizer context budgets are counted with.
+// A nil tokenizer restores DefaultTokenizer.
+func (s *Store) SetTokenizer(t Tokenizer) {
+	if t == nil {
+		t = DefaultTokenizer()
+	}
+	s.tokenizer = t
+}
+
+// Tokenizer returns the tokenizer context budgets are counted with.
+func (s *Store) Tokenizer() Tokenizer {
+	return s.tokenizer
+}
+
+// CountTokens counts the tokens in text with the store's tokenizer.
+func (s *Store) CountTokens(text string) int {
+	return s.tokenizer.CountTokens(text)
+}
+
+// contextEntryTokens returns the budget cost of one context result including formatting.
+func contextEntryTokens(tok Tokenizer, r ContextResult) int {
+	return tok.CountTokens(r.EntityName) + tok.CountTokens(r.Content) + contextEntryOverhead
+}
@@ -0,0 +1,94 @@
+package storage_test
+
+import (
+	"testing"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+func TestTokenizerForModel(t *testing.T) {
+	tests := []struct {
+		model   string
+		want    string
+		wantErr bool
+	}{
+		{"", storage.EncodingCL100KBase, false},
+		{"claude", storage.EncodingCL100KBase, false},
+		{"gpt-4-turbo", storage.EncodingCL100KBase, false},
+		{"gpt-4o-mini", storage.EncodingO200KBase, false},
+		{"O200K_BASE", storage.EncodingO200KBase, false},
+		{"chars", storage.EncodingChars, false},
+		{"llama-3", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			tok, err := storage.TokenizerForModel(tt.model)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error for %q", tt.model)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("TokenizerForModel failed: %v", err)
+			}
+			if tok.Name() != tt.want {
+				t.Errorf("TokenizerForModel(%q) = %s, want %s", tt.model, tok.Name(), tt.want)
+			}
+		})
+	}
+}
+
+func TestBPETokenizer_CountTokens(t *testing.T) {
+	// Reference counts from OpenAI's tiktoken for each encoding.
+	tests := []struct {
+		text          string
+		cl100k, o200k int
+	}{
+		{"", 0, 0},
+		{"Hello world!", 3, 3},
+		{"tiktoken is great!", 6, 6},
+		{"antidisestablishmentarianism", 6, 6},
+		{"We don't use globals; they're hard to test.", 12, 10},
+		{"Port 8080 is 12345 ms away", 10, 10},
+		{"func main() {\n\tfmt.Println(\"hi\")\n}", 10, 10},
+		{"記憶システム", 8, 6},
+		{"<|endoftext|>", 7, 7}, // Special tokens count as plain text
+	}
+
+	cl100k, _ := storage.TokenizerForModel(storage.EncodingCL100KBase)
+	o200k, _ := storage.TokenizerForModel(storage.EncodingO200KBase)
+	for _, tt := range tests {
+		if got := cl100k.CountTokens(tt.text); got != tt.cl100k {
+			t.Errorf("cl100k_base CountTokens(%q) = %d, want %d", tt.text, got, tt.cl100k)
+		}
+		if got := o200k.CountTokens(tt.text); got != tt.o200k {
+			t.Errorf("o200k_base CountTokens(%q) = %d, want %d", tt.text, got, tt.o200k)
+		}
+	}
+}
+
+func TestStore_SetTokenizer(t *testing.T) {
+	store, err := storage.NewStore(storage.InMemory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	if got := store.Tokenizer().Name(); got != storage.EncodingCL100KBase {
+		t.Errorf("default tokenizer = %s, want %s", got, storage.EncodingCL100KBase)
+	}
+	chars, _ := storage.TokenizerForModel("chars")
+	store.SetTokenizer(chars)
+	if got := store.CountTokens("12345678"); got != 2 {
+		t.Errorf("CountTokens with chars tokenizer = %d, want 2", got)
+	}
+	if got := storage.EstimateTokens("12345678"); got != 3 {
+		t.Errorf("expected EstimateTokens unaffected by a store's tokenizer, got %d", got)
+	}
+	store.SetTokenizer(nil)
+	if got := store.Tokenizer().Name(); got != storage.EncodingCL100KBase {
+		t.Errorf("SetTokenizer(nil) left %s, want the default", got)
+	}
+}
@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+)
+
+// ToolLatencyBuckets are the upper bounds, in milliseconds, of the latency
+// histogram kept per tool. Slower calls count in a last bucket with bound 0.
+var ToolLatencyBuckets = []int{10, 50, 100, 250, 500, 1000, 5000, 30000}
+
+// ToolLatencyBucket is one bucket of a tool's latency histogram.
+type ToolLatencyBucket struct {
+	LeMs  int `db:"le_ms" json:"leMs"` // Upper bound; 0 for calls slower than every bound
+	Calls int `db:"calls" json:"calls"`
+}
+
+// ToolStat is what RecordToolCall counted for one tool.
+type ToolStat struct {
+	Tool   string  `json:"tool"`
+	Calls  int     `json:"calls"`
+	Errors int     `json:"errors"`
+	AvgMs  float64 `json:"avgMs"`
+	MaxMs  float64 `json:"maxMs"`
+	// P50Ms and P95Ms are estimated from the histogram: the upper bound of
+	// the bucket the percentile falls in, at most the slowest call
+	P50Ms      float64             `json:"p50Ms"`
+	P95Ms      float64             `json:"p95Ms"`
+	LastCalled time.Time           `json:"lastCalled"`
+	Latency    []ToolLatencyBucket `json:"latency"` // Buckets with calls, fastest first
+}
+
+// toolLatencyBucket returns the upper bound of the bucket elapsed falls in.
+func toolLatencyBucket(elapsed time.Duration) int {
+	for _, le := range ToolLatencyBuckets {
+		if elapsed <= time.Duration(le)*time.Millisecond {
+			return le
+		}
+	}
+	return 0
+}
+
+// RecordToolCall counts a call of tool that took elapsed, and failed or not.
+// Read-only stores count nothing.
+func (s *Store) RecordToolCall(tool string, elapsed time.Duration, failed bool) error {
+	return s.RecordToolCallContext(context.Background(), tool, elapsed, failed)
+}
+
+// RecordToolCallContext is RecordToolCall with a context.
+func (s *Store) RecordToolCallContext(ctx context.Context, tool string, elapsed time.Duration, failed bool) error {
+	if s.readOnly {
+		return nil
+	}
+	if tool == "" {
+		return &ValidationError{Field: "tool", Reason: "must not be empty"}
+	}
+	failures := 0
+	if failed {
+		failures = 1
+	}
+	ms := float64(elapsed.Microseconds()) / 1000
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO tool_stats (tool, le_ms, calls, errors, total_ms, max_ms, last_called)
+		VALUES (?, ?, 1, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(tool, le_ms) DO UPDATE SET
+			calls = calls + 1,
+			errors = errors + excluded.errors,
+			total_ms = total_ms + excluded.total_ms,
+			max_ms = MAX(max_ms, excluded.max_ms),
+			last_called = excluded.last_called
+	`, tool, toolLatencyBucket(elapsed), failures, ms, ms)
+	return err
+}
+
+// ToolStats returns the counts of every tool called, most called first.
+func (s *Store) ToolStats() ([]ToolStat, error) {
+	return s.ToolStatsContext(context.Background())
+}
+
+// ToolStatsContext is ToolStats with a context.
+func (s *Store) ToolStatsContext(ctx context.Context) ([]ToolStat, error) {
+	var rows []struct {
+		Tool       string    `db:"tool"`
+		LeMs       int       `db:"le_ms"`
+		Calls      int       `db:"calls"`
+		Errors     int       `db:"errors"`
+		TotalMs    float64   `db:"total_ms"`
+		MaxMs      float64   `db:"max_ms"`
+		LastCalled time.Time `db:"last_called"`
+	}
+	if err := s.db.SelectContext(ctx, &rows, `
+		SELECT tool, le_ms, calls, errors, total_ms, max_ms, last_called
+		FROM tool_stats
+		ORDER BY tool, le_ms = 0, le_ms
+	`); err != nil {
+		return nil, err
+	}
+
+	var stats []ToolStat
+	var totalMs float64
+	for _, row := range rows {
+		if len(stats) == 0 || stats[len(stats)-1].Tool != row.Tool {
+			stats = append(stats, ToolStat{Tool: row.Tool})
+			totalMs = 0
+		}
+		stat := &stats[len(stats)-1]
+		stat.Calls += row.Calls
+		stat.Errors += row.Errors
+		stat.MaxMs = max(stat.MaxMs, row.MaxMs)
+		if row.LastCalled.After(stat.LastCalled) {
+			stat.LastCalled = row.LastCalled
+		}
+		stat.Latency = append(stat.Latency, ToolLatencyBucket{LeMs: row.LeMs, Calls: row.Calls})
+		totalMs += row.TotalMs
+		stat.AvgMs = totalMs / float64(stat.Calls)
+	}
+	for i := range stats {
+		stats[i].P50Ms = latencyPercentile(stats[i], 0.5)
+		stats[i].P95Ms = latencyPercentile(stats[i], 0.95)
+	}
+	slices.SortStableFunc(stats, func(a, b ToolStat) int { return cmp.Compare(b.Calls, a.Calls) })
+	return stats, nil
+}
+
+// latencyPercentile estimates the q-th percentile of stat's latency as the
+// upper bound of the bucket it falls in, at most the slowest call.
+func latencyPercentile(stat ToolStat, q float64) float64 {
+	seen := 0
+	for _, bucket := range stat.Latency {
+		seen += bucket.Calls
+		if float64(seen) >= q*float64(stat.Calls) {
+			if bucket.LeMs == 0 {
+				return stat.MaxMs
+			}
+			return min(float64(bucket.LeMs), stat.MaxMs)
+		}
+	}
+	return stat.MaxMs
+}
+
+// ResetToolStats forgets every counted tool call.
+func (s *Store) ResetToolStats() error {
+	return s.ResetToolStatsContext(context.Background())
+}
+
+// ResetToolStatsContext is ResetToolStats with a context.
+func (s *Store) ResetToolStatsContext(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM tool_stats")
+	return err
+}
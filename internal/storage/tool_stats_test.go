@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestToolStats(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	calls := []struct {
+		tool    string
+		elapsed time.Duration
+		failed  bool
+	}{
+		{"search_nodes", 5 * time.Millisecond, false},
+		{"search_nodes", 8 * time.Millisecond, false},
+		{"search_nodes", 40 * time.Millisecond, false},
+		{"search_nodes", 2 * time.Minute, true},
+		{"create_entities", 200 * time.Millisecond, false},
+	}
+	for _, c := range calls {
+		if err := store.RecordToolCall(c.tool, c.elapsed, c.failed); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats, err := store.ToolStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 2 || stats[0].Tool != "search_nodes" {
+		t.Fatalf("expected two tools, most called first, got %+v", stats)
+	}
+	search := stats[0]
+	if search.Calls != 4 || search.Errors != 1 || search.MaxMs != 120000 {
+		t.Errorf("unexpected counts: %+v", search)
+	}
+	if search.AvgMs < 30000 || search.AvgMs > 30100 {
+		t.Errorf("expected the mean of every call, got %v", search.AvgMs)
+	}
+	want := []ToolLatencyBucket{{LeMs: 10, Calls: 2}, {LeMs: 50, Calls: 1}, {LeMs: 0, Calls: 1}}
+	if len(search.Latency) != len(want) {
+		t.Fatalf("expected buckets %v, got %v", want, search.Latency)
+	}
+	for i := range want {
+		if search.Latency[i] != want[i] {
+			t.Errorf("expected buckets %v, got %v", want, search.Latency)
+		}
+	}
+	if search.P50Ms != 10 || search.P95Ms != 120000 {
+		t.Errorf("expected p50 10ms and p95 the slowest call, got %v and %v", search.P50Ms, search.P95Ms)
+	}
+	if create := stats[1]; create.P50Ms != 200 || create.LastCalled.IsZero() {
+		t.Errorf("expected p50 capped at the slowest call, got %+v", create)
+	}
+
+	if err := store.ResetToolStats(); err != nil {
+		t.Fatal(err)
+	}
+	if stats, _ := store.ToolStats(); len(stats) != 0 {
+		t.Errorf("expected nothing after reset, got %+v", stats)
+	}
+}
@@ -0,0 +1,309 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Operations Undo reverses.
+const (
+	OpDeleteEntity      = "delete_entity"
+	OpDeleteObservation = "delete_observation"
+	OpDeleteRelation    = "delete_relation"
+	OpArchive           = "archive" // ArchiveOldMemories and ArchiveExcessMemories
+	OpForget            = "forget"  // ForgetExpiredMemories
+	OpMerge             = "merge"   // Recorded by MergeEntities, reversed by UndoMerge
+)
+
+// DefaultUndoWindow is how long a destructive operation can be undone.
+const DefaultUndoWindow = 24 * time.Hour
+
+// UndoableOperation is a destructive operation Undo can reverse.
+type UndoableOperation struct {
+	ID           int64     `json:"id"` // The trash operation, or the merge for OpMerge
+	Operation    string    `json:"operation"`
+	Entities     []string  `json:"entities"`
+	Observations int       `json:"observations"`
+	Relations    int       `json:"relations"`
+	At           time.Time `json:"at"`
+}
+
+// trashSnapshot holds the rows an operation removed from one entity, read
+// by snapshotRows, and the archive rows it wrote in their place.
+type trashSnapshot struct {
+	Entities     []map[string]any `json:"entities,omitempty"`
+	Observations []map[string]any `json:"observations,omitempty"`
+	Relations    []map[string]any `json:"relations,omitempty"`
+	Attributes   []map[string]any `json:"attributes,omitempty"`
+	Aliases      []map[string]any `json:"aliases,omitempty"`
+	Archived     []int64          `json:"archived,omitempty"`
+}
+
+// trashBatch collects the snapshots of one operation, per entity.
+type trashBatch struct {
+	operation string
+	entities  []string
+	snapshots map[string]*trashSnapshot
+}
+
+func newTrashBatch(operation string) *trashBatch {
+	return &trashBatch{operation: operation, snapshots: map[string]*trashSnapshot{}}
+}
+
+// entity returns the snapshot of the rows removed from the entity named name.
+func (b *trashBatch) entity(name string) *trashSnapshot {
+	snapshot, ok := b.snapshots[name]
+	if !ok {
+		snapshot = &trashSnapshot{}
+		b.snapshots[name] = snapshot
+		b.entities = append(b.entities, name)
+	}
+	return snapshot
+}
+
+// SetUndoWindow sets how long destructive operations can be undone; their
+// snapshots are dropped once older. Zero or less stops keeping them.
+func (s *Store) SetUndoWindow(window time.Duration) {
+	s.undoWindow = window
+}
+
+// recordTrash writes the batch's snapshots as one operation, dropping those
+// that have left the undo window.
+func (s *Store) recordTrash(ctx context.Context, tx *sqlx.Tx, batch *trashBatch) error {
+	if s.undoWindow <= 0 || len(batch.entities) == 0 {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM trash WHERE created_at < ?",
+		sqlTime(time.Now().Add(-s.undoWindow))); err != nil {
+		return err
+	}
+	var op int64
+	if err := tx.GetContext(ctx, &op, "SELECT COALESCE(MAX(op), 0) + 1 FROM trash"); err != nil {
+		return err
+	}
+	for _, name := range batch.entities {
+		snapshot, err := json.Marshal(batch.snapshots[name])
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT INTO trash (op, operation, entity, snapshot) VALUES (?, ?, ?, ?)",
+			op, batch.operation, name, string(snapshot)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LastUndoable returns the operation Undo would reverse: the latest entity,
+// observation, or relation delete, archive or forget run, or merge not yet
+// undone, if it happened within the undo window.
+func (s *Store) LastUndoable() (*UndoableOperation, error) {
+	return s.LastUndoableContext(context.Background())
+}
+
+// LastUndoableContext is LastUndoable with a context.
+func (s *Store) LastUndoableContext(ctx context.Context) (*UndoableOperation, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	op, _, err := s.lastUndoable(ctx, tx)
+	return op, err
+}
+
+// Undo reverses the operation LastUndoable returns, putting back the rows it
+// removed. Restored observations go back on the current version of their
+// entity, which must still exist, as must both ends of a restored relation.
+// Embeddings are not restored; run embed generate to recreate them.
+func (s *Store) Undo() (*UndoableOperation, error) {
+	return s.UndoContext(context.Background())
+}
+
+// UndoContext is Undo with a context.
+func (s *Store) UndoContext(ctx context.Context) (*UndoableOperation, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	op, rows, err := s.lastUndoable(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	if op.Operation == OpMerge {
+		// UndoMerge has its own transaction
+		tx.Rollback()
+		if _, err := s.UndoMergeContext(ctx, op.ID); err != nil {
+			return nil, err
+		}
+		return op, nil
+	}
+
+	var uids []any
+	for _, row := range rows {
+		restored, err := s.restoreTrash(ctx, tx, op.Operation, row.Entity, row.snapshot)
+		if err != nil {
+			return nil, err
+		}
+		uids = append(uids, restored...)
+	}
+	// Deleting the rows left tombstones that would delete them on sync
+	for chunk := range slices.Chunk(uids, 500) {
+		query, args, err := sqlx.In("DELETE FROM tombstones WHERE uid IN (?)", chunk)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tx.ExecContext(ctx, tx.Rebind(query), args...); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE trash SET undone_at = ? WHERE op = ?",
+		sqlTime(time.Now()), op.ID); err != nil {
+		return nil, err
+	}
+	return op, tx.Commit()
+}
+
+// trashRow is one entity's share of a trash operation.
+type trashRow struct {
+	Entity    string    `db:"entity"`
+	Operation string    `db:"operation"`
+	Snapshot  string    `db:"snapshot"`
+	CreatedAt time.Time `db:"created_at"`
+	snapshot  trashSnapshot
+}
+
+// lastUndoable finds the latest operation not yet undone, in the trash or
+// among merges, and returns it with its trash rows.
+func (s *Store) lastUndoable(ctx context.Context, tx *sqlx.Tx) (*UndoableOperation, []trashRow, error) {
+	var op UndoableOperation
+	var rows []trashRow
+	if err := tx.GetContext(ctx, &op.ID, "SELECT COALESCE(MAX(op), 0) FROM trash WHERE undone_at IS NULL"); err != nil {
+		return nil, nil, err
+	}
+	if op.ID > 0 {
+		if err := tx.SelectContext(ctx, &rows,
+			"SELECT entity, operation, snapshot, created_at FROM trash WHERE op = ? ORDER BY id", op.ID); err != nil {
+			return nil, nil, err
+		}
+		op.Operation, op.At = rows[0].Operation, rows[0].CreatedAt
+		for i := range rows {
+			dec := json.NewDecoder(strings.NewReader(rows[i].Snapshot))
+			dec.UseNumber()
+			if err := dec.Decode(&rows[i].snapshot); err != nil {
+				return nil, nil, fmt.Errorf("reading trash operation #%d: %w", op.ID, err)
+			}
+			op.Entities = append(op.Entities, rows[i].Entity)
+			op.Observations += len(rows[i].snapshot.Observations)
+			op.Relations += len(rows[i].snapshot.Relations)
+		}
+	}
+
+	var merge struct {
+		ID        int64     `db:"id"`
+		Keep      string    `db:"keep"`
+		Merged    string    `db:"merged"`
+		Snapshot  string    `db:"snapshot"`
+		CreatedAt time.Time `db:"created_at"`
+	}
+	err := tx.GetContext(ctx, &merge, `SELECT id, keep, merged, snapshot, created_at FROM entity_merges
+		WHERE undone_at IS NULL ORDER BY id DESC LIMIT 1`)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, err
+	}
+	if err == nil && (op.ID == 0 || merge.CreatedAt.After(op.At)) {
+		var snapshot mergeSnapshot
+		if err := json.Unmarshal([]byte(merge.Snapshot), &snapshot); err != nil {
+			return nil, nil, fmt.Errorf("reading merge #%d: %w", merge.ID, err)
+		}
+		op = UndoableOperation{ID: merge.ID, Operation: OpMerge, Entities: []string{merge.Merged, merge.Keep},
+			Observations: len(snapshot.Observations), Relations: len(snapshot.Relations), At: merge.CreatedAt}
+		rows = nil
+	}
+
+	if op.ID == 0 || s.undoWindow <= 0 || op.At.Before(time.Now().Add(-s.undoWindow)) {
+		return nil, nil, &NotFoundError{"operation", "latest"}
+	}
+	return &op, rows, nil
+}
+
+// restoreTrash puts back the rows an operation removed from the entity named
+// name and returns their sync identities.
+func (s *Store) restoreTrash(ctx context.Context, tx *sqlx.Tx, operation, name string, snapshot trashSnapshot) ([]any, error) {
+	var uids []any
+	if operation == OpDeleteEntity {
+		if _, err := s.entityID(ctx, tx, name); err == nil {
+			return nil, &ValidationError{Field: "undo", Reason: fmt.Sprintf("an entity named %q exists again", name)}
+		} else if !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		for _, row := range snapshot.Entities {
+			if err := insertSnapshotRow(ctx, tx, "entities", row, false); err != nil {
+				return nil, err
+			}
+			uids = append(uids, row["uid"])
+		}
+	} else if len(snapshot.Observations) > 0 {
+		// The entity may have a new version since; observations go on it
+		entityID, err := s.entityID(ctx, tx, name)
+		if errors.Is(err, ErrNotFound) {
+			return nil, &ValidationError{Field: "undo", Reason: fmt.Sprintf("entity %q no longer exists", name)}
+		} else if err != nil {
+			return nil, err
+		}
+		for _, row := range snapshot.Observations {
+			row["entity_id"] = entityID
+		}
+	}
+
+	for _, row := range snapshot.Observations {
+		row["canonical_id"] = nil // Its canonical copy may be gone; relinking is cheap
+		if err := insertSnapshotRow(ctx, tx, "observations", row, true); err != nil {
+			return nil, err
+		}
+		uids = append(uids, row["uid"])
+	}
+	for _, row := range snapshot.Relations {
+		var ends int
+		if err := tx.GetContext(ctx, &ends, "SELECT COUNT(*) FROM entities WHERE id IN (?, ?)",
+			snapshotValue(row["from_entity_id"]), snapshotValue(row["to_entity_id"])); err != nil {
+			return nil, err
+		}
+		if ends < 2 && row["from_entity_id"] != row["to_entity_id"] {
+			if operation == OpDeleteRelation {
+				return nil, &ValidationError{Field: "undo", Reason: "an end of the relation no longer exists"}
+			}
+			continue // Its other end was deleted since
+		}
+		if err := insertSnapshotRow(ctx, tx, "relations", row, true); err != nil {
+			return nil, err
+		}
+		uids = append(uids, row["uid"])
+	}
+	for _, row := range snapshot.Attributes {
+		if err := insertSnapshotRow(ctx, tx, "entity_attributes", row, true); err != nil {
+			return nil, err
+		}
+	}
+	for _, row := range snapshot.Aliases {
+		if err := insertSnapshotRow(ctx, tx, "entity_aliases", row, true); err != nil {
+			return nil, err
+		}
+	}
+	for _, id := range snapshot.Archived {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM archived_observations WHERE id = ?", id); err != nil {
+			return nil, err
+		}
+	}
+	return uids, nil
+}
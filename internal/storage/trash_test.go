@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestUndo(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	if _, err := store.Undo(); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected nothing to undo on an empty store, got %v", err)
+	}
+
+	store.CreateEntity("Go", "language", []string{"Fast compiler"})
+	store.CreateEntity("Docker", "tool", []string{"Used for CI", "Runs the tests"})
+	store.CreateRelation("Docker", "Go", "builds")
+	store.SetAttributes("Docker", map[string]string{"version": "27"})
+	store.AddAlias("Docker", "docker-engine")
+
+	if _, err := store.MergeEntities("Go", "Docker"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.DeleteObservation("Go", "Fast compiler"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The latest operation goes first, then the one before
+	op, err := store.LastUndoable()
+	if err != nil || op.Operation != OpDeleteObservation || op.Observations != 1 {
+		t.Fatalf("LastUndoable = %+v, %v", op, err)
+	}
+	if _, err := store.Undo(); err != nil {
+		t.Fatalf("Undo observation delete: %v", err)
+	}
+	if op, err := store.Undo(); err != nil || op.Operation != OpMerge {
+		t.Fatalf("expected the merge undone next, got %+v, %v", op, err)
+	}
+	goEntity, _ := store.GetEntity("Go")
+	if !slices.Equal(goEntity.Observations, []string{"Fast compiler"}) {
+		t.Errorf("Go observations = %v", goEntity.Observations)
+	}
+
+	if err := store.DeleteEntity("Docker"); err != nil {
+		t.Fatal(err)
+	}
+	op, err = store.Undo()
+	if err != nil {
+		t.Fatalf("Undo entity delete: %v", err)
+	}
+	if op.Operation != OpDeleteEntity || !slices.Equal(op.Entities, []string{"Docker"}) || op.Observations != 2 || op.Relations != 1 {
+		t.Errorf("Undo = %+v", op)
+	}
+	docker, err := store.GetEntity("Docker")
+	if err != nil || len(docker.Observations) != 2 {
+		t.Fatalf("expected Docker restored with its observations, got %+v, %v", docker, err)
+	}
+	if relations, _ := store.ListRelations("Docker"); len(relations) != 1 {
+		t.Errorf("expected the relation restored, got %+v", relations)
+	}
+	if attrs, _ := store.GetAttributes("Docker"); attrs["version"] != "27" {
+		t.Errorf("expected the attributes restored, got %v", attrs)
+	}
+	if name, err := store.ResolveEntityName("docker-engine"); err != nil || name != "Docker" {
+		t.Errorf("expected the alias restored, got %q, %v", name, err)
+	}
+	var tombstones int
+	store.db.Get(&tombstones, "SELECT COUNT(*) FROM tombstones")
+	if tombstones != 0 {
+		t.Errorf("expected the restored rows' tombstones removed, %d left", tombstones)
+	}
+
+	if _, err := store.Undo(); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected nothing left to undo, got %v", err)
+	}
+
+	// An entity created since under the name is not overwritten
+	store.DeleteEntity("Docker")
+	store.CreateEntity("Docker", "tool", nil)
+	var verr *ValidationError
+	if _, err := store.Undo(); !errors.As(err, &verr) {
+		t.Errorf("expected a validation error, got %v", err)
+	}
+}
+
+func TestUndo_Archive(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	store.CreateEntity("Go", "language", []string{"one", "two", "three"})
+	cfg := DefaultDecayConfig()
+	cfg.KeepPerEntity = 1
+	if n, err := store.ArchiveExcessMemories(cfg); err != nil || n != 2 {
+		t.Fatalf("ArchiveExcessMemories = %d, %v", n, err)
+	}
+
+	op, err := store.Undo()
+	if err != nil || op.Operation != OpArchive || op.Observations != 2 {
+		t.Fatalf("Undo = %+v, %v", op, err)
+	}
+	entity, _ := store.GetEntity("Go")
+	if len(entity.Observations) != 3 {
+		t.Errorf("expected every observation back, got %v", entity.Observations)
+	}
+	if n, _ := store.GetArchiveCount(); n != 0 {
+		t.Errorf("expected the archive emptied, %d left", n)
+	}
+}
+
+func TestUndo_Window(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	store.CreateEntity("Go", "language", []string{"Fast compiler"})
+	store.DeleteObservation("Go", "Fast compiler")
+	if _, err := store.db.Exec("UPDATE trash SET created_at = datetime('now', '-2 days')"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Undo(); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected an operation outside the window ignored, got %v", err)
+	}
+
+	// Later operations drop snapshots past the window
+	store.CreateEntity("Rust", "language", nil)
+	store.DeleteEntity("Rust")
+	var count int
+	store.db.Get(&count, "SELECT COUNT(*) FROM trash")
+	if count != 1 {
+		t.Errorf("expected only the latest snapshot kept, got %d", count)
+	}
+
+	store.SetUndoWindow(0)
+	store.CreateEntity("Zig", "language", nil)
+	store.DeleteEntity("Zig")
+	if _, err := store.Undo(); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected nothing to undo with no window, got %v", err)
+	}
+}
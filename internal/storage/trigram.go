@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// trigramSchema indexes observations and entity names by every three
+// characters. unicode61 takes a run of Chinese or Japanese text, which has no
+// spaces, for one word, so only a substring index can find words inside it.
+const trigramSchema = `
+	DROP TABLE IF EXISTS observations_trigram;
+	DROP TABLE IF EXISTS entities_trigram;
+	DROP TRIGGER IF EXISTS observations_trigram_ai;
+	DROP TRIGGER IF EXISTS observations_trigram_ad;
+	DROP TRIGGER IF EXISTS observations_trigram_au;
+	DROP TRIGGER IF EXISTS entities_trigram_ai;
+	DROP TRIGGER IF EXISTS entities_trigram_ad;
+	DROP TRIGGER IF EXISTS entities_trigram_au;
+
+	CREATE VIRTUAL TABLE observations_trigram USING fts5(
+		content,
+		content='observations',
+		content_rowid='id',
+		tokenize='trigram'
+	);
+
+	CREATE VIRTUAL TABLE entities_trigram USING fts5(
+		name,
+		content='entities',
+		content_rowid='id',
+		tokenize='trigram'
+	);
+
+	CREATE TRIGGER observations_trigram_ai AFTER INSERT ON observations BEGIN
+		INSERT INTO observations_trigram(rowid, content) VALUES (new.id, new.content);
+	END;
+
+	CREATE TRIGGER observations_trigram_ad AFTER DELETE ON observations BEGIN
+		INSERT INTO observations_trigram(observations_trigram, rowid, content)
+		VALUES('delete', old.id, old.content);
+	END;
+
+	CREATE TRIGGER observations_trigram_au AFTER UPDATE OF content ON observations BEGIN
+		INSERT INTO observations_trigram(observations_trigram, rowid, content)
+		VALUES('delete', old.id, old.content);
+		INSERT INTO observations_trigram(rowid, content) VALUES (new.id, new.content);
+	END;
+
+	CREATE TRIGGER entities_trigram_ai AFTER INSERT ON entities BEGIN
+		INSERT INTO entities_trigram(rowid, name) VALUES (new.id, new.name);
+	END;
+
+	CREATE TRIGGER entities_trigram_ad AFTER DELETE ON entities BEGIN
+		INSERT INTO entities_trigram(entities_trigram, rowid, name)
+		VALUES('delete', old.id, old.name);
+	END;
+
+	CREATE TRIGGER entities_trigram_au AFTER UPDATE OF name ON entities BEGIN
+		INSERT INTO entities_trigram(entities_trigram, rowid, name)
+		VALUES('delete', old.id, old.name);
+		INSERT INTO entities_trigram(rowid, name) VALUES (new.id, new.name);
+	END;
+
+	INSERT INTO observations_trigram(observations_trigram) VALUES('rebuild');
+	INSERT INTO entities_trigram(entities_trigram) VALUES('rebuild');
+`
+
+// trigramObjects are the tables and triggers trigramSchema creates.
+var trigramObjects = []any{
+	"observations_trigram", "entities_trigram",
+	"observations_trigram_ai", "observations_trigram_ad", "observations_trigram_au",
+	"entities_trigram_ai", "entities_trigram_ad", "entities_trigram_au",
+}
+
+// initTrigram creates the trigram indexes, indexing existing rows, unless
+// they exist. A migration that rebuilds a table drops its triggers, so
+// missing ones rebuild the indexes too. SQLite before 3.34 has no trigram
+// tokenizer; CJK queries then use the unicode61 indexes like any other.
+func (s *Store) initTrigram() {
+	var count int
+	if err := s.db.Get(&count, `
+		SELECT COUNT(*) FROM sqlite_master
+		WHERE name IN (?`+strings.Repeat(", ?", len(trigramObjects)-1)+`)
+	`, trigramObjects...); err != nil {
+		return
+	}
+	if count < len(trigramObjects) {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return
+		}
+		defer tx.Rollback()
+		if _, err := tx.Exec(trigramSchema); err != nil || tx.Commit() != nil {
+			return
+		}
+	}
+	s.trigram = true
+}
+
+// hasCJK reports whether text contains Chinese, Japanese, or Korean script.
+func hasCJK(text string) bool {
+	for _, r := range text {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+			return true
+		}
+	}
+	return false
+}
+
+// trigramMatchSQL is observationMatchSQL against a trigram index, for
+// queries in CJK scripts. Trigrams can't match words under three characters,
+// so a query with one is matched by substring on table instead.
+func trigramMatchSQL(index, table, column, query string) (string, []any) {
+	for _, word := range strings.Fields(query) {
+		if utf8.RuneCountInString(word) < 3 {
+			return likeMatchSQL(table, column, query)
+		}
+	}
+	return `SELECT rowid AS id, bm25(` + index + `) AS score
+		FROM ` + index + ` WHERE ` + index + ` MATCH ?`, []any{prepareFTSQuery(query)}
+}
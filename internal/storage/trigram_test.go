@@ -0,0 +1,54 @@
+package storage
+
+import "testing"
+
+func TestSearchCJK(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+	if !store.trigram {
+		t.Skip("SQLite without the trigram tokenizer")
+	}
+
+	store.CreateEntity("数据库设计", "pattern", []string{"使用数据库连接池来提高性能"})
+	store.CreateEntity("キャッシュ", "pattern", []string{"東京のサーバーでキャッシュを使う"})
+	store.CreateEntity("cache", "pattern", []string{"Plain English"})
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"连接池", "数据库设计"},  // Inside a run of Chinese text
+		{"数据库", "数据库设计"},  // Entity name and observation
+		{"サーバー", "キャッシュ"}, // Japanese
+		{"東京", "キャッシュ"},   // Under three characters: substring match
+	}
+	for _, tt := range tests {
+		results, err := store.Search(tt.query)
+		if err != nil {
+			t.Fatalf("Search(%q): %v", tt.query, err)
+		}
+		if len(results) != 1 || results[0].Name != tt.want {
+			t.Errorf("Search(%q) = %+v, want %s", tt.query, results, tt.want)
+		}
+	}
+
+	// Edits and deletes reach the trigram index
+	store.UpdateObservation("キャッシュ", "東京のサーバーでキャッシュを使う", "大阪のサーバー")
+	if results, _ := store.Search("大阪のサ"); len(results) != 1 {
+		t.Errorf("expected the edited observation found, got %+v", results)
+	}
+	store.DeleteEntity("数据库设计")
+	if results, _ := store.Search("连接池"); len(results) != 0 {
+		t.Errorf("expected the deleted entity gone, got %+v", results)
+	}
+
+	// Reopening finds the indexes instead of rebuilding them
+	reopened, err := NewStore(store.path)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	defer reopened.Close()
+	if results, _ := reopened.Search("サーバー"); len(results) != 1 {
+		t.Errorf("expected search after reopening, got %+v", results)
+	}
+}
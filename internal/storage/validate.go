@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Limits on stored values, in bytes. Observations are injected into prompts,
+// so they are bounded well below what one context budget can hold.
+const (
+	MaxNameLength        = 256 // Entity names
+	MaxTypeLength        = 64  // Entity and relation types
+	MaxObservationLength = 16 << 10
+)
+
+// ValidationError reports a value rejected before it reached the database.
+type ValidationError struct {
+	Field  string // e.g. "entity name", "observation"
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Field + " " + e.Reason
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrInvalidInput
+}
+
+// validateText checks that value is non-blank, valid UTF-8, and within max
+// bytes; single-line values also may not contain control characters.
+func validateText(field, value string, max int, singleLine bool) error {
+	if strings.TrimSpace(value) == "" {
+		return &ValidationError{field, "is required"}
+	}
+	if len(value) > max {
+		return &ValidationError{field, fmt.Sprintf("is %d bytes, over the %d-byte limit", len(value), max)}
+	}
+	if !utf8.ValidString(value) {
+		return &ValidationError{field, "is not valid UTF-8"}
+	}
+	if singleLine && strings.IndexFunc(value, unicode.IsControl) >= 0 {
+		return &ValidationError{field, "contains control characters"}
+	}
+	return nil
+}
+
+// ValidateEntity checks an entity name and type.
+func ValidateEntity(name, entityType string) error {
+	if err := validateText("entity name", name, MaxNameLength, true); err != nil {
+		return err
+	}
+	return validateText("entity type", entityType, MaxTypeLength, true)
+}
+
+// ValidateObservation checks observation content. Newlines are allowed.
+func ValidateObservation(content string) error {
+	return validateText("observation", content, MaxObservationLength, false)
+}
+
+// validateEntityWithObservations is ValidateEntity plus each observation.
+func validateEntityWithObservations(name, entityType string, observations []string) error {
+	if err := ValidateEntity(name, entityType); err != nil {
+		return err
+	}
+	for _, obs := range observations {
+		if err := ValidateObservation(obs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateRelation checks a relation's endpoints and type. Self-relations
+// are rejected: they carry no information and inflate centrality.
+func ValidateRelation(from, to, relationType string) error {
+	if err := validateText("from entity", from, MaxNameLength, true); err != nil {
+		return err
+	}
+	if err := validateText("to entity", to, MaxNameLength, true); err != nil {
+		return err
+	}
+	if err := validateText("relation type", relationType, MaxTypeLength, true); err != nil {
+		return err
+	}
+	if NormalizeName(from) == NormalizeName(to) {
+		return &ValidationError{"relation", "links an entity to itself"}
+	}
+	return nil
+}
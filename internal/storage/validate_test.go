@@ -0,0 +1,102 @@
+package storage_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+func TestValidation(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+
+	store.CreateEntity("Go", "language", nil)
+	store.CreateEntity("Rust", "language", nil)
+
+	tests := []struct {
+		name string
+		op   func() error
+		want string
+	}{
+		{"empty entity name", func() error {
+			_, err := store.CreateEntity("  ", "language", nil)
+			return err
+		}, "entity name is required"},
+		{"entity name too long", func() error {
+			_, err := store.CreateEntity(strings.Repeat("x", storage.MaxNameLength+1), "language", nil)
+			return err
+		}, "entity name is 257 bytes, over the 256-byte limit"},
+		{"newline in entity name", func() error {
+			_, err := store.CreateOrUpdateEntity("Go\nlang", "language", nil)
+			return err
+		}, "entity name contains control characters"},
+		{"empty entity type", func() error {
+			_, err := store.CreateEntity("Zig", "", nil)
+			return err
+		}, "entity type is required"},
+		{"empty observation on create", func() error {
+			_, err := store.CreateEntity("Zig", "language", []string{""})
+			return err
+		}, "observation is required"},
+		{"oversized observation", func() error {
+			return store.AddObservation("Go", strings.Repeat("x", storage.MaxObservationLength+1))
+		}, "observation is 16385 bytes, over the 16384-byte limit"},
+		{"invalid UTF-8", func() error {
+			return store.AddObservationWithType("Go", "bad \xff byte", storage.FactTypeStatic)
+		}, "observation is not valid UTF-8"},
+		{"self-relation", func() error {
+			return store.CreateRelation("Go", "Go", "uses")
+		}, "relation links an entity to itself"},
+		{"empty relation type", func() error {
+			return store.CreateRelation("Go", "Rust", "")
+		}, "relation type is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.op()
+			if !errors.Is(err, storage.ErrInvalidInput) {
+				t.Fatalf("expected ErrInvalidInput, got %v", err)
+			}
+			var verr *storage.ValidationError
+			if !errors.As(err, &verr) || err.Error() != tt.want {
+				t.Errorf("error = %q, want %q", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := store.GetEntity("Zig"); err == nil {
+		t.Error("expected rejected entity not to be created")
+	}
+	if err := store.AddObservation("Go", "Multi-line\nobservations are fine"); err != nil {
+		t.Errorf("expected multi-line observation to be accepted, got %v", err)
+	}
+}
+
+func TestValidation_BatchRejectsPerItem(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+
+	results, err := store.CreateEntities([]storage.EntitySpec{
+		{Name: "Go", Type: "language", Observations: []string{"Fast compiler"}},
+		{Name: "Bad", Type: "language", Observations: []string{"   "}},
+	})
+	if err != nil {
+		t.Fatalf("CreateEntities failed: %v", err)
+	}
+	if results[0].Err != nil || !errors.Is(results[1].Err, storage.ErrInvalidInput) {
+		t.Errorf("expected only the second spec rejected, got %+v", results)
+	}
+
+	obs, err := store.AddObservationsBatch([]storage.ObservationSpec{
+		{EntityName: "Go", Content: ""},
+		{EntityName: "Go", Content: "Has generics"},
+	})
+	if err != nil {
+		t.Fatalf("AddObservationsBatch failed: %v", err)
+	}
+	if !errors.Is(obs[0].Err, storage.ErrInvalidInput) || !obs[1].Added {
+		t.Errorf("expected only the empty observation rejected, got %+v", obs)
+	}
+}
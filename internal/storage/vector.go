@@ -1,13 +1,24 @@
 package storage
 
 import (
+	"container/heap"
+	"context"
 	"database/sql"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"math"
 	"sort"
+
+	"github.com/jmoiron/sqlx"
 )
 
+// DefaultMaxVectorCandidates bounds the embeddings one VectorSearch scores.
+const DefaultMaxVectorCandidates = 500_000
+
+// vectorScanChunk is the number of embedding rows read per query.
+const vectorScanChunk = 1000
+
 // VectorResult represents a vector search result.
 type VectorResult struct {
 	EntityName string
@@ -17,24 +28,35 @@ type VectorResult struct {
 }
 
 // getObservationID returns the ID of an observation by entity and content.
-func (s *Store) getObservationID(entityID int64, content string) (int64, error) {
+func (s *Store) getObservationID(ctx context.Context, entityID int64, content string) (int64, error) {
 	var id int64
-	err := s.db.QueryRow(
+	err := s.db.QueryRowContext(ctx,
 		"SELECT id FROM observations WHERE entity_id = ? AND content = ?",
 		entityID, content,
 	).Scan(&id)
-	if err != nil {
-		return 0, fmt.Errorf("observation not found: %w", err)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, observationNotFound(content)
 	}
-	return id, nil
+	return id, err
 }
 
-// StoreEmbedding stores an embedding vector for an observation.
+// StoreEmbedding stores an embedding vector for an observation. The vector
+// must have the dimensions recorded for model; the first vector stored for a
+// model records them.
 func (s *Store) StoreEmbedding(observationID int64, embedding []float64, model string) error {
+	return s.StoreEmbeddingContext(context.Background(), observationID, embedding, model)
+}
+
+// StoreEmbeddingContext is StoreEmbedding with a context.
+func (s *Store) StoreEmbeddingContext(ctx context.Context, observationID int64, embedding []float64, model string) error {
+	if err := checkEmbedding(ctx, s.db, model, embedding); err != nil {
+		return err
+	}
+
 	// Encode embedding as binary (more efficient than JSON)
 	blob := encodeEmbedding(embedding)
 
-	_, err := s.db.Exec(`
+	_, err := s.db.ExecContext(ctx, `
 		INSERT OR REPLACE INTO observation_embeddings (observation_id, embedding, model, dimensions)
 		VALUES (?, ?, ?, ?)
 	`, observationID, blob, model, len(embedding))
@@ -46,8 +68,13 @@ func (s *Store) StoreEmbedding(observationID int64, embedding []float64, model s
 
 // GetEmbedding retrieves the embedding for an observation.
 func (s *Store) GetEmbedding(observationID int64) ([]float64, error) {
+	return s.GetEmbeddingContext(context.Background(), observationID)
+}
+
+// GetEmbeddingContext is GetEmbedding with a context.
+func (s *Store) GetEmbeddingContext(ctx context.Context, observationID int64) ([]float64, error) {
 	var blob []byte
-	err := s.db.QueryRow(
+	err := s.db.QueryRowContext(ctx,
 		"SELECT embedding FROM observation_embeddings WHERE observation_id = ?",
 		observationID,
 	).Scan(&blob)
@@ -59,8 +86,13 @@ func (s *Store) GetEmbedding(observationID int64) ([]float64, error) {
 
 // HasEmbedding checks if an observation has a stored embedding.
 func (s *Store) HasEmbedding(observationID int64) (bool, error) {
+	return s.HasEmbeddingContext(context.Background(), observationID)
+}
+
+// HasEmbeddingContext is HasEmbedding with a context.
+func (s *Store) HasEmbeddingContext(ctx context.Context, observationID int64) (bool, error) {
 	var count int
-	err := s.db.QueryRow(
+	err := s.db.QueryRowContext(ctx,
 		"SELECT COUNT(*) FROM observation_embeddings WHERE observation_id = ?",
 		observationID,
 	).Scan(&count)
@@ -70,58 +102,175 @@ func (s *Store) HasEmbedding(observationID int64) (bool, error) {
 	return count > 0, nil
 }
 
+// SetMaxVectorCandidates caps the embeddings VectorSearch scores, newest
+// first, so a huge embedding table cannot stall a search. 0 removes the cap.
+func (s *Store) SetMaxVectorCandidates(n int) {
+	s.maxVectorCandidates = n
+}
+
+// scoredObservation is a VectorSearch candidate.
+type scoredObservation struct {
+	id    int64
+	score float64
+}
+
+// topK is a min-heap holding the best-scoring candidates seen so far.
+type topK []scoredObservation
+
+func (h topK) Len() int           { return len(h) }
+func (h topK) Less(i, j int) bool { return h[i].score < h[j].score }
+func (h topK) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *topK) Push(x any)        { *h = append(*h, x.(scoredObservation)) }
+func (h *topK) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
 // VectorSearch finds observations similar to the query embedding.
+// Embeddings are scanned in chunks and only the best limit candidates are
+// kept, so memory stays flat however many embeddings are stored. A limit of
+// 0 or less returns every candidate.
 func (s *Store) VectorSearch(queryEmbedding []float64, limit int) ([]VectorResult, error) {
-	// Load all embeddings (for small knowledge graphs this is fine)
-	// For larger datasets, consider approximate nearest neighbor indices
-	rows, err := s.db.Query(`
-		SELECT oe.observation_id, oe.embedding, o.content, e.name, e.entity_type
+	return s.VectorSearchContext(context.Background(), queryEmbedding, limit)
+}
+
+// VectorSearchContext is VectorSearch with a context.
+func (s *Store) VectorSearchContext(ctx context.Context, queryEmbedding []float64, limit int) ([]VectorResult, error) {
+	return s.vectorSearch(ctx, queryEmbedding, limit, false)
+}
+
+// vectorSearch is VectorSearchContext, optionally scoring suppressed
+// observations too.
+func (s *Store) vectorSearch(ctx context.Context, queryEmbedding []float64, limit int, includeSuppressed bool) ([]VectorResult, error) {
+	best := &topK{}
+	scanned := 0
+	var lastID int64 = math.MaxInt64
+	for {
+		chunk := vectorScanChunk
+		if s.maxVectorCandidates > 0 {
+			chunk = min(chunk, s.maxVectorCandidates-scanned)
+		}
+		if chunk <= 0 {
+			break
+		}
+
+		n, err := s.scanEmbeddings(ctx, queryEmbedding, lastID, chunk, includeSuppressed, func(id int64, score float64) {
+			lastID = id
+			if limit <= 0 || best.Len() < limit {
+				heap.Push(best, scoredObservation{id, score})
+			} else if score > (*best)[0].score {
+				(*best)[0] = scoredObservation{id, score}
+				heap.Fix(best, 0)
+			}
+		})
+		if err != nil {
+			return nil, fmt.Errorf("loading embeddings: %w", err)
+		}
+		scanned += n
+		if n < chunk {
+			break
+		}
+	}
+
+	candidates := *best
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+	return s.vectorResults(ctx, candidates)
+}
+
+// scanEmbeddings scores up to limit embeddings with IDs below beforeID, in
+// descending ID order, and returns how many it read.
+func (s *Store) scanEmbeddings(ctx context.Context, query []float64, beforeID int64, limit int, includeSuppressed bool, fn func(id int64, score float64)) (int, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT oe.observation_id, oe.embedding
 		FROM observation_embeddings oe
 		JOIN observations o ON o.id = oe.observation_id
 		JOIN entities e ON e.id = o.entity_id
-	`)
+		WHERE (? OR COALESCE(o.suppressed, 0) = 0) AND oe.observation_id < ?
+		ORDER BY oe.observation_id DESC
+		LIMIT ?
+	`, includeSuppressed, beforeID, limit)
 	if err != nil {
-		return nil, fmt.Errorf("loading embeddings: %w", err)
+		return 0, err
 	}
 	defer rows.Close()
 
-	var results []VectorResult
+	n := 0
+	var blob []byte
 	for rows.Next() {
-		var obsID int64
-		var blob []byte
-		var content, entityName, entityType string
-
-		if err := rows.Scan(&obsID, &blob, &content, &entityName, &entityType); err != nil {
-			return nil, err
+		var id int64
+		if err := rows.Scan(&id, &blob); err != nil {
+			return n, err
 		}
-
-		embedding := decodeEmbedding(blob)
-		similarity := CosineSimilarity(queryEmbedding, embedding)
-
-		results = append(results, VectorResult{
-			EntityName: entityName,
-			EntityType: entityType,
-			Content:    content,
-			Score:      similarity,
-		})
+		fn(id, cosineSimilarityBlob(query, blob))
+		n++
 	}
+	return n, rows.Err()
+}
 
-	// Sort by similarity (descending)
-	sort.Slice(results, func(i, j int) bool {
-		return results[i].Score > results[j].Score
-	})
+// vectorResults loads the observations behind scored candidates, keeping their order.
+func (s *Store) vectorResults(ctx context.Context, candidates []scoredObservation) ([]VectorResult, error) {
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+	ids := make([]int64, len(candidates))
+	for i, c := range candidates {
+		ids[i] = c.id
+	}
 
-	// Apply limit
-	if limit > 0 && len(results) > limit {
-		results = results[:limit]
+	details := make(map[int64]VectorResult, len(ids))
+	for start := 0; start < len(ids); start += vectorScanChunk {
+		query, args, err := sqlx.In(`
+			SELECT o.id, o.content, e.name, e.entity_type
+			FROM observations o
+			JOIN entities e ON e.id = o.entity_id
+			WHERE o.id IN (?)
+		`, ids[start:min(start+vectorScanChunk, len(ids))])
+		if err != nil {
+			return nil, err
+		}
+		rows, err := s.db.QueryContext(ctx, s.db.Rebind(query), args...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var id int64
+			var r VectorResult
+			if err := rows.Scan(&id, &r.Content, &r.EntityName, &r.EntityType); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			details[id] = r
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
 	}
 
+	results := make([]VectorResult, 0, len(candidates))
+	for _, c := range candidates {
+		r, ok := details[c.id]
+		if !ok {
+			continue // Deleted since the scan
+		}
+		r.Score = c.score
+		results = append(results, r)
+	}
 	return results, nil
 }
 
 // GetObservationsWithoutEmbeddings returns observations that need embeddings.
 func (s *Store) GetObservationsWithoutEmbeddings() ([]ObservationWithID, error) {
-	rows, err := s.db.Query(`
+	return s.GetObservationsWithoutEmbeddingsContext(context.Background())
+}
+
+// GetObservationsWithoutEmbeddingsContext is GetObservationsWithoutEmbeddings with a context.
+func (s *Store) GetObservationsWithoutEmbeddingsContext(ctx context.Context) ([]ObservationWithID, error) {
+	rows, err := s.db.QueryContext(ctx, `
 		SELECT o.id, o.content, e.name, e.entity_type
 		FROM observations o
 		JOIN entities e ON e.id = o.entity_id
@@ -173,6 +322,28 @@ func CosineSimilarity(a, b []float64) float64 {
 	return dotProduct / (math.Sqrt(normA) * math.Sqrt(normB))
 }
 
+// cosineSimilarityBlob is CosineSimilarity against an encoded embedding,
+// without decoding it into a new slice.
+func cosineSimilarityBlob(a []float64, blob []byte) float64 {
+	if len(blob) != len(a)*8 || len(a) == 0 {
+		return 0
+	}
+
+	var dotProduct, normA, normB float64
+	for i := range a {
+		b := math.Float64frombits(binary.LittleEndian.Uint64(blob[i*8:]))
+		dotProduct += a[i] * b
+		normA += a[i] * a[i]
+		normB += b * b
+	}
+
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+
+	return dotProduct / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
 // encodeEmbedding converts a float64 slice to a binary blob.
 func encodeEmbedding(embedding []float64) []byte {
 	buf := make([]byte, len(embedding)*8)
@@ -192,19 +363,25 @@ func decodeEmbedding(blob []byte) []float64 {
 	return embedding
 }
 
-// BatchStoreEmbeddings stores multiple embeddings efficiently.
+// BatchStoreEmbeddings stores multiple embeddings efficiently. Like
+// StoreEmbedding it checks their dimensions; none are stored if one is off.
 func (s *Store) BatchStoreEmbeddings(observations []ObservationWithID, embeddings [][]float64, model string) error {
+	return s.BatchStoreEmbeddingsContext(context.Background(), observations, embeddings, model)
+}
+
+// BatchStoreEmbeddingsContext is BatchStoreEmbeddings with a context.
+func (s *Store) BatchStoreEmbeddingsContext(ctx context.Context, observations []ObservationWithID, embeddings [][]float64, model string) error {
 	if len(observations) != len(embeddings) {
 		return fmt.Errorf("observations and embeddings count mismatch: %d vs %d", len(observations), len(embeddings))
 	}
 
-	tx, err := s.db.Begin()
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	stmt, err := tx.Prepare(`
+	stmt, err := tx.PrepareContext(ctx, `
 		INSERT OR REPLACE INTO observation_embeddings (observation_id, embedding, model, dimensions)
 		VALUES (?, ?, ?, ?)
 	`)
@@ -214,8 +391,11 @@ func (s *Store) BatchStoreEmbeddings(observations []ObservationWithID, embedding
 	defer stmt.Close()
 
 	for i, obs := range observations {
+		if err := checkEmbedding(ctx, tx, model, embeddings[i]); err != nil {
+			return fmt.Errorf("embedding for obs %d: %w", obs.ID, err)
+		}
 		blob := encodeEmbedding(embeddings[i])
-		if _, err := stmt.Exec(obs.ID, blob, model, len(embeddings[i])); err != nil {
+		if _, err := stmt.ExecContext(ctx, obs.ID, blob, model, len(embeddings[i])); err != nil {
 			return fmt.Errorf("storing embedding for obs %d: %w", obs.ID, err)
 		}
 	}
@@ -225,12 +405,17 @@ func (s *Store) BatchStoreEmbeddings(observations []ObservationWithID, embedding
 
 // EmbeddingStats returns statistics about stored embeddings.
 func (s *Store) EmbeddingStats() (total, withEmbeddings int, err error) {
-	err = s.db.QueryRow("SELECT COUNT(*) FROM observations").Scan(&total)
+	return s.EmbeddingStatsContext(context.Background())
+}
+
+// EmbeddingStatsContext is EmbeddingStats with a context.
+func (s *Store) EmbeddingStatsContext(ctx context.Context) (total, withEmbeddings int, err error) {
+	err = s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM observations").Scan(&total)
 	if err != nil {
 		return 0, 0, err
 	}
 
-	err = s.db.QueryRow("SELECT COUNT(*) FROM observation_embeddings").Scan(&withEmbeddings)
+	err = s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM observation_embeddings").Scan(&withEmbeddings)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return total, 0, nil
@@ -1,6 +1,8 @@
 package storage
 
 import (
+	"context"
+	"fmt"
 	"math"
 	"path/filepath"
 	"testing"
@@ -27,7 +29,7 @@ func TestStoreEmbedding(t *testing.T) {
 	}
 
 	// Get observation ID
-	obsID, err := store.getObservationID(entity.ID, "prefers typescript")
+	obsID, err := store.getObservationID(context.Background(), entity.ID, "prefers typescript")
 	if err != nil {
 		t.Fatalf("failed to get observation ID: %v", err)
 	}
@@ -130,7 +132,7 @@ func TestVectorSearch(t *testing.T) {
 		if err != nil {
 			t.Fatalf("failed to create entity: %v", err)
 		}
-		obsID, err := store.getObservationID(entity.ID, td.observation)
+		obsID, err := store.getObservationID(context.Background(), entity.ID, td.observation)
 		if err != nil {
 			t.Fatalf("failed to get observation ID: %v", err)
 		}
@@ -176,7 +178,7 @@ func TestHasEmbedding(t *testing.T) {
 		t.Fatalf("failed to create entity: %v", err)
 	}
 
-	obsID, err := store.getObservationID(entity.ID, "observation")
+	obsID, err := store.getObservationID(context.Background(), entity.ID, "observation")
 	if err != nil {
 		t.Fatalf("failed to get observation ID: %v", err)
 	}
@@ -204,3 +206,57 @@ func TestHasEmbedding(t *testing.T) {
 		t.Error("expected embedding to exist")
 	}
 }
+
+func TestVectorSearch_TopKAndCandidateCap(t *testing.T) {
+	store := newTestStoreWithMigrations(t)
+	defer store.Close()
+
+	// Observation i points at angle i degrees, so similarity to the x axis falls with i
+	const n = 50
+	for i := range n {
+		content := fmt.Sprintf("obs %02d", i)
+		entity, err := store.CreateEntity(fmt.Sprintf("e%02d", i), "thing", []string{content})
+		if err != nil {
+			t.Fatalf("CreateEntity failed: %v", err)
+		}
+		obsID, _ := store.getObservationID(context.Background(), entity.ID, content)
+		angle := float64(i) * math.Pi / 180
+		store.StoreEmbedding(obsID, []float64{math.Cos(angle), math.Sin(angle)}, "test-model")
+	}
+
+	results, err := store.VectorSearch([]float64{1, 0}, 5)
+	if err != nil {
+		t.Fatalf("VectorSearch failed: %v", err)
+	}
+	if len(results) != 5 {
+		t.Fatalf("expected 5 results, got %d", len(results))
+	}
+	for i, r := range results {
+		if want := fmt.Sprintf("obs %02d", i); r.Content != want || r.EntityName != fmt.Sprintf("e%02d", i) {
+			t.Errorf("result %d = %s/%q, want %q", i, r.EntityName, r.Content, want)
+		}
+	}
+
+	all, _ := store.VectorSearch([]float64{1, 0}, 0)
+	if len(all) != n {
+		t.Errorf("expected all %d results without a limit, got %d", n, len(all))
+	}
+
+	// The cap scores only the newest embeddings
+	store.SetMaxVectorCandidates(10)
+	results, _ = store.VectorSearch([]float64{1, 0}, 3)
+	if len(results) != 3 || results[0].Content != "obs 40" {
+		t.Errorf("expected best of the newest 10 first, got %+v", results)
+	}
+}
+
+func TestCosineSimilarityBlob(t *testing.T) {
+	a := []float64{0.3, -1.2, 4.5}
+	b := []float64{2.0, 0.1, -0.7}
+	if got, want := cosineSimilarityBlob(a, encodeEmbedding(b)), CosineSimilarity(a, b); got != want {
+		t.Errorf("cosineSimilarityBlob = %v, want %v", got, want)
+	}
+	if got := cosineSimilarityBlob(a, encodeEmbedding(b[:2])); got != 0 {
+		t.Errorf("expected 0 for mismatched dimensions, got %v", got)
+	}
+}
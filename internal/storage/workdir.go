@@ -4,12 +4,18 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"sort"
 )
 
 // SetContainerTag sets the container_tag for an entity.
 // Container tags are used for working directory awareness (multi-project scoping).
 func (s *Store) SetContainerTag(entityName, containerTag string) error {
-	result, err := s.db.Exec(`
+	return s.SetContainerTagContext(context.Background(), entityName, containerTag)
+}
+
+// SetContainerTagContext is SetContainerTag with a context.
+func (s *Store) SetContainerTagContext(ctx context.Context, entityName, containerTag string) error {
+	result, err := s.db.ExecContext(ctx, `
 		UPDATE entities SET container_tag = ?
 		WHERE name = ? AND (is_latest = 1 OR is_latest IS NULL)
 	`, containerTag, entityName)
@@ -22,20 +28,25 @@ func (s *Store) SetContainerTag(entityName, containerTag string) error {
 		return err
 	}
 	if rows == 0 {
-		return ErrNotFound
+		return entityNotFound(entityName)
 	}
 	return nil
 }
 
 // GetContainerTag retrieves the container_tag for an entity.
 func (s *Store) GetContainerTag(entityName string) (string, error) {
+	return s.GetContainerTagContext(context.Background(), entityName)
+}
+
+// GetContainerTagContext is GetContainerTag with a context.
+func (s *Store) GetContainerTagContext(ctx context.Context, entityName string) (string, error) {
 	var tag sql.NullString
-	err := s.db.Get(&tag, `
+	err := s.db.GetContext(ctx, &tag, `
 		SELECT container_tag FROM entities
 		WHERE name = ? AND (is_latest = 1 OR is_latest IS NULL)
 	`, entityName)
 	if err == sql.ErrNoRows {
-		return "", ErrNotFound
+		return "", entityNotFound(entityName)
 	}
 	if err != nil {
 		return "", err
@@ -45,8 +56,13 @@ func (s *Store) GetContainerTag(entityName string) (string, error) {
 
 // GetEntitiesByContainerTag retrieves all entities with a specific container tag.
 func (s *Store) GetEntitiesByContainerTag(containerTag string) ([]*Entity, error) {
+	return s.GetEntitiesByContainerTagContext(context.Background(), containerTag)
+}
+
+// GetEntitiesByContainerTagContext is GetEntitiesByContainerTag with a context.
+func (s *Store) GetEntitiesByContainerTagContext(ctx context.Context, containerTag string) ([]*Entity, error) {
 	var entities []Entity
-	err := s.db.Select(&entities, `
+	err := s.db.SelectContext(ctx, &entities, `
 		SELECT id, name, entity_type, created_at,
 		       COALESCE(version, 1) as version,
 		       COALESCE(is_latest, 1) as is_latest,
@@ -69,7 +85,22 @@ func (s *Store) GetEntitiesByContainerTag(containerTag string) ([]*Entity, error
 
 // CreateEntityWithContainer creates an entity with a container tag in a single transaction.
 func (s *Store) CreateEntityWithContainer(name, entityType string, observations []string, containerTag string) (*Entity, error) {
-	tx, err := s.db.Begin()
+	return s.CreateEntityWithContainerContext(context.Background(), name, entityType, observations, containerTag)
+}
+
+// CreateEntityWithContainerContext is CreateEntityWithContainer with a context.
+func (s *Store) CreateEntityWithContainerContext(ctx context.Context, name, entityType string, observations []string, containerTag string) (*Entity, error) {
+	observations = s.redactAll(ctx, name, observations)
+	if err := validateEntityWithObservations(name, entityType, observations); err != nil {
+		return nil, err
+	}
+	name = NormalizeName(name)
+	entityType = s.CanonicalEntityType(entityType)
+	if err := s.checkObservations(ctx, name, entityType, observations, FactTypeDynamic); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -77,18 +108,18 @@ func (s *Store) CreateEntityWithContainer(name, entityType string, observations
 
 	// Check if entity already exists
 	var existingID int64
-	err = tx.QueryRow("SELECT id FROM entities WHERE name = ?", name).Scan(&existingID)
+	err = tx.QueryRowContext(ctx, "SELECT id FROM entities WHERE "+s.nameMatch("name"), name).Scan(&existingID)
 	if err == nil {
-		return nil, ErrEntityExists
+		return nil, entityExists(name)
 	}
 	if err != sql.ErrNoRows {
 		return nil, err
 	}
 
 	// Insert entity with container tag
-	result, err := tx.Exec(
-		"INSERT INTO entities (name, entity_type, container_tag) VALUES (?, ?, ?)",
-		name, entityType, containerTag,
+	result, err := tx.ExecContext(ctx,
+		"INSERT INTO entities (name, entity_type, container_tag, owner) VALUES (?, ?, ?, ?)",
+		name, entityType, containerTag, s.userValue(),
 	)
 	if err != nil {
 		return nil, err
@@ -100,14 +131,19 @@ func (s *Store) CreateEntityWithContainer(name, entityType string, observations
 	}
 
 	// Insert observations
+	added := make([]addedObservation, 0, len(observations))
 	for _, obs := range observations {
-		_, err := tx.Exec(
-			"INSERT INTO observations (entity_id, content) VALUES (?, ?)",
-			id, obs,
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO observations (entity_id, content, content_hash, source, author) VALUES (?, ?, ?, ?, ?)",
+			id, obs, contentHash(obs), s.sourceFor(ctx), s.userValue(),
 		)
 		if err != nil {
 			return nil, err
 		}
+		added = append(added, addedObservation{id, obs})
+	}
+	if err := s.observationsAdded(ctx, tx, added); err != nil {
+		return nil, err
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -133,9 +169,10 @@ func (s *Store) HybridSearchWithBoost(ctx context.Context, query string, queryEm
 
 	// Apply container tag boost
 	for i := range results {
-		tag, _ := s.GetContainerTag(results[i].EntityName)
+		tag, _ := s.GetContainerTagContext(ctx, results[i].EntityName)
 		if tag == containerTag && containerTag != "" {
 			results[i].FusionScore *= boostFactor
+			results[i].Boost = boostFactor
 		}
 	}
 
@@ -151,20 +188,21 @@ func (s *Store) HybridSearchWithBoost(ctx context.Context, query string, queryEm
 }
 
 // sortFusedResultsByScore sorts results by FusionScore descending (higher is better).
+// Ties keep their fused rank order.
 func sortFusedResultsByScore(results []FusedResult) {
-	// Simple bubble sort - results are typically small
-	for i := 0; i < len(results)-1; i++ {
-		for j := i + 1; j < len(results); j++ {
-			if results[j].FusionScore > results[i].FusionScore {
-				results[i], results[j] = results[j], results[i]
-			}
-		}
-	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].FusionScore > results[j].FusionScore
+	})
 }
 
 // GetContextWithContainerTag retrieves context with container tag boosting.
 // Memories with matching container_tag receive a score boost.
 func (s *Store) GetContextWithContainerTag(cfg ContextConfig, containerTag string) ([]ContextResult, error) {
+	return s.GetContextWithContainerTagContext(context.Background(), cfg, containerTag)
+}
+
+// GetContextWithContainerTagContext is GetContextWithContainerTag with a context.
+func (s *Store) GetContextWithContainerTagContext(ctx context.Context, cfg ContextConfig, containerTag string) ([]ContextResult, error) {
 	// Query all eligible observations with their entity's container_tag
 	query := `
 		SELECT e.name as entity_name, e.entity_type, o.content,
@@ -174,6 +212,8 @@ func (s *Store) GetContextWithContainerTag(cfg ContextConfig, containerTag strin
 		FROM observations o
 		JOIN entities e ON e.id = o.entity_id
 		WHERE e.is_latest = 1 AND o.importance >= ?
+		AND COALESCE(o.suppressed, 0) = 0
+		AND COALESCE(o.fact_type, '') != 'summary'
 		ORDER BY o.importance DESC
 	`
 
@@ -187,7 +227,7 @@ func (s *Store) GetContextWithContainerTag(cfg ContextConfig, containerTag strin
 	}
 
 	var rawResults []resultWithTag
-	err := s.db.Select(&rawResults, query, cfg.MinImportance)
+	err := s.db.SelectContext(ctx, &rawResults, query, cfg.MinImportance)
 	if err != nil {
 		return nil, err
 	}
@@ -209,26 +249,20 @@ func (s *Store) GetContextWithContainerTag(cfg ContextConfig, containerTag strin
 			results[i].FinalScore *= cfg.ProjectBoost
 		}
 
-		// Apply static fact boost
-		if r.FactType == "static" {
-			results[i].FinalScore *= 1.2
-		}
+		// Apply fact type boost
+		results[i].FinalScore *= s.importance.FactTypeBoost(r.FactType)
 	}
 
-	// Sort by final score (descending)
-	for i := 0; i < len(results)-1; i++ {
-		for j := i + 1; j < len(results); j++ {
-			if results[j].FinalScore > results[i].FinalScore {
-				results[i], results[j] = results[j], results[i]
-			}
-		}
-	}
+	// Sort by final score (descending); ties keep importance order
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].FinalScore > results[j].FinalScore
+	})
 
 	// Apply token budget
 	tokenCount := 0
 	var selected []ContextResult
 	for _, r := range results {
-		entryTokens := (len(r.EntityName) + len(r.Content) + 20) / 4
+		entryTokens := contextEntryTokens(s.tokenizer, r)
 		if tokenCount+entryTokens > cfg.TokenBudget {
 			break
 		}
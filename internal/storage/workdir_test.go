@@ -112,6 +112,15 @@ func TestStore_HybridSearchWithContainerBoost(t *testing.T) {
 	if results[0].EntityName != "Go" {
 		t.Errorf("expected Go to be first (boosted), got %s", results[0].EntityName)
 	}
+	for _, r := range results {
+		want := 0.0
+		if r.EntityName == "Go" {
+			want = 1.5
+		}
+		if r.Boost != want {
+			t.Errorf("%s: expected boost %v, got %v", r.EntityName, want, r.Boost)
+		}
+	}
 }
 
 func TestStore_GetContextWithContainerTag(t *testing.T) {
@@ -185,3 +194,44 @@ func TestStore_CreateEntityWithContainerTag(t *testing.T) {
 		t.Errorf("expected tag 'my-project', got %q", tag)
 	}
 }
+
+func TestStore_CloneContainer(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+
+	if err := store.Migrate(); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	store.CreateEntityWithContainer("old-app conventions", "pattern", []string{"Wrap errors with %w"}, "old-app")
+	store.CreateEntityWithContainer("Use Postgres", "decision", []string{"Chosen for JSONB"}, "old-app")
+	store.CreateEntityWithContainer("old-app", "project", []string{"Legacy billing"}, "old-app")
+	store.CreateRelation("old-app conventions", "Use Postgres", "relates_to")
+
+	clone, err := store.CloneContainer("old-app", "new-app", []string{"pattern", "decision"})
+	if err != nil {
+		t.Fatalf("CloneContainer failed: %v", err)
+	}
+	if len(clone.Entities) != 2 || clone.Observations != 2 || clone.Relations != 1 {
+		t.Errorf("expected 2 entities, 2 observations, and 1 relation cloned, got %+v", clone)
+	}
+
+	copied, err := store.GetEntity("new-app conventions")
+	if err != nil || len(copied.Observations) != 1 || copied.Observations[0] != "Wrap errors with %w" {
+		t.Errorf("expected the renamed copy with its observation, got %+v, %v", copied, err)
+	}
+	if tag, _ := store.GetContainerTag("Use Postgres (new-app)"); tag != "new-app" {
+		t.Errorf("expected the copy tagged new-app, got %q", tag)
+	}
+	if original, _ := store.GetEntity("old-app conventions"); original == nil || len(original.Observations) != 1 {
+		t.Errorf("expected the original untouched, got %+v", original)
+	}
+
+	again, err := store.CloneContainer("old-app", "new-app", nil)
+	if err != nil {
+		t.Fatalf("CloneContainer failed: %v", err)
+	}
+	if len(again.Entities) != 1 || len(again.Skipped) != 2 {
+		t.Errorf("expected existing copies skipped, got %+v", again)
+	}
+}
@@ -0,0 +1,184 @@
+// Package memory embeds mark42's memory engine in other programs: entities,
+// their observations, and the relations between them in SQLite, with
+// full-text search and token-budgeted context for prompts. It is the stable
+// part of what the mark42 CLI and MCP server use, and reads and writes the
+// same databases. Open also takes a postgres:// URL, in builds with -tags
+// postgres, for a memory service shared by many agents; this package is the
+// only way to use one, as the CLI and MCP server stay on SQLite.
+package memory
+
+import (
+	"context"
+
+	"github.com/mfenderov/mark42/internal/storage"
+)
+
+// InMemory is the path Open opens as a private in-memory database.
+const InMemory = storage.InMemory
+
+// Types shared with the mark42 database.
+type (
+	// Entity is a named thing in memory, with its observations.
+	Entity = storage.Entity
+	// Relation is a typed, directed link between two entities.
+	Relation = storage.Relation
+	// SearchResult is an entity a search matched, with its score.
+	SearchResult = storage.SearchResult
+	// ContextConfig controls which memories Memories selects; see
+	// DefaultContextConfig.
+	ContextConfig = storage.ContextConfig
+	// ContextResult is a memory Memories selected.
+	ContextResult = storage.ContextResult
+)
+
+// Errors returned by Store methods, matched with errors.Is.
+var (
+	ErrNotFound     = storage.ErrNotFound
+	ErrEntityExists = storage.ErrEntityExists
+	ErrInvalidInput = storage.ErrInvalidInput
+	// ErrPostgresUnavailable is returned by Open for postgres:// URLs in
+	// builds without -tags postgres.
+	ErrPostgresUnavailable = storage.ErrPostgresUnavailable
+)
+
+// DefaultContextConfig returns the context settings mark42 uses by default.
+func DefaultContextConfig() ContextConfig {
+	return storage.DefaultContextConfig()
+}
+
+// FormatContext renders what Memories selected as text for a prompt.
+func FormatContext(results []ContextResult) string {
+	return storage.FormatContextResults(results)
+}
+
+// Store is a memory database. It is safe for concurrent use.
+type Store struct {
+	store storage.Backend
+}
+
+// Open opens the SQLite database at path, creating it or upgrading its
+// schema as needed. InMemory opens a fresh database that lasts until Close.
+// A postgres:// URL opens that Postgres database instead, creating its
+// schema; it needs the pgvector extension.
+func Open(path string) (*Store, error) {
+	store, err := storage.OpenBackend(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{store: store}, nil
+}
+
+// OpenInMemory opens a fresh in-memory database, for tests and short-lived
+// agents.
+func OpenInMemory() (*Store, error) {
+	return Open(InMemory)
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.store.Close()
+}
+
+// CreateEntity creates an entity with optional observations.
+func (s *Store) CreateEntity(name, entityType string, observations ...string) (*Entity, error) {
+	return s.CreateEntityContext(context.Background(), name, entityType, observations...)
+}
+
+// CreateEntityContext is CreateEntity with a context.
+func (s *Store) CreateEntityContext(ctx context.Context, name, entityType string, observations ...string) (*Entity, error) {
+	return s.store.CreateEntityContext(ctx, name, entityType, observations)
+}
+
+// GetEntity returns the named entity with its observations.
+func (s *Store) GetEntity(name string) (*Entity, error) {
+	return s.GetEntityContext(context.Background(), name)
+}
+
+// GetEntityContext is GetEntity with a context.
+func (s *Store) GetEntityContext(ctx context.Context, name string) (*Entity, error) {
+	return s.store.GetEntityContext(ctx, name)
+}
+
+// ListEntities returns the entities of entityType, or every entity when it
+// is empty.
+func (s *Store) ListEntities(entityType string) ([]*Entity, error) {
+	return s.ListEntitiesContext(context.Background(), entityType)
+}
+
+// ListEntitiesContext is ListEntities with a context.
+func (s *Store) ListEntitiesContext(ctx context.Context, entityType string) ([]*Entity, error) {
+	return s.store.ListEntitiesContext(ctx, entityType)
+}
+
+// DeleteEntity deletes the named entity with its observations and relations.
+func (s *Store) DeleteEntity(name string) error {
+	return s.DeleteEntityContext(context.Background(), name)
+}
+
+// DeleteEntityContext is DeleteEntity with a context.
+func (s *Store) DeleteEntityContext(ctx context.Context, name string) error {
+	return s.store.DeleteEntityContext(ctx, name)
+}
+
+// AddObservation adds an observation to the named entity.
+func (s *Store) AddObservation(entity, content string) error {
+	return s.AddObservationContext(context.Background(), entity, content)
+}
+
+// AddObservationContext is AddObservation with a context.
+func (s *Store) AddObservationContext(ctx context.Context, entity, content string) error {
+	return s.store.AddObservationContext(ctx, entity, content)
+}
+
+// DeleteObservation deletes an observation from the named entity.
+func (s *Store) DeleteObservation(entity, content string) error {
+	return s.DeleteObservationContext(context.Background(), entity, content)
+}
+
+// DeleteObservationContext is DeleteObservation with a context.
+func (s *Store) DeleteObservationContext(ctx context.Context, entity, content string) error {
+	return s.store.DeleteObservationContext(ctx, entity, content)
+}
+
+// CreateRelation links two entities with relationType, e.g. "uses".
+func (s *Store) CreateRelation(from, to, relationType string) error {
+	return s.CreateRelationContext(context.Background(), from, to, relationType)
+}
+
+// CreateRelationContext is CreateRelation with a context.
+func (s *Store) CreateRelationContext(ctx context.Context, from, to, relationType string) error {
+	return s.store.CreateRelationContext(ctx, from, to, relationType)
+}
+
+// ListRelations returns the relations from and to the named entity.
+func (s *Store) ListRelations(entity string) ([]*Relation, error) {
+	return s.ListRelationsContext(context.Background(), entity)
+}
+
+// ListRelationsContext is ListRelations with a context.
+func (s *Store) ListRelationsContext(ctx context.Context, entity string) ([]*Relation, error) {
+	return s.store.ListRelationsContext(ctx, entity)
+}
+
+// Search finds entities whose names, types, or observations match query,
+// best first.
+func (s *Store) Search(query string) ([]*SearchResult, error) {
+	return s.SearchContext(context.Background(), query)
+}
+
+// SearchContext is Search with a context.
+func (s *Store) SearchContext(ctx context.Context, query string) ([]*SearchResult, error) {
+	return s.store.SearchContext(ctx, query)
+}
+
+// Memories selects the memories to put in a prompt within cfg's token
+// budget, boosting those of project when it is not empty. FormatContext
+// renders them.
+func (s *Store) Memories(cfg ContextConfig, project string) ([]ContextResult, error) {
+	return s.MemoriesContext(context.Background(), cfg, project)
+}
+
+// MemoriesContext is Memories with a context.
+func (s *Store) MemoriesContext(ctx context.Context, cfg ContextConfig, project string) ([]ContextResult, error) {
+	return s.store.GetContextForInjectionContext(ctx, cfg, project)
+}
@@ -0,0 +1,87 @@
+package memory_test
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mfenderov/mark42/pkg/memory"
+)
+
+func TestStore_InMemory(t *testing.T) {
+	store, err := memory.OpenInMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	if _, err := store.CreateEntity("Go", "language", "Fast compiler", "Garbage collected"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.CreateEntity("mark42", "project", "Written in Go"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.CreateRelation("mark42", "Go", "uses"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.AddObservation("Go", "Has generics"); err != nil {
+		t.Fatal(err)
+	}
+
+	entity, err := store.GetEntity("Go")
+	if err != nil || len(entity.Observations) != 3 {
+		t.Fatalf("expected Go with three observations, got %+v, %v", entity, err)
+	}
+	if relations, err := store.ListRelations("Go"); err != nil || len(relations) != 1 || relations[0].From != "mark42" {
+		t.Errorf("expected mark42 uses Go, got %+v, %v", relations, err)
+	}
+	results, err := store.Search("compiler")
+	if err != nil || len(results) != 1 || results[0].Name != "Go" {
+		t.Errorf("expected Go found, got %+v, %v", results, err)
+	}
+
+	memories, err := store.Memories(memory.DefaultContextConfig(), "")
+	if err != nil || len(memories) == 0 {
+		t.Fatalf("expected memories for a prompt, got %v, %v", memories, err)
+	}
+	if text := memory.FormatContext(memories); !strings.Contains(text, "Fast compiler") {
+		t.Errorf("expected the memories rendered, got %q", text)
+	}
+
+	if _, err := store.GetEntity("Rust"); !errors.Is(err, memory.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if _, err := store.CreateEntity("Go", "language"); !errors.Is(err, memory.ErrEntityExists) {
+		t.Errorf("expected ErrEntityExists, got %v", err)
+	}
+
+	// Each in-memory store is its own database
+	other, err := memory.OpenInMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	if entities, err := other.ListEntities(""); err != nil || len(entities) != 0 {
+		t.Errorf("expected a fresh database, got %d entities, %v", len(entities), err)
+	}
+}
+
+func TestStore_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "memory.db")
+	store, err := memory.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.CreateEntity("Go", "language", "Fast compiler")
+	store.Close()
+
+	store, err = memory.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if _, err := store.GetEntity("Go"); err != nil {
+		t.Errorf("expected Go kept in the file, got %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package integration_test
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
@@ -376,6 +377,59 @@ func BenchmarkContextInjection(b *testing.B) {
 	}
 }
 
+// newBenchGraph creates a store with entities spread over a few container
+// tags, each with a handful of observations.
+func newBenchGraph(b *testing.B, entities int) *storage.Store {
+	b.Helper()
+	store, err := storage.NewStore(filepath.Join(b.TempDir(), "bench.db"))
+	if err != nil {
+		b.Fatalf("NewStore failed: %v", err)
+	}
+	b.Cleanup(func() { store.Close() })
+	if err := store.Migrate(); err != nil {
+		b.Fatalf("Migrate failed: %v", err)
+	}
+
+	specs := make([]storage.EntitySpec, entities)
+	for i := range specs {
+		specs[i] = storage.EntitySpec{Name: fmt.Sprintf("Entity%05d", i), Type: "benchmark"}
+		for j := range 5 {
+			specs[i].Observations = append(specs[i].Observations,
+				fmt.Sprintf("Test observation %d about search performance for entity %d", j, i))
+		}
+	}
+	if _, err := store.CreateEntities(specs); err != nil {
+		b.Fatalf("CreateEntities failed: %v", err)
+	}
+	for i := 0; i < entities; i += 3 {
+		store.SetContainerTag(fmt.Sprintf("Entity%05d", i), "project-a")
+	}
+	return store
+}
+
+// BenchmarkHybridSearchWithBoost benchmarks boosted re-ranking of a large result set.
+func BenchmarkHybridSearchWithBoost(b *testing.B) {
+	store := newBenchGraph(b, 2000)
+	ctx := context.Background()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, _ = store.HybridSearchWithBoost(ctx, "test observation", nil, 500, "project-a", 1.5)
+	}
+}
+
+// BenchmarkContextWithContainerTag benchmarks ranking every eligible observation.
+func BenchmarkContextWithContainerTag(b *testing.B) {
+	store := newBenchGraph(b, 2000)
+	cfg := storage.DefaultContextConfig()
+	cfg.MinImportance = 0
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, _ = store.GetContextWithContainerTag(cfg, "project-a")
+	}
+}
+
 // BenchmarkImportanceRecalculation benchmarks importance recalculation.
 func BenchmarkImportanceRecalculation(b *testing.B) {
 	tmpDir := b.TempDir()